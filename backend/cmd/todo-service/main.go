@@ -2,17 +2,28 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
+	// база часовых поясов встроена в бинарник: в runtime-образе её может не быть
+	_ "time/tzdata"
 
-	"github.com/VLGKiwi/todo-site/backend/internal/adapter/memory"
 	"github.com/VLGKiwi/todo-site/backend/internal/controller/rest"
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/events"
+	"github.com/VLGKiwi/todo-site/backend/internal/idempotency"
+	"github.com/VLGKiwi/todo-site/backend/internal/outbox"
+	"github.com/VLGKiwi/todo-site/backend/internal/undo"
 	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+	"github.com/VLGKiwi/todo-site/backend/internal/webhooks"
 )
 
 func main() {
@@ -21,14 +32,111 @@ func main() {
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
-	// DB - in-memory (данные будут теряться при перезапуске!)
-	db := memory.New()
+	// DB - выбирается переменной STORAGE (по умолчанию in-memory,
+	// данные будут теряться при перезапуске!)
+	storageCfg := storageConfigFromEnv()
+
+	openCtx, openCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	db, closeDB, err := openStorage(openCtx, storageCfg)
+	openCancel()
+	if err != nil {
+		slog.Error("failed to open storage", "storage", storageCfg.Backend, "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := closeDB(); err != nil {
+			slog.Error("failed to close storage", "error", err)
+		}
+	}()
+	slog.Info("storage opened", "storage", storageCfg.Backend)
 
 	// USECASE
 	uc := usecase.New(db)
+	// общие списки: участники и приглашения хранятся там же, где задачи
+	uc.Lists = db
+	// правила для подзадач, по умолчанию выключены:
+	// SUBTASKS_AUTO_COMPLETE_PARENT=true - родитель завершается вместе с последней подзадачей
+	// SUBTASKS_BLOCK_OPEN=true - нельзя завершить задачу с открытыми подзадачами
+	uc.Rules = domain.SubtaskRules{
+		AutoCompleteParent: envBool("SUBTASKS_AUTO_COMPLETE_PARENT"),
+		BlockOpenSubtasks:  envBool("SUBTASKS_BLOCK_OPEN"),
+	}
+	// EVENTS - изменения задач рассылаются подписчикам GET /api/todos/events;
+	// последние события хранятся в памяти для переподключения с Last-Event-ID
+	hub := events.NewHub(events.DefaultBufferSize)
+	uc.Events = hub
+	// WEBHOOKS - подписки хранятся вместе с задачами, доставка идёт в фоне
+	// с повторами (WEBHOOK_MAX_ATTEMPTS, WEBHOOK_TIMEOUT); журнал доставок
+	// и недоставленные события живут только в памяти
+	dispatcher := webhooks.NewDispatcher(webhooks.Options{
+		Timeout:     envDuration("WEBHOOK_TIMEOUT", webhooks.DefaultTimeout),
+		MaxAttempts: envInt("WEBHOOK_MAX_ATTEMPTS", webhooks.DefaultMaxAttempts),
+	})
+	defer dispatcher.Close()
+	uc.Webhooks = db
+	uc.Deliveries = dispatcher
+	// OUTBOX - изменение задачи и его события сохраняются в одной транзакции,
+	// а затем доставляются подписчикам (SSE/WebSocket и вебхукам) хотя бы
+	// один раз, в том числе после падения процесса; повтор узнаётся по id события
+	uc.Tx = db
+	outboxDispatcher := outbox.NewDispatcher(db, outbox.Options{})
+	outboxDispatcher.Subscribe("events", func(ctx context.Context, event domain.TodoEvent) error {
+		hub.Publish(event)
+		return nil
+	})
+	outboxDispatcher.Subscribe("webhooks", uc.NotifyWebhooks)
+	uc.Outbox = outboxDispatcher
+	outboxDispatcher.Start()
+	// закрывается раньше вебхуков: последнее событие ещё может их вызвать
+	defer outboxDispatcher.Close()
+	// HISTORY - с EVENT_LOG доступны GET /api/todos/{id}/history и
+	// GET /api/todos?as_of=..., без него они отвечают 501
+	if history, ok := db.(usecase.TodoHistory); ok {
+		uc.History = history
+	}
+	// TRASH - удалённые задачи попадают в корзину (GET /api/trash), откуда
+	// их можно восстановить; фоновая задача раз в TRASH_PURGE_INTERVAL
+	// (по умолчанию 1h) удаляет навсегда всё, что лежит дольше
+	// TRASH_RETENTION (по умолчанию 720h, то есть 30 дней)
+	uc.Trash = db
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go purgeTrash(purgeCtx, uc,
+		envDuration("TRASH_RETENTION", usecase.DefaultTrashRetention),
+		envDuration("TRASH_PURGE_INTERVAL", time.Hour))
+	// UNDO - POST /api/undo и POST /api/redo отменяют и повторяют последние
+	// изменения задач пользователя; на пользователя хранится UNDO_DEPTH
+	// изменений (по умолчанию 50), только в памяти
+	uc.Commands = undo.NewLog(envInt("UNDO_DEPTH", undo.DefaultDepth))
+
+	// AUTH - токены подписываются AUTH_SECRET, срок жизни сессии AUTH_TOKEN_TTL (по умолчанию 24h)
+	auth := usecase.NewAuth(db, authSecret())
+	auth.TokenTTL = envDuration("AUTH_TOKEN_TTL", usecase.DefaultTokenTTL)
+
+	// WORKSPACES - рабочее пространство берётся из заголовка X-Tenant-ID
+	// или из поддомена TENANT_BASE_DOMAIN; TENANTS ограничивает их список
+	tenants, err := tenantResolver()
+	if err != nil {
+		slog.Error("invalid workspace configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// IDEMPOTENCY - повтор изменяющего запроса с тем же заголовком
+	// Idempotency-Key получает сохранённый ответ вместо повторного выполнения;
+	// ответы хранятся IDEMPOTENCY_TTL (по умолчанию 24h), только в памяти;
+	// IDEMPOTENCY_MAX_ENTRIES и IDEMPOTENCY_MAX_USER_ENTRIES ограничивают их
+	// число, старые ответы вытесняются новыми
+	idempotent := idempotency.NewStore(envDuration("IDEMPOTENCY_TTL", idempotency.DefaultTTL))
+	idempotent.MaxEntries = envInt("IDEMPOTENCY_MAX_ENTRIES", idempotency.DefaultMaxEntries)
+	idempotent.MaxUserEntries = envInt("IDEMPOTENCY_MAX_USER_ENTRIES", idempotency.DefaultMaxUserEntries)
+
+	// WEBSOCKET - браузер открывает /api/ws только с источника API и из
+	// WEBSOCKET_ORIGINS (через запятую, например https://todo.example.com);
+	// токен он передаёт подпротоколом "bearer.<token>" вместе с "todo-site"
+	socketOrigins := envList("WEBSOCKET_ORIGINS")
 
 	// SERVER
-	router := rest.NewRouter(uc)
+	router := rest.NewRouter(uc, auth, tenants, idempotent, socketOrigins)
 
 	// Добавляем CORS middleware
 	corsRouter := addCorsMiddleware(router)
@@ -90,6 +198,113 @@ func main() {
 	slog.Info("Server stopped")
 }
 
+// purgeTrash очищает корзину сразу и затем каждые interval, пока не отменён ctx
+func purgeTrash(ctx context.Context, uc *usecase.TodoUseCase, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := uc.EmptyTrash(ctx, retention)
+		if err != nil {
+			slog.Error("failed to empty trash", "error", err)
+		} else if n > 0 {
+			slog.Info("trash emptied", "purged", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// envBool читает флаг из переменной окружения, пустое или неверное значение - false
+func envBool(name string) bool {
+	v, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil && os.Getenv(name) != "" {
+		slog.Warn("invalid boolean in environment, using false", "name", name, "value", os.Getenv(name))
+	}
+	return err == nil && v
+}
+
+// envDuration читает длительность вида 30m или 12h, пустое или неверное значение - def
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		slog.Warn("invalid duration in environment, using default", "name", name, "value", v, "default", def)
+		return def
+	}
+	return d
+}
+
+// envInt читает положительное число, пустое или неверное значение - def
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		slog.Warn("invalid number in environment, using default", "name", name, "value", v, "default", def)
+		return def
+	}
+	return n
+}
+
+// envList читает значения через запятую, пустые пропускаются
+func envList(name string) []string {
+	var res []string
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// tenantResolver читает TENANT_BASE_DOMAIN и список TENANTS через запятую.
+// Пустой TENANTS разрешает любое имя рабочего пространства.
+func tenantResolver() (rest.TenantResolver, error) {
+	r := rest.TenantResolver{BaseDomain: strings.TrimSpace(os.Getenv("TENANT_BASE_DOMAIN"))}
+
+	for _, name := range strings.Split(os.Getenv("TENANTS"), ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		tenant, err := domain.ParseTenant(name)
+		if err != nil {
+			return rest.TenantResolver{}, fmt.Errorf("TENANTS: %q: %w", name, err)
+		}
+		r.Allowed = append(r.Allowed, tenant)
+	}
+
+	return r, nil
+}
+
+// authSecret берёт ключ подписи токенов из AUTH_SECRET. Без него ключ
+// генерируется при старте, и все сессии теряются при перезапуске.
+func authSecret() []byte {
+	if secret := os.Getenv("AUTH_SECRET"); secret != "" {
+		if len(secret) < 32 {
+			slog.Warn("AUTH_SECRET is shorter than 32 bytes")
+		}
+		return []byte(secret)
+	}
+
+	slog.Warn("AUTH_SECRET is not set, using a random secret: tokens will not survive a restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		slog.Error("failed to generate auth secret", "error", err)
+		os.Exit(1)
+	}
+	return secret
+}
+
 // Health check endpoint для Render
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
@@ -111,7 +326,8 @@ func addCorsMiddleware(next http.Handler) http.Handler {
 		}
 
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, If-Match, If-None-Match, X-Request-ID, X-Tenant-ID, Idempotency-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Location, Link, X-Next-Cursor, X-Request-ID, Idempotent-Replayed")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		// Обрабатываем preflight запросы
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/adapter/eventsourced"
+	"github.com/VLGKiwi/todo-site/backend/internal/adapter/file"
+	"github.com/VLGKiwi/todo-site/backend/internal/adapter/memory"
+	sqlrepo "github.com/VLGKiwi/todo-site/backend/internal/adapter/sql"
+	"github.com/VLGKiwi/todo-site/backend/internal/outbox"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+// Настройки хранилища из переменных окружения:
+//
+//	STORAGE      - memory (по умолчанию), file, sqlite или postgres
+//	DATA_DIR     - каталог для file-хранилища
+//	DATABASE_URL - DSN для sqlite/postgres
+//	EVENT_LOG    - файл журнала событий: если задан, задачи, теги и outbox
+//	               хранятся в нём, а STORAGE - только пользователи, списки и вебхуки
+type storageConfig struct {
+	Backend     string
+	DataDir     string
+	DatabaseURL string
+	EventLog    string
+}
+
+func storageConfigFromEnv() storageConfig {
+	cfg := storageConfig{
+		Backend:     strings.ToLower(os.Getenv("STORAGE")),
+		DataDir:     os.Getenv("DATA_DIR"),
+		DatabaseURL: os.Getenv("DATABASE_URL"),
+		EventLog:    os.Getenv("EVENT_LOG"),
+	}
+
+	// для совместимости: DATA_DIR без STORAGE означает file-хранилище
+	if cfg.Backend == "" && cfg.DataDir != "" {
+		cfg.Backend = "file"
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = "memory"
+	}
+
+	return cfg
+}
+
+// storage хранит и задачи, и пользователей с их сессиями
+type storage interface {
+	usecase.TodoRepository
+	usecase.TrashRepository
+	usecase.UserRepository
+	usecase.ListRepository
+	usecase.WebhookRepository
+	usecase.Transactor
+	outbox.Store
+}
+
+// eventSourcedStorage берёт задачи, теги, транзакции и outbox из журнала
+// событий, а остальное - из основного хранилища: методы журнала лежат
+// на уровень выше и перекрывают одноимённые методы storage.
+type eventSourcedStorage struct {
+	*eventsourced.EventSourcedTodoRepository
+	baseStorage
+}
+
+type baseStorage struct {
+	storage
+}
+
+// openStorage возвращает репозиторий и функцию для его закрытия.
+func openStorage(ctx context.Context, cfg storageConfig) (storage, func() error, error) {
+	base, closeBase, err := openBackend(ctx, cfg)
+	if err != nil || cfg.EventLog == "" {
+		return base, closeBase, err
+	}
+
+	stream, err := eventsourced.OpenFileStream(cfg.EventLog)
+	if err != nil {
+		closeBase()
+		return nil, nil, err
+	}
+	repo, err := eventsourced.New(ctx, stream)
+	if err != nil {
+		stream.Close()
+		closeBase()
+		return nil, nil, fmt.Errorf("open event log: %w", err)
+	}
+
+	closeAll := func() error {
+		return errors.Join(stream.Close(), closeBase())
+	}
+	return eventSourcedStorage{repo, baseStorage{base}}, closeAll, nil
+}
+
+// openBackend открывает хранилище, выбранное STORAGE.
+func openBackend(ctx context.Context, cfg storageConfig) (storage, func() error, error) {
+	switch cfg.Backend {
+	case "memory":
+		return memory.New(), func() error { return nil }, nil
+
+	case "file":
+		if cfg.DataDir == "" {
+			return nil, nil, fmt.Errorf("DATA_DIR is required for file storage")
+		}
+		repo, err := file.New(cfg.DataDir, file.Options{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("open file storage: %w", err)
+		}
+		return repo, repo.Close, nil
+
+	case "sqlite", "postgres":
+		if cfg.DatabaseURL == "" {
+			return nil, nil, fmt.Errorf("DATABASE_URL is required for %s storage", cfg.Backend)
+		}
+		dialect, err := sqlrepo.ParseDialect(cfg.Backend)
+		if err != nil {
+			return nil, nil, err
+		}
+		repo, err := sqlrepo.Open(ctx, dialect, cfg.DatabaseURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open %s storage: %w", cfg.Backend, err)
+		}
+		return repo, repo.Close, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+}
@@ -0,0 +1,630 @@
+// Package eventsourced keeps todos and tags as an ordered stream of events.
+// The stream is the source of truth: the current state is a projection of
+// it, rebuilt on open, and the state at any earlier time is rebuilt the
+// same way from the events up to that time.
+//
+// The outbox is kept in the stream too, so its events are stored together
+// with the change that caused them, but not in the history: once acked
+// they are dropped by compacting the stream.
+package eventsourced
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+type EventType string
+
+const (
+	TodoSaved   EventType = "todo_saved"
+	TodoUpdated EventType = "todo_updated"
+	TodoDeleted EventType = "todo_deleted"
+
+	TodoTrashed  EventType = "todo_trashed"
+	TodoRestored EventType = "todo_restored"
+	TodoPurged   EventType = "todo_purged"
+
+	TagSaved   EventType = "tag_saved"
+	TagUpdated EventType = "tag_updated"
+	TagDeleted EventType = "tag_deleted"
+
+	OutboxAdded EventType = "outbox_added"
+	OutboxAcked EventType = "outbox_acked"
+)
+
+// defaultCompactEvery is the number of acked outbox records after which
+// the stream is compacted.
+const defaultCompactEvery = 1000
+
+// Event is an entry of the stream. Todo and tag events carry the todo or
+// the tag as stored after the change, so a projection never looks at the
+// events before. Deleting a todo deletes its subtasks, and deleting a tag
+// updates the todos it was attached to, each with an event of its own.
+// Trash events carry the whole trash entry instead, subtasks included, as
+// it was trashed, restored or purged. Records of the default workspace leave Tenant empty.
+type Event struct {
+	Seq     uint64              `json:"seq"`
+	Type    EventType           `json:"type"`
+	Tenant  domain.Tenant       `json:"tenant,omitempty"`
+	ID      int                 `json:"id,omitempty"`
+	Todo    *domain.Todo        `json:"todo,omitempty"`
+	Tag     *domain.Tag         `json:"tag,omitempty"`
+	Trashed *domain.TrashedTodo `json:"trashed,omitempty"`
+	ActorID int                 `json:"actor_id,omitempty"`
+	At      time.Time           `json:"at"`
+
+	// Outbox is the event added to the outbox, Acked the ids of the
+	// events removed from it.
+	Outbox *domain.TodoEvent `json:"outbox,omitempty"`
+	Acked  []uint64          `json:"acked,omitempty"`
+}
+
+func (e Event) isOutbox() bool {
+	return e.Type == OutboxAdded || e.Type == OutboxAcked
+}
+
+// Stream stores the events in order.
+type Stream interface {
+	// Load returns every stored event, oldest first.
+	Load(ctx context.Context) ([]Event, error)
+	// Append stores the events after the others, all of them or none.
+	Append(ctx context.Context, events ...Event) error
+	// Rewrite stores the events in place of all the others, all of them
+	// or none.
+	Rewrite(ctx context.Context, events []Event) error
+}
+
+// EventSourcedTodoRepository keeps every workspace apart, each with its
+// own id sequences. The history part of the stream is held in memory as
+// well, for the history and the past states.
+type EventSourcedTodoRepository struct {
+	stream Stream
+	// Now is the clock of the events, time.Now when nil.
+	Now func() time.Time
+	// CompactEvery is the number of acked outbox records after which the
+	// stream is rewritten without them, 1000 when zero.
+	CompactEvery int
+
+	mu sync.RWMutex
+	// events holds the history, the outbox records left out
+	events  []Event
+	seq     uint64
+	lastAt  time.Time
+	tenants map[domain.Tenant]*projection
+
+	// outbox is shared by every workspace, each event carries its own.
+	outbox outbox
+}
+
+// projection is the state of one workspace.
+type projection struct {
+	DB        map[int]domain.Todo
+	NextID    int
+	Tags      map[int]domain.Tag
+	NextTagID int
+	Trash     map[int]domain.TrashedTodo
+}
+
+func newProjection() *projection {
+	return &projection{
+		DB:        map[int]domain.Todo{},
+		NextID:    1,
+		Tags:      map[int]domain.Tag{},
+		NextTagID: 1,
+		Trash:     map[int]domain.TrashedTodo{},
+	}
+}
+
+// New reads the stream and projects the current state from it. The acked
+// outbox records found on the way are dropped from the stream.
+func New(ctx context.Context, stream Stream) (*EventSourcedTodoRepository, error) {
+	events, err := stream.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load events: %w", err)
+	}
+
+	r := &EventSourcedTodoRepository{
+		stream:  stream,
+		tenants: map[domain.Tenant]*projection{},
+		outbox:  outbox{nextEventID: 1},
+	}
+	for _, event := range events {
+		r.apply(event)
+	}
+	r.keep(events)
+
+	if r.outbox.dropped > 0 {
+		if err := r.compact(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// project rebuilds the state of the workspaces from the history, the
+// outbox is left as it is. Must be called with r.mu held for writing.
+func (r *EventSourcedTodoRepository) project() {
+	r.tenants = map[domain.Tenant]*projection{}
+
+	for _, event := range r.events {
+		r.apply(event)
+	}
+}
+
+// keep adds the stored events to the history, the outbox records left
+// out. Must be called with r.mu held for writing.
+func (r *EventSourcedTodoRepository) keep(events []Event) {
+	for _, event := range events {
+		if !event.isOutbox() {
+			r.events = append(r.events, event)
+		}
+	}
+}
+
+// apply must be called with r.mu held for writing.
+func (r *EventSourcedTodoRepository) apply(event Event) {
+	// a rolled back transaction leaves a gap, never a sequence used twice
+	r.seq = max(r.seq, event.Seq)
+	if event.At.After(r.lastAt) {
+		r.lastAt = event.At
+	}
+
+	if event.isOutbox() {
+		r.outbox.apply(event)
+		return
+	}
+
+	p := r.tenants[tenantOf(event)]
+	if p == nil {
+		p = newProjection()
+		r.tenants[tenantOf(event)] = p
+	}
+	p.apply(event)
+}
+
+func (p *projection) apply(event Event) {
+	switch event.Type {
+	case TodoSaved, TodoUpdated:
+		if event.Todo != nil {
+			p.DB[event.ID] = *event.Todo
+		}
+	case TodoDeleted:
+		delete(p.DB, event.ID)
+	case TodoTrashed:
+		if event.Trashed != nil {
+			for _, v := range event.Trashed.Todos() {
+				delete(p.DB, v.ID)
+			}
+			p.Trash[event.ID] = *event.Trashed
+		}
+	case TodoRestored:
+		if event.Trashed != nil {
+			for _, v := range event.Trashed.Todos() {
+				p.DB[v.ID] = v
+			}
+			delete(p.Trash, event.ID)
+		}
+	case TodoPurged:
+		delete(p.Trash, event.ID)
+	case TagSaved, TagUpdated:
+		if event.Tag != nil {
+			p.Tags[event.ID] = *event.Tag
+		}
+	case TagDeleted:
+		delete(p.Tags, event.ID)
+	}
+
+	// ids of deleted todos and tags must never be handed out again
+	switch event.Type {
+	case TodoSaved, TodoUpdated, TodoDeleted, TodoTrashed, TodoRestored, TodoPurged:
+		p.NextID = max(p.NextID, event.ID+1)
+	case TagSaved, TagUpdated, TagDeleted:
+		p.NextTagID = max(p.NextTagID, event.ID+1)
+	}
+}
+
+func tenantOf(event Event) domain.Tenant {
+	if event.Tenant == "" {
+		return domain.DefaultTenant
+	}
+	return event.Tenant
+}
+
+// read returns the workspace of the context, empty when nothing was stored
+// in it yet. Must be called with r.mu held.
+func (r *EventSourcedTodoRepository) read(ctx context.Context) *projection {
+	if p, ok := r.tenants[domain.CurrentTenant(ctx)]; ok {
+		return p
+	}
+	return newProjection()
+}
+
+func (r *EventSourcedTodoRepository) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// record stores the events, stamped with the workspace and the user of
+// the context, and applies them. Within a transaction they are applied
+// right away but only stored on commit. Must be called with r.mu held for
+// writing.
+func (r *EventSourcedTodoRepository) record(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	// the stream stays in time order even when the clock steps back
+	at := r.now()
+	if at.Before(r.lastAt) {
+		at = r.lastAt
+	}
+	var actorID int
+	if user, ok := domain.UserFromContext(ctx); ok {
+		actorID = user.ID
+	}
+
+	for i := range events {
+		events[i].Seq = r.seq + uint64(i) + 1
+		events[i].At = at
+		if !events[i].isOutbox() {
+			events[i].ActorID = actorID
+			if tenant := domain.CurrentTenant(ctx); tenant != domain.DefaultTenant {
+				events[i].Tenant = tenant
+			}
+		}
+	}
+
+	if tx := r.tx(ctx); tx != nil {
+		tx.events = append(tx.events, events...)
+	} else {
+		if err := r.stream.Append(ctx, events...); err != nil {
+			return fmt.Errorf("append events: %w", err)
+		}
+		r.keep(events)
+	}
+
+	for _, event := range events {
+		r.apply(event)
+	}
+	r.maybeCompact(ctx)
+	return nil
+}
+
+// maybeCompact waits for the end of a transaction in ctx, as the events
+// of the transaction are not stored yet. Must be called with r.mu held
+// for writing.
+func (r *EventSourcedTodoRepository) maybeCompact(ctx context.Context) {
+	compactEvery := r.CompactEvery
+	if compactEvery <= 0 {
+		compactEvery = defaultCompactEvery
+	}
+	if r.outbox.dropped < compactEvery || r.tx(ctx) != nil {
+		return
+	}
+
+	// the events are already durable, so a failed compaction is not fatal
+	if err := r.compact(ctx); err != nil {
+		slog.Error("failed to compact event stream", "error", err)
+	}
+}
+
+// compact rewrites the stream with the history and the outbox records it
+// still needs. Must be called with r.mu held for writing.
+func (r *EventSourcedTodoRepository) compact(ctx context.Context) error {
+	events := slices.Concat(r.events, r.outbox.records())
+	slices.SortFunc(events, func(a Event, b Event) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+
+	if err := r.stream.Rewrite(ctx, events); err != nil {
+		return fmt.Errorf("compact events: %w", err)
+	}
+	r.outbox.dropped = 0
+
+	return nil
+}
+
+func (r *EventSourcedTodoRepository) Save(ctx context.Context, todo domain.Todo) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer r.lock(ctx)()
+
+	p := r.read(ctx)
+
+	tags, err := p.todoTags(todo.Tags)
+	if err != nil {
+		return 0, err
+	}
+	if err := p.checkParent(0, todo.ParentID); err != nil {
+		return 0, err
+	}
+
+	id := p.NextID
+	todo.ID = id
+	todo.Version = 1
+	todo.Tags = tags
+
+	if err := r.record(ctx, Event{Type: TodoSaved, ID: id, Todo: &todo}); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *EventSourcedTodoRepository) GetByID(ctx context.Context, id int) (domain.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Todo{}, err
+	}
+
+	defer r.rlock(ctx)()
+
+	v, ok := r.read(ctx).DB[id]
+	if !ok {
+		return domain.Todo{}, domain.ErrTodoNotExist
+	}
+
+	return v, nil
+}
+
+func (r *EventSourcedTodoRepository) UpdateByID(ctx context.Context, id int, todo domain.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock(ctx)()
+
+	p := r.read(ctx)
+
+	stored, ok := p.DB[id]
+	if !ok {
+		return domain.ErrTodoNotExist
+	}
+	if todo.Version != 0 && todo.Version != stored.Version {
+		return domain.ErrVersionMismatch
+	}
+	tags, err := p.todoTags(todo.Tags)
+	if err != nil {
+		return err
+	}
+	if err := p.checkParent(id, todo.ParentID); err != nil {
+		return err
+	}
+	todo.ID = id
+	todo.Version = stored.Version + 1
+	todo.Tags = tags
+
+	return r.record(ctx, Event{Type: TodoUpdated, ID: id, Todo: &todo})
+}
+
+func (r *EventSourcedTodoRepository) DeleteByID(ctx context.Context, id int, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock(ctx)()
+
+	p := r.read(ctx)
+
+	stored, ok := p.DB[id]
+	if !ok {
+		return domain.ErrTodoNotExist
+	}
+	if version != 0 && version != stored.Version {
+		return domain.ErrVersionMismatch
+	}
+
+	var events []Event
+	for _, v := range p.subtree(id) {
+		todo := p.DB[v]
+		events = append(events, Event{Type: TodoDeleted, ID: v, Todo: &todo})
+	}
+	return r.record(ctx, events...)
+}
+
+func (r *EventSourcedTodoRepository) ReadAll(ctx context.Context) ([]domain.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Todo{}, err
+	}
+
+	unlock := r.rlock(ctx)
+	res := slices.Collect(maps.Values(r.read(ctx).DB))
+	unlock()
+
+	if res == nil {
+		res = []domain.Todo{}
+	}
+	slices.SortFunc(res, func(a domain.Todo, b domain.Todo) int {
+		return a.ID - b.ID
+	})
+
+	return res, nil
+}
+
+func (r *EventSourcedTodoRepository) Query(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Todo{}, err
+	}
+
+	defer r.rlock(ctx)()
+
+	return q.Apply(maps.Values(r.read(ctx).DB)), nil
+}
+
+// checkParent makes sure the parent exists and is not the todo itself or
+// one of its subtasks.
+func (p *projection) checkParent(id int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+	if _, ok := p.DB[*parentID]; !ok {
+		return domain.ErrParentNotExist
+	}
+
+	for v := parentID; v != nil; v = p.DB[*v].ParentID {
+		if *v == id {
+			return domain.ErrParentCycle
+		}
+	}
+	return nil
+}
+
+// subtree returns the todo followed by all its subtasks at any depth.
+func (p *projection) subtree(id int) []int {
+	children := map[int][]int{}
+	for _, v := range p.DB {
+		if v.ParentID != nil {
+			children[*v.ParentID] = append(children[*v.ParentID], v.ID)
+		}
+	}
+	for _, ids := range children {
+		slices.Sort(ids)
+	}
+
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// todoTags normalizes the tag ids of a todo.
+func (p *projection) todoTags(ids []int) ([]int, error) {
+	ids = domain.NormalizeTagIDs(ids)
+	for _, id := range ids {
+		if _, ok := p.Tags[id]; !ok {
+			return nil, domain.ErrTagNotExist
+		}
+	}
+	return ids, nil
+}
+
+func (r *EventSourcedTodoRepository) SaveTag(ctx context.Context, tag domain.Tag) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer r.lock(ctx)()
+
+	p := r.read(ctx)
+
+	if p.tagNameTaken(tag.Name, 0) {
+		return 0, domain.ErrTagExists
+	}
+
+	id := p.NextTagID
+	tag.ID = id
+
+	if err := r.record(ctx, Event{Type: TagSaved, ID: id, Tag: &tag}); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *EventSourcedTodoRepository) GetTagByID(ctx context.Context, id int) (domain.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Tag{}, err
+	}
+
+	defer r.rlock(ctx)()
+
+	v, ok := r.read(ctx).Tags[id]
+	if !ok {
+		return domain.Tag{}, domain.ErrTagNotExist
+	}
+
+	return v, nil
+}
+
+func (r *EventSourcedTodoRepository) UpdateTagByID(ctx context.Context, id int, tag domain.Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock(ctx)()
+
+	p := r.read(ctx)
+
+	if _, ok := p.Tags[id]; !ok {
+		return domain.ErrTagNotExist
+	}
+	if p.tagNameTaken(tag.Name, id) {
+		return domain.ErrTagExists
+	}
+	tag.ID = id
+
+	return r.record(ctx, Event{Type: TagUpdated, ID: id, Tag: &tag})
+}
+
+func (r *EventSourcedTodoRepository) DeleteTagByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock(ctx)()
+
+	p := r.read(ctx)
+
+	if _, ok := p.Tags[id]; !ok {
+		return domain.ErrTagNotExist
+	}
+
+	var events []Event
+	for _, todo := range p.DB {
+		if !slices.Contains(todo.Tags, id) {
+			continue
+		}
+		// stored slices may be shared with callers, so never edit them in place
+		todo.Tags = slices.DeleteFunc(slices.Clone(todo.Tags), func(tagID int) bool {
+			return tagID == id
+		})
+		todo.Version++
+		events = append(events, Event{Type: TodoUpdated, ID: todo.ID, Todo: &todo})
+	}
+	slices.SortFunc(events, func(a Event, b Event) int {
+		return a.ID - b.ID
+	})
+	events = append(events, Event{Type: TagDeleted, ID: id})
+
+	return r.record(ctx, events...)
+}
+
+func (r *EventSourcedTodoRepository) ReadAllTags(ctx context.Context) ([]domain.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Tag{}, err
+	}
+
+	unlock := r.rlock(ctx)
+	res := slices.Collect(maps.Values(r.read(ctx).Tags))
+	unlock()
+
+	if res == nil {
+		res = []domain.Tag{}
+	}
+	slices.SortFunc(res, func(a domain.Tag, b domain.Tag) int {
+		return a.ID - b.ID
+	})
+
+	return res, nil
+}
+
+// tagNameTaken reports whether a tag other than self already has the name.
+func (p *projection) tagNameTaken(name string, self int) bool {
+	for _, t := range p.Tags {
+		if t.ID != self && domain.SameTagName(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
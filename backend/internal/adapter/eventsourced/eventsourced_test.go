@@ -0,0 +1,617 @@
+package eventsourced
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/adapter/memory"
+	"github.com/VLGKiwi/todo-site/backend/internal/adapter/repotest"
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+func openRepo(t *testing.T, path string) (*EventSourcedTodoRepository, *FileStream) {
+	t.Helper()
+
+	stream, err := OpenFileStream(path)
+	if err != nil {
+		t.Fatalf("unexpected error on open: got %v, want nil", err)
+	}
+	todoRepo, err := New(context.Background(), stream)
+	if err != nil {
+		stream.Close()
+		t.Fatalf("unexpected error on load: got %v, want nil", err)
+	}
+
+	return todoRepo, stream
+}
+
+// clock hands out the times one minute apart, the first a minute after start.
+func clock(start time.Time) func() time.Time {
+	now := start
+	return func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+}
+
+var start = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestReplay(t *testing.T) {
+	t.Run("restores todos and tags after restart", func(t *testing.T) {
+		// preparing
+		path := filepath.Join(t.TempDir(), "events.log")
+		ctx := context.Background()
+
+		todoRepo, stream := openRepo(t, path)
+
+		workID, _ := todoRepo.SaveTag(ctx, domain.Tag{Name: "work"})
+		firstID, _ := todoRepo.Save(ctx, domain.Todo{Title: "read the book", Tags: []int{workID}})
+		secondID, _ := todoRepo.Save(ctx, domain.Todo{Title: "complete the game"})
+		thirdID, _ := todoRepo.Save(ctx, domain.Todo{Title: "get an internship", ParentID: &secondID})
+
+		if err := todoRepo.UpdateByID(ctx, firstID, domain.Todo{Title: "read the book", Completed: true, Tags: []int{workID}}); err != nil {
+			t.Fatalf("unexpected error on update: got %v, want nil", err)
+		}
+		if err := todoRepo.DeleteTagByID(ctx, workID); err != nil {
+			t.Fatalf("unexpected error on tag delete: got %v, want nil", err)
+		}
+		if err := todoRepo.DeleteByID(ctx, secondID, 0); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+		stream.Close()
+
+		// act
+		reopened, stream := openRepo(t, path)
+		defer stream.Close()
+
+		// assert
+		todos, err := reopened.ReadAll(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(todos) != 1 {
+			t.Fatalf("unexpected length: got %d, want %d", len(todos), 1)
+		}
+		if todos[0].ID != firstID || !todos[0].Completed || len(todos[0].Tags) != 0 || todos[0].Version != 3 {
+			t.Errorf("changes were not replayed: got %+v", todos[0])
+		}
+
+		// the ids of deleted todos and tags must not be reused
+		id, _ := reopened.Save(ctx, domain.Todo{Title: "buy milk"})
+		if id != thirdID+1 {
+			t.Errorf("unexpected id: got %d, want %d", id, thirdID+1)
+		}
+		tagID, _ := reopened.SaveTag(ctx, domain.Tag{Name: "home"})
+		if tagID != workID+1 {
+			t.Errorf("unexpected tag id: got %d, want %d", tagID, workID+1)
+		}
+	})
+
+	t.Run("torn append is dropped whole", func(t *testing.T) {
+		// preparing
+		path := filepath.Join(t.TempDir(), "events.log")
+		ctx := context.Background()
+
+		todoRepo, stream := openRepo(t, path)
+		id, _ := todoRepo.Save(ctx, domain.Todo{Title: "read the book"})
+		err := todoRepo.Atomically(ctx, func(ctx context.Context) error {
+			if err := todoRepo.UpdateByID(ctx, id, domain.Todo{Title: "read the book", Completed: true}); err != nil {
+				return err
+			}
+			return todoRepo.AddEvents(ctx, domain.TodoEvent{Type: domain.TodoCompleted, Todo: domain.Todo{ID: id}})
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		stream.Close()
+
+		// the process died in the middle of writing the transaction
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat stream: %v", err)
+		}
+		if err := os.Truncate(path, info.Size()-10); err != nil {
+			t.Fatalf("truncate stream: %v", err)
+		}
+
+		// act
+		reopened, stream := openRepo(t, path)
+		defer stream.Close()
+
+		// assert
+		todo, err := reopened.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error on get: got %v, want nil", err)
+		}
+		if todo.Completed || todo.Version != 1 {
+			t.Errorf("torn update must be dropped: got %+v", todo)
+		}
+		if events, _ := reopened.PendingEvents(ctx, 10); len(events) != 0 {
+			t.Errorf("unexpected events: got %+v, want none", events)
+		}
+
+		// the stream goes on after the last whole append
+		if _, err := reopened.Save(ctx, domain.Todo{Title: "buy milk"}); err != nil {
+			t.Fatalf("unexpected error on save: got %v, want nil", err)
+		}
+		stream.Close()
+
+		again, stream := openRepo(t, path)
+		defer stream.Close()
+		if todos, _ := again.ReadAll(ctx); len(todos) != 2 {
+			t.Errorf("unexpected todos: got %+v, want 2", todos)
+		}
+	})
+}
+
+// lines returns the number of appends stored in the stream file.
+func lines(t *testing.T, path string) int {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read stream: %v", err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestCompaction(t *testing.T) {
+	t.Run("acked outbox records are dropped", func(t *testing.T) {
+		// preparing
+		path := filepath.Join(t.TempDir(), "events.log")
+		ctx := context.Background()
+
+		todoRepo, stream := openRepo(t, path)
+		todoRepo.CompactEvery = 4
+
+		id, _ := todoRepo.Save(ctx, domain.Todo{Title: "read the book"})
+		for range 3 {
+			if err := todoRepo.AddEvents(ctx, domain.TodoEvent{Type: domain.TodoUpdated, Todo: domain.Todo{ID: id}}); err != nil {
+				t.Fatalf("unexpected error on add: got %v, want nil", err)
+			}
+			events, _ := todoRepo.PendingEvents(ctx, 10)
+			if err := todoRepo.AckEvents(ctx, events[0].ID); err != nil {
+				t.Fatalf("unexpected error on ack: got %v, want nil", err)
+			}
+		}
+
+		// act
+		todoRepo.AddEvents(ctx, domain.TodoEvent{Type: domain.TodoDeleted, Todo: domain.Todo{ID: id}})
+		stream.Close()
+
+		// assert
+		// the todo, the last ack and the event still pending
+		if n := lines(t, path); n != 3 {
+			t.Errorf("unexpected stream length: got %d lines, want %d", n, 3)
+		}
+		if n := len(todoRepo.events); n != 1 {
+			t.Errorf("unexpected history length: got %d, want %d", n, 1)
+		}
+
+		reopened, stream := openRepo(t, path)
+		defer stream.Close()
+
+		events, _ := reopened.PendingEvents(ctx, 10)
+		if len(events) != 1 || events[0].ID != 4 || events[0].Type != domain.TodoDeleted {
+			t.Fatalf("unexpected pending events: got %+v", events)
+		}
+		if todo, err := reopened.GetByID(ctx, id); err != nil || todo.Title != "read the book" {
+			t.Errorf("unexpected todo: got %+v, %v", todo, err)
+		}
+
+		// the ids of acked events must not be reused
+		reopened.AckEvents(ctx, 4)
+		reopened.AddEvents(ctx, domain.TodoEvent{Type: domain.TodoCreated})
+		if events, _ := reopened.PendingEvents(ctx, 10); len(events) != 1 || events[0].ID != 5 {
+			t.Errorf("unexpected pending events: got %+v, want id %d", events, 5)
+		}
+	})
+
+	t.Run("acked outbox records are dropped on open", func(t *testing.T) {
+		// preparing
+		path := filepath.Join(t.TempDir(), "events.log")
+		ctx := context.Background()
+
+		todoRepo, stream := openRepo(t, path)
+		for range 3 {
+			todoRepo.AddEvents(ctx, domain.TodoEvent{Type: domain.TodoCreated})
+		}
+		todoRepo.AckEvents(ctx, 1, 2)
+		stream.Close()
+
+		// act
+		reopened, stream := openRepo(t, path)
+		defer stream.Close()
+
+		// assert
+		// the last ack and the event still pending
+		if n := lines(t, path); n != 2 {
+			t.Errorf("unexpected stream length: got %d lines, want %d", n, 2)
+		}
+		reopened.AddEvents(ctx, domain.TodoEvent{Type: domain.TodoCreated})
+		events, _ := reopened.PendingEvents(ctx, 10)
+		if len(events) != 2 || events[0].ID != 3 || events[1].ID != 4 {
+			t.Errorf("unexpected pending events: got %+v", events)
+		}
+	})
+
+	t.Run("rolled back transaction leaves the outbox as it was", func(t *testing.T) {
+		// preparing
+		ctx := context.Background()
+		todoRepo, stream := openRepo(t, filepath.Join(t.TempDir(), "events.log"))
+		defer stream.Close()
+
+		todoRepo.AddEvents(ctx, domain.TodoEvent{Type: domain.TodoCreated})
+		failed := errors.New("failed")
+
+		// act
+		err := todoRepo.Atomically(ctx, func(ctx context.Context) error {
+			todoRepo.AckEvents(ctx, 1)
+			todoRepo.AddEvents(ctx, domain.TodoEvent{Type: domain.TodoDeleted})
+			return failed
+		})
+
+		// assert
+		if !errors.Is(err, failed) {
+			t.Fatalf("unexpected error: got %v, want %v", err, failed)
+		}
+		events, _ := todoRepo.PendingEvents(ctx, 10)
+		if len(events) != 1 || events[0].ID != 1 {
+			t.Errorf("unexpected pending events: got %+v", events)
+		}
+	})
+}
+
+func TestTodoHistory(t *testing.T) {
+	// preparing
+	todoRepo, stream := openRepo(t, filepath.Join(t.TempDir(), "events.log"))
+	defer stream.Close()
+	todoRepo.Now = clock(start)
+
+	ann := domain.ContextWithUser(context.Background(), domain.User{ID: 7})
+	bob := domain.ContextWithUser(context.Background(), domain.User{ID: 8})
+
+	parentID, _ := todoRepo.Save(ann, domain.Todo{Title: "complete the game"})
+	id, _ := todoRepo.Save(ann, domain.Todo{Title: "read the book", ParentID: &parentID})
+	if err := todoRepo.UpdateByID(bob, id, domain.Todo{Title: "read the book", Completed: true, ParentID: &parentID}); err != nil {
+		t.Fatalf("unexpected error on update: got %v, want nil", err)
+	}
+	if err := todoRepo.DeleteByID(ann, parentID, 0); err != nil {
+		t.Fatalf("unexpected error on delete: got %v, want nil", err)
+	}
+
+	t.Run("every change in order", func(t *testing.T) {
+		// act
+		changes, err := todoRepo.TodoHistory(ann, id)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		want := []struct {
+			typ     domain.TodoChangeType
+			actorID int
+			at      time.Time
+			version int
+		}{
+			{domain.TodoChangeCreated, 7, start.Add(2 * time.Minute), 1},
+			{domain.TodoChangeUpdated, 8, start.Add(3 * time.Minute), 2},
+			// deleted together with its parent
+			{domain.TodoChangeDeleted, 7, start.Add(4 * time.Minute), 2},
+		}
+		if len(changes) != len(want) {
+			t.Fatalf("unexpected length: got %d, want %d", len(changes), len(want))
+		}
+		for i, w := range want {
+			c := changes[i]
+			if c.Type != w.typ || c.ActorID != w.actorID || !c.At.Equal(w.at) || c.Todo.Version != w.version || c.Todo.ID != id {
+				t.Errorf("unexpected change %d: got %+v", i, c)
+			}
+		}
+	})
+
+	t.Run("unknown todo -> ErrTodoNotExist", func(t *testing.T) {
+		// act
+		_, err := todoRepo.TodoHistory(ann, 42)
+
+		// assert
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+
+	t.Run("other workspace -> ErrTodoNotExist", func(t *testing.T) {
+		// act
+		_, err := todoRepo.TodoHistory(domain.ContextWithTenant(ann, "acme"), id)
+
+		// assert
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+}
+
+func TestTodoHistoryTrash(t *testing.T) {
+	// preparing
+	todoRepo, stream := openRepo(t, filepath.Join(t.TempDir(), "events.log"))
+	defer stream.Close()
+	todoRepo.Now = clock(start)
+
+	ctx := context.Background()
+
+	parentID, _ := todoRepo.Save(ctx, domain.Todo{Title: "complete the game"})            // +1m
+	id, _ := todoRepo.Save(ctx, domain.Todo{Title: "read the book", ParentID: &parentID}) // +2m
+	if err := todoRepo.TrashByID(ctx, parentID, 0, start); err != nil {                   // +3m
+		t.Fatalf("unexpected error on trash: got %v, want nil", err)
+	}
+	if _, err := todoRepo.RestoreByID(ctx, parentID); err != nil { // +4m
+		t.Fatalf("unexpected error on restore: got %v, want nil", err)
+	}
+	if err := todoRepo.TrashByID(ctx, parentID, 0, start); err != nil { // +5m
+		t.Fatalf("unexpected error on trash: got %v, want nil", err)
+	}
+	if err := todoRepo.PurgeByID(ctx, parentID); err != nil { // +6m
+		t.Fatalf("unexpected error on purge: got %v, want nil", err)
+	}
+
+	// act
+	changes, err := todoRepo.TodoHistory(ctx, id)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+
+	// the subtask goes along with its parent every time
+	want := []domain.TodoChangeType{
+		domain.TodoChangeCreated,
+		domain.TodoChangeDeleted,
+		domain.TodoChangeRestored,
+		domain.TodoChangeDeleted,
+		domain.TodoChangePurged,
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("unexpected length: got %d, want %d", len(changes), len(want))
+	}
+	for i, w := range want {
+		if changes[i].Type != w || changes[i].Todo.ID != id {
+			t.Errorf("unexpected change %d: got %+v, want type %s", i, changes[i], w)
+		}
+	}
+
+	// the state before the purge is rebuilt with the trash
+	todos, err := todoRepo.QueryAsOf(ctx, domain.TodoQuery{AsOf: start.Add(4 * time.Minute)})
+	if err != nil {
+		t.Fatalf("unexpected error on query: got %v, want nil", err)
+	}
+	if len(todos) != 2 {
+		t.Errorf("unexpected restored todos: got %+v", todos)
+	}
+}
+
+func TestTodoHistoryTenants(t *testing.T) {
+	// preparing
+	todoRepo, stream := openRepo(t, filepath.Join(t.TempDir(), "events.log"))
+	defer stream.Close()
+	todoRepo.Now = clock(start)
+
+	acme := domain.ContextWithTenant(context.Background(), "acme")
+	globex := domain.ContextWithTenant(context.Background(), "globex")
+
+	// ids are sequenced per workspace, so both todos get the same one
+	id, _ := todoRepo.Save(acme, domain.Todo{Title: "acme plan"})                   // +1m
+	todoRepo.Save(globex, domain.Todo{Title: "globex plan"})                        // +2m
+	todoRepo.UpdateByID(acme, id, domain.Todo{Title: "acme plan", Completed: true}) // +3m
+	if err := todoRepo.DeleteByID(globex, id, 0); err != nil {                      // +4m
+		t.Fatalf("unexpected error on delete: got %v, want nil", err)
+	}
+
+	t.Run("history of each workspace", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			ctx   context.Context
+			title string
+			want  []domain.TodoChangeType
+		}{
+			{"acme", acme, "acme plan", []domain.TodoChangeType{domain.TodoChangeCreated, domain.TodoChangeUpdated}},
+			{"globex", globex, "globex plan", []domain.TodoChangeType{domain.TodoChangeCreated, domain.TodoChangeDeleted}},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				// act
+				changes, err := todoRepo.TodoHistory(tc.ctx, id)
+
+				// assert
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+				if len(changes) != len(tc.want) {
+					t.Fatalf("unexpected changes: got %+v, want types %v", changes, tc.want)
+				}
+				for i, w := range tc.want {
+					if changes[i].Type != w || changes[i].Todo.Title != tc.title {
+						t.Errorf("unexpected change %d: got %+v, want %s of %q", i, changes[i], w, tc.title)
+					}
+				}
+			})
+		}
+
+		if _, err := todoRepo.TodoHistory(context.Background(), id); !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error in the default workspace: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+
+	t.Run("past state of each workspace", func(t *testing.T) {
+		tests := []struct {
+			name string
+			ctx  context.Context
+			asOf time.Time
+			want []string
+		}{
+			{"acme before the change of globex", acme, start.Add(2 * time.Minute), []string{"acme plan"}},
+			{"globex before the delete", globex, start.Add(3 * time.Minute), []string{"globex plan"}},
+			{"globex after the delete", globex, start.Add(4 * time.Minute), []string{}},
+			{"default workspace", context.Background(), start.Add(4 * time.Minute), []string{}},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				// act
+				todos, err := todoRepo.QueryAsOf(tc.ctx, domain.TodoQuery{AsOf: tc.asOf})
+
+				// assert
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+				titles := []string{}
+				for _, todo := range todos {
+					titles = append(titles, todo.Title)
+				}
+				if !slices.Equal(titles, tc.want) {
+					t.Errorf("unexpected todos: got %v, want %v", titles, tc.want)
+				}
+			})
+		}
+	})
+}
+
+func TestQueryAsOf(t *testing.T) {
+	// preparing
+	todoRepo, stream := openRepo(t, filepath.Join(t.TempDir(), "events.log"))
+	defer stream.Close()
+	todoRepo.Now = clock(start)
+
+	ctx := context.Background()
+	acme := domain.ContextWithTenant(ctx, "acme")
+
+	firstID, _ := todoRepo.Save(ctx, domain.Todo{Title: "read the book"})             // +1m
+	secondID, _ := todoRepo.Save(ctx, domain.Todo{Title: "complete the game"})        // +2m
+	todoRepo.UpdateByID(ctx, firstID, domain.Todo{Title: "read it", Completed: true}) // +3m
+	todoRepo.DeleteByID(ctx, secondID, 0)                                             // +4m
+	todoRepo.Save(acme, domain.Todo{Title: "ship the release"})                       // +5m
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		query domain.TodoQuery
+		want  []domain.Todo
+	}{
+		{
+			name:  "before the first change",
+			ctx:   ctx,
+			query: domain.TodoQuery{AsOf: start},
+			want:  []domain.Todo{},
+		},
+		{
+			name:  "after the creation",
+			ctx:   ctx,
+			query: domain.TodoQuery{AsOf: start.Add(2 * time.Minute)},
+			want: []domain.Todo{
+				{ID: firstID, Title: "read the book", Version: 1},
+				{ID: secondID, Title: "complete the game", Version: 1},
+			},
+		},
+		{
+			name:  "between the changes",
+			ctx:   ctx,
+			query: domain.TodoQuery{AsOf: start.Add(3*time.Minute + time.Second)},
+			want: []domain.Todo{
+				{ID: firstID, Title: "read it", Completed: true, Version: 2},
+				{ID: secondID, Title: "complete the game", Version: 1},
+			},
+		},
+		{
+			name:  "filters apply to the past state",
+			ctx:   ctx,
+			query: domain.TodoQuery{AsOf: start.Add(2 * time.Minute), Filter: domain.TodoFilter{Search: "game"}},
+			want: []domain.Todo{
+				{ID: secondID, Title: "complete the game", Version: 1},
+			},
+		},
+		{
+			name:  "zero time reads the current state",
+			ctx:   ctx,
+			query: domain.TodoQuery{},
+			want: []domain.Todo{
+				{ID: firstID, Title: "read it", Completed: true, Version: 2},
+			},
+		},
+		{
+			name:  "other workspace",
+			ctx:   acme,
+			query: domain.TodoQuery{AsOf: start.Add(4 * time.Minute)},
+			want:  []domain.Todo{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			todos, err := todoRepo.QueryAsOf(tc.ctx, tc.query)
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			if len(todos) != len(tc.want) {
+				t.Fatalf("unexpected length: got %+v, want %+v", todos, tc.want)
+			}
+			for i, want := range tc.want {
+				got := todos[i]
+				if got.ID != want.ID || got.Title != want.Title || got.Completed != want.Completed || got.Version != want.Version {
+					t.Errorf("unexpected todo %d: got %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConformance(t *testing.T) {
+	repotest.RunConformance(t, func(t *testing.T) usecase.TodoRepository {
+		todoRepo, stream := openRepo(t, filepath.Join(t.TempDir(), "events.log"))
+		t.Cleanup(func() { stream.Close() })
+		return todoRepo
+	})
+}
+
+// tenantRepo takes the users and the lists from memory, as the service
+// takes them from its main storage.
+type tenantRepo struct {
+	*EventSourcedTodoRepository
+	usecase.UserRepository
+	usecase.ListRepository
+}
+
+func TestTenantConformance(t *testing.T) {
+	repotest.RunTenantConformance(t, func(t *testing.T) repotest.TenantRepository {
+		todoRepo, stream := openRepo(t, filepath.Join(t.TempDir(), "events.log"))
+		t.Cleanup(func() { stream.Close() })
+		base := memory.New()
+		return tenantRepo{todoRepo, base, base}
+	})
+}
+
+func TestOutboxConformance(t *testing.T) {
+	repotest.RunOutboxConformance(t, func(t *testing.T) repotest.OutboxRepository {
+		todoRepo, stream := openRepo(t, filepath.Join(t.TempDir(), "events.log"))
+		t.Cleanup(func() { stream.Close() })
+		return todoRepo
+	})
+}
+
+func TestTrashConformance(t *testing.T) {
+	repotest.RunTrashConformance(t, func(t *testing.T) repotest.TrashRepository {
+		todoRepo, stream := openRepo(t, filepath.Join(t.TempDir(), "events.log"))
+		t.Cleanup(func() { stream.Close() })
+		return todoRepo
+	})
+}
@@ -0,0 +1,93 @@
+package eventsourced
+
+import (
+	"context"
+	"maps"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+var changeTypes = map[EventType]domain.TodoChangeType{
+	TodoSaved:   domain.TodoChangeCreated,
+	TodoUpdated: domain.TodoChangeUpdated,
+	TodoDeleted: domain.TodoChangeDeleted,
+
+	TodoTrashed:  domain.TodoChangeDeleted,
+	TodoRestored: domain.TodoChangeRestored,
+	TodoPurged:   domain.TodoChangePurged,
+}
+
+// TodoHistory returns every change of the todo in the workspace of the
+// context, oldest first, deleted todos included.
+func (r *EventSourcedTodoRepository) TodoHistory(ctx context.Context, id int) ([]domain.TodoChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer r.rlock(ctx)()
+
+	tenant := domain.CurrentTenant(ctx)
+
+	var changes []domain.TodoChange
+	for _, event := range r.events {
+		changeType, ok := changeTypes[event.Type]
+		if !ok || tenantOf(event) != tenant {
+			continue
+		}
+		for _, todo := range eventTodos(event) {
+			if todo.ID != id {
+				continue
+			}
+			changes = append(changes, domain.TodoChange{
+				Type:    changeType,
+				Todo:    todo,
+				ActorID: event.ActorID,
+				At:      event.At,
+			})
+		}
+	}
+	if len(changes) == 0 {
+		return nil, domain.ErrTodoNotExist
+	}
+
+	return changes, nil
+}
+
+// eventTodos returns the todos the event changed.
+func eventTodos(event Event) []domain.Todo {
+	switch {
+	case event.Trashed != nil:
+		return event.Trashed.Todos()
+	case event.Todo != nil:
+		return []domain.Todo{*event.Todo}
+	}
+	return nil
+}
+
+// QueryAsOf runs the query against the todos of the workspace of the
+// context as they were at q.AsOf, or as they are now when it is zero.
+func (r *EventSourcedTodoRepository) QueryAsOf(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+	if q.AsOf.IsZero() {
+		return r.Query(ctx, q)
+	}
+	if err := ctx.Err(); err != nil {
+		return []domain.Todo{}, err
+	}
+
+	defer r.rlock(ctx)()
+
+	tenant := domain.CurrentTenant(ctx)
+
+	p := newProjection()
+	for _, event := range r.events {
+		if event.At.After(q.AsOf) {
+			// the events are stamped in order, later ones are later still
+			break
+		}
+		if tenantOf(event) == tenant {
+			p.apply(event)
+		}
+	}
+
+	return q.Apply(maps.Values(p.DB)), nil
+}
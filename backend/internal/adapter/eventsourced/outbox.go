@@ -0,0 +1,114 @@
+package eventsourced
+
+import (
+	"context"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// outbox is the state of the outbox records of the stream.
+type outbox struct {
+	// pending holds the records of the events not acked yet
+	pending     []Event
+	nextEventID uint64
+	// lastAck is the last ack, with the highest id acked only. It outlives
+	// compactions, so the ids acked are never handed out again.
+	lastAck *Event
+	// dropped counts the records of the stream a compaction would drop
+	dropped int
+}
+
+func (o *outbox) apply(event Event) {
+	switch event.Type {
+	case OutboxAdded:
+		if event.Outbox != nil {
+			o.pending = append(o.pending, event)
+			o.nextEventID = max(o.nextEventID, event.Outbox.ID+1)
+		}
+	case OutboxAcked:
+		n := len(o.pending)
+		o.pending = withoutEvents(o.pending, event.Acked)
+		o.dropped += n - len(o.pending)
+
+		if len(event.Acked) == 0 {
+			o.dropped++
+			return
+		}
+		if o.lastAck != nil {
+			o.dropped++
+		}
+		id := slices.Max(event.Acked)
+		o.nextEventID = max(o.nextEventID, id+1)
+		event.Acked = []uint64{id}
+		o.lastAck = &event
+	}
+}
+
+// records returns the outbox records a compacted stream keeps.
+func (o *outbox) records() []Event {
+	records := slices.Clone(o.pending)
+	if o.lastAck != nil {
+		records = append(records, *o.lastAck)
+	}
+	return records
+}
+
+// AddEvents keeps the outbox in the stream too, so the events are stored
+// together with the change that caused them. Acked events are dropped when
+// the stream is compacted.
+func (r *EventSourcedTodoRepository) AddEvents(ctx context.Context, events ...domain.TodoEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock(ctx)()
+
+	added := make([]Event, 0, len(events))
+	for i, event := range events {
+		event.ID = r.outbox.nextEventID + uint64(i)
+		added = append(added, Event{Type: OutboxAdded, Outbox: &event})
+	}
+	return r.record(ctx, added...)
+}
+
+func (r *EventSourcedTodoRepository) PendingEvents(ctx context.Context, limit int) ([]domain.TodoEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer r.rlock(ctx)()
+
+	n := min(limit, len(r.outbox.pending))
+	events := make([]domain.TodoEvent, 0, n)
+	for _, record := range r.outbox.pending[:n] {
+		events = append(events, *record.Outbox)
+	}
+	return events, nil
+}
+
+func (r *EventSourcedTodoRepository) AckEvents(ctx context.Context, ids ...uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock(ctx)()
+
+	return r.record(ctx, Event{Type: OutboxAcked, Acked: ids})
+}
+
+// withoutEvents returns the records without those of the ids.
+func withoutEvents(records []Event, ids []uint64) []Event {
+	acked := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		acked[id] = true
+	}
+
+	kept := make([]Event, 0, len(records))
+	for _, record := range records {
+		if !acked[record.Outbox.ID] {
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,192 @@
+package eventsourced
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStream keeps the events in a file, one line per append, and waits
+// for every line to reach the disk. A line torn by a crash is dropped on
+// load, with all the events of its append.
+type FileStream struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileStream opens the stream file, creating it when it is missing.
+func OpenFileStream(path string) (*FileStream, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open event stream: %w", err)
+	}
+	return &FileStream{path: path, file: file}, nil
+}
+
+func (s *FileStream) Load(ctx context.Context) ([]Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(s.file)
+	var (
+		events []Event
+		offset int64
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// the process died in the middle of a write, drop the torn tail
+				slog.Warn("truncating incomplete event stream line", "offset", offset)
+				if err := s.file.Truncate(offset); err != nil {
+					return nil, fmt.Errorf("truncate torn line: %w", err)
+				}
+			}
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		var batch []Event
+		if err := json.Unmarshal(line, &batch); err != nil {
+			return nil, fmt.Errorf("decode events at offset %d: %w", offset, err)
+		}
+		offset += int64(len(line))
+
+		events = append(events, batch...)
+	}
+
+	if _, err := s.file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *FileStream) Append(ctx context.Context, events ...Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("encode events: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return errors.New("event stream is closed")
+	}
+	if _, err := s.file.Write(line); err != nil {
+		return fmt.Errorf("write events: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("sync event stream: %w", err)
+	}
+	return nil
+}
+
+// Rewrite writes the events to a new file, one line each, and moves it in
+// place of the stream, so a crash leaves either the old events or the new
+// ones.
+func (s *FileStream) Rewrite(ctx context.Context, events []Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, event := range events {
+		line, err := json.Marshal([]Event{event})
+		if err != nil {
+			return fmt.Errorf("encode events: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return errors.New("event stream is closed")
+	}
+
+	if err := writeFileAtomic(s.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("rewrite event stream: %w", err)
+	}
+
+	// the old file is gone, the appends go on in the new one
+	file, err := os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("reopen event stream: %w", err)
+	}
+	s.file.Close()
+	s.file = file
+
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
+
+// Close releases the file.
+func (s *FileStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,143 @@
+package eventsourced
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (r *EventSourcedTodoRepository) TrashByID(ctx context.Context, id int, version int, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock(ctx)()
+
+	p := r.read(ctx)
+
+	stored, ok := p.DB[id]
+	if !ok {
+		return domain.ErrTodoNotExist
+	}
+	if version != 0 && version != stored.Version {
+		return domain.ErrVersionMismatch
+	}
+
+	entry := domain.TrashedTodo{Todo: stored, Subtasks: []domain.Todo{}, DeletedAt: at}
+	for _, v := range p.subtree(id)[1:] {
+		entry.Subtasks = append(entry.Subtasks, p.DB[v])
+	}
+
+	return r.record(ctx, Event{Type: TodoTrashed, ID: id, Trashed: &entry})
+}
+
+func (r *EventSourcedTodoRepository) ReadTrash(ctx context.Context) ([]domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	unlock := r.rlock(ctx)
+	res := slices.Collect(maps.Values(r.read(ctx).Trash))
+	unlock()
+
+	if res == nil {
+		res = []domain.TrashedTodo{}
+	}
+	slices.SortFunc(res, func(a, b domain.TrashedTodo) int {
+		if c := b.DeletedAt.Compare(a.DeletedAt); c != 0 {
+			return c
+		}
+		return b.ID - a.ID
+	})
+
+	return res, nil
+}
+
+func (r *EventSourcedTodoRepository) GetTrashedByID(ctx context.Context, id int) (domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+
+	defer r.rlock(ctx)()
+
+	entry, ok := r.read(ctx).Trash[id]
+	if !ok {
+		return domain.TrashedTodo{}, domain.ErrTodoNotExist
+	}
+
+	return entry, nil
+}
+
+func (r *EventSourcedTodoRepository) RestoreByID(ctx context.Context, id int) (domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+
+	defer r.lock(ctx)()
+
+	p := r.read(ctx)
+
+	entry, ok := p.Trash[id]
+	if !ok {
+		return domain.TrashedTodo{}, domain.ErrTodoNotExist
+	}
+
+	parentExists := false
+	if entry.ParentID != nil {
+		_, parentExists = p.DB[*entry.ParentID]
+	}
+	todos := entry.Restored(func(id int) bool {
+		_, ok := p.Tags[id]
+		return ok
+	}, parentExists)
+	entry.Todo, entry.Subtasks = todos[0], todos[1:]
+
+	if err := r.record(ctx, Event{Type: TodoRestored, ID: id, Trashed: &entry}); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+	return entry, nil
+}
+
+func (r *EventSourcedTodoRepository) PurgeByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock(ctx)()
+
+	entry, ok := r.read(ctx).Trash[id]
+	if !ok {
+		return domain.ErrTodoNotExist
+	}
+
+	return r.record(ctx, Event{Type: TodoPurged, ID: id, Trashed: &entry})
+}
+
+func (r *EventSourcedTodoRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer r.lock(ctx)()
+
+	n := 0
+	for _, tenant := range slices.Sorted(maps.Keys(r.tenants)) {
+		p := r.tenants[tenant]
+
+		var events []Event
+		for _, id := range slices.Sorted(maps.Keys(p.Trash)) {
+			if entry := p.Trash[id]; entry.DeletedAt.Before(before) {
+				events = append(events, Event{Type: TodoPurged, ID: id, Trashed: &entry})
+			}
+		}
+		if err := r.record(domain.ContextWithTenant(ctx, tenant), events...); err != nil {
+			return n, err
+		}
+		n += len(events)
+	}
+
+	return n, nil
+}
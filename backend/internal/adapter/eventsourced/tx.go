@@ -0,0 +1,87 @@
+package eventsourced
+
+import (
+	"context"
+	"fmt"
+)
+
+type txKey struct{}
+
+// eventTx holds r.mu for writing until it ends; the calls made with its
+// context run under that lock instead of taking it again. Their events
+// are applied right away and appended to the stream at once on commit.
+type eventTx struct {
+	repo   *EventSourcedTodoRepository
+	done   bool
+	events []Event
+}
+
+// Atomically runs fn holding the lock of the repository and appends all
+// its events in one go, so the stream gets either all of them or none.
+// When fn fails, or the events cannot be appended, the state is projected
+// again from the stream.
+func (r *EventSourcedTodoRepository) Atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r.tx(ctx) != nil {
+		return fn(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &eventTx{repo: r}
+	// the outbox is not in the history, so it is put back as it was
+	outbox := r.outbox
+	committed := false
+	defer func() {
+		tx.done = true
+		if !committed && len(tx.events) > 0 {
+			r.outbox = outbox
+			r.project()
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if len(tx.events) > 0 {
+		if err := r.stream.Append(ctx, tx.events...); err != nil {
+			return fmt.Errorf("append events: %w", err)
+		}
+		r.keep(tx.events)
+	}
+	committed = true
+	r.maybeCompact(ctx)
+
+	return nil
+}
+
+// tx returns the transaction of the repository running in ctx, if any.
+func (r *EventSourcedTodoRepository) tx(ctx context.Context) *eventTx {
+	tx, ok := ctx.Value(txKey{}).(*eventTx)
+	if !ok || tx.repo != r || tx.done {
+		return nil
+	}
+	return tx
+}
+
+// lock takes r.mu for writing, unless a transaction of ctx holds it
+// already, and returns the matching unlock.
+func (r *EventSourcedTodoRepository) lock(ctx context.Context) func() {
+	if r.tx(ctx) != nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+// rlock is lock for reading.
+func (r *EventSourcedTodoRepository) rlock(ctx context.Context) func() {
+	if r.tx(ctx) != nil {
+		return func() {}
+	}
+	r.mu.RLock()
+	return r.mu.RUnlock
+}
@@ -0,0 +1,143 @@
+package file
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func toStoredAPIKey(key domain.APIKey) storedAPIKey {
+	return storedAPIKey{
+		ID:         key.ID,
+		UserID:     key.UserID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Hash:       key.Hash,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
+
+func (k storedAPIKey) apiKey() domain.APIKey {
+	return domain.APIKey{
+		ID:         k.ID,
+		UserID:     k.UserID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Hash:       k.Hash,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+	}
+}
+
+func (f *FileTodoRepository) SaveAPIKey(ctx context.Context, key domain.APIKey) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	id := d.NextAPIKeyID
+	key.ID = id
+
+	stored := toStoredAPIKey(key)
+	if err := f.append(ctx, record{Op: opSaveAPIKey, ID: id, APIKey: &stored}); err != nil {
+		return 0, err
+	}
+
+	d.APIKeys[id] = key
+	d.NextAPIKeyID++
+
+	return id, f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) GetAPIKeyByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.APIKey{}, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	for _, key := range d.APIKeys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+
+	return domain.APIKey{}, domain.ErrAPIKeyNotExist
+}
+
+func (f *FileTodoRepository) TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	key, ok := d.APIKeys[id]
+	if !ok {
+		return domain.ErrAPIKeyNotExist
+	}
+	key.LastUsedAt = &usedAt
+
+	stored := toStoredAPIKey(key)
+	if err := f.append(ctx, record{Op: opTouchAPIKey, ID: id, APIKey: &stored}); err != nil {
+		return err
+	}
+
+	d.APIKeys[id] = key
+
+	return f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) ListAPIKeys(ctx context.Context, userID int) ([]domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	keys := make([]domain.APIKey, 0)
+	for _, id := range slices.Sorted(maps.Keys(d.APIKeys)) {
+		if key := d.APIKeys[id]; key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (f *FileTodoRepository) DeleteAPIKey(ctx context.Context, userID, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	if key, ok := d.APIKeys[id]; !ok || key.UserID != userID {
+		return domain.ErrAPIKeyNotExist
+	}
+
+	if err := f.append(ctx, record{Op: opDeleteAPIKey, ID: id}); err != nil {
+		return err
+	}
+
+	delete(d.APIKeys, id)
+
+	return f.maybeCompact(ctx)
+}
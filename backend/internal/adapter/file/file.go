@@ -0,0 +1,1051 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const (
+	logFileName      = "todos.wal"
+	snapshotFileName = "todos.snapshot"
+
+	defaultCompactEvery = 1000
+)
+
+const (
+	opSave   = "save"
+	opUpdate = "update"
+	opDelete = "delete"
+
+	opTrash   = "trash"
+	opRestore = "restore"
+	opPurge   = "purge"
+
+	opSaveTag   = "save_tag"
+	opUpdateTag = "update_tag"
+	opDeleteTag = "delete_tag"
+
+	opSaveUser      = "save_user"
+	opSaveSession   = "save_session"
+	opDeleteSession = "delete_session"
+
+	opSaveAPIKey   = "save_api_key"
+	opTouchAPIKey  = "touch_api_key"
+	opDeleteAPIKey = "delete_api_key"
+
+	opSaveList   = "save_list"
+	opUpdateList = "update_list"
+	opDeleteList = "delete_list"
+
+	opSaveWebhook   = "save_webhook"
+	opDeleteWebhook = "delete_webhook"
+
+	opAddEvent  = "add_event"
+	opAckEvents = "ack_events"
+	opTx        = "tx"
+)
+
+// record is a single line of the write-ahead log. It carries the full state
+// of the todo or the tag after the mutation, so replaying is idempotent.
+// Tag, user, API key, list and webhook ops keep the tag, user, key, list
+// or webhook id in ID, session ops the session id in SessionID. Records of the default
+// workspace leave Tenant empty. Trash and restore records carry the whole
+// trash entry in Trashed. A transaction is written as a single tx
+// record holding the records of its writes, so it is replayed whole or not
+// at all.
+type record struct {
+	Seq       uint64              `json:"seq"`
+	Op        string              `json:"op"`
+	Tenant    domain.Tenant       `json:"tenant,omitempty"`
+	ID        int                 `json:"id"`
+	Todo      *domain.Todo        `json:"todo,omitempty"`
+	Trashed   *domain.TrashedTodo `json:"trashed,omitempty"`
+	Tag       *domain.Tag         `json:"tag,omitempty"`
+	User      *storedUser         `json:"user,omitempty"`
+	SessionID string              `json:"session_id,omitempty"`
+	Session   *domain.Session     `json:"session,omitempty"`
+	APIKey    *storedAPIKey       `json:"api_key,omitempty"`
+	List      *domain.List        `json:"list,omitempty"`
+	Webhook   *storedWebhook      `json:"webhook,omitempty"`
+	Event     *domain.TodoEvent   `json:"event,omitempty"`
+	EventIDs  []uint64            `json:"event_ids,omitempty"`
+	Records   []record            `json:"records,omitempty"`
+}
+
+// snapshot keeps the default workspace at the top level, as before
+// workspaces existed, and every other one in Tenants.
+type snapshot struct {
+	Seq uint64 `json:"seq"`
+	tenantSnapshot
+	Tenants []tenantSnapshot `json:"tenants,omitempty"`
+
+	NextEventID uint64             `json:"next_event_id,omitempty"`
+	Outbox      []domain.TodoEvent `json:"outbox,omitempty"`
+}
+
+type tenantSnapshot struct {
+	Tenant     domain.Tenant    `json:"tenant,omitempty"`
+	NextID     int              `json:"next_id"`
+	Todos      []domain.Todo    `json:"todos"`
+	NextTagID  int              `json:"next_tag_id"`
+	Tags       []domain.Tag     `json:"tags"`
+	NextUserID int              `json:"next_user_id"`
+	Users      []storedUser     `json:"users"`
+	Sessions   []domain.Session `json:"sessions"`
+
+	NextAPIKeyID int            `json:"next_api_key_id"`
+	APIKeys      []storedAPIKey `json:"api_keys"`
+
+	NextListID int           `json:"next_list_id"`
+	Lists      []domain.List `json:"lists"`
+
+	NextWebhookID int             `json:"next_webhook_id"`
+	Webhooks      []storedWebhook `json:"webhooks"`
+
+	Trash []domain.TrashedTodo `json:"trash,omitempty"`
+}
+
+// storedUser keeps the password hash, which domain.User leaves out of JSON.
+type storedUser struct {
+	ID           int       `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// storedAPIKey keeps the key hash, which domain.APIKey leaves out of JSON.
+type storedAPIKey struct {
+	ID         int            `json:"id"`
+	UserID     int            `json:"user_id"`
+	Name       string         `json:"name"`
+	Prefix     string         `json:"prefix"`
+	Hash       string         `json:"hash"`
+	Scopes     []domain.Scope `json:"scopes"`
+	CreatedAt  time.Time      `json:"created_at"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`
+}
+
+// storedWebhook keeps the secret, which domain.Webhook leaves out of JSON.
+type storedWebhook struct {
+	ID        int                    `json:"id"`
+	UserID    int                    `json:"user_id"`
+	URL       string                 `json:"url"`
+	Events    []domain.TodoEventType `json:"events"`
+	Secret    string                 `json:"secret"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type Options struct {
+	// CompactEvery is the number of log records after which the log is
+	// folded into a fresh snapshot. Zero means the default.
+	CompactEvery int
+}
+
+// FileTodoRepository keeps every workspace apart, each with its own id
+// sequences. The embedded data is the default workspace.
+type FileTodoRepository struct {
+	*tenantData
+	tenants map[domain.Tenant]*tenantData
+
+	dir          string
+	log          *os.File
+	seq          uint64
+	logRecords   int
+	compactEvery int
+	mu           sync.RWMutex
+
+	// outbox is shared by every workspace, each event carries its own.
+	outbox      []domain.TodoEvent
+	nextEventID uint64
+}
+
+// tenantData is everything stored in one workspace.
+type tenantData struct {
+	DB         map[int]domain.Todo
+	NextID     int
+	Tags       map[int]domain.Tag
+	NextTagID  int
+	Users      map[int]domain.User
+	NextUserID int
+	Sessions   map[string]domain.Session
+
+	APIKeys      map[int]domain.APIKey
+	NextAPIKeyID int
+
+	Lists      map[int]domain.List
+	NextListID int
+
+	Webhooks      map[int]domain.Webhook
+	NextWebhookID int
+
+	Trash map[int]domain.TrashedTodo
+}
+
+func newTenantData() *tenantData {
+	return &tenantData{
+		DB:            map[int]domain.Todo{},
+		NextID:        1,
+		Tags:          map[int]domain.Tag{},
+		NextTagID:     1,
+		Users:         map[int]domain.User{},
+		NextUserID:    1,
+		Sessions:      map[string]domain.Session{},
+		APIKeys:       map[int]domain.APIKey{},
+		NextAPIKeyID:  1,
+		Lists:         map[int]domain.List{},
+		NextListID:    1,
+		Webhooks:      map[int]domain.Webhook{},
+		NextWebhookID: 1,
+		Trash:         map[int]domain.TrashedTodo{},
+	}
+}
+
+func New(dir string, opts Options) (*FileTodoRepository, error) {
+	if opts.CompactEvery <= 0 {
+		opts.CompactEvery = defaultCompactEvery
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+
+	data := newTenantData()
+	f := &FileTodoRepository{
+		tenantData:   data,
+		tenants:      map[domain.Tenant]*tenantData{domain.DefaultTenant: data},
+		dir:          dir,
+		compactEvery: opts.CompactEvery,
+		nextEventID:  1,
+	}
+
+	if err := f.loadSnapshot(); err != nil {
+		return nil, fmt.Errorf("load snapshot: %w", err)
+	}
+
+	if err := f.replayLog(); err != nil {
+		return nil, fmt.Errorf("replay log: %w", err)
+	}
+
+	log, err := os.OpenFile(f.path(logFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open log: %w", err)
+	}
+	f.log = log
+
+	return f, nil
+}
+
+// read returns the workspace of the context, empty when nothing was stored
+// in it yet. Must be called with f.mu held.
+func (f *FileTodoRepository) read(ctx context.Context) *tenantData {
+	if data, ok := f.tenants[domain.CurrentTenant(ctx)]; ok {
+		return data
+	}
+	return newTenantData()
+}
+
+// write returns the workspace of the context, creating it on first use.
+// Within a transaction the workspace is kept as it was, to be restored on
+// rollback. Must be called with f.mu held for writing.
+func (f *FileTodoRepository) write(ctx context.Context) *tenantData {
+	tenant := domain.CurrentTenant(ctx)
+	_, ok := f.tenants[tenant]
+	data := f.data(tenant)
+	if tx := f.tx(ctx); tx != nil {
+		tx.keep(tenant, data, ok)
+	}
+	return data
+}
+
+// data must be called with f.mu held for writing.
+func (f *FileTodoRepository) data(tenant domain.Tenant) *tenantData {
+	data, ok := f.tenants[tenant]
+	if !ok {
+		data = newTenantData()
+		f.tenants[tenant] = data
+	}
+	return data
+}
+
+func (f *FileTodoRepository) Save(ctx context.Context, todo domain.Todo) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	tags, err := d.todoTags(todo.Tags)
+	if err != nil {
+		return 0, err
+	}
+	if err := d.checkParent(0, todo.ParentID); err != nil {
+		return 0, err
+	}
+
+	id := d.NextID
+	todo.ID = id
+	todo.Version = 1
+	todo.Tags = tags
+
+	if err := f.append(ctx, record{Op: opSave, ID: id, Todo: &todo}); err != nil {
+		return 0, err
+	}
+
+	d.DB[id] = todo
+	d.NextID++
+
+	return id, f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) GetByID(ctx context.Context, id int) (domain.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Todo{}, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	v, ok := d.DB[id]
+	if !ok {
+		return domain.Todo{}, domain.ErrTodoNotExist
+	}
+
+	return v, nil
+}
+
+func (f *FileTodoRepository) UpdateByID(ctx context.Context, id int, todo domain.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	stored, ok := d.DB[id]
+	if !ok {
+		return domain.ErrTodoNotExist
+	}
+	if todo.Version != 0 && todo.Version != stored.Version {
+		return domain.ErrVersionMismatch
+	}
+	tags, err := d.todoTags(todo.Tags)
+	if err != nil {
+		return err
+	}
+	if err := d.checkParent(id, todo.ParentID); err != nil {
+		return err
+	}
+	todo.ID = id
+	todo.Version = stored.Version + 1
+	todo.Tags = tags
+
+	if err := f.append(ctx, record{Op: opUpdate, ID: id, Todo: &todo}); err != nil {
+		return err
+	}
+
+	d.DB[id] = todo
+
+	return f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) DeleteByID(ctx context.Context, id int, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	stored, ok := d.DB[id]
+	if !ok {
+		return domain.ErrTodoNotExist
+	}
+	if version != 0 && version != stored.Version {
+		return domain.ErrVersionMismatch
+	}
+
+	// replaying the record removes the subtasks again
+	if err := f.append(ctx, record{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+
+	d.deleteTodo(id)
+
+	return f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) ReadAll(ctx context.Context) ([]domain.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Todo{}, err
+	}
+
+	unlock := f.rlock(ctx)
+	d := f.read(ctx)
+	res := make([]domain.Todo, 0, len(d.DB))
+	for _, v := range d.DB {
+		res = append(res, v)
+	}
+	unlock()
+
+	slices.SortFunc(res, func(a domain.Todo, b domain.Todo) int {
+		return a.ID - b.ID
+	})
+
+	return res, nil
+}
+
+func (f *FileTodoRepository) Query(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Todo{}, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	return q.Apply(maps.Values(d.DB)), nil
+}
+
+// checkParent makes sure the parent exists and is not the todo itself or
+// one of its subtasks. Must be called with f.mu held.
+func (d *tenantData) checkParent(id int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+	if _, ok := d.DB[*parentID]; !ok {
+		return domain.ErrParentNotExist
+	}
+
+	for p := parentID; p != nil; p = d.DB[*p].ParentID {
+		if *p == id {
+			return domain.ErrParentCycle
+		}
+	}
+	return nil
+}
+
+// deleteTodo removes the todo with all its subtasks at any depth. Must be
+// called with f.mu held.
+func (d *tenantData) deleteTodo(id int) {
+	for _, v := range d.subtree(id) {
+		delete(d.DB, v)
+	}
+}
+
+// subtree returns the todo followed by all its subtasks at any depth, each
+// after its parent.
+func (d *tenantData) subtree(id int) []int {
+	children := map[int][]int{}
+	for _, v := range d.DB {
+		if v.ParentID != nil {
+			children[*v.ParentID] = append(children[*v.ParentID], v.ID)
+		}
+	}
+	for _, ids := range children {
+		slices.Sort(ids)
+	}
+
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// todoTags normalizes the tag ids of a todo. Must be called with f.mu held.
+func (d *tenantData) todoTags(ids []int) ([]int, error) {
+	ids = domain.NormalizeTagIDs(ids)
+	for _, id := range ids {
+		if _, ok := d.Tags[id]; !ok {
+			return nil, domain.ErrTagNotExist
+		}
+	}
+	return ids, nil
+}
+
+func (f *FileTodoRepository) SaveTag(ctx context.Context, tag domain.Tag) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	if d.tagNameTaken(tag.Name, 0) {
+		return 0, domain.ErrTagExists
+	}
+
+	id := d.NextTagID
+	tag.ID = id
+
+	if err := f.append(ctx, record{Op: opSaveTag, ID: id, Tag: &tag}); err != nil {
+		return 0, err
+	}
+
+	d.Tags[id] = tag
+	d.NextTagID++
+
+	return id, f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) GetTagByID(ctx context.Context, id int) (domain.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Tag{}, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	v, ok := d.Tags[id]
+	if !ok {
+		return domain.Tag{}, domain.ErrTagNotExist
+	}
+
+	return v, nil
+}
+
+func (f *FileTodoRepository) UpdateTagByID(ctx context.Context, id int, tag domain.Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	if _, ok := d.Tags[id]; !ok {
+		return domain.ErrTagNotExist
+	}
+	if d.tagNameTaken(tag.Name, id) {
+		return domain.ErrTagExists
+	}
+	tag.ID = id
+
+	if err := f.append(ctx, record{Op: opUpdateTag, ID: id, Tag: &tag}); err != nil {
+		return err
+	}
+
+	d.Tags[id] = tag
+
+	return f.maybeCompact(ctx)
+}
+
+// DeleteTagByID logs the deletion only; replaying it detaches the tag from
+// the todos again.
+func (f *FileTodoRepository) DeleteTagByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	if _, ok := d.Tags[id]; !ok {
+		return domain.ErrTagNotExist
+	}
+
+	if err := f.append(ctx, record{Op: opDeleteTag, ID: id}); err != nil {
+		return err
+	}
+
+	d.deleteTag(id)
+
+	return f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) ReadAllTags(ctx context.Context) ([]domain.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Tag{}, err
+	}
+
+	unlock := f.rlock(ctx)
+	d := f.read(ctx)
+	res := make([]domain.Tag, 0, len(d.Tags))
+	for _, v := range d.Tags {
+		res = append(res, v)
+	}
+	unlock()
+
+	slices.SortFunc(res, func(a domain.Tag, b domain.Tag) int {
+		return a.ID - b.ID
+	})
+
+	return res, nil
+}
+
+// tagNameTaken reports whether a tag other than self already has the name.
+// Must be called with f.mu held.
+func (d *tenantData) tagNameTaken(name string, self int) bool {
+	for _, t := range d.Tags {
+		if t.ID != self && domain.SameTagName(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteTag must be called with f.mu held.
+func (d *tenantData) deleteTag(id int) {
+	delete(d.Tags, id)
+
+	for todoID, todo := range d.DB {
+		if !slices.Contains(todo.Tags, id) {
+			continue
+		}
+		// stored slices may be shared with callers, so never edit them in place
+		todo.Tags = slices.DeleteFunc(slices.Clone(todo.Tags), func(tagID int) bool {
+			return tagID == id
+		})
+		todo.Version++
+		d.DB[todoID] = todo
+	}
+}
+
+// Compact writes the current state into a new snapshot and truncates the log.
+func (f *FileTodoRepository) Compact() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.compact()
+}
+
+// Close compacts the log and releases the underlying file.
+func (f *FileTodoRepository) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.log == nil {
+		return nil
+	}
+
+	err := f.compact()
+	if cerr := f.log.Close(); err == nil {
+		err = cerr
+	}
+	f.log = nil
+
+	return err
+}
+
+func (f *FileTodoRepository) path(name string) string {
+	return filepath.Join(f.dir, name)
+}
+
+// append writes the record to the log and waits until it reaches the disk.
+// The record is tagged with the workspace of the context. Within a
+// transaction it is only written on commit. Must be called with f.mu held.
+func (f *FileTodoRepository) append(ctx context.Context, rec record) error {
+	if f.log == nil {
+		return errors.New("repository is closed")
+	}
+
+	if tenant := domain.CurrentTenant(ctx); tenant != domain.DefaultTenant {
+		rec.Tenant = tenant
+	}
+
+	if tx := f.tx(ctx); tx != nil {
+		tx.records = append(tx.records, rec)
+		return nil
+	}
+	return f.writeRecord(rec)
+}
+
+// writeRecord must be called with f.mu held.
+func (f *FileTodoRepository) writeRecord(rec record) error {
+	rec.Seq = f.seq + 1
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode log record: %w", err)
+	}
+	line = append(line, '\n')
+
+	if _, err := f.log.Write(line); err != nil {
+		return fmt.Errorf("write log record: %w", err)
+	}
+	if err := f.log.Sync(); err != nil {
+		return fmt.Errorf("sync log: %w", err)
+	}
+
+	f.seq = rec.Seq
+	f.logRecords++
+
+	return nil
+}
+
+// maybeCompact waits for the end of a transaction in ctx, as the snapshot
+// must not hold writes that may still be rolled back.
+func (f *FileTodoRepository) maybeCompact(ctx context.Context) error {
+	if f.logRecords < f.compactEvery || f.tx(ctx) != nil {
+		return nil
+	}
+
+	// the mutation is already durable, so a failed compaction is not fatal
+	if err := f.compact(); err != nil {
+		slog.Error("failed to compact todo log", "error", err)
+	}
+
+	return nil
+}
+
+// compact must be called with f.mu held. Expired sessions are left out
+// of the snapshot.
+func (f *FileTodoRepository) compact() error {
+	now := time.Now()
+	snap := snapshot{Seq: f.seq, NextEventID: f.nextEventID, Outbox: f.outbox}
+	for _, tenant := range slices.Sorted(maps.Keys(f.tenants)) {
+		ts := f.tenants[tenant].snapshot(now)
+		if tenant == domain.DefaultTenant {
+			snap.tenantSnapshot = ts
+			continue
+		}
+		ts.Tenant = tenant
+		snap.Tenants = append(snap.Tenants, ts)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	if err := writeFileAtomic(f.path(snapshotFileName), data); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	// records up to snap.Seq are skipped on replay, so a crash before the
+	// truncation below leaves a consistent state
+	if err := f.log.Truncate(0); err != nil {
+		return fmt.Errorf("truncate log: %w", err)
+	}
+	if err := f.log.Sync(); err != nil {
+		return fmt.Errorf("sync log: %w", err)
+	}
+	f.logRecords = 0
+
+	return nil
+}
+
+func (f *FileTodoRepository) loadSnapshot() error {
+	data, err := os.ReadFile(f.path(snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("decode snapshot: %w", err)
+	}
+
+	f.tenantData.load(snap.tenantSnapshot)
+	for _, ts := range snap.Tenants {
+		f.data(ts.Tenant).load(ts)
+	}
+	f.seq = snap.Seq
+	f.nextEventID = max(snap.NextEventID, 1)
+	f.outbox = snap.Outbox
+
+	return nil
+}
+
+func (d *tenantData) snapshot(now time.Time) tenantSnapshot {
+	snap := tenantSnapshot{
+		NextID:     d.NextID,
+		Todos:      make([]domain.Todo, 0, len(d.DB)),
+		NextTagID:  d.NextTagID,
+		Tags:       make([]domain.Tag, 0, len(d.Tags)),
+		NextUserID: d.NextUserID,
+		Users:      make([]storedUser, 0, len(d.Users)),
+		Sessions:   make([]domain.Session, 0, len(d.Sessions)),
+
+		NextAPIKeyID: d.NextAPIKeyID,
+		APIKeys:      make([]storedAPIKey, 0, len(d.APIKeys)),
+
+		NextListID: d.NextListID,
+		Lists:      make([]domain.List, 0, len(d.Lists)),
+
+		NextWebhookID: d.NextWebhookID,
+		Webhooks:      make([]storedWebhook, 0, len(d.Webhooks)),
+	}
+	for _, v := range d.DB {
+		snap.Todos = append(snap.Todos, v)
+	}
+	slices.SortFunc(snap.Todos, func(a domain.Todo, b domain.Todo) int {
+		return a.ID - b.ID
+	})
+	for _, v := range d.Tags {
+		snap.Tags = append(snap.Tags, v)
+	}
+	slices.SortFunc(snap.Tags, func(a domain.Tag, b domain.Tag) int {
+		return a.ID - b.ID
+	})
+	for _, v := range d.Users {
+		snap.Users = append(snap.Users, toStoredUser(v))
+	}
+	slices.SortFunc(snap.Users, func(a storedUser, b storedUser) int {
+		return a.ID - b.ID
+	})
+	for _, v := range d.Sessions {
+		if now.Before(v.ExpiresAt) {
+			snap.Sessions = append(snap.Sessions, v)
+		}
+	}
+	slices.SortFunc(snap.Sessions, func(a domain.Session, b domain.Session) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	for _, v := range d.APIKeys {
+		snap.APIKeys = append(snap.APIKeys, toStoredAPIKey(v))
+	}
+	slices.SortFunc(snap.APIKeys, func(a storedAPIKey, b storedAPIKey) int {
+		return a.ID - b.ID
+	})
+	for _, v := range d.Lists {
+		snap.Lists = append(snap.Lists, v)
+	}
+	slices.SortFunc(snap.Lists, func(a domain.List, b domain.List) int {
+		return a.ID - b.ID
+	})
+	for _, v := range d.Webhooks {
+		snap.Webhooks = append(snap.Webhooks, toStoredWebhook(v))
+	}
+	slices.SortFunc(snap.Webhooks, func(a storedWebhook, b storedWebhook) int {
+		return a.ID - b.ID
+	})
+	for _, v := range d.Trash {
+		snap.Trash = append(snap.Trash, v)
+	}
+	slices.SortFunc(snap.Trash, func(a domain.TrashedTodo, b domain.TrashedTodo) int {
+		return a.ID - b.ID
+	})
+
+	return snap
+}
+
+func (d *tenantData) load(snap tenantSnapshot) {
+	for _, v := range snap.Todos {
+		d.DB[v.ID] = v
+	}
+	d.NextID = max(snap.NextID, 1)
+	for _, v := range snap.Tags {
+		d.Tags[v.ID] = v
+	}
+	d.NextTagID = max(snap.NextTagID, 1)
+	for _, v := range snap.Users {
+		d.Users[v.ID] = v.user()
+	}
+	d.NextUserID = max(snap.NextUserID, 1)
+	for _, v := range snap.Sessions {
+		d.Sessions[v.ID] = v
+	}
+	for _, v := range snap.APIKeys {
+		d.APIKeys[v.ID] = v.apiKey()
+	}
+	d.NextAPIKeyID = max(snap.NextAPIKeyID, 1)
+	for _, v := range snap.Lists {
+		d.Lists[v.ID] = v
+	}
+	d.NextListID = max(snap.NextListID, 1)
+	for _, v := range snap.Webhooks {
+		d.Webhooks[v.ID] = v.webhook()
+	}
+	d.NextWebhookID = max(snap.NextWebhookID, 1)
+	for _, v := range snap.Trash {
+		d.Trash[v.ID] = v
+	}
+}
+
+func (f *FileTodoRepository) replayLog() error {
+	file, err := os.OpenFile(f.path(logFileName), os.O_RDWR, 0o644)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// the process died in the middle of a write, drop the torn tail
+				slog.Warn("truncating incomplete todo log record", "offset", offset)
+				if err := file.Truncate(offset); err != nil {
+					return fmt.Errorf("truncate torn record: %w", err)
+				}
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("decode log record at offset %d: %w", offset, err)
+		}
+		offset += int64(len(line))
+
+		f.logRecords++
+		if rec.Seq <= f.seq {
+			continue
+		}
+		f.apply(rec)
+	}
+}
+
+func (f *FileTodoRepository) apply(rec record) {
+	switch rec.Op {
+	case opTx:
+		for _, r := range rec.Records {
+			r.Seq = rec.Seq
+			f.apply(r)
+		}
+		return
+	case opAddEvent:
+		if rec.Event != nil {
+			f.outbox = append(f.outbox, *rec.Event)
+			f.nextEventID = max(f.nextEventID, rec.Event.ID+1)
+		}
+		f.seq = rec.Seq
+		return
+	case opAckEvents:
+		f.outbox = withoutEvents(f.outbox, rec.EventIDs)
+		f.seq = rec.Seq
+		return
+	}
+
+	tenant := rec.Tenant
+	if tenant == "" {
+		tenant = domain.DefaultTenant
+	}
+	d := f.data(tenant)
+
+	switch rec.Op {
+	case opSave, opUpdate:
+		if rec.Todo != nil {
+			d.DB[rec.ID] = *rec.Todo
+		}
+	case opDelete:
+		d.deleteTodo(rec.ID)
+	case opTrash:
+		if rec.Trashed != nil {
+			d.putTrash(*rec.Trashed)
+		}
+	case opRestore:
+		if rec.Trashed != nil {
+			d.restoreTodo(*rec.Trashed)
+		}
+	case opPurge:
+		delete(d.Trash, rec.ID)
+	case opSaveTag, opUpdateTag:
+		if rec.Tag != nil {
+			d.Tags[rec.ID] = *rec.Tag
+		}
+	case opDeleteTag:
+		d.deleteTag(rec.ID)
+	case opSaveUser:
+		if rec.User != nil {
+			d.Users[rec.ID] = rec.User.user()
+		}
+	case opSaveSession:
+		if rec.Session != nil {
+			d.Sessions[rec.SessionID] = *rec.Session
+		}
+	case opDeleteSession:
+		delete(d.Sessions, rec.SessionID)
+	case opSaveAPIKey, opTouchAPIKey:
+		if rec.APIKey != nil {
+			d.APIKeys[rec.ID] = rec.APIKey.apiKey()
+		}
+	case opDeleteAPIKey:
+		delete(d.APIKeys, rec.ID)
+	case opSaveList, opUpdateList:
+		if rec.List != nil {
+			d.Lists[rec.ID] = *rec.List
+		}
+	case opDeleteList:
+		delete(d.Lists, rec.ID)
+	case opSaveWebhook:
+		if rec.Webhook != nil {
+			d.Webhooks[rec.ID] = rec.Webhook.webhook()
+		}
+	case opDeleteWebhook:
+		delete(d.Webhooks, rec.ID)
+	}
+
+	// ids of deleted todos and tags must never be handed out again
+	switch rec.Op {
+	case opSaveTag, opUpdateTag, opDeleteTag:
+		d.NextTagID = max(d.NextTagID, rec.ID+1)
+	case opSaveUser:
+		d.NextUserID = max(d.NextUserID, rec.ID+1)
+	case opSaveAPIKey, opTouchAPIKey, opDeleteAPIKey:
+		d.NextAPIKeyID = max(d.NextAPIKeyID, rec.ID+1)
+	case opSaveList, opUpdateList, opDeleteList:
+		d.NextListID = max(d.NextListID, rec.ID+1)
+	case opSaveWebhook, opDeleteWebhook:
+		d.NextWebhookID = max(d.NextWebhookID, rec.ID+1)
+	case opSaveSession, opDeleteSession:
+	default:
+		d.NextID = max(d.NextID, rec.ID+1)
+	}
+	f.seq = rec.Seq
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -0,0 +1,764 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/adapter/repotest"
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+func openRepo(t *testing.T, dir string, opts Options) *FileTodoRepository {
+	t.Helper()
+
+	todoRepo, err := New(dir, opts)
+	if err != nil {
+		t.Fatalf("unexpected error on open: got %v, want nil", err)
+	}
+
+	return todoRepo
+}
+
+func TestSave(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// preparing
+		todoRepo := openRepo(t, t.TempDir(), Options{})
+		defer todoRepo.Close()
+
+		ctx := context.Background()
+
+		inputTitle := "read the book"
+
+		// act
+		id, err := todoRepo.Save(ctx, domain.Todo{Title: inputTitle})
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		wantID := 1
+		if id != wantID {
+			t.Fatalf("must return correct id: got %d, want %d", id, wantID)
+		}
+
+		if todoRepo.DB[id].Title != inputTitle {
+			t.Errorf("incorrect title: got %s, want %s", todoRepo.DB[id].Title, inputTitle)
+		}
+
+		wantNextID := 2
+		if todoRepo.NextID != wantNextID {
+			t.Errorf("must increment nextID field: got %d, want %d", todoRepo.NextID, wantNextID)
+		}
+	})
+
+	t.Run("context canceled -> error", func(t *testing.T) {
+		// preparing
+		todoRepo := openRepo(t, t.TempDir(), Options{})
+		defer todoRepo.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// act
+		_, err := todoRepo.Save(ctx, domain.Todo{Title: "read the book"})
+
+		// assert
+		if !errors.Is(err, ctx.Err()) {
+			t.Fatalf("unexpected error: got %v, want %v", err, ctx.Err())
+		}
+
+		if todoRepo.logRecords != 0 {
+			t.Errorf("must not write log when error: got %d records", todoRepo.logRecords)
+		}
+	})
+}
+
+func TestReplay(t *testing.T) {
+	t.Run("restores todos after restart", func(t *testing.T) {
+		// preparing
+		dir := t.TempDir()
+		ctx := context.Background()
+
+		todoRepo := openRepo(t, dir, Options{})
+
+		firstID, _ := todoRepo.Save(ctx, domain.Todo{Title: "read the book"})
+		secondID, _ := todoRepo.Save(ctx, domain.Todo{Title: "complete the game"})
+		thirdID, _ := todoRepo.Save(ctx, domain.Todo{Title: "get an internship"})
+
+		if err := todoRepo.UpdateByID(ctx, firstID, domain.Todo{Title: "read the book", Completed: true}); err != nil {
+			t.Fatalf("unexpected error on update: got %v, want nil", err)
+		}
+		if err := todoRepo.DeleteByID(ctx, thirdID, 0); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+
+		// simulate a crash: the log is not compacted
+		todoRepo.log.Close()
+
+		// act
+		reopened := openRepo(t, dir, Options{})
+		defer reopened.Close()
+
+		// assert
+		todos, err := reopened.ReadAll(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		if len(todos) != 2 {
+			t.Fatalf("unexpected length: got %d, want %d", len(todos), 2)
+		}
+
+		if todos[0].ID != firstID || !todos[0].Completed {
+			t.Errorf("update was not replayed: got %+v", todos[0])
+		}
+
+		if todos[1].ID != secondID {
+			t.Errorf("unexpected id: got %d, want %d", todos[1].ID, secondID)
+		}
+
+		// the id of the deleted todo must not be reused
+		wantNextID := thirdID + 1
+		if reopened.NextID != wantNextID {
+			t.Errorf("unexpected nextID: got %d, want %d", reopened.NextID, wantNextID)
+		}
+	})
+
+	t.Run("restores tags and cascaded deletes after restart", func(t *testing.T) {
+		// preparing
+		dir := t.TempDir()
+		ctx := context.Background()
+
+		todoRepo := openRepo(t, dir, Options{})
+
+		workID, _ := todoRepo.SaveTag(ctx, domain.Tag{Name: "work"})
+		homeID, _ := todoRepo.SaveTag(ctx, domain.Tag{Name: "home"})
+		todoID, _ := todoRepo.Save(ctx, domain.Todo{Title: "read the book", Tags: []int{workID, homeID}})
+
+		if err := todoRepo.UpdateTagByID(ctx, homeID, domain.Tag{Name: "house"}); err != nil {
+			t.Fatalf("unexpected error on update: got %v, want nil", err)
+		}
+		if err := todoRepo.DeleteTagByID(ctx, workID); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+
+		// simulate a crash: the log is not compacted
+		todoRepo.log.Close()
+
+		// act
+		reopened := openRepo(t, dir, Options{})
+		defer reopened.Close()
+
+		// assert
+		tags, err := reopened.ReadAllTags(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(tags) != 1 || tags[0] != (domain.Tag{ID: homeID, Name: "house"}) {
+			t.Errorf("unexpected tags: got %+v", tags)
+		}
+
+		todo := reopened.DB[todoID]
+		if len(todo.Tags) != 1 || todo.Tags[0] != homeID || todo.Version != 2 {
+			t.Errorf("cascade was not replayed: got tags %v version %d", todo.Tags, todo.Version)
+		}
+
+		wantNextTagID := homeID + 1
+		if reopened.NextTagID != wantNextTagID {
+			t.Errorf("unexpected nextTagID: got %d, want %d", reopened.NextTagID, wantNextTagID)
+		}
+		wantNextID := todoID + 1
+		if reopened.NextID != wantNextID {
+			t.Errorf("unexpected nextID: got %d, want %d", reopened.NextID, wantNextID)
+		}
+	})
+
+	t.Run("drops torn last record", func(t *testing.T) {
+		// preparing
+		dir := t.TempDir()
+		ctx := context.Background()
+
+		todoRepo := openRepo(t, dir, Options{})
+		if _, err := todoRepo.Save(ctx, domain.Todo{Title: "read the book"}); err != nil {
+			t.Fatalf("unexpected error on save: got %v, want nil", err)
+		}
+		todoRepo.log.Close()
+
+		logFile, err := os.OpenFile(filepath.Join(dir, logFileName), os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			t.Fatalf("open log: %v", err)
+		}
+		logFile.WriteString(`{"seq":2,"op":"save","id":2,"todo":{"id":2,"ti`)
+		logFile.Close()
+
+		// act
+		reopened := openRepo(t, dir, Options{})
+		defer reopened.Close()
+
+		// assert
+		if len(reopened.DB) != 1 {
+			t.Fatalf("unexpected length: got %d, want %d", len(reopened.DB), 1)
+		}
+
+		id, err := reopened.Save(ctx, domain.Todo{Title: "complete the game"})
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if id != 2 {
+			t.Errorf("unexpected id: got %d, want %d", id, 2)
+		}
+	})
+
+	t.Run("corrupted record -> error", func(t *testing.T) {
+		// preparing
+		dir := t.TempDir()
+
+		if err := os.WriteFile(filepath.Join(dir, logFileName), []byte("not json\n"), 0o644); err != nil {
+			t.Fatalf("write log: %v", err)
+		}
+
+		// act
+		_, err := New(dir, Options{})
+
+		// assert
+		if err == nil {
+			t.Fatalf("unexpected error: got nil, want error")
+		}
+	})
+}
+
+func TestReplayUsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		compact bool
+	}{
+		{name: "from log"},
+		{name: "from snapshot", compact: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			dir := t.TempDir()
+			ctx := context.Background()
+
+			todoRepo := openRepo(t, dir, Options{})
+
+			user := domain.User{Email: "ann@example.com", PasswordHash: "pbkdf2-sha256$1$c2FsdA$a2V5"}
+			id, err := todoRepo.SaveUser(ctx, user)
+			if err != nil {
+				t.Fatalf("unexpected error on save user: got %v, want nil", err)
+			}
+			expires := time.Now().Add(time.Hour)
+			for _, sessionID := range []string{"kept", "ended"} {
+				if err := todoRepo.SaveSession(ctx, domain.Session{ID: sessionID, UserID: id, ExpiresAt: expires}); err != nil {
+					t.Fatalf("unexpected error on save session: got %v, want nil", err)
+				}
+			}
+			if err := todoRepo.SaveSession(ctx, domain.Session{ID: "expired", UserID: id, ExpiresAt: time.Now()}); err != nil {
+				t.Fatalf("unexpected error on save session: got %v, want nil", err)
+			}
+			if err := todoRepo.DeleteSession(ctx, "ended"); err != nil {
+				t.Fatalf("unexpected error on delete session: got %v, want nil", err)
+			}
+
+			keyID, err := todoRepo.SaveAPIKey(ctx, domain.APIKey{UserID: id, Name: "backup", Hash: "kept", Scopes: domain.AllScopes})
+			if err != nil {
+				t.Fatalf("unexpected error on save api key: got %v, want nil", err)
+			}
+			usedAt := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+			if err := todoRepo.TouchAPIKey(ctx, keyID, usedAt); err != nil {
+				t.Fatalf("unexpected error on touch api key: got %v, want nil", err)
+			}
+			revokedID, err := todoRepo.SaveAPIKey(ctx, domain.APIKey{UserID: id, Name: "old", Hash: "revoked"})
+			if err != nil {
+				t.Fatalf("unexpected error on save api key: got %v, want nil", err)
+			}
+			if err := todoRepo.DeleteAPIKey(ctx, id, revokedID); err != nil {
+				t.Fatalf("unexpected error on delete api key: got %v, want nil", err)
+			}
+
+			listID, err := todoRepo.SaveList(ctx, domain.List{Name: "home", Members: []domain.Member{{UserID: id, Email: user.Email, Role: domain.RoleOwner}}})
+			if err != nil {
+				t.Fatalf("unexpected error on save list: got %v, want nil", err)
+			}
+			list, _ := todoRepo.GetListByID(ctx, listID)
+			list.Invitations = []domain.Invitation{{Email: "bob@example.com", Role: domain.RoleViewer, InvitedBy: id}}
+			if err := todoRepo.UpdateListByID(ctx, listID, list); err != nil {
+				t.Fatalf("unexpected error on update list: got %v, want nil", err)
+			}
+			droppedID, err := todoRepo.SaveList(ctx, domain.List{Name: "old"})
+			if err != nil {
+				t.Fatalf("unexpected error on save list: got %v, want nil", err)
+			}
+			if err := todoRepo.DeleteListByID(ctx, droppedID); err != nil {
+				t.Fatalf("unexpected error on delete list: got %v, want nil", err)
+			}
+
+			hookID, err := todoRepo.SaveWebhook(ctx, domain.Webhook{UserID: id, URL: "https://example.com/", Secret: "kept-secret-0123"})
+			if err != nil {
+				t.Fatalf("unexpected error on save webhook: got %v, want nil", err)
+			}
+			deletedHookID, err := todoRepo.SaveWebhook(ctx, domain.Webhook{UserID: id, URL: "https://example.com/old"})
+			if err != nil {
+				t.Fatalf("unexpected error on save webhook: got %v, want nil", err)
+			}
+			if err := todoRepo.DeleteWebhook(ctx, id, deletedHookID); err != nil {
+				t.Fatalf("unexpected error on delete webhook: got %v, want nil", err)
+			}
+
+			if tc.compact {
+				if err := todoRepo.Close(); err != nil {
+					t.Fatalf("unexpected error on close: got %v, want nil", err)
+				}
+			} else {
+				todoRepo.log.Close()
+			}
+
+			// act
+			reopened := openRepo(t, dir, Options{})
+			defer reopened.Close()
+
+			// assert
+			got, err := reopened.GetUserByEmail(ctx, user.Email)
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			if got.ID != id || got.PasswordHash != user.PasswordHash {
+				t.Errorf("unexpected user: got %+v", got)
+			}
+			if _, err := reopened.GetSession(ctx, "kept"); err != nil {
+				t.Errorf("unexpected error for kept session: got %v, want nil", err)
+			}
+			if _, err := reopened.GetSession(ctx, "ended"); !errors.Is(err, domain.ErrSessionNotExist) {
+				t.Errorf("unexpected error for deleted session: got %v, want %v", err, domain.ErrSessionNotExist)
+			}
+			// expired sessions do not survive a compaction
+			if _, err := reopened.GetSession(ctx, "expired"); tc.compact != errors.Is(err, domain.ErrSessionNotExist) {
+				t.Errorf("unexpected error for expired session: got %v", err)
+			}
+			if reopened.NextUserID != id+1 {
+				t.Errorf("unexpected nextUserID: got %d, want %d", reopened.NextUserID, id+1)
+			}
+
+			key, err := reopened.GetAPIKeyByHash(ctx, "kept")
+			if err != nil {
+				t.Fatalf("unexpected error for kept api key: got %v, want nil", err)
+			}
+			if key.ID != keyID || key.LastUsedAt == nil || !key.LastUsedAt.Equal(usedAt) {
+				t.Errorf("unexpected api key: got %+v", key)
+			}
+			if _, err := reopened.GetAPIKeyByHash(ctx, "revoked"); !errors.Is(err, domain.ErrAPIKeyNotExist) {
+				t.Errorf("unexpected error for revoked api key: got %v, want %v", err, domain.ErrAPIKeyNotExist)
+			}
+			if reopened.NextAPIKeyID != revokedID+1 {
+				t.Errorf("unexpected nextAPIKeyID: got %d, want %d", reopened.NextAPIKeyID, revokedID+1)
+			}
+
+			gotList, err := reopened.GetListByID(ctx, listID)
+			if err != nil {
+				t.Fatalf("unexpected error for kept list: got %v, want nil", err)
+			}
+			if gotList.Version != 2 || len(gotList.Members) != 1 || gotList.Invited("bob@example.com") != 0 {
+				t.Errorf("unexpected list: got %+v", gotList)
+			}
+			if _, err := reopened.GetListByID(ctx, droppedID); !errors.Is(err, domain.ErrListNotExist) {
+				t.Errorf("unexpected error for deleted list: got %v, want %v", err, domain.ErrListNotExist)
+			}
+			if reopened.NextListID != droppedID+1 {
+				t.Errorf("unexpected nextListID: got %d, want %d", reopened.NextListID, droppedID+1)
+			}
+
+			hooks, err := reopened.ListWebhooks(ctx, id)
+			if err != nil {
+				t.Fatalf("unexpected error for webhooks: got %v, want nil", err)
+			}
+			if len(hooks) != 1 || hooks[0].ID != hookID || hooks[0].Secret != "kept-secret-0123" {
+				t.Errorf("unexpected webhooks: got %+v", hooks)
+			}
+			if reopened.NextWebhookID != deletedHookID+1 {
+				t.Errorf("unexpected nextWebhookID: got %d, want %d", reopened.NextWebhookID, deletedHookID+1)
+			}
+		})
+	}
+}
+
+func TestReplayTenants(t *testing.T) {
+	tests := []struct {
+		name    string
+		compact bool
+	}{
+		{name: "from the log"},
+		{name: "from the snapshot", compact: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			dir := t.TempDir()
+			ctx := context.Background()
+			acme := domain.ContextWithTenant(ctx, "acme")
+
+			todoRepo := openRepo(t, dir, Options{})
+
+			defaultID, _ := todoRepo.Save(ctx, domain.Todo{Title: "read the book"})
+			tagID, err := todoRepo.SaveTag(acme, domain.Tag{Name: "work"})
+			if err != nil {
+				t.Fatalf("unexpected error on save tag: got %v, want nil", err)
+			}
+			acmeID, err := todoRepo.Save(acme, domain.Todo{Title: "ship the release", Tags: []int{tagID}})
+			if err != nil {
+				t.Fatalf("unexpected error on save: got %v, want nil", err)
+			}
+
+			if tc.compact {
+				if err := todoRepo.Close(); err != nil {
+					t.Fatalf("unexpected error on close: got %v, want nil", err)
+				}
+			} else {
+				// simulate a crash: the log is not compacted
+				todoRepo.log.Close()
+			}
+
+			// act
+			reopened := openRepo(t, dir, Options{})
+			defer reopened.Close()
+
+			// assert
+			if len(reopened.DB) != 1 || reopened.DB[defaultID].Title != "read the book" {
+				t.Errorf("unexpected default tenant todos: got %+v", reopened.DB)
+			}
+
+			got, err := reopened.GetByID(acme, acmeID)
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			if got.Title != "ship the release" || len(got.Tags) != 1 || got.Tags[0] != tagID {
+				t.Errorf("unexpected acme todo: got %+v", got)
+			}
+
+			// both tenants continue their own sequences
+			nextID, err := reopened.Save(acme, domain.Todo{Title: "write the notes"})
+			if err != nil {
+				t.Fatalf("unexpected error on save: got %v, want nil", err)
+			}
+			if nextID != acmeID+1 {
+				t.Errorf("unexpected acme id: got %d, want %d", nextID, acmeID+1)
+			}
+			if reopened.NextID != defaultID+1 {
+				t.Errorf("unexpected default nextID: got %d, want %d", reopened.NextID, defaultID+1)
+			}
+		})
+	}
+}
+
+func TestReplayOutbox(t *testing.T) {
+	tests := []struct {
+		name    string
+		compact bool
+	}{
+		{name: "from the log"},
+		{name: "from the snapshot", compact: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			dir := t.TempDir()
+			ctx := context.Background()
+
+			todoRepo := openRepo(t, dir, Options{})
+
+			var id int
+			err := todoRepo.Atomically(ctx, func(ctx context.Context) error {
+				var err error
+				if id, err = todoRepo.Save(ctx, domain.Todo{Title: "read the book"}); err != nil {
+					return err
+				}
+				return todoRepo.AddEvents(ctx,
+					domain.TodoEvent{Type: domain.TodoCreated, Tenant: domain.DefaultTenant, Todo: domain.Todo{ID: id}},
+					domain.TodoEvent{Type: domain.TodoCompleted, Tenant: domain.DefaultTenant, Todo: domain.Todo{ID: id}},
+				)
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			if err := todoRepo.AckEvents(ctx, 1); err != nil {
+				t.Fatalf("unexpected error on ack: got %v, want nil", err)
+			}
+
+			if tc.compact {
+				if err := todoRepo.Close(); err != nil {
+					t.Fatalf("unexpected error on close: got %v, want nil", err)
+				}
+			} else {
+				// simulate a crash: the log is not compacted
+				todoRepo.log.Close()
+			}
+
+			// act
+			reopened := openRepo(t, dir, Options{})
+			defer reopened.Close()
+
+			// assert
+			if _, err := reopened.GetByID(ctx, id); err != nil {
+				t.Errorf("unexpected error on get: got %v, want nil", err)
+			}
+			events, _ := reopened.PendingEvents(ctx, 10)
+			if len(events) != 1 || events[0].ID != 2 || events[0].Type != domain.TodoCompleted {
+				t.Errorf("unexpected events: got %+v", events)
+			}
+			if reopened.nextEventID != 3 {
+				t.Errorf("unexpected nextEventID: got %d, want %d", reopened.nextEventID, 3)
+			}
+		})
+	}
+
+	t.Run("torn transaction is dropped whole", func(t *testing.T) {
+		// preparing
+		dir := t.TempDir()
+		ctx := context.Background()
+
+		todoRepo := openRepo(t, dir, Options{})
+		err := todoRepo.Atomically(ctx, func(ctx context.Context) error {
+			id, err := todoRepo.Save(ctx, domain.Todo{Title: "read the book"})
+			if err != nil {
+				return err
+			}
+			return todoRepo.AddEvents(ctx, domain.TodoEvent{Type: domain.TodoCreated, Todo: domain.Todo{ID: id}})
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		todoRepo.log.Close()
+
+		// the process died in the middle of writing the transaction
+		path := filepath.Join(dir, logFileName)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat log: %v", err)
+		}
+		if err := os.Truncate(path, info.Size()-10); err != nil {
+			t.Fatalf("truncate log: %v", err)
+		}
+
+		// act
+		reopened := openRepo(t, dir, Options{})
+		defer reopened.Close()
+
+		// assert
+		if len(reopened.DB) != 0 {
+			t.Errorf("unexpected todos: got %+v, want none", reopened.DB)
+		}
+		if events, _ := reopened.PendingEvents(ctx, 10); len(events) != 0 {
+			t.Errorf("unexpected events: got %+v, want none", events)
+		}
+	})
+}
+
+func TestReplayTrash(t *testing.T) {
+	tests := []struct {
+		name    string
+		compact bool
+	}{
+		{name: "from the log"},
+		{name: "from the snapshot", compact: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			dir := t.TempDir()
+			ctx := context.Background()
+			at := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+			todoRepo := openRepo(t, dir, Options{})
+
+			trashedID, _ := todoRepo.Save(ctx, domain.Todo{Title: "move"})
+			childID, _ := todoRepo.Save(ctx, domain.Todo{Title: "pack", ParentID: &trashedID})
+			restoredID, _ := todoRepo.Save(ctx, domain.Todo{Title: "read the book"})
+			purgedID, _ := todoRepo.Save(ctx, domain.Todo{Title: "buy milk"})
+			for _, id := range []int{trashedID, restoredID, purgedID} {
+				if err := todoRepo.TrashByID(ctx, id, 0, at); err != nil {
+					t.Fatalf("unexpected error on trash: got %v, want nil", err)
+				}
+			}
+			if _, err := todoRepo.RestoreByID(ctx, restoredID); err != nil {
+				t.Fatalf("unexpected error on restore: got %v, want nil", err)
+			}
+			if err := todoRepo.PurgeByID(ctx, purgedID); err != nil {
+				t.Fatalf("unexpected error on purge: got %v, want nil", err)
+			}
+
+			if tc.compact {
+				if err := todoRepo.Close(); err != nil {
+					t.Fatalf("unexpected error on close: got %v, want nil", err)
+				}
+			} else {
+				// simulate a crash: the log is not compacted
+				todoRepo.log.Close()
+			}
+
+			// act
+			reopened := openRepo(t, dir, Options{})
+			defer reopened.Close()
+
+			// assert
+			if len(reopened.DB) != 1 || reopened.DB[restoredID].Title != "read the book" {
+				t.Errorf("unexpected todos: got %+v", reopened.DB)
+			}
+
+			entry, err := reopened.GetTrashedByID(ctx, trashedID)
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			if len(entry.Subtasks) != 1 || entry.Subtasks[0].ID != childID || !entry.DeletedAt.Equal(at) {
+				t.Errorf("unexpected trash entry: got %+v", entry)
+			}
+			if len(reopened.Trash) != 1 {
+				t.Errorf("unexpected trash: got %+v", reopened.Trash)
+			}
+
+			// ids of purged todos are never handed out again
+			if reopened.NextID != purgedID+1 {
+				t.Errorf("unexpected nextID: got %d, want %d", reopened.NextID, purgedID+1)
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	t.Run("folds log into snapshot", func(t *testing.T) {
+		// preparing
+		dir := t.TempDir()
+		ctx := context.Background()
+
+		todoRepo := openRepo(t, dir, Options{CompactEvery: 3})
+
+		for _, title := range []string{"read the book", "complete the game", "get an internship", "buy milk"} {
+			if _, err := todoRepo.Save(ctx, domain.Todo{Title: title}); err != nil {
+				t.Fatalf("unexpected error on save: got %v, want nil", err)
+			}
+		}
+
+		// assert
+		if todoRepo.logRecords != 1 {
+			t.Errorf("log must be truncated after compaction: got %d records, want %d", todoRepo.logRecords, 1)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, snapshotFileName)); err != nil {
+			t.Fatalf("snapshot must exist: %v", err)
+		}
+
+		todoRepo.log.Close()
+
+		reopened := openRepo(t, dir, Options{})
+		defer reopened.Close()
+
+		if len(reopened.DB) != 4 {
+			t.Errorf("unexpected length: got %d, want %d", len(reopened.DB), 4)
+		}
+
+		wantNextID := 5
+		if reopened.NextID != wantNextID {
+			t.Errorf("unexpected nextID: got %d, want %d", reopened.NextID, wantNextID)
+		}
+	})
+
+	t.Run("skips records already in snapshot", func(t *testing.T) {
+		// preparing
+		dir := t.TempDir()
+		ctx := context.Background()
+
+		todoRepo := openRepo(t, dir, Options{})
+		id, _ := todoRepo.Save(ctx, domain.Todo{Title: "read the book"})
+
+		// keep a copy of the log to emulate a crash between snapshot and truncate
+		stale, err := os.ReadFile(filepath.Join(dir, logFileName))
+		if err != nil {
+			t.Fatalf("read log: %v", err)
+		}
+
+		if err := todoRepo.DeleteByID(ctx, id, 0); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+		if err := todoRepo.Close(); err != nil {
+			t.Fatalf("unexpected error on close: got %v, want nil", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, logFileName), stale, 0o644); err != nil {
+			t.Fatalf("write log: %v", err)
+		}
+
+		// act
+		reopened := openRepo(t, dir, Options{})
+		defer reopened.Close()
+
+		// assert
+		if len(reopened.DB) != 0 {
+			t.Errorf("stale record must be skipped: got %d todos, want %d", len(reopened.DB), 0)
+		}
+	})
+}
+
+func TestConformance(t *testing.T) {
+	repotest.RunConformance(t, func(t *testing.T) usecase.TodoRepository {
+		todoRepo := openRepo(t, t.TempDir(), Options{CompactEvery: 16})
+		t.Cleanup(func() { todoRepo.Close() })
+		return todoRepo
+	})
+}
+
+func TestUserConformance(t *testing.T) {
+	repotest.RunUserConformance(t, func(t *testing.T) usecase.UserRepository {
+		todoRepo := openRepo(t, t.TempDir(), Options{CompactEvery: 16})
+		t.Cleanup(func() { todoRepo.Close() })
+		return todoRepo
+	})
+}
+
+func TestListConformance(t *testing.T) {
+	repotest.RunListConformance(t, func(t *testing.T) usecase.ListRepository {
+		todoRepo := openRepo(t, t.TempDir(), Options{CompactEvery: 16})
+		t.Cleanup(func() { todoRepo.Close() })
+		return todoRepo
+	})
+}
+
+func TestWebhookConformance(t *testing.T) {
+	repotest.RunWebhookConformance(t, func(t *testing.T) repotest.WebhookRepository {
+		todoRepo := openRepo(t, t.TempDir(), Options{CompactEvery: 16})
+		t.Cleanup(func() { todoRepo.Close() })
+		return todoRepo
+	})
+}
+
+func TestTenantConformance(t *testing.T) {
+	repotest.RunTenantConformance(t, func(t *testing.T) repotest.TenantRepository {
+		todoRepo := openRepo(t, t.TempDir(), Options{CompactEvery: 16})
+		t.Cleanup(func() { todoRepo.Close() })
+		return todoRepo
+	})
+}
+
+func TestOutboxConformance(t *testing.T) {
+	repotest.RunOutboxConformance(t, func(t *testing.T) repotest.OutboxRepository {
+		todoRepo := openRepo(t, t.TempDir(), Options{CompactEvery: 16})
+		t.Cleanup(func() { todoRepo.Close() })
+		return todoRepo
+	})
+}
+
+func TestTrashConformance(t *testing.T) {
+	repotest.RunTrashConformance(t, func(t *testing.T) repotest.TrashRepository {
+		todoRepo := openRepo(t, t.TempDir(), Options{CompactEvery: 16})
+		t.Cleanup(func() { todoRepo.Close() })
+		return todoRepo
+	})
+}
@@ -0,0 +1,143 @@
+package file
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (f *FileTodoRepository) SaveList(ctx context.Context, list domain.List) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	id := d.NextListID
+	list.ID = id
+	list.Version = 1
+	list = cloneList(list)
+
+	if err := f.append(ctx, record{Op: opSaveList, ID: id, List: &list}); err != nil {
+		return 0, err
+	}
+
+	d.Lists[id] = list
+	d.NextListID++
+
+	return id, f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) GetListByID(ctx context.Context, id int) (domain.List, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.List{}, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	list, ok := d.Lists[id]
+	if !ok {
+		return domain.List{}, domain.ErrListNotExist
+	}
+
+	return cloneList(list), nil
+}
+
+func (f *FileTodoRepository) UpdateListByID(ctx context.Context, id int, list domain.List) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	current, ok := d.Lists[id]
+	if !ok {
+		return domain.ErrListNotExist
+	}
+	if list.Version != current.Version {
+		return domain.ErrVersionMismatch
+	}
+
+	list.ID = id
+	list.Version++
+	list.CreatedAt = current.CreatedAt
+	list = cloneList(list)
+
+	if err := f.append(ctx, record{Op: opUpdateList, ID: id, List: &list}); err != nil {
+		return err
+	}
+
+	d.Lists[id] = list
+
+	return f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) DeleteListByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	if _, ok := d.Lists[id]; !ok {
+		return domain.ErrListNotExist
+	}
+
+	if err := f.append(ctx, record{Op: opDeleteList, ID: id}); err != nil {
+		return err
+	}
+
+	delete(d.Lists, id)
+
+	return f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) ListsByMember(ctx context.Context, userID int) ([]domain.List, error) {
+	return f.listsWhere(ctx, func(list domain.List) bool {
+		_, ok := list.RoleOf(userID)
+		return ok
+	})
+}
+
+func (f *FileTodoRepository) ListsByInvitee(ctx context.Context, email string) ([]domain.List, error) {
+	return f.listsWhere(ctx, func(list domain.List) bool {
+		return list.Invited(email) >= 0
+	})
+}
+
+func (f *FileTodoRepository) listsWhere(ctx context.Context, match func(domain.List) bool) ([]domain.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	lists := make([]domain.List, 0)
+	for _, id := range slices.Sorted(maps.Keys(d.Lists)) {
+		if list := d.Lists[id]; match(list) {
+			lists = append(lists, cloneList(list))
+		}
+	}
+
+	return lists, nil
+}
+
+// cloneList copies the members and invitations, so callers changing them
+// do not change the stored list.
+func cloneList(list domain.List) domain.List {
+	list.Members = slices.Clone(list.Members)
+	list.Invitations = slices.Clone(list.Invitations)
+	return list
+}
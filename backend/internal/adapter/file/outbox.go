@@ -0,0 +1,73 @@
+package file
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (f *FileTodoRepository) AddEvents(ctx context.Context, events ...domain.TodoEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// the events go into one record, together with the change when a
+	// transaction runs in ctx
+	return f.Atomically(ctx, func(ctx context.Context) error {
+		outbox := f.outbox
+		for _, event := range events {
+			event.ID = f.nextEventID
+			if err := f.append(ctx, record{Op: opAddEvent, Event: &event}); err != nil {
+				return err
+			}
+			f.nextEventID++
+			outbox = append(outbox, event)
+		}
+		f.outbox = outbox
+		return nil
+	})
+}
+
+func (f *FileTodoRepository) PendingEvents(ctx context.Context, limit int) ([]domain.TodoEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer f.rlock(ctx)()
+
+	n := min(limit, len(f.outbox))
+	events := make([]domain.TodoEvent, n)
+	copy(events, f.outbox)
+	return events, nil
+}
+
+func (f *FileTodoRepository) AckEvents(ctx context.Context, ids ...uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	if err := f.append(ctx, record{Op: opAckEvents, EventIDs: ids}); err != nil {
+		return err
+	}
+	f.outbox = withoutEvents(f.outbox, ids)
+
+	return f.maybeCompact(ctx)
+}
+
+// withoutEvents returns the outbox without the events of the ids.
+func withoutEvents(outbox []domain.TodoEvent, ids []uint64) []domain.TodoEvent {
+	acked := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		acked[id] = true
+	}
+
+	kept := make([]domain.TodoEvent, 0, len(outbox))
+	for _, event := range outbox {
+		if !acked[event.ID] {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
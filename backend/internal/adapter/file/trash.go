@@ -0,0 +1,181 @@
+package file
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (f *FileTodoRepository) TrashByID(ctx context.Context, id int, version int, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	stored, ok := d.DB[id]
+	if !ok {
+		return domain.ErrTodoNotExist
+	}
+	if version != 0 && version != stored.Version {
+		return domain.ErrVersionMismatch
+	}
+
+	entry := domain.TrashedTodo{Todo: stored, Subtasks: []domain.Todo{}, DeletedAt: at}
+	for _, v := range d.subtree(id)[1:] {
+		entry.Subtasks = append(entry.Subtasks, d.DB[v])
+	}
+
+	if err := f.append(ctx, record{Op: opTrash, ID: id, Trashed: &entry}); err != nil {
+		return err
+	}
+
+	d.putTrash(entry)
+
+	return f.maybeCompact(ctx)
+}
+
+// putTrash moves the todos of the entry to the trash. Must be called with
+// f.mu held.
+func (d *tenantData) putTrash(entry domain.TrashedTodo) {
+	for _, v := range entry.Todos() {
+		delete(d.DB, v.ID)
+	}
+	d.Trash[entry.ID] = entry
+}
+
+func (f *FileTodoRepository) ReadTrash(ctx context.Context) ([]domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	unlock := f.rlock(ctx)
+	res := slices.Collect(maps.Values(f.read(ctx).Trash))
+	unlock()
+
+	if res == nil {
+		res = []domain.TrashedTodo{}
+	}
+	slices.SortFunc(res, compareTrashed)
+
+	return res, nil
+}
+
+// compareTrashed orders the trash by deletion time, the latest first.
+func compareTrashed(a, b domain.TrashedTodo) int {
+	if c := b.DeletedAt.Compare(a.DeletedAt); c != 0 {
+		return c
+	}
+	return b.ID - a.ID
+}
+
+func (f *FileTodoRepository) GetTrashedByID(ctx context.Context, id int) (domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+
+	defer f.rlock(ctx)()
+
+	entry, ok := f.read(ctx).Trash[id]
+	if !ok {
+		return domain.TrashedTodo{}, domain.ErrTodoNotExist
+	}
+
+	return entry, nil
+}
+
+func (f *FileTodoRepository) RestoreByID(ctx context.Context, id int) (domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	entry, ok := d.Trash[id]
+	if !ok {
+		return domain.TrashedTodo{}, domain.ErrTodoNotExist
+	}
+
+	parentExists := false
+	if entry.ParentID != nil {
+		_, parentExists = d.DB[*entry.ParentID]
+	}
+	todos := entry.Restored(func(id int) bool {
+		_, ok := d.Tags[id]
+		return ok
+	}, parentExists)
+	entry.Todo, entry.Subtasks = todos[0], todos[1:]
+
+	// the record holds the todos as restored, so replaying needs no checks
+	if err := f.append(ctx, record{Op: opRestore, ID: id, Trashed: &entry}); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+
+	d.restoreTodo(entry)
+
+	return entry, f.maybeCompact(ctx)
+}
+
+// restoreTodo puts the todos of the entry back. Must be called with f.mu
+// held.
+func (d *tenantData) restoreTodo(entry domain.TrashedTodo) {
+	for _, v := range entry.Todos() {
+		d.DB[v.ID] = v
+	}
+	delete(d.Trash, entry.ID)
+}
+
+func (f *FileTodoRepository) PurgeByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	if _, ok := d.Trash[id]; !ok {
+		return domain.ErrTodoNotExist
+	}
+
+	if err := f.append(ctx, record{Op: opPurge, ID: id}); err != nil {
+		return err
+	}
+
+	delete(d.Trash, id)
+
+	return f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer f.lock(ctx)()
+
+	n := 0
+	for _, tenant := range slices.Sorted(maps.Keys(f.tenants)) {
+		tenantCtx := domain.ContextWithTenant(ctx, tenant)
+		d := f.write(tenantCtx)
+		for _, id := range slices.Sorted(maps.Keys(d.Trash)) {
+			if !d.Trash[id].DeletedAt.Before(before) {
+				continue
+			}
+			if err := f.append(tenantCtx, record{Op: opPurge, ID: id}); err != nil {
+				return n, err
+			}
+			delete(d.Trash, id)
+			n++
+		}
+	}
+
+	return n, f.maybeCompact(ctx)
+}
@@ -0,0 +1,147 @@
+package file
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+type txKey struct{}
+
+// fileTx holds f.mu for writing until it ends; the calls made with its
+// context run under that lock instead of taking it again. Their records
+// are kept back and written to the log at once on commit.
+type fileTx struct {
+	repo    *FileTodoRepository
+	done    bool
+	records []record
+
+	// kept are the workspaces as they were before their first write, nil
+	// for those the transaction created.
+	kept        map[domain.Tenant]*tenantData
+	outbox      []domain.TodoEvent
+	nextEventID uint64
+}
+
+// Atomically runs fn holding the lock of the repository and writes all its
+// records as a single one, so a crash loses either all of them or none.
+// When fn fails, or the record cannot be written, its writes are undone.
+func (f *FileTodoRepository) Atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	if f.tx(ctx) != nil {
+		return fn(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tx := &fileTx{
+		repo:        f,
+		kept:        map[domain.Tenant]*tenantData{},
+		outbox:      slices.Clone(f.outbox),
+		nextEventID: f.nextEventID,
+	}
+	committed := false
+	defer func() {
+		tx.done = true
+		if !committed {
+			tx.rollback()
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err := tx.commit(); err != nil {
+		return err
+	}
+	committed = true
+
+	return f.maybeCompact(ctx)
+}
+
+// tx returns the transaction of the repository running in ctx, if any.
+func (f *FileTodoRepository) tx(ctx context.Context) *fileTx {
+	tx, ok := ctx.Value(txKey{}).(*fileTx)
+	if !ok || tx.repo != f || tx.done {
+		return nil
+	}
+	return tx
+}
+
+// lock takes f.mu for writing, unless a transaction of ctx holds it
+// already, and returns the matching unlock.
+func (f *FileTodoRepository) lock(ctx context.Context) func() {
+	if f.tx(ctx) != nil {
+		return func() {}
+	}
+	f.mu.Lock()
+	return f.mu.Unlock
+}
+
+// rlock is lock for reading.
+func (f *FileTodoRepository) rlock(ctx context.Context) func() {
+	if f.tx(ctx) != nil {
+		return func() {}
+	}
+	f.mu.RLock()
+	return f.mu.RUnlock
+}
+
+// keep saves the workspace before its first write in the transaction.
+func (tx *fileTx) keep(tenant domain.Tenant, data *tenantData, existed bool) {
+	if _, ok := tx.kept[tenant]; ok {
+		return
+	}
+	if !existed {
+		tx.kept[tenant] = nil
+		return
+	}
+	tx.kept[tenant] = data.clone()
+}
+
+// commit writes the records of the transaction, a single one as it is.
+func (tx *fileTx) commit() error {
+	switch len(tx.records) {
+	case 0:
+		return nil
+	case 1:
+		return tx.repo.writeRecord(tx.records[0])
+	default:
+		return tx.repo.writeRecord(record{Op: opTx, Records: tx.records})
+	}
+}
+
+// rollback puts back what the transaction changed. The workspaces are
+// restored in place, as the repository embeds the default one.
+func (tx *fileTx) rollback() {
+	f := tx.repo
+	for tenant, kept := range tx.kept {
+		if kept == nil {
+			delete(f.tenants, tenant)
+			continue
+		}
+		*f.tenants[tenant] = *kept
+	}
+	f.outbox = tx.outbox
+	f.nextEventID = tx.nextEventID
+}
+
+// clone copies the maps, so the copy survives changes of the original.
+// Stored values are never edited in place, so they are shared.
+func (d *tenantData) clone() *tenantData {
+	c := *d
+	c.DB = maps.Clone(d.DB)
+	c.Tags = maps.Clone(d.Tags)
+	c.Users = maps.Clone(d.Users)
+	c.Sessions = maps.Clone(d.Sessions)
+	c.APIKeys = maps.Clone(d.APIKeys)
+	c.Lists = maps.Clone(d.Lists)
+	c.Webhooks = maps.Clone(d.Webhooks)
+	c.Trash = maps.Clone(d.Trash)
+	return &c
+}
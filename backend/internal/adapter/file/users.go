@@ -0,0 +1,153 @@
+package file
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func toStoredUser(user domain.User) storedUser {
+	return storedUser{
+		ID:           user.ID,
+		Email:        user.Email,
+		PasswordHash: user.PasswordHash,
+		CreatedAt:    user.CreatedAt,
+	}
+}
+
+func (u storedUser) user() domain.User {
+	return domain.User{
+		ID:           u.ID,
+		Email:        u.Email,
+		PasswordHash: u.PasswordHash,
+		CreatedAt:    u.CreatedAt,
+	}
+}
+
+func (f *FileTodoRepository) SaveUser(ctx context.Context, user domain.User) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	if _, ok := d.userByEmail(user.Email); ok {
+		return 0, domain.ErrUserExists
+	}
+
+	id := d.NextUserID
+	user.ID = id
+
+	stored := toStoredUser(user)
+	if err := f.append(ctx, record{Op: opSaveUser, ID: id, User: &stored}); err != nil {
+		return 0, err
+	}
+
+	d.Users[id] = user
+	d.NextUserID++
+
+	return id, f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) GetUserByID(ctx context.Context, id int) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	user, ok := d.Users[id]
+	if !ok {
+		return domain.User{}, domain.ErrUserNotExist
+	}
+
+	return user, nil
+}
+
+func (f *FileTodoRepository) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	user, ok := d.userByEmail(email)
+	if !ok {
+		return domain.User{}, domain.ErrUserNotExist
+	}
+
+	return user, nil
+}
+
+func (f *FileTodoRepository) SaveSession(ctx context.Context, session domain.Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	if err := f.append(ctx, record{Op: opSaveSession, SessionID: session.ID, Session: &session}); err != nil {
+		return err
+	}
+
+	d.Sessions[session.ID] = session
+
+	return f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) GetSession(ctx context.Context, id string) (domain.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Session{}, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	session, ok := d.Sessions[id]
+	if !ok {
+		return domain.Session{}, domain.ErrSessionNotExist
+	}
+
+	return session, nil
+}
+
+func (f *FileTodoRepository) DeleteSession(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	if _, ok := d.Sessions[id]; !ok {
+		return domain.ErrSessionNotExist
+	}
+
+	if err := f.append(ctx, record{Op: opDeleteSession, SessionID: id}); err != nil {
+		return err
+	}
+
+	delete(d.Sessions, id)
+
+	return f.maybeCompact(ctx)
+}
+
+// userByEmail must be called with f.mu held.
+func (d *tenantData) userByEmail(email string) (domain.User, bool) {
+	for _, user := range d.Users {
+		if user.Email == email {
+			return user, true
+		}
+	}
+	return domain.User{}, false
+}
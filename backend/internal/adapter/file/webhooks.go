@@ -0,0 +1,113 @@
+package file
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func toStoredWebhook(hook domain.Webhook) storedWebhook {
+	return storedWebhook{
+		ID:        hook.ID,
+		UserID:    hook.UserID,
+		URL:       hook.URL,
+		Events:    hook.Events,
+		Secret:    hook.Secret,
+		CreatedAt: hook.CreatedAt,
+	}
+}
+
+func (h storedWebhook) webhook() domain.Webhook {
+	return domain.Webhook{
+		ID:        h.ID,
+		UserID:    h.UserID,
+		URL:       h.URL,
+		Events:    h.Events,
+		Secret:    h.Secret,
+		CreatedAt: h.CreatedAt,
+	}
+}
+
+func (f *FileTodoRepository) SaveWebhook(ctx context.Context, hook domain.Webhook) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	id := d.NextWebhookID
+	hook.ID = id
+	hook.Events = slices.Clone(hook.Events)
+
+	stored := toStoredWebhook(hook)
+	if err := f.append(ctx, record{Op: opSaveWebhook, ID: id, Webhook: &stored}); err != nil {
+		return 0, err
+	}
+
+	d.Webhooks[id] = hook
+	d.NextWebhookID++
+
+	return id, f.maybeCompact(ctx)
+}
+
+func (f *FileTodoRepository) ListWebhooks(ctx context.Context, userID int) ([]domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	hooks := make([]domain.Webhook, 0)
+	for _, id := range slices.Sorted(maps.Keys(d.Webhooks)) {
+		if hook := d.Webhooks[id]; hook.UserID == userID {
+			hooks = append(hooks, hook)
+		}
+	}
+
+	return hooks, nil
+}
+
+func (f *FileTodoRepository) ReadAllWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer f.rlock(ctx)()
+
+	d := f.read(ctx)
+
+	hooks := make([]domain.Webhook, 0, len(d.Webhooks))
+	for _, id := range slices.Sorted(maps.Keys(d.Webhooks)) {
+		hooks = append(hooks, d.Webhooks[id])
+	}
+
+	return hooks, nil
+}
+
+func (f *FileTodoRepository) DeleteWebhook(ctx context.Context, userID, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer f.lock(ctx)()
+
+	d := f.write(ctx)
+
+	if hook, ok := d.Webhooks[id]; !ok || hook.UserID != userID {
+		return domain.ErrWebhookNotExist
+	}
+
+	if err := f.append(ctx, record{Op: opDeleteWebhook, ID: id}); err != nil {
+		return err
+	}
+
+	delete(d.Webhooks, id)
+
+	return f.maybeCompact(ctx)
+}
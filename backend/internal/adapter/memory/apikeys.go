@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (m *MemoryTodoRepository) SaveAPIKey(ctx context.Context, key domain.APIKey) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	id := d.NextAPIKeyID
+	key.ID = id
+	d.APIKeys[id] = key
+	d.NextAPIKeyID++
+	return id, nil
+}
+
+func (m *MemoryTodoRepository) GetAPIKeyByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.APIKey{}, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	for _, key := range d.APIKeys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+
+	return domain.APIKey{}, domain.ErrAPIKeyNotExist
+}
+
+func (m *MemoryTodoRepository) TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	key, ok := d.APIKeys[id]
+	if !ok {
+		return domain.ErrAPIKeyNotExist
+	}
+
+	key.LastUsedAt = &usedAt
+	d.APIKeys[id] = key
+	return nil
+}
+
+func (m *MemoryTodoRepository) ListAPIKeys(ctx context.Context, userID int) ([]domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	keys := make([]domain.APIKey, 0)
+	for _, id := range slices.Sorted(maps.Keys(d.APIKeys)) {
+		if key := d.APIKeys[id]; key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (m *MemoryTodoRepository) DeleteAPIKey(ctx context.Context, userID, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	if key, ok := d.APIKeys[id]; !ok || key.UserID != userID {
+		return domain.ErrAPIKeyNotExist
+	}
+
+	delete(d.APIKeys, id)
+	return nil
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (m *MemoryTodoRepository) SaveList(ctx context.Context, list domain.List) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	id := d.NextListID
+	list.ID = id
+	list.Version = 1
+	d.Lists[id] = cloneList(list)
+	d.NextListID++
+	return id, nil
+}
+
+func (m *MemoryTodoRepository) GetListByID(ctx context.Context, id int) (domain.List, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.List{}, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	list, ok := d.Lists[id]
+	if !ok {
+		return domain.List{}, domain.ErrListNotExist
+	}
+
+	return cloneList(list), nil
+}
+
+func (m *MemoryTodoRepository) UpdateListByID(ctx context.Context, id int, list domain.List) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	current, ok := d.Lists[id]
+	if !ok {
+		return domain.ErrListNotExist
+	}
+	if list.Version != current.Version {
+		return domain.ErrVersionMismatch
+	}
+
+	list.ID = id
+	list.Version++
+	list.CreatedAt = current.CreatedAt
+	d.Lists[id] = cloneList(list)
+	return nil
+}
+
+func (m *MemoryTodoRepository) DeleteListByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	if _, ok := d.Lists[id]; !ok {
+		return domain.ErrListNotExist
+	}
+
+	delete(d.Lists, id)
+	return nil
+}
+
+func (m *MemoryTodoRepository) ListsByMember(ctx context.Context, userID int) ([]domain.List, error) {
+	return m.listsWhere(ctx, func(list domain.List) bool {
+		_, ok := list.RoleOf(userID)
+		return ok
+	})
+}
+
+func (m *MemoryTodoRepository) ListsByInvitee(ctx context.Context, email string) ([]domain.List, error) {
+	return m.listsWhere(ctx, func(list domain.List) bool {
+		return list.Invited(email) >= 0
+	})
+}
+
+func (m *MemoryTodoRepository) listsWhere(ctx context.Context, match func(domain.List) bool) ([]domain.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	lists := make([]domain.List, 0)
+	for _, id := range slices.Sorted(maps.Keys(d.Lists)) {
+		if list := d.Lists[id]; match(list) {
+			lists = append(lists, cloneList(list))
+		}
+	}
+
+	return lists, nil
+}
+
+// cloneList copies the members and invitations, so callers changing them
+// do not change the stored list.
+func cloneList(list domain.List) domain.List {
+	list.Members = slices.Clone(list.Members)
+	list.Invitations = slices.Clone(list.Invitations)
+	return list
+}
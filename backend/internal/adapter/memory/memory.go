@@ -2,24 +2,110 @@ package memory
 
 import (
 	"context"
+	"maps"
 	"slices"
 	"sync"
 
 	"github.com/VLGKiwi/todo-site/backend/internal/domain"
 )
 
+// MemoryTodoRepository keeps every workspace apart, each with its own id
+// sequences. The embedded data is the default workspace.
 type MemoryTodoRepository struct {
-	DB     map[int]domain.Todo
-	NextID int
-	mu     sync.RWMutex
+	*tenantData
+	tenants map[domain.Tenant]*tenantData
+	mu      sync.RWMutex
+
+	// outbox is shared by every workspace, each event carries its own.
+	outbox      []domain.TodoEvent
+	nextEventID uint64
+}
+
+// tenantData is everything stored in one workspace.
+type tenantData struct {
+	DB            map[int]domain.Todo
+	NextID        int
+	Trash         map[int]domain.TrashedTodo
+	Tags          map[int]domain.Tag
+	NextTagID     int
+	Users         map[int]domain.User
+	NextUserID    int
+	Sessions      map[string]domain.Session
+	APIKeys       map[int]domain.APIKey
+	NextAPIKeyID  int
+	Lists         map[int]domain.List
+	NextListID    int
+	Webhooks      map[int]domain.Webhook
+	NextWebhookID int
 }
 
 func New() *MemoryTodoRepository {
+	data := newTenantData()
 	return &MemoryTodoRepository{
-		DB:     map[int]domain.Todo{},
-		NextID: 1,
-		mu:     sync.RWMutex{},
+		tenantData:  data,
+		tenants:     map[domain.Tenant]*tenantData{domain.DefaultTenant: data},
+		mu:          sync.RWMutex{},
+		nextEventID: 1,
+	}
+}
+
+func newTenantData() *tenantData {
+	return &tenantData{
+		DB:            map[int]domain.Todo{},
+		NextID:        1,
+		Trash:         map[int]domain.TrashedTodo{},
+		Tags:          map[int]domain.Tag{},
+		NextTagID:     1,
+		Users:         map[int]domain.User{},
+		NextUserID:    1,
+		Sessions:      map[string]domain.Session{},
+		APIKeys:       map[int]domain.APIKey{},
+		NextAPIKeyID:  1,
+		Lists:         map[int]domain.List{},
+		NextListID:    1,
+		Webhooks:      map[int]domain.Webhook{},
+		NextWebhookID: 1,
+	}
+}
+
+// read returns the workspace of the context, empty when nothing was stored
+// in it yet. Must be called with m.mu held.
+func (m *MemoryTodoRepository) read(ctx context.Context) *tenantData {
+	if data, ok := m.tenants[domain.CurrentTenant(ctx)]; ok {
+		return data
+	}
+	return newTenantData()
+}
+
+// write returns the workspace of the context, creating it on first use.
+// Within a transaction the workspace is kept as it was, to be restored on
+// rollback. Must be called with m.mu held for writing.
+func (m *MemoryTodoRepository) write(ctx context.Context) *tenantData {
+	tenant := domain.CurrentTenant(ctx)
+	data, ok := m.tenants[tenant]
+	if !ok {
+		data = newTenantData()
+		m.tenants[tenant] = data
+	}
+	if tx := m.tx(ctx); tx != nil {
+		tx.keep(tenant, data, ok)
 	}
+	return data
+}
+
+// clone copies the maps, so the copy survives changes of the original.
+// Stored values are never edited in place, so they are shared.
+func (d *tenantData) clone() *tenantData {
+	c := *d
+	c.DB = maps.Clone(d.DB)
+	c.Trash = maps.Clone(d.Trash)
+	c.Tags = maps.Clone(d.Tags)
+	c.Users = maps.Clone(d.Users)
+	c.Sessions = maps.Clone(d.Sessions)
+	c.APIKeys = maps.Clone(d.APIKeys)
+	c.Lists = maps.Clone(d.Lists)
+	c.Webhooks = maps.Clone(d.Webhooks)
+	return &c
 }
 
 func (m *MemoryTodoRepository) Save(ctx context.Context, todo domain.Todo) (int, error) {
@@ -27,13 +113,24 @@ func (m *MemoryTodoRepository) Save(ctx context.Context, todo domain.Todo) (int,
 		return 0, err
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	defer m.lock(ctx)()
 
-	id := m.NextID
+	d := m.write(ctx)
+
+	tags, err := d.todoTags(todo.Tags)
+	if err != nil {
+		return 0, err
+	}
+	if err := d.checkParent(0, todo.ParentID); err != nil {
+		return 0, err
+	}
+
+	id := d.NextID
 	todo.ID = id
-	m.DB[id] = todo
-	m.NextID++
+	todo.Version = 1
+	todo.Tags = tags
+	d.DB[id] = todo
+	d.NextID++
 	return id, nil
 }
 
@@ -42,10 +139,11 @@ func (m *MemoryTodoRepository) GetByID(ctx context.Context, id int) (domain.Todo
 		return domain.Todo{}, err
 	}
 
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	defer m.rlock(ctx)()
 
-	v, ok := m.DB[id]
+	d := m.read(ctx)
+
+	v, ok := d.DB[id]
 	if !ok {
 		return domain.Todo{}, domain.ErrTodoNotExist
 	}
@@ -58,32 +156,49 @@ func (m *MemoryTodoRepository) UpdateByID(ctx context.Context, id int, todo doma
 		return err
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
 
-	_, ok := m.DB[id]
+	stored, ok := d.DB[id]
 	if !ok {
 		return domain.ErrTodoNotExist
 	}
+	if todo.Version != 0 && todo.Version != stored.Version {
+		return domain.ErrVersionMismatch
+	}
+	tags, err := d.todoTags(todo.Tags)
+	if err != nil {
+		return err
+	}
+	if err := d.checkParent(id, todo.ParentID); err != nil {
+		return err
+	}
 	todo.ID = id
-	m.DB[id] = todo
+	todo.Version = stored.Version + 1
+	todo.Tags = tags
+	d.DB[id] = todo
 
 	return nil
 }
 
-func (m *MemoryTodoRepository) DeleteByID(ctx context.Context, id int) error {
+func (m *MemoryTodoRepository) DeleteByID(ctx context.Context, id int, version int) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
 
-	_, ok := m.DB[id]
+	stored, ok := d.DB[id]
 	if !ok {
 		return domain.ErrTodoNotExist
 	}
-	delete(m.DB, id)
+	if version != 0 && version != stored.Version {
+		return domain.ErrVersionMismatch
+	}
+	d.deleteTodo(id)
 
 	return nil
 }
@@ -93,12 +208,13 @@ func (m *MemoryTodoRepository) ReadAll(ctx context.Context) ([]domain.Todo, erro
 		return []domain.Todo{}, err
 	}
 
-	m.mu.RLock()
-	res := make([]domain.Todo, 0, len(m.DB))
-	for _, v := range m.DB {
+	unlock := m.rlock(ctx)
+	d := m.read(ctx)
+	res := make([]domain.Todo, 0, len(d.DB))
+	for _, v := range d.DB {
 		res = append(res, v)
 	}
-	m.mu.RUnlock()
+	unlock()
 
 	slices.SortFunc(res, func(a domain.Todo, b domain.Todo) int {
 		if a.ID < b.ID {
@@ -111,3 +227,190 @@ func (m *MemoryTodoRepository) ReadAll(ctx context.Context) ([]domain.Todo, erro
 
 	return res, nil
 }
+
+func (m *MemoryTodoRepository) Query(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Todo{}, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	return q.Apply(maps.Values(d.DB)), nil
+}
+
+// checkParent makes sure the parent exists and is not the todo itself or
+// one of its subtasks. Must be called with m.mu held.
+func (d *tenantData) checkParent(id int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+	if _, ok := d.DB[*parentID]; !ok {
+		return domain.ErrParentNotExist
+	}
+
+	for p := parentID; p != nil; p = d.DB[*p].ParentID {
+		if *p == id {
+			return domain.ErrParentCycle
+		}
+	}
+	return nil
+}
+
+// deleteTodo removes the todo with all its subtasks at any depth. Must be
+// called with m.mu held.
+func (d *tenantData) deleteTodo(id int) {
+	for _, v := range d.subtree(id) {
+		delete(d.DB, v)
+	}
+}
+
+// subtree returns the todo followed by all its subtasks at any depth, each
+// after its parent.
+func (d *tenantData) subtree(id int) []int {
+	children := map[int][]int{}
+	for _, v := range d.DB {
+		if v.ParentID != nil {
+			children[*v.ParentID] = append(children[*v.ParentID], v.ID)
+		}
+	}
+	for _, ids := range children {
+		slices.Sort(ids)
+	}
+
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// todoTags normalizes the tag ids of a todo. Must be called with m.mu held.
+func (d *tenantData) todoTags(ids []int) ([]int, error) {
+	ids = domain.NormalizeTagIDs(ids)
+	for _, id := range ids {
+		if _, ok := d.Tags[id]; !ok {
+			return nil, domain.ErrTagNotExist
+		}
+	}
+	return ids, nil
+}
+
+func (m *MemoryTodoRepository) SaveTag(ctx context.Context, tag domain.Tag) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	if d.tagNameTaken(tag.Name, 0) {
+		return 0, domain.ErrTagExists
+	}
+
+	id := d.NextTagID
+	tag.ID = id
+	d.Tags[id] = tag
+	d.NextTagID++
+	return id, nil
+}
+
+func (m *MemoryTodoRepository) GetTagByID(ctx context.Context, id int) (domain.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Tag{}, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	v, ok := d.Tags[id]
+	if !ok {
+		return domain.Tag{}, domain.ErrTagNotExist
+	}
+
+	return v, nil
+}
+
+func (m *MemoryTodoRepository) UpdateTagByID(ctx context.Context, id int, tag domain.Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	if _, ok := d.Tags[id]; !ok {
+		return domain.ErrTagNotExist
+	}
+	if d.tagNameTaken(tag.Name, id) {
+		return domain.ErrTagExists
+	}
+	tag.ID = id
+	d.Tags[id] = tag
+
+	return nil
+}
+
+func (m *MemoryTodoRepository) DeleteTagByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	if _, ok := d.Tags[id]; !ok {
+		return domain.ErrTagNotExist
+	}
+	delete(d.Tags, id)
+
+	for todoID, todo := range d.DB {
+		if !slices.Contains(todo.Tags, id) {
+			continue
+		}
+		// stored slices may be shared with callers, so never edit them in place
+		todo.Tags = slices.DeleteFunc(slices.Clone(todo.Tags), func(tagID int) bool {
+			return tagID == id
+		})
+		todo.Version++
+		d.DB[todoID] = todo
+	}
+
+	return nil
+}
+
+func (m *MemoryTodoRepository) ReadAllTags(ctx context.Context) ([]domain.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Tag{}, err
+	}
+
+	unlock := m.rlock(ctx)
+	d := m.read(ctx)
+	res := slices.Collect(maps.Values(d.Tags))
+	unlock()
+
+	if res == nil {
+		res = []domain.Tag{}
+	}
+	slices.SortFunc(res, func(a domain.Tag, b domain.Tag) int {
+		return a.ID - b.ID
+	})
+
+	return res, nil
+}
+
+// tagNameTaken reports whether a tag other than self already has the name.
+// Must be called with m.mu held.
+func (d *tenantData) tagNameTaken(name string, self int) bool {
+	for _, t := range d.Tags {
+		if t.ID != self && domain.SameTagName(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
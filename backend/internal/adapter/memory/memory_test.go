@@ -5,7 +5,9 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/VLGKiwi/todo-site/backend/internal/adapter/repotest"
 	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
 )
 
 func TestSave(t *testing.T) {
@@ -88,6 +90,35 @@ func TestSave(t *testing.T) {
 		}
 	})
 
+	t.Run("ids are sequenced per tenant", func(t *testing.T) {
+		// preparing
+		todoRepo := New()
+
+		acme := domain.ContextWithTenant(context.Background(), "acme")
+		globex := domain.ContextWithTenant(context.Background(), "globex")
+
+		if _, err := todoRepo.Save(acme, domain.Todo{Title: "acme plan"}); err != nil {
+			t.Fatalf("unexpected error on save: got %v, want nil", err)
+		}
+
+		// act
+		id, err := todoRepo.Save(globex, domain.Todo{Title: "globex plan"})
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		wantID := 1
+		if id != wantID {
+			t.Errorf("unexpected id: got %d, want %d", id, wantID)
+		}
+
+		// the default tenant stays untouched
+		if len(todoRepo.DB) != 0 || todoRepo.NextID != 1 {
+			t.Errorf("default tenant must stay empty: got %d todos, next id %d", len(todoRepo.DB), todoRepo.NextID)
+		}
+	})
 }
 
 func TestGetByID(t *testing.T) {
@@ -271,7 +302,7 @@ func TestDeleteByID(t *testing.T) {
 		todoRepo.DB[savedID] = savedTodo
 
 		// act
-		err := todoRepo.DeleteByID(ctx, savedID)
+		err := todoRepo.DeleteByID(ctx, savedID, 0)
 		if err != nil {
 			t.Fatalf("unexpected error: got %v, want nil", err)
 		}
@@ -292,7 +323,7 @@ func TestDeleteByID(t *testing.T) {
 		inputID := 1
 
 		// act
-		err := todoRepo.DeleteByID(ctx, inputID)
+		err := todoRepo.DeleteByID(ctx, inputID, 0)
 
 		// assert
 		if !errors.Is(err, ctx.Err()) {
@@ -309,7 +340,7 @@ func TestDeleteByID(t *testing.T) {
 		inputID := 1
 
 		// act
-		err := todoRepo.DeleteByID(ctx, inputID)
+		err := todoRepo.DeleteByID(ctx, inputID, 0)
 
 		// assert
 		if !errors.Is(err, domain.ErrTodoNotExist) {
@@ -381,3 +412,45 @@ func TestReadAll(t *testing.T) {
 		}
 	})
 }
+
+func TestConformance(t *testing.T) {
+	repotest.RunConformance(t, func(t *testing.T) usecase.TodoRepository {
+		return New()
+	})
+}
+
+func TestUserConformance(t *testing.T) {
+	repotest.RunUserConformance(t, func(t *testing.T) usecase.UserRepository {
+		return New()
+	})
+}
+
+func TestListConformance(t *testing.T) {
+	repotest.RunListConformance(t, func(t *testing.T) usecase.ListRepository {
+		return New()
+	})
+}
+
+func TestWebhookConformance(t *testing.T) {
+	repotest.RunWebhookConformance(t, func(t *testing.T) repotest.WebhookRepository {
+		return New()
+	})
+}
+
+func TestTenantConformance(t *testing.T) {
+	repotest.RunTenantConformance(t, func(t *testing.T) repotest.TenantRepository {
+		return New()
+	})
+}
+
+func TestOutboxConformance(t *testing.T) {
+	repotest.RunOutboxConformance(t, func(t *testing.T) repotest.OutboxRepository {
+		return New()
+	})
+}
+
+func TestTrashConformance(t *testing.T) {
+	repotest.RunTrashConformance(t, func(t *testing.T) repotest.TrashRepository {
+		return New()
+	})
+}
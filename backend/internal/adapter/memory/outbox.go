@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (m *MemoryTodoRepository) AddEvents(ctx context.Context, events ...domain.TodoEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	outbox := m.outbox
+	for _, event := range events {
+		event.ID = m.nextEventID
+		m.nextEventID++
+		outbox = append(outbox, event)
+	}
+	m.outbox = outbox
+	return nil
+}
+
+func (m *MemoryTodoRepository) PendingEvents(ctx context.Context, limit int) ([]domain.TodoEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer m.rlock(ctx)()
+
+	n := min(limit, len(m.outbox))
+	events := make([]domain.TodoEvent, n)
+	copy(events, m.outbox)
+	return events, nil
+}
+
+func (m *MemoryTodoRepository) AckEvents(ctx context.Context, ids ...uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	acked := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		acked[id] = true
+	}
+
+	outbox := make([]domain.TodoEvent, 0, len(m.outbox))
+	for _, event := range m.outbox {
+		if !acked[event.ID] {
+			outbox = append(outbox, event)
+		}
+	}
+	m.outbox = outbox
+	return nil
+}
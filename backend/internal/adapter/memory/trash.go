@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (m *MemoryTodoRepository) TrashByID(ctx context.Context, id int, version int, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	stored, ok := d.DB[id]
+	if !ok {
+		return domain.ErrTodoNotExist
+	}
+	if version != 0 && version != stored.Version {
+		return domain.ErrVersionMismatch
+	}
+	d.trashTodo(id, at)
+
+	return nil
+}
+
+// trashTodo moves the todo with all its subtasks to the trash. Must be
+// called with m.mu held.
+func (d *tenantData) trashTodo(id int, at time.Time) {
+	ids := d.subtree(id)
+
+	entry := domain.TrashedTodo{Todo: d.DB[id], Subtasks: []domain.Todo{}, DeletedAt: at}
+	for _, v := range ids[1:] {
+		entry.Subtasks = append(entry.Subtasks, d.DB[v])
+	}
+	for _, v := range ids {
+		delete(d.DB, v)
+	}
+	d.Trash[id] = entry
+}
+
+func (m *MemoryTodoRepository) ReadTrash(ctx context.Context) ([]domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	unlock := m.rlock(ctx)
+	res := slices.Collect(maps.Values(m.read(ctx).Trash))
+	unlock()
+
+	if res == nil {
+		res = []domain.TrashedTodo{}
+	}
+	slices.SortFunc(res, compareTrashed)
+
+	return res, nil
+}
+
+// compareTrashed orders the trash by deletion time, the latest first.
+func compareTrashed(a, b domain.TrashedTodo) int {
+	if c := b.DeletedAt.Compare(a.DeletedAt); c != 0 {
+		return c
+	}
+	return b.ID - a.ID
+}
+
+func (m *MemoryTodoRepository) GetTrashedByID(ctx context.Context, id int) (domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+
+	defer m.rlock(ctx)()
+
+	entry, ok := m.read(ctx).Trash[id]
+	if !ok {
+		return domain.TrashedTodo{}, domain.ErrTodoNotExist
+	}
+
+	return entry, nil
+}
+
+func (m *MemoryTodoRepository) RestoreByID(ctx context.Context, id int) (domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	entry, ok := d.Trash[id]
+	if !ok {
+		return domain.TrashedTodo{}, domain.ErrTodoNotExist
+	}
+
+	return d.restoreTodo(entry), nil
+}
+
+// restoreTodo puts the todos of the entry back and returns them. Must be
+// called with m.mu held.
+func (d *tenantData) restoreTodo(entry domain.TrashedTodo) domain.TrashedTodo {
+	parentExists := false
+	if entry.ParentID != nil {
+		_, parentExists = d.DB[*entry.ParentID]
+	}
+	todos := entry.Restored(func(id int) bool {
+		_, ok := d.Tags[id]
+		return ok
+	}, parentExists)
+
+	for _, todo := range todos {
+		d.DB[todo.ID] = todo
+	}
+	delete(d.Trash, entry.ID)
+
+	entry.Todo, entry.Subtasks = todos[0], todos[1:]
+	return entry
+}
+
+func (m *MemoryTodoRepository) PurgeByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	if _, ok := d.Trash[id]; !ok {
+		return domain.ErrTodoNotExist
+	}
+	delete(d.Trash, id)
+
+	return nil
+}
+
+func (m *MemoryTodoRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer m.lock(ctx)()
+
+	n := 0
+	for _, tenant := range slices.Sorted(maps.Keys(m.tenants)) {
+		d := m.write(domain.ContextWithTenant(ctx, tenant))
+		for id, entry := range d.Trash {
+			if entry.DeletedAt.Before(before) {
+				delete(d.Trash, id)
+				n++
+			}
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+type txKey struct{}
+
+// memoryTx holds m.mu for writing until it ends; the calls made with its
+// context run under that lock instead of taking it again.
+type memoryTx struct {
+	repo *MemoryTodoRepository
+	done bool
+
+	// kept are the workspaces as they were before their first write, nil
+	// for those the transaction created.
+	kept        map[domain.Tenant]*tenantData
+	outbox      []domain.TodoEvent
+	nextEventID uint64
+}
+
+// Atomically runs fn holding the lock of the repository, so nothing else
+// is seen or stored in between, and undoes its writes when it fails.
+func (m *MemoryTodoRepository) Atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.tx(ctx) != nil {
+		return fn(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &memoryTx{
+		repo:        m,
+		kept:        map[domain.Tenant]*tenantData{},
+		outbox:      slices.Clone(m.outbox),
+		nextEventID: m.nextEventID,
+	}
+	committed := false
+	defer func() {
+		tx.done = true
+		if !committed {
+			tx.rollback()
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// tx returns the transaction of the repository running in ctx, if any.
+func (m *MemoryTodoRepository) tx(ctx context.Context) *memoryTx {
+	tx, ok := ctx.Value(txKey{}).(*memoryTx)
+	if !ok || tx.repo != m || tx.done {
+		return nil
+	}
+	return tx
+}
+
+// lock takes m.mu for writing, unless a transaction of ctx holds it
+// already, and returns the matching unlock.
+func (m *MemoryTodoRepository) lock(ctx context.Context) func() {
+	if m.tx(ctx) != nil {
+		return func() {}
+	}
+	m.mu.Lock()
+	return m.mu.Unlock
+}
+
+// rlock is lock for reading.
+func (m *MemoryTodoRepository) rlock(ctx context.Context) func() {
+	if m.tx(ctx) != nil {
+		return func() {}
+	}
+	m.mu.RLock()
+	return m.mu.RUnlock
+}
+
+// keep saves the workspace before its first write in the transaction.
+func (tx *memoryTx) keep(tenant domain.Tenant, data *tenantData, existed bool) {
+	if _, ok := tx.kept[tenant]; ok {
+		return
+	}
+	if !existed {
+		tx.kept[tenant] = nil
+		return
+	}
+	tx.kept[tenant] = data.clone()
+}
+
+// rollback puts back what the transaction changed. The workspaces are
+// restored in place, as the repository embeds the default one.
+func (tx *memoryTx) rollback() {
+	m := tx.repo
+	for tenant, kept := range tx.kept {
+		if kept == nil {
+			delete(m.tenants, tenant)
+			continue
+		}
+		*m.tenants[tenant] = *kept
+	}
+	m.outbox = tx.outbox
+	m.nextEventID = tx.nextEventID
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (m *MemoryTodoRepository) SaveUser(ctx context.Context, user domain.User) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	if _, ok := d.userByEmail(user.Email); ok {
+		return 0, domain.ErrUserExists
+	}
+
+	id := d.NextUserID
+	user.ID = id
+	d.Users[id] = user
+	d.NextUserID++
+	return id, nil
+}
+
+func (m *MemoryTodoRepository) GetUserByID(ctx context.Context, id int) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	user, ok := d.Users[id]
+	if !ok {
+		return domain.User{}, domain.ErrUserNotExist
+	}
+
+	return user, nil
+}
+
+func (m *MemoryTodoRepository) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	user, ok := d.userByEmail(email)
+	if !ok {
+		return domain.User{}, domain.ErrUserNotExist
+	}
+
+	return user, nil
+}
+
+func (m *MemoryTodoRepository) SaveSession(ctx context.Context, session domain.Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	d.Sessions[session.ID] = session
+	return nil
+}
+
+func (m *MemoryTodoRepository) GetSession(ctx context.Context, id string) (domain.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Session{}, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	session, ok := d.Sessions[id]
+	if !ok {
+		return domain.Session{}, domain.ErrSessionNotExist
+	}
+
+	return session, nil
+}
+
+func (m *MemoryTodoRepository) DeleteSession(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	if _, ok := d.Sessions[id]; !ok {
+		return domain.ErrSessionNotExist
+	}
+
+	delete(d.Sessions, id)
+	return nil
+}
+
+// userByEmail must be called with m.mu held.
+func (d *tenantData) userByEmail(email string) (domain.User, bool) {
+	for _, user := range d.Users {
+		if user.Email == email {
+			return user, true
+		}
+	}
+	return domain.User{}, false
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (m *MemoryTodoRepository) SaveWebhook(ctx context.Context, hook domain.Webhook) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	id := d.NextWebhookID
+	hook.ID = id
+	hook.Events = slices.Clone(hook.Events)
+	d.Webhooks[id] = hook
+	d.NextWebhookID++
+	return id, nil
+}
+
+func (m *MemoryTodoRepository) ListWebhooks(ctx context.Context, userID int) ([]domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	hooks := make([]domain.Webhook, 0)
+	for _, id := range slices.Sorted(maps.Keys(d.Webhooks)) {
+		if hook := d.Webhooks[id]; hook.UserID == userID {
+			hooks = append(hooks, hook)
+		}
+	}
+
+	return hooks, nil
+}
+
+func (m *MemoryTodoRepository) ReadAllWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer m.rlock(ctx)()
+
+	d := m.read(ctx)
+
+	hooks := make([]domain.Webhook, 0, len(d.Webhooks))
+	for _, id := range slices.Sorted(maps.Keys(d.Webhooks)) {
+		hooks = append(hooks, d.Webhooks[id])
+	}
+
+	return hooks, nil
+}
+
+func (m *MemoryTodoRepository) DeleteWebhook(ctx context.Context, userID, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer m.lock(ctx)()
+
+	d := m.write(ctx)
+
+	if hook, ok := d.Webhooks[id]; !ok || hook.UserID != userID {
+		return domain.ErrWebhookNotExist
+	}
+
+	delete(d.Webhooks, id)
+	return nil
+}
@@ -0,0 +1,212 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+// ListFactory returns an empty list repository.
+type ListFactory func(t *testing.T) usecase.ListRepository
+
+// RunListConformance checks the behaviour every usecase.ListRepository
+// implementation must share.
+func RunListConformance(t *testing.T, factory ListFactory) {
+	t.Run("Lists", func(t *testing.T) { testLists(t, factory) })
+}
+
+func testLists(t *testing.T, factory ListFactory) {
+	ctx := context.Background()
+	created := time.Date(2030, time.March, 1, 9, 0, 0, 123456000, time.UTC)
+
+	newList := func() domain.List {
+		return domain.List{
+			Name: "home",
+			Members: []domain.Member{
+				{UserID: 7, Email: "ann@example.com", Role: domain.RoleOwner, JoinedAt: created},
+				{UserID: 3, Email: "bob@example.com", Role: domain.RoleViewer, JoinedAt: created.Add(time.Hour)},
+			},
+			Invitations: []domain.Invitation{
+				{Email: "dan@example.com", Role: domain.RoleEditor, InvitedBy: 7, CreatedAt: created},
+			},
+			CreatedAt: created,
+		}
+	}
+
+	mustSaveList := func(t *testing.T, repo usecase.ListRepository, list domain.List) int {
+		t.Helper()
+		id, err := repo.SaveList(ctx, list)
+		if err != nil {
+			t.Fatalf("unexpected error on save list: got %v, want nil", err)
+		}
+		return id
+	}
+
+	equalLists := func(got, want domain.List) bool {
+		if got.ID != want.ID || got.Name != want.Name || got.Version != want.Version || !got.CreatedAt.Equal(want.CreatedAt) ||
+			len(got.Members) != len(want.Members) || len(got.Invitations) != len(want.Invitations) {
+			return false
+		}
+		for i, m := range got.Members {
+			w := want.Members[i]
+			if m.UserID != w.UserID || m.Email != w.Email || m.Role != w.Role || !m.JoinedAt.Equal(w.JoinedAt) {
+				return false
+			}
+		}
+		for i, inv := range got.Invitations {
+			w := want.Invitations[i]
+			if inv.Email != w.Email || inv.Role != w.Role || inv.InvitedBy != w.InvitedBy || !inv.CreatedAt.Equal(w.CreatedAt) {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("stores members and invitations in order", func(t *testing.T) {
+		repo := factory(t)
+		want := newList()
+		want.ID = mustSaveList(t, repo, want)
+		want.Version = 1
+
+		got, err := repo.GetListByID(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if !equalLists(got, want) {
+			t.Errorf("unexpected list: got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("assigns distinct ids", func(t *testing.T) {
+		repo := factory(t)
+		first := mustSaveList(t, repo, newList())
+		second := mustSaveList(t, repo, newList())
+
+		if first == 0 || first == second {
+			t.Errorf("unexpected ids: got %d and %d", first, second)
+		}
+	})
+
+	t.Run("missing list -> error", func(t *testing.T) {
+		repo := factory(t)
+
+		if _, err := repo.GetListByID(ctx, 42); !errors.Is(err, domain.ErrListNotExist) {
+			t.Errorf("unexpected get error: got %v, want %v", err, domain.ErrListNotExist)
+		}
+		if err := repo.UpdateListByID(ctx, 42, domain.List{Name: "home", Version: 1}); !errors.Is(err, domain.ErrListNotExist) {
+			t.Errorf("unexpected update error: got %v, want %v", err, domain.ErrListNotExist)
+		}
+		if err := repo.DeleteListByID(ctx, 42); !errors.Is(err, domain.ErrListNotExist) {
+			t.Errorf("unexpected delete error: got %v, want %v", err, domain.ErrListNotExist)
+		}
+	})
+
+	t.Run("update replaces members and bumps the version", func(t *testing.T) {
+		repo := factory(t)
+		id := mustSaveList(t, repo, newList())
+
+		want, err := repo.GetListByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		want.Name = "home, shared"
+		want.Members[1].Role = domain.RoleEditor
+		want.Members = append(want.Members, domain.Member{UserID: 9, Email: "dan@example.com", Role: domain.RoleEditor, JoinedAt: created})
+		want.Invitations = nil
+
+		if err := repo.UpdateListByID(ctx, id, want); err != nil {
+			t.Fatalf("unexpected error on update: got %v, want nil", err)
+		}
+		// the version read before the update is stale now
+		if err := repo.UpdateListByID(ctx, id, want); !errors.Is(err, domain.ErrVersionMismatch) {
+			t.Errorf("unexpected error on stale update: got %v, want %v", err, domain.ErrVersionMismatch)
+		}
+
+		got, err := repo.GetListByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		want.Version = 2
+		if !equalLists(got, want) {
+			t.Errorf("unexpected list: got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("changing a read list leaves the stored one", func(t *testing.T) {
+		repo := factory(t)
+		id := mustSaveList(t, repo, newList())
+
+		read, err := repo.GetListByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		read.Members[0].Role = domain.RoleViewer
+
+		got, err := repo.GetListByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if got.Members[0].Role != domain.RoleOwner {
+			t.Errorf("unexpected role: got %s, want %s", got.Members[0].Role, domain.RoleOwner)
+		}
+	})
+
+	t.Run("finds lists by member and invitee", func(t *testing.T) {
+		repo := factory(t)
+		first := mustSaveList(t, repo, newList())
+		other := newList()
+		other.Members = other.Members[:1]
+		other.Invitations = append(other.Invitations, domain.Invitation{Email: "bob@example.com", Role: domain.RoleViewer, InvitedBy: 7})
+		second := mustSaveList(t, repo, other)
+
+		listIDs := func(lists []domain.List, err error) []int {
+			t.Helper()
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			ids := []int{}
+			for _, list := range lists {
+				ids = append(ids, list.ID)
+			}
+			return ids
+		}
+
+		cases := []struct {
+			name string
+			got  []int
+			want []int
+		}{
+			{"owner", listIDs(repo.ListsByMember(ctx, 7)), []int{first, second}},
+			{"viewer", listIDs(repo.ListsByMember(ctx, 3)), []int{first}},
+			{"stranger", listIDs(repo.ListsByMember(ctx, 42)), []int{}},
+			{"invited twice", listIDs(repo.ListsByInvitee(ctx, "dan@example.com")), []int{first, second}},
+			{"invited once", listIDs(repo.ListsByInvitee(ctx, "bob@example.com")), []int{second}},
+		}
+		for _, c := range cases {
+			if !slices.Equal(c.got, c.want) {
+				t.Errorf("%s: unexpected lists: got %v, want %v", c.name, c.got, c.want)
+			}
+		}
+	})
+
+	t.Run("delete drops members and invitations", func(t *testing.T) {
+		repo := factory(t)
+		id := mustSaveList(t, repo, newList())
+
+		if err := repo.DeleteListByID(ctx, id); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+		if _, err := repo.GetListByID(ctx, id); !errors.Is(err, domain.ErrListNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrListNotExist)
+		}
+		lists, err := repo.ListsByMember(ctx, 7)
+		if err != nil || len(lists) != 0 {
+			t.Errorf("unexpected lists: got %v, %v", lists, err)
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/outbox"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+// OutboxRepository stores the todos and their events in one transaction.
+type OutboxRepository interface {
+	usecase.TodoRepository
+	usecase.Transactor
+	outbox.Store
+}
+
+// OutboxFactory returns an empty outbox repository.
+type OutboxFactory func(t *testing.T) OutboxRepository
+
+// RunOutboxConformance checks the behaviour every usecase.Transactor and
+// outbox.Store implementation must share.
+func RunOutboxConformance(t *testing.T, factory OutboxFactory) {
+	t.Run("Atomically", func(t *testing.T) { testAtomically(t, factory) })
+	t.Run("Outbox", func(t *testing.T) { testOutbox(t, factory) })
+}
+
+var errRollback = errors.New("rollback")
+
+func todoEvent(typ domain.TodoEventType, tenant domain.Tenant, todo domain.Todo) domain.TodoEvent {
+	return domain.TodoEvent{
+		Type:    typ,
+		Tenant:  tenant,
+		Todo:    todo,
+		ActorID: 7,
+		At:      time.Date(2030, time.March, 1, 9, 0, 0, 123456000, time.UTC),
+	}
+}
+
+func mustPending(t *testing.T, repo OutboxRepository, limit int) []domain.TodoEvent {
+	t.Helper()
+
+	events, err := repo.PendingEvents(context.Background(), limit)
+	if err != nil {
+		t.Fatalf("unexpected error on pending events: got %v, want nil", err)
+	}
+
+	return events
+}
+
+func testAtomically(t *testing.T, factory OutboxFactory) {
+	ctx := context.Background()
+
+	t.Run("commit stores the change and its events", func(t *testing.T) {
+		repo := factory(t)
+
+		var id int
+		err := repo.Atomically(ctx, func(ctx context.Context) error {
+			var err error
+			id, err = repo.Save(ctx, domain.Todo{Title: "first"})
+			if err != nil {
+				return err
+			}
+
+			// the transaction sees its own writes
+			todo, err := repo.GetByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			return repo.AddEvents(ctx, todoEvent(domain.TodoCreated, domain.DefaultTenant, todo))
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		if _, err := repo.GetByID(ctx, id); err != nil {
+			t.Errorf("unexpected error on get: got %v, want nil", err)
+		}
+		events := mustPending(t, repo, 10)
+		want := todoEvent(domain.TodoCreated, domain.DefaultTenant, domain.Todo{ID: id, Title: "first"})
+		if len(events) != 1 {
+			t.Fatalf("unexpected events: got %+v", events)
+		}
+		got := events[0]
+		if got.ID == 0 || got.Type != want.Type || got.Tenant != want.Tenant || got.Todo.ID != id || got.Todo.Title != "first" ||
+			got.ActorID != want.ActorID || !got.At.Equal(want.At) {
+			t.Errorf("unexpected event: got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("error rolls back the change and its events", func(t *testing.T) {
+		repo := factory(t)
+		id := mustSave(t, repo, domain.Todo{Title: "kept"})
+
+		err := repo.Atomically(ctx, func(ctx context.Context) error {
+			if err := repo.UpdateByID(ctx, id, domain.Todo{Title: "changed"}); err != nil {
+				return err
+			}
+			if _, err := repo.Save(ctx, domain.Todo{Title: "second"}); err != nil {
+				return err
+			}
+			// a workspace the transaction creates is dropped as well
+			if _, err := repo.Save(domain.ContextWithTenant(ctx, "acme"), domain.Todo{Title: "elsewhere"}); err != nil {
+				return err
+			}
+			if err := repo.AddEvents(ctx, todoEvent(domain.TodoUpdated, domain.DefaultTenant, domain.Todo{ID: id})); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("unexpected error: got %v, want %v", err, errRollback)
+		}
+
+		got, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error on get: got %v, want nil", err)
+		}
+		if got.Title != "kept" || got.Version != 1 {
+			t.Errorf("unexpected todo: got %+v, want the one before the transaction", got)
+		}
+		if all, _ := repo.ReadAll(ctx); len(all) != 1 {
+			t.Errorf("unexpected todos: got %+v, want only the first", all)
+		}
+		if all, _ := repo.ReadAll(acme); len(all) != 0 {
+			t.Errorf("unexpected todos in the other workspace: got %+v, want none", all)
+		}
+		if events := mustPending(t, repo, 10); len(events) != 0 {
+			t.Errorf("unexpected events: got %+v, want none", events)
+		}
+	})
+
+	t.Run("nested call joins the outer one", func(t *testing.T) {
+		repo := factory(t)
+
+		err := repo.Atomically(ctx, func(ctx context.Context) error {
+			err := repo.Atomically(ctx, func(ctx context.Context) error {
+				_, err := repo.Save(ctx, domain.Todo{Title: "nested"})
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("unexpected error: got %v, want %v", err, errRollback)
+		}
+
+		if all, _ := repo.ReadAll(ctx); len(all) != 0 {
+			t.Errorf("unexpected todos: got %+v, want none", all)
+		}
+	})
+}
+
+func testOutbox(t *testing.T, factory OutboxFactory) {
+	ctx := context.Background()
+	repo := factory(t)
+
+	// act
+	adds := []struct {
+		ctx    context.Context
+		tenant domain.Tenant
+		types  []domain.TodoEventType
+	}{
+		{ctx: acme, tenant: "acme", types: []domain.TodoEventType{domain.TodoCreated}},
+		{ctx: globex, tenant: "globex", types: []domain.TodoEventType{domain.TodoUpdated}},
+		{ctx: ctx, tenant: domain.DefaultTenant, types: []domain.TodoEventType{domain.TodoCompleted, domain.TodoDeleted}},
+	}
+	for _, add := range adds {
+		var events []domain.TodoEvent
+		for _, typ := range add.types {
+			events = append(events, todoEvent(typ, add.tenant, domain.Todo{ID: 1}))
+		}
+		if err := repo.AddEvents(add.ctx, events...); err != nil {
+			t.Fatalf("unexpected error on add events: got %v, want nil", err)
+		}
+	}
+
+	t.Run("pending events of every workspace, oldest first", func(t *testing.T) {
+		events := mustPending(t, repo, 10)
+
+		want := []struct {
+			tenant domain.Tenant
+			typ    domain.TodoEventType
+		}{
+			{"acme", domain.TodoCreated},
+			{"globex", domain.TodoUpdated},
+			{domain.DefaultTenant, domain.TodoCompleted},
+			{domain.DefaultTenant, domain.TodoDeleted},
+		}
+		if len(events) != len(want) {
+			t.Fatalf("unexpected events: got %+v", events)
+		}
+		for i, w := range want {
+			if events[i].Tenant != w.tenant || events[i].Type != w.typ {
+				t.Errorf("unexpected event %d: got %+v, want %s in %s", i, events[i], w.typ, w.tenant)
+			}
+			if i > 0 && events[i].ID <= events[i-1].ID {
+				t.Errorf("event ids must increase: got %d after %d", events[i].ID, events[i-1].ID)
+			}
+		}
+
+		if got := mustPending(t, repo, 2); len(got) != 2 || got[0].ID != events[0].ID || got[1].ID != events[1].ID {
+			t.Errorf("unexpected limited events: got %+v", got)
+		}
+	})
+
+	t.Run("ack removes the events", func(t *testing.T) {
+		events := mustPending(t, repo, 10)
+
+		if err := repo.AckEvents(ctx, events[0].ID, events[2].ID, 999); err != nil {
+			t.Fatalf("unexpected error on ack: got %v, want nil", err)
+		}
+
+		got := mustPending(t, repo, 10)
+		if len(got) != 2 || got[0].ID != events[1].ID || got[1].ID != events[3].ID {
+			t.Errorf("unexpected events after ack: got %+v", got)
+		}
+	})
+
+	t.Run("ids are not reused", func(t *testing.T) {
+		events := mustPending(t, repo, 10)
+		if err := repo.AckEvents(ctx, events[0].ID, events[1].ID); err != nil {
+			t.Fatalf("unexpected error on ack: got %v, want nil", err)
+		}
+
+		if err := repo.AddEvents(ctx, todoEvent(domain.TodoCreated, domain.DefaultTenant, domain.Todo{ID: 2})); err != nil {
+			t.Fatalf("unexpected error on add events: got %v, want nil", err)
+		}
+
+		got := mustPending(t, repo, 10)
+		if len(got) != 1 || got[0].ID <= events[1].ID {
+			t.Errorf("unexpected events: got %+v, want a new id after %d", got, events[1].ID)
+		}
+	})
+}
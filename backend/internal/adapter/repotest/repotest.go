@@ -0,0 +1,1084 @@
+// Package repotest holds the behaviour every usecase.TodoRepository
+// implementation must share. Adapters run it from their own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		repotest.RunConformance(t, func(t *testing.T) usecase.TodoRepository {
+//			return New()
+//		})
+//	}
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+// Factory returns an empty repository. Resources should be released
+// with t.Cleanup.
+type Factory func(t *testing.T) usecase.TodoRepository
+
+func RunConformance(t *testing.T, factory Factory) {
+	t.Run("Save", func(t *testing.T) { testSave(t, factory) })
+	t.Run("GetByID", func(t *testing.T) { testGetByID(t, factory) })
+	t.Run("UpdateByID", func(t *testing.T) { testUpdateByID(t, factory) })
+	t.Run("DeleteByID", func(t *testing.T) { testDeleteByID(t, factory) })
+	t.Run("ReadAll", func(t *testing.T) { testReadAll(t, factory) })
+	t.Run("Query", func(t *testing.T) { testQuery(t, factory) })
+	t.Run("Versioning", func(t *testing.T) { testVersioning(t, factory) })
+	t.Run("Tags", func(t *testing.T) { testTags(t, factory) })
+	t.Run("Subtasks", func(t *testing.T) { testSubtasks(t, factory) })
+	t.Run("ContextCanceled", func(t *testing.T) { testContextCanceled(t, factory) })
+	t.Run("ConcurrentWriters", func(t *testing.T) { testConcurrentWriters(t, factory) })
+}
+
+func mustSave(t *testing.T, repo usecase.TodoRepository, todo domain.Todo) int {
+	t.Helper()
+
+	id, err := repo.Save(context.Background(), todo)
+	if err != nil {
+		t.Fatalf("unexpected error on save: got %v, want nil", err)
+	}
+
+	return id
+}
+
+func mustSaveTag(t *testing.T, repo usecase.TodoRepository, name string) int {
+	t.Helper()
+
+	id, err := repo.SaveTag(context.Background(), domain.Tag{Name: name})
+	if err != nil {
+		t.Fatalf("unexpected error on save tag: got %v, want nil", err)
+	}
+
+	return id
+}
+
+func testSave(t *testing.T, factory Factory) {
+	t.Run("assigns increasing ids starting from 1", func(t *testing.T) {
+		repo := factory(t)
+
+		for want := 1; want <= 3; want++ {
+			got := mustSave(t, repo, domain.Todo{Title: fmt.Sprintf("todo %d", want)})
+			if got != want {
+				t.Errorf("unexpected id: got %d, want %d", got, want)
+			}
+		}
+	})
+
+	t.Run("ignores id of the input", func(t *testing.T) {
+		repo := factory(t)
+
+		id := mustSave(t, repo, domain.Todo{ID: 42, Title: "read the book"})
+		if id != 1 {
+			t.Errorf("unexpected id: got %d, want %d", id, 1)
+		}
+	})
+
+	t.Run("stores every field", func(t *testing.T) {
+		repo := factory(t)
+		list := 3
+
+		input := domain.Todo{
+			Title:       "read the book",
+			Description: "chapter one",
+			Completed:   true,
+			OwnerID:     7,
+			ListID:      &list,
+		}
+
+		id := mustSave(t, repo, input)
+
+		got, err := repo.GetByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		input.ID = id
+		input.Version = 1
+		if !got.Equal(input) {
+			t.Errorf("unexpected todo: got %+v, want %+v", got, input)
+		}
+	})
+
+	t.Run("stores planning fields and timestamps", func(t *testing.T) {
+		repo := factory(t)
+
+		due := time.Date(2030, time.March, 10, 18, 30, 0, 0, time.FixedZone("", 3*60*60))
+		created := time.Date(2030, time.March, 1, 9, 0, 0, 123456000, time.UTC)
+		completed := created.Add(time.Hour)
+
+		input := domain.Todo{
+			Title:       "read the book",
+			Completed:   true,
+			Priority:    domain.PriorityHigh,
+			DueAt:       &due,
+			Recurrence:  &domain.Recurrence{Rule: "FREQ=WEEKLY;BYDAY=MO", Timezone: "Europe/Moscow", Start: due},
+			CreatedAt:   created,
+			UpdatedAt:   completed,
+			CompletedAt: &completed,
+		}
+
+		id := mustSave(t, repo, input)
+
+		got, err := repo.GetByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		input.ID = id
+		input.Version = 1
+		if !got.Equal(input) {
+			t.Errorf("unexpected todo: got %+v, want %+v", got, input)
+		}
+
+		// the offset the due date was given with is kept
+		if got.DueAt == nil || got.DueAt.Format(time.RFC3339) != due.Format(time.RFC3339) {
+			t.Errorf("unexpected due date: got %v, want %v", got.DueAt, due.Format(time.RFC3339))
+		}
+
+		input.Recurrence = nil
+		if err := repo.UpdateByID(context.Background(), id, input); err != nil {
+			t.Fatalf("unexpected error on update: got %v, want nil", err)
+		}
+		got, err = repo.GetByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if got.Recurrence != nil {
+			t.Errorf("unexpected recurrence: got %+v, want none", got.Recurrence)
+		}
+	})
+
+	t.Run("does not reuse ids of deleted todos", func(t *testing.T) {
+		repo := factory(t)
+
+		mustSave(t, repo, domain.Todo{Title: "read the book"})
+		second := mustSave(t, repo, domain.Todo{Title: "complete the game"})
+
+		if err := repo.DeleteByID(context.Background(), second, 0); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+
+		third := mustSave(t, repo, domain.Todo{Title: "get an internship"})
+		if third <= second {
+			t.Errorf("id must grow after delete: got %d, previous %d", third, second)
+		}
+	})
+}
+
+func testGetByID(t *testing.T, factory Factory) {
+	t.Run("todoNotExist -> error", func(t *testing.T) {
+		repo := factory(t)
+
+		_, err := repo.GetByID(context.Background(), 1)
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+}
+
+func testUpdateByID(t *testing.T, factory Factory) {
+	t.Run("replaces fields and keeps id", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		id := mustSave(t, repo, domain.Todo{Title: "read the book", Description: "chapter one"})
+
+		update := domain.Todo{ID: 42, Title: "complete the game", Completed: true}
+		if err := repo.UpdateByID(ctx, id, update); err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		got, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error on get: got %v, want nil", err)
+		}
+
+		want := domain.Todo{ID: id, Title: "complete the game", Completed: true, Version: 2}
+		if !got.Equal(want) {
+			t.Errorf("unexpected todo: got %+v, want %+v", got, want)
+		}
+
+		if _, err := repo.GetByID(ctx, 42); !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("update must not create todo with input id: got %v", err)
+		}
+	})
+
+	t.Run("todoNotExist -> error", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		err := repo.UpdateByID(ctx, 1, domain.Todo{Title: "read the book"})
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+
+		todos, _ := repo.ReadAll(ctx)
+		if len(todos) != 0 {
+			t.Errorf("update must not create todo: got %d todos", len(todos))
+		}
+	})
+}
+
+func testDeleteByID(t *testing.T, factory Factory) {
+	t.Run("success", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+
+		if err := repo.DeleteByID(ctx, id, 0); err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		if _, err := repo.GetByID(ctx, id); !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error on get: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+
+	t.Run("twice -> error", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+		if err := repo.DeleteByID(ctx, id, 0); err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		err := repo.DeleteByID(ctx, id, 0)
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+}
+
+func testQuery(t *testing.T, factory Factory) {
+	base := time.Date(2030, time.March, 10, 9, 0, 0, 0, time.FixedZone("", 3*60*60))
+	at := func(d time.Duration) *time.Time {
+		t := base.Add(d)
+		return &t
+	}
+
+	list := 1
+
+	seed := []domain.Todo{
+		{Title: "read the book", Description: "chapter one", Priority: domain.PriorityHigh, DueAt: at(48 * time.Hour), OwnerID: 7},
+		{Title: "Buy milk", Description: "and BREAD", Completed: true, Priority: domain.PriorityLow, DueAt: at(2 * time.Hour), OwnerID: 8, ListID: &list},
+		{Title: "complete the game", OwnerID: 7, ListID: &list},
+		{Title: "buy the book", Description: "100% off_sale", Completed: true, Priority: domain.PriorityHigh, DueAt: at(2 * time.Hour)},
+		{Title: "walk the dog", Description: "Парк Горького", Priority: domain.PriorityMedium},
+	}
+
+	newRepo := func(t *testing.T) usecase.TodoRepository {
+		repo := factory(t)
+		for _, todo := range seed {
+			mustSave(t, repo, todo)
+		}
+		return repo
+	}
+
+	ids := func(todos []domain.Todo) []int {
+		res := make([]int, 0, len(todos))
+		for _, todo := range todos {
+			res = append(res, todo.ID)
+		}
+		return res
+	}
+
+	yes, no := true, false
+	high := domain.PriorityHigh
+	owner := 7
+
+	tests := []struct {
+		name  string
+		query domain.TodoQuery
+		want  []int
+	}{
+		{name: "empty query returns all by id", query: domain.TodoQuery{}, want: []int{1, 2, 3, 4, 5}},
+		{name: "completed", query: domain.TodoQuery{Filter: domain.TodoFilter{Completed: &yes}}, want: []int{2, 4}},
+		{name: "not completed", query: domain.TodoQuery{Filter: domain.TodoFilter{Completed: &no}}, want: []int{1, 3, 5}},
+		{name: "title substring ignores case", query: domain.TodoQuery{Filter: domain.TodoFilter{Title: "BUY"}}, want: []int{2, 4}},
+		{name: "description substring", query: domain.TodoQuery{Filter: domain.TodoFilter{Description: "bread"}}, want: []int{2}},
+		{name: "non-ascii substring ignores case", query: domain.TodoQuery{Filter: domain.TodoFilter{Description: "горького"}}, want: []int{5}},
+		{name: "non-ascii search ignores case", query: domain.TodoQuery{Filter: domain.TodoFilter{Search: "ПАРК"}}, want: []int{5}},
+		{name: "like wildcards are literal", query: domain.TodoQuery{Filter: domain.TodoFilter{Description: "0% off_"}}, want: []int{4}},
+		{name: "underscore is literal", query: domain.TodoQuery{Filter: domain.TodoFilter{Title: "_"}}, want: []int{}},
+		{name: "search in title or description", query: domain.TodoQuery{Filter: domain.TodoFilter{Search: "book"}}, want: []int{1, 4}},
+		{name: "filters are combined", query: domain.TodoQuery{Filter: domain.TodoFilter{Completed: &no, Search: "the"}}, want: []int{1, 3, 5}},
+		{name: "priority", query: domain.TodoQuery{Filter: domain.TodoFilter{Priority: &high}}, want: []int{1, 4}},
+		{name: "owner", query: domain.TodoQuery{Filter: domain.TodoFilter{OwnerID: &owner}}, want: []int{1, 3}},
+		{name: "list", query: domain.TodoQuery{Filter: domain.TodoFilter{ListID: &list}}, want: []int{2, 3}},
+		{
+			name:  "visible without lists",
+			query: domain.TodoQuery{Filter: domain.TodoFilter{Visible: &domain.Visibility{UserID: owner}}},
+			want:  []int{1},
+		},
+		{
+			name:  "visible with lists",
+			query: domain.TodoQuery{Filter: domain.TodoFilter{Visible: &domain.Visibility{UserID: owner, ListIDs: []int{list, 9}}}},
+			want:  []int{1, 2, 3},
+		},
+		{name: "due from", query: domain.TodoQuery{Filter: domain.TodoFilter{DueFrom: at(3 * time.Hour)}}, want: []int{1}},
+		{name: "due before", query: domain.TodoQuery{Filter: domain.TodoFilter{DueBefore: at(3 * time.Hour)}}, want: []int{2, 4}},
+		{
+			name:  "due range is half-open",
+			query: domain.TodoQuery{Filter: domain.TodoFilter{DueFrom: at(2 * time.Hour), DueBefore: at(48 * time.Hour)}},
+			want:  []int{2, 4},
+		},
+		{name: "id desc", query: domain.TodoQuery{Desc: true}, want: []int{5, 4, 3, 2, 1}},
+		{name: "title asc", query: domain.TodoQuery{Sort: domain.SortByTitle}, want: []int{2, 4, 3, 1, 5}},
+		{name: "title desc", query: domain.TodoQuery{Sort: domain.SortByTitle, Desc: true}, want: []int{5, 1, 3, 4, 2}},
+		{name: "completed asc ties by id", query: domain.TodoQuery{Sort: domain.SortByCompleted}, want: []int{1, 3, 5, 2, 4}},
+		{name: "completed desc ties by id", query: domain.TodoQuery{Sort: domain.SortByCompleted, Desc: true}, want: []int{4, 2, 5, 3, 1}},
+		{name: "priority asc ties by id", query: domain.TodoQuery{Sort: domain.SortByPriority}, want: []int{3, 2, 5, 1, 4}},
+		{name: "priority desc ties by id", query: domain.TodoQuery{Sort: domain.SortByPriority, Desc: true}, want: []int{4, 1, 5, 2, 3}},
+		{name: "due asc missing last", query: domain.TodoQuery{Sort: domain.SortByDue}, want: []int{2, 4, 1, 3, 5}},
+		{name: "due desc missing first", query: domain.TodoQuery{Sort: domain.SortByDue, Desc: true}, want: []int{5, 3, 1, 4, 2}},
+		{name: "limit", query: domain.TodoQuery{Limit: 2}, want: []int{1, 2}},
+		{name: "after id", query: domain.TodoQuery{After: &domain.Todo{ID: 2}, Limit: 2}, want: []int{3, 4}},
+		{name: "after id desc", query: domain.TodoQuery{Desc: true, After: &domain.Todo{ID: 2}}, want: []int{1}},
+		{
+			name:  "after title",
+			query: domain.TodoQuery{Sort: domain.SortByTitle, After: &domain.Todo{ID: 3, Title: "complete the game"}},
+			want:  []int{1, 5},
+		},
+		{
+			name:  "after completed uses id as tie breaker",
+			query: domain.TodoQuery{Sort: domain.SortByCompleted, After: &domain.Todo{ID: 3}},
+			want:  []int{5, 2, 4},
+		},
+		{
+			name:  "after completed desc uses id as tie breaker",
+			query: domain.TodoQuery{Sort: domain.SortByCompleted, Desc: true, After: &domain.Todo{ID: 4, Completed: true}},
+			want:  []int{2, 5, 3, 1},
+		},
+		{
+			name:  "after due",
+			query: domain.TodoQuery{Sort: domain.SortByDue, After: &domain.Todo{ID: 4, DueAt: at(2 * time.Hour)}},
+			want:  []int{1, 3, 5},
+		},
+		{
+			name:  "after missing due",
+			query: domain.TodoQuery{Sort: domain.SortByDue, After: &domain.Todo{ID: 3}},
+			want:  []int{5},
+		},
+		{
+			name:  "after due desc",
+			query: domain.TodoQuery{Sort: domain.SortByDue, Desc: true, After: &domain.Todo{ID: 1, DueAt: at(48 * time.Hour)}},
+			want:  []int{4, 2},
+		},
+		{
+			name:  "after missing due desc",
+			query: domain.TodoQuery{Sort: domain.SortByDue, Desc: true, After: &domain.Todo{ID: 5}},
+			want:  []int{3, 1, 4, 2},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRepo(t)
+
+			got, err := repo.Query(context.Background(), tc.query)
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+
+			if !slices.Equal(ids(got), tc.want) {
+				t.Errorf("unexpected ids: got %v, want %v", ids(got), tc.want)
+			}
+		})
+	}
+
+	t.Run("pages cover everything exactly once", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+
+		for _, sort := range []domain.TodoSort{
+			domain.SortByID, domain.SortByTitle, domain.SortByCompleted, domain.SortByPriority, domain.SortByDue,
+		} {
+			for _, desc := range []bool{false, true} {
+				query := domain.TodoQuery{Sort: sort, Desc: desc}
+
+				all, err := repo.Query(ctx, query)
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+
+				var paged []domain.Todo
+				query.Limit = 2
+				for {
+					page, err := repo.Query(ctx, query)
+					if err != nil {
+						t.Fatalf("unexpected error: got %v, want nil", err)
+					}
+					paged = append(paged, page...)
+					if len(page) < query.Limit {
+						break
+					}
+					pivot := sort.Pivot(page[len(page)-1])
+					query.After = &pivot
+				}
+
+				if !slices.Equal(ids(paged), ids(all)) {
+					t.Errorf("sort %s desc %t: pages %v, want %v", sort, desc, ids(paged), ids(all))
+				}
+			}
+		}
+	})
+}
+
+func testVersioning(t *testing.T, factory Factory) {
+	t.Run("save starts at 1 and update increments", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		id := mustSave(t, repo, domain.Todo{Title: "read the book", Version: 7})
+
+		got, _ := repo.GetByID(ctx, id)
+		if got.Version != 1 {
+			t.Fatalf("unexpected version after save: got %d, want %d", got.Version, 1)
+		}
+
+		for want := 2; want <= 3; want++ {
+			if err := repo.UpdateByID(ctx, id, domain.Todo{Title: "read the book"}); err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+
+			got, _ := repo.GetByID(ctx, id)
+			if got.Version != want {
+				t.Errorf("unexpected version: got %d, want %d", got.Version, want)
+			}
+		}
+	})
+
+	t.Run("update with matching version", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+
+		err := repo.UpdateByID(ctx, id, domain.Todo{Title: "complete the game", Version: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		got, _ := repo.GetByID(ctx, id)
+		if got.Title != "complete the game" || got.Version != 2 {
+			t.Errorf("unexpected todo: got %+v", got)
+		}
+	})
+
+	t.Run("update with stale version -> error", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+		if err := repo.UpdateByID(ctx, id, domain.Todo{Title: "first writer", Version: 1}); err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		err := repo.UpdateByID(ctx, id, domain.Todo{Title: "second writer", Version: 1})
+		if !errors.Is(err, domain.ErrVersionMismatch) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrVersionMismatch)
+		}
+
+		got, _ := repo.GetByID(ctx, id)
+		if got.Title != "first writer" || got.Version != 2 {
+			t.Errorf("stale update must not be applied: got %+v", got)
+		}
+	})
+
+	t.Run("update missing todo with version -> not exist", func(t *testing.T) {
+		repo := factory(t)
+
+		err := repo.UpdateByID(context.Background(), 1, domain.Todo{Title: "read the book", Version: 1})
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+
+	t.Run("delete with stale version -> error", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+		if err := repo.UpdateByID(ctx, id, domain.Todo{Title: "complete the game"}); err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		err := repo.DeleteByID(ctx, id, 1)
+		if !errors.Is(err, domain.ErrVersionMismatch) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrVersionMismatch)
+		}
+
+		if err := repo.DeleteByID(ctx, id, 2); err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+	})
+
+	t.Run("concurrent updates with the same version", func(t *testing.T) {
+		const writers = 8
+
+		repo := factory(t)
+		ctx := context.Background()
+
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			succeeded int
+		)
+
+		for w := range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				err := repo.UpdateByID(ctx, id, domain.Todo{Title: fmt.Sprintf("writer %d", w), Version: 1})
+				if err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				} else if !errors.Is(err, domain.ErrVersionMismatch) {
+					t.Errorf("unexpected error: got %v, want %v", err, domain.ErrVersionMismatch)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if succeeded != 1 {
+			t.Errorf("exactly one writer must win: got %d", succeeded)
+		}
+	})
+}
+
+func testTags(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("save, get, update and list", func(t *testing.T) {
+		repo := factory(t)
+
+		all, err := repo.ReadAllTags(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if all == nil || len(all) != 0 {
+			t.Fatalf("unexpected tags of empty repository: got %#v, want empty slice", all)
+		}
+
+		work, err := repo.SaveTag(ctx, domain.Tag{ID: 42, Name: "work", Color: "#ff0000"})
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		home := mustSaveTag(t, repo, "home")
+		if work != 1 || home != 2 {
+			t.Fatalf("unexpected ids: got %d and %d, want 1 and 2", work, home)
+		}
+
+		if err := repo.UpdateTagByID(ctx, home, domain.Tag{Name: "house"}); err != nil {
+			t.Fatalf("unexpected error on update: got %v, want nil", err)
+		}
+
+		got, err := repo.GetTagByID(ctx, work)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if want := (domain.Tag{ID: work, Name: "work", Color: "#ff0000"}); got != want {
+			t.Errorf("unexpected tag: got %+v, want %+v", got, want)
+		}
+
+		all, err = repo.ReadAllTags(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		want := []domain.Tag{{ID: work, Name: "work", Color: "#ff0000"}, {ID: home, Name: "house"}}
+		if !slices.Equal(all, want) {
+			t.Errorf("unexpected tags: got %+v, want %+v", all, want)
+		}
+	})
+
+	t.Run("missing tag -> error", func(t *testing.T) {
+		repo := factory(t)
+
+		if _, err := repo.GetTagByID(ctx, 1); !errors.Is(err, domain.ErrTagNotExist) {
+			t.Errorf("unexpected error on get: got %v, want %v", err, domain.ErrTagNotExist)
+		}
+		if err := repo.UpdateTagByID(ctx, 1, domain.Tag{Name: "work"}); !errors.Is(err, domain.ErrTagNotExist) {
+			t.Errorf("unexpected error on update: got %v, want %v", err, domain.ErrTagNotExist)
+		}
+		if err := repo.DeleteTagByID(ctx, 1); !errors.Is(err, domain.ErrTagNotExist) {
+			t.Errorf("unexpected error on delete: got %v, want %v", err, domain.ErrTagNotExist)
+		}
+	})
+
+	t.Run("names are unique regardless of case", func(t *testing.T) {
+		repo := factory(t)
+		mustSaveTag(t, repo, "Work")
+		home := mustSaveTag(t, repo, "home")
+
+		if _, err := repo.SaveTag(ctx, domain.Tag{Name: "wORK"}); !errors.Is(err, domain.ErrTagExists) {
+			t.Errorf("unexpected error on save: got %v, want %v", err, domain.ErrTagExists)
+		}
+		if err := repo.UpdateTagByID(ctx, home, domain.Tag{Name: "work"}); !errors.Is(err, domain.ErrTagExists) {
+			t.Errorf("unexpected error on update: got %v, want %v", err, domain.ErrTagExists)
+		}
+
+		// renaming a tag to another case of its own name is fine
+		if err := repo.UpdateTagByID(ctx, home, domain.Tag{Name: "Home"}); err != nil {
+			t.Errorf("unexpected error on rename: got %v, want nil", err)
+		}
+	})
+
+	t.Run("todo tags are sorted and deduplicated", func(t *testing.T) {
+		repo := factory(t)
+		work := mustSaveTag(t, repo, "work")
+		home := mustSaveTag(t, repo, "home")
+
+		id := mustSave(t, repo, domain.Todo{Title: "read the book", Tags: []int{home, work, home}})
+
+		got, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if want := []int{work, home}; !slices.Equal(got.Tags, want) {
+			t.Errorf("unexpected tags: got %v, want %v", got.Tags, want)
+		}
+
+		if err := repo.UpdateByID(ctx, id, domain.Todo{Title: "read the book", Tags: []int{home}}); err != nil {
+			t.Fatalf("unexpected error on update: got %v, want nil", err)
+		}
+
+		todos, err := repo.ReadAll(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if want := []int{home}; len(todos) != 1 || !slices.Equal(todos[0].Tags, want) {
+			t.Errorf("unexpected todos: got %+v, want tags %v", todos, want)
+		}
+	})
+
+	t.Run("unknown tag on a todo -> error", func(t *testing.T) {
+		repo := factory(t)
+		work := mustSaveTag(t, repo, "work")
+
+		if _, err := repo.Save(ctx, domain.Todo{Title: "read the book", Tags: []int{work, 99}}); !errors.Is(err, domain.ErrTagNotExist) {
+			t.Fatalf("unexpected error on save: got %v, want %v", err, domain.ErrTagNotExist)
+		}
+
+		id := mustSave(t, repo, domain.Todo{Title: "read the book", Tags: []int{work}})
+		if err := repo.UpdateByID(ctx, id, domain.Todo{Title: "complete the game", Tags: []int{99}}); !errors.Is(err, domain.ErrTagNotExist) {
+			t.Fatalf("unexpected error on update: got %v, want %v", err, domain.ErrTagNotExist)
+		}
+
+		got, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if got.Title != "read the book" || got.Version != 1 || !slices.Equal(got.Tags, []int{work}) {
+			t.Errorf("failed update must not change the todo: got %+v", got)
+		}
+	})
+
+	t.Run("delete cascades to todos", func(t *testing.T) {
+		repo := factory(t)
+		work := mustSaveTag(t, repo, "work")
+		home := mustSaveTag(t, repo, "home")
+
+		tagged := mustSave(t, repo, domain.Todo{Title: "read the book", Tags: []int{work, home}})
+		other := mustSave(t, repo, domain.Todo{Title: "complete the game", Tags: []int{home}})
+
+		if err := repo.DeleteTagByID(ctx, work); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+
+		if _, err := repo.GetTagByID(ctx, work); !errors.Is(err, domain.ErrTagNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrTagNotExist)
+		}
+
+		got, err := repo.GetByID(ctx, tagged)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if !slices.Equal(got.Tags, []int{home}) || got.Version != 2 {
+			t.Errorf("tag must be detached and version bumped: got tags %v version %d", got.Tags, got.Version)
+		}
+
+		got, err = repo.GetByID(ctx, other)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if !slices.Equal(got.Tags, []int{home}) || got.Version != 1 {
+			t.Errorf("untouched todo must keep its version: got tags %v version %d", got.Tags, got.Version)
+		}
+
+		// the name is free again
+		if _, err := repo.SaveTag(ctx, domain.Tag{Name: "work"}); err != nil {
+			t.Errorf("unexpected error on save: got %v, want nil", err)
+		}
+	})
+
+	t.Run("query by tags", func(t *testing.T) {
+		repo := factory(t)
+		work := mustSaveTag(t, repo, "work")
+		home := mustSaveTag(t, repo, "home")
+		urgent := mustSaveTag(t, repo, "urgent")
+
+		mustSave(t, repo, domain.Todo{Title: "read the book", Tags: []int{home}})
+		mustSave(t, repo, domain.Todo{Title: "write the report", Tags: []int{work, urgent}})
+		mustSave(t, repo, domain.Todo{Title: "complete the game"})
+		mustSave(t, repo, domain.Todo{Title: "fix the roof", Tags: []int{home, urgent}})
+
+		tests := []struct {
+			name   string
+			filter domain.TodoFilter
+			want   []int
+		}{
+			{name: "any", filter: domain.TodoFilter{Tags: []int{home, work}}, want: []int{1, 2, 4}},
+			{name: "all", filter: domain.TodoFilter{Tags: []int{home, urgent}, AllTags: true}, want: []int{4}},
+			{name: "all with duplicates", filter: domain.TodoFilter{Tags: []int{urgent, urgent}, AllTags: true}, want: []int{2, 4}},
+			{name: "all without a match", filter: domain.TodoFilter{Tags: []int{home, work}, AllTags: true}, want: []int{}},
+			{name: "combined with other filters", filter: domain.TodoFilter{Tags: []int{urgent}, Search: "roof"}, want: []int{4}},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				got, err := repo.Query(ctx, domain.TodoQuery{Filter: tc.filter})
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+
+				ids := make([]int, 0, len(got))
+				for _, todo := range got {
+					ids = append(ids, todo.ID)
+				}
+				if !slices.Equal(ids, tc.want) {
+					t.Errorf("unexpected ids: got %v, want %v", ids, tc.want)
+				}
+			})
+		}
+	})
+}
+
+func testSubtasks(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("stores parent and checklist", func(t *testing.T) {
+		repo := factory(t)
+		parent := mustSave(t, repo, domain.Todo{Title: "move out"})
+
+		input := domain.Todo{
+			Title:    "pack the books",
+			ParentID: &parent,
+			Checklist: []domain.ChecklistItem{
+				{Title: "buy boxes", Done: true},
+				{Title: "label boxes"},
+			},
+		}
+		id := mustSave(t, repo, input)
+
+		got, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		input.ID = id
+		input.Version = 1
+		if !got.Equal(input) {
+			t.Errorf("unexpected todo: got %+v, want %+v", got, input)
+		}
+
+		// moving a subtask to the top level
+		input.ParentID = nil
+		input.Checklist = nil
+		if err := repo.UpdateByID(ctx, id, input); err != nil {
+			t.Fatalf("unexpected error on update: got %v, want nil", err)
+		}
+		got, err = repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if got.ParentID != nil || len(got.Checklist) != 0 {
+			t.Errorf("unexpected todo: got parent %v checklist %v, want none", got.ParentID, got.Checklist)
+		}
+	})
+
+	t.Run("unknown parent -> error", func(t *testing.T) {
+		repo := factory(t)
+		missing := 42
+
+		if _, err := repo.Save(ctx, domain.Todo{Title: "pack the books", ParentID: &missing}); !errors.Is(err, domain.ErrParentNotExist) {
+			t.Fatalf("unexpected error on save: got %v, want %v", err, domain.ErrParentNotExist)
+		}
+
+		id := mustSave(t, repo, domain.Todo{Title: "pack the books"})
+		if err := repo.UpdateByID(ctx, id, domain.Todo{Title: "pack the books", ParentID: &missing}); !errors.Is(err, domain.ErrParentNotExist) {
+			t.Fatalf("unexpected error on update: got %v, want %v", err, domain.ErrParentNotExist)
+		}
+	})
+
+	t.Run("cycles are rejected", func(t *testing.T) {
+		repo := factory(t)
+		root := mustSave(t, repo, domain.Todo{Title: "move out"})
+		child := mustSave(t, repo, domain.Todo{Title: "pack", ParentID: &root})
+		grandchild := mustSave(t, repo, domain.Todo{Title: "pack the books", ParentID: &child})
+
+		for _, parent := range []int{root, grandchild} {
+			err := repo.UpdateByID(ctx, root, domain.Todo{Title: "move out", ParentID: &parent})
+			if !errors.Is(err, domain.ErrParentCycle) {
+				t.Errorf("parent %d: unexpected error: got %v, want %v", parent, err, domain.ErrParentCycle)
+			}
+		}
+
+		got, err := repo.GetByID(ctx, root)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if got.ParentID != nil || got.Version != 1 {
+			t.Errorf("failed update must not change the todo: got %+v", got)
+		}
+
+		// moving a subtask under its sibling is fine
+		sibling := mustSave(t, repo, domain.Todo{Title: "clean", ParentID: &root})
+		if err := repo.UpdateByID(ctx, grandchild, domain.Todo{Title: "pack the books", ParentID: &sibling}); err != nil {
+			t.Errorf("unexpected error: got %v, want nil", err)
+		}
+	})
+
+	t.Run("query by parent", func(t *testing.T) {
+		repo := factory(t)
+		root := mustSave(t, repo, domain.Todo{Title: "move out"})
+		first := mustSave(t, repo, domain.Todo{Title: "pack", ParentID: &root})
+		mustSave(t, repo, domain.Todo{Title: "pack the books", ParentID: &first})
+		second := mustSave(t, repo, domain.Todo{Title: "clean", ParentID: &root, Completed: true})
+
+		got, err := repo.Query(ctx, domain.TodoQuery{Filter: domain.TodoFilter{ParentID: &root}})
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		ids := make([]int, 0, len(got))
+		for _, todo := range got {
+			ids = append(ids, todo.ID)
+		}
+		if want := []int{first, second}; !slices.Equal(ids, want) {
+			t.Errorf("unexpected ids: got %v, want %v", ids, want)
+		}
+	})
+
+	t.Run("delete removes subtasks at any depth", func(t *testing.T) {
+		repo := factory(t)
+		root := mustSave(t, repo, domain.Todo{Title: "move out"})
+		child := mustSave(t, repo, domain.Todo{Title: "pack", ParentID: &root})
+		mustSave(t, repo, domain.Todo{Title: "pack the books", ParentID: &child})
+		other := mustSave(t, repo, domain.Todo{Title: "read the book"})
+
+		if err := repo.DeleteByID(ctx, root, 0); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+
+		todos, err := repo.ReadAll(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(todos) != 1 || todos[0].ID != other {
+			t.Errorf("unexpected todos left: got %+v, want only %d", todos, other)
+		}
+	})
+}
+
+func testReadAll(t *testing.T, factory Factory) {
+	t.Run("empty", func(t *testing.T) {
+		repo := factory(t)
+
+		todos, err := repo.ReadAll(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(todos) != 0 {
+			t.Errorf("unexpected length: got %d, want %d", len(todos), 0)
+		}
+	})
+
+	t.Run("ordered by id", func(t *testing.T) {
+		repo := factory(t)
+		ctx := context.Background()
+
+		for i := range 10 {
+			mustSave(t, repo, domain.Todo{Title: fmt.Sprintf("todo %d", i)})
+		}
+		if err := repo.DeleteByID(ctx, 4, 0); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+		if err := repo.UpdateByID(ctx, 2, domain.Todo{Title: "updated"}); err != nil {
+			t.Fatalf("unexpected error on update: got %v, want nil", err)
+		}
+
+		todos, err := repo.ReadAll(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		if len(todos) != 9 {
+			t.Fatalf("unexpected length: got %d, want %d", len(todos), 9)
+		}
+		for i := 1; i < len(todos); i++ {
+			if todos[i-1].ID >= todos[i].ID {
+				t.Errorf("todos are not ordered by id: %d before %d", todos[i-1].ID, todos[i].ID)
+			}
+		}
+	})
+}
+
+func testContextCanceled(t *testing.T, factory Factory) {
+	canceled := func() context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx
+	}
+
+	t.Run("Save", func(t *testing.T) {
+		repo := factory(t)
+
+		_, err := repo.Save(canceled(), domain.Todo{Title: "read the book"})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error: got %v, want %v", err, context.Canceled)
+		}
+
+		todos, _ := repo.ReadAll(context.Background())
+		if len(todos) != 0 {
+			t.Errorf("must not save todo when context is canceled: got %d todos", len(todos))
+		}
+
+		if id := mustSave(t, repo, domain.Todo{Title: "read the book"}); id != 1 {
+			t.Errorf("canceled save must not consume an id: got %d, want %d", id, 1)
+		}
+	})
+
+	t.Run("GetByID", func(t *testing.T) {
+		repo := factory(t)
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+
+		_, err := repo.GetByID(canceled(), id)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error: got %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("UpdateByID", func(t *testing.T) {
+		repo := factory(t)
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+
+		err := repo.UpdateByID(canceled(), id, domain.Todo{Title: "complete the game"})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error: got %v, want %v", err, context.Canceled)
+		}
+
+		got, _ := repo.GetByID(context.Background(), id)
+		if got.Title != "read the book" {
+			t.Errorf("must not update todo when context is canceled: got %q", got.Title)
+		}
+	})
+
+	t.Run("DeleteByID", func(t *testing.T) {
+		repo := factory(t)
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+
+		err := repo.DeleteByID(canceled(), id, 0)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error: got %v, want %v", err, context.Canceled)
+		}
+
+		if _, err := repo.GetByID(context.Background(), id); err != nil {
+			t.Errorf("must not delete todo when context is canceled: got %v", err)
+		}
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		repo := factory(t)
+
+		_, err := repo.Query(canceled(), domain.TodoQuery{})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error: got %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("ReadAll", func(t *testing.T) {
+		repo := factory(t)
+
+		_, err := repo.ReadAll(canceled())
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error: got %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func testConcurrentWriters(t *testing.T, factory Factory) {
+	const writers = 8
+	const perWriter = 10
+
+	repo := factory(t)
+	ctx := context.Background()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = map[int]bool{}
+	)
+
+	errCh := make(chan error, writers*perWriter)
+
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range perWriter {
+				id, err := repo.Save(ctx, domain.Todo{Title: fmt.Sprintf("writer %d todo %d", w, i)})
+				if err != nil {
+					errCh <- err
+					continue
+				}
+
+				mu.Lock()
+				if seen[id] {
+					errCh <- fmt.Errorf("id %d handed out twice", id)
+				}
+				seen[id] = true
+				mu.Unlock()
+
+				if err := repo.UpdateByID(ctx, id, domain.Todo{Title: "done", Completed: true}); err != nil {
+					errCh <- err
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		t.Errorf("unexpected error from writer: %v", err)
+	}
+
+	todos, err := repo.ReadAll(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	if len(todos) != writers*perWriter {
+		t.Errorf("unexpected length: got %d, want %d", len(todos), writers*perWriter)
+	}
+	for _, todo := range todos {
+		if !todo.Completed {
+			t.Errorf("todo %d lost its update", todo.ID)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+// TenantRepository stores everything workspaces keep apart.
+type TenantRepository interface {
+	usecase.TodoRepository
+	usecase.UserRepository
+	usecase.ListRepository
+}
+
+// TenantFactory returns an empty repository.
+type TenantFactory func(t *testing.T) TenantRepository
+
+// RunTenantConformance checks that a repository scopes every call to the
+// workspace of the context: nothing stored in one workspace can be read,
+// changed or referenced from another.
+func RunTenantConformance(t *testing.T, factory TenantFactory) {
+	t.Run("Todos", func(t *testing.T) { testTenantTodos(t, factory) })
+	t.Run("Tags", func(t *testing.T) { testTenantTags(t, factory) })
+	t.Run("Users", func(t *testing.T) { testTenantUsers(t, factory) })
+	t.Run("Lists", func(t *testing.T) { testTenantLists(t, factory) })
+	t.Run("DefaultTenant", func(t *testing.T) { testDefaultTenant(t, factory) })
+}
+
+var (
+	acme   = domain.ContextWithTenant(context.Background(), "acme")
+	globex = domain.ContextWithTenant(context.Background(), "globex")
+)
+
+// saveIn stores the todos in the workspace of ctx and returns their ids.
+func saveIn(t *testing.T, ctx context.Context, repo usecase.TodoRepository, titles ...string) []int {
+	t.Helper()
+
+	ids := make([]int, 0, len(titles))
+	for _, title := range titles {
+		id, err := repo.Save(ctx, domain.Todo{Title: title})
+		if err != nil {
+			t.Fatalf("unexpected error on save: got %v, want nil", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// foreignID returns an id used in the first workspace only. Ids may be
+// sequenced per workspace, so the same id can exist in both.
+func foreignID(t *testing.T, ids []int, other []int) int {
+	t.Helper()
+
+	for i := len(ids) - 1; i >= 0; i-- {
+		if !slices.Contains(other, ids[i]) {
+			return ids[i]
+		}
+	}
+	t.Fatalf("every id of %v is used in the other workspace too: %v", ids, other)
+	return 0
+}
+
+func testTenantTodos(t *testing.T, factory TenantFactory) {
+	repo := factory(t)
+	acmeIDs := saveIn(t, acme, repo, "acme plan", "acme secret")
+	globexIDs := saveIn(t, globex, repo, "globex plan")
+	id := foreignID(t, acmeIDs, globexIDs)
+
+	t.Run("get from another tenant -> error", func(t *testing.T) {
+		_, err := repo.GetByID(globex, id)
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+
+	t.Run("update from another tenant -> error", func(t *testing.T) {
+		err := repo.UpdateByID(globex, id, domain.Todo{Title: "stolen", Version: 1})
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+		err = repo.UpdateByID(globex, id, domain.Todo{Title: "stolen"})
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error without version: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+
+	t.Run("delete from another tenant -> error", func(t *testing.T) {
+		err := repo.DeleteByID(globex, id, 0)
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+
+	t.Run("subtask of a todo of another tenant -> error", func(t *testing.T) {
+		_, err := repo.Save(globex, domain.Todo{Title: "child", ParentID: &id})
+		if !errors.Is(err, domain.ErrParentNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrParentNotExist)
+		}
+	})
+
+	t.Run("owner keeps the todo unchanged", func(t *testing.T) {
+		got, err := repo.GetByID(acme, id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if got.Title != "acme secret" || got.Version != 1 {
+			t.Errorf("unexpected todo: got %q version %d, want %q version %d", got.Title, got.Version, "acme secret", 1)
+		}
+	})
+
+	t.Run("lists see their own tenant only", func(t *testing.T) {
+		all, err := repo.ReadAll(globex)
+		if err != nil {
+			t.Fatalf("unexpected error on read all: got %v, want nil", err)
+		}
+		queried, err := repo.Query(globex, domain.TodoQuery{})
+		if err != nil {
+			t.Fatalf("unexpected error on query: got %v, want nil", err)
+		}
+		searched, err := repo.Query(globex, domain.TodoQuery{Filter: domain.TodoFilter{Search: "acme"}})
+		if err != nil {
+			t.Fatalf("unexpected error on search: got %v, want nil", err)
+		}
+
+		for _, got := range [][]domain.Todo{all, queried} {
+			if len(got) != 1 || got[0].Title != "globex plan" {
+				t.Errorf("unexpected todos: got %+v, want only %q", got, "globex plan")
+			}
+		}
+		if len(searched) != 0 {
+			t.Errorf("unexpected search result: got %+v, want none", searched)
+		}
+	})
+}
+
+func testTenantTags(t *testing.T, factory TenantFactory) {
+	repo := factory(t)
+
+	var acmeTags []int
+	for _, name := range []string{"work", "home"} {
+		id, err := repo.SaveTag(acme, domain.Tag{Name: name})
+		if err != nil {
+			t.Fatalf("unexpected error on save tag: got %v, want nil", err)
+		}
+		acmeTags = append(acmeTags, id)
+	}
+
+	// names are unique within a tenant only
+	globexTag, err := repo.SaveTag(globex, domain.Tag{Name: "Home"})
+	if err != nil {
+		t.Fatalf("unexpected error on save tag with a name of another tenant: got %v, want nil", err)
+	}
+	id := foreignID(t, acmeTags, []int{globexTag})
+
+	t.Run("get from another tenant -> error", func(t *testing.T) {
+		_, err := repo.GetTagByID(globex, id)
+		if !errors.Is(err, domain.ErrTagNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrTagNotExist)
+		}
+	})
+
+	t.Run("update and delete from another tenant -> error", func(t *testing.T) {
+		err := repo.UpdateTagByID(globex, id, domain.Tag{Name: "stolen"})
+		if !errors.Is(err, domain.ErrTagNotExist) {
+			t.Errorf("unexpected error on update: got %v, want %v", err, domain.ErrTagNotExist)
+		}
+		err = repo.DeleteTagByID(globex, id)
+		if !errors.Is(err, domain.ErrTagNotExist) {
+			t.Errorf("unexpected error on delete: got %v, want %v", err, domain.ErrTagNotExist)
+		}
+	})
+
+	t.Run("tagging with a tag of another tenant -> error", func(t *testing.T) {
+		_, err := repo.Save(globex, domain.Todo{Title: "tagged", Tags: []int{id}})
+		if !errors.Is(err, domain.ErrTagNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrTagNotExist)
+		}
+	})
+
+	t.Run("tags of each tenant are listed apart", func(t *testing.T) {
+		acmeGot, err := repo.ReadAllTags(acme)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		globexGot, err := repo.ReadAllTags(globex)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		if len(acmeGot) != 2 || acmeGot[1].Name != "home" {
+			t.Errorf("unexpected acme tags: got %+v", acmeGot)
+		}
+		if len(globexGot) != 1 || globexGot[0].Name != "Home" {
+			t.Errorf("unexpected globex tags: got %+v", globexGot)
+		}
+	})
+}
+
+func testTenantUsers(t *testing.T, factory TenantFactory) {
+	repo := factory(t)
+	created := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	// the same email signs up in both tenants
+	var ids []int
+	for _, ctx := range []context.Context{acme, globex} {
+		id, err := repo.SaveUser(ctx, domain.User{Email: "ann@example.com", PasswordHash: "x", CreatedAt: created})
+		if err != nil {
+			t.Fatalf("unexpected error on save user: got %v, want nil", err)
+		}
+		ids = append(ids, id)
+	}
+
+	session := domain.Session{ID: "acme-session", UserID: ids[0], CreatedAt: created, ExpiresAt: created.Add(time.Hour)}
+	if err := repo.SaveSession(acme, session); err != nil {
+		t.Fatalf("unexpected error on save session: got %v, want nil", err)
+	}
+	key := domain.APIKey{UserID: ids[0], Name: "ci", Prefix: "todo_abcdefgh", Hash: "acme-hash", Scopes: domain.AllScopes, CreatedAt: created}
+	keyID, err := repo.SaveAPIKey(acme, key)
+	if err != nil {
+		t.Fatalf("unexpected error on save api key: got %v, want nil", err)
+	}
+
+	t.Run("each tenant finds its own user", func(t *testing.T) {
+		for i, ctx := range []context.Context{acme, globex} {
+			got, err := repo.GetUserByEmail(ctx, "ann@example.com")
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			if got.ID != ids[i] {
+				t.Errorf("unexpected user id: got %d, want %d", got.ID, ids[i])
+			}
+		}
+
+		other := domain.ContextWithTenant(context.Background(), "initech")
+		if _, err := repo.GetUserByEmail(other, "ann@example.com"); !errors.Is(err, domain.ErrUserNotExist) {
+			t.Errorf("unexpected error in a third tenant: got %v, want %v", err, domain.ErrUserNotExist)
+		}
+	})
+
+	t.Run("session of another tenant -> error", func(t *testing.T) {
+		if _, err := repo.GetSession(globex, session.ID); !errors.Is(err, domain.ErrSessionNotExist) {
+			t.Errorf("unexpected error on get: got %v, want %v", err, domain.ErrSessionNotExist)
+		}
+		if err := repo.DeleteSession(globex, session.ID); !errors.Is(err, domain.ErrSessionNotExist) {
+			t.Errorf("unexpected error on delete: got %v, want %v", err, domain.ErrSessionNotExist)
+		}
+		if _, err := repo.GetSession(acme, session.ID); err != nil {
+			t.Errorf("unexpected error in the own tenant: got %v, want nil", err)
+		}
+	})
+
+	t.Run("api key of another tenant -> error", func(t *testing.T) {
+		if _, err := repo.GetAPIKeyByHash(globex, key.Hash); !errors.Is(err, domain.ErrAPIKeyNotExist) {
+			t.Errorf("unexpected error on get: got %v, want %v", err, domain.ErrAPIKeyNotExist)
+		}
+		if err := repo.DeleteAPIKey(globex, ids[0], keyID); !errors.Is(err, domain.ErrAPIKeyNotExist) {
+			t.Errorf("unexpected error on delete: got %v, want %v", err, domain.ErrAPIKeyNotExist)
+		}
+		if _, err := repo.GetAPIKeyByHash(acme, key.Hash); err != nil {
+			t.Errorf("unexpected error in the own tenant: got %v, want nil", err)
+		}
+	})
+}
+
+func testTenantLists(t *testing.T, factory TenantFactory) {
+	repo := factory(t)
+	created := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+	list := domain.List{
+		Name:        "home",
+		Members:     []domain.Member{{UserID: 1, Email: "ann@example.com", Role: domain.RoleOwner, JoinedAt: created}},
+		Invitations: []domain.Invitation{{Email: "bob@example.com", Role: domain.RoleViewer, InvitedBy: 1, CreatedAt: created}},
+		CreatedAt:   created,
+	}
+
+	id, err := repo.SaveList(acme, list)
+	if err != nil {
+		t.Fatalf("unexpected error on save list: got %v, want nil", err)
+	}
+
+	if _, err := repo.GetListByID(globex, id); !errors.Is(err, domain.ErrListNotExist) {
+		t.Errorf("unexpected error on get: got %v, want %v", err, domain.ErrListNotExist)
+	}
+	renamed := list
+	renamed.Name, renamed.Version = "stolen", 1
+	if err := repo.UpdateListByID(globex, id, renamed); !errors.Is(err, domain.ErrListNotExist) {
+		t.Errorf("unexpected error on update: got %v, want %v", err, domain.ErrListNotExist)
+	}
+	if err := repo.DeleteListByID(globex, id); !errors.Is(err, domain.ErrListNotExist) {
+		t.Errorf("unexpected error on delete: got %v, want %v", err, domain.ErrListNotExist)
+	}
+
+	byMember, err := repo.ListsByMember(globex, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	byInvitee, err := repo.ListsByInvitee(globex, "bob@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	if len(byMember) != 0 || len(byInvitee) != 0 {
+		t.Errorf("unexpected lists of another tenant: got %+v and %+v, want none", byMember, byInvitee)
+	}
+
+	got, err := repo.GetListByID(acme, id)
+	if err != nil {
+		t.Fatalf("unexpected error in the own tenant: got %v, want nil", err)
+	}
+	if got.Name != "home" || len(got.Members) != 1 || len(got.Invitations) != 1 {
+		t.Errorf("unexpected list: got %+v", got)
+	}
+}
+
+// testDefaultTenant checks that a context naming no tenant, as in
+// background jobs and data stored before tenants existed, uses the default.
+func testDefaultTenant(t *testing.T, factory TenantFactory) {
+	repo := factory(t)
+	id := mustSave(t, repo, domain.Todo{Title: "legacy"})
+
+	named := domain.ContextWithTenant(context.Background(), domain.DefaultTenant)
+	got, err := repo.GetByID(named, id)
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	if got.Title != "legacy" {
+		t.Errorf("unexpected title: got %q, want %q", got.Title, "legacy")
+	}
+
+	if _, err := repo.GetByID(acme, id); !errors.Is(err, domain.ErrTodoNotExist) {
+		t.Errorf("unexpected error in another tenant: got %v, want %v", err, domain.ErrTodoNotExist)
+	}
+}
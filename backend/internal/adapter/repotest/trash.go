@@ -0,0 +1,355 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+// TrashRepository stores the todos and keeps the deleted ones.
+type TrashRepository interface {
+	usecase.TodoRepository
+	usecase.TrashRepository
+}
+
+// TrashFactory returns an empty trash repository.
+type TrashFactory func(t *testing.T) TrashRepository
+
+// RunTrashConformance checks the behaviour every usecase.TrashRepository
+// implementation must share.
+func RunTrashConformance(t *testing.T, factory TrashFactory) {
+	t.Run("TrashByID", func(t *testing.T) { testTrashByID(t, factory) })
+	t.Run("RestoreByID", func(t *testing.T) { testRestoreByID(t, factory) })
+	t.Run("Purge", func(t *testing.T) { testPurge(t, factory) })
+	t.Run("Tenants", func(t *testing.T) { testTrashTenants(t, factory) })
+}
+
+var deletedAt = time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+func mustTrash(t *testing.T, ctx context.Context, repo TrashRepository, id int, at time.Time) {
+	t.Helper()
+
+	if err := repo.TrashByID(ctx, id, 0, at); err != nil {
+		t.Fatalf("unexpected error on trash: got %v, want nil", err)
+	}
+}
+
+func trashIDs(t *testing.T, ctx context.Context, repo TrashRepository) []int {
+	t.Helper()
+
+	entries, err := repo.ReadTrash(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error on read trash: got %v, want nil", err)
+	}
+
+	ids := []int{}
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+func testTrashByID(t *testing.T, factory TrashFactory) {
+	ctx := context.Background()
+
+	t.Run("moves the todo and its subtasks", func(t *testing.T) {
+		// preparing
+		repo := factory(t)
+		tagID := mustSaveTag(t, repo, "work")
+		id := mustSave(t, repo, domain.Todo{Title: "move", Tags: []int{tagID}})
+		childID := mustSave(t, repo, domain.Todo{Title: "pack", ParentID: &id})
+		grandchildID := mustSave(t, repo, domain.Todo{Title: "buy boxes", ParentID: &childID})
+		otherID := mustSave(t, repo, domain.Todo{Title: "read the book"})
+
+		// act
+		err := repo.TrashByID(ctx, id, 1, deletedAt)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		for _, v := range []int{id, childID, grandchildID} {
+			if _, err := repo.GetByID(ctx, v); !errors.Is(err, domain.ErrTodoNotExist) {
+				t.Errorf("todo %d must be gone: got %v, want %v", v, err, domain.ErrTodoNotExist)
+			}
+		}
+		if _, err := repo.GetByID(ctx, otherID); err != nil {
+			t.Errorf("other todo must stay: got %v, want nil", err)
+		}
+
+		entry, err := repo.GetTrashedByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error on get trashed: got %v, want nil", err)
+		}
+		if entry.Title != "move" || !slices.Equal(entry.Tags, []int{tagID}) || !entry.DeletedAt.Equal(deletedAt) {
+			t.Errorf("unexpected entry: got %+v", entry)
+		}
+		if len(entry.Subtasks) != 2 || entry.Subtasks[0].ID != childID || entry.Subtasks[1].ID != grandchildID {
+			t.Errorf("subtasks must follow their parents: got %+v", entry.Subtasks)
+		}
+
+		// subtasks are part of the entry of their parent
+		if _, err := repo.GetTrashedByID(ctx, childID); !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error for a subtask: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+		if got := trashIDs(t, ctx, repo); !slices.Equal(got, []int{id}) {
+			t.Errorf("unexpected trash: got %v, want %v", got, []int{id})
+		}
+	})
+
+	t.Run("the latest deleted comes first", func(t *testing.T) {
+		// preparing
+		repo := factory(t)
+		first := mustSave(t, repo, domain.Todo{Title: "first"})
+		second := mustSave(t, repo, domain.Todo{Title: "second"})
+		third := mustSave(t, repo, domain.Todo{Title: "third"})
+
+		// act
+		mustTrash(t, ctx, repo, second, deletedAt)
+		mustTrash(t, ctx, repo, first, deletedAt.Add(time.Hour))
+		mustTrash(t, ctx, repo, third, deletedAt)
+
+		// assert
+		want := []int{first, third, second}
+		if got := trashIDs(t, ctx, repo); !slices.Equal(got, want) {
+			t.Errorf("unexpected order: got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("version mismatch -> error", func(t *testing.T) {
+		// preparing
+		repo := factory(t)
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+
+		// act
+		err := repo.TrashByID(ctx, id, 2, deletedAt)
+
+		// assert
+		if !errors.Is(err, domain.ErrVersionMismatch) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrVersionMismatch)
+		}
+		if _, err := repo.GetByID(ctx, id); err != nil {
+			t.Errorf("todo must stay: got %v, want nil", err)
+		}
+		if got := trashIDs(t, ctx, repo); len(got) != 0 {
+			t.Errorf("unexpected trash: got %v, want none", got)
+		}
+	})
+
+	t.Run("missing todo -> error", func(t *testing.T) {
+		repo := factory(t)
+
+		err := repo.TrashByID(ctx, 42, 0, deletedAt)
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+		if _, err := repo.GetTrashedByID(ctx, 42); !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error on get trashed: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+}
+
+func testRestoreByID(t *testing.T, factory TrashFactory) {
+	ctx := context.Background()
+
+	t.Run("puts the todos back", func(t *testing.T) {
+		// preparing
+		repo := factory(t)
+		tagID := mustSaveTag(t, repo, "work")
+		id := mustSave(t, repo, domain.Todo{Title: "move", Tags: []int{tagID}})
+		childID := mustSave(t, repo, domain.Todo{Title: "pack", ParentID: &id})
+		mustTrash(t, ctx, repo, id, deletedAt)
+
+		// act
+		restored, err := repo.RestoreByID(ctx, id)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if restored.ID != id || restored.Version != 1 || len(restored.Subtasks) != 1 {
+			t.Errorf("unexpected restored entry: got %+v", restored)
+		}
+
+		todo, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error on get: got %v, want nil", err)
+		}
+		if todo.Title != "move" || todo.Version != 1 || !slices.Equal(todo.Tags, []int{tagID}) {
+			t.Errorf("unexpected todo: got %+v", todo)
+		}
+		child, err := repo.GetByID(ctx, childID)
+		if err != nil {
+			t.Fatalf("unexpected error on get subtask: got %v, want nil", err)
+		}
+		if child.ParentID == nil || *child.ParentID != id {
+			t.Errorf("subtask must keep its parent: got %+v", child)
+		}
+
+		if got := trashIDs(t, ctx, repo); len(got) != 0 {
+			t.Errorf("unexpected trash: got %v, want none", got)
+		}
+	})
+
+	t.Run("drops deleted tags and a missing parent", func(t *testing.T) {
+		// preparing
+		repo := factory(t)
+		workID := mustSaveTag(t, repo, "work")
+		homeID := mustSaveTag(t, repo, "home")
+		parentID := mustSave(t, repo, domain.Todo{Title: "move"})
+		id := mustSave(t, repo, domain.Todo{Title: "pack", ParentID: &parentID, Tags: []int{workID, homeID}})
+		mustTrash(t, ctx, repo, id, deletedAt)
+
+		if err := repo.DeleteTagByID(ctx, workID); err != nil {
+			t.Fatalf("unexpected error on tag delete: got %v, want nil", err)
+		}
+		mustTrash(t, ctx, repo, parentID, deletedAt)
+
+		// act
+		restored, err := repo.RestoreByID(ctx, id)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		todo, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error on get: got %v, want nil", err)
+		}
+		if todo.ParentID != nil || !slices.Equal(todo.Tags, []int{homeID}) || todo.Version != 2 {
+			t.Errorf("unexpected todo: got %+v", todo)
+		}
+		if restored.Version != todo.Version {
+			t.Errorf("unexpected restored version: got %d, want %d", restored.Version, todo.Version)
+		}
+
+		// the parent is still in the trash
+		if got := trashIDs(t, ctx, repo); !slices.Equal(got, []int{parentID}) {
+			t.Errorf("unexpected trash: got %v, want %v", got, []int{parentID})
+		}
+	})
+
+	t.Run("missing entry -> error", func(t *testing.T) {
+		repo := factory(t)
+		id := mustSave(t, repo, domain.Todo{Title: "read the book"})
+
+		_, err := repo.RestoreByID(ctx, id)
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+}
+
+func testPurge(t *testing.T, factory TrashFactory) {
+	ctx := context.Background()
+
+	t.Run("by id", func(t *testing.T) {
+		// preparing
+		repo := factory(t)
+		id := mustSave(t, repo, domain.Todo{Title: "move"})
+		mustSave(t, repo, domain.Todo{Title: "pack", ParentID: &id})
+		otherID := mustSave(t, repo, domain.Todo{Title: "read the book"})
+		mustTrash(t, ctx, repo, id, deletedAt)
+		mustTrash(t, ctx, repo, otherID, deletedAt)
+
+		// act
+		err := repo.PurgeByID(ctx, id)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if got := trashIDs(t, ctx, repo); !slices.Equal(got, []int{otherID}) {
+			t.Errorf("unexpected trash: got %v, want %v", got, []int{otherID})
+		}
+		if _, err := repo.RestoreByID(ctx, id); !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error on restore: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+		if err := repo.PurgeByID(ctx, id); !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error on second purge: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+
+		// ids of purged todos are never handed out again
+		if newID := mustSave(t, repo, domain.Todo{Title: "buy milk"}); newID <= otherID {
+			t.Errorf("id reused: got %d, want more than %d", newID, otherID)
+		}
+	})
+
+	t.Run("older than the retention in every tenant", func(t *testing.T) {
+		// preparing
+		repo := factory(t)
+		oldID := saveIn(t, ctx, repo, "old")[0]
+		newID := saveIn(t, ctx, repo, "new")[0]
+		acmeID := saveIn(t, acme, repo, "acme old")[0]
+		mustTrash(t, ctx, repo, oldID, deletedAt)
+		mustTrash(t, ctx, repo, newID, deletedAt.Add(time.Hour))
+		mustTrash(t, acme, repo, acmeID, deletedAt)
+
+		// act
+		n, err := repo.PurgeTrash(ctx, deletedAt.Add(time.Minute))
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if n != 2 {
+			t.Errorf("unexpected count: got %d, want %d", n, 2)
+		}
+		if got := trashIDs(t, ctx, repo); !slices.Equal(got, []int{newID}) {
+			t.Errorf("unexpected trash: got %v, want %v", got, []int{newID})
+		}
+		if got := trashIDs(t, acme, repo); len(got) != 0 {
+			t.Errorf("unexpected trash of acme: got %v, want none", got)
+		}
+	})
+}
+
+func testTrashTenants(t *testing.T, factory TrashFactory) {
+	repo := factory(t)
+	acmeIDs := saveIn(t, acme, repo, "acme plan", "acme secret")
+	globexIDs := saveIn(t, globex, repo, "globex plan")
+	id := foreignID(t, acmeIDs, globexIDs)
+
+	t.Run("trash from another tenant -> error", func(t *testing.T) {
+		err := repo.TrashByID(globex, id, 0, deletedAt)
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+
+	mustTrash(t, acme, repo, id, deletedAt)
+
+	t.Run("trash is seen by its own tenant only", func(t *testing.T) {
+		if got := trashIDs(t, globex, repo); len(got) != 0 {
+			t.Errorf("unexpected trash: got %v, want none", got)
+		}
+		if _, err := repo.GetTrashedByID(globex, id); !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error on get: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+
+	t.Run("restore or purge from another tenant -> error", func(t *testing.T) {
+		if _, err := repo.RestoreByID(globex, id); !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error on restore: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+		if err := repo.PurgeByID(globex, id); !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Errorf("unexpected error on purge: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+
+	t.Run("owner restores", func(t *testing.T) {
+		if _, err := repo.RestoreByID(acme, id); err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if _, err := repo.GetByID(acme, id); err != nil {
+			t.Errorf("unexpected error on get: got %v, want nil", err)
+		}
+	})
+}
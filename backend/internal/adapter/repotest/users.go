@@ -0,0 +1,214 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+// UserFactory returns an empty user repository.
+type UserFactory func(t *testing.T) usecase.UserRepository
+
+// RunUserConformance checks the behaviour every usecase.UserRepository
+// implementation must share.
+func RunUserConformance(t *testing.T, factory UserFactory) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, factory) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, factory) })
+	t.Run("APIKeys", func(t *testing.T) { testAPIKeys(t, factory) })
+}
+
+func mustSaveUser(t *testing.T, repo usecase.UserRepository, email string) domain.User {
+	t.Helper()
+
+	user := domain.User{
+		Email:        email,
+		PasswordHash: "pbkdf2-sha256$1$c2FsdA$a2V5",
+		CreatedAt:    time.Date(2030, time.March, 1, 9, 0, 0, 123456000, time.UTC),
+	}
+	id, err := repo.SaveUser(context.Background(), user)
+	if err != nil {
+		t.Fatalf("unexpected error on save user: got %v, want nil", err)
+	}
+	user.ID = id
+
+	return user
+}
+
+func testUsers(t *testing.T, factory UserFactory) {
+	ctx := context.Background()
+
+	t.Run("stores every field", func(t *testing.T) {
+		repo := factory(t)
+		want := mustSaveUser(t, repo, "ann@example.com")
+
+		byID, err := repo.GetUserByID(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		byEmail, err := repo.GetUserByEmail(ctx, want.Email)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		for _, got := range []domain.User{byID, byEmail} {
+			if got.ID != want.ID || got.Email != want.Email || got.PasswordHash != want.PasswordHash || !got.CreatedAt.Equal(want.CreatedAt) {
+				t.Errorf("unexpected user: got %+v, want %+v", got, want)
+			}
+		}
+	})
+
+	t.Run("assigns distinct ids", func(t *testing.T) {
+		repo := factory(t)
+		ann := mustSaveUser(t, repo, "ann@example.com")
+		bob := mustSaveUser(t, repo, "bob@example.com")
+
+		if ann.ID == 0 || ann.ID == bob.ID {
+			t.Errorf("unexpected ids: got %d and %d", ann.ID, bob.ID)
+		}
+	})
+
+	t.Run("taken email -> error", func(t *testing.T) {
+		repo := factory(t)
+		mustSaveUser(t, repo, "ann@example.com")
+
+		_, err := repo.SaveUser(ctx, domain.User{Email: "ann@example.com", PasswordHash: "x"})
+		if !errors.Is(err, domain.ErrUserExists) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrUserExists)
+		}
+	})
+
+	t.Run("missing user -> error", func(t *testing.T) {
+		repo := factory(t)
+
+		if _, err := repo.GetUserByID(ctx, 42); !errors.Is(err, domain.ErrUserNotExist) {
+			t.Errorf("unexpected error by id: got %v, want %v", err, domain.ErrUserNotExist)
+		}
+		if _, err := repo.GetUserByEmail(ctx, "ann@example.com"); !errors.Is(err, domain.ErrUserNotExist) {
+			t.Errorf("unexpected error by email: got %v, want %v", err, domain.ErrUserNotExist)
+		}
+	})
+}
+
+func testSessions(t *testing.T, factory UserFactory) {
+	ctx := context.Background()
+
+	repo := factory(t)
+	user := mustSaveUser(t, repo, "ann@example.com")
+
+	created := time.Date(2030, time.March, 1, 9, 0, 0, 123456000, time.UTC)
+	want := domain.Session{ID: "0f1e2d3c", UserID: user.ID, CreatedAt: created, ExpiresAt: created.Add(time.Hour)}
+	if err := repo.SaveSession(ctx, want); err != nil {
+		t.Fatalf("unexpected error on save session: got %v, want nil", err)
+	}
+
+	got, err := repo.GetSession(ctx, want.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	if got.ID != want.ID || got.UserID != want.UserID || !got.CreatedAt.Equal(want.CreatedAt) || !got.ExpiresAt.Equal(want.ExpiresAt) {
+		t.Errorf("unexpected session: got %+v, want %+v", got, want)
+	}
+
+	if err := repo.DeleteSession(ctx, want.ID); err != nil {
+		t.Fatalf("unexpected error on delete: got %v, want nil", err)
+	}
+	if _, err := repo.GetSession(ctx, want.ID); !errors.Is(err, domain.ErrSessionNotExist) {
+		t.Errorf("unexpected error after delete: got %v, want %v", err, domain.ErrSessionNotExist)
+	}
+	if err := repo.DeleteSession(ctx, want.ID); !errors.Is(err, domain.ErrSessionNotExist) {
+		t.Errorf("unexpected error on second delete: got %v, want %v", err, domain.ErrSessionNotExist)
+	}
+}
+
+func testAPIKeys(t *testing.T, factory UserFactory) {
+	ctx := context.Background()
+
+	repo := factory(t)
+	ann := mustSaveUser(t, repo, "ann@example.com")
+	bob := mustSaveUser(t, repo, "bob@example.com")
+
+	created := time.Date(2030, time.March, 1, 9, 0, 0, 123456000, time.UTC)
+	want := domain.APIKey{
+		UserID:    ann.ID,
+		Name:      "backup",
+		Prefix:    "todo_abcdefgh",
+		Hash:      "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		Scopes:    []domain.Scope{domain.ScopeTodosRead},
+		CreatedAt: created,
+	}
+	id, err := repo.SaveAPIKey(ctx, want)
+	if err != nil {
+		t.Fatalf("unexpected error on save api key: got %v, want nil", err)
+	}
+	want.ID = id
+
+	other := domain.APIKey{UserID: bob.ID, Name: "sync", Prefix: "todo_ijklmnop", Hash: "other", Scopes: domain.AllScopes, CreatedAt: created}
+	if _, err := repo.SaveAPIKey(ctx, other); err != nil {
+		t.Fatalf("unexpected error on save api key: got %v, want nil", err)
+	}
+
+	t.Run("stores every field", func(t *testing.T) {
+		got, err := repo.GetAPIKeyByHash(ctx, want.Hash)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if got.ID != want.ID || got.UserID != want.UserID || got.Name != want.Name || got.Prefix != want.Prefix ||
+			got.Hash != want.Hash || !slices.Equal(got.Scopes, want.Scopes) || !got.CreatedAt.Equal(want.CreatedAt) || got.LastUsedAt != nil {
+			t.Errorf("unexpected api key: got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("lists only the keys of the user", func(t *testing.T) {
+		got, err := repo.ListAPIKeys(ctx, ann.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(got) != 1 || got[0].ID != want.ID {
+			t.Errorf("unexpected api keys: got %+v", got)
+		}
+	})
+
+	t.Run("records the last use", func(t *testing.T) {
+		usedAt := created.Add(time.Hour)
+		if err := repo.TouchAPIKey(ctx, want.ID, usedAt); err != nil {
+			t.Fatalf("unexpected error on touch: got %v, want nil", err)
+		}
+
+		got, err := repo.GetAPIKeyByHash(ctx, want.Hash)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if got.LastUsedAt == nil || !got.LastUsedAt.Equal(usedAt) {
+			t.Errorf("unexpected last use: got %v, want %v", got.LastUsedAt, usedAt)
+		}
+	})
+
+	t.Run("missing key -> error", func(t *testing.T) {
+		if _, err := repo.GetAPIKeyByHash(ctx, "missing"); !errors.Is(err, domain.ErrAPIKeyNotExist) {
+			t.Errorf("unexpected error by hash: got %v, want %v", err, domain.ErrAPIKeyNotExist)
+		}
+		if err := repo.TouchAPIKey(ctx, 42, created); !errors.Is(err, domain.ErrAPIKeyNotExist) {
+			t.Errorf("unexpected error on touch: got %v, want %v", err, domain.ErrAPIKeyNotExist)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := repo.DeleteAPIKey(ctx, bob.ID, want.ID); !errors.Is(err, domain.ErrAPIKeyNotExist) {
+			t.Fatalf("unexpected error on delete by other user: got %v, want %v", err, domain.ErrAPIKeyNotExist)
+		}
+		if err := repo.DeleteAPIKey(ctx, ann.ID, want.ID); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+		if _, err := repo.GetAPIKeyByHash(ctx, want.Hash); !errors.Is(err, domain.ErrAPIKeyNotExist) {
+			t.Errorf("unexpected error after delete: got %v, want %v", err, domain.ErrAPIKeyNotExist)
+		}
+		if err := repo.DeleteAPIKey(ctx, ann.ID, want.ID); !errors.Is(err, domain.ErrAPIKeyNotExist) {
+			t.Errorf("unexpected error on second delete: got %v, want %v", err, domain.ErrAPIKeyNotExist)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+// WebhookRepository stores the users the webhooks belong to as well.
+type WebhookRepository interface {
+	usecase.UserRepository
+	usecase.WebhookRepository
+}
+
+// WebhookFactory returns an empty webhook repository.
+type WebhookFactory func(t *testing.T) WebhookRepository
+
+// RunWebhookConformance checks the behaviour every
+// usecase.WebhookRepository implementation must share.
+func RunWebhookConformance(t *testing.T, factory WebhookFactory) {
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, factory) })
+	t.Run("Tenants", func(t *testing.T) { testTenantWebhooks(t, factory) })
+}
+
+func testWebhooks(t *testing.T, factory WebhookFactory) {
+	ctx := context.Background()
+
+	repo := factory(t)
+	ann := mustSaveUser(t, repo, "ann@example.com")
+	bob := mustSaveUser(t, repo, "bob@example.com")
+
+	created := time.Date(2030, time.March, 1, 9, 0, 0, 123456000, time.UTC)
+	want := domain.Webhook{
+		UserID:    ann.ID,
+		URL:       "https://example.com/hooks/todos",
+		Events:    []domain.TodoEventType{domain.TodoCompleted, domain.TodoDeleted},
+		Secret:    "whsec_0123456789abcdef",
+		CreatedAt: created,
+	}
+	id, err := repo.SaveWebhook(ctx, want)
+	if err != nil {
+		t.Fatalf("unexpected error on save webhook: got %v, want nil", err)
+	}
+	want.ID = id
+
+	other := domain.Webhook{UserID: bob.ID, URL: "http://localhost:9000/", Events: domain.WebhookEvents, Secret: "other-secret-0123", CreatedAt: created}
+	otherID, err := repo.SaveWebhook(ctx, other)
+	if err != nil {
+		t.Fatalf("unexpected error on save webhook: got %v, want nil", err)
+	}
+
+	t.Run("stores every field", func(t *testing.T) {
+		got, err := repo.ListWebhooks(ctx, ann.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("unexpected webhooks: got %+v", got)
+		}
+		if got[0].ID != want.ID || got[0].UserID != want.UserID || got[0].URL != want.URL || !slices.Equal(got[0].Events, want.Events) ||
+			got[0].Secret != want.Secret || !got[0].CreatedAt.Equal(want.CreatedAt) {
+			t.Errorf("unexpected webhook: got %+v, want %+v", got[0], want)
+		}
+	})
+
+	t.Run("reads the webhooks of every user", func(t *testing.T) {
+		got, err := repo.ReadAllWebhooks(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(got) != 2 || got[0].ID != want.ID || got[1].ID != otherID {
+			t.Errorf("unexpected webhooks: got %+v", got)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := repo.DeleteWebhook(ctx, bob.ID, want.ID); !errors.Is(err, domain.ErrWebhookNotExist) {
+			t.Fatalf("unexpected error on delete by other user: got %v, want %v", err, domain.ErrWebhookNotExist)
+		}
+		if err := repo.DeleteWebhook(ctx, ann.ID, want.ID); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+		if got, _ := repo.ListWebhooks(ctx, ann.ID); len(got) != 0 {
+			t.Errorf("unexpected webhooks after delete: got %+v", got)
+		}
+		if err := repo.DeleteWebhook(ctx, ann.ID, want.ID); !errors.Is(err, domain.ErrWebhookNotExist) {
+			t.Errorf("unexpected error on second delete: got %v, want %v", err, domain.ErrWebhookNotExist)
+		}
+	})
+
+	t.Run("ids are not reused", func(t *testing.T) {
+		id, err := repo.SaveWebhook(ctx, want)
+		if err != nil {
+			t.Fatalf("unexpected error on save webhook: got %v, want nil", err)
+		}
+		if id <= otherID {
+			t.Errorf("unexpected id: got %d, want more than %d", id, otherID)
+		}
+	})
+}
+
+func testTenantWebhooks(t *testing.T, factory WebhookFactory) {
+	repo := factory(t)
+	created := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	userID, err := repo.SaveUser(acme, domain.User{Email: "ann@example.com", PasswordHash: "x", CreatedAt: created})
+	if err != nil {
+		t.Fatalf("unexpected error on save user: got %v, want nil", err)
+	}
+	hook := domain.Webhook{UserID: userID, URL: "https://example.com/", Events: domain.WebhookEvents, Secret: "acme-secret-0123", CreatedAt: created}
+	id, err := repo.SaveWebhook(acme, hook)
+	if err != nil {
+		t.Fatalf("unexpected error on save webhook: got %v, want nil", err)
+	}
+
+	if got, err := repo.ReadAllWebhooks(globex); err != nil || len(got) != 0 {
+		t.Errorf("unexpected webhooks of another tenant: got %+v, %v", got, err)
+	}
+	if got, err := repo.ListWebhooks(globex, userID); err != nil || len(got) != 0 {
+		t.Errorf("unexpected webhooks of the user in another tenant: got %+v, %v", got, err)
+	}
+	if err := repo.DeleteWebhook(globex, userID, id); !errors.Is(err, domain.ErrWebhookNotExist) {
+		t.Errorf("unexpected error on delete: got %v, want %v", err, domain.ErrWebhookNotExist)
+	}
+	if got, err := repo.ReadAllWebhooks(acme); err != nil || len(got) != 1 {
+		t.Errorf("unexpected webhooks in the own tenant: got %+v, %v", got, err)
+	}
+}
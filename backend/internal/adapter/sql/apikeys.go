@@ -0,0 +1,152 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const apiKeyColumns = `id, user_id, name, prefix, hash, scopes, created_at, last_used_at`
+
+func (s *SQLTodoRepository) SaveAPIKey(ctx context.Context, key domain.APIKey) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	scopes := []domain.Scope{}
+	if key.Scopes != nil {
+		scopes = key.Scopes
+	}
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return 0, fmt.Errorf("encode scopes: %w", err)
+	}
+
+	var lastUsedAt sql.NullInt64
+	if key.LastUsedAt != nil {
+		lastUsedAt = toMicros(*key.LastUsedAt)
+	}
+
+	var id int
+	err = s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		`INSERT INTO api_keys (user_id, name, prefix, hash, scopes, created_at, last_used_at, tenant)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		key.UserID, key.Name, key.Prefix, key.Hash, string(scopesJSON), toMicros(key.CreatedAt), lastUsedAt, tenant(ctx),
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert api key: %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *SQLTodoRepository) GetAPIKeyByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.APIKey{}, err
+	}
+
+	row := s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE hash = ? AND tenant = ?`), hash, tenant(ctx))
+
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.APIKey{}, domain.ErrAPIKeyNotExist
+	} else if err != nil {
+		return domain.APIKey{}, fmt.Errorf("select api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (s *SQLTodoRepository) TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+		`UPDATE api_keys SET last_used_at = ? WHERE id = ? AND tenant = ?`), toMicros(usedAt), id, tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("update api key: %w", err)
+	}
+
+	return apiKeyAffected(res)
+}
+
+func (s *SQLTodoRepository) ListAPIKeys(ctx context.Context, userID int) ([]domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.q(ctx).QueryContext(ctx, s.dialect.rebind(
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = ? AND tenant = ? ORDER BY id`), userID, tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("select api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]domain.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (s *SQLTodoRepository) DeleteAPIKey(ctx context.Context, userID, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+		`DELETE FROM api_keys WHERE id = ? AND user_id = ? AND tenant = ?`), id, userID, tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("delete api key: %w", err)
+	}
+
+	return apiKeyAffected(res)
+}
+
+func scanAPIKey(row scanner) (domain.APIKey, error) {
+	var (
+		key                   domain.APIKey
+		scopes                string
+		createdAt, lastUsedAt sql.NullInt64
+	)
+	if err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Hash, &scopes, &createdAt, &lastUsedAt); err != nil {
+		return domain.APIKey{}, err
+	}
+
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return domain.APIKey{}, fmt.Errorf("decode scopes: %w", err)
+	}
+	key.CreatedAt = fromMicros(createdAt)
+	if lastUsedAt.Valid {
+		t := fromMicros(lastUsedAt)
+		key.LastUsedAt = &t
+	}
+
+	return key, nil
+}
+
+func apiKeyAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrAPIKeyNotExist
+	}
+	return nil
+}
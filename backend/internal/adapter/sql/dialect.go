@@ -0,0 +1,138 @@
+package sql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// sqliteDriver is sqlite3 with LOWER folding the case of every letter. The
+// built-in one folds ASCII only, so "Молоко" would not match a search for
+// "молоко" as it does in Go and on Postgres.
+const sqliteDriver = "sqlite3_unicode"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("lower", strings.ToLower, true)
+		},
+	})
+}
+
+func ParseDialect(s string) (Dialect, error) {
+	switch Dialect(strings.ToLower(s)) {
+	case SQLite, "sqlite3":
+		return SQLite, nil
+	case Postgres, "postgresql", "pgx":
+		return Postgres, nil
+	}
+	return "", fmt.Errorf("unknown sql dialect %q", s)
+}
+
+func (d Dialect) driverName() string {
+	if d == Postgres {
+		return "postgres"
+	}
+	return sqliteDriver
+}
+
+// sqliteOptions are the connection options the repository relies on, each
+// with the aliases go-sqlite3 accepts for it. IMMEDIATE transactions take
+// the write lock up front, so concurrent writers wait on busy_timeout
+// instead of failing on lock upgrade.
+var sqliteOptions = []struct {
+	keys  []string
+	value string
+}{
+	{keys: []string{"_foreign_keys", "_fk"}, value: "on"},
+	{keys: []string{"_busy_timeout", "_timeout"}, value: "5000"},
+	{keys: []string{"_journal_mode", "_journal"}, value: "WAL"},
+	{keys: []string{"_txlock"}, value: "immediate"},
+}
+
+// dsn adds the connection options the repository relies on. Options the
+// DSN already sets are kept as they are.
+func (d Dialect) dsn(dsn string) string {
+	if d != SQLite {
+		return dsn
+	}
+
+	_, query, _ := strings.Cut(dsn, "?")
+	set, err := url.ParseQuery(query)
+	if err != nil {
+		// go-sqlite3 reports the malformed options once it opens the DSN
+		return dsn
+	}
+
+	var missing []string
+	for _, opt := range sqliteOptions {
+		if !slices.ContainsFunc(opt.keys, set.Has) {
+			missing = append(missing, opt.keys[0]+"="+opt.value)
+		}
+	}
+	if len(missing) == 0 {
+		return dsn
+	}
+
+	switch {
+	case !strings.Contains(dsn, "?"):
+		dsn += "?"
+	case !strings.HasSuffix(dsn, "?") && !strings.HasSuffix(dsn, "&"):
+		dsn += "&"
+	}
+	return dsn + strings.Join(missing, "&")
+}
+
+// rebind converts '?' placeholders to the dialect's native form.
+func (d Dialect) rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// tagIDsColumn selects the comma separated tag ids of the current todo row.
+func (d Dialect) tagIDsColumn() string {
+	if d == Postgres {
+		return `(SELECT string_agg(tag_id::text, ',') FROM todo_tags WHERE todo_tags.todo_id = todos.id)`
+	}
+	return `(SELECT group_concat(tag_id) FROM todo_tags WHERE todo_tags.todo_id = todos.id)`
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+	return false
+}
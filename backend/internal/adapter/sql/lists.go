@@ -0,0 +1,253 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (s *SQLTodoRepository) SaveList(ctx context.Context, list domain.List) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, s.dialect.rebind(
+			`INSERT INTO lists (name, version, created_at, tenant) VALUES (?, 1, ?, ?) RETURNING id`),
+			list.Name, toMicros(list.CreatedAt), tenant(ctx),
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("insert list: %w", err)
+		}
+
+		return s.insertListPeople(ctx, tx, id, list)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *SQLTodoRepository) GetListByID(ctx context.Context, id int) (domain.List, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.List{}, err
+	}
+
+	return s.getList(ctx, id)
+}
+
+func (s *SQLTodoRepository) UpdateListByID(ctx context.Context, id int, list domain.List) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, s.dialect.rebind(
+			`UPDATE lists SET name = ?, version = version + 1 WHERE id = ? AND tenant = ? AND version = ?`),
+			list.Name, id, tenant(ctx), list.Version)
+		if err != nil {
+			return fmt.Errorf("update list: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("rows affected: %w", err)
+		}
+		if n == 0 {
+			var exists int
+			err := tx.QueryRowContext(ctx, s.dialect.rebind(`SELECT COUNT(*) FROM lists WHERE id = ? AND tenant = ?`), id, tenant(ctx)).Scan(&exists)
+			if err != nil {
+				return fmt.Errorf("check list: %w", err)
+			}
+			if exists == 0 {
+				return domain.ErrListNotExist
+			}
+			return domain.ErrVersionMismatch
+		}
+
+		for _, table := range []string{"list_members", "list_invitations"} {
+			if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM `+table+` WHERE list_id = ?`), id); err != nil {
+				return fmt.Errorf("delete %s: %w", table, err)
+			}
+		}
+
+		return s.insertListPeople(ctx, tx, id, list)
+	})
+}
+
+func (s *SQLTodoRepository) DeleteListByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// a list of another workspace is not deleted below, which rolls back
+	// the statements before
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// the foreign keys cascade too, but only when the connection
+		// enables them
+		for _, table := range []string{"list_members", "list_invitations"} {
+			if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM `+table+` WHERE list_id = ?`), id); err != nil {
+				return fmt.Errorf("delete %s: %w", table, err)
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM lists WHERE id = ? AND tenant = ?`), id, tenant(ctx))
+		if err != nil {
+			return fmt.Errorf("delete list: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("rows affected: %w", err)
+		}
+		if n == 0 {
+			return domain.ErrListNotExist
+		}
+
+		return nil
+	})
+}
+
+func (s *SQLTodoRepository) ListsByMember(ctx context.Context, userID int) ([]domain.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.getLists(ctx, `SELECT list_id FROM list_members JOIN lists ON lists.id = list_members.list_id
+		WHERE user_id = ? AND tenant = ? ORDER BY list_id`, userID, tenant(ctx))
+}
+
+func (s *SQLTodoRepository) ListsByInvitee(ctx context.Context, email string) ([]domain.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.getLists(ctx, `SELECT list_id FROM list_invitations JOIN lists ON lists.id = list_invitations.list_id
+		WHERE email = ? AND tenant = ? ORDER BY list_id`, email, tenant(ctx))
+}
+
+// insertListPeople stores the members and invitations of the list in
+// their order.
+func (s *SQLTodoRepository) insertListPeople(ctx context.Context, tx *sql.Tx, id int, list domain.List) error {
+	for i, m := range list.Members {
+		_, err := tx.ExecContext(ctx, s.dialect.rebind(
+			`INSERT INTO list_members (list_id, user_id, email, role, joined_at, position) VALUES (?, ?, ?, ?, ?, ?)`),
+			id, m.UserID, m.Email, string(m.Role), toMicros(m.JoinedAt), i)
+		if err != nil {
+			return fmt.Errorf("insert list member: %w", err)
+		}
+	}
+
+	for i, inv := range list.Invitations {
+		_, err := tx.ExecContext(ctx, s.dialect.rebind(
+			`INSERT INTO list_invitations (list_id, email, role, invited_by, created_at, position) VALUES (?, ?, ?, ?, ?, ?)`),
+			id, inv.Email, string(inv.Role), inv.InvitedBy, toMicros(inv.CreatedAt), i)
+		if err != nil {
+			return fmt.Errorf("insert list invitation: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getLists reads the lists whose ids the query selects.
+func (s *SQLTodoRepository) getLists(ctx context.Context, query string, args ...any) ([]domain.List, error) {
+	rows, err := s.q(ctx).QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("select list ids: %w", err)
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan list id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate list ids: %w", err)
+	}
+
+	lists := make([]domain.List, 0, len(ids))
+	for _, id := range ids {
+		list, err := s.getList(ctx, id)
+		if errors.Is(err, domain.ErrListNotExist) {
+			// deleted in between
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+
+	return lists, nil
+}
+
+func (s *SQLTodoRepository) getList(ctx context.Context, id int) (domain.List, error) {
+	var (
+		list      domain.List
+		createdAt sql.NullInt64
+	)
+	err := s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		`SELECT id, name, version, created_at FROM lists WHERE id = ? AND tenant = ?`), id, tenant(ctx),
+	).Scan(&list.ID, &list.Name, &list.Version, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.List{}, domain.ErrListNotExist
+	} else if err != nil {
+		return domain.List{}, fmt.Errorf("select list: %w", err)
+	}
+	list.CreatedAt = fromMicros(createdAt)
+
+	members, err := s.q(ctx).QueryContext(ctx, s.dialect.rebind(
+		`SELECT user_id, email, role, joined_at FROM list_members WHERE list_id = ? ORDER BY position`), id)
+	if err != nil {
+		return domain.List{}, fmt.Errorf("select list members: %w", err)
+	}
+	defer members.Close()
+
+	for members.Next() {
+		var (
+			m        domain.Member
+			joinedAt sql.NullInt64
+		)
+		if err := members.Scan(&m.UserID, &m.Email, &m.Role, &joinedAt); err != nil {
+			return domain.List{}, fmt.Errorf("scan list member: %w", err)
+		}
+		m.JoinedAt = fromMicros(joinedAt)
+		list.Members = append(list.Members, m)
+	}
+	if err := members.Err(); err != nil {
+		return domain.List{}, fmt.Errorf("iterate list members: %w", err)
+	}
+
+	invitations, err := s.q(ctx).QueryContext(ctx, s.dialect.rebind(
+		`SELECT email, role, invited_by, created_at FROM list_invitations WHERE list_id = ? ORDER BY position`), id)
+	if err != nil {
+		return domain.List{}, fmt.Errorf("select list invitations: %w", err)
+	}
+	defer invitations.Close()
+
+	for invitations.Next() {
+		var (
+			inv       domain.Invitation
+			createdAt sql.NullInt64
+		)
+		if err := invitations.Scan(&inv.Email, &inv.Role, &inv.InvitedBy, &createdAt); err != nil {
+			return domain.List{}, fmt.Errorf("scan list invitation: %w", err)
+		}
+		inv.CreatedAt = fromMicros(createdAt)
+		list.Invitations = append(list.Invitations, inv)
+	}
+	if err := invitations.Err(); err != nil {
+		return domain.List{}, fmt.Errorf("iterate list invitations: %w", err)
+	}
+
+	return list, nil
+}
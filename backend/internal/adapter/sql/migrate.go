@@ -0,0 +1,204 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+func loadMigrations(d Dialect) ([]migration, error) {
+	dir := path.Join("migrations", string(d))
+
+	entries, err := fs.ReadDir(migrationsFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations dir: %w", err)
+	}
+
+	res := make([]migration, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %q: name must look like 0001_name.sql", e.Name())
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %q: parse version: %w", e.Name(), err)
+		}
+
+		data, err := fs.ReadFile(migrationsFS, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %q: %w", e.Name(), err)
+		}
+
+		res = append(res, migration{Version: version, Name: e.Name(), SQL: string(data)})
+	}
+
+	slices.SortFunc(res, func(a, b migration) int {
+		return a.Version - b.Version
+	})
+
+	for i := 1; i < len(res); i++ {
+		if res[i].Version == res[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", res[i].Version)
+		}
+	}
+
+	return res, nil
+}
+
+// migrationLockID is the key of the Postgres advisory lock held while
+// migrating. Any constant works, as long as nothing else locks it.
+const migrationLockID = 0x746f646f // "todo"
+
+// migrate applies every embedded migration that is not recorded in
+// schema_migrations yet. Each migration runs in its own transaction, all
+// of them on one connection. On Postgres that connection holds an advisory
+// lock throughout, so instances starting together migrate one at a time:
+// the later ones find the migrations applied and skip them.
+func migrate(ctx context.Context, db *sql.DB, d Dialect) (err error) {
+	migrations, err := loadMigrations(d)
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if d == Postgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+			return fmt.Errorf("lock migrations: %w", err)
+		}
+		defer func() {
+			// the lock belongs to the session, which outlives the connection going back to the pool
+			if _, uerr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err == nil && uerr != nil {
+				err = fmt.Errorf("unlock migrations: %w", uerr)
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER   NOT NULL PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied := map[int]bool{}
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("read applied migrations: %w", err)
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan applied migration: %w", err)
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read applied migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+
+		if err := applyMigration(ctx, conn, d, m); err != nil {
+			return fmt.Errorf("apply migration %s: %w", m.Name, err)
+		}
+
+		slog.Info("migration applied", "version", m.Version, "name", m.Name)
+	}
+
+	return nil
+}
+
+// applyMigration runs on a dedicated connection, so SQLite can switch
+// foreign keys off for it: rebuilding a table drops the old one, which
+// must not cascade into the rows referencing it. The keys are checked
+// before the commit instead.
+func applyMigration(ctx context.Context, conn *sql.Conn, d Dialect, m migration) (err error) {
+	if d == SQLite {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return err
+		}
+		defer func() {
+			// the connection goes back to the pool, which expects them on
+			if _, rerr := conn.ExecContext(context.Background(), `PRAGMA foreign_keys = ON`); err == nil {
+				err = rerr
+			}
+		}()
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+
+	if d == SQLite {
+		if err := checkForeignKeys(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, d.rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), m.Version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkForeignKeys fails when a SQLite migration left a row pointing to a
+// missing one.
+func checkForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		return fmt.Errorf("check foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var (
+			table, parent string
+			rowID         sql.NullInt64
+			fkID          int
+		)
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return fmt.Errorf("scan foreign key violation: %w", err)
+		}
+		return fmt.Errorf("row %d of %s references a missing row of %s", rowID.Int64, table, parent)
+	}
+
+	return rows.Err()
+}
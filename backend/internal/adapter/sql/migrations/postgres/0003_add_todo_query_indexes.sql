@@ -0,0 +1,2 @@
+CREATE INDEX todos_completed_id_idx ON todos (completed, id);
+CREATE INDEX todos_title_id_idx ON todos (title, id);
@@ -0,0 +1,15 @@
+-- name_key is the lowercased name, so tag names are unique regardless of case
+CREATE TABLE tags (
+    id       BIGSERIAL PRIMARY KEY,
+    name     TEXT      NOT NULL,
+    name_key TEXT      NOT NULL UNIQUE,
+    color    TEXT      NOT NULL DEFAULT ''
+);
+
+CREATE TABLE todo_tags (
+    todo_id BIGINT NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+    tag_id  BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, tag_id)
+);
+
+CREATE INDEX todo_tags_tag_id_todo_id_idx ON todo_tags (tag_id, todo_id);
@@ -0,0 +1,21 @@
+-- email is stored normalized, see domain.NormalizeEmail
+CREATE TABLE users (
+    id            BIGSERIAL PRIMARY KEY,
+    email         TEXT      NOT NULL UNIQUE,
+    password_hash TEXT      NOT NULL,
+    created_at    BIGINT
+);
+
+CREATE TABLE sessions (
+    id         TEXT   PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at BIGINT,
+    expires_at BIGINT NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+-- 0 marks todos created before users existed
+ALTER TABLE todos ADD COLUMN owner_id BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX todos_owner_id_id_idx ON todos (owner_id, id);
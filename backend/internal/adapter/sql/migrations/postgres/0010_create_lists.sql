@@ -0,0 +1,36 @@
+-- members and invitations keep their position, the order they were added in
+CREATE TABLE lists (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT   NOT NULL,
+    version    BIGINT NOT NULL DEFAULT 1,
+    created_at BIGINT
+);
+
+CREATE TABLE list_members (
+    list_id   BIGINT NOT NULL REFERENCES lists (id) ON DELETE CASCADE,
+    user_id   BIGINT NOT NULL,
+    email     TEXT   NOT NULL,
+    role      TEXT   NOT NULL,
+    joined_at BIGINT,
+    position  BIGINT NOT NULL,
+    PRIMARY KEY (list_id, user_id)
+);
+
+CREATE INDEX list_members_user_id_idx ON list_members (user_id);
+
+CREATE TABLE list_invitations (
+    list_id    BIGINT NOT NULL REFERENCES lists (id) ON DELETE CASCADE,
+    email      TEXT   NOT NULL,
+    role       TEXT   NOT NULL,
+    invited_by BIGINT NOT NULL,
+    created_at BIGINT,
+    position   BIGINT NOT NULL,
+    PRIMARY KEY (list_id, email)
+);
+
+CREATE INDEX list_invitations_email_idx ON list_invitations (email);
+
+-- NULL keeps a todo private to its owner
+ALTER TABLE todos ADD COLUMN list_id BIGINT;
+
+CREATE INDEX todos_list_id_id_idx ON todos (list_id, id);
@@ -0,0 +1,18 @@
+-- every row belongs to a workspace, rows stored before workspaces existed
+-- to the default one; ids stay global, lookups check the tenant too
+ALTER TABLE todos ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE tags ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE users ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE sessions ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE lists ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+
+-- tag names and emails are unique within a workspace only
+ALTER TABLE tags DROP CONSTRAINT tags_name_key_key;
+CREATE UNIQUE INDEX tags_tenant_name_key_idx ON tags (tenant, name_key);
+
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+CREATE UNIQUE INDEX users_tenant_email_idx ON users (tenant, email);
+
+CREATE INDEX todos_tenant_id_idx ON todos (tenant, id);
+CREATE INDEX lists_tenant_id_idx ON lists (tenant, id);
@@ -0,0 +1,12 @@
+-- the secret signs the payloads, so it is kept as is, not hashed
+CREATE TABLE webhooks (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    url        TEXT   NOT NULL,
+    events     TEXT   NOT NULL DEFAULT '[]',
+    secret     TEXT   NOT NULL,
+    created_at BIGINT,
+    tenant     TEXT   NOT NULL DEFAULT 'default'
+);
+
+CREATE INDEX webhooks_tenant_user_id_idx ON webhooks (tenant, user_id);
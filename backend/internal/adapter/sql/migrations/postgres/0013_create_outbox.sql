@@ -0,0 +1,10 @@
+-- events of stored changes wait here until every subscriber has handled
+-- them; the todo is kept as JSON, as it was when the event happened
+CREATE TABLE outbox (
+    id         BIGSERIAL PRIMARY KEY,
+    type       TEXT   NOT NULL,
+    todo       TEXT   NOT NULL,
+    actor_id   BIGINT,
+    created_at BIGINT,
+    tenant     TEXT   NOT NULL DEFAULT 'default'
+);
@@ -0,0 +1,6 @@
+CREATE TABLE todos (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    title       TEXT    NOT NULL,
+    description TEXT    NOT NULL DEFAULT '',
+    completed   BOOLEAN NOT NULL DEFAULT FALSE
+);
@@ -0,0 +1,11 @@
+-- times are stored as microseconds since the Unix epoch; due_offset keeps
+-- the UTC offset (in seconds) the due date was given with
+ALTER TABLE todos ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE todos ADD COLUMN due_at INTEGER;
+ALTER TABLE todos ADD COLUMN due_offset INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE todos ADD COLUMN created_at INTEGER;
+ALTER TABLE todos ADD COLUMN updated_at INTEGER;
+ALTER TABLE todos ADD COLUMN completed_at INTEGER;
+
+CREATE INDEX todos_due_at_id_idx ON todos (due_at, id);
+CREATE INDEX todos_priority_id_idx ON todos (priority, id);
@@ -0,0 +1,5 @@
+-- the checklist is stored as a JSON array of {"title", "done"} objects
+ALTER TABLE todos ADD COLUMN parent_id INTEGER REFERENCES todos (id) ON DELETE CASCADE;
+ALTER TABLE todos ADD COLUMN checklist TEXT NOT NULL DEFAULT '[]';
+
+CREATE INDEX todos_parent_id_id_idx ON todos (parent_id, id);
@@ -0,0 +1,2 @@
+-- the recurrence is stored as a JSON object of {"rule", "timezone", "start"}
+ALTER TABLE todos ADD COLUMN recurrence TEXT;
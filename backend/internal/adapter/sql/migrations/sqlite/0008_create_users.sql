@@ -0,0 +1,21 @@
+-- email is stored normalized, see domain.NormalizeEmail
+CREATE TABLE users (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    email         TEXT    NOT NULL UNIQUE,
+    password_hash TEXT    NOT NULL,
+    created_at    INTEGER
+);
+
+CREATE TABLE sessions (
+    id         TEXT    PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at INTEGER,
+    expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+-- 0 marks todos created before users existed
+ALTER TABLE todos ADD COLUMN owner_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX todos_owner_id_id_idx ON todos (owner_id, id);
@@ -0,0 +1,13 @@
+-- only the sha-256 of a key is stored, prefix is its plain start for display
+CREATE TABLE api_keys (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         TEXT    NOT NULL,
+    prefix       TEXT    NOT NULL,
+    hash         TEXT    NOT NULL UNIQUE,
+    scopes       TEXT    NOT NULL DEFAULT '[]',
+    created_at   INTEGER,
+    last_used_at INTEGER
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
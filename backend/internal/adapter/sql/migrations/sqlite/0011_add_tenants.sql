@@ -0,0 +1,45 @@
+-- every row belongs to a workspace, rows stored before workspaces existed
+-- to the default one; ids stay global, lookups check the tenant too
+ALTER TABLE todos ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE sessions ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE lists ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+
+-- tag names and emails are unique within a workspace only. A column
+-- constraint cannot be dropped in SQLite, so both tables are rebuilt; the
+-- migration runs with foreign keys off, the rows referencing them stay.
+-- The id sequences are carried over, so deleted ids are not reused.
+CREATE TABLE tags_new (
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    name     TEXT    NOT NULL,
+    name_key TEXT    NOT NULL,
+    color    TEXT    NOT NULL DEFAULT '',
+    tenant   TEXT    NOT NULL DEFAULT 'default'
+);
+
+INSERT INTO tags_new (id, name, name_key, color) SELECT id, name, name_key, color FROM tags;
+DELETE FROM sqlite_sequence WHERE name = 'tags_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'tags_new', seq FROM sqlite_sequence WHERE name = 'tags';
+DROP TABLE tags;
+ALTER TABLE tags_new RENAME TO tags;
+
+CREATE UNIQUE INDEX tags_tenant_name_key_idx ON tags (tenant, name_key);
+
+CREATE TABLE users_new (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    email         TEXT    NOT NULL,
+    password_hash TEXT    NOT NULL,
+    created_at    INTEGER,
+    tenant        TEXT    NOT NULL DEFAULT 'default'
+);
+
+INSERT INTO users_new (id, email, password_hash, created_at) SELECT id, email, password_hash, created_at FROM users;
+DELETE FROM sqlite_sequence WHERE name = 'users_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'users_new', seq FROM sqlite_sequence WHERE name = 'users';
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE UNIQUE INDEX users_tenant_email_idx ON users (tenant, email);
+
+CREATE INDEX todos_tenant_id_idx ON todos (tenant, id);
+CREATE INDEX lists_tenant_id_idx ON lists (tenant, id);
@@ -0,0 +1,11 @@
+-- events of stored changes wait here until every subscriber has handled
+-- them; the todo is kept as JSON, as it was when the event happened.
+-- AUTOINCREMENT keeps the ids of handled events from being reused
+CREATE TABLE outbox (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    type       TEXT    NOT NULL,
+    todo       TEXT    NOT NULL,
+    actor_id   INTEGER,
+    created_at INTEGER,
+    tenant     TEXT    NOT NULL DEFAULT 'default'
+);
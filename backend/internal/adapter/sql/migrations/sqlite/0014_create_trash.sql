@@ -0,0 +1,12 @@
+-- deleted todos wait here until they are restored or purged; the todo and
+-- the subtasks deleted along with it are kept as JSON. The id is the one of
+-- the deleted todo, ids of todos are never reused
+CREATE TABLE trash (
+    id         INTEGER PRIMARY KEY,
+    entry      TEXT    NOT NULL,
+    deleted_at INTEGER NOT NULL,
+    tenant     TEXT    NOT NULL DEFAULT 'default'
+);
+
+CREATE INDEX trash_tenant_deleted_at_idx ON trash (tenant, deleted_at);
+CREATE INDEX trash_deleted_at_idx ON trash (deleted_at);
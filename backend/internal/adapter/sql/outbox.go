@@ -0,0 +1,98 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (s *SQLTodoRepository) AddEvents(ctx context.Context, events ...domain.TodoEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Atomically(ctx, func(ctx context.Context) error {
+		for _, event := range events {
+			todo, err := json.Marshal(event.Todo)
+			if err != nil {
+				return fmt.Errorf("encode todo: %w", err)
+			}
+
+			actorID := sql.NullInt64{Int64: int64(event.ActorID), Valid: event.ActorID != 0}
+			_, err = s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+				`INSERT INTO outbox (type, todo, actor_id, created_at, tenant) VALUES (?, ?, ?, ?, ?)`),
+				string(event.Type), string(todo), actorID, toMicros(event.At), string(event.Tenant),
+			)
+			if err != nil {
+				return fmt.Errorf("insert event: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLTodoRepository) PendingEvents(ctx context.Context, limit int) ([]domain.TodoEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.q(ctx).QueryContext(ctx, s.dialect.rebind(
+		`SELECT id, type, todo, actor_id, created_at, tenant FROM outbox ORDER BY id LIMIT ?`), limit)
+	if err != nil {
+		return nil, fmt.Errorf("select events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]domain.TodoEvent, 0)
+	for rows.Next() {
+		var (
+			event     domain.TodoEvent
+			id        int64
+			todo      string
+			actorID   sql.NullInt64
+			createdAt sql.NullInt64
+		)
+		if err := rows.Scan(&id, &event.Type, &todo, &actorID, &createdAt, &event.Tenant); err != nil {
+			return nil, fmt.Errorf("scan event: %w", err)
+		}
+		if err := json.Unmarshal([]byte(todo), &event.Todo); err != nil {
+			return nil, fmt.Errorf("decode todo: %w", err)
+		}
+		event.ID = uint64(id)
+		event.ActorID = int(actorID.Int64)
+		event.At = fromMicros(createdAt)
+
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (s *SQLTodoRepository) AckEvents(ctx context.Context, ids ...uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = int64(id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	_, err := s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+		`DELETE FROM outbox WHERE id IN (`+placeholders+`)`), args...)
+	if err != nil {
+		return fmt.Errorf("delete events: %w", err)
+	}
+	return nil
+}
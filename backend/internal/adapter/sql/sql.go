@@ -0,0 +1,643 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const todoColumns = `id, title, description, completed, version,
+	priority, due_at, due_offset, created_at, updated_at, completed_at,
+	parent_id, checklist, recurrence, owner_id, list_id`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// querier is either the database or a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func scanTodo(row scanner) (domain.Todo, error) {
+	var (
+		todo                                   domain.Todo
+		dueAt, createdAt, updatedAt, completed sql.NullInt64
+		dueOffset                              int
+		parentID, listID                       sql.NullInt64
+		checklist                              string
+		recurrence                             sql.NullString
+		tagIDs                                 sql.NullString
+	)
+
+	err := row.Scan(&todo.ID, &todo.Title, &todo.Description, &todo.Completed, &todo.Version,
+		&todo.Priority, &dueAt, &dueOffset, &createdAt, &updatedAt, &completed,
+		&parentID, &checklist, &recurrence, &todo.OwnerID, &listID, &tagIDs)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		todo.ParentID = &id
+	}
+	if listID.Valid {
+		id := int(listID.Int64)
+		todo.ListID = &id
+	}
+	if err := json.Unmarshal([]byte(checklist), &todo.Checklist); err != nil {
+		return domain.Todo{}, fmt.Errorf("decode checklist: %w", err)
+	}
+	if len(todo.Checklist) == 0 {
+		todo.Checklist = nil
+	}
+	if recurrence.Valid {
+		if err := json.Unmarshal([]byte(recurrence.String), &todo.Recurrence); err != nil {
+			return domain.Todo{}, fmt.Errorf("decode recurrence: %w", err)
+		}
+	}
+
+	todo.Tags, err = parseTagIDs(tagIDs.String)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	if dueAt.Valid {
+		due := fromMicros(dueAt).In(zoneForOffset(dueOffset))
+		todo.DueAt = &due
+	}
+	todo.CreatedAt = fromMicros(createdAt)
+	todo.UpdatedAt = fromMicros(updatedAt)
+	if completed.Valid {
+		completedAt := fromMicros(completed)
+		todo.CompletedAt = &completedAt
+	}
+
+	return todo, nil
+}
+
+// parseTagIDs reads the output of Dialect.tagIDsColumn.
+func parseTagIDs(s string) ([]int, error) {
+	ids := []int{}
+	if s == "" {
+		return ids, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("parse tag id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// todoValues returns the stored columns of a todo except id and version,
+// in the order used by Save and UpdateByID.
+func todoValues(todo domain.Todo) ([]any, error) {
+	var (
+		dueAt     sql.NullInt64
+		dueOffset int
+	)
+	if todo.DueAt != nil {
+		dueAt = toMicros(*todo.DueAt)
+		_, dueOffset = todo.DueAt.Zone()
+	}
+
+	var completedAt sql.NullInt64
+	if todo.CompletedAt != nil {
+		completedAt = toMicros(*todo.CompletedAt)
+	}
+
+	var parentID sql.NullInt64
+	if todo.ParentID != nil {
+		parentID = sql.NullInt64{Int64: int64(*todo.ParentID), Valid: true}
+	}
+
+	var listID sql.NullInt64
+	if todo.ListID != nil {
+		listID = sql.NullInt64{Int64: int64(*todo.ListID), Valid: true}
+	}
+
+	checklist := []domain.ChecklistItem{}
+	if todo.Checklist != nil {
+		checklist = todo.Checklist
+	}
+	checklistJSON, err := json.Marshal(checklist)
+	if err != nil {
+		return nil, fmt.Errorf("encode checklist: %w", err)
+	}
+
+	var recurrence sql.NullString
+	if todo.Recurrence != nil {
+		b, err := json.Marshal(todo.Recurrence)
+		if err != nil {
+			return nil, fmt.Errorf("encode recurrence: %w", err)
+		}
+		recurrence = sql.NullString{String: string(b), Valid: true}
+	}
+
+	return []any{
+		todo.Title, todo.Description, todo.Completed,
+		int(todo.Priority), dueAt, dueOffset,
+		toMicros(todo.CreatedAt), toMicros(todo.UpdatedAt), completedAt,
+		parentID, string(checklistJSON), recurrence, todo.OwnerID, listID,
+	}, nil
+}
+
+// Times are stored as microseconds since the epoch: both dialects compare
+// and index integers the same way. The zero time is stored as NULL.
+func toMicros(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixMicro(), Valid: true}
+}
+
+func fromMicros(v sql.NullInt64) time.Time {
+	if !v.Valid {
+		return time.Time{}
+	}
+	return time.UnixMicro(v.Int64).UTC()
+}
+
+func zoneForOffset(offset int) *time.Location {
+	if offset == 0 {
+		return time.UTC
+	}
+	return time.FixedZone("", offset)
+}
+
+type SQLTodoRepository struct {
+	DB      *sql.DB
+	dialect Dialect
+}
+
+// Open connects to the database and brings its schema up to date.
+func Open(ctx context.Context, dialect Dialect, dsn string) (*SQLTodoRepository, error) {
+	db, err := sql.Open(dialect.driverName(), dialect.dsn(dsn))
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+
+	repo, err := New(ctx, db, dialect)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// New wraps an already opened database and applies pending migrations.
+func New(ctx context.Context, db *sql.DB, dialect Dialect) (*SQLTodoRepository, error) {
+	if err := migrate(ctx, db, dialect); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	return &SQLTodoRepository{
+		DB:      db,
+		dialect: dialect,
+	}, nil
+}
+
+func (s *SQLTodoRepository) Close() error {
+	return s.DB.Close()
+}
+
+// tenant returns the workspace every statement of the context is scoped to.
+func tenant(ctx context.Context) string {
+	return string(domain.CurrentTenant(ctx))
+}
+
+// selectTodo starts a query returning the columns scanTodo expects.
+func (s *SQLTodoRepository) selectTodo() string {
+	return `SELECT ` + todoColumns + `, ` + s.dialect.tagIDsColumn() + ` FROM todos`
+}
+
+// inTx runs fn in a transaction, committing when it returns nil. Within
+// Atomically fn joins its transaction, which commits later.
+func (s *SQLTodoRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if tx := s.tx(ctx); tx != nil {
+		return fn(tx)
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// setTodoTags replaces the tags of a todo. Selecting the tag in the insert
+// tells an unknown tag, or one of another workspace, apart without relying
+// on driver specific errors.
+func (s *SQLTodoRepository) setTodoTags(ctx context.Context, tx *sql.Tx, id int, tags []int) error {
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM todo_tags WHERE todo_id = ?`), id); err != nil {
+		return fmt.Errorf("delete todo tags: %w", err)
+	}
+
+	for _, tagID := range domain.NormalizeTagIDs(tags) {
+		res, err := tx.ExecContext(ctx, s.dialect.rebind(
+			`INSERT INTO todo_tags (todo_id, tag_id) SELECT ?, id FROM tags WHERE id = ? AND tenant = ?`), id, tagID, tenant(ctx))
+		if err != nil {
+			return fmt.Errorf("insert todo tag: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("rows affected: %w", err)
+		}
+		if n == 0 {
+			return domain.ErrTagNotExist
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLTodoRepository) Save(ctx context.Context, todo domain.Todo) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	values, err := todoValues(todo)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.checkParent(ctx, tx, 0, todo.ParentID); err != nil {
+			return err
+		}
+
+		err := tx.QueryRowContext(ctx, s.dialect.rebind(
+			`INSERT INTO todos (title, description, completed,
+				priority, due_at, due_offset, created_at, updated_at, completed_at,
+				parent_id, checklist, recurrence, owner_id, list_id, tenant)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+			append(values, tenant(ctx))...,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("insert todo: %w", err)
+		}
+
+		return s.setTodoTags(ctx, tx, id, todo.Tags)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *SQLTodoRepository) GetByID(ctx context.Context, id int) (domain.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Todo{}, err
+	}
+
+	todo, err := scanTodo(s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		s.selectTodo()+` WHERE id = ? AND tenant = ?`), id, tenant(ctx)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Todo{}, domain.ErrTodoNotExist
+	} else if err != nil {
+		return domain.Todo{}, fmt.Errorf("select todo: %w", err)
+	}
+
+	return todo, nil
+}
+
+func (s *SQLTodoRepository) UpdateByID(ctx context.Context, id int, todo domain.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	values, err := todoValues(todo)
+	if err != nil {
+		return err
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// the foreign key would reject a missing parent too, but without
+		// telling it apart from other violations
+		if err := s.checkParent(ctx, tx, id, todo.ParentID); err != nil {
+			return err
+		}
+
+		// the version check and the write are a single statement, so
+		// concurrent updates cannot both pass the check
+		args := append(values, id, tenant(ctx), todo.Version, todo.Version)
+		res, err := tx.ExecContext(ctx, s.dialect.rebind(
+			`UPDATE todos SET title = ?, description = ?, completed = ?,
+				priority = ?, due_at = ?, due_offset = ?, created_at = ?, updated_at = ?, completed_at = ?,
+				parent_id = ?, checklist = ?, recurrence = ?, owner_id = ?, list_id = ?,
+				version = version + 1
+			WHERE id = ? AND tenant = ? AND (? = 0 OR version = ?)`),
+			args...,
+		)
+		if err != nil {
+			return fmt.Errorf("update todo: %w", err)
+		}
+
+		if err := s.checkAffected(ctx, tx, res, id); err != nil {
+			return err
+		}
+
+		return s.setTodoTags(ctx, tx, id, todo.Tags)
+	})
+}
+
+func (s *SQLTodoRepository) DeleteByID(ctx context.Context, id int, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, s.dialect.rebind(
+			`DELETE FROM todos WHERE id = ? AND tenant = ? AND (? = 0 OR version = ?)`), id, tenant(ctx), version, version)
+		if err != nil {
+			return fmt.Errorf("delete todo: %w", err)
+		}
+		if err := s.checkAffected(ctx, tx, res, id); err != nil {
+			return err
+		}
+
+		// the foreign key cascades to the subtasks too, but only when the
+		// connection enables it
+		_, err = tx.ExecContext(ctx, s.dialect.rebind(`WITH RECURSIVE subtasks (id) AS (
+				SELECT id FROM todos WHERE parent_id = ?
+				UNION
+				SELECT todos.id FROM todos JOIN subtasks ON todos.parent_id = subtasks.id
+			)
+			DELETE FROM todos WHERE id IN (SELECT id FROM subtasks)`), id)
+		if err != nil {
+			return fmt.Errorf("delete subtasks: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// checkParent makes sure the parent exists and is not the todo itself or
+// one of its subtasks.
+func (s *SQLTodoRepository) checkParent(ctx context.Context, tx *sql.Tx, id int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+
+	// walks up from the parent; the todo showing up among the ancestors
+	// means the move would close a cycle. Ancestors share the workspace of
+	// the parent, so only the start is checked.
+	var found, cycle int
+	err := tx.QueryRowContext(ctx, s.dialect.rebind(`WITH RECURSIVE ancestors (id, parent_id) AS (
+			SELECT id, parent_id FROM todos WHERE id = ? AND tenant = ?
+			UNION
+			SELECT todos.id, todos.parent_id FROM todos JOIN ancestors ON todos.id = ancestors.parent_id
+		)
+		SELECT COUNT(*), COUNT(CASE WHEN id = ? THEN 1 END) FROM ancestors`), *parentID, tenant(ctx), id,
+	).Scan(&found, &cycle)
+	if err != nil {
+		return fmt.Errorf("check parent: %w", err)
+	}
+
+	switch {
+	case found == 0:
+		return domain.ErrParentNotExist
+	case cycle > 0:
+		return domain.ErrParentCycle
+	}
+	return nil
+}
+
+func (s *SQLTodoRepository) ReadAll(ctx context.Context) ([]domain.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Todo{}, err
+	}
+
+	return s.selectTodos(ctx, s.dialect.rebind(s.selectTodo()+` WHERE tenant = ? ORDER BY id`), tenant(ctx))
+}
+
+// Query translates the filter, order and keyset position into SQL, so only
+// the requested page leaves the database.
+func (s *SQLTodoRepository) Query(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Todo{}, err
+	}
+
+	where := []string{`tenant = ?`}
+	args := []any{tenant(ctx)}
+
+	if q.Filter.Completed != nil {
+		where = append(where, `completed = ?`)
+		args = append(args, *q.Filter.Completed)
+	}
+	if q.Filter.Title != "" {
+		where = append(where, `LOWER(title) LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(q.Filter.Title))
+	}
+	if q.Filter.Description != "" {
+		where = append(where, `LOWER(description) LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(q.Filter.Description))
+	}
+	if q.Filter.Search != "" {
+		where = append(where, `(LOWER(title) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\')`)
+		args = append(args, likePattern(q.Filter.Search), likePattern(q.Filter.Search))
+	}
+	if q.Filter.Priority != nil {
+		where = append(where, `priority = ?`)
+		args = append(args, int(*q.Filter.Priority))
+	}
+	if tags := domain.NormalizeTagIDs(q.Filter.Tags); len(tags) > 0 {
+		in := `todo_tags.todo_id = todos.id AND todo_tags.tag_id IN (?` + strings.Repeat(`, ?`, len(tags)-1) + `)`
+		for _, id := range tags {
+			args = append(args, id)
+		}
+		if q.Filter.AllTags {
+			where = append(where, `(SELECT COUNT(*) FROM todo_tags WHERE `+in+`) = ?`)
+			args = append(args, len(tags))
+		} else {
+			where = append(where, `EXISTS (SELECT 1 FROM todo_tags WHERE `+in+`)`)
+		}
+	}
+	if q.Filter.ParentID != nil {
+		where = append(where, `parent_id = ?`)
+		args = append(args, *q.Filter.ParentID)
+	}
+	if q.Filter.OwnerID != nil {
+		where = append(where, `owner_id = ?`)
+		args = append(args, *q.Filter.OwnerID)
+	}
+	if q.Filter.ListID != nil {
+		where = append(where, `list_id = ?`)
+		args = append(args, *q.Filter.ListID)
+	}
+	if v := q.Filter.Visible; v != nil {
+		cond := `(list_id IS NULL AND owner_id = ?)`
+		args = append(args, v.UserID)
+		if len(v.ListIDs) > 0 {
+			cond = `(` + cond + ` OR list_id IN (?` + strings.Repeat(`, ?`, len(v.ListIDs)-1) + `))`
+			for _, id := range v.ListIDs {
+				args = append(args, id)
+			}
+		}
+		where = append(where, cond)
+	}
+	if q.Filter.DueFrom != nil {
+		where = append(where, `due_at >= ?`)
+		args = append(args, q.Filter.DueFrom.UnixMicro())
+	}
+	if q.Filter.DueBefore != nil {
+		where = append(where, `due_at < ?`)
+		args = append(args, q.Filter.DueBefore.UnixMicro())
+	}
+
+	column, key := sortColumn(q.OrderBy())
+
+	dir := "ASC"
+	if q.Desc {
+		dir = "DESC"
+	}
+
+	if q.After != nil {
+		var cond string
+		cond, args = keysetCondition(column, key(*q.After), q.After.ID, q.Desc, args)
+		where = append(where, cond)
+	}
+
+	query := s.selectTodo() + ` WHERE ` + strings.Join(where, ` AND `)
+	query += ` ORDER BY `
+	if column == "due_at" {
+		// missing due dates go last, as in domain.TodoSort.Compare
+		query += `due_at IS NULL ` + dir + `, `
+	}
+	if column != "id" {
+		query += column + ` ` + dir + `, `
+	}
+	query += `id ` + dir
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	return s.selectTodos(ctx, s.dialect.rebind(query), args...)
+}
+
+func (s *SQLTodoRepository) selectTodos(ctx context.Context, query string, args ...any) ([]domain.Todo, error) {
+	rows, err := s.q(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return []domain.Todo{}, fmt.Errorf("select todos: %w", err)
+	}
+	defer rows.Close()
+
+	res := []domain.Todo{}
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return []domain.Todo{}, fmt.Errorf("scan todo: %w", err)
+		}
+		res = append(res, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return []domain.Todo{}, fmt.Errorf("read todos: %w", err)
+	}
+
+	return res, nil
+}
+
+// sortColumn maps a sort field to its column and to the value of that
+// column in a cursor pivot. A nil value stands for NULL.
+func sortColumn(sort domain.TodoSort) (string, func(domain.Todo) any) {
+	switch sort {
+	case domain.SortByTitle:
+		return "title", func(t domain.Todo) any { return t.Title }
+	case domain.SortByCompleted:
+		return "completed", func(t domain.Todo) any { return t.Completed }
+	case domain.SortByPriority:
+		return "priority", func(t domain.Todo) any { return int(t.Priority) }
+	case domain.SortByDue:
+		return "due_at", func(t domain.Todo) any {
+			if t.DueAt == nil {
+				return nil
+			}
+			return t.DueAt.UnixMicro()
+		}
+	}
+	return "id", func(t domain.Todo) any { return t.ID }
+}
+
+// keysetCondition selects rows strictly after the pivot in the given
+// order. NULLs, which only the due date can hold, sort after any value.
+func keysetCondition(column string, value any, id int, desc bool, args []any) (string, []any) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	switch {
+	case column == "id":
+		return `id ` + op + ` ?`, append(args, id)
+	case value == nil && !desc:
+		return `(` + column + ` IS NULL AND id > ?)`, append(args, id)
+	case value == nil:
+		return `(` + column + ` IS NOT NULL OR id < ?)`, append(args, id)
+	}
+
+	cond := column + ` ` + op + ` ? OR (` + column + ` = ? AND id ` + op + ` ?)`
+	if column == "due_at" && !desc {
+		cond = `due_at IS NULL OR ` + cond
+	}
+	return `(` + cond + `)`, append(args, value, value, id)
+}
+
+// likePattern builds a case-insensitive "contains" pattern.
+func likePattern(substr string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(strings.ToLower(substr)) + "%"
+}
+
+// checkAffected tells a missing todo from a failed version check when a
+// conditional statement touched no rows.
+func (s *SQLTodoRepository) checkAffected(ctx context.Context, q querier, res sql.Result, id int) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	var exists int
+	err = q.QueryRowContext(ctx, s.dialect.rebind(`SELECT 1 FROM todos WHERE id = ? AND tenant = ?`), id, tenant(ctx)).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrTodoNotExist
+	} else if err != nil {
+		return fmt.Errorf("check todo exists: %w", err)
+	}
+
+	return domain.ErrVersionMismatch
+}
@@ -0,0 +1,515 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/adapter/repotest"
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/usecase"
+)
+
+// openRepo opens a fresh SQLite database in a temp dir. When
+// TODO_TEST_POSTGRES_DSN is set the same tests can be pointed at Postgres
+// by running them with TODO_TEST_DIALECT=postgres.
+func openRepo(t *testing.T) *SQLTodoRepository {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if os.Getenv("TODO_TEST_DIALECT") == string(Postgres) {
+		dsn := os.Getenv("TODO_TEST_POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("TODO_TEST_POSTGRES_DSN is not set")
+		}
+
+		repo, err := Open(ctx, Postgres, dsn)
+		if err != nil {
+			t.Fatalf("open postgres: %v", err)
+		}
+		if _, err := repo.DB.ExecContext(ctx, `TRUNCATE todos, tags, users, sessions, api_keys, lists, webhooks, outbox, trash RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatalf("truncate todos: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	}
+
+	repo, err := Open(ctx, SQLite, filepath.Join(t.TempDir(), "todos.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func TestMigrate(t *testing.T) {
+	t.Run("applies migrations once", func(t *testing.T) {
+		// preparing
+		ctx := context.Background()
+		path := filepath.Join(t.TempDir(), "todos.db")
+
+		repo, err := Open(ctx, SQLite, path)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if _, err := repo.Save(ctx, domain.Todo{Title: "read the book"}); err != nil {
+			t.Fatalf("unexpected error on save: got %v, want nil", err)
+		}
+		repo.Close()
+
+		// act
+		reopened, err := Open(ctx, SQLite, path)
+		if err != nil {
+			t.Fatalf("unexpected error on reopen: got %v, want nil", err)
+		}
+		defer reopened.Close()
+
+		// assert
+		migrations, err := loadMigrations(SQLite)
+		if err != nil {
+			t.Fatalf("load migrations: %v", err)
+		}
+
+		var applied int
+		if err := reopened.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+			t.Fatalf("count migrations: %v", err)
+		}
+		if applied != len(migrations) {
+			t.Errorf("unexpected applied migrations: got %d, want %d", applied, len(migrations))
+		}
+
+		todos, err := reopened.ReadAll(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(todos) != 1 {
+			t.Errorf("data must survive reopen: got %d todos, want %d", len(todos), 1)
+		}
+	})
+
+	t.Run("moves existing rows into the default tenant", func(t *testing.T) {
+		// preparing
+		ctx := context.Background()
+		db, err := sql.Open(SQLite.driverName(), SQLite.dsn(filepath.Join(t.TempDir(), "todos.db")))
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		defer db.Close()
+
+		migrations, err := loadMigrations(SQLite)
+		if err != nil {
+			t.Fatalf("load migrations: %v", err)
+		}
+		if _, err := db.ExecContext(ctx, `CREATE TABLE schema_migrations (version INTEGER NOT NULL PRIMARY KEY)`); err != nil {
+			t.Fatalf("create schema_migrations: %v", err)
+		}
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("get connection: %v", err)
+		}
+		for _, m := range migrations {
+			if m.Version >= 11 {
+				break
+			}
+			if err := applyMigration(ctx, conn, SQLite, m); err != nil {
+				t.Fatalf("apply %s: %v", m.Name, err)
+			}
+		}
+		conn.Close()
+
+		// tags and users are rebuilt by the migration, the rows pointing
+		// to them must survive it
+		for _, stmt := range []string{
+			`INSERT INTO users (id, email, password_hash) VALUES (1, 'ann@example.com', 'x')`,
+			`INSERT INTO sessions (id, user_id, expires_at) VALUES ('s1', 1, 4102444800000000)`,
+			`INSERT INTO tags (id, name, name_key) VALUES (7, 'Work', 'work')`,
+			`INSERT INTO todos (id, title, owner_id) VALUES (1, 'read the book', 1)`,
+			`INSERT INTO todo_tags (todo_id, tag_id) VALUES (1, 7)`,
+		} {
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				t.Fatalf("seed %q: %v", stmt, err)
+			}
+		}
+
+		// act
+		repo, err := New(ctx, db, SQLite)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		if _, err := repo.GetSession(ctx, "s1"); err != nil {
+			t.Errorf("session must survive the migration: got %v, want nil", err)
+		}
+		todo, err := repo.GetByID(ctx, 1)
+		if err != nil {
+			t.Fatalf("unexpected error on get: got %v, want nil", err)
+		}
+		if len(todo.Tags) != 1 || todo.Tags[0] != 7 {
+			t.Errorf("todo tags must survive the migration: got %v, want %v", todo.Tags, []int{7})
+		}
+
+		// the tag ids continue after the old ones
+		tagID, err := repo.SaveTag(ctx, domain.Tag{Name: "home"})
+		if err != nil {
+			t.Fatalf("unexpected error on save tag: got %v, want nil", err)
+		}
+		if tagID <= 7 {
+			t.Errorf("unexpected tag id: got %d, want more than %d", tagID, 7)
+		}
+
+		var foreignKeys int
+		if err := db.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&foreignKeys); err != nil {
+			t.Fatalf("read foreign_keys: %v", err)
+		}
+		if foreignKeys != 1 {
+			t.Errorf("foreign keys must be on again: got %d, want %d", foreignKeys, 1)
+		}
+	})
+
+	t.Run("instances starting together migrate one at a time", func(t *testing.T) {
+		dsn := os.Getenv("TODO_TEST_POSTGRES_DSN")
+		if os.Getenv("TODO_TEST_DIALECT") != string(Postgres) || dsn == "" {
+			t.Skip("runs against Postgres only")
+		}
+
+		// preparing
+		ctx := context.Background()
+		var dbs []*sql.DB
+		for range 4 {
+			db, err := sql.Open(Postgres.driverName(), Postgres.dsn(dsn))
+			if err != nil {
+				t.Fatalf("open postgres: %v", err)
+			}
+			defer db.Close()
+			dbs = append(dbs, db)
+		}
+		if _, err := dbs[0].ExecContext(ctx, `DROP SCHEMA public CASCADE; CREATE SCHEMA public`); err != nil {
+			t.Fatalf("reset schema: %v", err)
+		}
+
+		// act
+		errs := make(chan error, len(dbs))
+		for _, db := range dbs {
+			go func() { errs <- migrate(ctx, db, Postgres) }()
+		}
+
+		// assert
+		for range dbs {
+			if err := <-errs; err != nil {
+				t.Errorf("unexpected error: got %v, want nil", err)
+			}
+		}
+
+		migrations, err := loadMigrations(Postgres)
+		if err != nil {
+			t.Fatalf("load migrations: %v", err)
+		}
+		var applied int
+		if err := dbs[0].QueryRowContext(ctx, `SELECT count(*) FROM schema_migrations`).Scan(&applied); err != nil {
+			t.Fatalf("count applied migrations: %v", err)
+		}
+		if applied != len(migrations) {
+			t.Errorf("unexpected applied migrations: got %d, want %d", applied, len(migrations))
+		}
+	})
+
+	t.Run("dialects have the same versions", func(t *testing.T) {
+		sqlite, err := loadMigrations(SQLite)
+		if err != nil {
+			t.Fatalf("load sqlite migrations: %v", err)
+		}
+		postgres, err := loadMigrations(Postgres)
+		if err != nil {
+			t.Fatalf("load postgres migrations: %v", err)
+		}
+
+		if len(sqlite) != len(postgres) {
+			t.Fatalf("unexpected migrations count: sqlite %d, postgres %d", len(sqlite), len(postgres))
+		}
+		for i := range sqlite {
+			if sqlite[i].Version != postgres[i].Version {
+				t.Errorf("unexpected version: sqlite %d, postgres %d", sqlite[i].Version, postgres[i].Version)
+			}
+		}
+	})
+}
+
+func TestSave(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// preparing
+		repo := openRepo(t)
+		ctx := context.Background()
+
+		inputTodo := domain.Todo{
+			Title:       "read the book",
+			Description: "chapter one",
+			Completed:   true,
+		}
+
+		// act
+		id, err := repo.Save(ctx, inputTodo)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		wantID := 1
+		if id != wantID {
+			t.Fatalf("must return correct id: got %d, want %d", id, wantID)
+		}
+
+		todo, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error on get: got %v, want nil", err)
+		}
+
+		inputTodo.ID = id
+		inputTodo.Version = 1
+		if !todo.Equal(inputTodo) {
+			t.Errorf("unexpected todo: got %+v, want %+v", todo, inputTodo)
+		}
+	})
+
+	t.Run("ids of deleted todos are not reused", func(t *testing.T) {
+		// preparing
+		repo := openRepo(t)
+		ctx := context.Background()
+
+		id, _ := repo.Save(ctx, domain.Todo{Title: "read the book"})
+		if err := repo.DeleteByID(ctx, id, 0); err != nil {
+			t.Fatalf("unexpected error on delete: got %v, want nil", err)
+		}
+
+		// act
+		nextID, err := repo.Save(ctx, domain.Todo{Title: "complete the game"})
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if nextID == id {
+			t.Errorf("id must not be reused: got %d", nextID)
+		}
+	})
+}
+
+func TestUpdateByID(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// preparing
+		repo := openRepo(t)
+		ctx := context.Background()
+
+		id, _ := repo.Save(ctx, domain.Todo{Title: "read the book"})
+
+		// act
+		err := repo.UpdateByID(ctx, id, domain.Todo{Title: "complete the game", Completed: true})
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		todo, _ := repo.GetByID(ctx, id)
+		if todo.Title != "complete the game" || !todo.Completed {
+			t.Errorf("todo was not updated: got %+v", todo)
+		}
+	})
+
+	t.Run("todoNotExist -> error", func(t *testing.T) {
+		// preparing
+		repo := openRepo(t)
+
+		// act
+		err := repo.UpdateByID(context.Background(), 1, domain.Todo{Title: "read the book"})
+
+		// assert
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+}
+
+func TestDeleteByID(t *testing.T) {
+	t.Run("todoNotExist -> error", func(t *testing.T) {
+		// preparing
+		repo := openRepo(t)
+
+		// act
+		err := repo.DeleteByID(context.Background(), 1, 0)
+
+		// assert
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+	})
+}
+
+func TestReadAll(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// preparing
+		repo := openRepo(t)
+		ctx := context.Background()
+
+		titles := []string{"read the book", "complete the game", "get an internship"}
+		for _, title := range titles {
+			repo.Save(ctx, domain.Todo{Title: title})
+		}
+
+		// act
+		todos, err := repo.ReadAll(ctx)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		if len(todos) != len(titles) {
+			t.Fatalf("unexpected length: got %d, want %d", len(todos), len(titles))
+		}
+
+		for i, todo := range todos {
+			if todo.Title != titles[i] {
+				t.Errorf("unexpected title: got %s, want %s", todo.Title, titles[i])
+			}
+		}
+	})
+
+	t.Run("context canceled -> error", func(t *testing.T) {
+		// preparing
+		repo := openRepo(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// act
+		_, err := repo.ReadAll(ctx)
+
+		// assert
+		if !errors.Is(err, ctx.Err()) {
+			t.Fatalf("unexpected error: got %v, want %v", err, ctx.Err())
+		}
+	})
+}
+
+func TestRebind(t *testing.T) {
+	query := `UPDATE todos SET title = ? WHERE id = ?`
+
+	if got := SQLite.rebind(query); got != query {
+		t.Errorf("sqlite must keep placeholders: got %q", got)
+	}
+
+	want := `UPDATE todos SET title = $1 WHERE id = $2`
+	if got := Postgres.rebind(query); got != want {
+		t.Errorf("unexpected postgres query: got %q, want %q", got, want)
+	}
+}
+
+func TestDSN(t *testing.T) {
+	const defaults = "_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{name: "plain path", dsn: "todo.db", want: "todo.db?" + defaults},
+		{name: "other options kept", dsn: "file:todo.db?cache=shared", want: "file:todo.db?cache=shared&" + defaults},
+		{name: "trailing question mark", dsn: "todo.db?", want: "todo.db?" + defaults},
+		{
+			name: "options set by the dsn win",
+			dsn:  "todo.db?_busy_timeout=100&_fk=off",
+			want: "todo.db?_busy_timeout=100&_fk=off&_journal_mode=WAL&_txlock=immediate",
+		},
+		{
+			name: "every option set",
+			dsn:  "todo.db?_foreign_keys=on&_timeout=1&_journal=DELETE&_txlock=deferred",
+			want: "todo.db?_foreign_keys=on&_timeout=1&_journal=DELETE&_txlock=deferred",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SQLite.dsn(tc.dsn); got != tc.want {
+				t.Errorf("unexpected dsn: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("postgres untouched", func(t *testing.T) {
+		dsn := "postgres://localhost/todo?sslmode=disable"
+		if got := Postgres.dsn(dsn); got != dsn {
+			t.Errorf("unexpected dsn: got %q, want %q", got, dsn)
+		}
+	})
+
+	t.Run("options applied to a dsn with options", func(t *testing.T) {
+		dsn := "file:" + filepath.Join(t.TempDir(), "todos.db") + "?cache=shared"
+		db, err := sql.Open(SQLite.driverName(), SQLite.dsn(dsn))
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		defer db.Close()
+
+		var fk int
+		var journal string
+		if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&fk); err != nil {
+			t.Fatalf("read foreign_keys: %v", err)
+		}
+		if err := db.QueryRow(`PRAGMA journal_mode`).Scan(&journal); err != nil {
+			t.Fatalf("read journal_mode: %v", err)
+		}
+		if fk != 1 || journal != "wal" {
+			t.Errorf("unexpected pragmas: got foreign_keys=%d journal_mode=%s, want 1 and wal", fk, journal)
+		}
+	})
+}
+
+func TestConformance(t *testing.T) {
+	repotest.RunConformance(t, func(t *testing.T) usecase.TodoRepository {
+		return openRepo(t)
+	})
+}
+
+func TestUserConformance(t *testing.T) {
+	repotest.RunUserConformance(t, func(t *testing.T) usecase.UserRepository {
+		return openRepo(t)
+	})
+}
+
+func TestListConformance(t *testing.T) {
+	repotest.RunListConformance(t, func(t *testing.T) usecase.ListRepository {
+		return openRepo(t)
+	})
+}
+
+func TestWebhookConformance(t *testing.T) {
+	repotest.RunWebhookConformance(t, func(t *testing.T) repotest.WebhookRepository {
+		return openRepo(t)
+	})
+}
+
+func TestTenantConformance(t *testing.T) {
+	repotest.RunTenantConformance(t, func(t *testing.T) repotest.TenantRepository {
+		return openRepo(t)
+	})
+}
+
+func TestOutboxConformance(t *testing.T) {
+	repotest.RunOutboxConformance(t, func(t *testing.T) repotest.OutboxRepository {
+		return openRepo(t)
+	})
+}
+
+func TestTrashConformance(t *testing.T) {
+	repotest.RunTrashConformance(t, func(t *testing.T) repotest.TrashRepository {
+		return openRepo(t)
+	})
+}
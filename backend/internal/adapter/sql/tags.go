@@ -0,0 +1,132 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (s *SQLTodoRepository) SaveTag(ctx context.Context, tag domain.Tag) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err := s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		`INSERT INTO tags (name, name_key, color, tenant) VALUES (?, ?, ?, ?) RETURNING id`),
+		tag.Name, domain.TagKey(tag.Name), tag.Color, tenant(ctx),
+	).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, domain.ErrTagExists
+	} else if err != nil {
+		return 0, fmt.Errorf("insert tag: %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *SQLTodoRepository) GetTagByID(ctx context.Context, id int) (domain.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Tag{}, err
+	}
+
+	var tag domain.Tag
+	err := s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		`SELECT id, name, color FROM tags WHERE id = ? AND tenant = ?`), id, tenant(ctx),
+	).Scan(&tag.ID, &tag.Name, &tag.Color)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Tag{}, domain.ErrTagNotExist
+	} else if err != nil {
+		return domain.Tag{}, fmt.Errorf("select tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+func (s *SQLTodoRepository) UpdateTagByID(ctx context.Context, id int, tag domain.Tag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+		`UPDATE tags SET name = ?, name_key = ?, color = ? WHERE id = ? AND tenant = ?`),
+		tag.Name, domain.TagKey(tag.Name), tag.Color, id, tenant(ctx),
+	)
+	if isUniqueViolation(err) {
+		return domain.ErrTagExists
+	} else if err != nil {
+		return fmt.Errorf("update tag: %w", err)
+	}
+
+	return checkTagAffected(res)
+}
+
+func (s *SQLTodoRepository) DeleteTagByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// a tag of another workspace is not deleted below, which rolls back the
+	// statements before
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// the tagged todos change, so their versions move on
+		_, err := tx.ExecContext(ctx, s.dialect.rebind(
+			`UPDATE todos SET version = version + 1
+			WHERE tenant = ? AND id IN (SELECT todo_id FROM todo_tags WHERE tag_id = ?)`), tenant(ctx), id)
+		if err != nil {
+			return fmt.Errorf("bump todo versions: %w", err)
+		}
+
+		// the foreign key cascades too, but only when the connection enables it
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM todo_tags WHERE tag_id = ?`), id); err != nil {
+			return fmt.Errorf("delete todo tags: %w", err)
+		}
+
+		res, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM tags WHERE id = ? AND tenant = ?`), id, tenant(ctx))
+		if err != nil {
+			return fmt.Errorf("delete tag: %w", err)
+		}
+
+		return checkTagAffected(res)
+	})
+}
+
+func (s *SQLTodoRepository) ReadAllTags(ctx context.Context) ([]domain.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return []domain.Tag{}, err
+	}
+
+	rows, err := s.q(ctx).QueryContext(ctx, s.dialect.rebind(`SELECT id, name, color FROM tags WHERE tenant = ? ORDER BY id`), tenant(ctx))
+	if err != nil {
+		return []domain.Tag{}, fmt.Errorf("select tags: %w", err)
+	}
+	defer rows.Close()
+
+	res := []domain.Tag{}
+	for rows.Next() {
+		var tag domain.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Color); err != nil {
+			return []domain.Tag{}, fmt.Errorf("scan tag: %w", err)
+		}
+		res = append(res, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return []domain.Tag{}, fmt.Errorf("read tags: %w", err)
+	}
+
+	return res, nil
+}
+
+func checkTagAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrTagNotExist
+	}
+	return nil
+}
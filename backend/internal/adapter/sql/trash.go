@@ -0,0 +1,281 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func scanTrashed(row scanner) (domain.TrashedTodo, error) {
+	var (
+		entry     domain.TrashedTodo
+		data      string
+		deletedAt sql.NullInt64
+	)
+	if err := row.Scan(&data, &deletedAt); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return domain.TrashedTodo{}, fmt.Errorf("decode trash entry: %w", err)
+	}
+	entry.DeletedAt = fromMicros(deletedAt)
+
+	return entry, nil
+}
+
+func (s *SQLTodoRepository) TrashByID(ctx context.Context, id int, version int, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Atomically(ctx, func(ctx context.Context) error {
+		todo, err := s.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != todo.Version {
+			return domain.ErrVersionMismatch
+		}
+
+		subtasks, err := s.selectTodos(ctx, s.dialect.rebind(`WITH RECURSIVE subtasks (id) AS (
+				SELECT id FROM todos WHERE parent_id = ?
+				UNION
+				SELECT todos.id FROM todos JOIN subtasks ON todos.parent_id = subtasks.id
+			)
+			`+s.selectTodo()+` WHERE id IN (SELECT id FROM subtasks) ORDER BY id`), id)
+		if err != nil {
+			return err
+		}
+
+		entry := domain.TrashedTodo{Todo: todo, Subtasks: parentFirst(id, subtasks), DeletedAt: at}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("encode trash entry: %w", err)
+		}
+
+		_, err = s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+			`INSERT INTO trash (id, entry, deleted_at, tenant) VALUES (?, ?, ?, ?)`),
+			id, string(data), toMicros(at), tenant(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("insert trash entry: %w", err)
+		}
+
+		return s.DeleteByID(ctx, id, 0)
+	})
+}
+
+// parentFirst orders the subtasks of the todo so that each comes after its
+// parent, siblings by id. The subtasks must be sorted by id.
+func parentFirst(id int, subtasks []domain.Todo) []domain.Todo {
+	children := map[int][]domain.Todo{}
+	for _, v := range subtasks {
+		children[*v.ParentID] = append(children[*v.ParentID], v)
+	}
+
+	res := append([]domain.Todo{}, children[id]...)
+	for i := 0; i < len(res); i++ {
+		res = append(res, children[res[i].ID]...)
+	}
+	return res
+}
+
+func (s *SQLTodoRepository) ReadTrash(ctx context.Context) ([]domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.q(ctx).QueryContext(ctx, s.dialect.rebind(
+		`SELECT entry, deleted_at FROM trash WHERE tenant = ? ORDER BY deleted_at DESC, id DESC`), tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("select trash: %w", err)
+	}
+	defer rows.Close()
+
+	res := []domain.TrashedTodo{}
+	for rows.Next() {
+		entry, err := scanTrashed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan trash entry: %w", err)
+		}
+		res = append(res, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read trash: %w", err)
+	}
+
+	return res, nil
+}
+
+func (s *SQLTodoRepository) GetTrashedByID(ctx context.Context, id int) (domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+
+	entry, err := scanTrashed(s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		`SELECT entry, deleted_at FROM trash WHERE id = ? AND tenant = ?`), id, tenant(ctx)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.TrashedTodo{}, domain.ErrTodoNotExist
+	} else if err != nil {
+		return domain.TrashedTodo{}, fmt.Errorf("select trash entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+func (s *SQLTodoRepository) RestoreByID(ctx context.Context, id int) (domain.TrashedTodo, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.TrashedTodo{}, err
+	}
+
+	var entry domain.TrashedTodo
+	err := s.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		entry, err = s.GetTrashedByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		tags, err := s.existingTags(ctx, entry.Todos())
+		if err != nil {
+			return err
+		}
+
+		parentExists := false
+		if entry.ParentID != nil {
+			_, err := s.GetByID(ctx, *entry.ParentID)
+			if err != nil && !errors.Is(err, domain.ErrTodoNotExist) {
+				return err
+			}
+			parentExists = err == nil
+		}
+
+		todos := entry.Restored(func(id int) bool { return tags[id] }, parentExists)
+		for _, todo := range todos {
+			if err := s.insertTodo(ctx, todo); err != nil {
+				return err
+			}
+		}
+		entry.Todo, entry.Subtasks = todos[0], todos[1:]
+
+		_, err = s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+			`DELETE FROM trash WHERE id = ? AND tenant = ?`), id, tenant(ctx))
+		if err != nil {
+			return fmt.Errorf("delete trash entry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.TrashedTodo{}, err
+	}
+
+	return entry, nil
+}
+
+// existingTags tells which of the tags of the todos are still stored.
+func (s *SQLTodoRepository) existingTags(ctx context.Context, todos []domain.Todo) (map[int]bool, error) {
+	var ids []int
+	for _, todo := range todos {
+		ids = append(ids, todo.Tags...)
+	}
+	ids = domain.NormalizeTagIDs(ids)
+
+	tags := map[int]bool{}
+	if len(ids) == 0 {
+		return tags, nil
+	}
+
+	args := []any{tenant(ctx)}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := s.q(ctx).QueryContext(ctx, s.dialect.rebind(
+		`SELECT id FROM tags WHERE tenant = ? AND id IN (?`+strings.Repeat(`, ?`, len(ids)-1)+`)`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("select tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		tags[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// insertTodo stores a restored todo under its own id and version. Must be
+// called within Atomically.
+func (s *SQLTodoRepository) insertTodo(ctx context.Context, todo domain.Todo) error {
+	values, err := todoValues(todo)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+		`INSERT INTO todos (title, description, completed,
+			priority, due_at, due_offset, created_at, updated_at, completed_at,
+			parent_id, checklist, recurrence, owner_id, list_id, id, version, tenant)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		append(values, todo.ID, todo.Version, tenant(ctx))...,
+	)
+	if err != nil {
+		return fmt.Errorf("insert todo: %w", err)
+	}
+
+	return s.setTodoTags(ctx, s.tx(ctx), todo.ID, todo.Tags)
+}
+
+func (s *SQLTodoRepository) PurgeByID(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+		`DELETE FROM trash WHERE id = ? AND tenant = ?`), id, tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("delete trash entry: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrTodoNotExist
+	}
+
+	return nil
+}
+
+func (s *SQLTodoRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	// every workspace at once, as the retention is the same for all
+	res, err := s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+		`DELETE FROM trash WHERE deleted_at < ?`), before.UnixMicro())
+	if err != nil {
+		return 0, fmt.Errorf("delete trash entries: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	return int(n), nil
+}
@@ -0,0 +1,53 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type txKey struct{}
+
+type sqlTx struct {
+	repo *SQLTodoRepository
+	tx   *sql.Tx
+}
+
+// Atomically runs fn in a database transaction, which the calls made with
+// the context handed to fn share, and commits it when fn returns nil.
+func (s *SQLTodoRepository) Atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, sqlTx{repo: s, tx: tx})); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// tx returns the transaction of the repository running in ctx, if any.
+func (s *SQLTodoRepository) tx(ctx context.Context) *sql.Tx {
+	if tx, ok := ctx.Value(txKey{}).(sqlTx); ok && tx.repo == s {
+		return tx.tx
+	}
+	return nil
+}
+
+// q returns the transaction running in ctx, or the database without one.
+func (s *SQLTodoRepository) q(ctx context.Context) querier {
+	if tx := s.tx(ctx); tx != nil {
+		return tx
+	}
+	return s.DB
+}
@@ -0,0 +1,119 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (s *SQLTodoRepository) SaveUser(ctx context.Context, user domain.User) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err := s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		`INSERT INTO users (email, password_hash, created_at, tenant) VALUES (?, ?, ?, ?) RETURNING id`),
+		user.Email, user.PasswordHash, toMicros(user.CreatedAt), tenant(ctx),
+	).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, domain.ErrUserExists
+	} else if err != nil {
+		return 0, fmt.Errorf("insert user: %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *SQLTodoRepository) GetUserByID(ctx context.Context, id int) (domain.User, error) {
+	return s.selectUser(ctx, `id = ?`, id)
+}
+
+func (s *SQLTodoRepository) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	return s.selectUser(ctx, `email = ?`, email)
+}
+
+func (s *SQLTodoRepository) selectUser(ctx context.Context, cond string, arg any) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, err
+	}
+
+	var (
+		user      domain.User
+		createdAt sql.NullInt64
+	)
+	err := s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		`SELECT id, email, password_hash, created_at FROM users WHERE tenant = ? AND `+cond), tenant(ctx), arg,
+	).Scan(&user.ID, &user.Email, &user.PasswordHash, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.User{}, domain.ErrUserNotExist
+	} else if err != nil {
+		return domain.User{}, fmt.Errorf("select user: %w", err)
+	}
+	user.CreatedAt = fromMicros(createdAt)
+
+	return user, nil
+}
+
+func (s *SQLTodoRepository) SaveSession(ctx context.Context, session domain.Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+		`INSERT INTO sessions (id, user_id, created_at, expires_at, tenant) VALUES (?, ?, ?, ?, ?)`),
+		session.ID, session.UserID, toMicros(session.CreatedAt), toMicros(session.ExpiresAt), tenant(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("insert session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLTodoRepository) GetSession(ctx context.Context, id string) (domain.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Session{}, err
+	}
+
+	var (
+		session              domain.Session
+		createdAt, expiresAt sql.NullInt64
+	)
+	err := s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		`SELECT id, user_id, created_at, expires_at FROM sessions WHERE id = ? AND tenant = ?`), id, tenant(ctx),
+	).Scan(&session.ID, &session.UserID, &createdAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Session{}, domain.ErrSessionNotExist
+	} else if err != nil {
+		return domain.Session{}, fmt.Errorf("select session: %w", err)
+	}
+	session.CreatedAt = fromMicros(createdAt)
+	session.ExpiresAt = fromMicros(expiresAt)
+
+	return session, nil
+}
+
+func (s *SQLTodoRepository) DeleteSession(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.q(ctx).ExecContext(ctx, s.dialect.rebind(`DELETE FROM sessions WHERE id = ? AND tenant = ?`), id, tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrSessionNotExist
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const webhookColumns = `id, user_id, url, events, secret, created_at`
+
+func (s *SQLTodoRepository) SaveWebhook(ctx context.Context, hook domain.Webhook) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	events := []domain.TodoEventType{}
+	if hook.Events != nil {
+		events = hook.Events
+	}
+	eventsJSON, err := json.Marshal(events)
+	if err != nil {
+		return 0, fmt.Errorf("encode events: %w", err)
+	}
+
+	var id int
+	err = s.q(ctx).QueryRowContext(ctx, s.dialect.rebind(
+		`INSERT INTO webhooks (user_id, url, events, secret, created_at, tenant)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id`),
+		hook.UserID, hook.URL, string(eventsJSON), hook.Secret, toMicros(hook.CreatedAt), tenant(ctx),
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert webhook: %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *SQLTodoRepository) ListWebhooks(ctx context.Context, userID int) ([]domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.q(ctx).QueryContext(ctx, s.dialect.rebind(
+		`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? AND tenant = ? ORDER BY id`), userID, tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("select webhooks: %w", err)
+	}
+
+	return scanWebhooks(rows)
+}
+
+func (s *SQLTodoRepository) ReadAllWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.q(ctx).QueryContext(ctx, s.dialect.rebind(
+		`SELECT `+webhookColumns+` FROM webhooks WHERE tenant = ? ORDER BY id`), tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("select webhooks: %w", err)
+	}
+
+	return scanWebhooks(rows)
+}
+
+func (s *SQLTodoRepository) DeleteWebhook(ctx context.Context, userID, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.q(ctx).ExecContext(ctx, s.dialect.rebind(
+		`DELETE FROM webhooks WHERE id = ? AND user_id = ? AND tenant = ?`), id, userID, tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrWebhookNotExist
+	}
+	return nil
+}
+
+func scanWebhooks(rows *sql.Rows) ([]domain.Webhook, error) {
+	defer rows.Close()
+
+	hooks := make([]domain.Webhook, 0)
+	for rows.Next() {
+		var (
+			hook      domain.Webhook
+			events    string
+			createdAt sql.NullInt64
+		)
+		if err := rows.Scan(&hook.ID, &hook.UserID, &hook.URL, &events, &hook.Secret, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", err)
+		}
+		if err := json.Unmarshal([]byte(events), &hook.Events); err != nil {
+			return nil, fmt.Errorf("decode events: %w", err)
+		}
+		hook.CreatedAt = fromMicros(createdAt)
+
+		hooks = append(hooks, hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate webhooks: %w", err)
+	}
+
+	return hooks, nil
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// test vectors for PBKDF2-HMAC-SHA256 from RFC 7914, section 11
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		keyLen     int
+		want       string
+	}{
+		{
+			password:   "passwd",
+			salt:       "salt",
+			iterations: 1,
+			keyLen:     64,
+			want: "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+				"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783",
+		},
+		{
+			password:   "Password",
+			salt:       "NaCl",
+			iterations: 80000,
+			keyLen:     64,
+			want: "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56" +
+				"a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.password, func(t *testing.T) {
+			// act
+			got := pbkdf2([]byte(tc.password), []byte(tc.salt), tc.iterations, tc.keyLen)
+
+			// assert
+			if hex.EncodeToString(got) != tc.want {
+				t.Errorf("unexpected key: got %x, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	// preparing
+	hash, err := HashPassword("correct horse", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	other, err := HashPassword("correct horse", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+		wantErr  error
+	}{
+		{name: "matching password", password: "correct horse", hash: hash, want: true},
+		{name: "wrong password", password: "correct horse!", hash: hash},
+		{name: "empty password", password: "", hash: hash},
+		{name: "unknown scheme -> error", password: "correct horse", hash: "bcrypt$10$abc$def", wantErr: ErrInvalidHash},
+		{name: "malformed hash -> error", password: "correct horse", hash: "pbkdf2-sha256$ten$abc$def", wantErr: ErrInvalidHash},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			got, err := VerifyPassword(tc.password, tc.hash)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("unexpected result: got %t, want %t", got, tc.want)
+			}
+		})
+	}
+
+	if hash == other {
+		t.Errorf("hashes of the same password must differ by salt: got %q twice", hash)
+	}
+	if !strings.HasPrefix(hash, "pbkdf2-sha256$10$") {
+		t.Errorf("unexpected hash format: got %q", hash)
+	}
+}
+
+func TestToken(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+	claims := Claims{Subject: "1", SessionID: "abc", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()}
+
+	token, err := SignToken(secret, claims)
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	parts := strings.Split(token, ".")
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{name: "valid token", secret: secret, token: token, now: now},
+		{name: "expired token -> error", secret: secret, token: token, now: now.Add(time.Hour), wantErr: ErrInvalidToken},
+		{name: "other secret -> error", secret: []byte("other"), token: token, now: now, wantErr: ErrInvalidToken},
+		{name: "not a jwt -> error", secret: secret, token: "abc", now: now, wantErr: ErrInvalidToken},
+		{
+			name:    "tampered claims -> error",
+			secret:  secret,
+			token:   parts[0] + "." + parts[1] + "x." + parts[2],
+			now:     now,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			// {"alg":"none","typ":"JWT"}
+			name:    "alg none -> error",
+			secret:  secret,
+			token:   "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." + parts[1] + ".",
+			now:     now,
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			got, err := ParseToken(tc.secret, tc.token, tc.now)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && got != claims {
+				t.Errorf("unexpected claims: got %+v, want %+v", got, claims)
+			}
+		})
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	// act
+	first, err := GenerateKey("todo_")
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	second, err := GenerateKey("todo_")
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+
+	// assert
+	if !strings.HasPrefix(first, "todo_") {
+		t.Errorf("key must start with the prefix: got %q", first)
+	}
+	if first == second {
+		t.Errorf("keys must differ: got %q twice", first)
+	}
+	if HashKey(first) != HashKey(first) || HashKey(first) == HashKey(second) {
+		t.Errorf("hash must be deterministic and tell keys apart")
+	}
+	// sha256("abc")
+	if got, want := HashKey("abc"), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got != want {
+		t.Errorf("unexpected hash: got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// keyBytes is the entropy of a generated key.
+const keyBytes = 32
+
+// GenerateKey returns a random key starting with the prefix.
+func GenerateKey(prefix string) (string, error) {
+	b := make([]byte, keyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate key: %w", err)
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashKey returns the hex SHA-256 of a generated key. Unlike passwords,
+// generated keys are random enough that a fast hash does not make them
+// guessable, and a plain hash can be looked up.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
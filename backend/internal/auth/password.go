@@ -0,0 +1,113 @@
+// Package auth implements the cryptography behind user accounts: password
+// hashing with PBKDF2-HMAC-SHA256 (RFC 8018) and HS256-signed JSON Web
+// Tokens (RFC 7519). It only uses the standard library.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultIterations is the PBKDF2-HMAC-SHA256 work factor recommended by
+// OWASP.
+const DefaultIterations = 600_000
+
+const (
+	hashScheme = "pbkdf2-sha256"
+	saltLength = 16
+	keyLength  = 32
+)
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// HashPassword derives a key from the password and a random salt. The
+// result is self-describing, "pbkdf2-sha256$<iterations>$<salt>$<key>",
+// so the work factor can be raised without invalidating stored hashes.
+func HashPassword(password string, iterations int) (string, error) {
+	if iterations < 1 {
+		return "", fmt.Errorf("iterations must be positive, got %d", iterations)
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+
+	key := pbkdf2([]byte(password), salt, iterations, keyLength)
+
+	return strings.Join([]string{
+		hashScheme,
+		strconv.Itoa(iterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+// VerifyPassword reports whether the password matches a hash made by
+// HashPassword. The keys are compared in constant time.
+func VerifyPassword(password, hash string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return false, ErrInvalidHash
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false, ErrInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, ErrInvalidHash
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(want) == 0 {
+		return false, ErrInvalidHash
+	}
+
+	got := pbkdf2([]byte(password), salt, iterations, len(want))
+
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
+
+// pbkdf2 implements PBKDF2 (RFC 8018, section 5.2) with HMAC-SHA256.
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	key := make([]byte, 0, blocks*hashLen)
+	var counter [4]byte
+	u := make([]byte, hashLen)
+	t := make([]byte, hashLen)
+
+	for block := 1; block <= blocks; block++ {
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(counter[:])
+		u = prf.Sum(u[:0])
+		copy(t, u)
+
+		for range iterations - 1 {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+
+		key = append(key, t...)
+	}
+
+	return key[:keyLen]
+}
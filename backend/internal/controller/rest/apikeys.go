@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// ScopeMiddleware lets requests made with an API key through only when
+// the key has the scope the request needs: todos:read to read, todos:write
+// to change anything. Requests made with a session are not limited.
+func ScopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := domain.APIKeyFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scope := requiredScope(r)
+		if !key.Allows(scope) {
+			// RFC 6750 section 3.1
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="api", error="insufficient_scope", scope=%q`, scope))
+			writeProblem(w, r, fmt.Errorf("%w: %s", domain.ErrInsufficientScope, scope))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requiredScope(r *http.Request) domain.Scope {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return domain.ScopeTodosRead
+	default:
+		return domain.ScopeTodosWrite
+	}
+}
+
+// CreateAPIKeyHandler issues a key. The response is the only one that
+// carries the key itself.
+func (h *AuthHandlers) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var key domain.APIKey
+
+	if err := json.NewDecoder(r.Body).Decode(&key); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode api key: %w", errMalformedRequest, err))
+		return
+	}
+
+	issued, err := h.Auth.CreateAPIKey(r.Context(), key)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("create api key: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/auth/keys/%d", issued.ID))
+	// keys must not end up in shared caches
+	w.Header().Set("Cache-Control", "no-store")
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(issued); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+
+	slog.Info("api key created", "id", issued.ID, "user_id", issued.UserID, "scopes", issued.Scopes)
+}
+
+func (h *AuthHandlers) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Auth.ListAPIKeys(r.Context())
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("list api keys: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(keys); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (h *AuthHandlers) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.Auth.RevokeAPIKey(r.Context(), id); err != nil {
+		writeProblem(w, r, fmt.Errorf("revoke api key %d: %w", id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	slog.Info("api key revoked", "id", id)
+}
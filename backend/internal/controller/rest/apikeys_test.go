@@ -0,0 +1,282 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const testAPIKey = domain.APIKeyPrefix + "abcdefgh"
+
+func TestScopeMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		scopes []domain.Scope
+
+		wantCode  int
+		wantCalls int
+	}{
+		{
+			name:      "read scope reads",
+			method:    http.MethodGet,
+			url:       "/api/todos/1",
+			scopes:    []domain.Scope{domain.ScopeTodosRead},
+			wantCode:  http.StatusOK,
+			wantCalls: 1,
+		},
+		{
+			name:     "read scope cannot write -> forbidden",
+			method:   http.MethodDelete,
+			url:      "/api/todos/1",
+			scopes:   []domain.Scope{domain.ScopeTodosRead},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "write scope cannot read -> forbidden",
+			method:   http.MethodGet,
+			url:      "/api/todos/1",
+			scopes:   []domain.Scope{domain.ScopeTodosWrite},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:      "write scope writes",
+			method:    http.MethodDelete,
+			url:       "/api/todos/1",
+			scopes:    []domain.Scope{domain.ScopeTodosWrite},
+			wantCode:  http.StatusNoContent,
+			wantCalls: 1,
+		},
+		{
+			name:     "api key cannot log out -> forbidden",
+			method:   http.MethodPost,
+			url:      "/api/auth/logout",
+			scopes:   domain.AllScopes,
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			usecase := &UseCaseMock{
+				GetTodoByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+					return domain.Todo{ID: id, Title: "read the book", Version: 1}, nil
+				},
+				DeleteTodoByIDFunc: func(ctx context.Context, id int, version int) error {
+					return nil
+				},
+			}
+			auth := &AuthUseCaseMock{
+				AuthenticateAPIKeyFunc: func(ctx context.Context, key string) (domain.User, domain.APIKey, error) {
+					return testUser, domain.APIKey{ID: 1, UserID: testUser.ID, Scopes: tc.scopes}, nil
+				},
+			}
+
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			req.Header.Set("Authorization", "Bearer "+testAPIKey)
+			rec := httptest.NewRecorder()
+
+			// act
+			NewRouter(usecase, auth, TenantResolver{}, nil, nil).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+			if auth.AuthenticateAPIKeyCalls != 1 || auth.LastToken != testAPIKey {
+				t.Fatalf("key must be checked once: got %d calls with %q", auth.AuthenticateAPIKeyCalls, auth.LastToken)
+			}
+			if auth.AuthenticateCalls != 0 {
+				t.Errorf("api key must not be parsed as a session token")
+			}
+			if got := usecase.GetTodoByIDCalls + usecase.DeleteTodoByIDCalls; got != tc.wantCalls {
+				t.Fatalf("unexpected calls: got %d, want %d", got, tc.wantCalls)
+			}
+
+			if tc.wantCode == http.StatusForbidden {
+				assertProblem(t, rec, http.StatusForbidden, problemInsufficientScope)
+			}
+			if tc.wantCode == http.StatusForbidden && strings.HasPrefix(tc.url, "/api/todos") {
+				if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, `error="insufficient_scope"`) {
+					t.Errorf("unexpected WWW-Authenticate: got %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyRoutes(t *testing.T) {
+	created := time.Date(2030, time.January, 2, 12, 0, 0, 0, time.UTC)
+	key := domain.APIKey{
+		ID:        7,
+		UserID:    testUser.ID,
+		Name:      "backup",
+		Prefix:    testAPIKey,
+		Hash:      "secret-hash",
+		Scopes:    domain.AllScopes,
+		CreatedAt: created,
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+
+		usecaseErr error
+
+		calls func(m *AuthUseCaseMock) int
+
+		wantCode    int
+		wantProblem string
+		wantCalls   int
+	}{
+		{
+			name:      "create",
+			method:    http.MethodPost,
+			url:       "/api/auth/keys",
+			body:      `{"name":"backup","scopes":["todos:read","todos:write"]}`,
+			wantCode:  http.StatusCreated,
+			calls:     func(m *AuthUseCaseMock) int { return m.CreateAPIKeyCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "create malformed body -> error",
+			method:      http.MethodPost,
+			url:         "/api/auth/keys",
+			body:        `{"name":`,
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *AuthUseCaseMock) int { return m.CreateAPIKeyCalls },
+		},
+		{
+			name:        "create unknown scope -> error",
+			method:      http.MethodPost,
+			url:         "/api/auth/keys",
+			body:        `{"name":"backup","scopes":["todos:admin"]}`,
+			usecaseErr:  domain.ValidationErrors{{Field: "scopes[0]", Err: domain.ErrUnknownValue}},
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemValidationFailed,
+			calls:       func(m *AuthUseCaseMock) int { return m.CreateAPIKeyCalls },
+			wantCalls:   1,
+		},
+		{
+			name:      "list",
+			method:    http.MethodGet,
+			url:       "/api/auth/keys",
+			wantCode:  http.StatusOK,
+			calls:     func(m *AuthUseCaseMock) int { return m.ListAPIKeysCalls },
+			wantCalls: 1,
+		},
+		{
+			name:      "revoke",
+			method:    http.MethodDelete,
+			url:       "/api/auth/keys/7",
+			wantCode:  http.StatusNoContent,
+			calls:     func(m *AuthUseCaseMock) int { return m.RevokeAPIKeyCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "revoke missing key -> not found",
+			method:      http.MethodDelete,
+			url:         "/api/auth/keys/8",
+			usecaseErr:  domain.ErrAPIKeyNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemAPIKeyNotFound,
+			calls:       func(m *AuthUseCaseMock) int { return m.RevokeAPIKeyCalls },
+			wantCalls:   1,
+		},
+		{
+			name:        "revoke invalid id -> error",
+			method:      http.MethodDelete,
+			url:         "/api/auth/keys/abc",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *AuthUseCaseMock) int { return m.RevokeAPIKeyCalls },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			auth := &AuthUseCaseMock{
+				AuthenticateFunc: func(ctx context.Context, token string) (domain.User, error) {
+					return testUser, nil
+				},
+				CreateAPIKeyFunc: func(ctx context.Context, k domain.APIKey) (domain.IssuedAPIKey, error) {
+					return domain.IssuedAPIKey{APIKey: key, Key: testAPIKey}, tc.usecaseErr
+				},
+				ListAPIKeysFunc: func(ctx context.Context) ([]domain.APIKey, error) {
+					return []domain.APIKey{key}, tc.usecaseErr
+				},
+				RevokeAPIKeyFunc: func(ctx context.Context, id int) error {
+					return tc.usecaseErr
+				},
+			}
+
+			req := httptest.NewRequest(tc.method, tc.url, bytes.NewBufferString(tc.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rec := httptest.NewRecorder()
+
+			// act
+			NewRouter(&UseCaseMock{}, auth, TenantResolver{}, nil, nil).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+			if got := tc.calls(auth); got != tc.wantCalls {
+				t.Fatalf("unexpected calls: got %d, want %d", got, tc.wantCalls)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+			if rec.Code == http.StatusNoContent {
+				if auth.LastAPIKeyID != key.ID {
+					t.Errorf("unexpected key id: got %d, want %d", auth.LastAPIKeyID, key.ID)
+				}
+				return
+			}
+
+			if strings.Contains(rec.Body.String(), key.Hash) {
+				t.Errorf("key hash must not be sent: got %s", rec.Body.String())
+			}
+
+			if tc.method == http.MethodPost {
+				var resp map[string]any
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if resp["key"] != testAPIKey || rec.Header().Get("Cache-Control") != "no-store" {
+					t.Errorf("key must be sent once and not cached: got %v", resp)
+				}
+				if auth.LastAPIKey.Name != "backup" || len(auth.LastAPIKey.Scopes) != 2 {
+					t.Errorf("unexpected api key: got %+v", auth.LastAPIKey)
+				}
+				return
+			}
+
+			var resp []map[string]any
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp) != 1 || resp[0]["prefix"] != testAPIKey {
+				t.Errorf("unexpected keys: got %v", resp)
+			}
+			if _, ok := resp[0]["key"]; ok {
+				t.Errorf("listed keys must not carry the key: got %v", resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+type AuthUseCase interface {
+	Register(ctx context.Context, c domain.Credentials) (domain.User, error)
+	Login(ctx context.Context, c domain.Credentials) (domain.AccessToken, error)
+	Logout(ctx context.Context, token string) error
+	Authenticate(ctx context.Context, token string) (domain.User, error)
+
+	CreateAPIKey(ctx context.Context, key domain.APIKey) (domain.IssuedAPIKey, error)
+	ListAPIKeys(ctx context.Context) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int) error
+	AuthenticateAPIKey(ctx context.Context, key string) (domain.User, domain.APIKey, error)
+}
+
+type AuthHandlers struct {
+	Auth AuthUseCase
+}
+
+func NewAuthHandlers(auth AuthUseCase) *AuthHandlers {
+	return &AuthHandlers{
+		Auth: auth,
+	}
+}
+
+// AuthMiddleware lets through only requests with a valid bearer token and
+// puts the user it was issued to into the context, scoped to the workspace
+// of the user. The token is either a session token or an API key; a key is
+// put into the context as well, see ScopeMiddleware. The WebSocket of
+// TodoSocketHandler may offer the token as a subprotocol instead, see
+// socketToken.
+func AuthMiddleware(auth AuthUseCase) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				token, ok = socketToken(r)
+			}
+			if !ok {
+				writeProblem(w, r, fmt.Errorf("%w: no bearer token", domain.ErrUnauthenticated))
+				return
+			}
+
+			if strings.HasPrefix(token, domain.APIKeyPrefix) {
+				user, key, err := auth.AuthenticateAPIKey(r.Context(), token)
+				if err != nil {
+					writeProblem(w, r, fmt.Errorf("authenticate api key: %w", err))
+					return
+				}
+
+				ctx := domain.ContextWithAPIKey(domain.ContextWithUser(r.Context(), user), key)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			user, err := auth.Authenticate(r.Context(), token)
+			if err != nil {
+				writeProblem(w, r, fmt.Errorf("authenticate: %w", err))
+				return
+			}
+
+			// a session token of the default workspace needs no X-Tenant-ID,
+			// later calls must still stay in the workspace of the token
+			ctx := domain.ContextWithTenant(domain.ContextWithUser(r.Context(), user), user.Tenant)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// bearerToken reads the token of the Authorization header (RFC 6750).
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func (h *AuthHandlers) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var creds domain.Credentials
+
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode credentials: %w", errMalformedRequest, err))
+		return
+	}
+
+	user, err := h.Auth.Register(r.Context(), creds)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("register: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+
+	slog.Info("user registered", "id", user.ID)
+}
+
+func (h *AuthHandlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var creds domain.Credentials
+
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode credentials: %w", errMalformedRequest, err))
+		return
+	}
+
+	token, err := h.Auth.Login(r.Context(), creds)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("login: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// tokens must not end up in shared caches
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// LogoutHandler revokes the token the request was made with.
+func (h *AuthHandlers) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := domain.APIKeyFromContext(r.Context()); ok {
+		writeProblem(w, r, fmt.Errorf("%w: api keys are revoked, not logged out", domain.ErrInsufficientScope))
+		return
+	}
+
+	token, ok := bearerToken(r)
+	if !ok {
+		writeProblem(w, r, fmt.Errorf("%w: no bearer token", domain.ErrUnauthenticated))
+		return
+	}
+
+	if err := h.Auth.Logout(r.Context(), token); err != nil {
+		writeProblem(w, r, fmt.Errorf("logout: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MeHandler returns the authenticated user.
+func (h *AuthHandlers) MeHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := domain.UserFromContext(r.Context())
+	if !ok {
+		writeProblem(w, r, domain.ErrUnauthenticated)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
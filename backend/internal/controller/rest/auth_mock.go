@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+type AuthUseCaseMock struct {
+	RegisterFunc     func(ctx context.Context, c domain.Credentials) (domain.User, error)
+	LoginFunc        func(ctx context.Context, c domain.Credentials) (domain.AccessToken, error)
+	LogoutFunc       func(ctx context.Context, token string) error
+	AuthenticateFunc func(ctx context.Context, token string) (domain.User, error)
+
+	CreateAPIKeyFunc       func(ctx context.Context, key domain.APIKey) (domain.IssuedAPIKey, error)
+	ListAPIKeysFunc        func(ctx context.Context) ([]domain.APIKey, error)
+	RevokeAPIKeyFunc       func(ctx context.Context, id int) error
+	AuthenticateAPIKeyFunc func(ctx context.Context, key string) (domain.User, domain.APIKey, error)
+
+	RegisterCalls     int
+	LoginCalls        int
+	LogoutCalls       int
+	AuthenticateCalls int
+
+	CreateAPIKeyCalls       int
+	ListAPIKeysCalls        int
+	RevokeAPIKeyCalls       int
+	AuthenticateAPIKeyCalls int
+
+	LastCredentials domain.Credentials
+	LastToken       string
+	LastAPIKey      domain.APIKey
+	LastAPIKeyID    int
+}
+
+func (a *AuthUseCaseMock) Register(ctx context.Context, c domain.Credentials) (domain.User, error) {
+	a.LastCredentials = c
+	a.RegisterCalls++
+
+	if a.RegisterFunc == nil {
+		panic("RegisterFunc is nil")
+	}
+
+	return a.RegisterFunc(ctx, c)
+}
+
+func (a *AuthUseCaseMock) Login(ctx context.Context, c domain.Credentials) (domain.AccessToken, error) {
+	a.LastCredentials = c
+	a.LoginCalls++
+
+	if a.LoginFunc == nil {
+		panic("LoginFunc is nil")
+	}
+
+	return a.LoginFunc(ctx, c)
+}
+
+func (a *AuthUseCaseMock) Logout(ctx context.Context, token string) error {
+	a.LastToken = token
+	a.LogoutCalls++
+
+	if a.LogoutFunc == nil {
+		panic("LogoutFunc is nil")
+	}
+
+	return a.LogoutFunc(ctx, token)
+}
+
+func (a *AuthUseCaseMock) Authenticate(ctx context.Context, token string) (domain.User, error) {
+	a.LastToken = token
+	a.AuthenticateCalls++
+
+	if a.AuthenticateFunc == nil {
+		panic("AuthenticateFunc is nil")
+	}
+
+	return a.AuthenticateFunc(ctx, token)
+}
+
+func (a *AuthUseCaseMock) CreateAPIKey(ctx context.Context, key domain.APIKey) (domain.IssuedAPIKey, error) {
+	a.LastAPIKey = key
+	a.CreateAPIKeyCalls++
+
+	if a.CreateAPIKeyFunc == nil {
+		panic("CreateAPIKeyFunc is nil")
+	}
+
+	return a.CreateAPIKeyFunc(ctx, key)
+}
+
+func (a *AuthUseCaseMock) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	a.ListAPIKeysCalls++
+
+	if a.ListAPIKeysFunc == nil {
+		panic("ListAPIKeysFunc is nil")
+	}
+
+	return a.ListAPIKeysFunc(ctx)
+}
+
+func (a *AuthUseCaseMock) RevokeAPIKey(ctx context.Context, id int) error {
+	a.LastAPIKeyID = id
+	a.RevokeAPIKeyCalls++
+
+	if a.RevokeAPIKeyFunc == nil {
+		panic("RevokeAPIKeyFunc is nil")
+	}
+
+	return a.RevokeAPIKeyFunc(ctx, id)
+}
+
+func (a *AuthUseCaseMock) AuthenticateAPIKey(ctx context.Context, key string) (domain.User, domain.APIKey, error) {
+	a.LastToken = key
+	a.AuthenticateAPIKeyCalls++
+
+	if a.AuthenticateAPIKeyFunc == nil {
+		panic("AuthenticateAPIKeyFunc is nil")
+	}
+
+	return a.AuthenticateAPIKeyFunc(ctx, key)
+}
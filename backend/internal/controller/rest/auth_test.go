@@ -0,0 +1,278 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const testToken = "test-token"
+
+var testUser = domain.User{ID: 1, Email: "ann@example.com", Tenant: domain.DefaultTenant}
+
+// newTestRouter serves the usecase to testUser, requests without an
+// Authorization header are sent with testToken.
+func newTestRouter(usecase UseCase) http.Handler {
+	auth := &AuthUseCaseMock{
+		AuthenticateFunc: func(ctx context.Context, token string) (domain.User, error) {
+			return testUser, nil
+		},
+	}
+	router := NewRouter(usecase, auth, TenantResolver{}, nil, nil)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+testToken)
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+
+		authErr error
+
+		wantCode  int
+		wantToken string
+		wantCalls int
+	}{
+		{name: "valid token", header: "Bearer abc", wantCode: http.StatusOK, wantToken: "abc", wantCalls: 1},
+		{name: "scheme ignores case", header: "bearer abc", wantCode: http.StatusOK, wantToken: "abc", wantCalls: 1},
+		{name: "missing header -> unauthorized", wantCode: http.StatusUnauthorized},
+		{name: "basic scheme -> unauthorized", header: "Basic YW5uOnB3", wantCode: http.StatusUnauthorized},
+		{name: "empty token -> unauthorized", header: "Bearer ", wantCode: http.StatusUnauthorized},
+		{
+			name:      "revoked token -> unauthorized",
+			header:    "Bearer abc",
+			authErr:   domain.ErrUnauthenticated,
+			wantCode:  http.StatusUnauthorized,
+			wantToken: "abc",
+			wantCalls: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			auth := &AuthUseCaseMock{
+				AuthenticateFunc: func(ctx context.Context, token string) (domain.User, error) {
+					return testUser, tc.authErr
+				},
+			}
+			var (
+				fromContext domain.User
+				tenant      domain.Tenant
+			)
+			handler := AuthMiddleware(auth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fromContext, _ = domain.UserFromContext(r.Context())
+				tenant, _ = domain.TenantFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/todos", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+
+			// act
+			handler.ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+			if auth.AuthenticateCalls != tc.wantCalls {
+				t.Fatalf("unexpected calls: got %d, want %d", auth.AuthenticateCalls, tc.wantCalls)
+			}
+			if tc.wantCalls > 0 && auth.LastToken != tc.wantToken {
+				t.Errorf("unexpected token: got %q, want %q", auth.LastToken, tc.wantToken)
+			}
+
+			if tc.wantCode == http.StatusUnauthorized {
+				assertProblem(t, rec, http.StatusUnauthorized, problemUnauthenticated)
+				if rec.Header().Get("WWW-Authenticate") == "" {
+					t.Errorf("WWW-Authenticate must be set")
+				}
+				return
+			}
+			if fromContext != testUser {
+				t.Errorf("unexpected user in context: got %+v, want %+v", fromContext, testUser)
+			}
+			if tenant != testUser.Tenant {
+				t.Errorf("unexpected tenant in context: got %q, want %q", tenant, testUser.Tenant)
+			}
+		})
+	}
+}
+
+func TestAuthRoutes(t *testing.T) {
+	expires := time.Date(2030, time.January, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		header string
+
+		usecaseErr error
+
+		calls func(m *AuthUseCaseMock) int
+
+		wantCode    int
+		wantProblem string
+		wantCalls   int
+	}{
+		{
+			name:      "register without a token",
+			method:    http.MethodPost,
+			url:       "/api/auth/register",
+			body:      `{"email":"ann@example.com","password":"password1"}`,
+			wantCode:  http.StatusCreated,
+			calls:     func(m *AuthUseCaseMock) int { return m.RegisterCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "register taken email -> conflict",
+			method:      http.MethodPost,
+			url:         "/api/auth/register",
+			body:        `{"email":"ann@example.com","password":"password1"}`,
+			usecaseErr:  domain.ErrUserExists,
+			wantCode:    http.StatusConflict,
+			wantProblem: problemUserExists,
+			calls:       func(m *AuthUseCaseMock) int { return m.RegisterCalls },
+			wantCalls:   1,
+		},
+		{
+			name:        "register malformed body -> error",
+			method:      http.MethodPost,
+			url:         "/api/auth/register",
+			body:        `{"email":`,
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *AuthUseCaseMock) int { return m.RegisterCalls },
+		},
+		{
+			name:      "login",
+			method:    http.MethodPost,
+			url:       "/api/auth/login",
+			body:      `{"email":"ann@example.com","password":"password1"}`,
+			wantCode:  http.StatusOK,
+			calls:     func(m *AuthUseCaseMock) int { return m.LoginCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "login wrong password -> unauthorized",
+			method:      http.MethodPost,
+			url:         "/api/auth/login",
+			body:        `{"email":"ann@example.com","password":"password2"}`,
+			usecaseErr:  domain.ErrInvalidCredentials,
+			wantCode:    http.StatusUnauthorized,
+			wantProblem: problemInvalidCredentials,
+			calls:       func(m *AuthUseCaseMock) int { return m.LoginCalls },
+			wantCalls:   1,
+		},
+		{
+			name:      "logout",
+			method:    http.MethodPost,
+			url:       "/api/auth/logout",
+			header:    "Bearer abc",
+			wantCode:  http.StatusNoContent,
+			calls:     func(m *AuthUseCaseMock) int { return m.LogoutCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "logout without a token -> unauthorized",
+			method:      http.MethodPost,
+			url:         "/api/auth/logout",
+			wantCode:    http.StatusUnauthorized,
+			wantProblem: problemUnauthenticated,
+			calls:       func(m *AuthUseCaseMock) int { return m.LogoutCalls },
+		},
+		{
+			name:      "me",
+			method:    http.MethodGet,
+			url:       "/api/auth/me",
+			header:    "Bearer abc",
+			wantCode:  http.StatusOK,
+			calls:     func(m *AuthUseCaseMock) int { return m.AuthenticateCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "todos without a token -> unauthorized",
+			method:      http.MethodGet,
+			url:         "/api/todos",
+			wantCode:    http.StatusUnauthorized,
+			wantProblem: problemUnauthenticated,
+			calls:       func(m *AuthUseCaseMock) int { return m.AuthenticateCalls },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			auth := &AuthUseCaseMock{
+				RegisterFunc: func(ctx context.Context, c domain.Credentials) (domain.User, error) {
+					return testUser, tc.usecaseErr
+				},
+				LoginFunc: func(ctx context.Context, c domain.Credentials) (domain.AccessToken, error) {
+					return domain.AccessToken{Token: "abc", TokenType: "Bearer", ExpiresAt: expires}, tc.usecaseErr
+				},
+				LogoutFunc: func(ctx context.Context, token string) error {
+					return tc.usecaseErr
+				},
+				AuthenticateFunc: func(ctx context.Context, token string) (domain.User, error) {
+					return testUser, nil
+				},
+			}
+
+			req := httptest.NewRequest(tc.method, tc.url, bytes.NewBufferString(tc.body))
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+
+			// act
+			NewRouter(&UseCaseMock{}, auth, TenantResolver{}, nil, nil).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+			if got := tc.calls(auth); got != tc.wantCalls {
+				t.Fatalf("unexpected calls: got %d, want %d", got, tc.wantCalls)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+			if rec.Code == http.StatusNoContent {
+				return
+			}
+
+			var resp map[string]any
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if _, ok := resp["password_hash"]; ok {
+				t.Errorf("password hash must not be sent: got %v", resp)
+			}
+			if _, ok := resp["email"]; !ok && tc.url != "/api/auth/login" {
+				t.Errorf("user must be sent: got %v", resp)
+			}
+			if tc.url == "/api/auth/login" && resp["access_token"] != "abc" {
+				t.Errorf("token must be sent: got %v", resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// batchResult is the outcome of an operation of a batch: the status the
+// single request doing the same would get, with its todo or its problem.
+type batchResult struct {
+	Status  int          `json:"status"`
+	ID      int          `json:"id,omitempty"`
+	Todo    *domain.Todo `json:"todo,omitempty"`
+	Problem *Problem     `json:"problem,omitempty"`
+}
+
+// BatchHandler runs a list of create, update, delete and complete
+// operations in one request, see domain.Batch.
+func (h *Handlers) BatchHandler(w http.ResponseWriter, r *http.Request) {
+	var batch domain.Batch
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode batch: %w", errMalformedRequest, err))
+		return
+	}
+
+	results, err := h.UseCase.RunBatch(r.Context(), batch)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("run batch: %w", err))
+		return
+	}
+
+	writeBatch(w, r, results, func(i int) domain.BatchOp {
+		return batch.Operations[i].Op
+	})
+}
+
+// CompleteAllHandler completes every open todo matching the filter of
+// GET /api/todos.
+func (h *Handlers) CompleteAllHandler(w http.ResponseWriter, r *http.Request) {
+	filter, atomic, err := parseBatchFilter(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	results, err := h.UseCase.CompleteTodos(r.Context(), filter, atomic)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("complete todos: %w", err))
+		return
+	}
+
+	writeBatch(w, r, results, func(int) domain.BatchOp {
+		return domain.BatchComplete
+	})
+}
+
+// DeleteCompletedHandler moves every completed todo matching the filter
+// of GET /api/todos to the trash.
+func (h *Handlers) DeleteCompletedHandler(w http.ResponseWriter, r *http.Request) {
+	filter, atomic, err := parseBatchFilter(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	results, err := h.UseCase.DeleteCompletedTodos(r.Context(), filter, atomic)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("delete completed todos: %w", err))
+		return
+	}
+
+	writeBatch(w, r, results, func(int) domain.BatchOp {
+		return domain.BatchDelete
+	})
+}
+
+// parseBatchFilter reads the filter of a batch action, as for listing
+// todos, and whether it is atomic.
+func parseBatchFilter(r *http.Request) (domain.TodoFilter, bool, error) {
+	values := r.URL.Query()
+
+	q, _, err := parseTodoQuery(values)
+	if err != nil {
+		return domain.TodoFilter{}, false, err
+	}
+	if !q.AsOf.IsZero() {
+		return domain.TodoFilter{}, false, invalidParam(fmt.Errorf("%w: as_of of a change", domain.ErrInvalidQuery), "as_of", "only the current todos can be changed")
+	}
+
+	var atomic bool
+	if s := values.Get("atomic"); s != "" {
+		if atomic, err = strconv.ParseBool(s); err != nil {
+			return domain.TodoFilter{}, false, invalidParam(fmt.Errorf("%w: %w", domain.ErrInvalidQuery, err), "atomic", "must be true or false")
+		}
+	}
+
+	return q.Filter, atomic, nil
+}
+
+// writeBatch responds 200 when every operation succeeded and 207 Multi-Status
+// otherwise, with the results in the order of the operations.
+func writeBatch(w http.ResponseWriter, r *http.Request, results []domain.BatchResult, opOf func(i int) domain.BatchOp) {
+	resp := struct {
+		Results []batchResult `json:"results"`
+	}{Results: make([]batchResult, 0, len(results))}
+
+	status := http.StatusOK
+	for i, res := range results {
+		item := batchResult{ID: res.ID, Todo: res.Todo}
+
+		switch {
+		case res.Err != nil:
+			p := problemFor(res.Err)
+			item.Status = p.Status
+			item.Problem = &p
+			status = http.StatusMultiStatus
+
+			if p.Status >= http.StatusInternalServerError {
+				slog.Error("batch operation failed", "error", res.Err, "index", i, "request_id", RequestIDFromContext(r.Context()))
+			} else {
+				slog.Warn("batch operation rejected", "error", res.Err, "type", p.Type, "index", i, "request_id", RequestIDFromContext(r.Context()))
+			}
+		case opOf(i) == domain.BatchCreate:
+			item.Status = http.StatusCreated
+		case opOf(i) == domain.BatchDelete:
+			item.Status = http.StatusNoContent
+		default:
+			item.Status = http.StatusOK
+		}
+
+		resp.Results = append(resp.Results, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,228 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestBatchHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+
+		results    []domain.BatchResult
+		usecaseErr error
+
+		wantCode     int
+		wantProblem  string
+		wantStatuses []int
+		wantCalls    int
+	}{
+		{
+			name: "every operation succeeded",
+			body: `{"operations":[{"op":"create","todo":{"title":"read"}},{"op":"complete","id":2},{"op":"delete","id":3}]}`,
+			results: []domain.BatchResult{
+				{ID: 7, Todo: &domain.Todo{ID: 7, Title: "read", Version: 1}},
+				{ID: 2, Todo: &domain.Todo{ID: 2, Completed: true, Version: 4}},
+				{ID: 3},
+			},
+			wantCode:     http.StatusOK,
+			wantStatuses: []int{http.StatusCreated, http.StatusOK, http.StatusNoContent},
+			wantCalls:    1,
+		},
+		{
+			name: "some operations failed -> multi-status",
+			body: `{"operations":[{"op":"delete","id":2},{"op":"delete","id":3}]}`,
+			results: []domain.BatchResult{
+				{ID: 2, Err: domain.ErrTodoNotExist},
+				{ID: 3},
+			},
+			wantCode:     http.StatusMultiStatus,
+			wantStatuses: []int{http.StatusNotFound, http.StatusNoContent},
+			wantCalls:    1,
+		},
+		{
+			name: "atomic batch aborted",
+			body: `{"atomic":true,"operations":[{"op":"delete","id":2},{"op":"delete","id":3}]}`,
+			results: []domain.BatchResult{
+				{ID: 2, Err: domain.ErrBatchAborted},
+				{ID: 3, Err: domain.ErrVersionMismatch},
+			},
+			wantCode:     http.StatusMultiStatus,
+			wantStatuses: []int{http.StatusFailedDependency, http.StatusPreconditionFailed},
+			wantCalls:    1,
+		},
+		{
+			name:        "invalid batch -> validation error",
+			body:        `{"operations":[]}`,
+			usecaseErr:  domain.ValidationErrors{{Field: "operations", Err: domain.ErrRequired}},
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemValidationFailed,
+			wantCalls:   1,
+		},
+		{
+			name:        "no transactions -> not implemented",
+			body:        `{"atomic":true,"operations":[{"op":"delete","id":2}]}`,
+			usecaseErr:  domain.ErrNoTransactions,
+			wantCode:    http.StatusNotImplemented,
+			wantProblem: problemNoTransactions,
+			wantCalls:   1,
+		},
+		{
+			name:        "malformed body -> error",
+			body:        `{"operations":`,
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			wantCalls:   0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				RunBatchFunc: func(ctx context.Context, batch domain.Batch) ([]domain.BatchResult, error) {
+					return tc.results, tc.usecaseErr
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/todos:batch", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if useCaseMock.RunBatchCalls != tc.wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", useCaseMock.RunBatchCalls, tc.wantCalls)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+
+			var resp struct {
+				Results []batchResult `json:"results"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.Results) != len(tc.wantStatuses) {
+				t.Fatalf("unexpected results: got %d, want %d", len(resp.Results), len(tc.wantStatuses))
+			}
+			for i, res := range resp.Results {
+				if res.Status != tc.wantStatuses[i] {
+					t.Errorf("unexpected status of result %d: got %d, want %d", i, res.Status, tc.wantStatuses[i])
+				}
+				if res.ID != tc.results[i].ID {
+					t.Errorf("unexpected id of result %d: got %d, want %d", i, res.ID, tc.results[i].ID)
+				}
+				if (res.Problem != nil) != (tc.results[i].Err != nil) {
+					t.Errorf("unexpected problem of result %d: got %+v", i, res.Problem)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchActionHandlers(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+
+		wantCode     int
+		wantProblem  string
+		wantComplete int
+		wantDelete   int
+		wantAtomic   bool
+		wantStatus   int
+	}{
+		{
+			name:         "complete all matching",
+			url:          "/api/todos:completeAll?priority=high&list_id=10",
+			wantCode:     http.StatusOK,
+			wantComplete: 1,
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:       "delete completed atomically",
+			url:        "/api/todos:deleteCompleted?priority=high&list_id=10&atomic=true",
+			wantCode:   http.StatusOK,
+			wantDelete: 1,
+			wantAtomic: true,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:        "malformed atomic -> error",
+			url:         "/api/todos:deleteCompleted?atomic=sure",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+		},
+		{
+			name:        "as_of -> error",
+			url:         "/api/todos:completeAll?as_of=2030-01-01T00:00:00Z",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			result := func(ctx context.Context, filter domain.TodoFilter, atomic bool) ([]domain.BatchResult, error) {
+				return []domain.BatchResult{{ID: 4}}, nil
+			}
+			useCaseMock := &UseCaseMock{CompleteTodosFunc: result, DeleteCompletedTodosFunc: result}
+
+			req := httptest.NewRequest(http.MethodPost, tc.url, nil)
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if useCaseMock.CompleteTodosCalls != tc.wantComplete || useCaseMock.DeleteCompletedTodosCalls != tc.wantDelete {
+				t.Errorf("unexpected calls: got %d complete and %d delete, want %d and %d",
+					useCaseMock.CompleteTodosCalls, useCaseMock.DeleteCompletedTodosCalls, tc.wantComplete, tc.wantDelete)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+
+			filter := useCaseMock.LastQuery.Filter
+			if filter.Priority == nil || *filter.Priority != domain.PriorityHigh || filter.ListID == nil || *filter.ListID != 10 {
+				t.Errorf("unexpected filter: got %+v", filter)
+			}
+			if useCaseMock.LastAtomic != tc.wantAtomic {
+				t.Errorf("unexpected atomic: got %v, want %v", useCaseMock.LastAtomic, tc.wantAtomic)
+			}
+
+			var resp struct {
+				Results []batchResult `json:"results"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.Results) != 1 || resp.Results[0].Status != tc.wantStatus {
+				t.Errorf("unexpected results: got %+v", resp.Results)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestDueTodosHandlers(t *testing.T) {
+	page := domain.TodoPage{Todos: []domain.Todo{{ID: 1, Title: "read the book"}}}
+
+	tests := []struct {
+		name string
+		url  string
+
+		usecaseErr error
+
+		wantCode     int
+		wantProblem  string
+		wantLocation string
+		wantDays     int
+
+		calls     func(m *UseCaseMock) int
+		wantCalls int
+	}{
+		{
+			name:      "overdue",
+			url:       "/api/todos/overdue?priority=high",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.ListOverdueTodosCalls },
+			wantCalls: 1,
+		},
+		{
+			name:         "due today in UTC by default",
+			url:          "/api/todos/due-today",
+			wantCode:     http.StatusOK,
+			wantLocation: "UTC",
+			calls:        func(m *UseCaseMock) int { return m.ListDueTodayTodosCalls },
+			wantCalls:    1,
+		},
+		{
+			name:         "due today in the given zone",
+			url:          "/api/todos/due-today?tz=Europe/Moscow",
+			wantCode:     http.StatusOK,
+			wantLocation: "Europe/Moscow",
+			calls:        func(m *UseCaseMock) int { return m.ListDueTodayTodosCalls },
+			wantCalls:    1,
+		},
+		{
+			name:        "unknown zone -> error",
+			url:         "/api/todos/due-today?tz=Mars/Olympus",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+			calls:       func(m *UseCaseMock) int { return m.ListDueTodayTodosCalls },
+			wantCalls:   0,
+		},
+		{
+			name:         "upcoming week by default",
+			url:          "/api/todos/upcoming",
+			wantCode:     http.StatusOK,
+			wantLocation: "UTC",
+			wantDays:     7,
+			calls:        func(m *UseCaseMock) int { return m.ListUpcomingTodosCalls },
+			wantCalls:    1,
+		},
+		{
+			name:         "upcoming days",
+			url:          "/api/todos/upcoming?days=30",
+			wantCode:     http.StatusOK,
+			wantLocation: "UTC",
+			wantDays:     30,
+			calls:        func(m *UseCaseMock) int { return m.ListUpcomingTodosCalls },
+			wantCalls:    1,
+		},
+		{
+			name:        "malformed days -> error",
+			url:         "/api/todos/upcoming?days=week",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+			calls:       func(m *UseCaseMock) int { return m.ListUpcomingTodosCalls },
+			wantCalls:   0,
+		},
+		{
+			name:        "days out of range -> error",
+			url:         "/api/todos/upcoming?days=1000",
+			usecaseErr:  fmt.Errorf("%w: days must be between 1 and 365", domain.ErrInvalidQuery),
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+			wantDays:    1000,
+			calls:       func(m *UseCaseMock) int { return m.ListUpcomingTodosCalls },
+			wantCalls:   1,
+		},
+		{
+			name:        "failed to list -> error",
+			url:         "/api/todos/overdue",
+			usecaseErr:  errors.New("some error in usecase"),
+			wantCode:    http.StatusInternalServerError,
+			wantProblem: problemInternal,
+			calls:       func(m *UseCaseMock) int { return m.ListOverdueTodosCalls },
+			wantCalls:   1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				ListOverdueTodosFunc: func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+					return page, tc.usecaseErr
+				},
+				ListDueTodayTodosFunc: func(ctx context.Context, q domain.TodoQuery, loc *time.Location, cursor string) (domain.TodoPage, error) {
+					return page, tc.usecaseErr
+				},
+				ListUpcomingTodosFunc: func(ctx context.Context, q domain.TodoQuery, days int, loc *time.Location, cursor string) (domain.TodoPage, error) {
+					return page, tc.usecaseErr
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Errorf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+			}
+
+			if got := tc.calls(useCaseMock); got != tc.wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", got, tc.wantCalls)
+			}
+
+			if tc.wantLocation != "" && (useCaseMock.LastLocation == nil || useCaseMock.LastLocation.String() != tc.wantLocation) {
+				t.Errorf("unexpected location: got %v, want %s", useCaseMock.LastLocation, tc.wantLocation)
+			}
+			if tc.wantDays != 0 && useCaseMock.LastDays != tc.wantDays {
+				t.Errorf("unexpected days: got %d, want %d", useCaseMock.LastDays, tc.wantDays)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+var errInvalidETag = errors.New("invalid entity tag")
+
+// formatETag renders the todo version as a strong entity tag.
+func formatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch returns the versions an If-Match header accepts, any of
+// them matches. None means no precondition: the header is absent or "*".
+// If-Match uses the strong comparison (RFC 9110, section 13.1.1), so weak
+// tags never match; a header of weak tags only fails with
+// domain.ErrVersionMismatch.
+func parseIfMatch(header string) ([]int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak, ok := strings.CutPrefix(tag, "W/"); ok {
+			if !quoted(weak) {
+				return nil, errInvalidETag
+			}
+			continue
+		}
+
+		v, err := parseETag(tag)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: only weak entity tags", domain.ErrVersionMismatch)
+	}
+	return versions, nil
+}
+
+// matchesIfNoneMatch reports whether an If-None-Match header matches the
+// current version using the weak comparison.
+func matchesIfNoneMatch(header string, version int) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+
+		v, err := parseETag(strings.TrimPrefix(tag, "W/"))
+		if err == nil && v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseETag(tag string) (int, error) {
+	if !quoted(tag) {
+		return 0, errInvalidETag
+	}
+
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version <= 0 {
+		return 0, errInvalidETag
+	}
+
+	return version, nil
+}
+
+func quoted(tag string) bool {
+	return len(tag) >= 2 && tag[0] == '"' && tag[len(tag)-1] == '"'
+}
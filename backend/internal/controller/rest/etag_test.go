@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    []int
+		wantErr error
+	}{
+		{header: ""},
+		{header: "*"},
+		{header: `"3"`, want: []int{3}},
+		{header: ` "12" `, want: []int{12}},
+		{header: `"1", "2"`, want: []int{1, 2}},
+		{header: `W/"3", "4"`, want: []int{4}},
+		{header: `W/"3"`, wantErr: domain.ErrVersionMismatch},
+		{header: `W/"2", W/"3"`, wantErr: domain.ErrVersionMismatch},
+		{header: `W/3`, wantErr: errInvalidETag},
+		{header: `"1", 2`, wantErr: errInvalidETag},
+		{header: `3`, wantErr: errInvalidETag},
+		{header: `"abc"`, wantErr: errInvalidETag},
+		{header: `"0"`, wantErr: errInvalidETag},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.header, func(t *testing.T) {
+			got, err := parseIfMatch(tc.header)
+
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("unexpected versions: got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMatchesIfNoneMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: `"3"`, want: true},
+		{header: `W/"3"`, want: true},
+		{header: `"1", "3"`, want: true},
+		{header: `*`, want: true},
+		{header: `"2"`, want: false},
+		{header: `garbage`, want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.header, func(t *testing.T) {
+			if got := matchesIfNoneMatch(tc.header, 3); got != tc.want {
+				t.Errorf("unexpected result: got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// DefaultHeartbeat is how often an idle event stream gets a comment, so
+// proxies do not close it.
+const DefaultHeartbeat = 15 * time.Second
+
+// TodoEventsHandler streams the changes of todos as Server-Sent Events. A
+// client that reconnects with Last-Event-ID gets the events it missed, or
+// a reset event when they are gone. The stream ends when the client falls
+// too far behind; it reconnects the same way.
+func (h *Handlers) TodoEventsHandler(w http.ResponseWriter, r *http.Request) {
+	lastEventID, err := parseLastEventID(r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		writeProblem(w, r, invalidParam(fmt.Errorf("%w: %w", errMalformedRequest, err), "Last-Event-ID", "must be an event id"))
+		return
+	}
+
+	events := h.UseCase.TodoEvents(r.Context(), lastEventID)
+
+	rc := http.NewResponseController(w)
+	// the stream outlives the write timeout of the server
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("failed to clear write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx buffers responses unless told otherwise
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := h.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeat
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func parseLastEventID(header string) (uint64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(header, 10, 64)
+}
+
+// eventData is what clients get about the event: a created, updated or
+// completed todo itself, the id of a deleted one.
+func eventData(event domain.TodoEvent) any {
+	switch event.Type {
+	case domain.TodoCreated, domain.TodoUpdated, domain.TodoCompleted:
+		return event.Todo
+	case domain.TodoDeleted:
+		return struct {
+			ID int `json:"id"`
+		}{event.Todo.ID}
+	default:
+		// an event without data is never dispatched by EventSource
+		return struct{}{}
+	}
+}
+
+// writeEvent renders the event in the text/event-stream format.
+func writeEvent(w http.ResponseWriter, event domain.TodoEvent) error {
+	b, err := json.Marshal(eventData(event))
+	if err != nil {
+		slog.Error("failed to encode event", "id", event.ID, "error", err)
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, b)
+	return err
+}
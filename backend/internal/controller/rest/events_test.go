@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestTodoEventsHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastEventID string
+
+		wantCode        int
+		wantProblem     string
+		wantLastEventID uint64
+		wantCalls       int
+	}{
+		{name: "new stream", wantCode: http.StatusOK, wantCalls: 1},
+		{name: "resumed stream", lastEventID: "41", wantCode: http.StatusOK, wantLastEventID: 41, wantCalls: 1},
+		{
+			name:        "malformed last event id -> error",
+			lastEventID: "latest",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				TodoEventsFunc: func(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent {
+					events := make(chan domain.TodoEvent, 3)
+					events <- domain.TodoEvent{ID: 42, Type: domain.TodoCreated, Todo: domain.Todo{ID: 7, Title: "new", Tags: []int{}}}
+					events <- domain.TodoEvent{ID: 43, Type: domain.TodoDeleted, Todo: domain.Todo{ID: 7, Title: "new"}}
+					events <- domain.TodoEvent{ID: 43, Type: domain.TodoEventsReset}
+					// the stream ends once the subscriber is dropped
+					close(events)
+					return events
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/todos/events", nil)
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+			if useCaseMock.TodoEventsCalls != tc.wantCalls {
+				t.Fatalf("unexpected calls: got %d, want %d", useCaseMock.TodoEventsCalls, tc.wantCalls)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+
+			if useCaseMock.LastEventID != tc.wantLastEventID {
+				t.Errorf("unexpected last event id: got %d, want %d", useCaseMock.LastEventID, tc.wantLastEventID)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+				t.Errorf("unexpected content type: got %q, want %q", ct, "text/event-stream")
+			}
+
+			body := rec.Body.String()
+			for _, want := range []string{
+				"id: 42\nevent: todo.created\ndata: {\"id\":7,\"title\":\"new\",",
+				"id: 43\nevent: todo.deleted\ndata: {\"id\":7}\n\n",
+				"id: 43\nevent: reset\ndata: {}\n\n",
+			} {
+				if !strings.Contains(body, want) {
+					t.Errorf("unexpected stream: got %q, want it to contain %q", body, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTodoEventsHandlerHeartbeat(t *testing.T) {
+	// preparing
+	useCaseMock := &UseCaseMock{
+		TodoEventsFunc: func(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent {
+			// nothing ever happens
+			return make(chan domain.TodoEvent)
+		},
+	}
+	handlers := &Handlers{UseCase: useCaseMock, Heartbeat: time.Millisecond}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/todos/events", nil)
+	rec := httptest.NewRecorder()
+
+	// act
+	handlers.TodoEventsHandler(rec, req)
+
+	// assert
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: got %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.HasPrefix(rec.Body.String(), ": heartbeat\n\n") {
+		t.Errorf("unexpected stream: got %q, want heartbeat comments", rec.Body.String())
+	}
+}
@@ -5,23 +5,90 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/VLGKiwi/todo-site/backend/internal/domain"
 )
 
 type UseCase interface {
 	CreateTodo(ctx context.Context, todo domain.Todo) (int, error)
-	GetAllTodos(ctx context.Context) ([]domain.Todo, error)
+	ListTodos(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error)
+	ListOverdueTodos(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error)
+	ListDueTodayTodos(ctx context.Context, q domain.TodoQuery, loc *time.Location, cursor string) (domain.TodoPage, error)
+	ListUpcomingTodos(ctx context.Context, q domain.TodoQuery, days int, loc *time.Location, cursor string) (domain.TodoPage, error)
 	GetTodoByID(ctx context.Context, id int) (domain.Todo, error)
 	UpdateTodoByID(ctx context.Context, id int, todo domain.Todo) error
-	DeleteTodoByID(ctx context.Context, id int) error
+	PatchTodoByID(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error)
+	DeleteTodoByID(ctx context.Context, id int, version int) error
+	RunBatch(ctx context.Context, batch domain.Batch) ([]domain.BatchResult, error)
+	CompleteTodos(ctx context.Context, filter domain.TodoFilter, atomic bool) ([]domain.BatchResult, error)
+	DeleteCompletedTodos(ctx context.Context, filter domain.TodoFilter, atomic bool) ([]domain.BatchResult, error)
+	ListSubtasks(ctx context.Context, id int, q domain.TodoQuery, cursor string) (domain.TodoPage, error)
+	GetTodoProgress(ctx context.Context, id int) (domain.Progress, error)
+	PreviewOccurrences(ctx context.Context, id int, n int) ([]time.Time, error)
+	GetTodoHistory(ctx context.Context, id int) ([]domain.TodoChange, error)
+	TodoEvents(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent
+
+	GetTrash(ctx context.Context) ([]domain.TrashedTodo, error)
+	RestoreTodo(ctx context.Context, id int) (domain.Todo, error)
+	PurgeTodo(ctx context.Context, id int) error
+
+	Undo(ctx context.Context) (domain.Command, error)
+	Redo(ctx context.Context) (domain.Command, error)
+
+	CreateTag(ctx context.Context, tag domain.Tag) (int, error)
+	GetAllTags(ctx context.Context) ([]domain.Tag, error)
+	GetTagByID(ctx context.Context, id int) (domain.Tag, error)
+	UpdateTagByID(ctx context.Context, id int, tag domain.Tag) error
+	DeleteTagByID(ctx context.Context, id int) error
+	AttachTag(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error)
+	DetachTag(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error)
+
+	CreateList(ctx context.Context, list domain.List) (int, error)
+	GetLists(ctx context.Context) ([]domain.List, error)
+	GetListByID(ctx context.Context, id int) (domain.List, error)
+	RenameList(ctx context.Context, id int, name string) (domain.List, error)
+	DeleteListByID(ctx context.Context, id int) error
+	InviteMember(ctx context.Context, listID int, inv domain.Invitation) (domain.List, error)
+	RevokeInvitation(ctx context.Context, listID int, email string) (domain.List, error)
+	GetInvitations(ctx context.Context) ([]domain.PendingInvitation, error)
+	AcceptInvitation(ctx context.Context, listID int) (domain.List, error)
+	DeclineInvitation(ctx context.Context, listID int) error
+	ChangeMemberRole(ctx context.Context, listID int, userID int, role domain.Role) (domain.List, error)
+	RemoveMember(ctx context.Context, listID int, userID int) error
+
+	CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.CreatedWebhook, error)
+	GetWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	DeleteWebhookByID(ctx context.Context, id int) error
+	GetWebhookDeliveries(ctx context.Context, id int) ([]domain.WebhookDelivery, error)
+	GetDeadLetters(ctx context.Context) ([]domain.WebhookDelivery, error)
+	RedeliverWebhook(ctx context.Context, id int, deliveryID int) error
 }
 
+const (
+	mergePatchContentType = "application/merge-patch+json"
+
+	defaultUpcomingDays = 7
+
+	defaultOccurrencesPreview = 5
+
+	maxPatchBytes = 1 << 20
+)
+
 type Handlers struct {
 	UseCase UseCase
+	// Heartbeat is how often an idle event stream gets a comment,
+	// DefaultHeartbeat when zero.
+	Heartbeat time.Duration
+	// SocketOrigins are the origins besides the one of the API that
+	// browsers may open the WebSocket from, see websocket.CheckOrigin.
+	SocketOrigins []string
 }
 
 func NewHandlers(usecase UseCase) *Handlers {
@@ -34,19 +101,13 @@ func (h *Handlers) CreateTodoHandler(w http.ResponseWriter, r *http.Request) {
 	var todo domain.Todo
 
 	if err := json.NewDecoder(r.Body).Decode(&todo); err != nil {
-		slog.Warn("failed to decode request", "error", err)
-		http.Error(w, "bad request", http.StatusBadRequest)
+		writeProblem(w, r, fmt.Errorf("%w: decode todo: %w", errMalformedRequest, err))
 		return
 	}
 
 	id, err := h.UseCase.CreateTodo(r.Context(), todo)
-	if errors.Is(err, domain.ErrNoTitle) {
-		slog.Warn("todo validation failed", "error", err)
-		http.Error(w, "bad request", http.StatusBadRequest)
-		return
-	} else if err != nil {
-		slog.Error("failed to create todo", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("create todo: %w", err))
 		return
 	}
 
@@ -65,38 +126,121 @@ func (h *Handlers) CreateTodoHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) GetAllTodosHandler(w http.ResponseWriter, r *http.Request) {
-	todos, err := h.UseCase.GetAllTodos(r.Context())
+	q, cursor, err := parseTodoQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	page, err := h.UseCase.ListTodos(r.Context(), q, cursor)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("list todos: %w", err))
+		return
+	}
+
+	writeTodoPage(w, r, page)
+}
+
+func (h *Handlers) GetOverdueTodosHandler(w http.ResponseWriter, r *http.Request) {
+	q, cursor, err := parseTodoQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	page, err := h.UseCase.ListOverdueTodos(r.Context(), q, cursor)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("list overdue todos: %w", err))
+		return
+	}
+
+	writeTodoPage(w, r, page)
+}
+
+func (h *Handlers) GetDueTodayTodosHandler(w http.ResponseWriter, r *http.Request) {
+	q, cursor, err := parseTodoQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	loc, err := parseLocation(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	page, err := h.UseCase.ListDueTodayTodos(r.Context(), q, loc, cursor)
 	if err != nil {
-		slog.Error("failed to get all todos", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeProblem(w, r, fmt.Errorf("list todos due today: %w", err))
 		return
 	}
 
+	writeTodoPage(w, r, page)
+}
+
+func (h *Handlers) GetUpcomingTodosHandler(w http.ResponseWriter, r *http.Request) {
+	q, cursor, err := parseTodoQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	loc, err := parseLocation(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	days := defaultUpcomingDays
+	if s := r.URL.Query().Get("days"); s != "" {
+		days, err = strconv.Atoi(s)
+		if err != nil {
+			writeProblem(w, r, invalidParam(fmt.Errorf("%w: %w", domain.ErrInvalidQuery, err), "days", "must be an integer"))
+			return
+		}
+	}
+
+	page, err := h.UseCase.ListUpcomingTodos(r.Context(), q, days, loc, cursor)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("list upcoming todos: %w", err))
+		return
+	}
+
+	writeTodoPage(w, r, page)
+}
+
+// writeTodoPage renders a page as a JSON array, the link to the next page
+// goes into the headers.
+func writeTodoPage(w http.ResponseWriter, r *http.Request, page domain.TodoPage) {
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextPageURL(r.URL, page.NextCursor)))
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(todos); err != nil {
+	if err := json.NewEncoder(w).Encode(page.Todos); err != nil {
 		slog.Error("failed to encode response", "error", err)
 	}
 }
 
 func (h *Handlers) GetTodoHandler(w http.ResponseWriter, r *http.Request) {
-
-	idStr := r.PathValue("id")
-
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r)
 	if err != nil {
-		slog.Warn("failed to convert id from string", "error", err)
-		http.Error(w, "bad request", http.StatusBadRequest)
+		writeProblem(w, r, err)
 		return
 	}
 
 	todo, err := h.UseCase.GetTodoByID(r.Context(), id)
-	if errors.Is(err, domain.ErrTodoNotExist) {
-		slog.Warn("failed to get todo by id", "error", err, "id", id)
-		http.Error(w, "todo not found", http.StatusNotFound)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get todo %d: %w", id, err))
 		return
-	} else if err != nil {
-		slog.Error("failed to get todo by id", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+
+	w.Header().Set("ETag", formatETag(todo.Version))
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" && matchesIfNoneMatch(inm, todo.Version) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -109,37 +253,38 @@ func (h *Handlers) GetTodoHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) UpdateTodoHandler(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
 
-	id, err := strconv.Atoi(idStr)
+	version, err := h.ifMatchVersion(r, id)
 	if err != nil {
-		slog.Warn("failed to convert id from string", "error", err)
-		http.Error(w, "bad request", http.StatusBadRequest)
+		writeProblem(w, r, err)
 		return
 	}
 
 	var todo domain.Todo
 
 	if err := json.NewDecoder(r.Body).Decode(&todo); err != nil {
-		slog.Warn("failed to decode request", "error", err)
-		http.Error(w, "bad request", http.StatusBadRequest)
+		writeProblem(w, r, fmt.Errorf("%w: decode todo: %w", errMalformedRequest, err))
 		return
 	}
 
-	if err := h.UseCase.UpdateTodoByID(r.Context(), id, todo); errors.Is(err, domain.ErrTodoNotExist) {
-		slog.Warn("failed to update todo", "error", err, "id", id)
-		http.Error(w, err.Error(), http.StatusNotFound)
-		return
-	} else if errors.Is(err, domain.ErrNoTitle) {
-		slog.Warn("todo validation failed", "error", err)
-		http.Error(w, "bad request", http.StatusBadRequest)
-		return
-	} else if err != nil {
-		slog.Error("failed to update todo", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+	// If-Match wins over a version sent in the body
+	if version != 0 {
+		todo.Version = version
+	}
+
+	if err := h.UseCase.UpdateTodoByID(r.Context(), id, todo); err != nil {
+		writeProblem(w, r, fmt.Errorf("update todo %d: %w", id, err))
 		return
 	}
 
+	if todo.Version != 0 {
+		w.Header().Set("ETag", formatETag(todo.Version+1))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	resp := map[string]string{"message": "todo successfully updated"}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -148,25 +293,107 @@ func (h *Handlers) UpdateTodoHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func (h *Handlers) DeleteTodoHandler(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
+func (h *Handlers) PatchTodoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
 
-	id, err := strconv.Atoi(idStr)
+	version, err := h.ifMatchVersion(r, id)
 	if err != nil {
-		slog.Warn("failed to convert id from string", "error", err)
-		http.Error(w, "bad request", http.StatusBadRequest)
+		writeProblem(w, r, err)
 		return
 	}
 
-	if err := h.UseCase.DeleteTodoByID(r.Context(), id); errors.Is(err, domain.ErrTodoNotExist) {
-		slog.Warn("failed to delete todo", "error", err, "id", id)
-		http.Error(w, "todo not found", http.StatusNotFound)
+	// RFC 7396 documents; plain JSON is accepted for convenience
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != mergePatchContentType && mediaType != "application/json") {
+			w.Header().Set("Accept-Patch", mergePatchContentType)
+			writeProblem(w, r, invalidParam(errUnsupportedMediaType, "Content-Type", "must be "+mergePatchContentType))
+			return
+		}
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: read patch: %w", errMalformedRequest, err))
 		return
-	} else if err != nil {
-		slog.Error("failed to delete todo", "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+
+	todo, err := h.UseCase.PatchTodoByID(r.Context(), id, version, patch)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("patch todo %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(todo.Version))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (h *Handlers) DeleteTodoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	version, err := h.ifMatchVersion(r, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.UseCase.DeleteTodoByID(r.Context(), id, version); err != nil {
+		writeProblem(w, r, fmt.Errorf("delete todo %d: %w", id, err))
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func pathID(r *http.Request) (int, error) {
+	return pathInt(r, "id")
+}
+
+func pathInt(r *http.Request, name string) (int, error) {
+	v, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		return 0, invalidParam(fmt.Errorf("%w: %w", errMalformedRequest, err), name, "must be an integer")
+	}
+	return v, nil
+}
+
+// ifMatchVersion returns the version of the todo the If-Match header
+// requires, zero for none. A list of tags is resolved against the current
+// version of the todo; the use case still checks it when it stores the
+// change.
+func (h *Handlers) ifMatchVersion(r *http.Request, id int) (int, error) {
+	versions, err := parseIfMatch(r.Header.Get("If-Match"))
+	if errors.Is(err, errInvalidETag) {
+		return 0, invalidParam(fmt.Errorf("%w: %w", errMalformedRequest, err), "If-Match", "must be a list of entity tags")
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	switch len(versions) {
+	case 0:
+		return 0, nil
+	case 1:
+		return versions[0], nil
+	}
+
+	todo, err := h.UseCase.GetTodoByID(r.Context(), id)
+	if err != nil {
+		return 0, fmt.Errorf("get todo %d: %w", id, err)
+	}
+	if !slices.Contains(versions, todo.Version) {
+		return 0, domain.ErrVersionMismatch
+	}
+	return todo.Version, nil
+}
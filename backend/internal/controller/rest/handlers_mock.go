@@ -2,25 +2,144 @@ package rest
 
 import (
 	"context"
+	"time"
 
 	"github.com/VLGKiwi/todo-site/backend/internal/domain"
 )
 
 type UseCaseMock struct {
-	CreateTodoFunc     func(ctx context.Context, todo domain.Todo) (int, error)
-	GetAllTodosFunc    func(ctx context.Context) ([]domain.Todo, error)
-	GetTodoByIDFunc    func(ctx context.Context, id int) (domain.Todo, error)
-	UpdateTodoByIDFunc func(ctx context.Context, id int, todo domain.Todo) error
-	DeleteTodoByIDFunc func(ctx context.Context, id int) error
-
-	CreateTodoCalls     int
-	GetAllTodosCalls    int
-	GetTodoByIDCalls    int
-	UpdateTodoByIDCalls int
-	DeleteTodoByIDCalls int
+	CreateTodoFunc func(ctx context.Context, todo domain.Todo) (int, error)
+	ListTodosFunc  func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error)
+
+	ListOverdueTodosFunc     func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error)
+	ListDueTodayTodosFunc    func(ctx context.Context, q domain.TodoQuery, loc *time.Location, cursor string) (domain.TodoPage, error)
+	ListUpcomingTodosFunc    func(ctx context.Context, q domain.TodoQuery, days int, loc *time.Location, cursor string) (domain.TodoPage, error)
+	GetTodoByIDFunc          func(ctx context.Context, id int) (domain.Todo, error)
+	UpdateTodoByIDFunc       func(ctx context.Context, id int, todo domain.Todo) error
+	PatchTodoByIDFunc        func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error)
+	DeleteTodoByIDFunc       func(ctx context.Context, id int, version int) error
+	RunBatchFunc             func(ctx context.Context, batch domain.Batch) ([]domain.BatchResult, error)
+	CompleteTodosFunc        func(ctx context.Context, filter domain.TodoFilter, atomic bool) ([]domain.BatchResult, error)
+	DeleteCompletedTodosFunc func(ctx context.Context, filter domain.TodoFilter, atomic bool) ([]domain.BatchResult, error)
+	ListSubtasksFunc         func(ctx context.Context, id int, q domain.TodoQuery, cursor string) (domain.TodoPage, error)
+	GetTodoProgressFunc      func(ctx context.Context, id int) (domain.Progress, error)
+	PreviewOccurrencesFunc   func(ctx context.Context, id int, n int) ([]time.Time, error)
+	GetTodoHistoryFunc       func(ctx context.Context, id int) ([]domain.TodoChange, error)
+	TodoEventsFunc           func(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent
+
+	GetTrashFunc    func(ctx context.Context) ([]domain.TrashedTodo, error)
+	RestoreTodoFunc func(ctx context.Context, id int) (domain.Todo, error)
+	PurgeTodoFunc   func(ctx context.Context, id int) error
+
+	UndoFunc func(ctx context.Context) (domain.Command, error)
+	RedoFunc func(ctx context.Context) (domain.Command, error)
+
+	CreateTagFunc     func(ctx context.Context, tag domain.Tag) (int, error)
+	GetAllTagsFunc    func(ctx context.Context) ([]domain.Tag, error)
+	GetTagByIDFunc    func(ctx context.Context, id int) (domain.Tag, error)
+	UpdateTagByIDFunc func(ctx context.Context, id int, tag domain.Tag) error
+	DeleteTagByIDFunc func(ctx context.Context, id int) error
+	AttachTagFunc     func(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error)
+	DetachTagFunc     func(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error)
+
+	CreateListFunc        func(ctx context.Context, list domain.List) (int, error)
+	GetListsFunc          func(ctx context.Context) ([]domain.List, error)
+	GetListByIDFunc       func(ctx context.Context, id int) (domain.List, error)
+	RenameListFunc        func(ctx context.Context, id int, name string) (domain.List, error)
+	DeleteListByIDFunc    func(ctx context.Context, id int) error
+	InviteMemberFunc      func(ctx context.Context, listID int, inv domain.Invitation) (domain.List, error)
+	RevokeInvitationFunc  func(ctx context.Context, listID int, email string) (domain.List, error)
+	GetInvitationsFunc    func(ctx context.Context) ([]domain.PendingInvitation, error)
+	AcceptInvitationFunc  func(ctx context.Context, listID int) (domain.List, error)
+	DeclineInvitationFunc func(ctx context.Context, listID int) error
+	ChangeMemberRoleFunc  func(ctx context.Context, listID int, userID int, role domain.Role) (domain.List, error)
+	RemoveMemberFunc      func(ctx context.Context, listID int, userID int) error
+
+	CreateWebhookFunc        func(ctx context.Context, hook domain.Webhook) (domain.CreatedWebhook, error)
+	GetWebhooksFunc          func(ctx context.Context) ([]domain.Webhook, error)
+	DeleteWebhookByIDFunc    func(ctx context.Context, id int) error
+	GetWebhookDeliveriesFunc func(ctx context.Context, id int) ([]domain.WebhookDelivery, error)
+	GetDeadLettersFunc       func(ctx context.Context) ([]domain.WebhookDelivery, error)
+	RedeliverWebhookFunc     func(ctx context.Context, id int, deliveryID int) error
+
+	CreateTodoCalls int
+	ListTodosCalls  int
+
+	ListOverdueTodosCalls     int
+	ListDueTodayTodosCalls    int
+	ListUpcomingTodosCalls    int
+	GetTodoByIDCalls          int
+	UpdateTodoByIDCalls       int
+	PatchTodoByIDCalls        int
+	DeleteTodoByIDCalls       int
+	RunBatchCalls             int
+	CompleteTodosCalls        int
+	DeleteCompletedTodosCalls int
+	ListSubtasksCalls         int
+	GetTodoProgressCalls      int
+	PreviewOccurrencesCalls   int
+	GetTodoHistoryCalls       int
+	TodoEventsCalls           int
+
+	GetTrashCalls    int
+	RestoreTodoCalls int
+	PurgeTodoCalls   int
+
+	UndoCalls int
+	RedoCalls int
+
+	CreateTagCalls     int
+	GetAllTagsCalls    int
+	GetTagByIDCalls    int
+	UpdateTagByIDCalls int
+	DeleteTagByIDCalls int
+	AttachTagCalls     int
+	DetachTagCalls     int
+
+	CreateListCalls        int
+	GetListsCalls          int
+	GetListByIDCalls       int
+	RenameListCalls        int
+	DeleteListByIDCalls    int
+	InviteMemberCalls      int
+	RevokeInvitationCalls  int
+	GetInvitationsCalls    int
+	AcceptInvitationCalls  int
+	DeclineInvitationCalls int
+	ChangeMemberRoleCalls  int
+	RemoveMemberCalls      int
+
+	CreateWebhookCalls        int
+	GetWebhooksCalls          int
+	DeleteWebhookByIDCalls    int
+	GetWebhookDeliveriesCalls int
+	GetDeadLettersCalls       int
+	RedeliverWebhookCalls     int
 
 	LastSavedTodo domain.Todo
 	LastGetID     int
+	LastPatch     []byte
+	LastVersion   int
+	LastQuery     domain.TodoQuery
+	LastCursor    string
+	LastLocation  *time.Location
+	LastDays      int
+	LastCount     int
+	LastSavedTag  domain.Tag
+	LastTagID     int
+	LastEventID   uint64
+	LastBatch     domain.Batch
+	LastAtomic    bool
+
+	LastSavedList  domain.List
+	LastListID     int
+	LastInvitation domain.Invitation
+	LastMemberID   int
+	LastRole       domain.Role
+
+	LastSavedWebhook domain.Webhook
+	LastWebhookID    int
+	LastDeliveryID   int
 }
 
 func (u *UseCaseMock) CreateTodo(ctx context.Context, todo domain.Todo) (int, error) {
@@ -34,13 +153,55 @@ func (u *UseCaseMock) CreateTodo(ctx context.Context, todo domain.Todo) (int, er
 	return u.CreateTodoFunc(ctx, todo)
 }
 
-func (u *UseCaseMock) GetAllTodos(ctx context.Context) ([]domain.Todo, error) {
-	u.GetAllTodosCalls++
-	if u.GetAllTodosFunc == nil {
-		panic("GetAllTodosFunc is nil")
+func (u *UseCaseMock) ListTodos(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+	u.LastQuery = q
+	u.LastCursor = cursor
+	u.ListTodosCalls++
+
+	if u.ListTodosFunc == nil {
+		panic("ListTodosFunc is nil")
+	}
+
+	return u.ListTodosFunc(ctx, q, cursor)
+}
+
+func (u *UseCaseMock) ListOverdueTodos(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+	u.LastQuery = q
+	u.LastCursor = cursor
+	u.ListOverdueTodosCalls++
+
+	if u.ListOverdueTodosFunc == nil {
+		panic("ListOverdueTodosFunc is nil")
 	}
 
-	return u.GetAllTodosFunc(ctx)
+	return u.ListOverdueTodosFunc(ctx, q, cursor)
+}
+
+func (u *UseCaseMock) ListDueTodayTodos(ctx context.Context, q domain.TodoQuery, loc *time.Location, cursor string) (domain.TodoPage, error) {
+	u.LastQuery = q
+	u.LastLocation = loc
+	u.LastCursor = cursor
+	u.ListDueTodayTodosCalls++
+
+	if u.ListDueTodayTodosFunc == nil {
+		panic("ListDueTodayTodosFunc is nil")
+	}
+
+	return u.ListDueTodayTodosFunc(ctx, q, loc, cursor)
+}
+
+func (u *UseCaseMock) ListUpcomingTodos(ctx context.Context, q domain.TodoQuery, days int, loc *time.Location, cursor string) (domain.TodoPage, error) {
+	u.LastQuery = q
+	u.LastDays = days
+	u.LastLocation = loc
+	u.LastCursor = cursor
+	u.ListUpcomingTodosCalls++
+
+	if u.ListUpcomingTodosFunc == nil {
+		panic("ListUpcomingTodosFunc is nil")
+	}
+
+	return u.ListUpcomingTodosFunc(ctx, q, days, loc, cursor)
 }
 
 func (u *UseCaseMock) GetTodoByID(ctx context.Context, id int) (domain.Todo, error) {
@@ -66,13 +227,454 @@ func (u *UseCaseMock) UpdateTodoByID(ctx context.Context, id int, todo domain.To
 	return u.UpdateTodoByIDFunc(ctx, id, todo)
 }
 
-func (u *UseCaseMock) DeleteTodoByID(ctx context.Context, id int) error {
+func (u *UseCaseMock) PatchTodoByID(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+	u.LastGetID = id
+	u.LastVersion = version
+	u.LastPatch = patch
+	u.PatchTodoByIDCalls++
+
+	if u.PatchTodoByIDFunc == nil {
+		panic("PatchTodoByIDFunc is nil")
+	}
+
+	return u.PatchTodoByIDFunc(ctx, id, version, patch)
+}
+
+func (u *UseCaseMock) DeleteTodoByID(ctx context.Context, id int, version int) error {
 	u.LastGetID = id
+	u.LastVersion = version
 	u.DeleteTodoByIDCalls++
 
 	if u.DeleteTodoByIDFunc == nil {
 		panic("DeleteTodoByIDFunc is nil")
 	}
 
-	return u.DeleteTodoByIDFunc(ctx, id)
+	return u.DeleteTodoByIDFunc(ctx, id, version)
+}
+
+func (u *UseCaseMock) RunBatch(ctx context.Context, batch domain.Batch) ([]domain.BatchResult, error) {
+	u.LastBatch = batch
+	u.RunBatchCalls++
+
+	if u.RunBatchFunc == nil {
+		panic("RunBatchFunc is nil")
+	}
+
+	return u.RunBatchFunc(ctx, batch)
+}
+
+func (u *UseCaseMock) CompleteTodos(ctx context.Context, filter domain.TodoFilter, atomic bool) ([]domain.BatchResult, error) {
+	u.LastQuery = domain.TodoQuery{Filter: filter}
+	u.LastAtomic = atomic
+	u.CompleteTodosCalls++
+
+	if u.CompleteTodosFunc == nil {
+		panic("CompleteTodosFunc is nil")
+	}
+
+	return u.CompleteTodosFunc(ctx, filter, atomic)
+}
+
+func (u *UseCaseMock) DeleteCompletedTodos(ctx context.Context, filter domain.TodoFilter, atomic bool) ([]domain.BatchResult, error) {
+	u.LastQuery = domain.TodoQuery{Filter: filter}
+	u.LastAtomic = atomic
+	u.DeleteCompletedTodosCalls++
+
+	if u.DeleteCompletedTodosFunc == nil {
+		panic("DeleteCompletedTodosFunc is nil")
+	}
+
+	return u.DeleteCompletedTodosFunc(ctx, filter, atomic)
+}
+
+func (u *UseCaseMock) ListSubtasks(ctx context.Context, id int, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+	u.LastGetID = id
+	u.LastQuery = q
+	u.LastCursor = cursor
+	u.ListSubtasksCalls++
+
+	if u.ListSubtasksFunc == nil {
+		panic("ListSubtasksFunc is nil")
+	}
+
+	return u.ListSubtasksFunc(ctx, id, q, cursor)
+}
+
+func (u *UseCaseMock) GetTodoProgress(ctx context.Context, id int) (domain.Progress, error) {
+	u.LastGetID = id
+	u.GetTodoProgressCalls++
+
+	if u.GetTodoProgressFunc == nil {
+		panic("GetTodoProgressFunc is nil")
+	}
+
+	return u.GetTodoProgressFunc(ctx, id)
+}
+
+func (u *UseCaseMock) PreviewOccurrences(ctx context.Context, id int, n int) ([]time.Time, error) {
+	u.LastGetID = id
+	u.LastCount = n
+	u.PreviewOccurrencesCalls++
+
+	if u.PreviewOccurrencesFunc == nil {
+		panic("PreviewOccurrencesFunc is nil")
+	}
+
+	return u.PreviewOccurrencesFunc(ctx, id, n)
+}
+
+func (u *UseCaseMock) GetTodoHistory(ctx context.Context, id int) ([]domain.TodoChange, error) {
+	u.LastGetID = id
+	u.GetTodoHistoryCalls++
+
+	if u.GetTodoHistoryFunc == nil {
+		panic("GetTodoHistoryFunc is nil")
+	}
+
+	return u.GetTodoHistoryFunc(ctx, id)
+}
+
+func (u *UseCaseMock) TodoEvents(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent {
+	u.LastEventID = lastEventID
+	u.TodoEventsCalls++
+
+	if u.TodoEventsFunc == nil {
+		panic("TodoEventsFunc is nil")
+	}
+
+	return u.TodoEventsFunc(ctx, lastEventID)
+}
+
+func (u *UseCaseMock) GetTrash(ctx context.Context) ([]domain.TrashedTodo, error) {
+	u.GetTrashCalls++
+
+	if u.GetTrashFunc == nil {
+		panic("GetTrashFunc is nil")
+	}
+
+	return u.GetTrashFunc(ctx)
+}
+
+func (u *UseCaseMock) RestoreTodo(ctx context.Context, id int) (domain.Todo, error) {
+	u.LastGetID = id
+	u.RestoreTodoCalls++
+
+	if u.RestoreTodoFunc == nil {
+		panic("RestoreTodoFunc is nil")
+	}
+
+	return u.RestoreTodoFunc(ctx, id)
+}
+
+func (u *UseCaseMock) PurgeTodo(ctx context.Context, id int) error {
+	u.LastGetID = id
+	u.PurgeTodoCalls++
+
+	if u.PurgeTodoFunc == nil {
+		panic("PurgeTodoFunc is nil")
+	}
+
+	return u.PurgeTodoFunc(ctx, id)
+}
+
+func (u *UseCaseMock) Undo(ctx context.Context) (domain.Command, error) {
+	u.UndoCalls++
+
+	if u.UndoFunc == nil {
+		panic("UndoFunc is nil")
+	}
+
+	return u.UndoFunc(ctx)
+}
+
+func (u *UseCaseMock) Redo(ctx context.Context) (domain.Command, error) {
+	u.RedoCalls++
+
+	if u.RedoFunc == nil {
+		panic("RedoFunc is nil")
+	}
+
+	return u.RedoFunc(ctx)
+}
+
+func (u *UseCaseMock) CreateTag(ctx context.Context, tag domain.Tag) (int, error) {
+	u.LastSavedTag = tag
+	u.CreateTagCalls++
+
+	if u.CreateTagFunc == nil {
+		panic("CreateTagFunc is nil")
+	}
+
+	return u.CreateTagFunc(ctx, tag)
+}
+
+func (u *UseCaseMock) GetAllTags(ctx context.Context) ([]domain.Tag, error) {
+	u.GetAllTagsCalls++
+
+	if u.GetAllTagsFunc == nil {
+		panic("GetAllTagsFunc is nil")
+	}
+
+	return u.GetAllTagsFunc(ctx)
+}
+
+func (u *UseCaseMock) GetTagByID(ctx context.Context, id int) (domain.Tag, error) {
+	u.LastTagID = id
+	u.GetTagByIDCalls++
+
+	if u.GetTagByIDFunc == nil {
+		panic("GetTagByIDFunc is nil")
+	}
+
+	return u.GetTagByIDFunc(ctx, id)
+}
+
+func (u *UseCaseMock) UpdateTagByID(ctx context.Context, id int, tag domain.Tag) error {
+	u.LastTagID = id
+	u.LastSavedTag = tag
+	u.UpdateTagByIDCalls++
+
+	if u.UpdateTagByIDFunc == nil {
+		panic("UpdateTagByIDFunc is nil")
+	}
+
+	return u.UpdateTagByIDFunc(ctx, id, tag)
+}
+
+func (u *UseCaseMock) DeleteTagByID(ctx context.Context, id int) error {
+	u.LastTagID = id
+	u.DeleteTagByIDCalls++
+
+	if u.DeleteTagByIDFunc == nil {
+		panic("DeleteTagByIDFunc is nil")
+	}
+
+	return u.DeleteTagByIDFunc(ctx, id)
+}
+
+func (u *UseCaseMock) AttachTag(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error) {
+	u.LastGetID = todoID
+	u.LastTagID = tagID
+	u.LastVersion = version
+	u.AttachTagCalls++
+
+	if u.AttachTagFunc == nil {
+		panic("AttachTagFunc is nil")
+	}
+
+	return u.AttachTagFunc(ctx, todoID, tagID, version)
+}
+
+func (u *UseCaseMock) DetachTag(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error) {
+	u.LastGetID = todoID
+	u.LastTagID = tagID
+	u.LastVersion = version
+	u.DetachTagCalls++
+
+	if u.DetachTagFunc == nil {
+		panic("DetachTagFunc is nil")
+	}
+
+	return u.DetachTagFunc(ctx, todoID, tagID, version)
+}
+
+func (u *UseCaseMock) CreateList(ctx context.Context, list domain.List) (int, error) {
+	u.LastSavedList = list
+	u.CreateListCalls++
+
+	if u.CreateListFunc == nil {
+		panic("CreateListFunc is nil")
+	}
+
+	return u.CreateListFunc(ctx, list)
+}
+
+func (u *UseCaseMock) GetLists(ctx context.Context) ([]domain.List, error) {
+	u.GetListsCalls++
+
+	if u.GetListsFunc == nil {
+		panic("GetListsFunc is nil")
+	}
+
+	return u.GetListsFunc(ctx)
+}
+
+func (u *UseCaseMock) GetListByID(ctx context.Context, id int) (domain.List, error) {
+	u.LastListID = id
+	u.GetListByIDCalls++
+
+	if u.GetListByIDFunc == nil {
+		panic("GetListByIDFunc is nil")
+	}
+
+	return u.GetListByIDFunc(ctx, id)
+}
+
+func (u *UseCaseMock) RenameList(ctx context.Context, id int, name string) (domain.List, error) {
+	u.LastListID = id
+	u.LastSavedList = domain.List{Name: name}
+	u.RenameListCalls++
+
+	if u.RenameListFunc == nil {
+		panic("RenameListFunc is nil")
+	}
+
+	return u.RenameListFunc(ctx, id, name)
+}
+
+func (u *UseCaseMock) DeleteListByID(ctx context.Context, id int) error {
+	u.LastListID = id
+	u.DeleteListByIDCalls++
+
+	if u.DeleteListByIDFunc == nil {
+		panic("DeleteListByIDFunc is nil")
+	}
+
+	return u.DeleteListByIDFunc(ctx, id)
+}
+
+func (u *UseCaseMock) InviteMember(ctx context.Context, listID int, inv domain.Invitation) (domain.List, error) {
+	u.LastListID = listID
+	u.LastInvitation = inv
+	u.InviteMemberCalls++
+
+	if u.InviteMemberFunc == nil {
+		panic("InviteMemberFunc is nil")
+	}
+
+	return u.InviteMemberFunc(ctx, listID, inv)
+}
+
+func (u *UseCaseMock) RevokeInvitation(ctx context.Context, listID int, email string) (domain.List, error) {
+	u.LastListID = listID
+	u.LastInvitation = domain.Invitation{Email: email}
+	u.RevokeInvitationCalls++
+
+	if u.RevokeInvitationFunc == nil {
+		panic("RevokeInvitationFunc is nil")
+	}
+
+	return u.RevokeInvitationFunc(ctx, listID, email)
+}
+
+func (u *UseCaseMock) GetInvitations(ctx context.Context) ([]domain.PendingInvitation, error) {
+	u.GetInvitationsCalls++
+
+	if u.GetInvitationsFunc == nil {
+		panic("GetInvitationsFunc is nil")
+	}
+
+	return u.GetInvitationsFunc(ctx)
+}
+
+func (u *UseCaseMock) AcceptInvitation(ctx context.Context, listID int) (domain.List, error) {
+	u.LastListID = listID
+	u.AcceptInvitationCalls++
+
+	if u.AcceptInvitationFunc == nil {
+		panic("AcceptInvitationFunc is nil")
+	}
+
+	return u.AcceptInvitationFunc(ctx, listID)
+}
+
+func (u *UseCaseMock) DeclineInvitation(ctx context.Context, listID int) error {
+	u.LastListID = listID
+	u.DeclineInvitationCalls++
+
+	if u.DeclineInvitationFunc == nil {
+		panic("DeclineInvitationFunc is nil")
+	}
+
+	return u.DeclineInvitationFunc(ctx, listID)
+}
+
+func (u *UseCaseMock) ChangeMemberRole(ctx context.Context, listID int, userID int, role domain.Role) (domain.List, error) {
+	u.LastListID = listID
+	u.LastMemberID = userID
+	u.LastRole = role
+	u.ChangeMemberRoleCalls++
+
+	if u.ChangeMemberRoleFunc == nil {
+		panic("ChangeMemberRoleFunc is nil")
+	}
+
+	return u.ChangeMemberRoleFunc(ctx, listID, userID, role)
+}
+
+func (u *UseCaseMock) RemoveMember(ctx context.Context, listID int, userID int) error {
+	u.LastListID = listID
+	u.LastMemberID = userID
+	u.RemoveMemberCalls++
+
+	if u.RemoveMemberFunc == nil {
+		panic("RemoveMemberFunc is nil")
+	}
+
+	return u.RemoveMemberFunc(ctx, listID, userID)
+}
+
+func (u *UseCaseMock) CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.CreatedWebhook, error) {
+	u.LastSavedWebhook = hook
+	u.CreateWebhookCalls++
+
+	if u.CreateWebhookFunc == nil {
+		panic("CreateWebhookFunc is nil")
+	}
+
+	return u.CreateWebhookFunc(ctx, hook)
+}
+
+func (u *UseCaseMock) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	u.GetWebhooksCalls++
+
+	if u.GetWebhooksFunc == nil {
+		panic("GetWebhooksFunc is nil")
+	}
+
+	return u.GetWebhooksFunc(ctx)
+}
+
+func (u *UseCaseMock) DeleteWebhookByID(ctx context.Context, id int) error {
+	u.LastWebhookID = id
+	u.DeleteWebhookByIDCalls++
+
+	if u.DeleteWebhookByIDFunc == nil {
+		panic("DeleteWebhookByIDFunc is nil")
+	}
+
+	return u.DeleteWebhookByIDFunc(ctx, id)
+}
+
+func (u *UseCaseMock) GetWebhookDeliveries(ctx context.Context, id int) ([]domain.WebhookDelivery, error) {
+	u.LastWebhookID = id
+	u.GetWebhookDeliveriesCalls++
+
+	if u.GetWebhookDeliveriesFunc == nil {
+		panic("GetWebhookDeliveriesFunc is nil")
+	}
+
+	return u.GetWebhookDeliveriesFunc(ctx, id)
+}
+
+func (u *UseCaseMock) GetDeadLetters(ctx context.Context) ([]domain.WebhookDelivery, error) {
+	u.GetDeadLettersCalls++
+
+	if u.GetDeadLettersFunc == nil {
+		panic("GetDeadLettersFunc is nil")
+	}
+
+	return u.GetDeadLettersFunc(ctx)
+}
+
+func (u *UseCaseMock) RedeliverWebhook(ctx context.Context, id int, deliveryID int) error {
+	u.LastWebhookID = id
+	u.LastDeliveryID = deliveryID
+	u.RedeliverWebhookCalls++
+
+	if u.RedeliverWebhookFunc == nil {
+		panic("RedeliverWebhookFunc is nil")
+	}
+
+	return u.RedeliverWebhookFunc(ctx, id, deliveryID)
 }
@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/VLGKiwi/todo-site/backend/internal/domain"
 )
@@ -23,7 +26,7 @@ func TestCreateTodoHandler(t *testing.T) {
 		wantCode        int
 		wantContentType string
 		wantLocation    string
-		wantBody        string
+		wantProblem     string
 
 		wantID    int
 		wantTitle string
@@ -49,8 +52,8 @@ func TestCreateTodoHandler(t *testing.T) {
 			method:          http.MethodPost,
 			body:            `"title": "complete the task"`,
 			wantCode:        http.StatusBadRequest,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "bad request\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemMalformedRequest,
 			wantCalls:       0,
 		},
 		{
@@ -61,8 +64,8 @@ func TestCreateTodoHandler(t *testing.T) {
 				return 0, domain.ErrNoTitle
 			},
 			wantCode:        http.StatusBadRequest,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "bad request\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemValidationFailed,
 			wantTitle:       "",
 			wantCalls:       1,
 		},
@@ -74,8 +77,8 @@ func TestCreateTodoHandler(t *testing.T) {
 				return 0, errors.New("some error in usecase")
 			},
 			wantCode:        http.StatusInternalServerError,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "internal server error\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemInternal,
 			wantTitle:       "complete the task",
 			wantCalls:       1,
 		},
@@ -126,10 +129,8 @@ func TestCreateTodoHandler(t *testing.T) {
 				}
 			}
 
-			if tc.wantBody != "" {
-				if rec.Body.String() != tc.wantBody {
-					t.Errorf("unexpected body: got %q, want %q", rec.Body.String(), tc.wantBody)
-				}
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
 			}
 
 			if tc.wantID != 0 {
@@ -163,40 +164,164 @@ func TestGetAllTodosHandler(t *testing.T) {
 		{ID: 2, Title: "read the book"},
 	}
 
+	completed := true
+	listID := 7
+
 	tests := []struct {
 		name   string
 		method string
+		url    string
 
-		usecaseFunc func(ctx context.Context) ([]domain.Todo, error)
+		usecaseFunc func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error)
 
 		wantCode        int
 		wantContentType string
-		wantBody        string
+		wantProblem     string
+		wantNextCursor  string
+		wantLink        string
 
-		wantTodos bool
+		wantTodos  bool
+		wantQuery  domain.TodoQuery
+		wantCursor string
 
 		wantCalls int
 	}{
 		{
 			name:   "success",
 			method: http.MethodGet,
-			usecaseFunc: func(ctx context.Context) ([]domain.Todo, error) {
-				return todos, nil
+			url:    "/api/todos",
+			usecaseFunc: func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+				return domain.TodoPage{Todos: todos}, nil
 			},
 			wantCode:        http.StatusOK,
 			wantContentType: "application/json",
 			wantTodos:       true,
 			wantCalls:       1,
 		},
+		{
+			name:   "query parameters are passed to usecase",
+			method: http.MethodGet,
+			url:    "/api/todos?completed=true&title=book&description=chapter&q=read&sort=title&order=desc&limit=2&cursor=abc",
+			usecaseFunc: func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+				return domain.TodoPage{Todos: todos}, nil
+			},
+			wantCode:  http.StatusOK,
+			wantTodos: true,
+			wantQuery: domain.TodoQuery{
+				Filter: domain.TodoFilter{
+					Completed:   &completed,
+					Title:       "book",
+					Description: "chapter",
+					Search:      "read",
+				},
+				Sort:  domain.SortByTitle,
+				Desc:  true,
+				Limit: 2,
+			},
+			wantCursor: "abc",
+			wantCalls:  1,
+		},
+		{
+			name:   "next page -> cursor headers",
+			method: http.MethodGet,
+			url:    "/api/todos?limit=2&sort=title",
+			usecaseFunc: func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+				return domain.TodoPage{Todos: todos, NextCursor: "next"}, nil
+			},
+			wantCode:       http.StatusOK,
+			wantTodos:      true,
+			wantNextCursor: "next",
+			wantLink:       `</api/todos?cursor=next&limit=2&sort=title>; rel="next"`,
+			wantQuery:      domain.TodoQuery{Sort: domain.SortByTitle, Limit: 2},
+			wantCalls:      1,
+		},
+		{
+			name:            "invalid completed -> error",
+			method:          http.MethodGet,
+			url:             "/api/todos?completed=maybe",
+			wantCode:        http.StatusBadRequest,
+			wantContentType: problemContentType,
+			wantProblem:     problemInvalidQuery,
+			wantCalls:       0,
+		},
+		{
+			name:   "list filter",
+			method: http.MethodGet,
+			url:    "/api/todos?list_id=7",
+			usecaseFunc: func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+				return domain.TodoPage{Todos: todos}, nil
+			},
+			wantCode:  http.StatusOK,
+			wantTodos: true,
+			wantQuery: domain.TodoQuery{Filter: domain.TodoFilter{ListID: &listID}},
+			wantCalls: 1,
+		},
+		{
+			name:        "invalid list id -> error",
+			method:      http.MethodGet,
+			url:         "/api/todos?list_id=home",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+			wantCalls:   0,
+		},
+		{
+			name:   "past state",
+			method: http.MethodGet,
+			url:    "/api/todos?as_of=2024-05-01T12:00:00Z",
+			usecaseFunc: func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+				return domain.TodoPage{Todos: todos}, nil
+			},
+			wantCode:  http.StatusOK,
+			wantTodos: true,
+			wantQuery: domain.TodoQuery{AsOf: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+			wantCalls: 1,
+		},
+		{
+			name:        "invalid as of -> error",
+			method:      http.MethodGet,
+			url:         "/api/todos?as_of=yesterday",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+			wantCalls:   0,
+		},
+		{
+			name:        "invalid order -> error",
+			method:      http.MethodGet,
+			url:         "/api/todos?order=up",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+			wantCalls:   0,
+		},
+		{
+			name:        "invalid limit -> error",
+			method:      http.MethodGet,
+			url:         "/api/todos?limit=0",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+			wantCalls:   0,
+		},
+		{
+			name:   "invalid query -> error",
+			method: http.MethodGet,
+			url:    "/api/todos?sort=deadline",
+			usecaseFunc: func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+				return domain.TodoPage{}, fmt.Errorf("%w: unknown sort field", domain.ErrInvalidQuery)
+			},
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+			wantQuery:   domain.TodoQuery{Sort: "deadline"},
+			wantCalls:   1,
+		},
 		{
 			name:   "failed to get todos -> error",
 			method: http.MethodGet,
-			usecaseFunc: func(ctx context.Context) ([]domain.Todo, error) {
-				return []domain.Todo{}, errors.New("some error in usecase")
+			url:    "/api/todos",
+			usecaseFunc: func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+				return domain.TodoPage{}, errors.New("some error in usecase")
 			},
 			wantCode:        http.StatusInternalServerError,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "internal server error\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemInternal,
 			wantCalls:       1,
 		},
 	}
@@ -204,18 +329,18 @@ func TestGetAllTodosHandler(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			// preparing
-			req := httptest.NewRequest(tc.method, "/todos", nil)
+			req := httptest.NewRequest(tc.method, tc.url, nil)
 			rec := httptest.NewRecorder()
 
 			useCaseMock := &UseCaseMock{}
 
 			if tc.wantCalls == 0 {
-				useCaseMock.GetAllTodosFunc = func(ctx context.Context) ([]domain.Todo, error) {
-					t.Fatalf("GetAllTodos must not be called")
-					return []domain.Todo{}, nil
+				useCaseMock.ListTodosFunc = func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+					t.Fatalf("ListTodos must not be called")
+					return domain.TodoPage{}, nil
 				}
 			} else {
-				useCaseMock.GetAllTodosFunc = tc.usecaseFunc
+				useCaseMock.ListTodosFunc = tc.usecaseFunc
 			}
 
 			handlers := Handlers{
@@ -237,11 +362,15 @@ func TestGetAllTodosHandler(t *testing.T) {
 				}
 			}
 
-			if tc.wantBody != "" {
-				gotBody := rec.Body.String()
-				if gotBody != tc.wantBody {
-					t.Errorf("unexpected body: got %q, want %q", gotBody, tc.wantBody)
-				}
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+			}
+
+			if got := rec.Header().Get("X-Next-Cursor"); got != tc.wantNextCursor {
+				t.Errorf("unexpected X-Next-Cursor: got %q, want %q", got, tc.wantNextCursor)
+			}
+			if got := rec.Header().Get("Link"); got != tc.wantLink {
+				t.Errorf("unexpected Link: got %q, want %q", got, tc.wantLink)
 			}
 
 			if tc.wantTodos {
@@ -265,8 +394,17 @@ func TestGetAllTodosHandler(t *testing.T) {
 				}
 			}
 
-			if useCaseMock.GetAllTodosCalls != tc.wantCalls {
-				t.Errorf("unexpected calls: got %d, want %d", useCaseMock.GetAllTodosCalls, tc.wantCalls)
+			if useCaseMock.ListTodosCalls != tc.wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", useCaseMock.ListTodosCalls, tc.wantCalls)
+			}
+
+			if tc.wantCalls > 0 {
+				if !reflect.DeepEqual(useCaseMock.LastQuery, tc.wantQuery) {
+					t.Errorf("unexpected query: got %+v, want %+v", useCaseMock.LastQuery, tc.wantQuery)
+				}
+				if useCaseMock.LastCursor != tc.wantCursor {
+					t.Errorf("unexpected cursor: got %q, want %q", useCaseMock.LastCursor, tc.wantCursor)
+				}
 			}
 		})
 	}
@@ -286,7 +424,7 @@ func TestGetTodoHandler(t *testing.T) {
 
 		wantCode        int
 		wantContentType string
-		wantBody        string
+		wantProblem     string
 
 		pathValue string
 		wantTodo  bool
@@ -311,8 +449,8 @@ func TestGetTodoHandler(t *testing.T) {
 			name:            "failed to get path value -> error",
 			method:          http.MethodGet,
 			wantCode:        http.StatusBadRequest,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "bad request\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemMalformedRequest,
 			pathValue:       "abc",
 			wantCalls:       0,
 		},
@@ -323,8 +461,8 @@ func TestGetTodoHandler(t *testing.T) {
 				return domain.Todo{}, domain.ErrTodoNotExist
 			},
 			wantCode:        http.StatusNotFound,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "todo not found\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemTodoNotFound,
 			pathValue:       "1",
 			wantID:          1,
 			wantCalls:       1,
@@ -336,8 +474,8 @@ func TestGetTodoHandler(t *testing.T) {
 				return domain.Todo{}, errors.New("some error from usecase")
 			},
 			wantCode:        http.StatusInternalServerError,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "internal server error\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemInternal,
 			pathValue:       "1",
 			wantID:          1,
 			wantCalls:       1,
@@ -383,11 +521,8 @@ func TestGetTodoHandler(t *testing.T) {
 				}
 			}
 
-			if tc.wantBody != "" {
-				gotBody := rec.Body.String()
-				if gotBody != tc.wantBody {
-					t.Errorf("unexpected body: got %q, want %q", gotBody, tc.wantBody)
-				}
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
 			}
 
 			if tc.wantTodo {
@@ -427,7 +562,7 @@ func TestUpdateTodoHandler(t *testing.T) {
 		usecaseFunc     func(ctx context.Context, id int, todo domain.Todo) error
 		wantCode        int
 		wantContentType string
-		wantBody        string
+		wantProblem     string
 
 		pathValue string
 		wantTitle string
@@ -455,8 +590,8 @@ func TestUpdateTodoHandler(t *testing.T) {
 			name:            "failed to get path value -> error",
 			method:          http.MethodPut,
 			wantCode:        http.StatusBadRequest,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "bad request\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemMalformedRequest,
 			pathValue:       "abc",
 			wantCalls:       0,
 		},
@@ -465,8 +600,8 @@ func TestUpdateTodoHandler(t *testing.T) {
 			method:          http.MethodPut,
 			body:            `}`,
 			wantCode:        http.StatusBadRequest,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "bad request\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemMalformedRequest,
 			pathValue:       "1",
 			wantCalls:       0,
 		},
@@ -478,7 +613,7 @@ func TestUpdateTodoHandler(t *testing.T) {
 				return domain.ErrTodoNotExist
 			},
 			wantCode:        http.StatusNotFound,
-			wantContentType: "text/plain; charset=utf-8",
+			wantContentType: problemContentType,
 			pathValue:       "1",
 			wantTitle:       "read the book",
 			wantID:          1,
@@ -492,7 +627,7 @@ func TestUpdateTodoHandler(t *testing.T) {
 				return domain.ErrNoTitle
 			},
 			wantCode:        http.StatusBadRequest,
-			wantContentType: "text/plain; charset=utf-8",
+			wantContentType: problemContentType,
 			pathValue:       "1",
 			wantTitle:       "",
 			wantID:          1,
@@ -506,7 +641,7 @@ func TestUpdateTodoHandler(t *testing.T) {
 				return errors.New("some error from usecase")
 			},
 			wantCode:        http.StatusInternalServerError,
-			wantContentType: "text/plain; charset=utf-8",
+			wantContentType: problemContentType,
 			pathValue:       "1",
 			wantTitle:       "read the book",
 			wantID:          1,
@@ -555,13 +690,8 @@ func TestUpdateTodoHandler(t *testing.T) {
 				}
 			}
 
-			if tc.wantBody != "" {
-
-				gotBody := rec.Body.String()
-				if gotBody != tc.wantBody {
-					t.Errorf("unexpected body: got %q, want %q", gotBody, tc.wantBody)
-				}
-
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
 			}
 
 			if tc.wantJson != "" {
@@ -592,15 +722,220 @@ func TestUpdateTodoHandler(t *testing.T) {
 	}
 }
 
+func TestPatchTodoHandler(t *testing.T) {
+	patchedTodo := domain.Todo{
+		ID:        1,
+		Title:     "read the book",
+		Completed: true,
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		contentType string
+
+		usecaseFunc func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error)
+
+		wantCode        int
+		wantContentType string
+		wantProblem     string
+
+		pathValue string
+		wantTodo  bool
+		wantID    int
+
+		wantCalls int
+	}{
+		{
+			name:        "success",
+			method:      http.MethodPatch,
+			body:        `{"completed": true}`,
+			contentType: "application/merge-patch+json",
+			usecaseFunc: func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+				return patchedTodo, nil
+			},
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json",
+			pathValue:       "1",
+			wantTodo:        true,
+			wantID:          1,
+			wantCalls:       1,
+		},
+		{
+			name:        "plain json is accepted",
+			method:      http.MethodPatch,
+			body:        `{"completed": true}`,
+			contentType: "application/json; charset=utf-8",
+			usecaseFunc: func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+				return patchedTodo, nil
+			},
+			wantCode:  http.StatusOK,
+			pathValue: "1",
+			wantTodo:  true,
+			wantID:    1,
+			wantCalls: 1,
+		},
+		{
+			name:            "failed to get path id",
+			method:          http.MethodPatch,
+			body:            `{"completed": true}`,
+			wantCode:        http.StatusBadRequest,
+			wantContentType: problemContentType,
+			wantProblem:     problemMalformedRequest,
+			pathValue:       "abc",
+			wantCalls:       0,
+		},
+		{
+			name:            "unsupported content type -> error",
+			method:          http.MethodPatch,
+			body:            `[{"op": "replace", "path": "/completed", "value": true}]`,
+			contentType:     "application/json-patch+json",
+			wantCode:        http.StatusUnsupportedMediaType,
+			wantContentType: problemContentType,
+			wantProblem:     problemUnsupportedMediaType,
+			pathValue:       "1",
+			wantCalls:       0,
+		},
+		{
+			name:   "invalid patch -> error",
+			method: http.MethodPatch,
+			body:   `{"completed": "yes"}`,
+			usecaseFunc: func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+				return domain.Todo{}, domain.ErrInvalidPatch
+			},
+			wantCode:        http.StatusBadRequest,
+			wantContentType: problemContentType,
+			wantProblem:     problemInvalidPatch,
+			pathValue:       "1",
+			wantID:          1,
+			wantCalls:       1,
+		},
+		{
+			name:   "merged todo is invalid -> error",
+			method: http.MethodPatch,
+			body:   `{"title": null}`,
+			usecaseFunc: func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+				return domain.Todo{}, domain.ErrNoTitle
+			},
+			wantCode:        http.StatusBadRequest,
+			wantContentType: problemContentType,
+			wantProblem:     problemValidationFailed,
+			pathValue:       "1",
+			wantID:          1,
+			wantCalls:       1,
+		},
+		{
+			name:   "todo not exist -> error",
+			method: http.MethodPatch,
+			body:   `{"completed": true}`,
+			usecaseFunc: func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+				return domain.Todo{}, domain.ErrTodoNotExist
+			},
+			wantCode:        http.StatusNotFound,
+			wantContentType: problemContentType,
+			wantProblem:     problemTodoNotFound,
+			pathValue:       "1",
+			wantID:          1,
+			wantCalls:       1,
+		},
+		{
+			name:   "internal server error -> error",
+			method: http.MethodPatch,
+			body:   `{"completed": true}`,
+			usecaseFunc: func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+				return domain.Todo{}, errors.New("some error from usecase")
+			},
+			wantCode:        http.StatusInternalServerError,
+			wantContentType: problemContentType,
+			wantProblem:     problemInternal,
+			pathValue:       "1",
+			wantID:          1,
+			wantCalls:       1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			req := httptest.NewRequest(tc.method, "/todos/1", strings.NewReader(tc.body))
+			req.SetPathValue("id", tc.pathValue)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rec := httptest.NewRecorder()
+
+			useCaseMock := &UseCaseMock{}
+
+			if tc.wantCalls == 0 {
+				useCaseMock.PatchTodoByIDFunc = func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+					t.Fatalf("PatchTodoByID must not be called")
+					return domain.Todo{}, nil
+				}
+			} else {
+				useCaseMock.PatchTodoByIDFunc = tc.usecaseFunc
+			}
+
+			handlers := Handlers{
+				UseCase: useCaseMock,
+			}
+
+			// act
+			handlers.PatchTodoHandler(rec, req)
+
+			// assert
+			if tc.wantCode != rec.Code {
+				t.Errorf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if tc.wantContentType != "" {
+				gotCT := rec.Header().Get("Content-Type")
+				if gotCT != tc.wantContentType {
+					t.Errorf("unexpected Content-Type: got %q, want %q", gotCT, tc.wantContentType)
+				}
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+			}
+
+			if tc.wantTodo {
+				var resp domain.Todo
+
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode json: %v, body=%q", err, rec.Body.String())
+				}
+
+				if !resp.Equal(patchedTodo) {
+					t.Errorf("unexpected todo: got %+v, want %+v", resp, patchedTodo)
+				}
+			}
+
+			if useCaseMock.PatchTodoByIDCalls != tc.wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", useCaseMock.PatchTodoByIDCalls, tc.wantCalls)
+			}
+
+			if tc.wantCalls > 0 {
+				if useCaseMock.LastGetID != tc.wantID {
+					t.Errorf("unexpected id: got %d, want %d", useCaseMock.LastGetID, tc.wantID)
+				}
+				if string(useCaseMock.LastPatch) != tc.body {
+					t.Errorf("unexpected patch: got %q, want %q", useCaseMock.LastPatch, tc.body)
+				}
+			}
+		})
+	}
+}
+
 func TestDeleteTodoHandler(t *testing.T) {
 	tests := []struct {
 		name   string
 		method string
 
-		usecaseFunc     func(ctx context.Context, id int) error
+		usecaseFunc     func(ctx context.Context, id int, version int) error
 		wantCode        int
 		wantContentType string
-		wantBody        string
+		wantProblem     string
 
 		pathValue string
 		wantID    int
@@ -610,11 +945,10 @@ func TestDeleteTodoHandler(t *testing.T) {
 		{
 			name:   "success",
 			method: http.MethodDelete,
-			usecaseFunc: func(ctx context.Context, id int) error {
+			usecaseFunc: func(ctx context.Context, id int, version int) error {
 				return nil
 			},
 			wantCode:  http.StatusNoContent,
-			wantBody:  "",
 			pathValue: "1",
 			wantID:    1,
 			wantCalls: 1,
@@ -623,20 +957,20 @@ func TestDeleteTodoHandler(t *testing.T) {
 			name:            "failed to get path id",
 			method:          http.MethodDelete,
 			wantCode:        http.StatusBadRequest,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "bad request\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemMalformedRequest,
 			pathValue:       "abc",
 			wantCalls:       0,
 		},
 		{
 			name:   "failed to delete -> error",
 			method: http.MethodDelete,
-			usecaseFunc: func(ctx context.Context, id int) error {
+			usecaseFunc: func(ctx context.Context, id int, version int) error {
 				return domain.ErrTodoNotExist
 			},
 			wantCode:        http.StatusNotFound,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "todo not found\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemTodoNotFound,
 			pathValue:       "1",
 			wantID:          1,
 			wantCalls:       1,
@@ -644,12 +978,12 @@ func TestDeleteTodoHandler(t *testing.T) {
 		{
 			name:   "internal server error -> error",
 			method: http.MethodDelete,
-			usecaseFunc: func(ctx context.Context, id int) error {
+			usecaseFunc: func(ctx context.Context, id int, version int) error {
 				return errors.New("some error from usecase")
 			},
 			wantCode:        http.StatusInternalServerError,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBody:        "internal server error\n",
+			wantContentType: problemContentType,
+			wantProblem:     problemInternal,
 			pathValue:       "1",
 			wantID:          1,
 			wantCalls:       1,
@@ -668,7 +1002,7 @@ func TestDeleteTodoHandler(t *testing.T) {
 			}
 
 			if tc.wantCalls == 0 {
-				useCaseMock.DeleteTodoByIDFunc = func(ctx context.Context, id int) error {
+				useCaseMock.DeleteTodoByIDFunc = func(ctx context.Context, id int, version int) error {
 					t.Fatalf("DeleteTodoByID must not be called")
 					return nil
 				}
@@ -695,11 +1029,8 @@ func TestDeleteTodoHandler(t *testing.T) {
 				}
 			}
 
-			if tc.wantBody != "" {
-				gotBody := rec.Body.String()
-				if gotBody != tc.wantBody {
-					t.Errorf("unexpected body: got %q, want %q", gotBody, tc.wantBody)
-				}
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
 			}
 
 			if useCaseMock.DeleteTodoByIDCalls != tc.wantCalls {
@@ -714,3 +1045,222 @@ func TestDeleteTodoHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestConditionalRequests(t *testing.T) {
+	storedTodo := domain.Todo{
+		ID:      1,
+		Title:   "read the book",
+		Version: 3,
+	}
+
+	getTodo := func(ctx context.Context, id int) (domain.Todo, error) {
+		return storedTodo, nil
+	}
+	checkVersion := func(version int) error {
+		if version != 0 && version != storedTodo.Version {
+			return domain.ErrVersionMismatch
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		headers map[string]string
+
+		wantCode    int
+		wantETag    string
+		wantVersion int
+	}{
+		{
+			name:     "get sets etag",
+			method:   http.MethodGet,
+			wantCode: http.StatusOK,
+			wantETag: `"3"`,
+		},
+		{
+			name:     "get with matching If-None-Match -> not modified",
+			method:   http.MethodGet,
+			headers:  map[string]string{"If-None-Match": `W/"3"`},
+			wantCode: http.StatusNotModified,
+			wantETag: `"3"`,
+		},
+		{
+			name:     "get with other If-None-Match",
+			method:   http.MethodGet,
+			headers:  map[string]string{"If-None-Match": `"2"`},
+			wantCode: http.StatusOK,
+			wantETag: `"3"`,
+		},
+		{
+			name:        "put with matching If-Match",
+			method:      http.MethodPut,
+			body:        `{"title": "read the book"}`,
+			headers:     map[string]string{"If-Match": `"3"`},
+			wantCode:    http.StatusOK,
+			wantETag:    `"4"`,
+			wantVersion: 3,
+		},
+		{
+			name:        "put with stale If-Match -> precondition failed",
+			method:      http.MethodPut,
+			body:        `{"title": "read the book"}`,
+			headers:     map[string]string{"If-Match": `"2"`},
+			wantCode:    http.StatusPreconditionFailed,
+			wantVersion: 2,
+		},
+		{
+			name:        "put with stale version in body -> precondition failed",
+			method:      http.MethodPut,
+			body:        `{"title": "read the book", "version": 1}`,
+			wantCode:    http.StatusPreconditionFailed,
+			wantVersion: 1,
+		},
+		{
+			name:        "put with If-Match list containing the version",
+			method:      http.MethodPut,
+			body:        `{"title": "read the book"}`,
+			headers:     map[string]string{"If-Match": `"2", "3"`},
+			wantCode:    http.StatusOK,
+			wantETag:    `"4"`,
+			wantVersion: 3,
+		},
+		{
+			name:     "put with If-Match list of stale versions -> precondition failed",
+			method:   http.MethodPut,
+			body:     `{"title": "read the book"}`,
+			headers:  map[string]string{"If-Match": `"1", "2"`},
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "put with weak If-Match -> precondition failed",
+			method:   http.MethodPut,
+			body:     `{"title": "read the book"}`,
+			headers:  map[string]string{"If-Match": `W/"3"`},
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "put with malformed If-Match -> bad request",
+			method:   http.MethodPut,
+			body:     `{"title": "read the book"}`,
+			headers:  map[string]string{"If-Match": `3`},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:        "patch with matching If-Match",
+			method:      http.MethodPatch,
+			body:        `{"completed": true}`,
+			headers:     map[string]string{"If-Match": `"3"`},
+			wantCode:    http.StatusOK,
+			wantETag:    `"4"`,
+			wantVersion: 3,
+		},
+		{
+			name:        "patch with stale If-Match -> precondition failed",
+			method:      http.MethodPatch,
+			body:        `{"completed": true}`,
+			headers:     map[string]string{"If-Match": `"1"`},
+			wantCode:    http.StatusPreconditionFailed,
+			wantVersion: 1,
+		},
+		{
+			name:        "delete with matching If-Match",
+			method:      http.MethodDelete,
+			headers:     map[string]string{"If-Match": `"3"`},
+			wantCode:    http.StatusNoContent,
+			wantVersion: 3,
+		},
+		{
+			name:        "delete with stale If-Match -> precondition failed",
+			method:      http.MethodDelete,
+			headers:     map[string]string{"If-Match": `"2"`},
+			wantCode:    http.StatusPreconditionFailed,
+			wantVersion: 2,
+		},
+		{
+			name:        "delete with weak and strong If-Match",
+			method:      http.MethodDelete,
+			headers:     map[string]string{"If-Match": `W/"3", "3"`},
+			wantCode:    http.StatusNoContent,
+			wantVersion: 3,
+		},
+		{
+			name:     "delete with If-Match star",
+			method:   http.MethodDelete,
+			headers:  map[string]string{"If-Match": `*`},
+			wantCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			req := httptest.NewRequest(tc.method, "/api/todos/1", strings.NewReader(tc.body))
+			req.SetPathValue("id", "1")
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			var gotVersion int
+
+			useCaseMock := &UseCaseMock{
+				GetTodoByIDFunc: getTodo,
+				UpdateTodoByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+					gotVersion = todo.Version
+					return checkVersion(todo.Version)
+				},
+				PatchTodoByIDFunc: func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+					gotVersion = version
+					if err := checkVersion(version); err != nil {
+						return domain.Todo{}, err
+					}
+					todo := storedTodo
+					todo.Completed = true
+					todo.Version++
+					return todo, nil
+				},
+				DeleteTodoByIDFunc: func(ctx context.Context, id int, version int) error {
+					gotVersion = version
+					return checkVersion(version)
+				},
+			}
+
+			handlers := Handlers{
+				UseCase: useCaseMock,
+			}
+
+			// act
+			switch tc.method {
+			case http.MethodGet:
+				handlers.GetTodoHandler(rec, req)
+			case http.MethodPut:
+				handlers.UpdateTodoHandler(rec, req)
+			case http.MethodPatch:
+				handlers.PatchTodoHandler(rec, req)
+			case http.MethodDelete:
+				handlers.DeleteTodoHandler(rec, req)
+			}
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Errorf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if tc.wantETag != "" {
+				if got := rec.Header().Get("ETag"); got != tc.wantETag {
+					t.Errorf("unexpected ETag: got %q, want %q", got, tc.wantETag)
+				}
+			}
+
+			if tc.wantCode == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("not modified response must have no body: got %q", rec.Body.String())
+			}
+
+			if gotVersion != tc.wantVersion {
+				t.Errorf("unexpected version passed to usecase: got %d, want %d", gotVersion, tc.wantVersion)
+			}
+		})
+	}
+}
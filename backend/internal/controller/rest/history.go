@@ -0,0 +1,29 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// GetTodoHistoryHandler lists every change of the todo, oldest first, with
+// the time and the user who made it. Deleted todos keep their history.
+func (h *Handlers) GetTodoHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	changes, err := h.UseCase.GetTodoHistory(r.Context(), id)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get history of todo %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(changes); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestGetTodoHistoryHandler(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	changes := []domain.TodoChange{
+		{Type: domain.TodoChangeCreated, Todo: domain.Todo{ID: 7, Title: "read the book", Version: 1}, ActorID: 1, At: at},
+		{Type: domain.TodoChangeDeleted, Todo: domain.Todo{ID: 7, Title: "read the book", Version: 1}, ActorID: 2, At: at.Add(time.Hour)},
+	}
+
+	tests := []struct {
+		name string
+		url  string
+
+		usecaseErr error
+
+		wantCode    int
+		wantProblem string
+		wantCalls   int
+	}{
+		{
+			name:      "success",
+			url:       "/api/todos/7/history",
+			wantCode:  http.StatusOK,
+			wantCalls: 1,
+		},
+		{
+			name:        "missing todo -> not found",
+			url:         "/api/todos/7/history",
+			usecaseErr:  domain.ErrTodoNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemTodoNotFound,
+			wantCalls:   1,
+		},
+		{
+			name:        "history not kept -> not implemented",
+			url:         "/api/todos/7/history",
+			usecaseErr:  domain.ErrHistoryNotKept,
+			wantCode:    http.StatusNotImplemented,
+			wantProblem: problemHistoryNotKept,
+			wantCalls:   1,
+		},
+		{
+			name:        "malformed id -> error",
+			url:         "/api/todos/seven/history",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			wantCalls:   0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				GetTodoHistoryFunc: func(ctx context.Context, id int) ([]domain.TodoChange, error) {
+					if tc.usecaseErr != nil {
+						return nil, tc.usecaseErr
+					}
+					return changes, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if useCaseMock.GetTodoHistoryCalls != tc.wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", useCaseMock.GetTodoHistoryCalls, tc.wantCalls)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+
+			if useCaseMock.LastGetID != 7 {
+				t.Errorf("unexpected id: got %d, want %d", useCaseMock.LastGetID, 7)
+			}
+
+			var resp []map[string]any
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp) != 2 {
+				t.Fatalf("unexpected length: got %d, want %d", len(resp), 2)
+			}
+			if resp[1]["type"] != "deleted" || resp[1]["actor_id"] != 2.0 || resp[1]["at"] != "2024-05-01T13:00:00Z" {
+				t.Errorf("unexpected change: got %+v", resp[1])
+			}
+		})
+	}
+}
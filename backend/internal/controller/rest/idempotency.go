@@ -0,0 +1,177 @@
+package rest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/idempotency"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	replayedHeader       = "Idempotent-Replayed"
+
+	maxIdempotentBytes = 1 << 20
+)
+
+// IdempotencyMiddleware runs a POST, PUT, PATCH or DELETE request sent with
+// an Idempotency-Key header once per key of the user. A retry with the same
+// method, URL and body gets the stored response, marked with an
+// Idempotent-Replayed header; reusing the key for another request is a 422.
+// Once the handler ran the key stays claimed whatever the outcome, server
+// errors included, as the change may already be stored: a retry of a
+// request whose response was too large to keep, or whose handler
+// panicked, gets a 409 instead of running again. A nil store leaves every
+// request alone.
+func IdempotencyMiddleware(store *idempotency.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if store == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := r.Header.Get(idempotencyKeyHeader)
+			if name == "" || !changesState(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// the same rules as for X-Request-ID
+			if !validRequestID(name) {
+				writeProblem(w, r, invalidParam(errMalformedRequest, idempotencyKeyHeader, "must be at most 128 letters, digits, '-', '_' or '.'"))
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBytes))
+			if err != nil {
+				writeProblem(w, r, fmt.Errorf("%w: read body: %w", errMalformedRequest, err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			user, _ := domain.UserFromContext(r.Context())
+			key := idempotency.Key{Tenant: domain.CurrentTenant(r.Context()), UserID: user.ID, Key: name}
+
+			stored, err := store.Begin(key, fingerprint(r, body))
+			if err != nil {
+				writeProblem(w, r, fmt.Errorf("idempotency key %s: %w", name, err))
+				return
+			}
+			if stored != nil {
+				slog.Info("response replayed", "idempotency_key", name, "request_id", RequestIDFromContext(r.Context()))
+				replay(w, *stored)
+				return
+			}
+
+			rec := &recordingWriter{ResponseWriter: w, before: w.Header().Clone(), limit: store.MaxResponseBytes}
+			finished := false
+			defer func() {
+				// the handler panicked, it may have stored the change already
+				if !finished {
+					store.Finish(key, idempotency.Response{Status: http.StatusInternalServerError, Lost: true})
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			resp := rec.response()
+			if rec.overflow {
+				slog.Warn("response too large to keep", "idempotency_key", name, "request_id", RequestIDFromContext(r.Context()))
+				resp.Lost = true
+			}
+			store.Finish(key, resp)
+			finished = true
+		})
+	}
+}
+
+func changesState(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// fingerprint tells requests reusing a key apart from retries.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes a stored response. The headers already set for the retry,
+// such as its X-Request-ID, are kept.
+func replay(w http.ResponseWriter, resp idempotency.Response) {
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(replayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	if _, err := w.Write(resp.Body); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+}
+
+// recordingWriter passes a response through and keeps a copy of it. Only
+// the headers set by the handler are kept, the ones set before, e.g. by
+// RequestIDMiddleware, belong to the request. A body longer than limit is
+// passed through but no longer kept.
+type recordingWriter struct {
+	http.ResponseWriter
+	before      http.Header
+	limit       int
+	resp        idempotency.Response
+	wroteHeader bool
+	overflow    bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.resp.Status = status
+		w.resp.Header = http.Header{}
+		for name, values := range w.Header() {
+			if !slices.Equal(values, w.before[name]) {
+				w.resp.Header[name] = slices.Clone(values)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.overflow && len(w.resp.Body)+len(b) > w.limit {
+		w.overflow = true
+		w.resp.Body = nil
+	}
+	if !w.overflow {
+		w.resp.Body = append(w.resp.Body, b...)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// response returns the recorded response; a handler writing nothing sends
+// 200 OK.
+func (w *recordingWriter) response() idempotency.Response {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.resp
+}
@@ -0,0 +1,210 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/idempotency"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	type request struct {
+		key  string
+		body string
+	}
+
+	tests := []struct {
+		name     string
+		requests []request
+		// failFirst makes the first create fail with a server error
+		failFirst bool
+		// maxResponse caps the responses kept when not zero
+		maxResponse int
+
+		wantCodes    []int
+		wantReplayed []bool
+		wantProblem  string
+		wantCreates  int
+	}{
+		{
+			name:         "retry -> stored response",
+			requests:     []request{{key: "k1", body: `{"title":"milk"}`}, {key: "k1", body: `{"title":"milk"}`}},
+			wantCodes:    []int{http.StatusCreated, http.StatusCreated},
+			wantReplayed: []bool{false, true},
+			wantCreates:  1,
+		},
+		{
+			name:         "response too large to keep -> conflict",
+			requests:     []request{{key: "k1", body: `{"title":"milk"}`}, {key: "k1", body: `{"title":"milk"}`}},
+			maxResponse:  1,
+			wantCodes:    []int{http.StatusCreated, http.StatusConflict},
+			wantReplayed: []bool{false, false},
+			wantProblem:  problemResponseNotKept,
+			wantCreates:  1,
+		},
+		{
+			name:         "key reused with another body -> unprocessable",
+			requests:     []request{{key: "k1", body: `{"title":"milk"}`}, {key: "k1", body: `{"title":"bread"}`}},
+			wantCodes:    []int{http.StatusCreated, http.StatusUnprocessableEntity},
+			wantReplayed: []bool{false, false},
+			wantProblem:  problemIdempotencyKeyReused,
+			wantCreates:  1,
+		},
+		{
+			name:         "another key -> run again",
+			requests:     []request{{key: "k1", body: `{"title":"milk"}`}, {key: "k2", body: `{"title":"milk"}`}},
+			wantCodes:    []int{http.StatusCreated, http.StatusCreated},
+			wantReplayed: []bool{false, false},
+			wantCreates:  2,
+		},
+		{
+			name:         "no key -> run again",
+			requests:     []request{{body: `{"title":"milk"}`}, {body: `{"title":"milk"}`}},
+			wantCodes:    []int{http.StatusCreated, http.StatusCreated},
+			wantReplayed: []bool{false, false},
+			wantCreates:  2,
+		},
+		{
+			name:         "server error -> stored, the change may have been made",
+			requests:     []request{{key: "k1", body: `{"title":"milk"}`}, {key: "k1", body: `{"title":"milk"}`}},
+			failFirst:    true,
+			wantCodes:    []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			wantReplayed: []bool{false, true},
+			wantCreates:  1,
+		},
+		{
+			name:         "unsafe key -> bad request",
+			requests:     []request{{key: "k1\tk2", body: `{"title":"milk"}`}},
+			wantCodes:    []int{http.StatusBadRequest},
+			wantReplayed: []bool{false},
+			wantProblem:  problemMalformedRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				CreateTodoFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+					return 1, nil
+				},
+			}
+			if tc.failFirst {
+				useCaseMock.CreateTodoFunc = func(ctx context.Context, todo domain.Todo) (int, error) {
+					if useCaseMock.CreateTodoCalls == 1 {
+						return 0, errors.New("connection reset")
+					}
+					return 1, nil
+				}
+			}
+			auth := &AuthUseCaseMock{
+				AuthenticateFunc: func(ctx context.Context, token string) (domain.User, error) {
+					return testUser, nil
+				},
+			}
+			store := idempotency.NewStore(0)
+			if tc.maxResponse > 0 {
+				store.MaxResponseBytes = tc.maxResponse
+			}
+			router := NewRouter(useCaseMock, auth, TenantResolver{}, store, nil)
+
+			var last *httptest.ResponseRecorder
+			for i, r := range tc.requests {
+				req := httptest.NewRequest(http.MethodPost, "/api/todos", strings.NewReader(r.body))
+				req.Header.Set("Authorization", "Bearer "+testToken)
+				if r.key != "" {
+					req.Header.Set(idempotencyKeyHeader, r.key)
+				}
+				rec := httptest.NewRecorder()
+
+				// act
+				router.ServeHTTP(rec, req)
+
+				// assert
+				if rec.Code != tc.wantCodes[i] {
+					t.Fatalf("unexpected status code of request %d: got %d, want %d", i, rec.Code, tc.wantCodes[i])
+				}
+				if got := rec.Header().Get(replayedHeader) == "true"; got != tc.wantReplayed[i] {
+					t.Errorf("unexpected replay of request %d: got %v, want %v", i, got, tc.wantReplayed[i])
+				}
+				if tc.wantReplayed[i] {
+					if got, want := rec.Body.String(), last.Body.String(); got != want {
+						t.Errorf("unexpected replayed body: got %q, want %q", got, want)
+					}
+					if got, want := rec.Header().Get("Location"), last.Header().Get("Location"); got != want {
+						t.Errorf("unexpected replayed location: got %q, want %q", got, want)
+					}
+					if rec.Header().Get(requestIDHeader) == last.Header().Get(requestIDHeader) {
+						t.Errorf("replay must keep the request id of the retry")
+					}
+				}
+				last = rec
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, last, tc.wantCodes[len(tc.wantCodes)-1], tc.wantProblem)
+			}
+			if useCaseMock.CreateTodoCalls != tc.wantCreates {
+				t.Errorf("unexpected creates: got %d, want %d", useCaseMock.CreateTodoCalls, tc.wantCreates)
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddlewareOversizedBatch(t *testing.T) {
+	// preparing
+	var todos []domain.Todo
+	useCaseMock := &UseCaseMock{
+		RunBatchFunc: func(ctx context.Context, batch domain.Batch) ([]domain.BatchResult, error) {
+			results := make([]domain.BatchResult, 0, len(batch.Operations))
+			for _, op := range batch.Operations {
+				todo := *op.Todo
+				todo.ID = len(todos) + 1
+				todo.Version = 1
+				todos = append(todos, todo)
+				results = append(results, domain.BatchResult{ID: todo.ID, Todo: &todo})
+			}
+			return results, nil
+		},
+	}
+	auth := &AuthUseCaseMock{
+		AuthenticateFunc: func(ctx context.Context, token string) (domain.User, error) {
+			return testUser, nil
+		},
+	}
+	router := NewRouter(useCaseMock, auth, TenantResolver{}, idempotency.NewStore(0), nil)
+
+	// the response is larger than idempotency.DefaultMaxResponseBytes
+	ops := make([]string, 100)
+	for i := range ops {
+		ops[i] = `{"op":"create","todo":{"title":"` + strings.Repeat("a", 1000) + `"}}`
+	}
+	body := `{"operations":[` + strings.Join(ops, ",") + `]}`
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/todos:batch", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		req.Header.Set(idempotencyKeyHeader, "batch-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// act
+	first := send()
+	retry := send()
+
+	// assert
+	if first.Code != http.StatusOK || first.Body.Len() <= idempotency.DefaultMaxResponseBytes {
+		t.Fatalf("unexpected first response: got %d with %d bytes", first.Code, first.Body.Len())
+	}
+	assertProblem(t, retry, http.StatusConflict, problemResponseNotKept)
+	if len(todos) != len(ops) || useCaseMock.RunBatchCalls != 1 {
+		t.Errorf("unexpected todos: got %d after %d batches, want %d after 1", len(todos), useCaseMock.RunBatchCalls, len(ops))
+	}
+}
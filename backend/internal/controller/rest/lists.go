@@ -0,0 +1,264 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (h *Handlers) CreateListHandler(w http.ResponseWriter, r *http.Request) {
+	var list domain.List
+
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode list: %w", errMalformedRequest, err))
+		return
+	}
+
+	id, err := h.UseCase.CreateList(r.Context(), list)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("create list: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/lists/%d", id))
+
+	w.WriteHeader(http.StatusCreated)
+
+	resp := map[string]int{"id": id}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+
+	slog.Info("list created", "id", id)
+}
+
+// GetListsHandler responds with the lists the user is a member of.
+func (h *Handlers) GetListsHandler(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.UseCase.GetLists(r.Context())
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get lists: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(lists); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (h *Handlers) GetListHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	list, err := h.UseCase.GetListByID(r.Context(), id)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get list %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// RenameListHandler serves PATCH /api/lists/{id}; the name is the only
+// field of a list a client changes directly.
+func (h *Handlers) RenameListHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode list: %w", errMalformedRequest, err))
+		return
+	}
+
+	list, err := h.UseCase.RenameList(r.Context(), id, body.Name)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("rename list %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// DeleteListHandler deletes an empty list, todos are not deleted along.
+func (h *Handlers) DeleteListHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.UseCase.DeleteListByID(r.Context(), id); err != nil {
+		writeProblem(w, r, fmt.Errorf("delete list %d: %w", id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) InviteMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	var inv domain.Invitation
+	if err := json.NewDecoder(r.Body).Decode(&inv); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode invitation: %w", errMalformedRequest, err))
+		return
+	}
+
+	list, err := h.UseCase.InviteMember(r.Context(), id, inv)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("invite to list %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (h *Handlers) RevokeInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	list, err := h.UseCase.RevokeInvitation(r.Context(), id, r.PathValue("email"))
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("revoke invitation to list %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (h *Handlers) ChangeMemberRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	userID, err := pathInt(r, "userID")
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	var body struct {
+		Role domain.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode member: %w", errMalformedRequest, err))
+		return
+	}
+
+	list, err := h.UseCase.ChangeMemberRole(r.Context(), id, userID, body.Role)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("change role of %d in list %d: %w", userID, id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// RemoveMemberHandler removes a member; members remove themselves to
+// leave the list.
+func (h *Handlers) RemoveMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	userID, err := pathInt(r, "userID")
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.UseCase.RemoveMember(r.Context(), id, userID); err != nil {
+		writeProblem(w, r, fmt.Errorf("remove %d from list %d: %w", userID, id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetInvitationsHandler responds with the invitations sent to the user.
+func (h *Handlers) GetInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.UseCase.GetInvitations(r.Context())
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get invitations: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(invitations); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (h *Handlers) AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	listID, err := pathInt(r, "listID")
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	list, err := h.UseCase.AcceptInvitation(r.Context(), listID)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("accept invitation to list %d: %w", listID, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (h *Handlers) DeclineInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	listID, err := pathInt(r, "listID")
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.UseCase.DeclineInvitation(r.Context(), listID); err != nil {
+		writeProblem(w, r, fmt.Errorf("decline invitation to list %d: %w", listID, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
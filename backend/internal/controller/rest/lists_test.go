@@ -0,0 +1,317 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestListHandlers(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+
+		usecaseErr error
+
+		wantCode     int
+		wantProblem  string
+		wantLocation string
+
+		calls     func(m *UseCaseMock) int
+		wantCalls int
+	}{
+		{
+			name:         "create",
+			method:       http.MethodPost,
+			url:          "/api/lists",
+			body:         `{"name": "home"}`,
+			wantCode:     http.StatusCreated,
+			wantLocation: "/api/lists/7",
+			calls:        func(m *UseCaseMock) int { return m.CreateListCalls },
+			wantCalls:    1,
+		},
+		{
+			name:        "create malformed body -> error",
+			method:      http.MethodPost,
+			url:         "/api/lists",
+			body:        `{"name":`,
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *UseCaseMock) int { return m.CreateListCalls },
+		},
+		{
+			name:      "list",
+			method:    http.MethodGet,
+			url:       "/api/lists",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.GetListsCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "get list of others -> not found",
+			method:      http.MethodGet,
+			url:         "/api/lists/7",
+			usecaseErr:  domain.ErrListNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemListNotFound,
+			calls:       func(m *UseCaseMock) int { return m.GetListByIDCalls },
+			wantCalls:   1,
+		},
+		{
+			name:      "rename",
+			method:    http.MethodPatch,
+			url:       "/api/lists/7",
+			body:      `{"name": "home, shared"}`,
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.RenameListCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "rename as editor -> forbidden",
+			method:      http.MethodPatch,
+			url:         "/api/lists/7",
+			body:        `{"name": "mine"}`,
+			usecaseErr:  domain.ErrForbidden,
+			wantCode:    http.StatusForbidden,
+			wantProblem: problemForbidden,
+			calls:       func(m *UseCaseMock) int { return m.RenameListCalls },
+			wantCalls:   1,
+		},
+		{
+			name:        "delete with todos -> conflict",
+			method:      http.MethodDelete,
+			url:         "/api/lists/7",
+			usecaseErr:  domain.ErrListNotEmpty,
+			wantCode:    http.StatusConflict,
+			wantProblem: problemListNotEmpty,
+			calls:       func(m *UseCaseMock) int { return m.DeleteListByIDCalls },
+			wantCalls:   1,
+		},
+		{
+			name:      "invite",
+			method:    http.MethodPost,
+			url:       "/api/lists/7/invitations",
+			body:      `{"email": "bob@example.com", "role": "viewer"}`,
+			wantCode:  http.StatusCreated,
+			calls:     func(m *UseCaseMock) int { return m.InviteMemberCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "invite a member -> conflict",
+			method:      http.MethodPost,
+			url:         "/api/lists/7/invitations",
+			body:        `{"email": "bob@example.com", "role": "viewer"}`,
+			usecaseErr:  domain.ErrMemberExists,
+			wantCode:    http.StatusConflict,
+			wantProblem: problemMemberExists,
+			calls:       func(m *UseCaseMock) int { return m.InviteMemberCalls },
+			wantCalls:   1,
+		},
+		{
+			name:      "revoke invitation",
+			method:    http.MethodDelete,
+			url:       "/api/lists/7/invitations/bob@example.com",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.RevokeInvitationCalls },
+			wantCalls: 1,
+		},
+		{
+			name:      "change role",
+			method:    http.MethodPut,
+			url:       "/api/lists/7/members/2",
+			body:      `{"role": "editor"}`,
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.ChangeMemberRoleCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "change role malformed user id -> error",
+			method:      http.MethodPut,
+			url:         "/api/lists/7/members/bob",
+			body:        `{"role": "editor"}`,
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *UseCaseMock) int { return m.ChangeMemberRoleCalls },
+		},
+		{
+			name:        "remove missing member -> not found",
+			method:      http.MethodDelete,
+			url:         "/api/lists/7/members/2",
+			usecaseErr:  domain.ErrMemberNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemMemberNotFound,
+			calls:       func(m *UseCaseMock) int { return m.RemoveMemberCalls },
+			wantCalls:   1,
+		},
+		{
+			name:      "pending invitations",
+			method:    http.MethodGet,
+			url:       "/api/invitations",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.GetInvitationsCalls },
+			wantCalls: 1,
+		},
+		{
+			name:      "accept",
+			method:    http.MethodPost,
+			url:       "/api/invitations/7/accept",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.AcceptInvitationCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "accept without invitation -> not found",
+			method:      http.MethodPost,
+			url:         "/api/invitations/7/accept",
+			usecaseErr:  domain.ErrInvitationNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemInvitationNotFound,
+			calls:       func(m *UseCaseMock) int { return m.AcceptInvitationCalls },
+			wantCalls:   1,
+		},
+		{
+			name:      "decline",
+			method:    http.MethodDelete,
+			url:       "/api/invitations/7",
+			wantCode:  http.StatusNoContent,
+			calls:     func(m *UseCaseMock) int { return m.DeclineInvitationCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "failed to list -> error",
+			method:      http.MethodGet,
+			url:         "/api/lists",
+			usecaseErr:  errors.New("some error in usecase"),
+			wantCode:    http.StatusInternalServerError,
+			wantProblem: problemInternal,
+			calls:       func(m *UseCaseMock) int { return m.GetListsCalls },
+			wantCalls:   1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			list := domain.List{ID: 7, Name: "home", Version: 2, Members: []domain.Member{
+				{UserID: testUser.ID, Email: testUser.Email, Role: domain.RoleOwner},
+			}}
+			useCaseMock := &UseCaseMock{
+				CreateListFunc: func(ctx context.Context, l domain.List) (int, error) {
+					return 7, tc.usecaseErr
+				},
+				GetListsFunc: func(ctx context.Context) ([]domain.List, error) {
+					return []domain.List{list}, tc.usecaseErr
+				},
+				GetListByIDFunc: func(ctx context.Context, id int) (domain.List, error) {
+					return list, tc.usecaseErr
+				},
+				RenameListFunc: func(ctx context.Context, id int, name string) (domain.List, error) {
+					return list, tc.usecaseErr
+				},
+				DeleteListByIDFunc: func(ctx context.Context, id int) error {
+					return tc.usecaseErr
+				},
+				InviteMemberFunc: func(ctx context.Context, listID int, inv domain.Invitation) (domain.List, error) {
+					return list, tc.usecaseErr
+				},
+				RevokeInvitationFunc: func(ctx context.Context, listID int, email string) (domain.List, error) {
+					return list, tc.usecaseErr
+				},
+				GetInvitationsFunc: func(ctx context.Context) ([]domain.PendingInvitation, error) {
+					return []domain.PendingInvitation{{ListID: 7, ListName: "home"}}, tc.usecaseErr
+				},
+				AcceptInvitationFunc: func(ctx context.Context, listID int) (domain.List, error) {
+					return list, tc.usecaseErr
+				},
+				DeclineInvitationFunc: func(ctx context.Context, listID int) error {
+					return tc.usecaseErr
+				},
+				ChangeMemberRoleFunc: func(ctx context.Context, listID int, userID int, role domain.Role) (domain.List, error) {
+					return list, tc.usecaseErr
+				},
+				RemoveMemberFunc: func(ctx context.Context, listID int, userID int) error {
+					return tc.usecaseErr
+				},
+			}
+
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Errorf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+			}
+
+			if got := tc.calls(useCaseMock); got != tc.wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", got, tc.wantCalls)
+			}
+
+			if got := rec.Header().Get("Location"); got != tc.wantLocation {
+				t.Errorf("unexpected Location: got %q, want %q", got, tc.wantLocation)
+			}
+		})
+	}
+
+	t.Run("passes list, member and role", func(t *testing.T) {
+		// preparing
+		useCaseMock := &UseCaseMock{
+			ChangeMemberRoleFunc: func(ctx context.Context, listID int, userID int, role domain.Role) (domain.List, error) {
+				return domain.List{ID: listID, Members: []domain.Member{{UserID: userID, Role: role}}}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/api/lists/7/members/3", strings.NewReader(`{"role": "viewer"}`))
+		rec := httptest.NewRecorder()
+
+		// act
+		newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+		// assert
+		if useCaseMock.LastListID != 7 || useCaseMock.LastMemberID != 3 || useCaseMock.LastRole != domain.RoleViewer {
+			t.Fatalf("unexpected arguments: list %d member %d role %q", useCaseMock.LastListID, useCaseMock.LastMemberID, useCaseMock.LastRole)
+		}
+
+		var resp domain.List
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if role, _ := resp.RoleOf(3); role != domain.RoleViewer {
+			t.Errorf("unexpected role: got %q, want %q", role, domain.RoleViewer)
+		}
+	})
+
+	t.Run("revoke passes the email", func(t *testing.T) {
+		// preparing
+		useCaseMock := &UseCaseMock{
+			RevokeInvitationFunc: func(ctx context.Context, listID int, email string) (domain.List, error) {
+				return domain.List{ID: listID}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/lists/7/invitations/bob%2Bwork@example.com", nil)
+		rec := httptest.NewRecorder()
+
+		// act
+		newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+		// assert
+		if got := useCaseMock.LastInvitation.Email; got != "bob+work@example.com" {
+			t.Errorf("unexpected email: got %q, want %q", got, "bob+work@example.com")
+		}
+	})
+}
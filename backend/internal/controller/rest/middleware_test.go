@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+
+		wantKept bool
+	}{
+		{name: "client id is kept", header: "3f2a-b_c.d", wantKept: true},
+		{name: "missing id -> generated", header: ""},
+		{name: "unsafe id -> generated", header: "id\nwith newline"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			var fromContext string
+			handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fromContext = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/todos", nil)
+			if tc.header != "" {
+				req.Header.Set(requestIDHeader, tc.header)
+			}
+			rec := httptest.NewRecorder()
+
+			// act
+			handler.ServeHTTP(rec, req)
+
+			// assert
+			got := rec.Header().Get(requestIDHeader)
+			if got == "" {
+				t.Fatalf("request id must be set")
+			}
+			if got != fromContext {
+				t.Errorf("context and header differ: got %q, want %q", fromContext, got)
+			}
+			if tc.wantKept && got != tc.header {
+				t.Errorf("unexpected request id: got %q, want %q", got, tc.header)
+			}
+			if !tc.wantKept && got == tc.header {
+				t.Errorf("request id must be replaced: got %q", got)
+			}
+		})
+	}
+}
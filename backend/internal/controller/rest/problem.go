@@ -0,0 +1,388 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/websocket"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem types are stable identifiers clients can switch on, the titles
+// and details are for humans and may change.
+const (
+	problemMalformedRequest     = "/problems/malformed-request"
+	problemValidationFailed     = "/problems/validation-failed"
+	problemInvalidPatch         = "/problems/invalid-patch"
+	problemInvalidQuery         = "/problems/invalid-query"
+	problemHistoryNotKept       = "/problems/history-not-kept"
+	problemTodoNotFound         = "/problems/todo-not-found"
+	problemTagNotFound          = "/problems/tag-not-found"
+	problemTagExists            = "/problems/tag-exists"
+	problemOpenSubtasks         = "/problems/open-subtasks"
+	problemNothingToUndo        = "/problems/nothing-to-undo"
+	problemUndoConflict         = "/problems/undo-conflict"
+	problemBatchAborted         = "/problems/batch-aborted"
+	problemNoTransactions       = "/problems/transactions-not-supported"
+	problemIdempotencyKeyInUse  = "/problems/idempotency-key-in-use"
+	problemIdempotencyKeyReused = "/problems/idempotency-key-reused"
+	problemResponseNotKept      = "/problems/response-not-kept"
+	problemUnauthenticated      = "/problems/unauthenticated"
+	problemInvalidCredentials   = "/problems/invalid-credentials"
+	problemUserExists           = "/problems/user-exists"
+	problemInsufficientScope    = "/problems/insufficient-scope"
+	problemAPIKeyNotFound       = "/problems/api-key-not-found"
+	problemForbidden            = "/problems/forbidden"
+	problemOriginNotAllowed     = "/problems/origin-not-allowed"
+	problemListNotFound         = "/problems/list-not-found"
+	problemListNotEmpty         = "/problems/list-not-empty"
+	problemListFull             = "/problems/list-full"
+	problemMemberNotFound       = "/problems/member-not-found"
+	problemMemberExists         = "/problems/member-exists"
+	problemInvitationNotFound   = "/problems/invitation-not-found"
+	problemTenantNotFound       = "/problems/tenant-not-found"
+	problemWebhookNotFound      = "/problems/webhook-not-found"
+	problemDeliveryNotFound     = "/problems/delivery-not-found"
+	problemPreconditionFailed   = "/problems/precondition-failed"
+	problemUnsupportedMediaType = "/problems/unsupported-media-type"
+	problemInternal             = "/problems/internal-error"
+)
+
+// errors detected by the handlers themselves, before the usecase is called
+var (
+	errMalformedRequest     = errors.New("malformed request")
+	errUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// InvalidParam names the part of the request a problem is about: a body
+// field, a path or query parameter or a header.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// paramError ties an error to the parameter that caused it.
+type paramError struct {
+	Name   string
+	Reason string
+	Err    error
+}
+
+func (e *paramError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *paramError) Unwrap() error {
+	return e.Err
+}
+
+func invalidParam(err error, name, reason string) error {
+	return &paramError{Name: name, Reason: reason, Err: err}
+}
+
+// problemFor maps an error returned by a handler step to the problem sent
+// to the client. Unknown errors become an internal error without details.
+func problemFor(err error) Problem {
+	var p Problem
+
+	switch {
+	case errors.Is(err, domain.ErrValidation), errors.Is(err, domain.ErrNoTitle):
+		p = Problem{
+			Type:          problemValidationFailed,
+			Title:         "Validation failed",
+			Status:        http.StatusBadRequest,
+			Detail:        "The request body is not valid.",
+			InvalidParams: validationParams(err),
+		}
+	case errors.Is(err, domain.ErrInvalidPatch):
+		p = Problem{
+			Type:   problemInvalidPatch,
+			Title:  "Invalid patch",
+			Status: http.StatusBadRequest,
+			Detail: "The body is not a JSON merge patch applicable to a todo.",
+		}
+	case errors.Is(err, domain.ErrInvalidQuery):
+		p = Problem{
+			Type:   problemInvalidQuery,
+			Title:  "Invalid query",
+			Status: http.StatusBadRequest,
+			Detail: "The list parameters are not valid.",
+		}
+	case errors.Is(err, domain.ErrHistoryNotKept):
+		p = Problem{
+			Type:   problemHistoryNotKept,
+			Title:  "History not kept",
+			Status: http.StatusNotImplemented,
+			Detail: "The server does not keep the past of the todos.",
+		}
+	case errors.Is(err, domain.ErrNoTransactions):
+		p = Problem{
+			Type:   problemNoTransactions,
+			Title:  "Transactions not supported",
+			Status: http.StatusNotImplemented,
+			Detail: "The storage cannot run an atomic batch, send it without atomic.",
+		}
+	case errors.Is(err, domain.ErrTodoNotExist):
+		p = Problem{
+			Type:   problemTodoNotFound,
+			Title:  "Todo not found",
+			Status: http.StatusNotFound,
+		}
+	case errors.Is(err, domain.ErrTagNotExist):
+		p = Problem{
+			Type:   problemTagNotFound,
+			Title:  "Tag not found",
+			Status: http.StatusNotFound,
+		}
+	case errors.Is(err, domain.ErrTagExists):
+		p = Problem{
+			Type:          problemTagExists,
+			Title:         "Tag already exists",
+			Status:        http.StatusConflict,
+			InvalidParams: []InvalidParam{{Name: "name", Reason: domain.ErrTagExists.Error()}},
+		}
+	case errors.Is(err, domain.ErrOpenSubtasks):
+		p = Problem{
+			Type:   problemOpenSubtasks,
+			Title:  "Todo has open subtasks",
+			Status: http.StatusConflict,
+			Detail: "Complete the subtasks of the todo first.",
+		}
+	case errors.Is(err, domain.ErrNothingToUndo), errors.Is(err, domain.ErrNothingToRedo):
+		p = Problem{
+			Type:   problemNothingToUndo,
+			Title:  "Nothing to undo",
+			Status: http.StatusConflict,
+			Detail: "There is no change of yours left to undo or redo.",
+		}
+	case errors.Is(err, domain.ErrBatchAborted):
+		p = Problem{
+			Type:   problemBatchAborted,
+			Title:  "Batch aborted",
+			Status: http.StatusFailedDependency,
+			Detail: "Another operation of the atomic batch failed, nothing was stored.",
+		}
+	case errors.Is(err, domain.ErrIdempotencyKeyInUse):
+		p = Problem{
+			Type:   problemIdempotencyKeyInUse,
+			Title:  "Request in progress",
+			Status: http.StatusConflict,
+			Detail: "A request with this Idempotency-Key is still running, retry later.",
+		}
+	case errors.Is(err, domain.ErrIdempotencyKeyReused):
+		p = Problem{
+			Type:   problemIdempotencyKeyReused,
+			Title:  "Idempotency key reused",
+			Status: http.StatusUnprocessableEntity,
+			Detail: "The Idempotency-Key was sent with another request, use a new key.",
+		}
+	case errors.Is(err, domain.ErrIdempotentResponseLost):
+		p = Problem{
+			Type:   problemResponseNotKept,
+			Title:  "Response not kept",
+			Status: http.StatusConflict,
+			Detail: "The request with this Idempotency-Key already ran, but its response cannot be replayed.",
+		}
+	case errors.Is(err, domain.ErrUndoConflict):
+		p = Problem{
+			Type:   problemUndoConflict,
+			Title:  "Change cannot be undone",
+			Status: http.StatusConflict,
+			Detail: "A todo of the change was changed since.",
+		}
+	case errors.Is(err, domain.ErrUnauthenticated):
+		p = Problem{
+			Type:   problemUnauthenticated,
+			Title:  "Authentication required",
+			Status: http.StatusUnauthorized,
+			Detail: "Send a valid access token in the Authorization header.",
+		}
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		p = Problem{
+			Type:   problemInvalidCredentials,
+			Title:  "Invalid credentials",
+			Status: http.StatusUnauthorized,
+			Detail: "The email or the password is wrong.",
+		}
+	case errors.Is(err, domain.ErrUserExists):
+		p = Problem{
+			Type:          problemUserExists,
+			Title:         "User already exists",
+			Status:        http.StatusConflict,
+			InvalidParams: []InvalidParam{{Name: "email", Reason: domain.ErrUserExists.Error()}},
+		}
+	case errors.Is(err, domain.ErrInsufficientScope):
+		p = Problem{
+			Type:   problemInsufficientScope,
+			Title:  "Insufficient scope",
+			Status: http.StatusForbidden,
+			Detail: "The API key is not allowed to do this.",
+		}
+	case errors.Is(err, domain.ErrAPIKeyNotExist):
+		p = Problem{
+			Type:   problemAPIKeyNotFound,
+			Title:  "API key not found",
+			Status: http.StatusNotFound,
+		}
+	case errors.Is(err, domain.ErrForbidden):
+		p = Problem{
+			Type:   problemForbidden,
+			Title:  "Forbidden",
+			Status: http.StatusForbidden,
+			Detail: "Your role in the list does not allow this.",
+		}
+	case errors.Is(err, websocket.ErrBadOrigin):
+		p = Problem{
+			Type:   problemOriginNotAllowed,
+			Title:  "Origin not allowed",
+			Status: http.StatusForbidden,
+			Detail: "The WebSocket cannot be opened from this site.",
+		}
+	case errors.Is(err, domain.ErrListNotExist):
+		p = Problem{
+			Type:   problemListNotFound,
+			Title:  "List not found",
+			Status: http.StatusNotFound,
+		}
+	case errors.Is(err, domain.ErrListNotEmpty):
+		p = Problem{
+			Type:   problemListNotEmpty,
+			Title:  "List is not empty",
+			Status: http.StatusConflict,
+			Detail: "Delete the todos of the list, those in the trash included, or move them out first.",
+		}
+	case errors.Is(err, domain.ErrTooManyMembers):
+		p = Problem{
+			Type:   problemListFull,
+			Title:  "List is full",
+			Status: http.StatusConflict,
+			Detail: fmt.Sprintf("A list has at most %d members and invitations.", domain.MaxListMembers),
+		}
+	case errors.Is(err, domain.ErrMemberNotExist):
+		p = Problem{
+			Type:   problemMemberNotFound,
+			Title:  "Member not found",
+			Status: http.StatusNotFound,
+		}
+	case errors.Is(err, domain.ErrMemberExists):
+		p = Problem{
+			Type:          problemMemberExists,
+			Title:         "Already a member or invited",
+			Status:        http.StatusConflict,
+			InvalidParams: []InvalidParam{{Name: "email", Reason: domain.ErrMemberExists.Error()}},
+		}
+	case errors.Is(err, domain.ErrInvitationNotExist):
+		p = Problem{
+			Type:   problemInvitationNotFound,
+			Title:  "Invitation not found",
+			Status: http.StatusNotFound,
+		}
+	case errors.Is(err, domain.ErrTenantNotExist):
+		p = Problem{
+			Type:   problemTenantNotFound,
+			Title:  "Workspace not found",
+			Status: http.StatusNotFound,
+			Detail: "The workspace named by the request does not exist.",
+		}
+	case errors.Is(err, domain.ErrWebhookNotExist):
+		p = Problem{
+			Type:   problemWebhookNotFound,
+			Title:  "Webhook not found",
+			Status: http.StatusNotFound,
+		}
+	case errors.Is(err, domain.ErrDeliveryNotExist):
+		p = Problem{
+			Type:   problemDeliveryNotFound,
+			Title:  "Delivery not found",
+			Status: http.StatusNotFound,
+			Detail: "Only dead letters still in the log can be redelivered.",
+		}
+	case errors.Is(err, domain.ErrVersionMismatch):
+		p = Problem{
+			Type:   problemPreconditionFailed,
+			Title:  "Precondition failed",
+			Status: http.StatusPreconditionFailed,
+			Detail: "The todo was modified since the given version.",
+		}
+	case errors.Is(err, errUnsupportedMediaType):
+		p = Problem{
+			Type:   problemUnsupportedMediaType,
+			Title:  "Unsupported media type",
+			Status: http.StatusUnsupportedMediaType,
+		}
+	case errors.Is(err, errMalformedRequest):
+		p = Problem{
+			Type:   problemMalformedRequest,
+			Title:  "Malformed request",
+			Status: http.StatusBadRequest,
+		}
+	default:
+		return Problem{
+			Type:   problemInternal,
+			Title:  "Internal server error",
+			Status: http.StatusInternalServerError,
+		}
+	}
+
+	var pe *paramError
+	if errors.As(err, &pe) {
+		p.InvalidParams = append(p.InvalidParams, InvalidParam{Name: pe.Name, Reason: pe.Reason})
+	}
+
+	return p
+}
+
+// validationParams lists every invalid field of the entity.
+func validationParams(err error) []InvalidParam {
+	var verrs domain.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []InvalidParam{{Name: "title", Reason: domain.ErrNoTitle.Error()}}
+	}
+
+	params := make([]InvalidParam, 0, len(verrs))
+	for _, e := range verrs {
+		params = append(params, InvalidParam{Name: e.Field, Reason: e.Reason()})
+	}
+	return params
+}
+
+// writeProblem logs the error and renders it as application/problem+json.
+func writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := problemFor(err)
+	p.Instance = r.URL.Path
+	p.RequestID = RequestIDFromContext(r.Context())
+
+	if p.Status >= http.StatusInternalServerError {
+		slog.Error("request failed", "error", err, "method", r.Method, "path", r.URL.Path, "request_id", p.RequestID)
+	} else {
+		slog.Warn("request rejected", "error", err, "type", p.Type, "method", r.Method, "path", r.URL.Path, "request_id", p.RequestID)
+	}
+
+	if p.Type == problemUnauthenticated {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		slog.Error("failed to encode problem", "error", err)
+	}
+}
@@ -0,0 +1,283 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// assertProblem checks that the response is a problem of the given type.
+func assertProblem(t *testing.T, rec *httptest.ResponseRecorder, wantStatus int, wantType string) Problem {
+	t.Helper()
+
+	if got := rec.Header().Get("Content-Type"); got != problemContentType {
+		t.Errorf("unexpected Content-Type: got %q, want %q", got, problemContentType)
+	}
+
+	var p Problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("decode problem: %v, body=%q", err, rec.Body.String())
+	}
+
+	if p.Type != wantType {
+		t.Errorf("unexpected problem type: got %q, want %q", p.Type, wantType)
+	}
+	if p.Status != wantStatus {
+		t.Errorf("unexpected problem status: got %d, want %d", p.Status, wantStatus)
+	}
+
+	return p
+}
+
+func TestProblemFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+
+		wantType          string
+		wantStatus        int
+		wantInvalidParams []InvalidParam
+	}{
+		{
+			name: "validation",
+			err: fmt.Errorf("create todo: %w", domain.ValidationErrors{
+				{Field: "title", Err: domain.ErrTooLong, Limit: domain.MaxTitleLength},
+				{Field: "description", Err: domain.ErrControlCharacter},
+			}),
+			wantType:   problemValidationFailed,
+			wantStatus: http.StatusBadRequest,
+			wantInvalidParams: []InvalidParam{
+				{Name: "title", Reason: "is too long (max 200 characters)"},
+				{Name: "description", Reason: "must not contain control characters"},
+			},
+		},
+		{
+			name:       "missing title",
+			err:        fmt.Errorf("create todo: %w", domain.ErrNoTitle),
+			wantType:   problemValidationFailed,
+			wantStatus: http.StatusBadRequest,
+			wantInvalidParams: []InvalidParam{
+				{Name: "title", Reason: "title is empty"},
+			},
+		},
+		{
+			name:       "invalid patch",
+			err:        fmt.Errorf("patch todo 1: %w", domain.ErrInvalidPatch),
+			wantType:   problemInvalidPatch,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "history not kept",
+			err:        fmt.Errorf("query todos: %w", domain.ErrHistoryNotKept),
+			wantType:   problemHistoryNotKept,
+			wantStatus: http.StatusNotImplemented,
+		},
+		{
+			name:       "not found",
+			err:        fmt.Errorf("get todo 1: %w", domain.ErrTodoNotExist),
+			wantType:   problemTodoNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "tag not found",
+			err:        fmt.Errorf("attach tag 2 on todo 1: %w", domain.ErrTagNotExist),
+			wantType:   problemTagNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "tag exists",
+			err:        fmt.Errorf("create tag: %w", domain.ErrTagExists),
+			wantType:   problemTagExists,
+			wantStatus: http.StatusConflict,
+			wantInvalidParams: []InvalidParam{
+				{Name: "name", Reason: "tag with this name already exists"},
+			},
+		},
+		{
+			name:       "open subtasks",
+			err:        fmt.Errorf("patch todo 1: complete todo 1: %w", domain.ErrOpenSubtasks),
+			wantType:   problemOpenSubtasks,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "unauthenticated",
+			err:        fmt.Errorf("authenticate: %w: token expired", domain.ErrUnauthenticated),
+			wantType:   problemUnauthenticated,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid credentials",
+			err:        fmt.Errorf("login: %w", domain.ErrInvalidCredentials),
+			wantType:   problemInvalidCredentials,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "insufficient scope",
+			err:        fmt.Errorf("%w: todos:write", domain.ErrInsufficientScope),
+			wantType:   problemInsufficientScope,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "api key not found",
+			err:        fmt.Errorf("revoke api key 7: delete api key: %w", domain.ErrAPIKeyNotExist),
+			wantType:   problemAPIKeyNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "forbidden",
+			err:        fmt.Errorf("delete todo 3: %w: viewer cannot change todos", domain.ErrForbidden),
+			wantType:   problemForbidden,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "list not found",
+			err:        fmt.Errorf("get list 7: %w", domain.ErrListNotExist),
+			wantType:   problemListNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "list not empty",
+			err:        fmt.Errorf("delete list 7: %w", domain.ErrListNotEmpty),
+			wantType:   problemListNotEmpty,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "list full",
+			err:        fmt.Errorf("invite to list 7: %w", domain.ErrTooManyMembers),
+			wantType:   problemListFull,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "member not found",
+			err:        fmt.Errorf("remove 3 from list 7: %w", domain.ErrMemberNotExist),
+			wantType:   problemMemberNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:              "member exists",
+			err:               fmt.Errorf("invite to list 7: %w", domain.ErrMemberExists),
+			wantType:          problemMemberExists,
+			wantStatus:        http.StatusConflict,
+			wantInvalidParams: []InvalidParam{{Name: "email", Reason: domain.ErrMemberExists.Error()}},
+		},
+		{
+			name:       "invitation not found",
+			err:        fmt.Errorf("accept invitation to list 7: %w", domain.ErrInvitationNotExist),
+			wantType:   problemInvitationNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "tenant not found",
+			err:        fmt.Errorf("resolve workspace: %w: initech", domain.ErrTenantNotExist),
+			wantType:   problemTenantNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "user exists",
+			err:        fmt.Errorf("register: save user in db: %w", domain.ErrUserExists),
+			wantType:   problemUserExists,
+			wantStatus: http.StatusConflict,
+			wantInvalidParams: []InvalidParam{
+				{Name: "email", Reason: "user with this email already exists"},
+			},
+		},
+		{
+			name:       "version mismatch",
+			err:        fmt.Errorf("update todo 1: %w", domain.ErrVersionMismatch),
+			wantType:   problemPreconditionFailed,
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "malformed parameter",
+			err:        invalidParam(fmt.Errorf("%w: bad id", errMalformedRequest), "id", "must be an integer"),
+			wantType:   problemMalformedRequest,
+			wantStatus: http.StatusBadRequest,
+			wantInvalidParams: []InvalidParam{
+				{Name: "id", Reason: "must be an integer"},
+			},
+		},
+		{
+			name:       "invalid query parameter",
+			err:        invalidParam(domain.ErrInvalidQuery, "limit", "must be a positive integer"),
+			wantType:   problemInvalidQuery,
+			wantStatus: http.StatusBadRequest,
+			wantInvalidParams: []InvalidParam{
+				{Name: "limit", Reason: "must be a positive integer"},
+			},
+		},
+		{
+			name:       "unknown error",
+			err:        errors.New("connection refused"),
+			wantType:   problemInternal,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			p := problemFor(tc.err)
+
+			// assert
+			if p.Type != tc.wantType {
+				t.Errorf("unexpected type: got %q, want %q", p.Type, tc.wantType)
+			}
+			if p.Status != tc.wantStatus {
+				t.Errorf("unexpected status: got %d, want %d", p.Status, tc.wantStatus)
+			}
+			if !reflect.DeepEqual(p.InvalidParams, tc.wantInvalidParams) {
+				t.Errorf("unexpected invalid params: got %+v, want %+v", p.InvalidParams, tc.wantInvalidParams)
+			}
+		})
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	t.Run("internal errors are not leaked", func(t *testing.T) {
+		// preparing
+		req := httptest.NewRequest(http.MethodGet, "/api/todos/1", nil)
+		rec := httptest.NewRecorder()
+
+		// act
+		writeProblem(rec, req, errors.New("pq: password authentication failed"))
+
+		// assert
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("unexpected status code: got %d, want %d", rec.Code, http.StatusInternalServerError)
+		}
+
+		p := assertProblem(t, rec, http.StatusInternalServerError, problemInternal)
+		if p.Detail != "" {
+			t.Errorf("detail must be empty: got %q", p.Detail)
+		}
+		if p.Instance != "/api/todos/1" {
+			t.Errorf("unexpected instance: got %q, want %q", p.Instance, "/api/todos/1")
+		}
+	})
+
+	t.Run("request id from middleware", func(t *testing.T) {
+		// preparing
+		handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeProblem(w, r, domain.ErrTodoNotExist)
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/todos/1", nil)
+		req.Header.Set("X-Request-ID", "req-42")
+		rec := httptest.NewRecorder()
+
+		// act
+		handler.ServeHTTP(rec, req)
+
+		// assert
+		p := assertProblem(t, rec, http.StatusNotFound, problemTodoNotFound)
+		if p.RequestID != "req-42" {
+			t.Errorf("unexpected request id: got %q, want %q", p.RequestID, "req-42")
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// parseTodoQuery reads the list parameters of GET /api/todos. Unknown
+// parameters are ignored, malformed known ones are rejected.
+func parseTodoQuery(values url.Values) (domain.TodoQuery, string, error) {
+	var q domain.TodoQuery
+
+	if s := values.Get("completed"); s != "" {
+		completed, err := strconv.ParseBool(s)
+		if err != nil {
+			return domain.TodoQuery{}, "", invalidParam(fmt.Errorf("%w: %w", domain.ErrInvalidQuery, err), "completed", "must be true or false")
+		}
+		q.Filter.Completed = &completed
+	}
+
+	q.Filter.Title = values.Get("title")
+	q.Filter.Description = values.Get("description")
+	q.Filter.Search = values.Get("q")
+
+	if s := values.Get("priority"); s != "" {
+		priority, err := domain.ParsePriority(s)
+		if err != nil {
+			return domain.TodoQuery{}, "", invalidParam(fmt.Errorf("%w: %w", domain.ErrInvalidQuery, err), "priority", "must be none, low, medium or high")
+		}
+		q.Filter.Priority = &priority
+	}
+
+	for _, name := range values["tag"] {
+		if name != "" {
+			q.Filter.TagNames = append(q.Filter.TagNames, name)
+		}
+	}
+
+	switch mode := values.Get("tag_mode"); mode {
+	case "", "any":
+	case "all":
+		q.Filter.AllTags = true
+	default:
+		return domain.TodoQuery{}, "", invalidParam(fmt.Errorf("%w: unknown tag mode %q", domain.ErrInvalidQuery, mode), "tag_mode", "must be any or all")
+	}
+
+	if s := values.Get("list_id"); s != "" {
+		listID, err := strconv.Atoi(s)
+		if err != nil {
+			return domain.TodoQuery{}, "", invalidParam(fmt.Errorf("%w: %w", domain.ErrInvalidQuery, err), "list_id", "must be an integer")
+		}
+		q.Filter.ListID = &listID
+	}
+
+	q.Sort = domain.TodoSort(values.Get("sort"))
+
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return domain.TodoQuery{}, "", invalidParam(fmt.Errorf("%w: unknown order %q", domain.ErrInvalidQuery, order), "order", "must be asc or desc")
+	}
+
+	if s := values.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			return domain.TodoQuery{}, "", invalidParam(fmt.Errorf("%w: invalid limit %q", domain.ErrInvalidQuery, s), "limit", "must be a positive integer")
+		}
+		q.Limit = limit
+	}
+
+	if s := values.Get("as_of"); s != "" {
+		asOf, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return domain.TodoQuery{}, "", invalidParam(fmt.Errorf("%w: %w", domain.ErrInvalidQuery, err), "as_of", "must be an RFC 3339 timestamp")
+		}
+		q.AsOf = asOf
+	}
+
+	return q, values.Get("cursor"), nil
+}
+
+// parseLocation reads the tz parameter, an IANA zone name. UTC is the
+// default.
+func parseLocation(values url.Values) (*time.Location, error) {
+	name := values.Get("tz")
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, invalidParam(fmt.Errorf("%w: %w", domain.ErrInvalidQuery, err), "tz", "must be an IANA time zone name")
+	}
+	return loc, nil
+}
+
+// nextPageURL is the request URL with the cursor replaced.
+func nextPageURL(u *url.URL, cursor string) string {
+	values := u.Query()
+	values.Set("cursor", cursor)
+
+	next := url.URL{Path: u.Path, RawQuery: values.Encode()}
+	return next.String()
+}
@@ -0,0 +1,41 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// GetOccurrencesHandler previews the due dates that follow the current one
+// of a recurring todo.
+func (h *Handlers) GetOccurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	count := defaultOccurrencesPreview
+	if s := r.URL.Query().Get("count"); s != "" {
+		count, err = strconv.Atoi(s)
+		if err != nil {
+			writeProblem(w, r, invalidParam(fmt.Errorf("%w: %w", domain.ErrInvalidQuery, err), "count", "must be an integer"))
+			return
+		}
+	}
+
+	occurrences, err := h.UseCase.PreviewOccurrences(r.Context(), id, count)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("preview occurrences of todo %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(occurrences); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
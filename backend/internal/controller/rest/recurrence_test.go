@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestGetOccurrencesHandler(t *testing.T) {
+	due := time.Date(2030, time.March, 31, 9, 0, 0, 0, time.FixedZone("", 2*60*60))
+
+	tests := []struct {
+		name string
+		url  string
+
+		usecaseErr error
+
+		wantCode    int
+		wantProblem string
+		wantCount   int
+		wantCalls   int
+	}{
+		{name: "default count", url: "/api/todos/1/occurrences", wantCode: http.StatusOK, wantCount: defaultOccurrencesPreview, wantCalls: 1},
+		{name: "count", url: "/api/todos/1/occurrences?count=2", wantCode: http.StatusOK, wantCount: 2, wantCalls: 1},
+		{
+			name:        "malformed count -> error",
+			url:         "/api/todos/1/occurrences?count=two",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+		},
+		{
+			name:        "count out of range -> error",
+			url:         "/api/todos/1/occurrences?count=1000",
+			usecaseErr:  fmt.Errorf("%w: count must be between 1 and 100", domain.ErrInvalidQuery),
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+			wantCount:   1000,
+			wantCalls:   1,
+		},
+		{
+			name:        "missing todo -> not found",
+			url:         "/api/todos/1/occurrences",
+			usecaseErr:  domain.ErrTodoNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemTodoNotFound,
+			wantCount:   defaultOccurrencesPreview,
+			wantCalls:   1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				PreviewOccurrencesFunc: func(ctx context.Context, id int, n int) ([]time.Time, error) {
+					return []time.Time{due}, tc.usecaseErr
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+			if useCaseMock.PreviewOccurrencesCalls != tc.wantCalls {
+				t.Fatalf("unexpected calls: got %d, want %d", useCaseMock.PreviewOccurrencesCalls, tc.wantCalls)
+			}
+			if tc.wantCalls > 0 && useCaseMock.LastCount != tc.wantCount {
+				t.Errorf("unexpected count: got %d, want %d", useCaseMock.LastCount, tc.wantCount)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+
+			var resp []string
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if want := "2030-03-31T09:00:00+02:00"; len(resp) != 1 || resp[0] != want {
+				t.Errorf("unexpected occurrences: got %v, want [%s]", resp, want)
+			}
+		})
+	}
+}
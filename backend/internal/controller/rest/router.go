@@ -1,19 +1,90 @@
 package rest
 
-import "net/http"
+import (
+	"net/http"
 
-func NewRouter(usecase UseCase) http.Handler {
+	"github.com/VLGKiwi/todo-site/backend/internal/idempotency"
+)
+
+// NewRouter serves the API. Everything but registration and login needs
+// a bearer token, a session token or an API key with the matching scope.
+// Every request is made in the workspace tenants resolves. Changes sent
+// with an Idempotency-Key are run once per key when idempotent is not nil,
+// see IdempotencyMiddleware. Browsers may open the WebSocket from the
+// origin of the API and from socketOrigins only.
+func NewRouter(usecase UseCase, auth AuthUseCase, tenants TenantResolver, idempotent *idempotency.Store, socketOrigins []string) http.Handler {
 	mux := http.NewServeMux()
 
 	handlers := NewHandlers(usecase)
+	handlers.SocketOrigins = socketOrigins
+	authHandlers := NewAuthHandlers(auth)
 
 	mux.HandleFunc("POST /api/todos", handlers.CreateTodoHandler)
+	mux.HandleFunc("POST /api/todos:batch", handlers.BatchHandler)
+	mux.HandleFunc("POST /api/todos:completeAll", handlers.CompleteAllHandler)
+	mux.HandleFunc("POST /api/todos:deleteCompleted", handlers.DeleteCompletedHandler)
 	mux.HandleFunc("GET /api/todos", handlers.GetAllTodosHandler)
+	mux.HandleFunc("GET /api/todos/overdue", handlers.GetOverdueTodosHandler)
+	mux.HandleFunc("GET /api/todos/due-today", handlers.GetDueTodayTodosHandler)
+	mux.HandleFunc("GET /api/todos/upcoming", handlers.GetUpcomingTodosHandler)
+	mux.HandleFunc("GET /api/todos/events", handlers.TodoEventsHandler)
+	mux.HandleFunc("GET "+socketPath, handlers.TodoSocketHandler)
 	mux.HandleFunc("GET /api/todos/{id}", handlers.GetTodoHandler)
 	mux.HandleFunc("PUT /api/todos/{id}", handlers.UpdateTodoHandler)
+	mux.HandleFunc("PATCH /api/todos/{id}", handlers.PatchTodoHandler)
 	mux.HandleFunc("DELETE /api/todos/{id}", handlers.DeleteTodoHandler)
+	mux.HandleFunc("GET /api/todos/{id}/subtasks", handlers.GetSubtasksHandler)
+	mux.HandleFunc("GET /api/todos/{id}/progress", handlers.GetTodoProgressHandler)
+	mux.HandleFunc("GET /api/todos/{id}/occurrences", handlers.GetOccurrencesHandler)
+	mux.HandleFunc("GET /api/todos/{id}/history", handlers.GetTodoHistoryHandler)
+	mux.HandleFunc("PUT /api/todos/{id}/tags/{tagID}", handlers.AttachTagHandler)
+	mux.HandleFunc("DELETE /api/todos/{id}/tags/{tagID}", handlers.DetachTagHandler)
+
+	mux.HandleFunc("GET /api/trash", handlers.GetTrashHandler)
+	mux.HandleFunc("POST /api/trash/{id}/restore", handlers.RestoreTodoHandler)
+	mux.HandleFunc("DELETE /api/trash/{id}", handlers.PurgeTodoHandler)
+
+	mux.HandleFunc("POST /api/undo", handlers.UndoHandler)
+	mux.HandleFunc("POST /api/redo", handlers.RedoHandler)
+
+	mux.HandleFunc("POST /api/tags", handlers.CreateTagHandler)
+	mux.HandleFunc("GET /api/tags", handlers.GetAllTagsHandler)
+	mux.HandleFunc("GET /api/tags/{id}", handlers.GetTagHandler)
+	mux.HandleFunc("PUT /api/tags/{id}", handlers.UpdateTagHandler)
+	mux.HandleFunc("DELETE /api/tags/{id}", handlers.DeleteTagHandler)
+
+	mux.HandleFunc("POST /api/lists", handlers.CreateListHandler)
+	mux.HandleFunc("GET /api/lists", handlers.GetListsHandler)
+	mux.HandleFunc("GET /api/lists/{id}", handlers.GetListHandler)
+	mux.HandleFunc("PATCH /api/lists/{id}", handlers.RenameListHandler)
+	mux.HandleFunc("DELETE /api/lists/{id}", handlers.DeleteListHandler)
+	mux.HandleFunc("POST /api/lists/{id}/invitations", handlers.InviteMemberHandler)
+	mux.HandleFunc("DELETE /api/lists/{id}/invitations/{email}", handlers.RevokeInvitationHandler)
+	mux.HandleFunc("PUT /api/lists/{id}/members/{userID}", handlers.ChangeMemberRoleHandler)
+	mux.HandleFunc("DELETE /api/lists/{id}/members/{userID}", handlers.RemoveMemberHandler)
+	mux.HandleFunc("GET /api/invitations", handlers.GetInvitationsHandler)
+	mux.HandleFunc("POST /api/invitations/{listID}/accept", handlers.AcceptInvitationHandler)
+	mux.HandleFunc("DELETE /api/invitations/{listID}", handlers.DeclineInvitationHandler)
+
+	mux.HandleFunc("POST /api/webhooks", handlers.CreateWebhookHandler)
+	mux.HandleFunc("GET /api/webhooks", handlers.GetWebhooksHandler)
+	mux.HandleFunc("GET /api/webhooks/dead-letters", handlers.GetDeadLettersHandler)
+	mux.HandleFunc("DELETE /api/webhooks/{id}", handlers.DeleteWebhookHandler)
+	mux.HandleFunc("GET /api/webhooks/{id}/deliveries", handlers.GetWebhookDeliveriesHandler)
+	mux.HandleFunc("POST /api/webhooks/{id}/deliveries/{deliveryID}/redeliver", handlers.RedeliverWebhookHandler)
+
+	mux.HandleFunc("POST /api/auth/logout", authHandlers.LogoutHandler)
+	mux.HandleFunc("GET /api/auth/me", authHandlers.MeHandler)
+	mux.HandleFunc("POST /api/auth/keys", authHandlers.CreateAPIKeyHandler)
+	mux.HandleFunc("GET /api/auth/keys", authHandlers.ListAPIKeysHandler)
+	mux.HandleFunc("DELETE /api/auth/keys/{id}", authHandlers.RevokeAPIKeyHandler)
+
+	public := http.NewServeMux()
+	public.HandleFunc("POST /api/auth/register", authHandlers.RegisterHandler)
+	public.HandleFunc("POST /api/auth/login", authHandlers.LoginHandler)
+	public.Handle("/", AuthMiddleware(auth)(ScopeMiddleware(IdempotencyMiddleware(idempotent)(mux))))
 
-	wrappedMux := LoggingMiddleware(mux)
+	wrappedMux := RequestIDMiddleware(LoggingMiddleware(TenantMiddleware(tenants)(public)))
 
 	return wrappedMux
 }
@@ -0,0 +1,292 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/websocket"
+)
+
+// socketCommand is a message of the client. ID is echoed in the reply, so
+// the client can tell which command it answers.
+type socketCommand struct {
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	TodoID  int             `json:"todo_id"`
+	Version int             `json:"version"`
+	Todo    json.RawMessage `json:"todo"`
+	Patch   json.RawMessage `json:"patch"`
+}
+
+// socketMessage is a message of the server: the result of a command, the
+// problem it failed with, or an event.
+type socketMessage struct {
+	Type    string               `json:"type"`
+	ID      string               `json:"id,omitempty"`
+	EventID uint64               `json:"event_id,omitempty"`
+	Event   domain.TodoEventType `json:"event,omitempty"`
+	Data    any                  `json:"data,omitempty"`
+	Error   *Problem             `json:"error,omitempty"`
+}
+
+const (
+	// socketPath is the route of TodoSocketHandler.
+	socketPath = "/api/ws"
+	// socketProtocol is the subprotocol of the socket, picked when the
+	// client offers it.
+	socketProtocol = "todo-site"
+	// socketTokenPrefix marks a bearer token offered as a subprotocol:
+	// browsers cannot set the Authorization header of a WebSocket, so they
+	// connect with new WebSocket(url, ["todo-site", "bearer." + token]).
+	// The token is only taken along with socketProtocol, which is the one
+	// picked, so the token never comes back in the response.
+	socketTokenPrefix = "bearer."
+)
+
+const (
+	socketResult = "result"
+	socketError  = "error"
+	socketEvent  = "event"
+)
+
+// TodoSocketHandler upgrades the request to a WebSocket that carries the
+// changes of todos as events, like TodoEventsHandler, and takes create,
+// update, patch and delete commands answered in order. Commands go through
+// the same use case as the REST handlers, so they are validated and
+// authorized the same way. Browsers may connect from the origin of the API
+// and from SocketOrigins only.
+func (h *Handlers) TodoSocketHandler(w http.ResponseWriter, r *http.Request) {
+	if err := websocket.CheckOrigin(r, h.SocketOrigins); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	lastEventID, err := parseLastEventID(r.URL.Query().Get("last_event_id"))
+	if err != nil {
+		writeProblem(w, r, invalidParam(fmt.Errorf("%w: %w", errMalformedRequest, err), "last_event_id", "must be an event id"))
+		return
+	}
+
+	offered := websocket.Subprotocols(r)
+	if slices.ContainsFunc(offered, isSocketToken) && !slices.Contains(offered, socketProtocol) {
+		writeProblem(w, r, invalidParam(errMalformedRequest, "Sec-WebSocket-Protocol", "a token must be offered along with "+socketProtocol))
+		return
+	}
+	if slices.Contains(offered, socketProtocol) {
+		w.Header().Set("Sec-WebSocket-Protocol", socketProtocol)
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if errors.Is(err, websocket.ErrBadHandshake) {
+		writeProblem(w, r, fmt.Errorf("%w: %w", errMalformedRequest, err))
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("upgrade to websocket: %w", err))
+		return
+	}
+
+	heartbeat := h.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeat
+	}
+	conn.MaxMessageSize = maxPatchBytes
+	// a client that misses two pings in a row is gone
+	conn.ReadTimeout = 2 * heartbeat
+	conn.WriteTimeout = heartbeat
+
+	ctx, cancel := context.WithCancel(r.Context())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		conn.Close(websocket.CloseGoingAway, "")
+		wg.Wait()
+	}()
+
+	events := h.UseCase.TodoEvents(ctx, lastEventID)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.pushEvents(ctx, conn, events, heartbeat)
+	}()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			var cerr *websocket.CloseError
+			if !errors.As(err, &cerr) && ctx.Err() == nil {
+				slog.Info("websocket closed", "error", err, "request_id", RequestIDFromContext(ctx))
+			}
+			return
+		}
+
+		if err := writeSocket(conn, h.runCommand(ctx, r, msg)); err != nil {
+			return
+		}
+	}
+}
+
+// socketToken reads the bearer token a WebSocket handshake of socketPath
+// offers as a subprotocol along with socketProtocol. Other requests must
+// use the Authorization header.
+func socketToken(r *http.Request) (string, bool) {
+	if r.Method != http.MethodGet || r.URL.Path != socketPath {
+		return "", false
+	}
+
+	offered := websocket.Subprotocols(r)
+	if !slices.Contains(offered, socketProtocol) {
+		return "", false
+	}
+	for _, p := range offered {
+		if isSocketToken(p) {
+			return strings.TrimPrefix(p, socketTokenPrefix), true
+		}
+	}
+	return "", false
+}
+
+func isSocketToken(protocol string) bool {
+	return strings.HasPrefix(protocol, socketTokenPrefix) && len(protocol) > len(socketTokenPrefix)
+}
+
+// pushEvents sends the events and the pings until ctx is done. A
+// subscriber that falls behind is disconnected and resumes with
+// last_event_id.
+func (h *Handlers) pushEvents(ctx context.Context, conn *websocket.Conn, events <-chan domain.TodoEvent, heartbeat time.Duration) {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				conn.Close(websocket.CloseTryAgainLater, "resume with last_event_id")
+				return
+			}
+			msg := socketMessage{Type: socketEvent, EventID: event.ID, Event: event.Type, Data: eventData(event)}
+			if err := writeSocket(conn, msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// runCommand runs a command and returns the reply.
+func (h *Handlers) runCommand(ctx context.Context, r *http.Request, msg []byte) socketMessage {
+	var cmd socketCommand
+	if err := json.Unmarshal(msg, &cmd); err != nil {
+		return socketFailure(ctx, r, cmd, fmt.Errorf("%w: decode command: %w", errMalformedRequest, err))
+	}
+
+	data, err := h.execute(ctx, cmd)
+	if err != nil {
+		return socketFailure(ctx, r, cmd, err)
+	}
+
+	return socketMessage{Type: socketResult, ID: cmd.ID, Data: data}
+}
+
+func (h *Handlers) execute(ctx context.Context, cmd socketCommand) (any, error) {
+	switch cmd.Type {
+	case "create", "update", "patch", "delete":
+	default:
+		return nil, invalidParam(fmt.Errorf("%w: unknown command %q", errMalformedRequest, cmd.Type), "type", "must be create, update, patch or delete")
+	}
+	// the upgrade itself only needed todos:read
+	if key, ok := domain.APIKeyFromContext(ctx); ok && !key.Allows(domain.ScopeTodosWrite) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrInsufficientScope, domain.ScopeTodosWrite)
+	}
+
+	switch cmd.Type {
+	case "create":
+		var todo domain.Todo
+		if err := decodeCommandTodo(cmd, &todo); err != nil {
+			return nil, err
+		}
+		id, err := h.UseCase.CreateTodo(ctx, todo)
+		if err != nil {
+			return nil, fmt.Errorf("create todo: %w", err)
+		}
+		return map[string]int{"id": id}, nil
+
+	case "update":
+		var todo domain.Todo
+		if err := decodeCommandTodo(cmd, &todo); err != nil {
+			return nil, err
+		}
+		// like If-Match, the version of the command wins over the todo
+		if cmd.Version != 0 {
+			todo.Version = cmd.Version
+		}
+		if err := h.UseCase.UpdateTodoByID(ctx, cmd.TodoID, todo); err != nil {
+			return nil, fmt.Errorf("update todo %d: %w", cmd.TodoID, err)
+		}
+		return nil, nil
+
+	case "patch":
+		if len(cmd.Patch) == 0 {
+			return nil, invalidParam(fmt.Errorf("%w: no patch", errMalformedRequest), "patch", "must be a merge patch")
+		}
+		todo, err := h.UseCase.PatchTodoByID(ctx, cmd.TodoID, cmd.Version, cmd.Patch)
+		if err != nil {
+			return nil, fmt.Errorf("patch todo %d: %w", cmd.TodoID, err)
+		}
+		return todo, nil
+
+	default:
+		if err := h.UseCase.DeleteTodoByID(ctx, cmd.TodoID, cmd.Version); err != nil {
+			return nil, fmt.Errorf("delete todo %d: %w", cmd.TodoID, err)
+		}
+		return nil, nil
+	}
+}
+
+func decodeCommandTodo(cmd socketCommand, todo *domain.Todo) error {
+	if len(cmd.Todo) == 0 {
+		return invalidParam(fmt.Errorf("%w: no todo", errMalformedRequest), "todo", "must be a todo")
+	}
+	if err := json.Unmarshal(cmd.Todo, todo); err != nil {
+		return fmt.Errorf("%w: decode todo: %w", errMalformedRequest, err)
+	}
+	return nil
+}
+
+// socketFailure logs the error of a command and turns it into the problem
+// the REST handlers would answer with.
+func socketFailure(ctx context.Context, r *http.Request, cmd socketCommand, err error) socketMessage {
+	p := problemFor(err)
+	p.Instance = r.URL.Path
+	p.RequestID = RequestIDFromContext(ctx)
+
+	if p.Status >= http.StatusInternalServerError {
+		slog.Error("websocket command failed", "error", err, "command", cmd.Type, "id", cmd.ID, "request_id", p.RequestID)
+	} else {
+		slog.Warn("websocket command rejected", "error", err, "type", p.Type, "command", cmd.Type, "id", cmd.ID, "request_id", p.RequestID)
+	}
+
+	return socketMessage{Type: socketError, ID: cmd.ID, Error: &p}
+}
+
+func writeSocket(conn *websocket.Conn, msg socketMessage) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("failed to encode websocket message", "error", err)
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, b)
+}
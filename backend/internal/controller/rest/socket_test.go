@@ -0,0 +1,407 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestRunCommand(t *testing.T) {
+	readOnly := domain.ContextWithAPIKey(context.Background(), domain.APIKey{Scopes: []domain.Scope{domain.ScopeTodosRead}})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		command string
+
+		usecaseErr error
+
+		wantType    string
+		wantNoID    bool
+		wantProblem string
+		wantData    string
+		wantCalls   int
+	}{
+		{
+			name:      "create",
+			command:   `{"id":"c1","type":"create","todo":{"title":"milk"}}`,
+			wantType:  socketResult,
+			wantData:  `{"id":3}`,
+			wantCalls: 1,
+		},
+		{
+			name:      "update with version",
+			command:   `{"id":"c1","type":"update","todo_id":1,"version":4,"todo":{"title":"milk","version":2}}`,
+			wantType:  socketResult,
+			wantCalls: 1,
+		},
+		{
+			name:      "patch",
+			command:   `{"id":"c1","type":"patch","todo_id":1,"version":4,"patch":{"completed":true}}`,
+			wantType:  socketResult,
+			wantData:  `{"id":1,"title":"milk"`,
+			wantCalls: 1,
+		},
+		{
+			name:      "delete",
+			command:   `{"id":"c1","type":"delete","todo_id":1,"version":4}`,
+			wantType:  socketResult,
+			wantCalls: 1,
+		},
+		{
+			name:        "invalid todo -> validation error",
+			command:     `{"id":"c1","type":"create","todo":{"title":""}}`,
+			usecaseErr:  domain.ErrNoTitle,
+			wantType:    socketError,
+			wantProblem: problemValidationFailed,
+			wantCalls:   1,
+		},
+		{
+			name:        "stale version -> precondition failed",
+			command:     `{"id":"c1","type":"delete","todo_id":1,"version":3}`,
+			usecaseErr:  domain.ErrVersionMismatch,
+			wantType:    socketError,
+			wantProblem: problemPreconditionFailed,
+			wantCalls:   1,
+		},
+		{
+			name:        "malformed command -> error without id",
+			command:     `{"id":"c1","type":`,
+			wantType:    socketError,
+			wantNoID:    true,
+			wantProblem: problemMalformedRequest,
+		},
+		{
+			name:        "unknown command -> error",
+			command:     `{"id":"c1","type":"rename"}`,
+			wantType:    socketError,
+			wantProblem: problemMalformedRequest,
+		},
+		{
+			name:        "create without todo -> error",
+			command:     `{"id":"c1","type":"create"}`,
+			wantType:    socketError,
+			wantProblem: problemMalformedRequest,
+		},
+		{
+			name:        "read-only api key -> insufficient scope",
+			ctx:         readOnly,
+			command:     `{"id":"c1","type":"delete","todo_id":1}`,
+			wantType:    socketError,
+			wantProblem: problemInsufficientScope,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				CreateTodoFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+					return 3, tc.usecaseErr
+				},
+				UpdateTodoByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+					if todo.Version != 4 {
+						t.Errorf("unexpected version: got %d, want 4", todo.Version)
+					}
+					return tc.usecaseErr
+				},
+				PatchTodoByIDFunc: func(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+					if version != 4 || string(patch) != `{"completed":true}` {
+						t.Errorf("unexpected patch: got version %d %s", version, patch)
+					}
+					return domain.Todo{ID: id, Title: "milk", Completed: true}, tc.usecaseErr
+				},
+				DeleteTodoByIDFunc: func(ctx context.Context, id int, version int) error {
+					return tc.usecaseErr
+				},
+			}
+			handlers := NewHandlers(useCaseMock)
+
+			ctx := tc.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/ws", nil)
+
+			// act
+			reply := handlers.runCommand(ctx, req, []byte(tc.command))
+
+			// assert
+			calls := useCaseMock.CreateTodoCalls + useCaseMock.UpdateTodoByIDCalls + useCaseMock.PatchTodoByIDCalls + useCaseMock.DeleteTodoByIDCalls
+			if calls != tc.wantCalls {
+				t.Fatalf("unexpected calls: got %d, want %d", calls, tc.wantCalls)
+			}
+			wantID := "c1"
+			if tc.wantNoID {
+				wantID = ""
+			}
+			if reply.Type != tc.wantType || reply.ID != wantID {
+				t.Fatalf("unexpected reply: got %s to %q, want %s to %q", reply.Type, reply.ID, tc.wantType, wantID)
+			}
+			if tc.wantProblem != "" && (reply.Error == nil || reply.Error.Type != tc.wantProblem) {
+				t.Errorf("unexpected problem: got %+v, want %s", reply.Error, tc.wantProblem)
+			}
+
+			data, _ := json.Marshal(reply.Data)
+			if tc.wantData != "" && !strings.HasPrefix(string(data), tc.wantData) {
+				t.Errorf("unexpected data: got %s, want %s...", data, tc.wantData)
+			}
+		})
+	}
+}
+
+// dialSocket upgrades a connection to the server and returns it.
+func dialSocket(t *testing.T, srv *httptest.Server, path string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, br, resp := handshakeSocket(t, srv, path, "Authorization: Bearer "+testToken)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status code: got %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+
+	return conn, br
+}
+
+// handshakeSocket sends an opening handshake with the extra header lines
+// and returns the response.
+func handshakeSocket(t *testing.T, srv *httptest.Server, path string, headers ...string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n", path)
+	for _, h := range headers {
+		fmt.Fprintf(conn, "%s\r\n", h)
+	}
+	fmt.Fprint(conn, "\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+
+	return conn, br, resp
+}
+
+// sendSocket writes a masked text frame, as clients do.
+func sendSocket(t *testing.T, conn net.Conn, msg string) {
+	t.Helper()
+
+	mask := []byte{7, 7, 7, 7}
+	frame := []byte{0x81, 0x80 | byte(len(msg))}
+	frame = append(frame, mask...)
+	for i := range len(msg) {
+		frame = append(frame, msg[i]^mask[i%4])
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("write frame: %v", err)
+	}
+}
+
+// receiveSocket reads the next text message, skipping pings.
+func receiveSocket(t *testing.T, br *bufio.Reader) socketMessage {
+	t.Helper()
+
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(br, head[:]); err != nil {
+			t.Fatalf("read frame: %v", err)
+		}
+		n := int(head[1])
+		if n == 126 {
+			var ext [2]byte
+			io.ReadFull(br, ext[:])
+			n = int(binary.BigEndian.Uint16(ext[:]))
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			t.Fatalf("read payload: %v", err)
+		}
+		if head[0]&0x0f != 0x1 {
+			continue
+		}
+
+		var msg socketMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			t.Fatalf("decode message: %v", err)
+		}
+		return msg
+	}
+}
+
+func TestTodoSocketHandler(t *testing.T) {
+	// preparing
+	events := make(chan domain.TodoEvent)
+	useCaseMock := &UseCaseMock{
+		TodoEventsFunc: func(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent {
+			return events
+		},
+		DeleteTodoByIDFunc: func(ctx context.Context, id int, version int) error {
+			return domain.ErrTodoNotExist
+		},
+	}
+	srv := httptest.NewServer(newTestRouter(useCaseMock))
+	defer srv.Close()
+
+	conn, br := dialSocket(t, srv, "/api/ws?last_event_id=41")
+
+	// act
+	events <- domain.TodoEvent{ID: 42, Type: domain.TodoDeleted, Todo: domain.Todo{ID: 7}}
+	event := receiveSocket(t, br)
+	sendSocket(t, conn, `{"id":"c9","type":"delete","todo_id":7}`)
+	reply := receiveSocket(t, br)
+
+	// assert
+	if useCaseMock.LastEventID != 41 {
+		t.Errorf("unexpected last event id: got %d, want 41", useCaseMock.LastEventID)
+	}
+	if event.Type != socketEvent || event.EventID != 42 || event.Event != domain.TodoDeleted {
+		t.Errorf("unexpected event: got %+v", event)
+	}
+	if data, _ := json.Marshal(event.Data); string(data) != `{"id":7}` {
+		t.Errorf("unexpected event data: got %s, want %s", data, `{"id":7}`)
+	}
+	if reply.Type != socketError || reply.ID != "c9" || reply.Error == nil || reply.Error.Type != problemTodoNotFound {
+		t.Errorf("unexpected reply: got %+v, want %s to c9", reply, problemTodoNotFound)
+	}
+}
+
+func TestTodoSocketHandlerHandshake(t *testing.T) {
+	// preparing
+	useCaseMock := &UseCaseMock{}
+	req := httptest.NewRequest(http.MethodGet, "/api/ws", nil)
+	rec := httptest.NewRecorder()
+
+	// act
+	newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+	// assert
+	assertProblem(t, rec, http.StatusBadRequest, problemMalformedRequest)
+	if useCaseMock.TodoEventsCalls != 0 {
+		t.Errorf("unexpected calls: got %d, want 0", useCaseMock.TodoEventsCalls)
+	}
+}
+
+func TestTodoSocketHandlerProtocolToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+
+		wantCode     int
+		wantProtocol string
+		wantToken    string
+	}{
+		{
+			name:         "token with the socket protocol -> socket protocol picked",
+			headers:      []string{"Sec-WebSocket-Protocol: todo-site, bearer." + testToken},
+			wantCode:     http.StatusSwitchingProtocols,
+			wantProtocol: socketProtocol,
+			wantToken:    testToken,
+		},
+		{
+			name:     "token without the socket protocol -> unauthenticated",
+			headers:  []string{"Sec-WebSocket-Protocol: bearer." + testToken},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:      "token without the socket protocol and a header -> bad request",
+			headers:   []string{"Sec-WebSocket-Protocol: bearer." + testToken, "Authorization: Bearer " + testToken},
+			wantCode:  http.StatusBadRequest,
+			wantToken: testToken,
+		},
+		{
+			name:         "header with the socket protocol -> socket protocol picked",
+			headers:      []string{"Sec-WebSocket-Protocol: todo-site", "Authorization: Bearer " + testToken},
+			wantCode:     http.StatusSwitchingProtocols,
+			wantProtocol: socketProtocol,
+			wantToken:    testToken,
+		},
+		{
+			name:         "allowed origin -> switched",
+			headers:      []string{"Sec-WebSocket-Protocol: todo-site, bearer." + testToken, "Origin: https://todo.example.com"},
+			wantCode:     http.StatusSwitchingProtocols,
+			wantProtocol: socketProtocol,
+			wantToken:    testToken,
+		},
+		{
+			name:     "no token -> unauthenticated",
+			headers:  []string{"Sec-WebSocket-Protocol: todo-site"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:      "another origin -> forbidden",
+			headers:   []string{"Sec-WebSocket-Protocol: todo-site, bearer." + testToken, "Origin: https://evil.example"},
+			wantCode:  http.StatusForbidden,
+			wantToken: testToken,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			var gotToken string
+			auth := &AuthUseCaseMock{
+				AuthenticateFunc: func(ctx context.Context, token string) (domain.User, error) {
+					gotToken = token
+					return testUser, nil
+				},
+			}
+			useCaseMock := &UseCaseMock{
+				TodoEventsFunc: func(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent {
+					return make(chan domain.TodoEvent)
+				},
+			}
+			srv := httptest.NewServer(NewRouter(useCaseMock, auth, TenantResolver{}, nil, []string{"https://todo.example.com"}))
+			defer srv.Close()
+
+			// act
+			_, _, resp := handshakeSocket(t, srv, socketPath, tc.headers...)
+
+			// assert
+			if resp.StatusCode != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", resp.StatusCode, tc.wantCode)
+			}
+			if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != tc.wantProtocol {
+				t.Errorf("unexpected protocol: got %q, want %q", got, tc.wantProtocol)
+			}
+			if gotToken != tc.wantToken {
+				t.Errorf("unexpected token: got %q, want %q", gotToken, tc.wantToken)
+			}
+		})
+	}
+}
+
+func TestProtocolTokenOutsideSocket(t *testing.T) {
+	// preparing
+	auth := &AuthUseCaseMock{}
+	req := httptest.NewRequest(http.MethodGet, "/api/todos", nil)
+	req.Header.Set("Sec-WebSocket-Protocol", "bearer."+testToken)
+	rec := httptest.NewRecorder()
+
+	// act
+	NewRouter(&UseCaseMock{}, auth, TenantResolver{}, nil, nil).ServeHTTP(rec, req)
+
+	// assert
+	assertProblem(t, rec, http.StatusUnauthorized, problemUnauthenticated)
+	if auth.AuthenticateCalls != 0 {
+		t.Errorf("unexpected calls: got %d, want 0", auth.AuthenticateCalls)
+	}
+}
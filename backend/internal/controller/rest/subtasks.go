@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// GetSubtasksHandler lists the direct subtasks of a todo and takes the same
+// parameters as GET /api/todos.
+func (h *Handlers) GetSubtasksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	q, cursor, err := parseTodoQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	page, err := h.UseCase.ListSubtasks(r.Context(), id, q, cursor)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("list subtasks of todo %d: %w", id, err))
+		return
+	}
+
+	writeTodoPage(w, r, page)
+}
+
+func (h *Handlers) GetTodoProgressHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	progress, err := h.UseCase.GetTodoProgress(r.Context(), id)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get progress of todo %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(progress); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestSubtaskHandlers(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+
+		usecaseErr error
+
+		wantCode    int
+		wantProblem string
+
+		calls     func(m *UseCaseMock) int
+		wantCalls int
+	}{
+		{
+			name:      "list subtasks",
+			url:       "/api/todos/1/subtasks?limit=1",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.ListSubtasksCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "list subtasks of missing todo -> not found",
+			url:         "/api/todos/1/subtasks",
+			usecaseErr:  domain.ErrTodoNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemTodoNotFound,
+			calls:       func(m *UseCaseMock) int { return m.ListSubtasksCalls },
+			wantCalls:   1,
+		},
+		{
+			name:        "list subtasks with bad query -> error",
+			url:         "/api/todos/1/subtasks?limit=many",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemInvalidQuery,
+			calls:       func(m *UseCaseMock) int { return m.ListSubtasksCalls },
+			wantCalls:   0,
+		},
+		{
+			name:      "progress",
+			url:       "/api/todos/1/progress",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.GetTodoProgressCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "progress of malformed id -> error",
+			url:         "/api/todos/one/progress",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *UseCaseMock) int { return m.GetTodoProgressCalls },
+			wantCalls:   0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				ListSubtasksFunc: func(ctx context.Context, id int, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+					return domain.TodoPage{Todos: []domain.Todo{{ID: 2, Title: "pack the books", ParentID: &id}}}, tc.usecaseErr
+				},
+				GetTodoProgressFunc: func(ctx context.Context, id int) (domain.Progress, error) {
+					return domain.Progress{TodoID: id, Percent: 50, Subtasks: 2, SubtasksCompleted: 1}, tc.usecaseErr
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Errorf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+			}
+
+			if got := tc.calls(useCaseMock); got != tc.wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", got, tc.wantCalls)
+			}
+		})
+	}
+
+	t.Run("progress response", func(t *testing.T) {
+		// preparing
+		useCaseMock := &UseCaseMock{
+			GetTodoProgressFunc: func(ctx context.Context, id int) (domain.Progress, error) {
+				return domain.Progress{TodoID: id, Percent: 62, Subtasks: 2, SubtasksCompleted: 1, ChecklistItems: 2, ChecklistDone: 1}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/api/todos/7/progress", nil)
+		rec := httptest.NewRecorder()
+
+		// act
+		newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+		// assert
+		var resp domain.Progress
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		want := domain.Progress{TodoID: 7, Percent: 62, Subtasks: 2, SubtasksCompleted: 1, ChecklistItems: 2, ChecklistDone: 1}
+		if resp != want {
+			t.Errorf("unexpected progress: got %+v, want %+v", resp, want)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (h *Handlers) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	var tag domain.Tag
+
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode tag: %w", errMalformedRequest, err))
+		return
+	}
+
+	id, err := h.UseCase.CreateTag(r.Context(), tag)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("create tag: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/tags/%d", id))
+
+	w.WriteHeader(http.StatusCreated)
+
+	resp := map[string]int{"id": id}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+
+	slog.Info("tag created", "id", id)
+}
+
+func (h *Handlers) GetAllTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.UseCase.GetAllTags(r.Context())
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get all tags: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (h *Handlers) GetTagHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	tag, err := h.UseCase.GetTagByID(r.Context(), id)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get tag %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tag); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (h *Handlers) UpdateTagHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	var tag domain.Tag
+
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode tag: %w", errMalformedRequest, err))
+		return
+	}
+
+	if err := h.UseCase.UpdateTagByID(r.Context(), id, tag); err != nil {
+		writeProblem(w, r, fmt.Errorf("update tag %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resp := map[string]string{"message": "tag successfully updated"}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// DeleteTagHandler removes the tag from every todo as well.
+func (h *Handlers) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.UseCase.DeleteTagByID(r.Context(), id); err != nil {
+		writeProblem(w, r, fmt.Errorf("delete tag %d: %w", id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) AttachTagHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeTodoTag(w, r, "attach", h.UseCase.AttachTag)
+}
+
+func (h *Handlers) DetachTagHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeTodoTag(w, r, "detach", h.UseCase.DetachTag)
+}
+
+// changeTodoTag serves /api/todos/{id}/tags/{tagID} and responds with the
+// changed todo, like PATCH does.
+func (h *Handlers) changeTodoTag(w http.ResponseWriter, r *http.Request, action string,
+	change func(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error),
+) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	tagID, err := pathInt(r, "tagID")
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	version, err := h.ifMatchVersion(r, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	todo, err := change(r.Context(), id, tagID, version)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("%s tag %d on todo %d: %w", action, tagID, id, err))
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(todo.Version))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
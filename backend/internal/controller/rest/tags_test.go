@@ -0,0 +1,296 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestTagHandlers(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		body    string
+		ifMatch string
+
+		usecaseErr error
+
+		wantCode     int
+		wantProblem  string
+		wantLocation string
+		wantETag     string
+
+		calls     func(m *UseCaseMock) int
+		wantCalls int
+	}{
+		{
+			name:         "create",
+			method:       http.MethodPost,
+			url:          "/api/tags",
+			body:         `{"name": "work", "color": "#ff0000"}`,
+			wantCode:     http.StatusCreated,
+			wantLocation: "/api/tags/3",
+			calls:        func(m *UseCaseMock) int { return m.CreateTagCalls },
+			wantCalls:    1,
+		},
+		{
+			name:        "create malformed body -> error",
+			method:      http.MethodPost,
+			url:         "/api/tags",
+			body:        `{"name":`,
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *UseCaseMock) int { return m.CreateTagCalls },
+			wantCalls:   0,
+		},
+		{
+			name:        "create existing name -> conflict",
+			method:      http.MethodPost,
+			url:         "/api/tags",
+			body:        `{"name": "work"}`,
+			usecaseErr:  domain.ErrTagExists,
+			wantCode:    http.StatusConflict,
+			wantProblem: problemTagExists,
+			calls:       func(m *UseCaseMock) int { return m.CreateTagCalls },
+			wantCalls:   1,
+		},
+		{
+			name:      "list",
+			method:    http.MethodGet,
+			url:       "/api/tags",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.GetAllTagsCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "get missing -> not found",
+			method:      http.MethodGet,
+			url:         "/api/tags/3",
+			usecaseErr:  domain.ErrTagNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemTagNotFound,
+			calls:       func(m *UseCaseMock) int { return m.GetTagByIDCalls },
+			wantCalls:   1,
+		},
+		{
+			name:        "update invalid -> error",
+			method:      http.MethodPut,
+			url:         "/api/tags/3",
+			body:        `{"name": ""}`,
+			usecaseErr:  domain.ValidationErrors{{Field: "name", Err: domain.ErrRequired}},
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemValidationFailed,
+			calls:       func(m *UseCaseMock) int { return m.UpdateTagByIDCalls },
+			wantCalls:   1,
+		},
+		{
+			name:      "delete",
+			method:    http.MethodDelete,
+			url:       "/api/tags/3",
+			wantCode:  http.StatusNoContent,
+			calls:     func(m *UseCaseMock) int { return m.DeleteTagByIDCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "delete malformed id -> error",
+			method:      http.MethodDelete,
+			url:         "/api/tags/work",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *UseCaseMock) int { return m.DeleteTagByIDCalls },
+			wantCalls:   0,
+		},
+		{
+			name:      "attach",
+			method:    http.MethodPut,
+			url:       "/api/todos/1/tags/3",
+			ifMatch:   `"2"`,
+			wantCode:  http.StatusOK,
+			wantETag:  `"3"`,
+			calls:     func(m *UseCaseMock) int { return m.AttachTagCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "attach missing tag -> not found",
+			method:      http.MethodPut,
+			url:         "/api/todos/1/tags/3",
+			usecaseErr:  domain.ErrTagNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemTagNotFound,
+			calls:       func(m *UseCaseMock) int { return m.AttachTagCalls },
+			wantCalls:   1,
+		},
+		{
+			name:        "attach malformed tag id -> error",
+			method:      http.MethodPut,
+			url:         "/api/todos/1/tags/work",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *UseCaseMock) int { return m.AttachTagCalls },
+			wantCalls:   0,
+		},
+		{
+			name:        "detach with stale version -> precondition failed",
+			method:      http.MethodDelete,
+			url:         "/api/todos/1/tags/3",
+			ifMatch:     `"1"`,
+			usecaseErr:  domain.ErrVersionMismatch,
+			wantCode:    http.StatusPreconditionFailed,
+			wantProblem: problemPreconditionFailed,
+			calls:       func(m *UseCaseMock) int { return m.DetachTagCalls },
+			wantCalls:   1,
+		},
+		{
+			name:        "failed to list -> error",
+			method:      http.MethodGet,
+			url:         "/api/tags",
+			usecaseErr:  errors.New("some error in usecase"),
+			wantCode:    http.StatusInternalServerError,
+			wantProblem: problemInternal,
+			calls:       func(m *UseCaseMock) int { return m.GetAllTagsCalls },
+			wantCalls:   1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			todo := domain.Todo{ID: 1, Title: "read the book", Version: 3, Tags: []int{3}}
+			useCaseMock := &UseCaseMock{
+				CreateTagFunc: func(ctx context.Context, tag domain.Tag) (int, error) {
+					return 3, tc.usecaseErr
+				},
+				GetAllTagsFunc: func(ctx context.Context) ([]domain.Tag, error) {
+					return []domain.Tag{{ID: 3, Name: "work"}}, tc.usecaseErr
+				},
+				GetTagByIDFunc: func(ctx context.Context, id int) (domain.Tag, error) {
+					return domain.Tag{ID: id, Name: "work"}, tc.usecaseErr
+				},
+				UpdateTagByIDFunc: func(ctx context.Context, id int, tag domain.Tag) error {
+					return tc.usecaseErr
+				},
+				DeleteTagByIDFunc: func(ctx context.Context, id int) error {
+					return tc.usecaseErr
+				},
+				AttachTagFunc: func(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error) {
+					return todo, tc.usecaseErr
+				},
+				DetachTagFunc: func(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error) {
+					return todo, tc.usecaseErr
+				},
+			}
+
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Errorf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+			}
+
+			if got := tc.calls(useCaseMock); got != tc.wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", got, tc.wantCalls)
+			}
+
+			if got := rec.Header().Get("Location"); got != tc.wantLocation {
+				t.Errorf("unexpected Location: got %q, want %q", got, tc.wantLocation)
+			}
+			if got := rec.Header().Get("ETag"); got != tc.wantETag {
+				t.Errorf("unexpected ETag: got %q, want %q", got, tc.wantETag)
+			}
+		})
+	}
+
+	t.Run("attach passes ids and version", func(t *testing.T) {
+		// preparing
+		useCaseMock := &UseCaseMock{
+			AttachTagFunc: func(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error) {
+				return domain.Todo{ID: todoID, Title: "read the book", Version: version + 1, Tags: []int{tagID}}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/api/todos/7/tags/5", nil)
+		req.Header.Set("If-Match", `"2"`)
+		rec := httptest.NewRecorder()
+
+		// act
+		newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+		// assert
+		if useCaseMock.LastGetID != 7 || useCaseMock.LastTagID != 5 || useCaseMock.LastVersion != 2 {
+			t.Fatalf("unexpected arguments: todo %d tag %d version %d", useCaseMock.LastGetID, useCaseMock.LastTagID, useCaseMock.LastVersion)
+		}
+
+		var resp domain.Todo
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if !slices.Equal(resp.Tags, []int{5}) {
+			t.Errorf("unexpected tags: got %v, want %v", resp.Tags, []int{5})
+		}
+	})
+}
+
+func TestParseTagQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		wantNames []string
+		wantAll   bool
+		wantCode  int
+	}{
+		{name: "any by default", url: "/api/todos?tag=work&tag=home", wantNames: []string{"work", "home"}, wantCode: http.StatusOK},
+		{name: "all", url: "/api/todos?tag=work&tag=home&tag_mode=all", wantNames: []string{"work", "home"}, wantAll: true, wantCode: http.StatusOK},
+		{name: "empty names are skipped", url: "/api/todos?tag=&tag=work", wantNames: []string{"work"}, wantCode: http.StatusOK},
+		{name: "unknown mode -> error", url: "/api/todos?tag=work&tag_mode=some", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				ListTodosFunc: func(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+					return domain.TodoPage{Todos: []domain.Todo{}}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+			if tc.wantCode != http.StatusOK {
+				assertProblem(t, rec, tc.wantCode, problemInvalidQuery)
+				return
+			}
+
+			f := useCaseMock.LastQuery.Filter
+			if !slices.Equal(f.TagNames, tc.wantNames) || f.AllTags != tc.wantAll {
+				t.Errorf("unexpected filter: got names %v all %t, want %v %t", f.TagNames, f.AllTags, tc.wantNames, tc.wantAll)
+			}
+		})
+	}
+}
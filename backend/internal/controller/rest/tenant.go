@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const tenantHeader = "X-Tenant-ID"
+
+// TenantResolver finds the workspace a request is made in: the
+// X-Tenant-ID header or the subdomain of BaseDomain in the Host. A request
+// naming neither is made in domain.DefaultTenant, or in the workspace of
+// its session token, see usecase.AuthUseCase.Authenticate.
+type TenantResolver struct {
+	// BaseDomain turns subdomains into workspaces: acme.todo.example.com
+	// is the acme workspace when BaseDomain is todo.example.com. Empty
+	// leaves the Host alone.
+	BaseDomain string
+	// Allowed lists the workspaces that exist. Empty accepts any name.
+	Allowed []domain.Tenant
+}
+
+// Resolve returns the workspace named by the request, false when it names
+// none. Naming a different workspace in the header and in the subdomain is
+// an error.
+func (t TenantResolver) Resolve(r *http.Request) (domain.Tenant, bool, error) {
+	header := r.Header.Get(tenantHeader)
+	sub := t.subdomain(r.Host)
+	if header == "" && sub == "" {
+		return "", false, nil
+	}
+
+	param, name := tenantHeader, header
+	if header == "" {
+		param, name = "Host", sub
+	}
+
+	tenant, err := domain.ParseTenant(name)
+	if err != nil {
+		return "", false, invalidParam(fmt.Errorf("%w: %w", errMalformedRequest, err), param, "must be a workspace name")
+	}
+	if header != "" && sub != "" {
+		if other, err := domain.ParseTenant(sub); err != nil || other != tenant {
+			return "", false, invalidParam(errMalformedRequest, tenantHeader, "must match the subdomain")
+		}
+	}
+
+	if len(t.Allowed) > 0 && !slices.Contains(t.Allowed, tenant) {
+		return "", false, fmt.Errorf("%w: %s", domain.ErrTenantNotExist, tenant)
+	}
+
+	return tenant, true, nil
+}
+
+// subdomain returns the label in front of BaseDomain, empty for the base
+// domain itself and for other hosts.
+func (t TenantResolver) subdomain(host string) string {
+	if t.BaseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	sub, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(t.BaseDomain))
+	if !ok {
+		return ""
+	}
+	return sub
+}
+
+// TenantMiddleware puts the workspace the request names into the context.
+// Every repository call made for the request is scoped to it.
+func TenantMiddleware(t TenantResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant, ok, err := t.Resolve(r)
+			if err != nil {
+				writeProblem(w, r, fmt.Errorf("resolve workspace: %w", err))
+				return
+			}
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(domain.ContextWithTenant(r.Context(), tenant)))
+		})
+	}
+}
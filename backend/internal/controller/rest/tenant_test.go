@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	resolver := TenantResolver{BaseDomain: "todo.example.com"}
+	allowed := TenantResolver{Allowed: []domain.Tenant{"acme", "globex"}}
+
+	tests := []struct {
+		name     string
+		resolver TenantResolver
+		host     string
+		header   string
+
+		wantTenant  domain.Tenant
+		wantNamed   bool
+		wantStatus  int
+		wantProblem string
+	}{
+		{name: "nothing named -> default", resolver: resolver, host: "todo.example.com", wantTenant: domain.DefaultTenant},
+		{name: "header", resolver: resolver, host: "todo.example.com", header: "Acme", wantTenant: "acme", wantNamed: true},
+		{name: "subdomain", resolver: resolver, host: "acme.todo.example.com:8080", wantTenant: "acme", wantNamed: true},
+		{name: "header matching the subdomain", resolver: resolver, host: "acme.todo.example.com", header: "acme", wantTenant: "acme", wantNamed: true},
+		{name: "other host is not a subdomain", resolver: resolver, host: "acme.example.org", wantTenant: domain.DefaultTenant},
+		{name: "subdomains off", resolver: TenantResolver{}, host: "acme.todo.example.com", wantTenant: domain.DefaultTenant},
+		{name: "allowed tenant", resolver: allowed, header: "globex", wantTenant: "globex", wantNamed: true},
+		{
+			name:        "invalid header -> error",
+			resolver:    resolver,
+			header:      "acme_corp",
+			wantStatus:  http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+		},
+		{
+			name:        "nested subdomain -> error",
+			resolver:    resolver,
+			host:        "a.b.todo.example.com",
+			wantStatus:  http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+		},
+		{
+			name:        "header and subdomain differ -> error",
+			resolver:    resolver,
+			host:        "acme.todo.example.com",
+			header:      "globex",
+			wantStatus:  http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+		},
+		{
+			name:        "unknown tenant -> error",
+			resolver:    allowed,
+			header:      "initech",
+			wantStatus:  http.StatusNotFound,
+			wantProblem: problemTenantNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			var (
+				called bool
+				tenant domain.Tenant
+				named  bool
+			)
+			handler := TenantMiddleware(tc.resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				tenant = domain.CurrentTenant(r.Context())
+				_, named = domain.TenantFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/todos", nil)
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+			if tc.header != "" {
+				req.Header.Set(tenantHeader, tc.header)
+			}
+			rec := httptest.NewRecorder()
+
+			// act
+			handler.ServeHTTP(rec, req)
+
+			// assert
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantStatus, tc.wantProblem)
+				if called {
+					t.Errorf("next handler must not be called")
+				}
+				return
+			}
+
+			if !called {
+				t.Fatalf("next handler must be called, got status %d", rec.Code)
+			}
+			if tenant != tc.wantTenant || named != tc.wantNamed {
+				t.Errorf("unexpected tenant: got %q named %v, want %q named %v", tenant, named, tc.wantTenant, tc.wantNamed)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// GetTrashHandler lists the deleted todos the user sees, the most recently
+// deleted first, each with the subtasks deleted along with it.
+func (h *Handlers) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.UseCase.GetTrash(r.Context())
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get trash: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// RestoreTodoHandler puts a deleted todo back with its subtasks and
+// responds with the todo as restored.
+func (h *Handlers) RestoreTodoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	todo, err := h.UseCase.RestoreTodo(r.Context(), id)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("restore todo %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", formatETag(todo.Version))
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// PurgeTodoHandler deletes a todo in the trash for good.
+func (h *Handlers) PurgeTodoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.UseCase.PurgeTodo(r.Context(), id); err != nil {
+		writeProblem(w, r, fmt.Errorf("purge todo %d: %w", id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
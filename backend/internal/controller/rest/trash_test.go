@@ -0,0 +1,215 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestGetTrashHandler(t *testing.T) {
+	// preparing
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	parentID := 7
+	useCaseMock := &UseCaseMock{
+		GetTrashFunc: func(ctx context.Context) ([]domain.TrashedTodo, error) {
+			return []domain.TrashedTodo{{
+				Todo:      domain.Todo{ID: 7, Title: "move", Version: 2},
+				Subtasks:  []domain.Todo{{ID: 8, Title: "pack", ParentID: &parentID}},
+				DeletedAt: at,
+			}}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/trash", nil)
+	rec := httptest.NewRecorder()
+
+	// act
+	newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+	// assert
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: got %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp []map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 1 {
+		t.Fatalf("unexpected length: got %d, want %d", len(resp), 1)
+	}
+	if resp[0]["id"] != 7.0 || resp[0]["deleted_at"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("unexpected entry: got %+v", resp[0])
+	}
+	if subtasks, _ := resp[0]["subtasks"].([]any); len(subtasks) != 1 {
+		t.Errorf("unexpected subtasks: got %+v", resp[0]["subtasks"])
+	}
+}
+
+func TestRestoreTodoHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+
+		usecaseErr error
+
+		wantCode    int
+		wantProblem string
+		wantCalls   int
+	}{
+		{
+			name:      "success",
+			url:       "/api/trash/7/restore",
+			wantCode:  http.StatusOK,
+			wantCalls: 1,
+		},
+		{
+			name:        "not in the trash -> not found",
+			url:         "/api/trash/7/restore",
+			usecaseErr:  domain.ErrTodoNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemTodoNotFound,
+			wantCalls:   1,
+		},
+		{
+			name:        "viewer -> forbidden",
+			url:         "/api/trash/7/restore",
+			usecaseErr:  fmt.Errorf("%w: viewer cannot change todos", domain.ErrForbidden),
+			wantCode:    http.StatusForbidden,
+			wantProblem: problemForbidden,
+			wantCalls:   1,
+		},
+		{
+			name:        "malformed id -> error",
+			url:         "/api/trash/seven/restore",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			wantCalls:   0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				RestoreTodoFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+					if tc.usecaseErr != nil {
+						return domain.Todo{}, tc.usecaseErr
+					}
+					return domain.Todo{ID: id, Title: "move", Version: 3}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, tc.url, nil)
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if useCaseMock.RestoreTodoCalls != tc.wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", useCaseMock.RestoreTodoCalls, tc.wantCalls)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+
+			if useCaseMock.LastGetID != 7 {
+				t.Errorf("unexpected id: got %d, want %d", useCaseMock.LastGetID, 7)
+			}
+			if etag := rec.Header().Get("ETag"); etag != `"3"` {
+				t.Errorf("unexpected etag: got %s, want %s", etag, `"3"`)
+			}
+
+			var resp domain.Todo
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.ID != 7 || resp.Version != 3 {
+				t.Errorf("unexpected todo: got %+v", resp)
+			}
+		})
+	}
+}
+
+func TestPurgeTodoHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+
+		usecaseErr error
+
+		wantCode    int
+		wantProblem string
+		wantCalls   int
+	}{
+		{
+			name:      "success",
+			url:       "/api/trash/7",
+			wantCode:  http.StatusNoContent,
+			wantCalls: 1,
+		},
+		{
+			name:        "not in the trash -> not found",
+			url:         "/api/trash/7",
+			usecaseErr:  domain.ErrTodoNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemTodoNotFound,
+			wantCalls:   1,
+		},
+		{
+			name:        "malformed id -> error",
+			url:         "/api/trash/seven",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			wantCalls:   0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			useCaseMock := &UseCaseMock{
+				PurgeTodoFunc: func(ctx context.Context, id int) error {
+					return tc.usecaseErr
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, tc.url, nil)
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if useCaseMock.PurgeTodoCalls != tc.wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", useCaseMock.PurgeTodoCalls, tc.wantCalls)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+
+			if useCaseMock.LastGetID != 7 {
+				t.Errorf("unexpected id: got %d, want %d", useCaseMock.LastGetID, 7)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// UndoHandler reverts the last change of the user and responds with the
+// todos it changed back, each before and after.
+func (h *Handlers) UndoHandler(w http.ResponseWriter, r *http.Request) {
+	h.applyCommand(w, r, "undo", h.UseCase.Undo)
+}
+
+// RedoHandler makes the last undone change again, see UndoHandler.
+func (h *Handlers) RedoHandler(w http.ResponseWriter, r *http.Request) {
+	h.applyCommand(w, r, "redo", h.UseCase.Redo)
+}
+
+func (h *Handlers) applyCommand(w http.ResponseWriter, r *http.Request, name string, apply func(ctx context.Context) (domain.Command, error)) {
+	cmd, err := apply(r.Context())
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("%s: %w", name, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cmd); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestUndoRedoHandlers(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+
+		usecaseErr error
+
+		wantCode    int
+		wantProblem string
+		wantUndo    int
+		wantRedo    int
+	}{
+		{
+			name:     "undo",
+			url:      "/api/undo",
+			wantCode: http.StatusOK,
+			wantUndo: 1,
+		},
+		{
+			name:     "redo",
+			url:      "/api/redo",
+			wantCode: http.StatusOK,
+			wantRedo: 1,
+		},
+		{
+			name:        "nothing to undo -> conflict",
+			url:         "/api/undo",
+			usecaseErr:  domain.ErrNothingToUndo,
+			wantCode:    http.StatusConflict,
+			wantProblem: problemNothingToUndo,
+			wantUndo:    1,
+		},
+		{
+			name:        "nothing to redo -> conflict",
+			url:         "/api/redo",
+			usecaseErr:  domain.ErrNothingToRedo,
+			wantCode:    http.StatusConflict,
+			wantProblem: problemNothingToUndo,
+			wantRedo:    1,
+		},
+		{
+			name:        "changed since -> conflict",
+			url:         "/api/undo",
+			usecaseErr:  fmt.Errorf("%w: todo 7", domain.ErrUndoConflict),
+			wantCode:    http.StatusConflict,
+			wantProblem: problemUndoConflict,
+			wantUndo:    1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			apply := func(ctx context.Context) (domain.Command, error) {
+				if tc.usecaseErr != nil {
+					return domain.Command{}, tc.usecaseErr
+				}
+				return domain.Command{Steps: []domain.CommandStep{{
+					ID:     7,
+					Before: &domain.Todo{ID: 7, Title: "move", Completed: true, Version: 2},
+					After:  &domain.Todo{ID: 7, Title: "move", Version: 3},
+				}}}, nil
+			}
+			useCaseMock := &UseCaseMock{UndoFunc: apply, RedoFunc: apply}
+
+			req := httptest.NewRequest(http.MethodPost, tc.url, nil)
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(useCaseMock).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+
+			if useCaseMock.UndoCalls != tc.wantUndo || useCaseMock.RedoCalls != tc.wantRedo {
+				t.Errorf("unexpected calls: got %d undo and %d redo, want %d and %d",
+					useCaseMock.UndoCalls, useCaseMock.RedoCalls, tc.wantUndo, tc.wantRedo)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+
+			var resp domain.Command
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.Steps) != 1 || resp.Steps[0].ID != 7 || resp.Steps[0].After == nil || resp.Steps[0].After.Version != 3 {
+				t.Errorf("unexpected command: got %+v", resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// CreateWebhookHandler subscribes a URL to the changes of todos. The
+// response is the only one that carries the secret.
+func (h *Handlers) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var hook struct {
+		domain.Webhook
+		Secret string `json:"secret"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: decode webhook: %w", errMalformedRequest, err))
+		return
+	}
+	hook.Webhook.Secret = hook.Secret
+
+	created, err := h.UseCase.CreateWebhook(r.Context(), hook.Webhook)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("create webhook: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/webhooks/%d", created.ID))
+	// secrets must not end up in shared caches
+	w.Header().Set("Cache-Control", "no-store")
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+
+	slog.Info("webhook created", "id", created.ID, "user_id", created.UserID, "events", created.Events)
+}
+
+func (h *Handlers) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.UseCase.GetWebhooks(r.Context())
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get webhooks: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(hooks); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (h *Handlers) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.UseCase.DeleteWebhookByID(r.Context(), id); err != nil {
+		writeProblem(w, r, fmt.Errorf("delete webhook %d: %w", id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	slog.Info("webhook deleted", "id", id)
+}
+
+// GetWebhookDeliveriesHandler responds with the delivery log of a webhook,
+// newest first, every attempt included.
+func (h *Handlers) GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	deliveries, err := h.UseCase.GetWebhookDeliveries(r.Context(), id)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get deliveries of webhook %d: %w", id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(deliveries); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// GetDeadLettersHandler responds with the deliveries to the webhooks of the
+// user that ran out of attempts.
+func (h *Handlers) GetDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.UseCase.GetDeadLetters(r.Context())
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("get dead letters: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(deliveries); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// RedeliverWebhookHandler sends a dead letter again. The delivery runs in
+// the background, its outcome shows up in the log.
+func (h *Handlers) RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	deliveryID, err := pathInt(r, "deliveryID")
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.UseCase.RedeliverWebhook(r.Context(), id, deliveryID); err != nil {
+		writeProblem(w, r, fmt.Errorf("redeliver to webhook %d: %w", id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+
+	slog.Info("webhook delivery retried", "id", deliveryID, "webhook_id", id)
+}
@@ -0,0 +1,225 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestWebhookRoutes(t *testing.T) {
+	created := time.Date(2030, time.January, 2, 12, 0, 0, 0, time.UTC)
+	hook := domain.Webhook{
+		ID:        7,
+		UserID:    testUser.ID,
+		URL:       "https://example.com/hook",
+		Events:    []domain.TodoEventType{domain.TodoCompleted},
+		Secret:    "whsec_0123456789abcdef",
+		CreatedAt: created,
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+
+		usecaseErr error
+
+		calls func(m *UseCaseMock) int
+
+		wantCode    int
+		wantProblem string
+		wantCalls   int
+	}{
+		{
+			name:      "create",
+			method:    http.MethodPost,
+			url:       "/api/webhooks",
+			body:      `{"url":"https://example.com/hook","events":["todo.completed"],"secret":"whsec_0123456789abcdef"}`,
+			wantCode:  http.StatusCreated,
+			calls:     func(m *UseCaseMock) int { return m.CreateWebhookCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "create malformed body -> error",
+			method:      http.MethodPost,
+			url:         "/api/webhooks",
+			body:        `{"url":`,
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *UseCaseMock) int { return m.CreateWebhookCalls },
+		},
+		{
+			name:        "create invalid url -> error",
+			method:      http.MethodPost,
+			url:         "/api/webhooks",
+			body:        `{"url":"ftp://example.com"}`,
+			usecaseErr:  domain.ValidationErrors{{Field: "url", Err: domain.ErrInvalidFormat}},
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemValidationFailed,
+			calls:       func(m *UseCaseMock) int { return m.CreateWebhookCalls },
+			wantCalls:   1,
+		},
+		{
+			name:      "list",
+			method:    http.MethodGet,
+			url:       "/api/webhooks",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.GetWebhooksCalls },
+			wantCalls: 1,
+		},
+		{
+			name:      "delete",
+			method:    http.MethodDelete,
+			url:       "/api/webhooks/7",
+			wantCode:  http.StatusNoContent,
+			calls:     func(m *UseCaseMock) int { return m.DeleteWebhookByIDCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "delete missing webhook -> not found",
+			method:      http.MethodDelete,
+			url:         "/api/webhooks/7",
+			usecaseErr:  domain.ErrWebhookNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemWebhookNotFound,
+			calls:       func(m *UseCaseMock) int { return m.DeleteWebhookByIDCalls },
+			wantCalls:   1,
+		},
+		{
+			name:        "delete invalid id -> error",
+			method:      http.MethodDelete,
+			url:         "/api/webhooks/abc",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *UseCaseMock) int { return m.DeleteWebhookByIDCalls },
+		},
+		{
+			name:      "deliveries",
+			method:    http.MethodGet,
+			url:       "/api/webhooks/7/deliveries",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.GetWebhookDeliveriesCalls },
+			wantCalls: 1,
+		},
+		{
+			name:      "dead letters",
+			method:    http.MethodGet,
+			url:       "/api/webhooks/dead-letters",
+			wantCode:  http.StatusOK,
+			calls:     func(m *UseCaseMock) int { return m.GetDeadLettersCalls },
+			wantCalls: 1,
+		},
+		{
+			name:      "redeliver",
+			method:    http.MethodPost,
+			url:       "/api/webhooks/7/deliveries/3/redeliver",
+			wantCode:  http.StatusAccepted,
+			calls:     func(m *UseCaseMock) int { return m.RedeliverWebhookCalls },
+			wantCalls: 1,
+		},
+		{
+			name:        "redeliver missing delivery -> not found",
+			method:      http.MethodPost,
+			url:         "/api/webhooks/7/deliveries/3/redeliver",
+			usecaseErr:  domain.ErrDeliveryNotExist,
+			wantCode:    http.StatusNotFound,
+			wantProblem: problemDeliveryNotFound,
+			calls:       func(m *UseCaseMock) int { return m.RedeliverWebhookCalls },
+			wantCalls:   1,
+		},
+		{
+			name:        "redeliver invalid delivery id -> error",
+			method:      http.MethodPost,
+			url:         "/api/webhooks/7/deliveries/abc/redeliver",
+			wantCode:    http.StatusBadRequest,
+			wantProblem: problemMalformedRequest,
+			calls:       func(m *UseCaseMock) int { return m.RedeliverWebhookCalls },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			delivery := domain.WebhookDelivery{ID: 3, WebhookID: hook.ID, Status: domain.DeliveryFailed, Payload: []byte(`{"secret":"payload"}`)}
+			usecase := &UseCaseMock{
+				CreateWebhookFunc: func(ctx context.Context, h domain.Webhook) (domain.CreatedWebhook, error) {
+					return domain.CreatedWebhook{Webhook: hook, Secret: hook.Secret}, tc.usecaseErr
+				},
+				GetWebhooksFunc: func(ctx context.Context) ([]domain.Webhook, error) {
+					return []domain.Webhook{hook}, tc.usecaseErr
+				},
+				DeleteWebhookByIDFunc: func(ctx context.Context, id int) error {
+					return tc.usecaseErr
+				},
+				GetWebhookDeliveriesFunc: func(ctx context.Context, id int) ([]domain.WebhookDelivery, error) {
+					return []domain.WebhookDelivery{delivery}, tc.usecaseErr
+				},
+				GetDeadLettersFunc: func(ctx context.Context) ([]domain.WebhookDelivery, error) {
+					return []domain.WebhookDelivery{delivery}, tc.usecaseErr
+				},
+				RedeliverWebhookFunc: func(ctx context.Context, id int, deliveryID int) error {
+					return tc.usecaseErr
+				},
+			}
+
+			req := httptest.NewRequest(tc.method, tc.url, bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			// act
+			newTestRouter(usecase).ServeHTTP(rec, req)
+
+			// assert
+			if rec.Code != tc.wantCode {
+				t.Fatalf("unexpected status code: got %d, want %d", rec.Code, tc.wantCode)
+			}
+			if got := tc.calls(usecase); got != tc.wantCalls {
+				t.Fatalf("unexpected calls: got %d, want %d", got, tc.wantCalls)
+			}
+
+			if tc.wantProblem != "" {
+				assertProblem(t, rec, tc.wantCode, tc.wantProblem)
+				return
+			}
+
+			switch rec.Code {
+			case http.StatusCreated:
+				if usecase.LastSavedWebhook.Secret != hook.Secret || usecase.LastSavedWebhook.URL != hook.URL {
+					t.Errorf("unexpected webhook: got %+v", usecase.LastSavedWebhook)
+				}
+				if got := rec.Header().Get("Location"); got != "/api/webhooks/7" {
+					t.Errorf("unexpected Location: got %q", got)
+				}
+				if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+					t.Errorf("unexpected Cache-Control: got %q", got)
+				}
+
+				var resp map[string]any
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if resp["secret"] != hook.Secret {
+					t.Errorf("secret must be sent on creation: got %v", resp)
+				}
+			case http.StatusNoContent, http.StatusAccepted:
+				if usecase.LastWebhookID != hook.ID {
+					t.Errorf("unexpected webhook id: got %d, want %d", usecase.LastWebhookID, hook.ID)
+				}
+				if rec.Code == http.StatusAccepted && usecase.LastDeliveryID != delivery.ID {
+					t.Errorf("unexpected delivery id: got %d, want %d", usecase.LastDeliveryID, delivery.ID)
+				}
+			default:
+				if body := rec.Body.String(); strings.Contains(body, hook.Secret) || strings.Contains(body, "payload") {
+					t.Errorf("secret and payload must not be sent: got %s", body)
+				}
+			}
+		})
+	}
+}
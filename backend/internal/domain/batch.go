@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MaxBatchOperations caps the operations of a batch.
+const MaxBatchOperations = 100
+
+// BatchOp is what an operation of a batch does with a todo.
+type BatchOp string
+
+const (
+	BatchCreate   BatchOp = "create"
+	BatchUpdate   BatchOp = "update"
+	BatchDelete   BatchOp = "delete"
+	BatchComplete BatchOp = "complete"
+)
+
+// Batch is a list of changes sent at once. An atomic batch is stored all
+// or nothing; otherwise every operation stands on its own.
+type Batch struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation is a single change of a batch. Create takes the new todo
+// in Todo, update a JSON Merge Patch in Patch; the others need only the id.
+// A non-zero version must match the stored one.
+type BatchOperation struct {
+	Op      BatchOp         `json:"op"`
+	ID      int             `json:"id,omitempty"`
+	Version int             `json:"version,omitempty"`
+	Todo    *Todo           `json:"todo,omitempty"`
+	Patch   json.RawMessage `json:"patch,omitempty"`
+}
+
+// BatchResult is the outcome of an operation: the todo as stored, without
+// one for a deleted todo, or the error.
+type BatchResult struct {
+	ID   int
+	Todo *Todo
+	Err  error
+}
+
+// Validate checks that every operation has what it needs. The todos and
+// patches themselves are validated when the operation runs.
+func (b Batch) Validate() error {
+	var v validator
+
+	switch {
+	case len(b.Operations) == 0:
+		v.add("operations", ErrRequired, 0)
+	case len(b.Operations) > MaxBatchOperations:
+		// the operations of a batch that big are not worth reporting
+		v.add("operations", ErrTooMany, MaxBatchOperations)
+		return v.err()
+	}
+
+	for i, op := range b.Operations {
+		field := fmt.Sprintf("operations[%d].", i)
+
+		switch op.Op {
+		case BatchCreate:
+			if op.Todo == nil {
+				v.add(field+"todo", ErrRequired, 0)
+			}
+		case BatchUpdate:
+			if len(op.Patch) == 0 {
+				v.add(field+"patch", ErrRequired, 0)
+			}
+		case BatchDelete, BatchComplete:
+		default:
+			v.add(field+"op", ErrUnknownValue, 0)
+			continue
+		}
+
+		if op.Op != BatchCreate && op.ID <= 0 {
+			v.add(field+"id", ErrRequired, 0)
+		}
+	}
+
+	return v.err()
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestValidateBatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		batch Batch
+
+		wantErrs []FieldError
+	}{
+		{
+			name: "valid",
+			batch: Batch{Operations: []BatchOperation{
+				{Op: BatchCreate, Todo: &Todo{Title: "read"}},
+				{Op: BatchUpdate, ID: 1, Patch: json.RawMessage(`{"title":"write"}`)},
+				{Op: BatchComplete, ID: 2, Version: 3},
+				{Op: BatchDelete, ID: 3},
+			}},
+		},
+		{name: "no operations", batch: Batch{}, wantErrs: []FieldError{{Field: "operations", Err: ErrRequired}}},
+		{
+			name:     "too many operations",
+			batch:    Batch{Operations: make([]BatchOperation, MaxBatchOperations+1)},
+			wantErrs: []FieldError{{Field: "operations", Err: ErrTooMany, Limit: MaxBatchOperations}},
+		},
+		{
+			name: "every operation is reported",
+			batch: Batch{Operations: []BatchOperation{
+				{Op: BatchCreate},
+				{Op: BatchUpdate, ID: 1},
+				{Op: BatchDelete},
+				{Op: "archive", ID: 4},
+			}},
+			wantErrs: []FieldError{
+				{Field: "operations[0].todo", Err: ErrRequired},
+				{Field: "operations[1].patch", Err: ErrRequired},
+				{Field: "operations[2].id", Err: ErrRequired},
+				{Field: "operations[3].op", Err: ErrUnknownValue},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			err := tc.batch.Validate()
+
+			// assert
+			if len(tc.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("unexpected error type: got %T, want %T", err, verrs)
+			}
+			if !slices.Equal(verrs, tc.wantErrs) {
+				t.Errorf("unexpected errors: got %+v, want %+v", verrs, tc.wantErrs)
+			}
+		})
+	}
+}
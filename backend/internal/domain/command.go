@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// CommandOwner is the user a command belongs to, in the workspace it was
+// done in. UserID is zero without authentication.
+type CommandOwner struct {
+	Tenant Tenant
+	UserID int
+}
+
+// Command is a change made by a user, kept to be undone and redone. Its
+// steps are the todos it changed, in the order they were changed, with
+// everything that followed from it such as the next occurrence of a
+// recurring todo.
+type Command struct {
+	Steps []CommandStep `json:"steps"`
+	At    time.Time     `json:"at"`
+}
+
+// CommandStep is a todo as it was before and after the change. Before is
+// nil for a created todo, After for a deleted one; a deleted todo took its
+// subtasks to the trash along with it.
+type CommandStep struct {
+	ID     int   `json:"id"`
+	Before *Todo `json:"before"`
+	After  *Todo `json:"after"`
+}
+
+// Inverse returns the command that reverts this one: the steps go back in
+// the reverse order, each from its after to its before state.
+func (c Command) Inverse() Command {
+	steps := make([]CommandStep, 0, len(c.Steps))
+	for _, step := range slices.Backward(c.Steps) {
+		steps = append(steps, CommandStep{ID: step.ID, Before: step.After, After: step.Before})
+	}
+	return Command{Steps: steps, At: c.At}
+}
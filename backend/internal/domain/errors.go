@@ -5,4 +5,52 @@ import "errors"
 var (
 	ErrNoTitle      = errors.New("title is empty")
 	ErrTodoNotExist = errors.New("todo with specified id does not exist")
+	ErrInvalidPatch = errors.New("invalid patch document")
+
+	ErrVersionMismatch = errors.New("todo version does not match")
+
+	ErrInvalidQuery = errors.New("invalid todo query")
+
+	ErrHistoryNotKept = errors.New("todo history is not kept")
+
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+	ErrUndoConflict  = errors.New("todo was changed since")
+
+	ErrBatchAborted   = errors.New("batch aborted by another operation")
+	ErrNoTransactions = errors.New("transactions are not supported")
+
+	ErrIdempotencyKeyInUse    = errors.New("request with this idempotency key is in progress")
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was used for another request")
+	ErrIdempotentResponseLost = errors.New("response to the idempotency key was not kept")
+
+	ErrTagNotExist = errors.New("tag with specified id does not exist")
+	ErrTagExists   = errors.New("tag with this name already exists")
+
+	ErrParentNotExist = errors.New("parent todo does not exist")
+	ErrParentCycle    = errors.New("todo cannot be a subtask of itself or of its subtasks")
+	ErrOpenSubtasks   = errors.New("todo has open subtasks")
+
+	ErrUserExists         = errors.New("user with this email already exists")
+	ErrUserNotExist       = errors.New("user with specified id does not exist")
+	ErrSessionNotExist    = errors.New("session does not exist")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrUnauthenticated    = errors.New("authentication required")
+
+	ErrAPIKeyNotExist    = errors.New("api key with specified id does not exist")
+	ErrInsufficientScope = errors.New("api key lacks the required scope")
+
+	ErrListNotExist       = errors.New("list with specified id does not exist")
+	ErrListNotEmpty       = errors.New("list still has todos")
+	ErrForbidden          = errors.New("not allowed for the role in the list")
+	ErrMemberNotExist     = errors.New("member does not exist")
+	ErrMemberExists       = errors.New("user is already a member or invited")
+	ErrInvitationNotExist = errors.New("invitation does not exist")
+	ErrTooManyMembers     = errors.New("list has too many members")
+
+	ErrWebhookNotExist  = errors.New("webhook with specified id does not exist")
+	ErrDeliveryNotExist = errors.New("webhook delivery with specified id does not exist")
+
+	ErrInvalidTenant  = errors.New("invalid workspace name")
+	ErrTenantNotExist = errors.New("workspace does not exist")
 )
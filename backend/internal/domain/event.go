@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// TodoEventType tells what happened to a todo.
+type TodoEventType string
+
+const (
+	TodoCreated TodoEventType = "todo.created"
+	TodoUpdated TodoEventType = "todo.updated"
+	// TodoCompleted follows TodoUpdated when the change completed the todo.
+	TodoCompleted TodoEventType = "todo.completed"
+	TodoDeleted   TodoEventType = "todo.deleted"
+	// TodoEventsReset tells a subscriber that the events it asked to resume
+	// from are gone: it has to read the todos again.
+	TodoEventsReset TodoEventType = "reset"
+)
+
+// TodoEvent is a stored change of a todo. IDs grow with every event, so a
+// subscriber resumes from the last one it has seen. Todo is the todo after
+// the change, or before it for TodoDeleted. ActorID is the user who made
+// the change, zero when it was made without one.
+type TodoEvent struct {
+	ID      uint64        `json:"id"`
+	Type    TodoEventType `json:"type"`
+	Tenant  Tenant        `json:"tenant,omitempty"`
+	Todo    Todo          `json:"todo"`
+	ActorID int           `json:"actor_id,omitempty"`
+	At      time.Time     `json:"at"`
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// TodoChangeType tells what a change in the history of a todo did.
+type TodoChangeType string
+
+const (
+	TodoChangeCreated TodoChangeType = "created"
+	TodoChangeUpdated TodoChangeType = "updated"
+	TodoChangeDeleted TodoChangeType = "deleted"
+	// TodoChangeRestored brings a deleted todo back from the trash, and
+	// TodoChangePurged removes it from there for good.
+	TodoChangeRestored TodoChangeType = "restored"
+	TodoChangePurged   TodoChangeType = "purged"
+)
+
+// TodoChange is an entry in the history of a todo. Todo is the todo after
+// the change, or before it for TodoChangeDeleted. ActorID is the user who
+// made the change, zero when it was made without one, as by a background
+// job.
+type TodoChange struct {
+	Type    TodoChangeType `json:"type"`
+	Todo    Todo           `json:"todo"`
+	ActorID int            `json:"actor_id,omitempty"`
+	At      time.Time      `json:"at"`
+}
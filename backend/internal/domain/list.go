@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	MaxListNameLength = 100
+	// MaxListMembers caps the members and pending invitations of a list.
+	MaxListMembers = 50
+)
+
+// Role is what a member may do with a list and its todos.
+type Role string
+
+const (
+	// RoleOwner manages the list, its members and invitations.
+	RoleOwner Role = "owner"
+	// RoleEditor creates, changes and deletes the todos of the list.
+	RoleEditor Role = "editor"
+	// RoleViewer only reads the todos of the list.
+	RoleViewer Role = "viewer"
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleOwner, RoleEditor, RoleViewer:
+		return true
+	}
+	return false
+}
+
+// Allows reports whether the role grants everything the other one does.
+func (r Role) Allows(other Role) bool {
+	return r.rank() >= other.rank()
+}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// List groups todos and shares them with its members. The list is stored
+// together with its members and pending invitations.
+type List struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Version grows by one on every change of the list, its members or
+	// invitations.
+	Version int `json:"version"`
+	// Members include the owner, ordered by the time they joined.
+	Members []Member `json:"members"`
+	// Invitations are shown to the owner only.
+	Invitations []Invitation `json:"invitations,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Member struct {
+	UserID   int       `json:"user_id"`
+	Email    string    `json:"email"`
+	Role     Role      `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// Invitation lets the user with the email join the list with the role.
+type Invitation struct {
+	Email     string    `json:"email"`
+	Role      Role      `json:"role"`
+	InvitedBy int       `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PendingInvitation is an invitation as seen by the invited user.
+type PendingInvitation struct {
+	ListID   int    `json:"list_id"`
+	ListName string `json:"list_name"`
+	Invitation
+}
+
+// Validate checks the fields a client chooses, only the name.
+func (l List) Validate() error {
+	var v validator
+
+	switch {
+	case l.Name == "":
+		v.add("name", ErrRequired, 0)
+	case strings.TrimSpace(l.Name) == "":
+		v.add("name", ErrBlank, 0)
+	default:
+		v.text("name", l.Name, MaxListNameLength, false)
+	}
+
+	return v.err()
+}
+
+// Validate checks an invitation sent by a client. The owner role is not
+// handed out, every list has exactly one owner.
+func (i Invitation) Validate() error {
+	var v validator
+
+	switch {
+	case i.Email == "":
+		v.add("email", ErrRequired, 0)
+	case len(i.Email) > MaxEmailLength:
+		v.add("email", ErrTooLong, MaxEmailLength)
+	case !validEmail(i.Email):
+		v.add("email", ErrInvalidFormat, 0)
+	}
+
+	validateMemberRole(&v, i.Role)
+
+	return v.err()
+}
+
+// ValidateMemberRole checks a role given to a member by the owner.
+func ValidateMemberRole(role Role) error {
+	var v validator
+	validateMemberRole(&v, role)
+	return v.err()
+}
+
+func validateMemberRole(v *validator, role Role) {
+	switch {
+	case role == "":
+		v.add("role", ErrRequired, 0)
+	case role != RoleEditor && role != RoleViewer:
+		v.add("role", ErrUnknownValue, 0)
+	}
+}
+
+// RoleOf returns the role of the user in the list, false for non-members.
+func (l List) RoleOf(userID int) (Role, bool) {
+	i := slices.IndexFunc(l.Members, func(m Member) bool { return m.UserID == userID })
+	if i < 0 {
+		return "", false
+	}
+	return l.Members[i].Role, true
+}
+
+// Invited returns the index of the invitation for the email, -1 if there
+// is none.
+func (l List) Invited(email string) int {
+	return slices.IndexFunc(l.Invitations, func(i Invitation) bool { return i.Email == email })
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestValidateInvitation(t *testing.T) {
+	tests := []struct {
+		name string
+		inv  Invitation
+
+		wantErrs []FieldError
+	}{
+		{name: "editor", inv: Invitation{Email: "bob@example.com", Role: RoleEditor}},
+		{name: "viewer", inv: Invitation{Email: "bob@example.com", Role: RoleViewer}},
+		{
+			name:     "owner is not handed out",
+			inv:      Invitation{Email: "bob@example.com", Role: RoleOwner},
+			wantErrs: []FieldError{{Field: "role", Err: ErrUnknownValue}},
+		},
+		{
+			name: "every field is reported",
+			inv:  Invitation{Email: "bob"},
+			wantErrs: []FieldError{
+				{Field: "email", Err: ErrInvalidFormat},
+				{Field: "role", Err: ErrRequired},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			err := tc.inv.Validate()
+
+			// assert
+			if len(tc.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("unexpected error type: got %T, want %T", err, verrs)
+			}
+			if !slices.Equal(verrs, tc.wantErrs) {
+				t.Errorf("unexpected errors: got %+v, want %+v", verrs, tc.wantErrs)
+			}
+		})
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role  Role
+		other Role
+		want  bool
+	}{
+		{RoleOwner, RoleEditor, true},
+		{RoleEditor, RoleEditor, true},
+		{RoleViewer, RoleEditor, false},
+		{RoleViewer, RoleViewer, true},
+		{"", RoleViewer, false},
+	}
+
+	for _, tc := range tests {
+		if got := tc.role.Allows(tc.other); got != tc.want {
+			t.Errorf("%q allows %q: got %t, want %t", tc.role, tc.other, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package domain
+
+import "fmt"
+
+// Priority is stored as a number, so todos can be ordered by it, and
+// rendered as a word in JSON.
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
+var priorityNames = [...]string{
+	PriorityNone:   "none",
+	PriorityLow:    "low",
+	PriorityMedium: "medium",
+	PriorityHigh:   "high",
+}
+
+func (p Priority) Valid() bool {
+	return p >= PriorityNone && p <= PriorityHigh
+}
+
+func (p Priority) String() string {
+	if !p.Valid() {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+func ParsePriority(s string) (Priority, error) {
+	for p, name := range priorityNames {
+		if s == name {
+			return Priority(p), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown priority %q", s)
+}
+
+func (p Priority) MarshalText() ([]byte, error) {
+	if !p.Valid() {
+		return nil, fmt.Errorf("unknown priority %d", int(p))
+	}
+	return []byte(priorityNames[p]), nil
+}
+
+func (p *Priority) UnmarshalText(text []byte) error {
+	v, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
@@ -0,0 +1,257 @@
+package domain
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"strings"
+	"time"
+)
+
+type TodoSort string
+
+const (
+	SortByID        TodoSort = "id"
+	SortByTitle     TodoSort = "title"
+	SortByCompleted TodoSort = "completed"
+	SortByPriority  TodoSort = "priority"
+	// todos without a due date go after every dated one
+	SortByDue TodoSort = "due_at"
+)
+
+func (s TodoSort) Valid() bool {
+	switch s {
+	case SortByID, SortByTitle, SortByCompleted, SortByPriority, SortByDue:
+		return true
+	}
+	return false
+}
+
+// Compare orders todos by the sort field, ties are broken by id so the
+// order is total and can be used for keyset pagination.
+func (s TodoSort) Compare(a, b Todo) int {
+	var c int
+	switch s {
+	case SortByTitle:
+		c = strings.Compare(a.Title, b.Title)
+	case SortByCompleted:
+		c = compareBool(a.Completed, b.Completed)
+	case SortByPriority:
+		c = cmp.Compare(a.Priority, b.Priority)
+	case SortByDue:
+		c = compareDue(a.DueAt, b.DueAt)
+	}
+	if c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// Pivot keeps only the fields Compare looks at. It is what a cursor
+// remembers about the last todo of a page.
+func (s TodoSort) Pivot(t Todo) Todo {
+	p := Todo{ID: t.ID}
+	switch s {
+	case SortByTitle:
+		p.Title = t.Title
+	case SortByCompleted:
+		p.Completed = t.Completed
+	case SortByPriority:
+		p.Priority = t.Priority
+	case SortByDue:
+		p.DueAt = t.DueAt
+	}
+	return p
+}
+
+type TodoFilter struct {
+	Completed *bool
+	// substrings, matched case-insensitively
+	Title       string
+	Description string
+	// Search matches either the title or the description
+	Search string
+
+	Priority *Priority
+	// Tags selects todos having any of the tag ids, or all of them when
+	// AllTags is set.
+	Tags    []int
+	AllTags bool
+	// TagNames are resolved into Tags by the use case before the query
+	// reaches a repository.
+	TagNames []string
+	// ParentID selects the direct subtasks of a todo.
+	ParentID *int
+	// OwnerID selects the todos of a user. The use case sets it from the
+	// authenticated user.
+	OwnerID *int
+	// ListID selects the todos of a list.
+	ListID *int
+	// Visible selects the todos a user may read. The use case sets it
+	// from the authenticated user.
+	Visible *Visibility
+	// DueFrom and DueBefore select todos due in [DueFrom, DueBefore);
+	// setting either of them drops todos without a due date.
+	DueFrom   *time.Time
+	DueBefore *time.Time
+}
+
+func (f TodoFilter) Match(t Todo) bool {
+	if f.Completed != nil && t.Completed != *f.Completed {
+		return false
+	}
+	if f.Title != "" && !containsFold(t.Title, f.Title) {
+		return false
+	}
+	if f.Description != "" && !containsFold(t.Description, f.Description) {
+		return false
+	}
+	if f.Search != "" && !containsFold(t.Title, f.Search) && !containsFold(t.Description, f.Search) {
+		return false
+	}
+	if f.Priority != nil && t.Priority != *f.Priority {
+		return false
+	}
+	if len(f.Tags) > 0 && !f.matchTags(t.Tags) {
+		return false
+	}
+	if f.ParentID != nil && (t.ParentID == nil || *t.ParentID != *f.ParentID) {
+		return false
+	}
+	if f.OwnerID != nil && t.OwnerID != *f.OwnerID {
+		return false
+	}
+	if f.ListID != nil && (t.ListID == nil || *t.ListID != *f.ListID) {
+		return false
+	}
+	if f.Visible != nil && !f.Visible.Match(t) {
+		return false
+	}
+	if f.DueFrom != nil || f.DueBefore != nil {
+		if t.DueAt == nil {
+			return false
+		}
+		if f.DueFrom != nil && t.DueAt.Before(*f.DueFrom) {
+			return false
+		}
+		if f.DueBefore != nil && !t.DueAt.Before(*f.DueBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// Visibility is what a user may read: their own todos outside of lists
+// and every todo of the lists they are a member of.
+type Visibility struct {
+	UserID  int
+	ListIDs []int
+}
+
+func (v Visibility) Match(t Todo) bool {
+	if t.ListID == nil {
+		return t.OwnerID == v.UserID
+	}
+	return slices.Contains(v.ListIDs, *t.ListID)
+}
+
+type TodoQuery struct {
+	Filter TodoFilter
+	Sort   TodoSort
+	Desc   bool
+	// After is the pivot of the last todo already returned; only todos
+	// strictly after it in the requested order are selected.
+	After *Todo
+	// Limit of zero means no limit.
+	Limit int
+	// AsOf selects the todos as they were at that time; zero means now.
+	// Only a repository keeping the history can read past states.
+	AsOf time.Time
+}
+
+// OrderBy is the sort field with the default applied.
+func (q TodoQuery) OrderBy() TodoSort {
+	if q.Sort == "" {
+		return SortByID
+	}
+	return q.Sort
+}
+
+// Compare orders todos in the requested direction.
+func (q TodoQuery) Compare(a, b Todo) int {
+	c := q.OrderBy().Compare(a, b)
+	if q.Desc {
+		return -c
+	}
+	return c
+}
+
+// Apply runs the query over an unordered set of todos. It is meant for
+// repositories that keep everything in memory: only matching todos are
+// copied.
+func (q TodoQuery) Apply(todos iter.Seq[Todo]) []Todo {
+	res := make([]Todo, 0)
+	for t := range todos {
+		if !q.Filter.Match(t) {
+			continue
+		}
+		if q.After != nil && q.Compare(t, *q.After) <= 0 {
+			continue
+		}
+		res = append(res, t)
+	}
+
+	slices.SortFunc(res, q.Compare)
+
+	if q.Limit > 0 && len(res) > q.Limit {
+		res = res[:q.Limit]
+	}
+
+	return res
+}
+
+type TodoPage struct {
+	Todos []Todo
+	// NextCursor is empty on the last page
+	NextCursor string
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
+
+func (f TodoFilter) matchTags(tags []int) bool {
+	for _, id := range f.Tags {
+		has := slices.Contains(tags, id)
+		if has && !f.AllTags {
+			return true
+		}
+		if !has && f.AllTags {
+			return false
+		}
+	}
+	return f.AllTags
+}
+
+// compareDue orders a missing due date after any date.
+func compareDue(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/rrule"
+)
+
+// MaxOccurrencesPreview caps how many occurrences can be previewed at once.
+const MaxOccurrencesPreview = 100
+
+// Recurrence repeats a todo: completing an occurrence creates the next one
+// and hands the recurrence over to it.
+type Recurrence struct {
+	// Rule is an iCalendar RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO,WE".
+	Rule string `json:"rule"`
+	// Timezone is the IANA zone the rule is evaluated in, UTC when empty.
+	// Occurrences keep their wall clock time across its DST changes.
+	Timezone string `json:"timezone,omitempty"`
+	// Start is the due date of the first occurrence; COUNT and UNTIL are
+	// counted from it. The usecase takes it from due_at when not given.
+	Start time.Time `json:"start"`
+}
+
+func (r Recurrence) Equal(o Recurrence) bool {
+	return r.Rule == o.Rule && r.Timezone == o.Timezone && r.Start.Equal(o.Start)
+}
+
+func (r Recurrence) location() (*time.Location, error) {
+	if r.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(r.Timezone)
+}
+
+// Next returns the occurrence that follows the one due at due, false when
+// the rule has ended.
+func (r Recurrence) Next(due time.Time) (time.Time, bool, error) {
+	next, err := r.Upcoming(due, 1)
+	if err != nil || len(next) == 0 {
+		return time.Time{}, false, err
+	}
+	return next[0], true, nil
+}
+
+// Upcoming returns up to n occurrences after the given time.
+func (r Recurrence) Upcoming(after time.Time, n int) ([]time.Time, error) {
+	rule, err := rrule.Parse(r.Rule)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := r.location()
+	if err != nil {
+		return nil, fmt.Errorf("load time zone: %w", err)
+	}
+
+	res := []time.Time{}
+	for occ := range rule.All(r.Start.In(loc)) {
+		if len(res) == n {
+			break
+		}
+		if occ.After(after) {
+			res = append(res, occ)
+		}
+	}
+	return res, nil
+}
+
+func (r Recurrence) validate(v *validator) {
+	if _, err := rrule.Parse(r.Rule); err != nil {
+		v.add("recurrence.rule", fmt.Errorf("%w (%v)", ErrInvalidFormat, err), 0)
+	}
+	// "Local" depends on the server, not on the user
+	if _, err := r.location(); err != nil || r.Timezone == "Local" {
+		v.add("recurrence.timezone", ErrUnknownValue, 0)
+	}
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateRecurrence(t *testing.T) {
+	due := time.Date(2030, time.January, 7, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		todo       Todo
+		wantFields []string
+		wantErrs   []error
+	}{
+		{
+			name: "valid",
+			todo: Todo{Title: "water the plants", DueAt: &due, Recurrence: &Recurrence{Rule: "FREQ=WEEKLY;BYDAY=MO", Timezone: "Europe/Berlin"}},
+		},
+		{
+			name:       "unsupported rule",
+			todo:       Todo{Title: "water the plants", DueAt: &due, Recurrence: &Recurrence{Rule: "FREQ=HOURLY"}},
+			wantFields: []string{"recurrence.rule"},
+			wantErrs:   []error{ErrInvalidFormat},
+		},
+		{
+			name:       "unknown time zone",
+			todo:       Todo{Title: "water the plants", DueAt: &due, Recurrence: &Recurrence{Rule: "FREQ=DAILY", Timezone: "Mars/Olympus"}},
+			wantFields: []string{"recurrence.timezone"},
+			wantErrs:   []error{ErrUnknownValue},
+		},
+		{
+			name:       "no due date",
+			todo:       Todo{Title: "water the plants", Recurrence: &Recurrence{Rule: "FREQ=DAILY"}},
+			wantFields: []string{"due_at"},
+			wantErrs:   []error{ErrRequired},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			err := tc.todo.Validate()
+
+			// assert
+			if len(tc.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) || len(verrs) != len(tc.wantFields) {
+				t.Fatalf("unexpected error: got %v, want errors of %v", err, tc.wantFields)
+			}
+			for i, field := range tc.wantFields {
+				if verrs[i].Field != field || !errors.Is(verrs[i], tc.wantErrs[i]) {
+					t.Errorf("unexpected error: got %v, want %s: %v", verrs[i], field, tc.wantErrs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+
+	// preparing
+	start := time.Date(2030, time.March, 29, 9, 0, 0, 0, berlin)
+	r := Recurrence{Rule: "FREQ=DAILY;COUNT=3", Timezone: "Europe/Berlin", Start: start.UTC()}
+
+	// act
+	second, ok2, err2 := r.Next(start)
+	third, ok3, err3 := r.Next(second)
+	_, ok4, err4 := r.Next(third)
+
+	// assert
+	if err := errors.Join(err2, err3, err4); err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	// 2030-03-31 is the first day of summer time
+	if want := time.Date(2030, time.March, 31, 9, 0, 0, 0, berlin); !ok3 || !third.Equal(want) {
+		t.Errorf("unexpected third occurrence: got %v, want %v", third, want)
+	}
+	if !ok2 || second.Hour() != 9 {
+		t.Errorf("unexpected second occurrence: got %v", second)
+	}
+	if ok4 {
+		t.Errorf("the series must end after COUNT occurrences")
+	}
+}
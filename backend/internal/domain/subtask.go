@@ -0,0 +1,33 @@
+package domain
+
+// MaxChecklistItems caps the checklist of a single todo.
+const MaxChecklistItems = 100
+
+// ChecklistItem is a step of a todo too small to be a subtask of its own.
+type ChecklistItem struct {
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+// Progress tells how far a todo is. Percent counts the direct subtasks,
+// each weighted by its own progress, and the checklist items alike; a
+// completed todo is always at 100.
+type Progress struct {
+	TodoID            int `json:"todo_id"`
+	Percent           int `json:"percent"`
+	Subtasks          int `json:"subtasks"`
+	SubtasksCompleted int `json:"subtasks_completed"`
+	ChecklistItems    int `json:"checklist_items"`
+	ChecklistDone     int `json:"checklist_done"`
+}
+
+// SubtaskRules are the optional constraints between a todo and its
+// subtasks.
+type SubtaskRules struct {
+	// AutoCompleteParent completes the parent once its last open subtask
+	// is completed, all the way up.
+	AutoCompleteParent bool
+	// BlockOpenSubtasks rejects completing a todo while any of its direct
+	// subtasks is open.
+	BlockOpenSubtasks bool
+}
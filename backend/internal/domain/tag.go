@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const MaxTagNameLength = 50
+
+var tagColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Tag is a label shared by many todos. Names are unique regardless of
+// case.
+type Tag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Color is an optional #rrggbb hint for clients.
+	Color string `json:"color,omitempty"`
+}
+
+func (t Tag) Validate() error {
+	var v validator
+
+	switch {
+	case t.Name == "":
+		v.add("name", ErrRequired, 0)
+	case strings.TrimSpace(t.Name) == "":
+		v.add("name", ErrBlank, 0)
+	default:
+		v.text("name", t.Name, MaxTagNameLength, false)
+	}
+
+	if t.Color != "" && !tagColorRe.MatchString(t.Color) {
+		v.add("color", ErrInvalidFormat, 0)
+	}
+
+	return v.err()
+}
+
+// TagKey is the form a tag name is unique in.
+func TagKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// SameTagName reports whether two names denote the same tag.
+func SameTagName(a, b string) bool {
+	return TagKey(a) == TagKey(b)
+}
+
+// NormalizeTagIDs returns the ids sorted and without duplicates. The
+// result is never nil, so a todo without tags is rendered as [].
+func NormalizeTagIDs(ids []int) []int {
+	res := slices.Clone(ids)
+	if res == nil {
+		res = []int{}
+	}
+	slices.Sort(res)
+	return slices.Compact(res)
+}
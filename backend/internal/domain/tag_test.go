@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  Tag
+
+		wantErrs []FieldError
+	}{
+		{name: "valid", tag: Tag{Name: "work", Color: "#FF8800"}},
+		{name: "without color", tag: Tag{Name: "work"}},
+		{name: "empty name", tag: Tag{}, wantErrs: []FieldError{{Field: "name", Err: ErrRequired}}},
+		{name: "blank name", tag: Tag{Name: "  "}, wantErrs: []FieldError{{Field: "name", Err: ErrBlank}}},
+		{
+			name:     "name is too long",
+			tag:      Tag{Name: strings.Repeat("я", MaxTagNameLength+1)},
+			wantErrs: []FieldError{{Field: "name", Err: ErrTooLong, Limit: MaxTagNameLength}},
+		},
+		{
+			name: "every field is reported",
+			tag:  Tag{Name: "work\n", Color: "orange"},
+			wantErrs: []FieldError{
+				{Field: "name", Err: ErrControlCharacter},
+				{Field: "color", Err: ErrInvalidFormat},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			err := tc.tag.Validate()
+
+			// assert
+			if len(tc.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("unexpected error type: got %T, want %T", err, verrs)
+			}
+			if !slices.Equal(verrs, tc.wantErrs) {
+				t.Errorf("unexpected errors: got %+v, want %+v", verrs, tc.wantErrs)
+			}
+		})
+	}
+}
+
+func TestNormalizeTagIDs(t *testing.T) {
+	if got := NormalizeTagIDs(nil); got == nil || len(got) != 0 {
+		t.Errorf("unexpected result for nil: got %#v, want empty slice", got)
+	}
+
+	input := []int{3, 1, 3, 2}
+	if got, want := NormalizeTagIDs(input), []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("unexpected result: got %v, want %v", got, want)
+	}
+	if want := []int{3, 1, 3, 2}; !slices.Equal(input, want) {
+		t.Errorf("input must not change: got %v, want %v", input, want)
+	}
+}
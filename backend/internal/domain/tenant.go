@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Tenant names a workspace. Every todo, tag, user and list lives in exactly
+// one workspace and is invisible from the others.
+type Tenant string
+
+// DefaultTenant is the workspace of requests that do not name one. Data
+// stored before workspaces existed belongs to it.
+const DefaultTenant Tenant = "default"
+
+// MaxTenantLength keeps a workspace name usable as a subdomain.
+const MaxTenantLength = 63
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ParseTenant accepts a workspace name in any case. Names are DNS labels:
+// lowercase letters, digits and inner hyphens.
+func ParseTenant(s string) (Tenant, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) > MaxTenantLength || !tenantPattern.MatchString(s) {
+		return "", ErrInvalidTenant
+	}
+	return Tenant(s), nil
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a context scoped to the workspace.
+func ContextWithTenant(ctx context.Context, tenant Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the workspace the request named, false when it
+// did not name one.
+func TenantFromContext(ctx context.Context) (Tenant, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(Tenant)
+	return tenant, ok
+}
+
+// CurrentTenant returns the workspace repositories scope the context to:
+// the one the request named, DefaultTenant otherwise.
+func CurrentTenant(ctx context.Context) Tenant {
+	if tenant, ok := TenantFromContext(ctx); ok {
+		return tenant
+	}
+	return DefaultTenant
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseTenant(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+
+		want    Tenant
+		wantErr error
+	}{
+		{name: "slug", input: "acme-corp", want: "acme-corp"},
+		{name: "case and spaces are dropped", input: " ACME ", want: "acme"},
+		{name: "digits", input: "42", want: "42"},
+		{name: "longest", input: strings.Repeat("a", MaxTenantLength), want: Tenant(strings.Repeat("a", MaxTenantLength))},
+		{name: "empty -> error", input: "", wantErr: ErrInvalidTenant},
+		{name: "too long -> error", input: strings.Repeat("a", MaxTenantLength+1), wantErr: ErrInvalidTenant},
+		{name: "leading hyphen -> error", input: "-acme", wantErr: ErrInvalidTenant},
+		{name: "trailing hyphen -> error", input: "acme-", wantErr: ErrInvalidTenant},
+		{name: "dot -> error", input: "acme.corp", wantErr: ErrInvalidTenant},
+		{name: "underscore -> error", input: "acme_corp", wantErr: ErrInvalidTenant},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			got, err := ParseTenant(tc.input)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("unexpected tenant: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCurrentTenant(t *testing.T) {
+	// preparing
+	background := context.Background()
+
+	// act
+	scoped := ContextWithTenant(background, "acme")
+
+	// assert
+	if _, ok := TenantFromContext(background); ok {
+		t.Errorf("context without a tenant must not name one")
+	}
+	if got := CurrentTenant(background); got != DefaultTenant {
+		t.Errorf("unexpected tenant: got %q, want %q", got, DefaultTenant)
+	}
+	if got, ok := TenantFromContext(scoped); !ok || got != "acme" {
+		t.Errorf("unexpected tenant: got %q, %v, want %q, true", got, ok, "acme")
+	}
+	if got := CurrentTenant(scoped); got != "acme" {
+		t.Errorf("unexpected tenant: got %q, want %q", got, "acme")
+	}
+}
@@ -1,15 +1,135 @@
 package domain
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
 type Todo struct {
 	ID          int    `json:"id"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Completed   bool   `json:"completed"`
+	// Version grows by one on every change. A non-zero version passed to
+	// an update is the version the caller expects to overwrite.
+	Version int `json:"version"`
+
+	Priority Priority `json:"priority"`
+	// DueAt keeps the offset it was given with, so clients get back the
+	// time in the zone it was planned in.
+	DueAt *time.Time `json:"due_at,omitempty"`
+	// Tags are ids of the attached tags in ascending order.
+	Tags []int `json:"tags"`
+	// ParentID makes the todo a subtask of another one.
+	ParentID  *int            `json:"parent_id,omitempty"`
+	Checklist []ChecklistItem `json:"checklist,omitempty"`
+	// Recurrence repeats the todo, it needs a due date.
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// OwnerID is the user who created the todo, zero for todos created
+	// outside of a request. It is managed by the usecase.
+	OwnerID int `json:"owner_id,omitempty"`
+	// ListID shares the todo with the members of a list. Todos outside of
+	// lists are seen by their owner only.
+	ListID *int `json:"list_id,omitempty"`
+
+	// timestamps are maintained by the usecase, values sent by clients
+	// are ignored
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
 }
 
+// Equal reports whether two todos hold the same data. Times are compared
+// as instants, so it does not depend on how a repository stores them.
+func (t Todo) Equal(o Todo) bool {
+	return t.ID == o.ID &&
+		t.Title == o.Title &&
+		t.Description == o.Description &&
+		t.Completed == o.Completed &&
+		t.Version == o.Version &&
+		t.Priority == o.Priority &&
+		equalTimePtr(t.DueAt, o.DueAt) &&
+		slices.Equal(t.Tags, o.Tags) &&
+		equalIntPtr(t.ParentID, o.ParentID) &&
+		slices.Equal(t.Checklist, o.Checklist) &&
+		equalRecurrencePtr(t.Recurrence, o.Recurrence) &&
+		t.OwnerID == o.OwnerID &&
+		equalIntPtr(t.ListID, o.ListID) &&
+		t.CreatedAt.Equal(o.CreatedAt) &&
+		t.UpdatedAt.Equal(o.UpdatedAt) &&
+		equalTimePtr(t.CompletedAt, o.CompletedAt)
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalRecurrencePtr(a, b *Recurrence) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// Validate checks every field and returns ValidationErrors listing all
+// violations, or nil.
 func (t Todo) Validate() error {
-	if t.Title == "" {
-		return ErrNoTitle
+	var v validator
+
+	switch {
+	case t.Title == "":
+		v.add("title", ErrNoTitle, 0)
+	case strings.TrimSpace(t.Title) == "":
+		v.add("title", ErrBlank, 0)
+	default:
+		v.text("title", t.Title, MaxTitleLength, false)
+	}
+
+	v.text("description", t.Description, MaxDescriptionLength, true)
+
+	if !t.Priority.Valid() {
+		v.add("priority", ErrUnknownValue, 0)
+	}
+
+	if t.ParentID != nil && t.ID != 0 && *t.ParentID == t.ID {
+		v.add("parent_id", ErrParentCycle, 0)
+	}
+
+	if len(t.Checklist) > MaxChecklistItems {
+		// the items are not worth a violation each
+		v.add("checklist", ErrTooMany, MaxChecklistItems)
+	} else {
+		for i, item := range t.Checklist {
+			field := fmt.Sprintf("checklist[%d].title", i)
+			switch {
+			case item.Title == "":
+				v.add(field, ErrRequired, 0)
+			case strings.TrimSpace(item.Title) == "":
+				v.add(field, ErrBlank, 0)
+			default:
+				v.text(field, item.Title, MaxTitleLength, false)
+			}
+		}
 	}
-	return nil
+
+	if t.Recurrence != nil {
+		t.Recurrence.validate(&v)
+		if t.DueAt == nil {
+			v.add("due_at", ErrRequired, 0)
+		}
+	}
+
+	return v.err()
 }
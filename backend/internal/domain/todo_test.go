@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		todo Todo
+
+		wantErrs []FieldError
+	}{
+		{
+			name: "valid",
+			todo: Todo{Title: "read the book", Description: "chapter one\n\tchapter two"},
+		},
+		{
+			name:     "empty title",
+			todo:     Todo{},
+			wantErrs: []FieldError{{Field: "title", Err: ErrNoTitle}},
+		},
+		{
+			name:     "whitespace-only title",
+			todo:     Todo{Title: " \t\n"},
+			wantErrs: []FieldError{{Field: "title", Err: ErrBlank}},
+		},
+		{
+			name:     "title is too long",
+			todo:     Todo{Title: strings.Repeat("я", MaxTitleLength+1)},
+			wantErrs: []FieldError{{Field: "title", Err: ErrTooLong, Limit: MaxTitleLength}},
+		},
+		{
+			name: "title of max length in multibyte characters",
+			todo: Todo{Title: strings.Repeat("я", MaxTitleLength)},
+		},
+		{
+			name:     "newline in title",
+			todo:     Todo{Title: "read\nthe book"},
+			wantErrs: []FieldError{{Field: "title", Err: ErrControlCharacter}},
+		},
+		{
+			name:     "invalid utf-8 in title",
+			todo:     Todo{Title: "read \xff"},
+			wantErrs: []FieldError{{Field: "title", Err: ErrInvalidUTF8}},
+		},
+		{
+			name:     "description is too long",
+			todo:     Todo{Title: "read the book", Description: strings.Repeat("a", MaxDescriptionLength+1)},
+			wantErrs: []FieldError{{Field: "description", Err: ErrTooLong, Limit: MaxDescriptionLength}},
+		},
+		{
+			name: "checklist",
+			todo: Todo{Title: "move out", Checklist: []ChecklistItem{{Title: "call the landlord", Done: true}, {Title: "hand over the keys"}}},
+		},
+		{
+			name: "checklist items are validated one by one",
+			todo: Todo{Title: "move out", Checklist: []ChecklistItem{{Title: "call the landlord"}, {}, {Title: " "}}},
+			wantErrs: []FieldError{
+				{Field: "checklist[1].title", Err: ErrRequired},
+				{Field: "checklist[2].title", Err: ErrBlank},
+			},
+		},
+		{
+			name:     "checklist is too long",
+			todo:     Todo{Title: "move out", Checklist: make([]ChecklistItem, MaxChecklistItems+1)},
+			wantErrs: []FieldError{{Field: "checklist", Err: ErrTooMany, Limit: MaxChecklistItems}},
+		},
+		{
+			name:     "todo is its own parent",
+			todo:     Todo{ID: 3, Title: "move out", ParentID: &[]int{3}[0]},
+			wantErrs: []FieldError{{Field: "parent_id", Err: ErrParentCycle}},
+		},
+		{
+			name: "every field is reported",
+			todo: Todo{Description: "escape \x1b[31m"},
+			wantErrs: []FieldError{
+				{Field: "title", Err: ErrNoTitle},
+				{Field: "description", Err: ErrControlCharacter},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			err := tc.todo.Validate()
+
+			// assert
+			if len(tc.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrValidation) {
+				t.Fatalf("unexpected error: got %v, want %v", err, ErrValidation)
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("unexpected error type: got %T, want %T", err, verrs)
+			}
+
+			if len(verrs) != len(tc.wantErrs) {
+				t.Fatalf("unexpected errors: got %v, want %v", verrs, tc.wantErrs)
+			}
+			for i, want := range tc.wantErrs {
+				if verrs[i] != want {
+					t.Errorf("unexpected error: got %+v, want %+v", verrs[i], want)
+				}
+				if !errors.Is(err, want.Err) {
+					t.Errorf("errors.Is must see %v", want.Err)
+				}
+			}
+		})
+	}
+}
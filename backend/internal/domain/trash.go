@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// TrashedTodo is a deleted todo waiting in the trash, together with the
+// subtasks deleted along with it, until it is restored or purged. Every
+// subtask comes after its parent.
+type TrashedTodo struct {
+	Todo
+	Subtasks  []Todo    `json:"subtasks"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Todos returns the todo followed by its subtasks.
+func (t TrashedTodo) Todos() []Todo {
+	return append([]Todo{t.Todo}, t.Subtasks...)
+}
+
+// Restored returns the todos of the entry as they are put back: the tags
+// deleted in the meantime are dropped and, when its parent is gone, the
+// todo becomes a top-level one. A todo changed this way gets a new
+// version.
+func (t TrashedTodo) Restored(tagExists func(id int) bool, parentExists bool) []Todo {
+	todos := t.Todos()
+	for i, todo := range todos {
+		changed := false
+
+		tags := slices.DeleteFunc(slices.Clone(todo.Tags), func(id int) bool {
+			return !tagExists(id)
+		})
+		if len(tags) != len(todo.Tags) {
+			todo.Tags = tags
+			changed = true
+		}
+		if i == 0 && todo.ParentID != nil && !parentExists {
+			todo.ParentID = nil
+			changed = true
+		}
+
+		if changed {
+			todo.Version++
+		}
+		todos[i] = todo
+	}
+	return todos
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTrashedTodoRestored(t *testing.T) {
+	parentID, rootID := 1, 2
+	entry := TrashedTodo{
+		Todo:     Todo{ID: rootID, ParentID: &parentID, Tags: []int{1, 2}, Version: 3},
+		Subtasks: []Todo{{ID: 3, ParentID: &rootID, Tags: []int{2}, Version: 1}},
+	}
+	tagExists := func(id int) bool { return id == 1 }
+
+	tests := []struct {
+		name         string
+		tagExists    func(id int) bool
+		parentExists bool
+
+		wantParent   bool
+		wantTags     [][]int
+		wantVersions []int
+	}{
+		{
+			name:         "nothing changed",
+			tagExists:    func(id int) bool { return true },
+			parentExists: true,
+			wantParent:   true,
+			wantTags:     [][]int{{1, 2}, {2}},
+			wantVersions: []int{3, 1},
+		},
+		{
+			name:         "deleted tags are dropped",
+			tagExists:    tagExists,
+			parentExists: true,
+			wantParent:   true,
+			wantTags:     [][]int{{1}, {}},
+			wantVersions: []int{4, 2},
+		},
+		{
+			name:         "missing parent is detached",
+			tagExists:    func(id int) bool { return true },
+			wantTags:     [][]int{{1, 2}, {2}},
+			wantVersions: []int{4, 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			todos := entry.Restored(tc.tagExists, tc.parentExists)
+
+			// assert
+			if len(todos) != 2 || todos[0].ID != rootID || todos[1].ID != 3 {
+				t.Fatalf("unexpected todos: got %+v", todos)
+			}
+			if (todos[0].ParentID != nil) != tc.wantParent {
+				t.Errorf("unexpected parent: got %v, want set %t", todos[0].ParentID, tc.wantParent)
+			}
+			if todos[1].ParentID == nil || *todos[1].ParentID != rootID {
+				t.Errorf("subtask must keep its parent: got %v", todos[1].ParentID)
+			}
+			for i, todo := range todos {
+				if !slices.Equal(todo.Tags, tc.wantTags[i]) || todo.Version != tc.wantVersions[i] {
+					t.Errorf("unexpected todo %d: got tags %v, version %d", i, todo.Tags, todo.Version)
+				}
+			}
+
+			// the entry itself is left as it was
+			if len(entry.Tags) != 2 || entry.ParentID == nil || entry.Version != 3 {
+				t.Errorf("entry must not change: got %+v", entry.Todo)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"context"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxEmailLength    = 254
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
+)
+
+// User owns todos. The password hash never leaves the service. A user
+// belongs to a single workspace, the same email may sign up in several.
+type User struct {
+	ID           int       `json:"id"`
+	Email        string    `json:"email"`
+	Tenant       Tenant    `json:"tenant"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Credentials are what a user registers and logs in with.
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (c Credentials) Validate() error {
+	var v validator
+
+	switch {
+	case c.Email == "":
+		v.add("email", ErrRequired, 0)
+	case utf8.RuneCountInString(c.Email) > MaxEmailLength:
+		v.add("email", ErrTooLong, MaxEmailLength)
+	case !validEmail(c.Email):
+		v.add("email", ErrInvalidFormat, 0)
+	}
+
+	switch n := utf8.RuneCountInString(c.Password); {
+	case !utf8.ValidString(c.Password):
+		v.add("password", ErrInvalidUTF8, 0)
+	case n == 0:
+		v.add("password", ErrRequired, 0)
+	case n < MinPasswordLength:
+		v.add("password", ErrTooShort, MinPasswordLength)
+	case n > MaxPasswordLength:
+		v.add("password", ErrTooLong, MaxPasswordLength)
+	}
+
+	return v.err()
+}
+
+// validEmail accepts a bare address, without a display name.
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// NormalizeEmail returns the form an email is unique in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Session is a login of a user. Tokens carry the session id, so deleting
+// the session revokes them before they expire.
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AccessToken is handed out on login.
+type AccessToken struct {
+	Token     string    `json:"access_token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type userKey struct{}
+
+// ContextWithUser returns a context carrying the authenticated user.
+func ContextWithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the authenticated user, false for a context
+// without one.
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey{}).(User)
+	return user, ok
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name  string
+		creds Credentials
+
+		wantErrs []FieldError
+	}{
+		{name: "valid", creds: Credentials{Email: "ann@example.com", Password: "password1"}},
+		{name: "unicode password", creds: Credentials{Email: "ann@example.com", Password: "пароль12"}},
+		{
+			name:     "display name -> error",
+			creds:    Credentials{Email: "Ann <ann@example.com>", Password: "password1"},
+			wantErrs: []FieldError{{Field: "email", Err: ErrInvalidFormat}},
+		},
+		{
+			name:     "short password -> error",
+			creds:    Credentials{Email: "ann@example.com", Password: "пароль1"},
+			wantErrs: []FieldError{{Field: "password", Err: ErrTooShort, Limit: MinPasswordLength}},
+		},
+		{
+			name:     "long password -> error",
+			creds:    Credentials{Email: "ann@example.com", Password: strings.Repeat("a", MaxPasswordLength+1)},
+			wantErrs: []FieldError{{Field: "password", Err: ErrTooLong, Limit: MaxPasswordLength}},
+		},
+		{
+			name:  "every field is reported",
+			creds: Credentials{},
+			wantErrs: []FieldError{
+				{Field: "email", Err: ErrRequired},
+				{Field: "password", Err: ErrRequired},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			err := tc.creds.Validate()
+
+			// assert
+			if len(tc.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("unexpected error type: got %T, want %T", err, verrs)
+			}
+			if !slices.Equal(verrs, tc.wantErrs) {
+				t.Errorf("unexpected errors: got %+v, want %+v", verrs, tc.wantErrs)
+			}
+		})
+	}
+}
+
+func TestFieldErrorReasonMinimum(t *testing.T) {
+	// act
+	got := FieldError{Field: "password", Err: ErrTooShort, Limit: MinPasswordLength}.Reason()
+
+	// assert
+	if want := "is too short (min 8 characters)"; got != want {
+		t.Errorf("unexpected reason: got %q, want %q", got, want)
+	}
+}
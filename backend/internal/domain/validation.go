@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// lengths are counted in characters, not bytes
+	MaxTitleLength       = 200
+	MaxDescriptionLength = 10000
+)
+
+// ErrValidation matches any ValidationErrors with errors.Is.
+var ErrValidation = errors.New("validation failed")
+
+// rule violations; ErrNoTitle is the "required" rule of the title
+var (
+	ErrBlank            = errors.New("must not be blank")
+	ErrTooLong          = errors.New("is too long")
+	ErrTooShort         = errors.New("is too short")
+	ErrControlCharacter = errors.New("must not contain control characters")
+	ErrInvalidUTF8      = errors.New("must be valid UTF-8")
+	ErrUnknownValue     = errors.New("is not one of the allowed values")
+	ErrRequired         = errors.New("is required")
+	ErrInvalidFormat    = errors.New("has an invalid format")
+	ErrTooMany          = errors.New("has too many items")
+	ErrListMismatch     = errors.New("must be the list of the parent todo and of the subtasks")
+)
+
+// FieldError is a single rule violated by a single field. Field is the
+// JSON name of the field.
+type FieldError struct {
+	Field string
+	Err   error
+	// Limit is set for length and count rules.
+	Limit int
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Reason()
+}
+
+// Reason describes the violation without naming the field.
+func (e FieldError) Reason() string {
+	if e.Limit > 0 && errors.Is(e.Err, ErrTooMany) {
+		return fmt.Sprintf("%v (max %d)", e.Err, e.Limit)
+	}
+	if e.Limit > 0 && errors.Is(e.Err, ErrTooShort) {
+		return fmt.Sprintf("%v (min %d characters)", e.Err, e.Limit)
+	}
+	if e.Limit > 0 {
+		return fmt.Sprintf("%v (max %d characters)", e.Err, e.Limit)
+	}
+	return e.Err.Error()
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors collects every violation found in an entity, so all of
+// them can be reported at once.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (v ValidationErrors) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Unwrap makes errors.Is see the rule of every field, e.g. ErrNoTitle.
+func (v ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(v))
+	for i, e := range v {
+		errs[i] = e
+	}
+	return errs
+}
+
+// validator accumulates field errors; err returns nil when there are none.
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(field string, err error, limit int) {
+	v.errs = append(v.errs, FieldError{Field: field, Err: err, Limit: limit})
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// text checks a free-form string. Only the first violation of a field is
+// reported.
+func (v *validator) text(field, s string, maxLen int, multiline bool) {
+	if !utf8.ValidString(s) {
+		v.add(field, ErrInvalidUTF8, 0)
+		return
+	}
+	if utf8.RuneCountInString(s) > maxLen {
+		v.add(field, ErrTooLong, maxLen)
+		return
+	}
+	for _, r := range s {
+		if multiline && (r == '\n' || r == '\r' || r == '\t') {
+			continue
+		}
+		if unicode.IsControl(r) {
+			v.add(field, ErrControlCharacter, 0)
+			return
+		}
+	}
+}
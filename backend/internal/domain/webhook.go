@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+const (
+	MaxWebhookURLLength    = 2000
+	MinWebhookSecretLength = 16
+	MaxWebhookSecretLength = 200
+	// WebhookSecretPrefix starts every generated secret.
+	WebhookSecretPrefix = "whsec_"
+)
+
+// WebhookEvents are the events a webhook can subscribe to, all of them
+// for a webhook created without events.
+var WebhookEvents = []TodoEventType{TodoCreated, TodoUpdated, TodoCompleted, TodoDeleted}
+
+// Webhook sends the changes of the todos its owner can see to URL. Every
+// payload is signed with the secret, which is shown once, on creation.
+type Webhook struct {
+	ID     int             `json:"id"`
+	UserID int             `json:"user_id"`
+	URL    string          `json:"url"`
+	Events []TodoEventType `json:"events"`
+	Secret string          `json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the fields a client chooses: the URL, the events and
+// the secret, which may be left empty to have one generated.
+func (h Webhook) Validate() error {
+	var v validator
+
+	switch {
+	case h.URL == "":
+		v.add("url", ErrRequired, 0)
+	case len(h.URL) > MaxWebhookURLLength:
+		v.add("url", ErrTooLong, MaxWebhookURLLength)
+	default:
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+			v.add("url", ErrInvalidFormat, 0)
+		}
+	}
+
+	for i, e := range h.Events {
+		if !slices.Contains(WebhookEvents, e) {
+			v.add(fmt.Sprintf("events[%d]", i), ErrUnknownValue, 0)
+		}
+	}
+
+	switch {
+	case h.Secret == "":
+	case len(h.Secret) < MinWebhookSecretLength:
+		v.add("secret", ErrTooShort, MinWebhookSecretLength)
+	default:
+		v.text("secret", h.Secret, MaxWebhookSecretLength, false)
+	}
+
+	return v.err()
+}
+
+// Wants reports whether the webhook subscribed to the event.
+func (h Webhook) Wants(typ TodoEventType) bool {
+	return slices.Contains(h.Events, typ)
+}
+
+// NormalizeWebhookEvents returns the events sorted and without
+// duplicates; no events at all stand for every event.
+func NormalizeWebhookEvents(events []TodoEventType) []TodoEventType {
+	if len(events) == 0 {
+		return slices.Clone(WebhookEvents)
+	}
+	res := slices.Clone(events)
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+// CreatedWebhook is a new webhook together with its secret.
+type CreatedWebhook struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// DeliveryStatus tells where a delivery is in its life.
+type DeliveryStatus string
+
+const (
+	// DeliveryPending is waiting for its next attempt.
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySucceeded was answered with a 2xx status.
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryFailed ran out of attempts and is a dead letter until it is
+	// redelivered.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is a single event sent to a single webhook, with every
+// attempt made so far. The payload is built once, retries send the same
+// bytes. EventID is the id of the event, the same after a restart, unlike
+// ID, which only tells the deliveries of the log apart.
+type WebhookDelivery struct {
+	ID        int               `json:"id"`
+	WebhookID int               `json:"webhook_id"`
+	Tenant    Tenant            `json:"-"`
+	EventID   uint64            `json:"event_id,omitempty"`
+	Event     TodoEventType     `json:"event"`
+	TodoID    int               `json:"todo_id"`
+	Status    DeliveryStatus    `json:"status"`
+	Attempts  []DeliveryAttempt `json:"attempts"`
+	Payload   []byte            `json:"-"`
+
+	CreatedAt     time.Time  `json:"created_at"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+// DeliveryAttempt is a single request of a delivery. StatusCode is zero
+// when no response came back, Error tells why.
+type DeliveryAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
@@ -0,0 +1,120 @@
+// Package events fans out the changes of todos to live subscribers.
+//
+// The hub keeps the latest events in a bounded buffer, so a subscriber that
+// reconnects with the id of the last event it has seen gets the ones it
+// missed. A subscriber that falls too far behind is dropped: its channel is
+// closed and it has to subscribe again.
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const (
+	// DefaultBufferSize is how many of the latest events a hub keeps for
+	// subscribers to resume from.
+	DefaultBufferSize = 1000
+
+	// subscriberBuffer is how many events a subscriber may lag behind
+	// before it is dropped.
+	subscriberBuffer = 64
+)
+
+// Hub is a publish/subscribe hub of todo events, safe for concurrent use.
+// Events are kept in memory only: ids start over when the process restarts.
+type Hub struct {
+	mu     sync.Mutex
+	size   int
+	buffer []domain.TodoEvent
+	lastID uint64
+	subs   map[chan domain.TodoEvent]struct{}
+}
+
+// NewHub returns a hub that keeps the latest size events.
+func NewHub(size int) *Hub {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &Hub{
+		size: size,
+		subs: make(map[chan domain.TodoEvent]struct{}),
+	}
+}
+
+// Publish assigns the event the next id, keeps it and hands it to every
+// subscriber. It never blocks on a slow subscriber.
+func (h *Hub) Publish(event domain.TodoEvent) domain.TodoEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event.ID = h.lastID
+
+	h.buffer = append(h.buffer, event)
+	if len(h.buffer) > h.size {
+		h.buffer = h.buffer[1:]
+	}
+
+	for ch := range h.subs {
+		select {
+		case ch <- event:
+		default:
+			// the subscriber resumes from its last event once it reconnects
+			h.drop(ch)
+		}
+	}
+
+	return event
+}
+
+// Subscribe returns the events published from now on until ctx is done.
+// A non-zero lastEventID first replays the kept events after it; when they
+// are not kept anymore, or the id was never issued, the channel starts with
+// a domain.TodoEventsReset event carrying the id to resume from instead.
+func (h *Hub) Subscribe(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var replay []domain.TodoEvent
+	if lastEventID != 0 {
+		var ok bool
+		if replay, ok = h.since(lastEventID); !ok {
+			replay = []domain.TodoEvent{{ID: h.lastID, Type: domain.TodoEventsReset}}
+		}
+	}
+
+	ch := make(chan domain.TodoEvent, len(replay)+subscriberBuffer)
+	for _, event := range replay {
+		ch <- event
+	}
+	h.subs[ch] = struct{}{}
+
+	context.AfterFunc(ctx, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.drop(ch)
+	})
+
+	return ch
+}
+
+// since returns the kept events after id, false when some of them are gone.
+func (h *Hub) since(id uint64) ([]domain.TodoEvent, bool) {
+	first := h.lastID - uint64(len(h.buffer)) + 1
+	if id > h.lastID || id+1 < first {
+		return nil, false
+	}
+	return h.buffer[id+1-first:], true
+}
+
+// drop closes the channel of a subscriber once.
+func (h *Hub) drop(ch chan domain.TodoEvent) {
+	if _, ok := h.subs[ch]; !ok {
+		return
+	}
+	delete(h.subs, ch)
+	close(ch)
+}
@@ -0,0 +1,165 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func publish(h *Hub, ids ...int) {
+	for _, id := range ids {
+		h.Publish(domain.TodoEvent{Type: domain.TodoUpdated, Todo: domain.Todo{ID: id}})
+	}
+}
+
+// received drains the events already sent to the channel.
+func received(ch <-chan domain.TodoEvent) []domain.TodoEvent {
+	var got []domain.TodoEvent
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, event)
+		default:
+			return got
+		}
+	}
+}
+
+func eventIDs(events []domain.TodoEvent) []uint64 {
+	ids := make([]uint64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestSubscribe(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastEventID uint64
+
+		wantIDs   []uint64
+		wantReset bool
+	}{
+		{
+			name:    "no last event id -> live events only",
+			wantIDs: []uint64{6},
+		},
+		{
+			name:        "kept last event id -> missed events first",
+			lastEventID: 3,
+			wantIDs:     []uint64{4, 5, 6},
+		},
+		{
+			name:        "latest event id -> live events only",
+			lastEventID: 5,
+			wantIDs:     []uint64{6},
+		},
+		{
+			name:        "event id before the buffer -> reset",
+			lastEventID: 1,
+			wantIDs:     []uint64{5, 6},
+			wantReset:   true,
+		},
+		{
+			name:        "event id never issued -> reset",
+			lastEventID: 42,
+			wantIDs:     []uint64{5, 6},
+			wantReset:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			hub := NewHub(3)
+			publish(hub, 1, 2, 3, 4, 5)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// act
+			ch := hub.Subscribe(ctx, tc.lastEventID)
+			publish(hub, 6)
+			got := received(ch)
+
+			// assert
+			if ids := eventIDs(got); !slices.Equal(ids, tc.wantIDs) {
+				t.Fatalf("unexpected event ids: got %v, want %v", ids, tc.wantIDs)
+			}
+			if reset := got[0].Type == domain.TodoEventsReset; reset != tc.wantReset {
+				t.Errorf("unexpected reset: got %v, want %v", reset, tc.wantReset)
+			}
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	t.Run("every subscriber gets the event", func(t *testing.T) {
+		// preparing
+		hub := NewHub(DefaultBufferSize)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		first, second := hub.Subscribe(ctx, 0), hub.Subscribe(ctx, 0)
+
+		// act
+		event := hub.Publish(domain.TodoEvent{Type: domain.TodoCreated, Todo: domain.Todo{ID: 7}})
+
+		// assert
+		if event.ID != 1 {
+			t.Errorf("unexpected event id: got %d, want 1", event.ID)
+		}
+		for _, ch := range []<-chan domain.TodoEvent{first, second} {
+			if got := received(ch); len(got) != 1 || got[0].Todo.ID != 7 {
+				t.Errorf("unexpected events: got %+v, want todo 7", got)
+			}
+		}
+	})
+
+	t.Run("slow subscriber is dropped", func(t *testing.T) {
+		// preparing
+		hub := NewHub(DefaultBufferSize)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch := hub.Subscribe(ctx, 0)
+
+		// act
+		for i := range subscriberBuffer + 1 {
+			publish(hub, i)
+		}
+
+		// assert
+		if got := received(ch); len(got) != subscriberBuffer {
+			t.Errorf("unexpected number of events: got %d, want %d", len(got), subscriberBuffer)
+		}
+		if _, ok := <-ch; ok {
+			t.Error("channel of a dropped subscriber must be closed")
+		}
+
+		// the dropped subscriber resumes from its last event
+		resumed := hub.Subscribe(ctx, subscriberBuffer)
+		if got := received(resumed); len(got) != 1 || got[0].ID != subscriberBuffer+1 {
+			t.Errorf("unexpected resumed events: got %v, want [%d]", eventIDs(got), subscriberBuffer+1)
+		}
+	})
+
+	t.Run("subscription ends with the context", func(t *testing.T) {
+		// preparing
+		hub := NewHub(DefaultBufferSize)
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := hub.Subscribe(ctx, 0)
+
+		// act
+		cancel()
+
+		// assert
+		if _, ok := <-ch; ok {
+			t.Error("channel must be closed once the context is done")
+		}
+		publish(hub, 1)
+	})
+}
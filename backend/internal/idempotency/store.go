@@ -0,0 +1,223 @@
+// Package idempotency keeps the responses to requests sent with an
+// Idempotency-Key, so a retried request gets the response of the first one
+// instead of being run again.
+//
+// A key is claimed by the first request that uses it and holds the
+// fingerprint of that request. Once the request is done its response is
+// kept for the TTL; a request that never ran releases the key, so it can
+// be retried. The store lives in memory only: it is empty after a restart.
+// Its size is capped per user and in total, the oldest responses giving
+// way to new ones. A response too large to keep leaves a marker instead,
+// so the request is still not run again.
+package idempotency
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const (
+	// DefaultTTL is how long a store keeps a response by default.
+	DefaultTTL = 24 * time.Hour
+
+	DefaultMaxEntries       = 10000
+	DefaultMaxUserEntries   = 1000
+	DefaultMaxResponseBytes = 64 << 10
+)
+
+// Key is an idempotency key sent by a user. Users and workspaces never
+// share keys.
+type Key struct {
+	Tenant domain.Tenant
+	UserID int
+	Key    string
+}
+
+// Response is a response kept to be replayed. A lost response is only a
+// marker of a request that ran without a response to keep: it was too
+// large or the handler never finished. It is not replayed, Begin fails
+// with domain.ErrIdempotentResponseLost instead.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	Lost   bool
+}
+
+// Store is a store of responses safe for concurrent use. The caps are
+// set to their defaults by NewStore and may be changed before the store is
+// used.
+type Store struct {
+	// MaxEntries and MaxUserEntries cap the keys kept in total and per
+	// user. A new key beyond a cap drops the oldest finished entry, keys of
+	// requests in progress are never dropped.
+	MaxEntries     int
+	MaxUserEntries int
+	// MaxResponseBytes caps the body of a response kept. A larger one is
+	// kept as a lost response, without its body.
+	MaxResponseBytes int
+
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[Key]*entry
+	// perUser counts the entries of every user
+	perUser map[user]int
+	// expiring lists the claims in the order they were made, which is the
+	// order they expire in, as the TTL is the same for all of them
+	expiring []claim
+}
+
+// user owns keys, see Key.
+type user struct {
+	tenant domain.Tenant
+	id     int
+}
+
+func (k Key) user() user {
+	return user{tenant: k.Tenant, id: k.UserID}
+}
+
+type claim struct {
+	key     Key
+	expires time.Time
+}
+
+type entry struct {
+	fingerprint string
+	expires     time.Time
+	// resp is nil while the request is in progress
+	resp *Response
+}
+
+// NewStore returns a store that keeps responses for ttl.
+func NewStore(ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{
+		MaxEntries:       DefaultMaxEntries,
+		MaxUserEntries:   DefaultMaxUserEntries,
+		MaxResponseBytes: DefaultMaxResponseBytes,
+		ttl:              ttl,
+		now:              time.Now,
+		entries:          make(map[Key]*entry),
+		perUser:          make(map[user]int),
+	}
+}
+
+// Begin claims the key for a request with the fingerprint and returns nil
+// when it is claimed; the request must then be finished with Finish or
+// Cancel. When the key was already used with the same fingerprint, Begin
+// returns the response to replay, domain.ErrIdempotentResponseLost when
+// it was not kept, or domain.ErrIdempotencyKeyInUse while the first
+// request is in progress. A key used with another fingerprint fails with
+// domain.ErrIdempotencyKeyReused.
+func (s *Store) Begin(key Key, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+
+	if e, ok := s.entries[key]; ok {
+		switch {
+		case e.fingerprint != fingerprint:
+			return nil, domain.ErrIdempotencyKeyReused
+		case e.resp == nil:
+			return nil, domain.ErrIdempotencyKeyInUse
+		case e.resp.Lost:
+			return nil, domain.ErrIdempotentResponseLost
+		default:
+			return e.resp, nil
+		}
+	}
+
+	if s.perUser[key.user()] >= s.MaxUserEntries {
+		s.evict(func(k Key) bool { return k.user() == key.user() })
+	}
+	if len(s.entries) >= s.MaxEntries {
+		s.evict(func(Key) bool { return true })
+	}
+
+	expires := now.Add(s.ttl)
+	s.entries[key] = &entry{fingerprint: fingerprint, expires: expires}
+	s.perUser[key.user()]++
+	s.expiring = append(s.expiring, claim{key: key, expires: expires})
+	return nil, nil
+}
+
+// Finish keeps the response to the request that claimed the key. A
+// response larger than MaxResponseBytes is kept as a lost one, so the key
+// stays claimed.
+func (s *Store) Finish(key Key, resp Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || e.resp != nil {
+		return
+	}
+	if len(resp.Body) > s.MaxResponseBytes {
+		resp = Response{Status: resp.Status, Header: resp.Header, Lost: true}
+	}
+	e.resp = &resp
+}
+
+// Cancel releases the key claimed by a request that never ran, so it can
+// be retried. A request that ran must be finished, with a lost response
+// if there is none to keep.
+func (s *Store) Cancel(key Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.resp == nil {
+		s.drop(key)
+	}
+}
+
+// expire drops the entries expired by now. A claim whose key was
+// cancelled and claimed again no longer has an entry of its own. Must be
+// called with the mutex held.
+func (s *Store) expire(now time.Time) {
+	n := 0
+	for _, c := range s.expiring {
+		if c.expires.After(now) {
+			break
+		}
+		if e, ok := s.entries[c.key]; ok && e.expires.Equal(c.expires) {
+			s.drop(c.key)
+		}
+		n++
+	}
+	s.expiring = s.expiring[n:]
+}
+
+// evict drops the oldest finished entry of the keys that match, if any.
+// Must be called with the mutex held.
+func (s *Store) evict(match func(Key) bool) {
+	for i, c := range s.expiring {
+		e, ok := s.entries[c.key]
+		if !ok || !e.expires.Equal(c.expires) || e.resp == nil || !match(c.key) {
+			continue
+		}
+		s.drop(c.key)
+		s.expiring = slices.Delete(s.expiring, i, i+1)
+		return
+	}
+}
+
+// drop deletes the entry of the key. Must be called with the mutex held.
+func (s *Store) drop(key Key) {
+	delete(s.entries, key)
+
+	u := key.user()
+	if s.perUser[u]--; s.perUser[u] <= 0 {
+		delete(s.perUser, u)
+	}
+}
@@ -0,0 +1,201 @@
+package idempotency
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+var (
+	annKey = Key{Tenant: domain.DefaultTenant, UserID: 1, Key: "k1"}
+	bobKey = Key{Tenant: domain.DefaultTenant, UserID: 2, Key: "k1"}
+)
+
+var created = Response{Status: http.StatusCreated, Body: []byte(`{"id":1}`)}
+
+func TestStore(t *testing.T) {
+	tests := []struct {
+		name string
+		// first is run after claiming annKey with "a"
+		first       func(s *Store)
+		key         Key
+		fingerprint string
+
+		wantResp bool
+		wantErr  error
+	}{
+		{
+			name:        "retry after the response",
+			first:       func(s *Store) { s.Finish(annKey, created) },
+			key:         annKey,
+			fingerprint: "a",
+			wantResp:    true,
+		},
+		{
+			name:        "retry in progress",
+			first:       func(s *Store) {},
+			key:         annKey,
+			fingerprint: "a",
+			wantErr:     domain.ErrIdempotencyKeyInUse,
+		},
+		{
+			name:        "another body",
+			first:       func(s *Store) { s.Finish(annKey, created) },
+			key:         annKey,
+			fingerprint: "b",
+			wantErr:     domain.ErrIdempotencyKeyReused,
+		},
+		{
+			name:        "retry after cancel",
+			first:       func(s *Store) { s.Cancel(annKey) },
+			key:         annKey,
+			fingerprint: "b",
+		},
+		{
+			name:        "same key of another user",
+			first:       func(s *Store) { s.Finish(annKey, created) },
+			key:         bobKey,
+			fingerprint: "b",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			s := NewStore(0)
+			if resp, err := s.Begin(annKey, "a"); resp != nil || err != nil {
+				t.Fatalf("unexpected claim: got %v and %v, want nil", resp, err)
+			}
+			tc.first(s)
+
+			// act
+			resp, err := s.Begin(tc.key, tc.fingerprint)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if (resp != nil) != tc.wantResp {
+				t.Fatalf("unexpected response: got %v, want one: %v", resp, tc.wantResp)
+			}
+			if resp != nil && resp.Status != created.Status {
+				t.Errorf("unexpected status: got %d, want %d", resp.Status, created.Status)
+			}
+		})
+	}
+}
+
+func TestStoreExpires(t *testing.T) {
+	// preparing
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStore(time.Hour)
+	s.now = func() time.Time { return now }
+
+	s.Begin(annKey, "a")
+	s.Finish(annKey, created)
+	now = now.Add(30 * time.Minute)
+	s.Begin(bobKey, "a")
+	s.Cancel(bobKey)
+	s.Begin(bobKey, "a")
+	s.Finish(bobKey, created)
+
+	// act
+	now = now.Add(45 * time.Minute)
+	annResp, annErr := s.Begin(annKey, "b")
+	bobResp, bobErr := s.Begin(bobKey, "a")
+
+	// assert
+	if annResp != nil || annErr != nil {
+		t.Errorf("expired key must be claimed anew: got %v and %v", annResp, annErr)
+	}
+	if bobResp == nil || bobErr != nil {
+		t.Errorf("key claimed again must be kept: got %v and %v", bobResp, bobErr)
+	}
+	if len(s.entries) != 2 {
+		t.Errorf("unexpected entries: got %d, want %d", len(s.entries), 2)
+	}
+}
+
+func TestStoreCaps(t *testing.T) {
+	key := func(userID int, name string) Key {
+		return Key{Tenant: domain.DefaultTenant, UserID: userID, Key: name}
+	}
+	kept := func(s *Store, k Key) bool {
+		_, ok := s.entries[k]
+		return ok
+	}
+
+	t.Run("per user -> oldest finished entry of the user dropped", func(t *testing.T) {
+		// preparing
+		s := NewStore(0)
+		s.MaxUserEntries = 2
+		s.Begin(key(1, "a"), "x")
+		s.Begin(key(1, "b"), "x")
+		s.Finish(key(1, "b"), created)
+		s.Begin(key(2, "a"), "x")
+		s.Finish(key(2, "a"), created)
+
+		// act
+		s.Begin(key(1, "c"), "x")
+
+		// assert
+		if !kept(s, key(1, "a")) {
+			t.Errorf("request in progress must be kept")
+		}
+		if kept(s, key(1, "b")) {
+			t.Errorf("oldest finished entry of the user must be dropped")
+		}
+		if !kept(s, key(1, "c")) || !kept(s, key(2, "a")) {
+			t.Errorf("unexpected entries: got %v", s.entries)
+		}
+		if s.perUser[key(1, "").user()] != 2 {
+			t.Errorf("unexpected count: got %d, want 2", s.perUser[key(1, "").user()])
+		}
+	})
+
+	t.Run("in total -> oldest finished entry dropped", func(t *testing.T) {
+		// preparing
+		s := NewStore(0)
+		s.MaxEntries = 3
+		for i := range 3 {
+			s.Begin(key(i, "a"), "x")
+			s.Finish(key(i, "a"), created)
+		}
+
+		// act
+		s.Begin(key(3, "a"), "x")
+
+		// assert
+		if len(s.entries) != 3 || kept(s, key(0, "a")) || !kept(s, key(3, "a")) {
+			t.Errorf("unexpected entries: got %v", s.entries)
+		}
+		if _, ok := s.perUser[key(0, "").user()]; ok {
+			t.Errorf("count of a user without entries must be dropped")
+		}
+		if len(s.expiring) != 3 {
+			t.Errorf("unexpected claims: got %d, want 3", len(s.expiring))
+		}
+	})
+
+	t.Run("response too large -> kept as lost", func(t *testing.T) {
+		// preparing
+		s := NewStore(0)
+		s.MaxResponseBytes = len(created.Body) - 1
+		s.Begin(annKey, "a")
+
+		// act
+		s.Finish(annKey, created)
+		resp, err := s.Begin(annKey, "a")
+
+		// assert
+		if resp != nil || !errors.Is(err, domain.ErrIdempotentResponseLost) {
+			t.Errorf("unexpected retry: got %v and %v, want %v", resp, err, domain.ErrIdempotentResponseLost)
+		}
+		if e := s.entries[annKey]; e == nil || e.resp == nil || e.resp.Body != nil {
+			t.Errorf("unexpected entry: got %+v, want a lost response without body", e)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+// Package mergepatch implements JSON Merge Patch (RFC 7396).
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidPatch = errors.New("invalid merge patch")
+
+// Apply merges patch into the JSON document doc and returns the result.
+// Members set to null in the patch are removed from the target, objects
+// are merged recursively and any other value replaces the target as is.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var patchValue any
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var target any
+	if len(doc) > 0 {
+		if err := json.Unmarshal(doc, &target); err != nil {
+			return nil, fmt.Errorf("decode document: %w", err)
+		}
+	}
+
+	return json.Marshal(merge(target, patchValue))
+}
+
+func merge(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = merge(targetObj[k], v)
+	}
+
+	return targetObj
+}
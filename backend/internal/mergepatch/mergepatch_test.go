@@ -0,0 +1,59 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	// examples from RFC 7396, appendix A
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{name: "replace member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "remove member", doc: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{name: "remove one of two", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "array replaces", doc: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "value becomes array", doc: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{name: "nested merge", doc: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{name: "arrays are not merged", doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{name: "non-object patch replaces", doc: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{name: "null in nested new object", doc: `{"e":null}`, patch: `{"a":1}`, want: `{"a":1,"e":null}`},
+		{name: "nested object is created", doc: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			got, err := Apply([]byte(tc.doc), []byte(tc.patch))
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+
+			var gotValue, wantValue any
+			if err := json.Unmarshal(got, &gotValue); err != nil {
+				t.Fatalf("decode result: %v", err)
+			}
+			json.Unmarshal([]byte(tc.want), &wantValue)
+
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Errorf("unexpected result: got %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("malformed patch -> error", func(t *testing.T) {
+		_, err := Apply([]byte(`{"a":"b"}`), []byte(`{"a":`))
+		if !errors.Is(err, ErrInvalidPatch) {
+			t.Fatalf("unexpected error: got %v, want %v", err, ErrInvalidPatch)
+		}
+	})
+}
@@ -0,0 +1,261 @@
+// Package outbox delivers the events stored together with the changes of
+// todos to the subscribers of this process.
+//
+// An event leaves the outbox only once every subscriber has handled it, so
+// each one gets it at least once: after a crash, or while a subscriber
+// keeps failing, it is handed over again. Subscribers tell repeats apart by
+// the id of the event, which stays the same.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const (
+	DefaultPollInterval   = time.Second
+	DefaultBatchSize      = 100
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = time.Minute
+)
+
+// Store keeps the events until they are acknowledged. The repositories of
+// the adapters implement it.
+type Store interface {
+	// AddEvents assigns the events the next ids and stores them.
+	AddEvents(ctx context.Context, events ...domain.TodoEvent) error
+	// PendingEvents returns up to limit events of every workspace, oldest
+	// first.
+	PendingEvents(ctx context.Context, limit int) ([]domain.TodoEvent, error)
+	// AckEvents removes the events; unknown ids are skipped.
+	AckEvents(ctx context.Context, ids ...uint64) error
+}
+
+// Handler handles a single event. The context carries the workspace of the
+// event. An error makes the dispatcher retry the event later, for this
+// handler only.
+type Handler func(ctx context.Context, event domain.TodoEvent) error
+
+type Options struct {
+	// PollInterval is how often the outbox is checked for events nobody
+	// woke the dispatcher up for, such as those left by a crash.
+	PollInterval time.Duration
+	// BatchSize caps the events read at once.
+	BatchSize int
+	// InitialBackoff is the wait after a failed event, doubled for every
+	// next failure in a row up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Dispatcher hands the stored events to the subscribers one by one, in the
+// order they were stored. A failing subscriber holds back the events after
+// the failed one, for every subscriber, until it succeeds. It is safe for
+// concurrent use.
+type Dispatcher struct {
+	store Store
+	opts  Options
+
+	wake   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	started bool
+	subs    []subscriber
+
+	// handled keeps the subscribers that are done with an event still in
+	// the outbox, so a retry skips them. Only run touches it.
+	handled map[uint64]map[string]bool
+}
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+func NewDispatcher(store Store, opts Options) *Dispatcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		store:   store,
+		opts:    opts,
+		wake:    make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		handled: map[uint64]map[string]bool{},
+	}
+}
+
+// Subscribe registers a handler under a name unique to the dispatcher.
+// Subscribers registered after Start miss nothing still in the outbox.
+func (d *Dispatcher) Subscribe(name string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.subs = append(d.subs, subscriber{name: name, handler: handler})
+}
+
+// Start delivers the events in the background until Close.
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.started {
+		return
+	}
+	d.started = true
+	go d.run()
+}
+
+// Close stops the delivery and waits for the handler in progress. Events
+// not handled yet stay in the outbox for the next start.
+func (d *Dispatcher) Close() {
+	d.cancel()
+
+	d.mu.Lock()
+	started := d.started
+	d.mu.Unlock()
+	if started {
+		<-d.done
+	}
+}
+
+// AddEvents stores the events in the outbox; a transaction in ctx stores
+// them together with the change. See Wake.
+func (d *Dispatcher) AddEvents(ctx context.Context, events ...domain.TodoEvent) error {
+	return d.store.AddEvents(ctx, events...)
+}
+
+// Wake makes the dispatcher check the outbox right away. It never blocks.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	failures := 0
+	for {
+		n, err := d.dispatch()
+		if err != nil {
+			failures++
+			wait := d.backoff(failures)
+			slog.Warn("failed to dispatch outbox event", "failures", failures, "retry_in", wait, "error", err)
+
+			// a new event does not fix the failed one, so waking is ignored
+			if !d.sleep(wait, nil) {
+				return
+			}
+			continue
+		}
+		failures = 0
+
+		wait := d.opts.PollInterval
+		if n == d.opts.BatchSize {
+			// more events are waiting
+			wait = 0
+		}
+		if !d.sleep(wait, d.wake) {
+			return
+		}
+	}
+}
+
+// sleep waits for the duration or for wake, whichever comes first. It
+// reports false once the dispatcher is closed.
+func (d *Dispatcher) sleep(wait time.Duration, wake <-chan struct{}) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-d.ctx.Done():
+		return false
+	case <-wake:
+	case <-timer.C:
+	}
+	return true
+}
+
+// dispatch hands a batch of events to the subscribers and acknowledges
+// those every subscriber has handled. It stops at the first failure, so
+// the order is kept. It returns the number of events read.
+func (d *Dispatcher) dispatch() (int, error) {
+	events, err := d.store.PendingEvents(d.ctx, d.opts.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("read pending events: %w", err)
+	}
+
+	d.mu.Lock()
+	subs := d.subs
+	d.mu.Unlock()
+
+	for _, event := range events {
+		if err := d.deliver(subs, event); err != nil {
+			return len(events), err
+		}
+
+		if err := d.store.AckEvents(d.ctx, event.ID); err != nil {
+			return len(events), fmt.Errorf("ack event %d: %w", event.ID, err)
+		}
+		delete(d.handled, event.ID)
+	}
+
+	return len(events), nil
+}
+
+// deliver hands the event to every subscriber that has not handled it yet.
+func (d *Dispatcher) deliver(subs []subscriber, event domain.TodoEvent) error {
+	ctx := domain.ContextWithTenant(d.ctx, event.Tenant)
+
+	for _, sub := range subs {
+		if d.handled[event.ID][sub.name] {
+			continue
+		}
+
+		if err := sub.handler(ctx, event); err != nil {
+			return fmt.Errorf("%s: event %d: %w", sub.name, event.ID, err)
+		}
+
+		if d.handled[event.ID] == nil {
+			d.handled[event.ID] = map[string]bool{}
+		}
+		d.handled[event.ID][sub.name] = true
+	}
+
+	return nil
+}
+
+// backoff is the wait after the given number of failures in a row.
+func (d *Dispatcher) backoff(failures int) time.Duration {
+	wait := d.opts.InitialBackoff
+	for range failures - 1 {
+		if wait >= d.opts.MaxBackoff/2 {
+			return d.opts.MaxBackoff
+		}
+		wait *= 2
+	}
+	return min(wait, d.opts.MaxBackoff)
+}
@@ -0,0 +1,213 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/adapter/memory"
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+var testOptions = Options{PollInterval: 5 * time.Millisecond, InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+
+// recorder keeps the events a subscriber got along with the workspace of
+// the context, failing the first fails deliveries of the given event.
+type recorder struct {
+	mu     sync.Mutex
+	failID uint64
+	fails  int
+	got    []uint64
+	tenant []domain.Tenant
+}
+
+func (rc *recorder) handle(ctx context.Context, event domain.TodoEvent) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.got = append(rc.got, event.ID)
+	rc.tenant = append(rc.tenant, domain.CurrentTenant(ctx))
+
+	if event.ID == rc.failID && rc.fails > 0 {
+		rc.fails--
+		return errors.New("subscriber is down")
+	}
+	return nil
+}
+
+func (rc *recorder) events() []uint64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return slices.Clone(rc.got)
+}
+
+func addEvents(t *testing.T, store Store) {
+	t.Helper()
+
+	acme := domain.ContextWithTenant(context.Background(), "acme")
+	events := []domain.TodoEvent{
+		{Type: domain.TodoCreated, Tenant: "acme", Todo: domain.Todo{ID: 1}},
+		{Type: domain.TodoCompleted, Tenant: "acme", Todo: domain.Todo{ID: 1}},
+	}
+	if err := store.AddEvents(acme, events...); err != nil {
+		t.Fatalf("unexpected error on add events: got %v, want nil", err)
+	}
+	if err := store.AddEvents(context.Background(), domain.TodoEvent{Type: domain.TodoDeleted, Tenant: domain.DefaultTenant}); err != nil {
+		t.Fatalf("unexpected error on add events: got %v, want nil", err)
+	}
+}
+
+// drained waits until every event is acknowledged.
+func drained(t *testing.T, store Store) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		events, err := store.PendingEvents(context.Background(), 1)
+		if err == nil && len(events) == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("events are still pending")
+}
+
+func TestDispatch(t *testing.T) {
+	// preparing
+	store := memory.New()
+	addEvents(t, store)
+
+	first, second := &recorder{}, &recorder{}
+	d := NewDispatcher(store, testOptions)
+	d.Subscribe("first", first.handle)
+	d.Subscribe("second", second.handle)
+
+	// act
+	d.Start()
+	defer d.Close()
+	drained(t, store)
+
+	// assert
+	want := []uint64{1, 2, 3}
+	for _, rc := range []*recorder{first, second} {
+		if got := rc.events(); !slices.Equal(got, want) {
+			t.Errorf("unexpected events: got %v, want %v", got, want)
+		}
+		wantTenants := []domain.Tenant{"acme", "acme", domain.DefaultTenant}
+		if !slices.Equal(rc.tenant, wantTenants) {
+			t.Errorf("unexpected workspaces of the context: got %v, want %v", rc.tenant, wantTenants)
+		}
+	}
+}
+
+func TestWake(t *testing.T) {
+	// preparing
+	store := memory.New()
+	rc := &recorder{}
+	d := NewDispatcher(store, Options{PollInterval: time.Hour})
+	d.Subscribe("rc", rc.handle)
+	d.Start()
+	defer d.Close()
+
+	// act
+	addEvents(t, store)
+	d.Wake()
+
+	// assert
+	drained(t, store)
+	if got := rc.events(); len(got) != 3 {
+		t.Errorf("unexpected events: got %v, want 3", got)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	// preparing
+	store := memory.New()
+	addEvents(t, store)
+
+	flaky := &recorder{failID: 2, fails: 2}
+	steady := &recorder{}
+	d := NewDispatcher(store, testOptions)
+	d.Subscribe("steady", steady.handle)
+	d.Subscribe("flaky", flaky.handle)
+
+	// act
+	d.Start()
+	defer d.Close()
+	drained(t, store)
+
+	// assert
+	if got, want := flaky.events(), []uint64{1, 2, 2, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("unexpected events of the failing subscriber: got %v, want %v", got, want)
+	}
+	// the retry skips the subscribers that handled the event
+	if got, want := steady.events(), []uint64{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("unexpected events of the other subscriber: got %v, want %v", got, want)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	// preparing
+	store := memory.New()
+	addEvents(t, store)
+
+	down := &recorder{failID: 1, fails: 1000}
+	d := NewDispatcher(store, testOptions)
+	d.Subscribe("rc", down.handle)
+	d.Start()
+	for len(down.events()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	d.Close()
+
+	// act: the next process delivers what the first one could not
+	rc := &recorder{}
+	restarted := NewDispatcher(store, testOptions)
+	restarted.Subscribe("rc", rc.handle)
+	restarted.Start()
+	defer restarted.Close()
+	drained(t, store)
+
+	// assert
+	if got, want := rc.events(), []uint64{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("unexpected events: got %v, want %v", got, want)
+	}
+}
+
+func TestClose(t *testing.T) {
+	t.Run("before start", func(t *testing.T) {
+		d := NewDispatcher(memory.New(), testOptions)
+		d.Close()
+	})
+
+	t.Run("twice", func(t *testing.T) {
+		d := NewDispatcher(memory.New(), testOptions)
+		d.Start()
+		d.Close()
+		d.Close()
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(memory.New(), Options{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute})
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 10 * time.Second},
+		{failures: 2, want: 20 * time.Second},
+		{failures: 3, want: 40 * time.Second},
+		{failures: 4, want: time.Minute},
+		{failures: 100, want: time.Minute},
+	}
+
+	for _, tc := range tests {
+		if got := d.backoff(tc.failures); got != tc.want {
+			t.Errorf("unexpected backoff after %d failures: got %v, want %v", tc.failures, got, tc.want)
+		}
+	}
+}
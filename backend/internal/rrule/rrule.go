@@ -0,0 +1,459 @@
+// Package rrule parses and evaluates a subset of iCalendar recurrence rules
+// (RFC 5545, section 3.3.10).
+//
+// Supported parts are FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL,
+// COUNT, UNTIL, BYDAY, BYMONTHDAY and WKST. BYDAY limits DAILY and picks
+// the days of WEEKLY and MONTHLY rules, where it may carry an ordinal such
+// as 2TU or -1FR; BYMONTHDAY is only allowed in MONTHLY rules and cannot
+// be combined with BYDAY.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// Weekday is an entry of BYDAY. N picks the n-th such weekday of the
+// month, counting from the end when negative; zero means every one.
+type Weekday struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed RRULE.
+type Rule struct {
+	Freq Frequency
+	// Interval is at least 1.
+	Interval int
+	// Count limits the number of occurrences, zero means no limit.
+	Count int
+	// Until is the last moment an occurrence may fall on, zero means no
+	// limit. A date-only UNTIL covers the whole day in the zone of the
+	// occurrences.
+	Until     time.Time
+	untilDate bool
+
+	ByDay      []Weekday
+	ByMonthDay []int
+	WeekStart  time.Weekday
+}
+
+// maxEmptyPeriods stops rules that never match again, e.g. the 30th of
+// every twelfth month starting in February.
+const maxEmptyPeriods = 1000
+
+var weekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=MO,WE". The "RRULE:"
+// prefix is optional and names are case-insensitive.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+
+	r := Rule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("%w: part %q is not NAME=VALUE", ErrInvalidRule, part)
+		}
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%w: %s is given twice", ErrInvalidRule, name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch f := Frequency(value); f {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = f
+			default:
+				err = fmt.Errorf("FREQ %s is not supported", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = positive(value)
+		case "COUNT":
+			r.Count, err = positive(value)
+		case "UNTIL":
+			err = r.parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseByMonthDay(value)
+		case "WKST":
+			var d Weekday
+			if d, err = parseWeekday(value); err == nil && d.N != 0 {
+				err = errors.New("WKST takes a plain weekday")
+			}
+			r.WeekStart = d.Day
+		default:
+			err = fmt.Errorf("%s is not supported", name)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+
+	if err := r.check(); err != nil {
+		return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+
+	return r, nil
+}
+
+// check rejects combinations of parts the evaluator does not handle.
+func (r Rule) check() error {
+	switch {
+	case r.Freq == "":
+		return errors.New("FREQ is required")
+	case r.Count > 0 && !r.Until.IsZero():
+		return errors.New("COUNT and UNTIL cannot be combined")
+	case len(r.ByMonthDay) > 0 && r.Freq != Monthly:
+		return errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	case len(r.ByMonthDay) > 0 && len(r.ByDay) > 0:
+		return errors.New("BYDAY and BYMONTHDAY cannot be combined")
+	case len(r.ByDay) > 0 && r.Freq == Yearly:
+		return errors.New("BYDAY is not supported with FREQ=YEARLY")
+	}
+
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly {
+			return errors.New("BYDAY ordinals are only supported with FREQ=MONTHLY")
+		}
+	}
+
+	return nil
+}
+
+func positive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s is not a positive integer", s)
+	}
+	return n, nil
+}
+
+func (r *Rule) parseUntil(s string) error {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		r.Until = t
+		return nil
+	}
+	if t, err := time.Parse("20060102", s); err == nil {
+		r.Until = t
+		r.untilDate = true
+		return nil
+	}
+	return fmt.Errorf("UNTIL %s is neither a date nor a UTC date-time", s)
+}
+
+func parseByDay(s string) ([]Weekday, error) {
+	var days []Weekday
+	for _, item := range strings.Split(s, ",") {
+		d, err := parseWeekday(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(days, d) {
+			days = append(days, d)
+		}
+	}
+	return days, nil
+}
+
+// parseWeekday reads an optionally numbered weekday such as MO or -1FR.
+func parseWeekday(s string) (Weekday, error) {
+	if len(s) < 2 {
+		return Weekday{}, fmt.Errorf("%q is not a weekday", s)
+	}
+
+	i := slices.Index(weekdays, s[len(s)-2:])
+	if i < 0 {
+		return Weekday{}, fmt.Errorf("%q is not a weekday", s)
+	}
+
+	d := Weekday{Day: time.Weekday(i)}
+	if n := s[:len(s)-2]; n != "" {
+		v, err := strconv.Atoi(n)
+		if err != nil || v == 0 || v < -5 || v > 5 {
+			return Weekday{}, fmt.Errorf("%q has an invalid ordinal", s)
+		}
+		d.N = v
+	}
+
+	return d, nil
+}
+
+func parseByMonthDay(s string) ([]int, error) {
+	var days []int
+	for _, item := range strings.Split(s, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || v == 0 || v < -31 || v > 31 {
+			return nil, fmt.Errorf("%q is not a day of the month", item)
+		}
+		if !slices.Contains(days, v) {
+			days = append(days, v)
+		}
+	}
+	return days, nil
+}
+
+// String formats the rule with its parts in a fixed order, so equal rules
+// read the same.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdays[r.WeekStart])
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		if r.untilDate {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func (d Weekday) String() string {
+	if d.N == 0 {
+		return weekdays[d.Day]
+	}
+	return strconv.Itoa(d.N) + weekdays[d.Day]
+}
+
+// All yields the occurrences of the rule from start on, in the location
+// of start. Every occurrence keeps the wall clock time of start, so a
+// daily rule at 09:00 stays at 09:00 across DST changes. start itself is
+// yielded only if it matches the rule.
+func (r Rule) All(start time.Time) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		loc := start.Location()
+		hour, minute, sec := start.Clock()
+		first := date(start.Year(), start.Month(), start.Day())
+
+		n, empty := 0, 0
+		for period := 0; empty < maxEmptyPeriods; period++ {
+			days := r.periodDays(first, period)
+			if len(days) == 0 {
+				empty++
+				continue
+			}
+			empty = 0
+
+			for _, day := range days {
+				t := wallTime(day.Year(), day.Month(), day.Day(), hour, minute, sec, start.Nanosecond(), loc)
+				if t.Before(start) {
+					continue
+				}
+				if r.ended(t, n) {
+					return
+				}
+				n++
+				if !yield(t) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// After returns the first occurrence strictly after t, false when the
+// rule ends before.
+func (r Rule) After(start, t time.Time) (time.Time, bool) {
+	for occ := range r.All(start) {
+		if occ.After(t) {
+			return occ, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ended reports whether t, following n earlier occurrences, is past the
+// end of the rule.
+func (r Rule) ended(t time.Time, n int) bool {
+	if r.Count > 0 && n >= r.Count {
+		return true
+	}
+	if r.Until.IsZero() {
+		return false
+	}
+	if r.untilDate {
+		return date(t.Year(), t.Month(), t.Day()).After(r.Until)
+	}
+	return t.After(r.Until)
+}
+
+// periodDays returns the days of the given period the rule matches, in
+// order. Days are midnights in UTC, which have no DST to get in the way.
+func (r Rule) periodDays(first time.Time, period int) []time.Time {
+	step := period * r.Interval
+
+	switch r.Freq {
+	case Daily:
+		day := first.AddDate(0, 0, step)
+		if len(r.ByDay) > 0 && !r.onWeekday(day) {
+			return nil
+		}
+		return []time.Time{day}
+
+	case Weekly:
+		offset := (int(first.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := first.AddDate(0, 0, 7*step-offset)
+
+		var days []time.Time
+		for i := range 7 {
+			day := weekStart.AddDate(0, 0, i)
+			if (len(r.ByDay) == 0 && day.Weekday() == first.Weekday()) || r.onWeekday(day) {
+				days = append(days, day)
+			}
+		}
+		return days
+
+	case Monthly:
+		return r.monthDays(date(first.Year(), first.Month()+time.Month(step), 1), first.Day())
+
+	case Yearly:
+		day := date(first.Year()+step, first.Month(), first.Day())
+		// February 29 only exists in leap years
+		if day.Day() != first.Day() {
+			return nil
+		}
+		return []time.Time{day}
+	}
+
+	return nil
+}
+
+func (r Rule) onWeekday(day time.Time) bool {
+	return slices.ContainsFunc(r.ByDay, func(d Weekday) bool { return d.Day == day.Weekday() })
+}
+
+// monthDays returns the matching days of the month starting at month.
+// Without BYDAY and BYMONTHDAY the rule repeats on startDay, skipping
+// months too short for it.
+func (r Rule) monthDays(month time.Time, startDay int) []time.Time {
+	length := month.AddDate(0, 1, -1).Day()
+
+	var nums []int
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = length + d + 1
+			}
+			nums = append(nums, d)
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			// the first day of the month that is this weekday
+			firstDay := 1 + (int(wd.Day)-int(month.Weekday())+7)%7
+			switch {
+			case wd.N > 0:
+				nums = append(nums, firstDay+7*(wd.N-1))
+			case wd.N < 0:
+				lastDay := firstDay + 7*((length-firstDay)/7)
+				nums = append(nums, lastDay+7*(wd.N+1))
+			default:
+				for d := firstDay; d <= length; d += 7 {
+					nums = append(nums, d)
+				}
+			}
+		}
+	default:
+		nums = []int{startDay}
+	}
+
+	slices.Sort(nums)
+	nums = slices.Compact(nums)
+
+	days := make([]time.Time, 0, len(nums))
+	for _, d := range nums {
+		if d >= 1 && d <= length {
+			days = append(days, month.AddDate(0, 0, d-1))
+		}
+	}
+	return days
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// wallTime returns the moment the clocks in loc show the given time.
+// A time skipped by a DST change is read with the offset in effect before
+// the change, so it lands after the gap; a time that happens twice is the
+// first one. Both follow RFC 5545.
+func wallTime(year int, month time.Month, day, hour, minute, sec, nsec int, loc *time.Location) time.Time {
+	wall := time.Date(year, month, day, hour, minute, sec, nsec, time.UTC)
+
+	// a DST change moves the clocks by hours, so the offsets a day away
+	// are the ones around it
+	_, before := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	early := wall.Add(-time.Duration(before) * time.Second).In(loc)
+	late := wall.Add(-time.Duration(after) * time.Second).In(loc)
+
+	shows := func(t time.Time) bool {
+		return t.Year() == year && t.Month() == month && t.Day() == day &&
+			t.Hour() == hour && t.Minute() == minute && t.Second() == sec
+	}
+
+	switch {
+	case shows(early) && shows(late):
+		if late.Before(early) {
+			return late
+		}
+		return early
+	case shows(late):
+		return late
+	default:
+		// early also covers the gap
+		return early
+	}
+}
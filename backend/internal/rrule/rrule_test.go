@@ -0,0 +1,262 @@
+package rrule
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{name: "daily", rule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{name: "prefix and case", rule: "rrule:freq=weekly;byday=mo,we,mo;interval=2", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"},
+		{name: "monthly by day", rule: "FREQ=MONTHLY;BYMONTHDAY=1,-1", want: "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{name: "monthly by ordinal weekday", rule: "FREQ=MONTHLY;BYDAY=2TU,-1FR", want: "FREQ=MONTHLY;BYDAY=2TU,-1FR"},
+		{name: "week start", rule: "FREQ=WEEKLY;WKST=SU", want: "FREQ=WEEKLY;WKST=SU"},
+		{name: "count", rule: "FREQ=YEARLY;COUNT=3", want: "FREQ=YEARLY;COUNT=3"},
+		{name: "until date", rule: "FREQ=DAILY;UNTIL=20300105", want: "FREQ=DAILY;UNTIL=20300105"},
+		{name: "until date-time", rule: "FREQ=DAILY;UNTIL=20300105T120000Z", want: "FREQ=DAILY;UNTIL=20300105T120000Z"},
+		{name: "empty -> error", rule: "", wantErr: true},
+		{name: "no freq -> error", rule: "INTERVAL=2", wantErr: true},
+		{name: "unknown freq -> error", rule: "FREQ=HOURLY", wantErr: true},
+		{name: "unsupported part -> error", rule: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+		{name: "part twice -> error", rule: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{name: "zero interval -> error", rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "count with until -> error", rule: "FREQ=DAILY;COUNT=2;UNTIL=20300105", wantErr: true},
+		{name: "floating until -> error", rule: "FREQ=DAILY;UNTIL=20300105T120000", wantErr: true},
+		{name: "bad weekday -> error", rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "ordinal in weekly -> error", rule: "FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+		{name: "month day in weekly -> error", rule: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{name: "month day out of range -> error", rule: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{name: "by day with month day -> error", rule: "FREQ=MONTHLY;BYDAY=MO;BYMONTHDAY=1", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			r, err := Parse(tc.rule)
+
+			// assert
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidRule) {
+					t.Fatalf("unexpected error: got %v, want %v", err, ErrInvalidRule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			if got := r.String(); got != tc.want {
+				t.Errorf("unexpected rule: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAll(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		n     int
+		want  []string
+	}{
+		{
+			name:  "daily every other day",
+			rule:  "FREQ=DAILY;INTERVAL=2",
+			start: "2030-01-30T09:00:00Z",
+			n:     3,
+			want:  []string{"2030-01-30T09:00:00Z", "2030-02-01T09:00:00Z", "2030-02-03T09:00:00Z"},
+		},
+		{
+			name:  "daily on weekdays",
+			rule:  "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			start: "2030-01-04T09:00:00Z", // Friday
+			n:     2,
+			want:  []string{"2030-01-04T09:00:00Z", "2030-01-07T09:00:00Z"},
+		},
+		{
+			name:  "weekly on given weekdays skips the start",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,FR",
+			start: "2030-01-02T09:00:00Z", // Wednesday
+			n:     3,
+			want:  []string{"2030-01-04T09:00:00Z", "2030-01-07T09:00:00Z", "2030-01-11T09:00:00Z"},
+		},
+		{
+			name:  "every other week",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			start: "2030-01-01T09:00:00Z", // Tuesday
+			n:     4,
+			want:  []string{"2030-01-01T09:00:00Z", "2030-01-03T09:00:00Z", "2030-01-15T09:00:00Z", "2030-01-17T09:00:00Z"},
+		},
+		{
+			name:  "monthly skips short months",
+			rule:  "FREQ=MONTHLY",
+			start: "2030-01-31T09:00:00Z",
+			n:     3,
+			want:  []string{"2030-01-31T09:00:00Z", "2030-03-31T09:00:00Z", "2030-05-31T09:00:00Z"},
+		},
+		{
+			name:  "monthly on the last day",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: "2030-01-15T09:00:00Z",
+			n:     3,
+			want:  []string{"2030-01-31T09:00:00Z", "2030-02-28T09:00:00Z", "2030-03-31T09:00:00Z"},
+		},
+		{
+			name:  "monthly on the second tuesday and the last friday",
+			rule:  "FREQ=MONTHLY;BYDAY=2TU,-1FR",
+			start: "2030-01-01T09:00:00Z",
+			n:     4,
+			want:  []string{"2030-01-08T09:00:00Z", "2030-01-25T09:00:00Z", "2030-02-12T09:00:00Z", "2030-02-22T09:00:00Z"},
+		},
+		{
+			name:  "yearly on leap day",
+			rule:  "FREQ=YEARLY",
+			start: "2028-02-29T09:00:00Z",
+			n:     2,
+			want:  []string{"2028-02-29T09:00:00Z", "2032-02-29T09:00:00Z"},
+		},
+		{
+			name:  "count",
+			rule:  "FREQ=DAILY;COUNT=2",
+			start: "2030-01-01T09:00:00Z",
+			n:     5,
+			want:  []string{"2030-01-01T09:00:00Z", "2030-01-02T09:00:00Z"},
+		},
+		{
+			name:  "until date is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20300102",
+			start: "2030-01-01T09:00:00Z",
+			n:     5,
+			want:  []string{"2030-01-01T09:00:00Z", "2030-01-02T09:00:00Z"},
+		},
+		{
+			name:  "until date-time",
+			rule:  "FREQ=DAILY;UNTIL=20300102T080000Z",
+			start: "2030-01-01T09:00:00Z",
+			n:     5,
+			want:  []string{"2030-01-01T09:00:00Z"},
+		},
+		{
+			name:  "never matching again",
+			rule:  "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=30",
+			start: "2030-02-01T09:00:00Z",
+			n:     1,
+			want:  nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			r, err := Parse(tc.rule)
+			if err != nil {
+				t.Fatalf("parse rule: %v", err)
+			}
+			start, err := time.Parse(time.RFC3339, tc.start)
+			if err != nil {
+				t.Fatalf("parse start: %v", err)
+			}
+
+			// act
+			var got []string
+			for occ := range r.All(start) {
+				if len(got) == tc.n {
+					break
+				}
+				got = append(got, occ.Format(time.RFC3339))
+			}
+
+			// assert
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("unexpected occurrences: got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAllAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		start time.Time
+		want  []string
+	}{
+		{
+			name:  "wall clock time is kept in spring",
+			start: time.Date(2030, time.March, 30, 9, 0, 0, 0, berlin),
+			want:  []string{"2030-03-30T09:00:00+01:00", "2030-03-31T09:00:00+02:00", "2030-04-01T09:00:00+02:00"},
+		},
+		{
+			name:  "wall clock time is kept in autumn",
+			start: time.Date(2030, time.October, 26, 9, 0, 0, 0, berlin),
+			want:  []string{"2030-10-26T09:00:00+02:00", "2030-10-27T09:00:00+01:00", "2030-10-28T09:00:00+01:00"},
+		},
+		{
+			name:  "skipped time moves past the gap",
+			start: time.Date(2030, time.March, 30, 2, 30, 0, 0, berlin),
+			want:  []string{"2030-03-30T02:30:00+01:00", "2030-03-31T03:30:00+02:00", "2030-04-01T02:30:00+02:00"},
+		},
+		{
+			name:  "repeated time is the first one",
+			start: time.Date(2030, time.October, 26, 2, 30, 0, 0, berlin),
+			want:  []string{"2030-10-26T02:30:00+02:00", "2030-10-27T02:30:00+02:00", "2030-10-28T02:30:00+01:00"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			r, err := Parse("FREQ=DAILY")
+			if err != nil {
+				t.Fatalf("parse rule: %v", err)
+			}
+
+			// act
+			var got []string
+			for occ := range r.All(tc.start) {
+				if len(got) == len(tc.want) {
+					break
+				}
+				got = append(got, occ.Format(time.RFC3339))
+			}
+
+			// assert
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("unexpected occurrences: got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAfter(t *testing.T) {
+	// preparing
+	r, err := Parse("FREQ=WEEKLY;BYDAY=MO;COUNT=2")
+	if err != nil {
+		t.Fatalf("parse rule: %v", err)
+	}
+	start := time.Date(2030, time.January, 7, 9, 0, 0, 0, time.UTC)
+
+	// act
+	next, ok := r.After(start, start)
+	_, more := r.After(start, next)
+
+	// assert
+	if want := start.AddDate(0, 0, 7); !ok || !next.Equal(want) {
+		t.Errorf("unexpected next occurrence: got %v %t, want %v", next, ok, want)
+	}
+	if more {
+		t.Errorf("the rule must end after COUNT occurrences")
+	}
+}
@@ -0,0 +1,121 @@
+// Package undo keeps the recent commands of every user, so they can be
+// undone and redone.
+//
+// Every user has two stacks: the commands done and the commands undone. A
+// new command forgets the undone ones, as they no longer follow from the
+// current state. The log lives in memory only: it is empty after a
+// restart.
+package undo
+
+import (
+	"sync"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// DefaultDepth is how many commands of a user a log keeps by default.
+const DefaultDepth = 50
+
+// Log is a command log safe for concurrent use. Undo and redo of the same
+// user run one at a time.
+type Log struct {
+	depth int
+
+	mu    sync.Mutex
+	users map[domain.CommandOwner]*stacks
+}
+
+type stacks struct {
+	// mu is held while a command is applied, so the stacks stay as they
+	// were until it is known whether it succeeded.
+	mu     sync.Mutex
+	done   []domain.Command
+	undone []domain.Command
+}
+
+// NewLog returns a log that keeps the latest depth commands of every user.
+func NewLog(depth int) *Log {
+	if depth <= 0 {
+		depth = DefaultDepth
+	}
+	return &Log{
+		depth: depth,
+		users: make(map[domain.CommandOwner]*stacks),
+	}
+}
+
+func (l *Log) stacks(owner domain.CommandOwner) *stacks {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s, ok := l.users[owner]
+	if !ok {
+		s = &stacks{}
+		l.users[owner] = s
+	}
+	return s
+}
+
+// Record adds a command the user has just done. The oldest one is dropped
+// beyond the depth, and the undone ones can no longer be redone.
+func (l *Log) Record(owner domain.CommandOwner, cmd domain.Command) {
+	s := l.stacks(owner)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.done = push(s.done, cmd, l.depth)
+	s.undone = nil
+}
+
+// Undo hands the last command done by the user to apply, which returns the
+// command that reverted it. That one is kept to be redone. A command that
+// fails to apply is dropped, so it does not block the ones before it.
+func (l *Log) Undo(owner domain.CommandOwner, apply func(domain.Command) (domain.Command, error)) (domain.Command, error) {
+	s := l.stacks(owner)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return l.move(&s.done, &s.undone, apply, domain.ErrNothingToUndo)
+}
+
+// Redo hands the last command undone by the user to apply, which returns
+// the command done again. That one can be undone once more. A command
+// that fails to apply is dropped.
+func (l *Log) Redo(owner domain.CommandOwner, apply func(domain.Command) (domain.Command, error)) (domain.Command, error) {
+	s := l.stacks(owner)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return l.move(&s.undone, &s.done, apply, domain.ErrNothingToRedo)
+}
+
+// move applies the top command of from and pushes the result onto to.
+// Must be called with the mutex of the stacks held.
+func (l *Log) move(from, to *[]domain.Command, apply func(domain.Command) (domain.Command, error), empty error) (domain.Command, error) {
+	if len(*from) == 0 {
+		return domain.Command{}, empty
+	}
+
+	cmd := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+
+	applied, err := apply(cmd)
+	if err != nil {
+		return domain.Command{}, err
+	}
+	*to = push(*to, applied, l.depth)
+
+	return applied, nil
+}
+
+func push(stack []domain.Command, cmd domain.Command, depth int) []domain.Command {
+	stack = append(stack, cmd)
+	if len(stack) > depth {
+		// a fresh slice, so the dropped commands are not kept alive
+		stack = append([]domain.Command(nil), stack[len(stack)-depth:]...)
+	}
+	return stack
+}
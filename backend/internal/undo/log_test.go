@@ -0,0 +1,157 @@
+package undo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+var (
+	ann = domain.CommandOwner{Tenant: domain.DefaultTenant, UserID: 1}
+	bob = domain.CommandOwner{Tenant: domain.DefaultTenant, UserID: 2}
+)
+
+// command is told apart by the id of its only step.
+func command(id int) domain.Command {
+	return domain.Command{Steps: []domain.CommandStep{{ID: id}}}
+}
+
+// inverse applies nothing and returns the command reverting cmd.
+func inverse(cmd domain.Command) (domain.Command, error) {
+	return cmd.Inverse(), nil
+}
+
+func stepID(t *testing.T, cmd domain.Command) int {
+	t.Helper()
+
+	if len(cmd.Steps) != 1 {
+		t.Fatalf("unexpected command: got %+v", cmd)
+	}
+	return cmd.Steps[0].ID
+}
+
+func TestUndoRedo(t *testing.T) {
+	t.Run("latest first, redone in reverse", func(t *testing.T) {
+		// preparing
+		log := NewLog(0)
+		log.Record(ann, command(1))
+		log.Record(ann, command(2))
+
+		// act and assert
+		for _, want := range []int{2, 1} {
+			cmd, err := log.Undo(ann, inverse)
+			if err != nil {
+				t.Fatalf("unexpected error on undo: got %v, want nil", err)
+			}
+			if got := stepID(t, cmd); got != want {
+				t.Errorf("unexpected undo: got %d, want %d", got, want)
+			}
+		}
+		if _, err := log.Undo(ann, inverse); !errors.Is(err, domain.ErrNothingToUndo) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrNothingToUndo)
+		}
+
+		for _, want := range []int{1, 2} {
+			cmd, err := log.Redo(ann, inverse)
+			if err != nil {
+				t.Fatalf("unexpected error on redo: got %v, want nil", err)
+			}
+			if got := stepID(t, cmd); got != want {
+				t.Errorf("unexpected redo: got %d, want %d", got, want)
+			}
+		}
+		if _, err := log.Redo(ann, inverse); !errors.Is(err, domain.ErrNothingToRedo) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrNothingToRedo)
+		}
+	})
+
+	t.Run("a new command forgets the undone ones", func(t *testing.T) {
+		// preparing
+		log := NewLog(0)
+		log.Record(ann, command(1))
+		if _, err := log.Undo(ann, inverse); err != nil {
+			t.Fatalf("unexpected error on undo: got %v, want nil", err)
+		}
+
+		// act
+		log.Record(ann, command(2))
+
+		// assert
+		if _, err := log.Redo(ann, inverse); !errors.Is(err, domain.ErrNothingToRedo) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrNothingToRedo)
+		}
+	})
+
+	t.Run("failed command is dropped", func(t *testing.T) {
+		// preparing
+		log := NewLog(0)
+		log.Record(ann, command(1))
+		log.Record(ann, command(2))
+
+		// act
+		_, err := log.Undo(ann, func(cmd domain.Command) (domain.Command, error) {
+			return domain.Command{}, domain.ErrUndoConflict
+		})
+
+		// assert
+		if !errors.Is(err, domain.ErrUndoConflict) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrUndoConflict)
+		}
+
+		cmd, err := log.Undo(ann, inverse)
+		if err != nil {
+			t.Fatalf("unexpected error on undo: got %v, want nil", err)
+		}
+		if got := stepID(t, cmd); got != 1 {
+			t.Errorf("unexpected undo: got %d, want %d", got, 1)
+		}
+		if _, err := log.Redo(ann, inverse); err != nil {
+			t.Errorf("only the undone command must be kept: got %v, want nil", err)
+		}
+		if _, err := log.Redo(ann, inverse); !errors.Is(err, domain.ErrNothingToRedo) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrNothingToRedo)
+		}
+	})
+
+	t.Run("keeps the latest commands up to the depth", func(t *testing.T) {
+		// preparing
+		log := NewLog(2)
+		for id := 1; id <= 3; id++ {
+			log.Record(ann, command(id))
+		}
+
+		// act
+		var got []int
+		for {
+			cmd, err := log.Undo(ann, inverse)
+			if err != nil {
+				break
+			}
+			got = append(got, stepID(t, cmd))
+		}
+
+		// assert
+		if len(got) != 2 || got[0] != 3 || got[1] != 2 {
+			t.Errorf("unexpected undone commands: got %v, want %v", got, []int{3, 2})
+		}
+	})
+
+	t.Run("users and workspaces are apart", func(t *testing.T) {
+		// preparing
+		log := NewLog(0)
+		log.Record(ann, command(1))
+
+		// act and assert
+		if _, err := log.Undo(bob, inverse); !errors.Is(err, domain.ErrNothingToUndo) {
+			t.Errorf("unexpected error for another user: got %v, want %v", err, domain.ErrNothingToUndo)
+		}
+		acme := domain.CommandOwner{Tenant: "acme", UserID: ann.UserID}
+		if _, err := log.Undo(acme, inverse); !errors.Is(err, domain.ErrNothingToUndo) {
+			t.Errorf("unexpected error for another workspace: got %v, want %v", err, domain.ErrNothingToUndo)
+		}
+		if _, err := log.Undo(ann, inverse); err != nil {
+			t.Errorf("unexpected error for the user: got %v, want nil", err)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/auth"
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// APIKeyTouchInterval limits how often the last use of a key is written:
+// scripts may make many requests a second, auditing needs far less.
+const APIKeyTouchInterval = time.Minute
+
+// apiKeyPrefixChars is how much of the random part is kept in plain text.
+const apiKeyPrefixChars = 8
+
+// CreateAPIKey issues a key to the user of the context. The returned key
+// is the only time its secret is available, only the hash is stored.
+func (a *AuthUseCase) CreateAPIKey(ctx context.Context, key domain.APIKey) (domain.IssuedAPIKey, error) {
+	user, err := loggedInUser(ctx)
+	if err != nil {
+		return domain.IssuedAPIKey{}, err
+	}
+
+	if err := key.Validate(); err != nil {
+		return domain.IssuedAPIKey{}, fmt.Errorf("validate api key: %w", err)
+	}
+
+	secret, err := auth.GenerateKey(domain.APIKeyPrefix)
+	if err != nil {
+		return domain.IssuedAPIKey{}, err
+	}
+
+	stored := domain.APIKey{
+		UserID:    user.ID,
+		Name:      key.Name,
+		Prefix:    secret[:len(domain.APIKeyPrefix)+apiKeyPrefixChars],
+		Hash:      auth.HashKey(secret),
+		Scopes:    domain.NormalizeScopes(key.Scopes),
+		CreatedAt: a.now(),
+	}
+
+	id, err := a.Users.SaveAPIKey(ctx, stored)
+	if err != nil {
+		return domain.IssuedAPIKey{}, fmt.Errorf("save api key in db: %w", err)
+	}
+	stored.ID = id
+
+	return domain.IssuedAPIKey{APIKey: stored, Key: secret}, nil
+}
+
+// ListAPIKeys returns the keys of the user of the context.
+func (a *AuthUseCase) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	user, err := loggedInUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := a.Users.ListAPIKeys(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey deletes a key of the user of the context; requests with it
+// fail from then on.
+func (a *AuthUseCase) RevokeAPIKey(ctx context.Context, id int) error {
+	user, err := loggedInUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := a.Users.DeleteAPIKey(ctx, user.ID, id); err != nil {
+		return fmt.Errorf("delete api key: %w", err)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey returns the user a key was issued to and the key
+// itself, with its scopes. Keys are looked up in the workspace of the
+// context only. It fails with domain.ErrUnauthenticated for an unknown or
+// revoked key.
+func (a *AuthUseCase) AuthenticateAPIKey(ctx context.Context, key string) (domain.User, domain.APIKey, error) {
+	stored, err := a.Users.GetAPIKeyByHash(ctx, auth.HashKey(key))
+	if errors.Is(err, domain.ErrAPIKeyNotExist) {
+		return domain.User{}, domain.APIKey{}, fmt.Errorf("%w: unknown api key", domain.ErrUnauthenticated)
+	}
+	if err != nil {
+		return domain.User{}, domain.APIKey{}, fmt.Errorf("get api key: %w", err)
+	}
+
+	user, err := a.Users.GetUserByID(ctx, stored.UserID)
+	if errors.Is(err, domain.ErrUserNotExist) {
+		return domain.User{}, domain.APIKey{}, fmt.Errorf("%w: user does not exist", domain.ErrUnauthenticated)
+	}
+	if err != nil {
+		return domain.User{}, domain.APIKey{}, fmt.Errorf("get user by id: %w", err)
+	}
+	user.Tenant = domain.CurrentTenant(ctx)
+
+	now := a.now()
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= APIKeyTouchInterval {
+		// a failed audit write must not lock scripts out
+		if err := a.Users.TouchAPIKey(ctx, stored.ID, now); err != nil {
+			slog.Warn("failed to record api key use", "id", stored.ID, "error", err)
+		} else {
+			stored.LastUsedAt = &now
+		}
+	}
+
+	return user, stored, nil
+}
+
+// loggedInUser returns the user of the context. Keys are managed only
+// after a login, so a leaked key cannot issue itself successors.
+func loggedInUser(ctx context.Context) (domain.User, error) {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.User{}, domain.ErrUnauthenticated
+	}
+	if _, ok := domain.APIKeyFromContext(ctx); ok {
+		return domain.User{}, fmt.Errorf("%w: api keys are managed after a login", domain.ErrInsufficientScope)
+	}
+	return user, nil
+}
@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	ann := domain.User{ID: 1, Email: "ann@example.com"}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		key  domain.APIKey
+
+		wantErr    error
+		wantScopes []domain.Scope
+	}{
+		{
+			name:       "without scopes -> every scope",
+			ctx:        domain.ContextWithUser(context.Background(), ann),
+			key:        domain.APIKey{Name: "backup"},
+			wantScopes: domain.AllScopes,
+		},
+		{
+			name:       "scopes are deduplicated",
+			ctx:        domain.ContextWithUser(context.Background(), ann),
+			key:        domain.APIKey{Name: "report", Scopes: []domain.Scope{domain.ScopeTodosRead, domain.ScopeTodosRead}},
+			wantScopes: []domain.Scope{domain.ScopeTodosRead},
+		},
+		{
+			name:    "unknown scope -> error",
+			ctx:     domain.ContextWithUser(context.Background(), ann),
+			key:     domain.APIKey{Name: "report", Scopes: []domain.Scope{"todos:admin"}},
+			wantErr: domain.ErrUnknownValue,
+		},
+		{
+			name:    "blank name -> error",
+			ctx:     domain.ContextWithUser(context.Background(), ann),
+			key:     domain.APIKey{Name: "  "},
+			wantErr: domain.ErrBlank,
+		},
+		{
+			name:    "without a user -> error",
+			ctx:     context.Background(),
+			key:     domain.APIKey{Name: "backup"},
+			wantErr: domain.ErrUnauthenticated,
+		},
+		{
+			name: "with an api key -> error",
+			ctx: domain.ContextWithAPIKey(
+				domain.ContextWithUser(context.Background(), ann),
+				domain.APIKey{ID: 1, UserID: ann.ID, Scopes: domain.AllScopes},
+			),
+			key:     domain.APIKey{Name: "backup"},
+			wantErr: domain.ErrInsufficientScope,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			now := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+			repo := newUserRepo()
+			usecase := newTestAuth(repo, &now)
+
+			// act
+			issued, err := usecase.CreateAPIKey(tc.ctx, tc.key)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if repo.SaveAPIKeyCalls != 0 {
+					t.Errorf("unexpected key: got %+v", repo.LastSavedAPIKey)
+				}
+				return
+			}
+
+			if !strings.HasPrefix(issued.Key, domain.APIKeyPrefix) || !strings.HasPrefix(issued.Key, issued.Prefix) {
+				t.Errorf("unexpected key: got %q with prefix %q", issued.Key, issued.Prefix)
+			}
+			if !slices.Equal(issued.Scopes, tc.wantScopes) {
+				t.Errorf("unexpected scopes: got %v, want %v", issued.Scopes, tc.wantScopes)
+			}
+			saved := repo.LastSavedAPIKey
+			if saved.UserID != ann.ID || !saved.CreatedAt.Equal(now) {
+				t.Errorf("unexpected saved key: got %+v", saved)
+			}
+			if saved.Hash == "" || strings.Contains(saved.Hash, issued.Key) {
+				t.Errorf("key must be stored hashed: got %q", saved.Hash)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	// preparing
+	now := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+	repo := newUserRepo()
+	usecase := newTestAuth(repo, &now)
+
+	user, err := usecase.Register(context.Background(), domain.Credentials{Email: "ann@example.com", Password: "password1"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	ctx := domain.ContextWithUser(context.Background(), user)
+	issued, err := usecase.CreateAPIKey(ctx, domain.APIKey{Name: "backup", Scopes: []domain.Scope{domain.ScopeTodosRead}})
+	if err != nil {
+		t.Fatalf("create api key: %v", err)
+	}
+
+	// act
+	gotUser, gotKey, err := usecase.AuthenticateAPIKey(context.Background(), issued.Key)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	if gotUser.ID != user.ID || gotKey.ID != issued.ID || !gotKey.Allows(domain.ScopeTodosRead) || gotKey.Allows(domain.ScopeTodosWrite) {
+		t.Errorf("unexpected user or key: got %+v, %+v", gotUser, gotKey)
+	}
+	if gotKey.LastUsedAt == nil || !gotKey.LastUsedAt.Equal(now) {
+		t.Errorf("last use must be recorded: got %v, want %v", gotKey.LastUsedAt, now)
+	}
+
+	t.Run("last use is written once per interval", func(t *testing.T) {
+		now = now.Add(APIKeyTouchInterval / 2)
+		if _, _, err := usecase.AuthenticateAPIKey(context.Background(), issued.Key); err != nil {
+			t.Fatalf("authenticate: %v", err)
+		}
+		if repo.TouchAPIKeyCalls != 1 {
+			t.Fatalf("unexpected touches: got %d, want 1", repo.TouchAPIKeyCalls)
+		}
+
+		now = now.Add(APIKeyTouchInterval)
+		if _, _, err := usecase.AuthenticateAPIKey(context.Background(), issued.Key); err != nil {
+			t.Fatalf("authenticate: %v", err)
+		}
+		if repo.TouchAPIKeyCalls != 2 || !repo.LastUsedAt.Equal(now) {
+			t.Errorf("unexpected touch: got %d calls, last %v", repo.TouchAPIKeyCalls, repo.LastUsedAt)
+		}
+	})
+
+	t.Run("other user cannot revoke the key", func(t *testing.T) {
+		other := domain.ContextWithUser(context.Background(), domain.User{ID: user.ID + 1})
+		if err := usecase.RevokeAPIKey(other, issued.ID); !errors.Is(err, domain.ErrAPIKeyNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrAPIKeyNotExist)
+		}
+		keys, err := usecase.ListAPIKeys(other)
+		if err != nil || len(keys) != 0 {
+			t.Errorf("unexpected keys: got %v, %v", keys, err)
+		}
+	})
+
+	t.Run("revoked key -> error", func(t *testing.T) {
+		if err := usecase.RevokeAPIKey(ctx, issued.ID); err != nil {
+			t.Fatalf("revoke: %v", err)
+		}
+		if _, _, err := usecase.AuthenticateAPIKey(context.Background(), issued.Key); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrUnauthenticated)
+		}
+	})
+
+	t.Run("unknown key -> error", func(t *testing.T) {
+		if _, _, err := usecase.AuthenticateAPIKey(context.Background(), domain.APIKeyPrefix+"garbage"); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrUnauthenticated)
+		}
+	})
+}
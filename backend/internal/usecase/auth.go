@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/auth"
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// UserRepository stores users, their sessions and API keys. Emails are
+// stored normalized, see domain.NormalizeEmail.
+type UserRepository interface {
+	// SaveUser fails with domain.ErrUserExists when the email is taken.
+	SaveUser(ctx context.Context, user domain.User) (int, error)
+	// GetUserByID and GetUserByEmail fail with domain.ErrUserNotExist.
+	GetUserByID(ctx context.Context, id int) (domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (domain.User, error)
+
+	SaveSession(ctx context.Context, session domain.Session) error
+	// GetSession and DeleteSession fail with domain.ErrSessionNotExist.
+	GetSession(ctx context.Context, id string) (domain.Session, error)
+	DeleteSession(ctx context.Context, id string) error
+
+	SaveAPIKey(ctx context.Context, key domain.APIKey) (int, error)
+	// GetAPIKeyByHash and TouchAPIKey fail with domain.ErrAPIKeyNotExist.
+	GetAPIKeyByHash(ctx context.Context, hash string) (domain.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error
+	// ListAPIKeys returns the keys of the user ordered by id.
+	ListAPIKeys(ctx context.Context, userID int) ([]domain.APIKey, error)
+	// DeleteAPIKey fails with domain.ErrAPIKeyNotExist also when the key
+	// belongs to another user.
+	DeleteAPIKey(ctx context.Context, userID, id int) error
+}
+
+// DefaultTokenTTL is how long a login lasts unless configured otherwise.
+const DefaultTokenTTL = 24 * time.Hour
+
+type AuthUseCase struct {
+	Users UserRepository
+	// Secret signs the access tokens.
+	Secret []byte
+	// TokenTTL is the lifetime of a session and of its token.
+	TokenTTL time.Duration
+	// PasswordIterations is the work factor of new password hashes.
+	PasswordIterations int
+	// Now is the clock used for sessions, time.Now when nil.
+	Now func() time.Time
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+func NewAuth(repo UserRepository, secret []byte) *AuthUseCase {
+	return &AuthUseCase{
+		Users:              repo,
+		Secret:             secret,
+		TokenTTL:           DefaultTokenTTL,
+		PasswordIterations: auth.DefaultIterations,
+		Now:                time.Now,
+	}
+}
+
+func (a *AuthUseCase) now() time.Time {
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+	return now().UTC().Truncate(time.Microsecond)
+}
+
+// Register creates a user with the credentials.
+func (a *AuthUseCase) Register(ctx context.Context, c domain.Credentials) (domain.User, error) {
+	c.Email = domain.NormalizeEmail(c.Email)
+	if err := c.Validate(); err != nil {
+		return domain.User{}, fmt.Errorf("validate credentials: %w", err)
+	}
+
+	hash, err := auth.HashPassword(c.Password, a.PasswordIterations)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("hash password: %w", err)
+	}
+
+	user := domain.User{
+		Email:        c.Email,
+		Tenant:       domain.CurrentTenant(ctx),
+		PasswordHash: hash,
+		CreatedAt:    a.now(),
+	}
+
+	id, err := a.Users.SaveUser(ctx, user)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("save user in db: %w", err)
+	}
+	user.ID = id
+
+	return user, nil
+}
+
+// Login checks the credentials, opens a session and returns a token for
+// it. Unknown emails and wrong passwords fail alike, with
+// domain.ErrInvalidCredentials, and take about the same time.
+func (a *AuthUseCase) Login(ctx context.Context, c domain.Credentials) (domain.AccessToken, error) {
+	user, err := a.Users.GetUserByEmail(ctx, domain.NormalizeEmail(c.Email))
+	if errors.Is(err, domain.ErrUserNotExist) {
+		// hash anyway, so response times do not tell which emails exist
+		_, _ = auth.VerifyPassword(c.Password, a.dummy())
+		return domain.AccessToken{}, domain.ErrInvalidCredentials
+	}
+	if err != nil {
+		return domain.AccessToken{}, fmt.Errorf("get user by email: %w", err)
+	}
+
+	ok, err := auth.VerifyPassword(c.Password, user.PasswordHash)
+	if err != nil {
+		return domain.AccessToken{}, fmt.Errorf("verify password: %w", err)
+	}
+	if !ok {
+		return domain.AccessToken{}, domain.ErrInvalidCredentials
+	}
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		return domain.AccessToken{}, err
+	}
+
+	now := a.now()
+	session := domain.Session{
+		ID:        sessionID,
+		UserID:    user.ID,
+		CreatedAt: now,
+		// tokens count expiry in whole seconds
+		ExpiresAt: now.Add(a.TokenTTL).Truncate(time.Second),
+	}
+	if err := a.Users.SaveSession(ctx, session); err != nil {
+		return domain.AccessToken{}, fmt.Errorf("save session in db: %w", err)
+	}
+
+	token, err := auth.SignToken(a.Secret, auth.Claims{
+		Subject:   strconv.Itoa(user.ID),
+		SessionID: session.ID,
+		Tenant:    string(domain.CurrentTenant(ctx)),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: session.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return domain.AccessToken{}, fmt.Errorf("sign token: %w", err)
+	}
+
+	return domain.AccessToken{Token: token, TokenType: "Bearer", ExpiresAt: session.ExpiresAt}, nil
+}
+
+// Logout ends the session of the token, revoking it.
+func (a *AuthUseCase) Logout(ctx context.Context, token string) error {
+	claims, ctx, err := a.parse(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	err = a.Users.DeleteSession(ctx, claims.SessionID)
+	if err != nil && !errors.Is(err, domain.ErrSessionNotExist) {
+		return fmt.Errorf("delete session: %w", err)
+	}
+
+	return nil
+}
+
+// Authenticate returns the user a token was issued to, with the workspace
+// of the token. It fails with domain.ErrUnauthenticated for a malformed,
+// expired or revoked token and for a token of another workspace than the
+// one the request named.
+func (a *AuthUseCase) Authenticate(ctx context.Context, token string) (domain.User, error) {
+	claims, ctx, err := a.parse(ctx, token)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	session, err := a.Users.GetSession(ctx, claims.SessionID)
+	if errors.Is(err, domain.ErrSessionNotExist) {
+		return domain.User{}, fmt.Errorf("%w: session has ended", domain.ErrUnauthenticated)
+	}
+	if err != nil {
+		return domain.User{}, fmt.Errorf("get session: %w", err)
+	}
+	if strconv.Itoa(session.UserID) != claims.Subject || !a.now().Before(session.ExpiresAt) {
+		return domain.User{}, fmt.Errorf("%w: session has ended", domain.ErrUnauthenticated)
+	}
+
+	user, err := a.Users.GetUserByID(ctx, session.UserID)
+	if errors.Is(err, domain.ErrUserNotExist) {
+		return domain.User{}, fmt.Errorf("%w: user does not exist", domain.ErrUnauthenticated)
+	}
+	if err != nil {
+		return domain.User{}, fmt.Errorf("get user by id: %w", err)
+	}
+	user.Tenant = domain.CurrentTenant(ctx)
+
+	return user, nil
+}
+
+// parse checks the token and returns the context scoped to the workspace
+// the token was issued in. Tokens without one predate workspaces and
+// belong to the default workspace.
+func (a *AuthUseCase) parse(ctx context.Context, token string) (auth.Claims, context.Context, error) {
+	claims, err := auth.ParseToken(a.Secret, token, a.now())
+	if err != nil {
+		return auth.Claims{}, ctx, fmt.Errorf("%w: %v", domain.ErrUnauthenticated, err)
+	}
+
+	tenant := domain.Tenant(claims.Tenant)
+	if tenant == "" {
+		tenant = domain.DefaultTenant
+	}
+	if named, ok := domain.TenantFromContext(ctx); ok && named != tenant {
+		return auth.Claims{}, ctx, fmt.Errorf("%w: token was issued in another workspace", domain.ErrUnauthenticated)
+	}
+
+	return claims, domain.ContextWithTenant(ctx, tenant), nil
+}
+
+// dummy is the hash passwords of unknown emails are checked against.
+func (a *AuthUseCase) dummy() string {
+	a.dummyOnce.Do(func() {
+		hash, err := auth.HashPassword("", a.PasswordIterations)
+		if err == nil {
+			a.dummyHash = hash
+		}
+	})
+	return a.dummyHash
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,170 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+type UserRepositoryMock struct {
+	SaveUserFunc       func(ctx context.Context, user domain.User) (int, error)
+	GetUserByIDFunc    func(ctx context.Context, id int) (domain.User, error)
+	GetUserByEmailFunc func(ctx context.Context, email string) (domain.User, error)
+	SaveSessionFunc    func(ctx context.Context, session domain.Session) error
+	GetSessionFunc     func(ctx context.Context, id string) (domain.Session, error)
+	DeleteSessionFunc  func(ctx context.Context, id string) error
+
+	SaveAPIKeyFunc      func(ctx context.Context, key domain.APIKey) (int, error)
+	GetAPIKeyByHashFunc func(ctx context.Context, hash string) (domain.APIKey, error)
+	TouchAPIKeyFunc     func(ctx context.Context, id int, usedAt time.Time) error
+	ListAPIKeysFunc     func(ctx context.Context, userID int) ([]domain.APIKey, error)
+	DeleteAPIKeyFunc    func(ctx context.Context, userID, id int) error
+
+	SaveUserCalls       int
+	GetUserByIDCalls    int
+	GetUserByEmailCalls int
+	SaveSessionCalls    int
+	GetSessionCalls     int
+	DeleteSessionCalls  int
+
+	SaveAPIKeyCalls      int
+	GetAPIKeyByHashCalls int
+	TouchAPIKeyCalls     int
+	ListAPIKeysCalls     int
+	DeleteAPIKeyCalls    int
+
+	LastSavedUser    domain.User
+	LastUserID       int
+	LastEmail        string
+	LastSavedSession domain.Session
+	LastSessionID    string
+
+	LastSavedAPIKey domain.APIKey
+	LastAPIKeyHash  string
+	LastAPIKeyID    int
+	LastUsedAt      time.Time
+}
+
+func (t *UserRepositoryMock) SaveUser(ctx context.Context, user domain.User) (int, error) {
+	t.SaveUserCalls++
+	t.LastSavedUser = user
+
+	if t.SaveUserFunc == nil {
+		panic("SaveUserFunc is nil")
+	}
+
+	return t.SaveUserFunc(ctx, user)
+}
+
+func (t *UserRepositoryMock) GetUserByID(ctx context.Context, id int) (domain.User, error) {
+	t.GetUserByIDCalls++
+	t.LastUserID = id
+
+	if t.GetUserByIDFunc == nil {
+		panic("GetUserByIDFunc is nil")
+	}
+
+	return t.GetUserByIDFunc(ctx, id)
+}
+
+func (t *UserRepositoryMock) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	t.GetUserByEmailCalls++
+	t.LastEmail = email
+
+	if t.GetUserByEmailFunc == nil {
+		panic("GetUserByEmailFunc is nil")
+	}
+
+	return t.GetUserByEmailFunc(ctx, email)
+}
+
+func (t *UserRepositoryMock) SaveSession(ctx context.Context, session domain.Session) error {
+	t.SaveSessionCalls++
+	t.LastSavedSession = session
+
+	if t.SaveSessionFunc == nil {
+		panic("SaveSessionFunc is nil")
+	}
+
+	return t.SaveSessionFunc(ctx, session)
+}
+
+func (t *UserRepositoryMock) GetSession(ctx context.Context, id string) (domain.Session, error) {
+	t.GetSessionCalls++
+	t.LastSessionID = id
+
+	if t.GetSessionFunc == nil {
+		panic("GetSessionFunc is nil")
+	}
+
+	return t.GetSessionFunc(ctx, id)
+}
+
+func (t *UserRepositoryMock) DeleteSession(ctx context.Context, id string) error {
+	t.DeleteSessionCalls++
+	t.LastSessionID = id
+
+	if t.DeleteSessionFunc == nil {
+		panic("DeleteSessionFunc is nil")
+	}
+
+	return t.DeleteSessionFunc(ctx, id)
+}
+
+func (t *UserRepositoryMock) SaveAPIKey(ctx context.Context, key domain.APIKey) (int, error) {
+	t.SaveAPIKeyCalls++
+	t.LastSavedAPIKey = key
+
+	if t.SaveAPIKeyFunc == nil {
+		panic("SaveAPIKeyFunc is nil")
+	}
+
+	return t.SaveAPIKeyFunc(ctx, key)
+}
+
+func (t *UserRepositoryMock) GetAPIKeyByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	t.GetAPIKeyByHashCalls++
+	t.LastAPIKeyHash = hash
+
+	if t.GetAPIKeyByHashFunc == nil {
+		panic("GetAPIKeyByHashFunc is nil")
+	}
+
+	return t.GetAPIKeyByHashFunc(ctx, hash)
+}
+
+func (t *UserRepositoryMock) TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error {
+	t.TouchAPIKeyCalls++
+	t.LastAPIKeyID = id
+	t.LastUsedAt = usedAt
+
+	if t.TouchAPIKeyFunc == nil {
+		panic("TouchAPIKeyFunc is nil")
+	}
+
+	return t.TouchAPIKeyFunc(ctx, id, usedAt)
+}
+
+func (t *UserRepositoryMock) ListAPIKeys(ctx context.Context, userID int) ([]domain.APIKey, error) {
+	t.ListAPIKeysCalls++
+	t.LastUserID = userID
+
+	if t.ListAPIKeysFunc == nil {
+		panic("ListAPIKeysFunc is nil")
+	}
+
+	return t.ListAPIKeysFunc(ctx, userID)
+}
+
+func (t *UserRepositoryMock) DeleteAPIKey(ctx context.Context, userID, id int) error {
+	t.DeleteAPIKeyCalls++
+	t.LastUserID = userID
+	t.LastAPIKeyID = id
+
+	if t.DeleteAPIKeyFunc == nil {
+		panic("DeleteAPIKeyFunc is nil")
+	}
+
+	return t.DeleteAPIKeyFunc(ctx, userID, id)
+}
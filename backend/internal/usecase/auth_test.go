@@ -0,0 +1,368 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// newUserRepo returns a mock keeping users and sessions in maps.
+func newUserRepo() *UserRepositoryMock {
+	users := map[int]domain.User{}
+	sessions := map[string]domain.Session{}
+	keys := map[int]domain.APIKey{}
+	nextKeyID := 1
+
+	return &UserRepositoryMock{
+		SaveUserFunc: func(ctx context.Context, user domain.User) (int, error) {
+			for _, u := range users {
+				if u.Email == user.Email {
+					return 0, domain.ErrUserExists
+				}
+			}
+			user.ID = len(users) + 1
+			users[user.ID] = user
+			return user.ID, nil
+		},
+		GetUserByIDFunc: func(ctx context.Context, id int) (domain.User, error) {
+			if u, ok := users[id]; ok {
+				return u, nil
+			}
+			return domain.User{}, domain.ErrUserNotExist
+		},
+		GetUserByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+			for _, u := range users {
+				if u.Email == email {
+					return u, nil
+				}
+			}
+			return domain.User{}, domain.ErrUserNotExist
+		},
+		SaveSessionFunc: func(ctx context.Context, session domain.Session) error {
+			sessions[session.ID] = session
+			return nil
+		},
+		GetSessionFunc: func(ctx context.Context, id string) (domain.Session, error) {
+			if s, ok := sessions[id]; ok {
+				return s, nil
+			}
+			return domain.Session{}, domain.ErrSessionNotExist
+		},
+		DeleteSessionFunc: func(ctx context.Context, id string) error {
+			if _, ok := sessions[id]; !ok {
+				return domain.ErrSessionNotExist
+			}
+			delete(sessions, id)
+			return nil
+		},
+		SaveAPIKeyFunc: func(ctx context.Context, key domain.APIKey) (int, error) {
+			key.ID = nextKeyID
+			keys[key.ID] = key
+			nextKeyID++
+			return key.ID, nil
+		},
+		GetAPIKeyByHashFunc: func(ctx context.Context, hash string) (domain.APIKey, error) {
+			for _, k := range keys {
+				if k.Hash == hash {
+					return k, nil
+				}
+			}
+			return domain.APIKey{}, domain.ErrAPIKeyNotExist
+		},
+		TouchAPIKeyFunc: func(ctx context.Context, id int, usedAt time.Time) error {
+			k, ok := keys[id]
+			if !ok {
+				return domain.ErrAPIKeyNotExist
+			}
+			k.LastUsedAt = &usedAt
+			keys[id] = k
+			return nil
+		},
+		ListAPIKeysFunc: func(ctx context.Context, userID int) ([]domain.APIKey, error) {
+			var res []domain.APIKey
+			for id := 1; id < nextKeyID; id++ {
+				if k, ok := keys[id]; ok && k.UserID == userID {
+					res = append(res, k)
+				}
+			}
+			return res, nil
+		},
+		DeleteAPIKeyFunc: func(ctx context.Context, userID, id int) error {
+			if k, ok := keys[id]; !ok || k.UserID != userID {
+				return domain.ErrAPIKeyNotExist
+			}
+			delete(keys, id)
+			return nil
+		},
+	}
+}
+
+// newTestAuth keeps password hashing cheap and the clock under control.
+func newTestAuth(repo UserRepository, now *time.Time) *AuthUseCase {
+	a := NewAuth(repo, []byte("secret"))
+	a.PasswordIterations = 10
+	a.TokenTTL = time.Hour
+	a.Now = func() time.Time { return *now }
+	return a
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name  string
+		creds domain.Credentials
+
+		wantErr   error
+		wantEmail string
+		wantSaves int
+	}{
+		{
+			name:      "email is normalized",
+			creds:     domain.Credentials{Email: " Ann@Example.com ", Password: "password1"},
+			wantEmail: "ann@example.com",
+			wantSaves: 1,
+		},
+		{
+			name:    "short password -> error",
+			creds:   domain.Credentials{Email: "ann@example.com", Password: "short"},
+			wantErr: domain.ErrTooShort,
+		},
+		{
+			name:    "malformed email -> error",
+			creds:   domain.Credentials{Email: "Ann <ann@example.com>", Password: "password1"},
+			wantErr: domain.ErrInvalidFormat,
+		},
+		{
+			name:      "taken email -> error",
+			creds:     domain.Credentials{Email: "BOB@example.com", Password: "password1"},
+			wantErr:   domain.ErrUserExists,
+			wantSaves: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			now := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+			repo := newUserRepo()
+			usecase := newTestAuth(repo, &now)
+			if _, err := usecase.Register(context.Background(), domain.Credentials{Email: "bob@example.com", Password: "password1"}); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+			repo.SaveUserCalls = 0
+
+			// act
+			user, err := usecase.Register(context.Background(), tc.creds)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if repo.SaveUserCalls != tc.wantSaves {
+				t.Fatalf("unexpected calls: got %d, want %d", repo.SaveUserCalls, tc.wantSaves)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if user.ID == 0 || user.Email != tc.wantEmail || !user.CreatedAt.Equal(now) {
+				t.Errorf("unexpected user: got %+v", user)
+			}
+			if user.PasswordHash == "" || user.PasswordHash == tc.creds.Password {
+				t.Errorf("password must be stored hashed: got %q", user.PasswordHash)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		creds   domain.Credentials
+		wantErr error
+	}{
+		{name: "valid credentials", creds: domain.Credentials{Email: "Ann@example.com", Password: "password1"}},
+		{name: "wrong password -> error", creds: domain.Credentials{Email: "ann@example.com", Password: "password2"}, wantErr: domain.ErrInvalidCredentials},
+		{name: "unknown email -> error", creds: domain.Credentials{Email: "bob@example.com", Password: "password1"}, wantErr: domain.ErrInvalidCredentials},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			now := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+			repo := newUserRepo()
+			usecase := newTestAuth(repo, &now)
+			registered, err := usecase.Register(context.Background(), domain.Credentials{Email: "ann@example.com", Password: "password1"})
+			if err != nil {
+				t.Fatalf("register: %v", err)
+			}
+
+			// act
+			token, err := usecase.Login(context.Background(), tc.creds)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if repo.SaveSessionCalls != 0 {
+					t.Errorf("unexpected session: got %+v", repo.LastSavedSession)
+				}
+				return
+			}
+			if token.TokenType != "Bearer" || !token.ExpiresAt.Equal(now.Add(time.Hour)) {
+				t.Errorf("unexpected token: got %+v", token)
+			}
+
+			user, err := usecase.Authenticate(context.Background(), token.Token)
+			if err != nil {
+				t.Fatalf("authenticate: %v", err)
+			}
+			if user.ID != registered.ID {
+				t.Errorf("unexpected user: got %d, want %d", user.ID, registered.ID)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, usecase *AuthUseCase, now *time.Time, token string) string
+		wantErr error
+	}{
+		{
+			name: "valid token",
+			prepare: func(t *testing.T, usecase *AuthUseCase, now *time.Time, token string) string {
+				return token
+			},
+		},
+		{
+			name: "logged out -> error",
+			prepare: func(t *testing.T, usecase *AuthUseCase, now *time.Time, token string) string {
+				if err := usecase.Logout(context.Background(), token); err != nil {
+					t.Fatalf("logout: %v", err)
+				}
+				return token
+			},
+			wantErr: domain.ErrUnauthenticated,
+		},
+		{
+			name: "expired -> error",
+			prepare: func(t *testing.T, usecase *AuthUseCase, now *time.Time, token string) string {
+				*now = now.Add(time.Hour)
+				return token
+			},
+			wantErr: domain.ErrUnauthenticated,
+		},
+		{
+			name: "other secret -> error",
+			prepare: func(t *testing.T, usecase *AuthUseCase, now *time.Time, token string) string {
+				usecase.Secret = []byte("rotated")
+				return token
+			},
+			wantErr: domain.ErrUnauthenticated,
+		},
+		{
+			name: "garbage -> error",
+			prepare: func(t *testing.T, usecase *AuthUseCase, now *time.Time, token string) string {
+				return "garbage"
+			},
+			wantErr: domain.ErrUnauthenticated,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			now := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+			usecase := newTestAuth(newUserRepo(), &now)
+			creds := domain.Credentials{Email: "ann@example.com", Password: "password1"}
+			if _, err := usecase.Register(context.Background(), creds); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+			token, err := usecase.Login(context.Background(), creds)
+			if err != nil {
+				t.Fatalf("login: %v", err)
+			}
+			raw := tc.prepare(t, usecase, &now, token.Token)
+
+			// act
+			user, err := usecase.Authenticate(context.Background(), raw)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && user.Email != creds.Email {
+				t.Errorf("unexpected user: got %+v", user)
+			}
+		})
+	}
+}
+
+func TestAuthenticateTenant(t *testing.T) {
+	acme := domain.ContextWithTenant(context.Background(), "acme")
+	globex := domain.ContextWithTenant(context.Background(), "globex")
+
+	tests := []struct {
+		name     string
+		issuedIn context.Context
+		usedIn   context.Context
+
+		wantErr    error
+		wantTenant domain.Tenant
+		wantCalls  int
+	}{
+		{name: "workspace of the token", issuedIn: acme, usedIn: context.Background(), wantTenant: "acme", wantCalls: 1},
+		{name: "same workspace named", issuedIn: acme, usedIn: acme, wantTenant: "acme", wantCalls: 1},
+		{name: "default workspace", issuedIn: context.Background(), usedIn: context.Background(), wantTenant: domain.DefaultTenant, wantCalls: 1},
+		{name: "other workspace named -> error", issuedIn: acme, usedIn: globex, wantErr: domain.ErrUnauthenticated},
+		{name: "default token in other workspace -> error", issuedIn: context.Background(), usedIn: acme, wantErr: domain.ErrUnauthenticated},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			now := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+			repo := newUserRepo()
+			getSession := repo.GetSessionFunc
+			var lookedUpIn domain.Tenant
+			repo.GetSessionFunc = func(ctx context.Context, id string) (domain.Session, error) {
+				lookedUpIn = domain.CurrentTenant(ctx)
+				return getSession(ctx, id)
+			}
+			usecase := newTestAuth(repo, &now)
+
+			creds := domain.Credentials{Email: "ann@example.com", Password: "password1"}
+			if _, err := usecase.Register(tc.issuedIn, creds); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+			token, err := usecase.Login(tc.issuedIn, creds)
+			if err != nil {
+				t.Fatalf("login: %v", err)
+			}
+
+			// act
+			user, err := usecase.Authenticate(tc.usedIn, token.Token)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if repo.GetSessionCalls != tc.wantCalls {
+				t.Errorf("unexpected session lookups: got %d, want %d", repo.GetSessionCalls, tc.wantCalls)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if user.Tenant != tc.wantTenant {
+				t.Errorf("unexpected user tenant: got %q, want %q", user.Tenant, tc.wantTenant)
+			}
+			if lookedUpIn != tc.wantTenant {
+				t.Errorf("session looked up in the wrong tenant: got %q, want %q", lookedUpIn, tc.wantTenant)
+			}
+		})
+	}
+}
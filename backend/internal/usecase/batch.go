@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// RunBatch runs the operations of the batch in order and returns their
+// results in the same order. An atomic batch needs Tx and fails with
+// domain.ErrNoTransactions without it: when one of its operations fails
+// nothing is stored, that operation gets its error and every other one
+// domain.ErrBatchAborted. Either way the batch is undone as a whole.
+func (u *TodoUseCase) RunBatch(ctx context.Context, batch domain.Batch) ([]domain.BatchResult, error) {
+	if err := batch.Validate(); err != nil {
+		return nil, fmt.Errorf("validate batch: %w", err)
+	}
+
+	return u.runBatch(ctx, batch.Operations, batch.Atomic)
+}
+
+// CompleteTodos completes the open todos matching the filter that the
+// user may change, subtasks before their parents, as a batch.
+func (u *TodoUseCase) CompleteTodos(ctx context.Context, filter domain.TodoFilter, atomic bool) ([]domain.BatchResult, error) {
+	open := false
+	filter.Completed = &open
+
+	todos, err := u.changeable(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := map[int]domain.Todo{}
+	for _, todo := range todos {
+		matched[todo.ID] = todo
+	}
+	depth := func(todo domain.Todo) int {
+		n := 0
+		for todo.ParentID != nil {
+			parent, ok := matched[*todo.ParentID]
+			if !ok {
+				break
+			}
+			todo = parent
+			n++
+		}
+		return n
+	}
+	// a parent may need its subtasks completed first, see Rules
+	slices.SortStableFunc(todos, func(a domain.Todo, b domain.Todo) int {
+		return cmp.Compare(depth(b), depth(a))
+	})
+
+	ops := make([]domain.BatchOperation, 0, len(todos))
+	for _, todo := range todos {
+		ops = append(ops, domain.BatchOperation{Op: domain.BatchComplete, ID: todo.ID, Version: todo.Version})
+	}
+	return u.runBatch(ctx, ops, atomic)
+}
+
+// DeleteCompletedTodos deletes the completed todos matching the filter
+// that the user may change, as a batch. A subtask deleted along with its
+// parent gets no result of its own.
+func (u *TodoUseCase) DeleteCompletedTodos(ctx context.Context, filter domain.TodoFilter, atomic bool) ([]domain.BatchResult, error) {
+	completed := true
+	filter.Completed = &completed
+
+	todos, err := u.changeable(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := map[int]bool{}
+	for _, todo := range todos {
+		matched[todo.ID] = true
+	}
+
+	ops := make([]domain.BatchOperation, 0, len(todos))
+	for _, todo := range todos {
+		deleted, err := u.hasAncestorIn(ctx, todo, matched)
+		if err != nil {
+			return nil, err
+		}
+		if !deleted {
+			ops = append(ops, domain.BatchOperation{Op: domain.BatchDelete, ID: todo.ID, Version: todo.Version})
+		}
+	}
+	return u.runBatch(ctx, ops, atomic)
+}
+
+func (u *TodoUseCase) runBatch(ctx context.Context, ops []domain.BatchOperation, atomic bool) ([]domain.BatchResult, error) {
+	results := make([]domain.BatchResult, len(ops))
+
+	if !atomic {
+		err := u.recording(ctx, func(ctx context.Context) error {
+			for i, op := range ops {
+				results[i] = u.runOperation(ctx, op)
+			}
+			return nil
+		})
+		return results, err
+	}
+
+	if u.Tx == nil {
+		return nil, domain.ErrNoTransactions
+	}
+
+	failed := -1
+	err := u.command(ctx, func(ctx context.Context) error {
+		for i, op := range ops {
+			results[i] = u.runOperation(ctx, op)
+			if results[i].Err != nil {
+				failed = i
+				return results[i].Err
+			}
+		}
+		return nil
+	})
+	if failed >= 0 {
+		for i, op := range ops {
+			if i != failed {
+				results[i] = domain.BatchResult{ID: op.ID, Err: domain.ErrBatchAborted}
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// runOperation runs an operation like the single request doing the same.
+func (u *TodoUseCase) runOperation(ctx context.Context, op domain.BatchOperation) domain.BatchResult {
+	res := domain.BatchResult{ID: op.ID}
+
+	switch op.Op {
+	case domain.BatchCreate:
+		id, err := u.CreateTodo(ctx, *op.Todo)
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		res.ID = id
+		if todo, err := u.TodoRepo.GetByID(ctx, id); err == nil {
+			res.Todo = &todo
+		}
+	case domain.BatchUpdate, domain.BatchComplete:
+		patch := []byte(op.Patch)
+		if op.Op == domain.BatchComplete {
+			patch = []byte(`{"completed":true}`)
+		}
+		todo, err := u.PatchTodoByID(ctx, op.ID, op.Version, patch)
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		res.Todo = &todo
+	case domain.BatchDelete:
+		res.Err = u.DeleteTodoByID(ctx, op.ID, op.Version)
+	default:
+		res.Err = domain.ValidationErrors{{Field: "op", Err: domain.ErrUnknownValue}}
+	}
+
+	return res
+}
+
+// changeable returns the todos matching the filter that the user may
+// change, by id.
+func (u *TodoUseCase) changeable(ctx context.Context, filter domain.TodoFilter) ([]domain.Todo, error) {
+	visible, err := u.visibility(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter.Visible = visible
+
+	if ok, err := u.resolveTagNames(ctx, &filter); err != nil {
+		return nil, err
+	} else if !ok {
+		return []domain.Todo{}, nil
+	}
+
+	todos, err := u.TodoRepo.Query(ctx, domain.TodoQuery{Filter: filter})
+	if err != nil {
+		return nil, fmt.Errorf("query todos: %w", err)
+	}
+
+	res := make([]domain.Todo, 0, len(todos))
+	for _, todo := range todos {
+		err := u.checkAccess(ctx, todo, accessWrite)
+		switch {
+		case err == nil:
+			res = append(res, todo)
+		case errors.Is(err, domain.ErrForbidden):
+		default:
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// hasAncestorIn reports whether a parent of the todo, at any depth, is one
+// of the ids.
+func (u *TodoUseCase) hasAncestorIn(ctx context.Context, todo domain.Todo, ids map[int]bool) (bool, error) {
+	for todo.ParentID != nil {
+		if ids[*todo.ParentID] {
+			return true, nil
+		}
+
+		parent, err := u.TodoRepo.GetByID(ctx, *todo.ParentID)
+		if err != nil {
+			return false, fmt.Errorf("get parent todo: %w", err)
+		}
+		todo = parent
+	}
+	return false, nil
+}
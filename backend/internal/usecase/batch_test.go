@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestRunBatch(t *testing.T) {
+	operations := []domain.BatchOperation{
+		{Op: domain.BatchCreate, Todo: &domain.Todo{Title: "new"}},
+		{Op: domain.BatchComplete, ID: 1},
+		{Op: domain.BatchDelete, ID: 99},
+		{Op: domain.BatchUpdate, ID: 2, Version: 1, Patch: json.RawMessage(`{"title":"renamed"}`)},
+	}
+
+	tests := []struct {
+		name   string
+		batch  domain.Batch
+		noTx   bool
+		failTx bool
+
+		wantIDs    []int
+		wantErrs   []error
+		wantSteps  []string
+		wantErr    error
+		wantCommit bool
+	}{
+		{
+			name:      "best effort",
+			batch:     domain.Batch{Operations: operations},
+			wantIDs:   []int{100, 1, 99, 2},
+			wantErrs:  []error{nil, nil, domain.ErrTodoNotExist, nil},
+			wantSteps: []string{"create 100 v1", "update 1 v1->v2", "update 2 v1->v2"},
+		},
+		{
+			name:      "atomic",
+			batch:     domain.Batch{Atomic: true, Operations: slices.Delete(slices.Clone(operations), 2, 3)},
+			wantIDs:   []int{100, 1, 2},
+			wantErrs:  []error{nil, nil, nil},
+			wantSteps: []string{"create 100 v1", "update 1 v1->v2", "update 2 v1->v2"},
+		},
+		{
+			name:     "atomic with a failing operation",
+			batch:    domain.Batch{Atomic: true, Operations: operations},
+			failTx:   true,
+			wantIDs:  []int{0, 1, 99, 2},
+			wantErrs: []error{domain.ErrBatchAborted, domain.ErrBatchAborted, domain.ErrTodoNotExist, domain.ErrBatchAborted},
+		},
+		{
+			name:    "atomic without transactions",
+			batch:   domain.Batch{Atomic: true, Operations: operations},
+			noTx:    true,
+			wantErr: domain.ErrNoTransactions,
+		},
+		{
+			name:    "invalid batch",
+			batch:   domain.Batch{Operations: []domain.BatchOperation{{Op: domain.BatchDelete}}},
+			wantErr: domain.ErrValidation,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			repo, trash := newUndoRepos(
+				domain.Todo{ID: 1, Title: "read", OwnerID: ann.ID, Version: 1},
+				domain.Todo{ID: 2, Title: "write", OwnerID: ann.ID, Version: 1},
+			)
+			commands := &CommandLogMock{}
+			tx := &TransactorMock{}
+			usecase := New(repo)
+			usecase.Trash = trash
+			usecase.Commands = commands
+			if !tc.noTx {
+				usecase.Tx = tx
+			}
+
+			ctx := domain.ContextWithUser(context.Background(), ann)
+
+			// act
+			got, err := usecase.RunBatch(ctx, tc.batch)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			if len(got) != len(tc.wantIDs) {
+				t.Fatalf("unexpected results: got %d, want %d", len(got), len(tc.wantIDs))
+			}
+			for i, res := range got {
+				if res.ID != tc.wantIDs[i] || !errors.Is(res.Err, tc.wantErrs[i]) || (tc.wantErrs[i] == nil) != (res.Err == nil) {
+					t.Errorf("unexpected result %d: got id %d and %v, want id %d and %v", i, res.ID, res.Err, tc.wantIDs[i], tc.wantErrs[i])
+				}
+				if res.Err == nil && res.Todo == nil {
+					t.Errorf("result %d must carry the todo", i)
+				}
+			}
+
+			if tc.failTx && tx.RolledBack == 0 {
+				t.Errorf("must roll back")
+			}
+			if tc.wantSteps == nil {
+				if len(commands.Recorded) != 0 {
+					t.Errorf("must not record: got %d commands", len(commands.Recorded))
+				}
+				return
+			}
+			if len(commands.Recorded) != 1 {
+				t.Fatalf("must be a single command: got %d", len(commands.Recorded))
+			}
+			if steps := describeSteps(commands.Recorded[0]); !slices.Equal(steps, tc.wantSteps) {
+				t.Errorf("unexpected steps: got %v, want %v", steps, tc.wantSteps)
+			}
+		})
+	}
+}
+
+func TestCompleteTodos(t *testing.T) {
+	// preparing
+	listID := sharedList().ID
+	parentID := 1
+	repo, trash := newUndoRepos(
+		domain.Todo{ID: 1, Title: "move", OwnerID: ann.ID, Version: 1},
+		domain.Todo{ID: 2, Title: "pack", OwnerID: ann.ID, ParentID: &parentID, Version: 1},
+		domain.Todo{ID: 3, Title: "done", OwnerID: ann.ID, Completed: true, Version: 1},
+		domain.Todo{ID: 4, Title: "private", OwnerID: bob.ID, Version: 1},
+		domain.Todo{ID: 5, Title: "shared", OwnerID: bob.ID, ListID: &listID, Version: 1},
+	)
+	usecase := New(repo)
+	usecase.Lists = newListRepo(sharedList())
+	usecase.Trash = trash
+	usecase.Rules.BlockOpenSubtasks = true
+
+	ctx := domain.ContextWithUser(context.Background(), ann)
+
+	// act
+	got, err := usecase.CompleteTodos(ctx, domain.TodoFilter{}, false)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+
+	ids := []int{}
+	for _, res := range got {
+		if res.Err != nil {
+			t.Errorf("unexpected error of todo %d: %v", res.ID, res.Err)
+		}
+		ids = append(ids, res.ID)
+	}
+	// the subtask goes first, or the parent could not be completed
+	if want := []int{2, 1, 5}; !slices.Equal(ids, want) {
+		t.Errorf("unexpected todos: got %v, want %v", ids, want)
+	}
+	if todo, _ := repo.GetByID(ctx, 4); todo.Completed {
+		t.Errorf("todo of another user must stay open")
+	}
+}
+
+func TestDeleteCompletedTodos(t *testing.T) {
+	// preparing
+	parentID, openID := 1, 3
+	repo, trash := newUndoRepos(
+		domain.Todo{ID: 1, Title: "move", OwnerID: ann.ID, Completed: true, Version: 1},
+		domain.Todo{ID: 2, Title: "pack", OwnerID: ann.ID, Completed: true, ParentID: &parentID, Version: 1},
+		domain.Todo{ID: 3, Title: "clean", OwnerID: ann.ID, Version: 1},
+		domain.Todo{ID: 4, Title: "sweep", OwnerID: ann.ID, Completed: true, ParentID: &openID, Version: 1},
+		domain.Todo{ID: 5, Title: "private", OwnerID: bob.ID, Completed: true, Version: 1},
+	)
+	usecase := New(repo)
+	usecase.Trash = trash
+
+	ctx := domain.ContextWithUser(context.Background(), ann)
+
+	// act
+	got, err := usecase.DeleteCompletedTodos(ctx, domain.TodoFilter{}, false)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+
+	ids := []int{}
+	for _, res := range got {
+		if res.Err != nil {
+			t.Errorf("unexpected error of todo %d: %v", res.ID, res.Err)
+		}
+		ids = append(ids, res.ID)
+	}
+	// the subtask of a deleted todo goes to the trash along with it
+	if want := []int{1, 4}; !slices.Equal(ids, want) {
+		t.Errorf("unexpected todos: got %v, want %v", ids, want)
+	}
+	if trash.TrashByIDCalls != 2 {
+		t.Errorf("unexpected trash calls: got %d, want %d", trash.TrashByIDCalls, 2)
+	}
+}
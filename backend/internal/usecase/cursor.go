@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// cursor is what a client gets back to fetch the next page. It is opaque
+// for clients and bound to the order it was issued for.
+type cursor struct {
+	Sort  domain.TodoSort `json:"s"`
+	Desc  bool            `json:"d,omitempty"`
+	Pivot cursorPivot     `json:"p"`
+}
+
+// cursorPivot holds the fields a sort can look at, see domain.TodoSort.Pivot.
+type cursorPivot struct {
+	ID        int             `json:"i"`
+	Title     string          `json:"t,omitempty"`
+	Completed bool            `json:"c,omitempty"`
+	Priority  domain.Priority `json:"p,omitempty"`
+	DueAt     *time.Time      `json:"d,omitempty"`
+}
+
+func encodeCursor(q domain.TodoQuery, last domain.Todo) string {
+	pivot := q.OrderBy().Pivot(last)
+	data, _ := json.Marshal(cursor{
+		Sort: q.OrderBy(),
+		Desc: q.Desc,
+		Pivot: cursorPivot{
+			ID:        pivot.ID,
+			Title:     pivot.Title,
+			Completed: pivot.Completed,
+			Priority:  pivot.Priority,
+			DueAt:     pivot.DueAt,
+		},
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(q domain.TodoQuery, s string) (domain.Todo, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return domain.Todo{}, fmt.Errorf("%w: malformed cursor", domain.ErrInvalidQuery)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return domain.Todo{}, fmt.Errorf("%w: malformed cursor", domain.ErrInvalidQuery)
+	}
+
+	if c.Sort != q.OrderBy() || c.Desc != q.Desc {
+		return domain.Todo{}, fmt.Errorf("%w: cursor was issued for another order", domain.ErrInvalidQuery)
+	}
+
+	return domain.Todo{
+		ID:        c.Pivot.ID,
+		Title:     c.Pivot.Title,
+		Completed: c.Pivot.Completed,
+		Priority:  c.Pivot.Priority,
+		DueAt:     c.Pivot.DueAt,
+	}, nil
+}
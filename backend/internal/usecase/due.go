@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// MaxUpcomingDays caps the window of ListUpcomingTodos.
+const MaxUpcomingDays = 365
+
+// ListOverdueTodos returns todos whose due date has already passed.
+func (u *TodoUseCase) ListOverdueTodos(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+	now := u.now()
+	return u.ListTodos(ctx, dueQuery(q, nil, &now), cursor)
+}
+
+// ListDueTodayTodos returns todos due during the current calendar day in
+// loc, including the hours already passed.
+func (u *TodoUseCase) ListDueTodayTodos(ctx context.Context, q domain.TodoQuery, loc *time.Location, cursor string) (domain.TodoPage, error) {
+	y, m, d := u.now().In(loc).Date()
+
+	// a day is not always 24 hours long, the next midnight is computed
+	// from the calendar instead
+	from := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	to := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+
+	return u.ListTodos(ctx, dueQuery(q, &from, &to), cursor)
+}
+
+// ListUpcomingTodos returns todos due from now until the same time of day
+// the given number of calendar days later in loc.
+func (u *TodoUseCase) ListUpcomingTodos(ctx context.Context, q domain.TodoQuery, days int, loc *time.Location, cursor string) (domain.TodoPage, error) {
+	if days <= 0 || days > MaxUpcomingDays {
+		return domain.TodoPage{}, fmt.Errorf("%w: days must be between 1 and %d", domain.ErrInvalidQuery, MaxUpcomingDays)
+	}
+
+	from := u.now()
+	to := from.In(loc).AddDate(0, 0, days)
+
+	return u.ListTodos(ctx, dueQuery(q, &from, &to), cursor)
+}
+
+// dueQuery narrows q to the due window. Only open todos are listed unless
+// the client filtered by completion itself, the earliest due go first
+// unless another order was asked for.
+func dueQuery(q domain.TodoQuery, from, to *time.Time) domain.TodoQuery {
+	q.Filter.DueFrom = from
+	q.Filter.DueBefore = to
+
+	if q.Filter.Completed == nil {
+		open := false
+		q.Filter.Completed = &open
+	}
+	if q.Sort == "" {
+		q.Sort = domain.SortByDue
+	}
+
+	return q
+}
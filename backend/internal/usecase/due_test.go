@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestDueLists(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+
+	// clocks go forward in Berlin on this day, it lasts 23 hours
+	now := time.Date(2030, time.March, 31, 10, 0, 0, 0, berlin)
+	at := func(d time.Duration) *time.Time {
+		due := now.Add(d)
+		return &due
+	}
+
+	stored := []domain.Todo{
+		{ID: 1, Title: "yesterday evening", DueAt: at(-11 * time.Hour)},
+		{ID: 2, Title: "this morning", DueAt: at(-2 * time.Hour)},
+		{ID: 3, Title: "this morning, done", DueAt: at(-time.Hour), Completed: true},
+		{ID: 4, Title: "tonight", DueAt: at(13*time.Hour + 30*time.Minute)},
+		{ID: 5, Title: "after midnight", DueAt: at(14*time.Hour + 30*time.Minute)},
+		{ID: 6, Title: "next week", DueAt: at(7 * 24 * time.Hour)},
+		{ID: 7, Title: "someday"},
+	}
+
+	newUseCase := func() *TodoUseCase {
+		usecase := New(&TodoRepositoryMock{
+			QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+				return q.Apply(slices.Values(stored)), nil
+			},
+		})
+		usecase.Now = func() time.Time { return now }
+		return usecase
+	}
+
+	ids := func(page domain.TodoPage) []int {
+		res := []int{}
+		for _, todo := range page.Todos {
+			res = append(res, todo.ID)
+		}
+		return res
+	}
+
+	yes := true
+
+	tests := []struct {
+		name string
+		list func(u *TodoUseCase) (domain.TodoPage, error)
+		want []int
+	}{
+		{
+			name: "overdue",
+			list: func(u *TodoUseCase) (domain.TodoPage, error) {
+				return u.ListOverdueTodos(context.Background(), domain.TodoQuery{}, "")
+			},
+			want: []int{1, 2},
+		},
+		{
+			name: "overdue desc",
+			list: func(u *TodoUseCase) (domain.TodoPage, error) {
+				return u.ListOverdueTodos(context.Background(), domain.TodoQuery{Desc: true}, "")
+			},
+			want: []int{2, 1},
+		},
+		{
+			name: "due today follows the calendar day",
+			list: func(u *TodoUseCase) (domain.TodoPage, error) {
+				return u.ListDueTodayTodos(context.Background(), domain.TodoQuery{}, berlin, "")
+			},
+			want: []int{2, 4},
+		},
+		{
+			name: "due today in another zone",
+			list: func(u *TodoUseCase) (domain.TodoPage, error) {
+				// 31 March in Tokyo is 30 March 17:00 - 31 March 17:00 in Berlin
+				tokyo := time.FixedZone("", 9*60*60)
+				return u.ListDueTodayTodos(context.Background(), domain.TodoQuery{}, tokyo, "")
+			},
+			want: []int{1, 2},
+		},
+		{
+			name: "due today completed",
+			list: func(u *TodoUseCase) (domain.TodoPage, error) {
+				q := domain.TodoQuery{Filter: domain.TodoFilter{Completed: &yes}}
+				return u.ListDueTodayTodos(context.Background(), q, berlin, "")
+			},
+			want: []int{3},
+		},
+		{
+			name: "upcoming day",
+			list: func(u *TodoUseCase) (domain.TodoPage, error) {
+				return u.ListUpcomingTodos(context.Background(), domain.TodoQuery{}, 1, berlin, "")
+			},
+			want: []int{4, 5},
+		},
+		{
+			name: "upcoming week",
+			list: func(u *TodoUseCase) (domain.TodoPage, error) {
+				return u.ListUpcomingTodos(context.Background(), domain.TodoQuery{}, 8, berlin, "")
+			},
+			want: []int{4, 5, 6},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			page, err := tc.list(newUseCase())
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			if !slices.Equal(ids(page), tc.want) {
+				t.Errorf("unexpected ids: got %v, want %v", ids(page), tc.want)
+			}
+		})
+	}
+
+	t.Run("upcoming days out of range -> error", func(t *testing.T) {
+		for _, days := range []int{0, -1, MaxUpcomingDays + 1} {
+			_, err := newUseCase().ListUpcomingTodos(context.Background(), domain.TodoQuery{}, days, berlin, "")
+			if !errors.Is(err, domain.ErrInvalidQuery) {
+				t.Errorf("days %d: unexpected error: got %v, want %v", days, err, domain.ErrInvalidQuery)
+			}
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// EventHub fans out the changes of todos to live subscribers, see
+// events.Hub.
+type EventHub interface {
+	Publish(event domain.TodoEvent) domain.TodoEvent
+	Subscribe(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent
+}
+
+// TodoEvents streams the changes of the todos the user can see in the
+// workspace of the context, until ctx is done or the subscriber falls
+// behind; either way the channel is closed. A non-zero lastEventID resumes
+// after that event. Without Events nothing is ever sent.
+func (u *TodoUseCase) TodoEvents(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent {
+	if u.Events == nil {
+		return nil
+	}
+
+	in := u.Events.Subscribe(ctx, lastEventID)
+	out := make(chan domain.TodoEvent)
+	tenant := domain.CurrentTenant(ctx)
+
+	go func() {
+		defer close(out)
+
+		for event := range in {
+			if event.Type != domain.TodoEventsReset && !u.canSee(ctx, tenant, event) {
+				continue
+			}
+
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// canSee reports whether the subscriber may see the event. The role is
+// checked when the event is sent, so a user who has left a list stops
+// getting its changes.
+func (u *TodoUseCase) canSee(ctx context.Context, tenant domain.Tenant, event domain.TodoEvent) bool {
+	if event.Tenant != tenant {
+		return false
+	}
+
+	userID, ok := owner(ctx)
+	if !ok {
+		return true
+	}
+
+	role, err := u.todoRole(ctx, userID, event.Todo)
+	if err != nil {
+		slog.Warn("failed to check todo event", "id", event.ID, "todo_id", event.Todo.ID, "error", err)
+		return false
+	}
+	return role != ""
+}
+
+// publishing reports whether stored changes go anywhere, so callers can
+// skip reading what only the events need.
+func (u *TodoUseCase) publishing() bool {
+	return u.Outbox != nil || u.Events != nil || (u.Webhooks != nil && u.Deliveries != nil)
+}
+
+// publish hands a change to the outbox, to be stored with it, or without
+// an outbox straight to the subscribers and the webhooks. Only a failure
+// of the outbox is returned: without one the change is already stored, so
+// failures are only logged.
+func (u *TodoUseCase) publish(ctx context.Context, typ domain.TodoEventType, todo domain.Todo) error {
+	if !u.publishing() {
+		return nil
+	}
+
+	event := domain.TodoEvent{
+		Type:   typ,
+		Tenant: domain.CurrentTenant(ctx),
+		Todo:   todo,
+		At:     u.now(),
+	}
+	if userID, ok := owner(ctx); ok {
+		event.ActorID = userID
+	}
+
+	if u.Outbox != nil {
+		if err := u.Outbox.AddEvents(ctx, event); err != nil {
+			return fmt.Errorf("add %s event: %w", typ, err)
+		}
+		return nil
+	}
+
+	if u.Events != nil {
+		event = u.Events.Publish(event)
+	}
+	if err := u.NotifyWebhooks(ctx, event); err != nil {
+		slog.Warn("failed to notify webhooks", "type", event.Type, "todo_id", event.Todo.ID, "error", err)
+	}
+	return nil
+}
+
+// publishStored reads the todo back as stored and publishes it once for
+// every type.
+func (u *TodoUseCase) publishStored(ctx context.Context, id int, types ...domain.TodoEventType) error {
+	if !u.publishing() {
+		return nil
+	}
+
+	todo, err := u.TodoRepo.GetByID(ctx, id)
+	if err != nil {
+		if u.Outbox != nil {
+			return fmt.Errorf("read todo %d for events: %w", id, err)
+		}
+		slog.Warn("failed to publish todo event", "types", types, "todo_id", id, "error", err)
+		return nil
+	}
+
+	for _, typ := range types {
+		if err := u.publish(ctx, typ, todo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updatedEvents are the events of a stored update: the update itself and
+// the completion, when the update completed the todo.
+func updatedEvents(todo domain.Todo, current domain.Todo) []domain.TodoEventType {
+	if todo.Completed && !current.Completed {
+		return []domain.TodoEventType{domain.TodoUpdated, domain.TodoCompleted}
+	}
+	return []domain.TodoEventType{domain.TodoUpdated}
+}
@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// EventHubMock keeps the published events and numbers them from 1.
+type EventHubMock struct {
+	SubscribeFunc func(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent
+
+	SubscribeCalls int
+
+	Published   []domain.TodoEvent
+	LastEventID uint64
+}
+
+func (h *EventHubMock) Publish(event domain.TodoEvent) domain.TodoEvent {
+	event.ID = uint64(len(h.Published) + 1)
+	h.Published = append(h.Published, event)
+	return event
+}
+
+func (h *EventHubMock) Subscribe(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent {
+	h.SubscribeCalls++
+	h.LastEventID = lastEventID
+
+	if h.SubscribeFunc == nil {
+		panic("SubscribeFunc is nil")
+	}
+
+	return h.SubscribeFunc(ctx, lastEventID)
+}
@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestTodoEventsPublished(t *testing.T) {
+	ctx := domain.ContextWithTenant(domain.ContextWithUser(context.Background(), ann), "acme")
+
+	type published struct {
+		typ domain.TodoEventType
+		id  int
+	}
+
+	tests := []struct {
+		name string
+		act  func(u *TodoUseCase) error
+
+		want []published
+	}{
+		{
+			name: "create",
+			act: func(u *TodoUseCase) error {
+				_, err := u.CreateTodo(ctx, domain.Todo{Title: "new"})
+				return err
+			},
+			want: []published{{domain.TodoCreated, 3}},
+		},
+		{
+			name: "update",
+			act: func(u *TodoUseCase) error {
+				return u.UpdateTodoByID(ctx, 1, domain.Todo{Title: "renamed"})
+			},
+			want: []published{{domain.TodoUpdated, 1}},
+		},
+		{
+			name: "patch",
+			act: func(u *TodoUseCase) error {
+				_, err := u.PatchTodoByID(ctx, 1, 1, []byte(`{"completed":true}`))
+				return err
+			},
+			want: []published{{domain.TodoUpdated, 1}, {domain.TodoCompleted, 1}},
+		},
+		{
+			name: "update of a completed todo -> no completion",
+			act: func(u *TodoUseCase) error {
+				return u.UpdateTodoByID(ctx, 2, domain.Todo{Title: "done", Completed: true})
+			},
+			want: []published{{domain.TodoUpdated, 2}},
+		},
+		{
+			name: "patch of a stale version -> nothing",
+			act: func(u *TodoUseCase) error {
+				_, err := u.PatchTodoByID(ctx, 1, 7, []byte(`{"completed":true}`))
+				if err == nil {
+					t.Error("unexpected success of a stale patch")
+				}
+				return nil
+			},
+		},
+		{
+			name: "attach tag",
+			act: func(u *TodoUseCase) error {
+				_, err := u.AttachTag(ctx, 2, 5, 0)
+				return err
+			},
+			want: []published{{domain.TodoUpdated, 2}},
+		},
+		{
+			name: "delete",
+			act: func(u *TodoUseCase) error {
+				return u.DeleteTodoByID(ctx, 1, 0)
+			},
+			want: []published{{domain.TodoDeleted, 1}},
+		},
+		{
+			name: "delete tag -> tagged todos updated",
+			act: func(u *TodoUseCase) error {
+				return u.DeleteTagByID(ctx, 4)
+			},
+			want: []published{{domain.TodoUpdated, 1}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			todos := map[int]domain.Todo{
+				1: {ID: 1, Title: "mine", OwnerID: ann.ID, Version: 1, Tags: []int{4}},
+				2: {ID: 2, Title: "also mine", OwnerID: ann.ID, Version: 1, Completed: true},
+			}
+			mockRepo := &TodoRepositoryMock{
+				SaveFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+					todo.ID, todo.Version = 3, 1
+					todos[3] = todo
+					return 3, nil
+				},
+				GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+					if todo, ok := todos[id]; ok {
+						return todo, nil
+					}
+					return domain.Todo{}, domain.ErrTodoNotExist
+				},
+				UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+					todo.ID = id
+					todo.Version++
+					todos[id] = todo
+					return nil
+				},
+				DeleteByIDFunc: func(ctx context.Context, id int, version int) error {
+					delete(todos, id)
+					return nil
+				},
+				QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+					var res []domain.Todo
+					for _, id := range []int{1, 2} {
+						if slices.Contains(todos[id].Tags, q.Filter.Tags[0]) {
+							res = append(res, todos[id])
+						}
+					}
+					return res, nil
+				},
+				GetTagByIDFunc: func(ctx context.Context, id int) (domain.Tag, error) {
+					return domain.Tag{ID: id, Name: "work"}, nil
+				},
+				DeleteTagByIDFunc: func(ctx context.Context, id int) error {
+					return nil
+				},
+			}
+			hub := &EventHubMock{}
+			usecase := New(mockRepo)
+			usecase.Events = hub
+
+			// act
+			err := tc.act(usecase)
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			var got []published
+			for _, event := range hub.Published {
+				got = append(got, published{event.Type, event.Todo.ID})
+				if event.Tenant != "acme" {
+					t.Errorf("unexpected tenant of event: got %q, want %q", event.Tenant, "acme")
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("unexpected events: got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTodoEventsVisibility(t *testing.T) {
+	// preparing
+	listID := sharedList().ID
+	in := make(chan domain.TodoEvent, 10)
+	hub := &EventHubMock{
+		SubscribeFunc: func(ctx context.Context, lastEventID uint64) <-chan domain.TodoEvent {
+			return in
+		},
+	}
+	usecase := New(&TodoRepositoryMock{})
+	usecase.Lists = newListRepo(sharedList())
+	usecase.Events = hub
+
+	ctx, cancel := context.WithCancel(domain.ContextWithUser(context.Background(), cat))
+	defer cancel()
+
+	events := []domain.TodoEvent{
+		{ID: 1, Type: domain.TodoCreated, Tenant: domain.DefaultTenant, Todo: domain.Todo{ID: 1, OwnerID: cat.ID}},
+		{ID: 2, Type: domain.TodoCreated, Tenant: domain.DefaultTenant, Todo: domain.Todo{ID: 2, OwnerID: ann.ID}},
+		{ID: 3, Type: domain.TodoUpdated, Tenant: domain.DefaultTenant, Todo: domain.Todo{ID: 3, OwnerID: ann.ID, ListID: &listID}},
+		{ID: 4, Type: domain.TodoCreated, Tenant: "acme", Todo: domain.Todo{ID: 1, OwnerID: cat.ID}},
+		{ID: 5, Type: domain.TodoEventsReset},
+		{ID: 6, Type: domain.TodoDeleted, Tenant: domain.DefaultTenant, Todo: domain.Todo{ID: 1, OwnerID: cat.ID}},
+	}
+	for _, event := range events {
+		in <- event
+	}
+	close(in)
+
+	// act
+	out := usecase.TodoEvents(ctx, 42)
+
+	// assert
+	var got []uint64
+	timeout := time.After(time.Second)
+	for done := false; !done; {
+		select {
+		case event, ok := <-out:
+			if ok {
+				got = append(got, event.ID)
+			}
+			done = !ok
+		case <-timeout:
+			t.Fatal("event stream was not closed")
+		}
+	}
+
+	if want := []uint64{1, 3, 5, 6}; !slices.Equal(got, want) {
+		t.Errorf("unexpected events: got %v, want %v", got, want)
+	}
+	if hub.LastEventID != 42 {
+		t.Errorf("unexpected last event id: got %d, want 42", hub.LastEventID)
+	}
+}
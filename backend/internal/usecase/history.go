@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// TodoHistory reads the past of the todos, see eventsourced.EventSourcedTodoRepository.
+type TodoHistory interface {
+	// TodoHistory returns every change of the todo, oldest first, even
+	// when it is deleted by now; ErrTodoNotExist when it never existed.
+	TodoHistory(ctx context.Context, id int) ([]domain.TodoChange, error)
+	// QueryAsOf runs the query against the todos as they were at q.AsOf.
+	QueryAsOf(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error)
+}
+
+// GetTodoHistory returns every change of the todo, oldest first. The user
+// must be able to read the todo as it is now, or as it was last when it
+// is deleted.
+func (u *TodoUseCase) GetTodoHistory(ctx context.Context, id int) ([]domain.TodoChange, error) {
+	if u.History == nil {
+		return nil, domain.ErrHistoryNotKept
+	}
+
+	changes, err := u.History.TodoHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get todo history: %w", err)
+	}
+
+	if err := u.checkAccess(ctx, changes[len(changes)-1].Todo, accessRead); err != nil {
+		return nil, fmt.Errorf("get todo history: %w", err)
+	}
+
+	return changes, nil
+}
+
+// queryTodos reads the todos of q from the history when q.AsOf is set.
+func (u *TodoUseCase) queryTodos(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+	if q.AsOf.IsZero() {
+		return u.TodoRepo.Query(ctx, q)
+	}
+	if u.History == nil {
+		return nil, domain.ErrHistoryNotKept
+	}
+	return u.History.QueryAsOf(ctx, q)
+}
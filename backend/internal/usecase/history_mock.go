@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+type TodoHistoryMock struct {
+	TodoHistoryFunc func(ctx context.Context, id int) ([]domain.TodoChange, error)
+	QueryAsOfFunc   func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error)
+
+	TodoHistoryCalls int
+	QueryAsOfCalls   int
+
+	LastID    int
+	LastQuery domain.TodoQuery
+}
+
+func (t *TodoHistoryMock) TodoHistory(ctx context.Context, id int) ([]domain.TodoChange, error) {
+	t.TodoHistoryCalls++
+	t.LastID = id
+
+	if t.TodoHistoryFunc == nil {
+		panic("TodoHistoryFunc is nil")
+	}
+
+	return t.TodoHistoryFunc(ctx, id)
+}
+
+func (t *TodoHistoryMock) QueryAsOf(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+	t.QueryAsOfCalls++
+	t.LastQuery = q
+
+	if t.QueryAsOfFunc == nil {
+		panic("QueryAsOfFunc is nil")
+	}
+
+	return t.QueryAsOfFunc(ctx, q)
+}
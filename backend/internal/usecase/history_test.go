@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestGetTodoHistory(t *testing.T) {
+	listID := sharedList().ID
+	at := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	changes := func(last domain.Todo) []domain.TodoChange {
+		first := last
+		first.Version = 1
+		return []domain.TodoChange{
+			{Type: domain.TodoChangeCreated, Todo: first, ActorID: ann.ID, At: at},
+			{Type: domain.TodoChangeDeleted, Todo: last, ActorID: ann.ID, At: at.Add(time.Hour)},
+		}
+	}
+	errHistory := errors.New("history is down")
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		noHistory  bool
+		historyErr error
+		last       domain.Todo
+
+		wantErr error
+	}{
+		{
+			name: "owner",
+			ctx:  domain.ContextWithUser(context.Background(), ann),
+			last: domain.Todo{ID: 1, OwnerID: ann.ID, Version: 2},
+		},
+		{
+			name: "member of the list",
+			ctx:  domain.ContextWithUser(context.Background(), cat),
+			last: domain.Todo{ID: 1, OwnerID: ann.ID, ListID: &listID, Version: 2},
+		},
+		{
+			name: "without authentication",
+			ctx:  context.Background(),
+			last: domain.Todo{ID: 1, OwnerID: ann.ID, Version: 2},
+		},
+		{
+			name:    "todo of another user -> ErrTodoNotExist",
+			ctx:     domain.ContextWithUser(context.Background(), bob),
+			last:    domain.Todo{ID: 1, OwnerID: ann.ID, Version: 2},
+			wantErr: domain.ErrTodoNotExist,
+		},
+		{
+			name:    "not a member of the list -> ErrTodoNotExist",
+			ctx:     domain.ContextWithUser(context.Background(), dan),
+			last:    domain.Todo{ID: 1, OwnerID: ann.ID, ListID: &listID, Version: 2},
+			wantErr: domain.ErrTodoNotExist,
+		},
+		{
+			name:       "history error -> error",
+			ctx:        domain.ContextWithUser(context.Background(), ann),
+			historyErr: errHistory,
+			wantErr:    errHistory,
+		},
+		{
+			name:      "history not kept -> ErrHistoryNotKept",
+			ctx:       domain.ContextWithUser(context.Background(), ann),
+			noHistory: true,
+			wantErr:   domain.ErrHistoryNotKept,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			history := &TodoHistoryMock{
+				TodoHistoryFunc: func(ctx context.Context, id int) ([]domain.TodoChange, error) {
+					if tc.historyErr != nil {
+						return nil, tc.historyErr
+					}
+					return changes(tc.last), nil
+				},
+			}
+
+			usecase := New(&TodoRepositoryMock{})
+			usecase.Lists = newListRepo(sharedList())
+			if !tc.noHistory {
+				usecase.History = history
+			}
+
+			// act
+			got, err := usecase.GetTodoHistory(tc.ctx, 1)
+
+			// assert
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+
+			if len(got) != 2 || got[1].Type != domain.TodoChangeDeleted {
+				t.Errorf("unexpected changes: got %+v", got)
+			}
+			if history.LastID != 1 {
+				t.Errorf("unexpected id: got %d, want %d", history.LastID, 1)
+			}
+		})
+	}
+}
+
+func TestListTodosAsOf(t *testing.T) {
+	asOf := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+	past := []domain.Todo{
+		{ID: 1, Title: "read the book", OwnerID: ann.ID},
+		{ID: 2, Title: "complete the game", OwnerID: bob.ID},
+		{ID: 3, Title: "buy milk", OwnerID: ann.ID},
+	}
+	ctx := domain.ContextWithUser(context.Background(), ann)
+
+	t.Run("reads the past state", func(t *testing.T) {
+		// preparing
+		mockRepo := &TodoRepositoryMock{}
+		history := &TodoHistoryMock{
+			QueryAsOfFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+				return q.Apply(slices.Values(past)), nil
+			},
+		}
+		usecase := New(mockRepo)
+		usecase.History = history
+
+		// act
+		page, err := usecase.ListTodos(ctx, domain.TodoQuery{AsOf: asOf, Limit: 1}, "")
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		if len(page.Todos) != 1 || page.Todos[0].ID != 1 || page.NextCursor == "" {
+			t.Errorf("unexpected page: got %+v", page)
+		}
+		if !history.LastQuery.AsOf.Equal(asOf) {
+			t.Errorf("unexpected as of: got %v, want %v", history.LastQuery.AsOf, asOf)
+		}
+		if history.LastQuery.Filter.Visible == nil || history.LastQuery.Filter.Visible.UserID != ann.ID {
+			t.Errorf("must only read the todos the user sees now: got %+v", history.LastQuery.Filter.Visible)
+		}
+		if mockRepo.QueryCalls != 0 {
+			t.Errorf("unexpected repository calls: got %d, want %d", mockRepo.QueryCalls, 0)
+		}
+
+		// act
+		page, err = usecase.ListTodos(ctx, domain.TodoQuery{AsOf: asOf, Limit: 1}, page.NextCursor)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(page.Todos) != 1 || page.Todos[0].ID != 3 || page.NextCursor != "" {
+			t.Errorf("unexpected second page: got %+v", page)
+		}
+	})
+
+	t.Run("zero time reads the repository", func(t *testing.T) {
+		// preparing
+		mockRepo := &TodoRepositoryMock{
+			QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+				return []domain.Todo{}, nil
+			},
+		}
+		history := &TodoHistoryMock{}
+		usecase := New(mockRepo)
+		usecase.History = history
+
+		// act
+		_, err := usecase.ListTodos(ctx, domain.TodoQuery{}, "")
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if mockRepo.QueryCalls != 1 || history.QueryAsOfCalls != 0 {
+			t.Errorf("unexpected calls: got %d queries and %d past queries", mockRepo.QueryCalls, history.QueryAsOfCalls)
+		}
+	})
+
+	t.Run("history not kept -> ErrHistoryNotKept", func(t *testing.T) {
+		// preparing
+		usecase := New(&TodoRepositoryMock{})
+
+		// act
+		_, err := usecase.ListTodos(ctx, domain.TodoQuery{AsOf: asOf}, "")
+
+		// assert
+		if !errors.Is(err, domain.ErrHistoryNotKept) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrHistoryNotKept)
+		}
+	})
+}
@@ -0,0 +1,392 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// ListRepository stores lists together with their members and pending
+// invitations.
+type ListRepository interface {
+	SaveList(ctx context.Context, list domain.List) (int, error)
+	// GetListByID, UpdateListByID and DeleteListByID fail with
+	// domain.ErrListNotExist.
+	GetListByID(ctx context.Context, id int) (domain.List, error)
+	// UpdateListByID fails with domain.ErrVersionMismatch unless the
+	// version of the list is the stored one, and bumps it.
+	UpdateListByID(ctx context.Context, id int, list domain.List) error
+	DeleteListByID(ctx context.Context, id int) error
+	// ListsByMember returns the lists the user is a member of, ordered by
+	// id.
+	ListsByMember(ctx context.Context, userID int) ([]domain.List, error)
+	// ListsByInvitee returns the lists with a pending invitation for the
+	// email, ordered by id.
+	ListsByInvitee(ctx context.Context, email string) ([]domain.List, error)
+}
+
+// listUpdateAttempts is how often a change of a list is retried when
+// another one gets in between.
+const listUpdateAttempts = 3
+
+// CreateList creates a list owned by the authenticated user.
+func (u *TodoUseCase) CreateList(ctx context.Context, list domain.List) (int, error) {
+	user, err := u.listUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := list.Validate(); err != nil {
+		return 0, fmt.Errorf("validate list: %w", err)
+	}
+
+	now := u.now()
+	list = domain.List{
+		Name:      list.Name,
+		Members:   []domain.Member{{UserID: user.ID, Email: user.Email, Role: domain.RoleOwner, JoinedAt: now}},
+		CreatedAt: now,
+	}
+
+	id, err := u.Lists.SaveList(ctx, list)
+	if err != nil {
+		return 0, fmt.Errorf("save list in db: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetLists returns the lists the authenticated user is a member of.
+func (u *TodoUseCase) GetLists(ctx context.Context) ([]domain.List, error) {
+	user, err := u.listUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := u.Lists.ListsByMember(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get lists of user: %w", err)
+	}
+	for i := range lists {
+		lists[i] = visibleList(lists[i], user.ID)
+	}
+
+	return lists, nil
+}
+
+func (u *TodoUseCase) GetListByID(ctx context.Context, id int) (domain.List, error) {
+	user, err := u.listUser(ctx)
+	if err != nil {
+		return domain.List{}, err
+	}
+
+	list, err := u.getList(ctx, id, user.ID, domain.RoleViewer)
+	if err != nil {
+		return domain.List{}, err
+	}
+
+	return visibleList(list, user.ID), nil
+}
+
+// RenameList changes the name of a list, only its owner may.
+func (u *TodoUseCase) RenameList(ctx context.Context, id int, name string) (domain.List, error) {
+	if err := (domain.List{Name: name}).Validate(); err != nil {
+		return domain.List{}, fmt.Errorf("validate list: %w", err)
+	}
+
+	return u.changeList(ctx, id, domain.RoleOwner, func(list *domain.List, _ domain.User) error {
+		list.Name = name
+		return nil
+	})
+}
+
+// DeleteListByID deletes an empty list, only its owner may. Todos of the
+// list in the trash count as well: without the list they could not be
+// restored.
+func (u *TodoUseCase) DeleteListByID(ctx context.Context, id int) error {
+	user, err := u.listUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := u.getList(ctx, id, user.ID, domain.RoleOwner); err != nil {
+		return err
+	}
+
+	todos, err := u.TodoRepo.Query(ctx, domain.TodoQuery{Filter: domain.TodoFilter{ListID: &id}, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("query todos of list: %w", err)
+	}
+	if len(todos) > 0 {
+		return fmt.Errorf("delete list %d: %w", id, domain.ErrListNotEmpty)
+	}
+	if u.Trash != nil {
+		entries, err := u.Trash.ReadTrash(ctx)
+		if err != nil {
+			return fmt.Errorf("read trash: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.Todo.ListID != nil && *entry.Todo.ListID == id {
+				return fmt.Errorf("delete list %d: trashed todo %d: %w", id, entry.Todo.ID, domain.ErrListNotEmpty)
+			}
+		}
+	}
+
+	if err := u.Lists.DeleteListByID(ctx, id); err != nil {
+		return fmt.Errorf("delete list in db: %w", err)
+	}
+
+	return nil
+}
+
+// InviteMember lets the user with the email join the list, only the owner
+// may invite. The user does not need to be registered yet.
+func (u *TodoUseCase) InviteMember(ctx context.Context, listID int, inv domain.Invitation) (domain.List, error) {
+	inv.Email = domain.NormalizeEmail(inv.Email)
+	if err := inv.Validate(); err != nil {
+		return domain.List{}, fmt.Errorf("validate invitation: %w", err)
+	}
+
+	return u.changeList(ctx, listID, domain.RoleOwner, func(list *domain.List, user domain.User) error {
+		isMember := slices.ContainsFunc(list.Members, func(m domain.Member) bool { return m.Email == inv.Email })
+		if isMember || list.Invited(inv.Email) >= 0 {
+			return domain.ErrMemberExists
+		}
+		if len(list.Members)+len(list.Invitations) >= domain.MaxListMembers {
+			return domain.ErrTooManyMembers
+		}
+
+		list.Invitations = append(list.Invitations, domain.Invitation{
+			Email:     inv.Email,
+			Role:      inv.Role,
+			InvitedBy: user.ID,
+			CreatedAt: u.now(),
+		})
+		return nil
+	})
+}
+
+// RevokeInvitation withdraws a pending invitation, only the owner may.
+func (u *TodoUseCase) RevokeInvitation(ctx context.Context, listID int, email string) (domain.List, error) {
+	email = domain.NormalizeEmail(email)
+
+	return u.changeList(ctx, listID, domain.RoleOwner, func(list *domain.List, _ domain.User) error {
+		i := list.Invited(email)
+		if i < 0 {
+			return domain.ErrInvitationNotExist
+		}
+		list.Invitations = slices.Delete(list.Invitations, i, i+1)
+		return nil
+	})
+}
+
+// GetInvitations returns the invitations pending for the authenticated
+// user.
+func (u *TodoUseCase) GetInvitations(ctx context.Context) ([]domain.PendingInvitation, error) {
+	user, err := u.listUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := u.Lists.ListsByInvitee(ctx, user.Email)
+	if err != nil {
+		return nil, fmt.Errorf("get lists of invitee: %w", err)
+	}
+
+	res := make([]domain.PendingInvitation, 0, len(lists))
+	for _, list := range lists {
+		if i := list.Invited(user.Email); i >= 0 {
+			res = append(res, domain.PendingInvitation{ListID: list.ID, ListName: list.Name, Invitation: list.Invitations[i]})
+		}
+	}
+
+	return res, nil
+}
+
+// AcceptInvitation makes the authenticated user a member of the list with
+// the role they were invited with. Lists without an invitation for the
+// user are reported as having none, whether they exist or not.
+func (u *TodoUseCase) AcceptInvitation(ctx context.Context, listID int) (domain.List, error) {
+	return u.answerInvitation(ctx, listID, true)
+}
+
+// DeclineInvitation drops the invitation of the authenticated user.
+func (u *TodoUseCase) DeclineInvitation(ctx context.Context, listID int) error {
+	_, err := u.answerInvitation(ctx, listID, false)
+	return err
+}
+
+func (u *TodoUseCase) answerInvitation(ctx context.Context, listID int, accept bool) (domain.List, error) {
+	user, err := u.listUser(ctx)
+	if err != nil {
+		return domain.List{}, err
+	}
+
+	list, err := u.updateList(ctx, listID, func(list *domain.List) error {
+		i := list.Invited(user.Email)
+		if i < 0 {
+			return domain.ErrInvitationNotExist
+		}
+		inv := list.Invitations[i]
+		list.Invitations = slices.Delete(list.Invitations, i, i+1)
+
+		if accept {
+			list.Members = append(list.Members, domain.Member{
+				UserID:   user.ID,
+				Email:    user.Email,
+				Role:     inv.Role,
+				JoinedAt: u.now(),
+			})
+		}
+		return nil
+	})
+	if errors.Is(err, domain.ErrListNotExist) {
+		return domain.List{}, fmt.Errorf("get list %d: %w", listID, domain.ErrInvitationNotExist)
+	}
+	if err != nil {
+		return domain.List{}, err
+	}
+
+	return visibleList(list, user.ID), nil
+}
+
+// ChangeMemberRole gives a member another role, only the owner may. The
+// owner keeps their role.
+func (u *TodoUseCase) ChangeMemberRole(ctx context.Context, listID int, userID int, role domain.Role) (domain.List, error) {
+	if err := domain.ValidateMemberRole(role); err != nil {
+		return domain.List{}, fmt.Errorf("validate role: %w", err)
+	}
+
+	return u.changeList(ctx, listID, domain.RoleOwner, func(list *domain.List, _ domain.User) error {
+		i := slices.IndexFunc(list.Members, func(m domain.Member) bool { return m.UserID == userID })
+		if i < 0 {
+			return domain.ErrMemberNotExist
+		}
+		if list.Members[i].Role == domain.RoleOwner {
+			return fmt.Errorf("%w: the owner keeps their role", domain.ErrForbidden)
+		}
+		list.Members[i].Role = role
+		return nil
+	})
+}
+
+// RemoveMember takes a member out of the list. The owner removes anyone
+// but themselves, other members only themselves, which is leaving.
+func (u *TodoUseCase) RemoveMember(ctx context.Context, listID int, userID int) error {
+	user, err := u.listUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	need := domain.RoleOwner
+	if userID == user.ID {
+		need = domain.RoleViewer
+	}
+
+	_, err = u.changeList(ctx, listID, need, func(list *domain.List, _ domain.User) error {
+		i := slices.IndexFunc(list.Members, func(m domain.Member) bool { return m.UserID == userID })
+		if i < 0 {
+			return domain.ErrMemberNotExist
+		}
+		if list.Members[i].Role == domain.RoleOwner {
+			return fmt.Errorf("%w: the owner cannot leave the list", domain.ErrForbidden)
+		}
+		list.Members = slices.Delete(list.Members, i, i+1)
+		return nil
+	})
+	return err
+}
+
+// listUser returns the authenticated user, lists are always someone's.
+func (u *TodoUseCase) listUser(ctx context.Context) (domain.User, error) {
+	if u.Lists == nil {
+		return domain.User{}, errors.New("lists are not configured")
+	}
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.User{}, domain.ErrUnauthenticated
+	}
+	return user, nil
+}
+
+// getList reads a list the user has at least the role in. Lists the user
+// is not a member of are reported as missing, so their ids do not leak;
+// members with a lesser role get domain.ErrForbidden.
+func (u *TodoUseCase) getList(ctx context.Context, id int, userID int, need domain.Role) (domain.List, error) {
+	list, err := u.Lists.GetListByID(ctx, id)
+	if err != nil {
+		return domain.List{}, fmt.Errorf("get list by id: %w", err)
+	}
+
+	role, ok := list.RoleOf(userID)
+	if !ok {
+		return domain.List{}, fmt.Errorf("get list by id: %w", domain.ErrListNotExist)
+	}
+	if !role.Allows(need) {
+		return domain.List{}, fmt.Errorf("%w: %s of list %d, %s needed", domain.ErrForbidden, role, id, need)
+	}
+
+	return list, nil
+}
+
+// changeList applies change to a list the authenticated user has at least
+// the role in.
+func (u *TodoUseCase) changeList(ctx context.Context, id int, need domain.Role, change func(list *domain.List, user domain.User) error) (domain.List, error) {
+	user, err := u.listUser(ctx)
+	if err != nil {
+		return domain.List{}, err
+	}
+
+	list, err := u.updateList(ctx, id, func(list *domain.List) error {
+		role, ok := list.RoleOf(user.ID)
+		if !ok {
+			return domain.ErrListNotExist
+		}
+		if !role.Allows(need) {
+			return fmt.Errorf("%w: %s of list %d, %s needed", domain.ErrForbidden, role, id, need)
+		}
+		return change(list, user)
+	})
+	if err != nil {
+		return domain.List{}, err
+	}
+
+	return visibleList(list, user.ID), nil
+}
+
+// updateList writes back the stored list changed by change. It starts over
+// when another change gets in between.
+func (u *TodoUseCase) updateList(ctx context.Context, id int, change func(list *domain.List) error) (domain.List, error) {
+	for attempt := 1; ; attempt++ {
+		list, err := u.Lists.GetListByID(ctx, id)
+		if err != nil {
+			return domain.List{}, fmt.Errorf("get list by id: %w", err)
+		}
+
+		if err := change(&list); err != nil {
+			return domain.List{}, fmt.Errorf("change list %d: %w", id, err)
+		}
+
+		err = u.Lists.UpdateListByID(ctx, id, list)
+		if errors.Is(err, domain.ErrVersionMismatch) && attempt < listUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return domain.List{}, fmt.Errorf("update list in db: %w", err)
+		}
+
+		list.Version++
+		return list, nil
+	}
+}
+
+// visibleList hides the invitations from everyone but the owner.
+func visibleList(list domain.List, userID int) domain.List {
+	if role, _ := list.RoleOf(userID); role != domain.RoleOwner {
+		list.Invitations = nil
+	}
+	return list
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+type ListRepositoryMock struct {
+	SaveListFunc       func(ctx context.Context, list domain.List) (int, error)
+	GetListByIDFunc    func(ctx context.Context, id int) (domain.List, error)
+	UpdateListByIDFunc func(ctx context.Context, id int, list domain.List) error
+	DeleteListByIDFunc func(ctx context.Context, id int) error
+	ListsByMemberFunc  func(ctx context.Context, userID int) ([]domain.List, error)
+	ListsByInviteeFunc func(ctx context.Context, email string) ([]domain.List, error)
+
+	SaveListCalls       int
+	GetListByIDCalls    int
+	UpdateListByIDCalls int
+	DeleteListByIDCalls int
+	ListsByMemberCalls  int
+	ListsByInviteeCalls int
+
+	LastSavedList   domain.List
+	LastUpdatedList domain.List
+	LastListID      int
+}
+
+func (t *ListRepositoryMock) SaveList(ctx context.Context, list domain.List) (int, error) {
+	t.SaveListCalls++
+	t.LastSavedList = list
+
+	if t.SaveListFunc == nil {
+		panic("SaveListFunc is nil")
+	}
+
+	return t.SaveListFunc(ctx, list)
+}
+
+func (t *ListRepositoryMock) GetListByID(ctx context.Context, id int) (domain.List, error) {
+	t.GetListByIDCalls++
+	t.LastListID = id
+
+	if t.GetListByIDFunc == nil {
+		panic("GetListByIDFunc is nil")
+	}
+
+	return t.GetListByIDFunc(ctx, id)
+}
+
+func (t *ListRepositoryMock) UpdateListByID(ctx context.Context, id int, list domain.List) error {
+	t.UpdateListByIDCalls++
+	t.LastListID = id
+	t.LastUpdatedList = list
+
+	if t.UpdateListByIDFunc == nil {
+		panic("UpdateListByIDFunc is nil")
+	}
+
+	return t.UpdateListByIDFunc(ctx, id, list)
+}
+
+func (t *ListRepositoryMock) DeleteListByID(ctx context.Context, id int) error {
+	t.DeleteListByIDCalls++
+	t.LastListID = id
+
+	if t.DeleteListByIDFunc == nil {
+		panic("DeleteListByIDFunc is nil")
+	}
+
+	return t.DeleteListByIDFunc(ctx, id)
+}
+
+func (t *ListRepositoryMock) ListsByMember(ctx context.Context, userID int) ([]domain.List, error) {
+	t.ListsByMemberCalls++
+
+	if t.ListsByMemberFunc == nil {
+		panic("ListsByMemberFunc is nil")
+	}
+
+	return t.ListsByMemberFunc(ctx, userID)
+}
+
+func (t *ListRepositoryMock) ListsByInvitee(ctx context.Context, email string) ([]domain.List, error) {
+	t.ListsByInviteeCalls++
+
+	if t.ListsByInviteeFunc == nil {
+		panic("ListsByInviteeFunc is nil")
+	}
+
+	return t.ListsByInviteeFunc(ctx, email)
+}
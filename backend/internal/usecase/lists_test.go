@@ -0,0 +1,520 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+var (
+	ann = domain.User{ID: 1, Email: "ann@example.com"}
+	bob = domain.User{ID: 2, Email: "bob@example.com"}
+	cat = domain.User{ID: 3, Email: "cat@example.com"}
+	dan = domain.User{ID: 4, Email: "dan@example.com"}
+)
+
+// newListRepo keeps the lists in a map and checks versions like a
+// repository does.
+func newListRepo(lists ...domain.List) *ListRepositoryMock {
+	stored := map[int]domain.List{}
+	for _, list := range lists {
+		stored[list.ID] = list
+	}
+	nextID := len(lists) + 100
+
+	byID := func(match func(domain.List) bool) []domain.List {
+		res := []domain.List{}
+		for _, id := range slices.Sorted(maps.Keys(stored)) {
+			if match(stored[id]) {
+				res = append(res, stored[id])
+			}
+		}
+		return res
+	}
+
+	return &ListRepositoryMock{
+		SaveListFunc: func(ctx context.Context, list domain.List) (int, error) {
+			list.ID = nextID
+			list.Version = 1
+			stored[list.ID] = list
+			nextID++
+			return list.ID, nil
+		},
+		GetListByIDFunc: func(ctx context.Context, id int) (domain.List, error) {
+			list, ok := stored[id]
+			if !ok {
+				return domain.List{}, domain.ErrListNotExist
+			}
+			list.Members = slices.Clone(list.Members)
+			list.Invitations = slices.Clone(list.Invitations)
+			return list, nil
+		},
+		UpdateListByIDFunc: func(ctx context.Context, id int, list domain.List) error {
+			current, ok := stored[id]
+			if !ok {
+				return domain.ErrListNotExist
+			}
+			if list.Version != current.Version {
+				return domain.ErrVersionMismatch
+			}
+			list.Version++
+			stored[id] = list
+			return nil
+		},
+		DeleteListByIDFunc: func(ctx context.Context, id int) error {
+			if _, ok := stored[id]; !ok {
+				return domain.ErrListNotExist
+			}
+			delete(stored, id)
+			return nil
+		},
+		ListsByMemberFunc: func(ctx context.Context, userID int) ([]domain.List, error) {
+			return byID(func(l domain.List) bool { _, ok := l.RoleOf(userID); return ok }), nil
+		},
+		ListsByInviteeFunc: func(ctx context.Context, email string) ([]domain.List, error) {
+			return byID(func(l domain.List) bool { return l.Invited(email) >= 0 }), nil
+		},
+	}
+}
+
+// sharedList is owned by ann, bob edits it and cat views it.
+func sharedList() domain.List {
+	return domain.List{
+		ID:      10,
+		Name:    "home",
+		Version: 1,
+		Members: []domain.Member{
+			{UserID: ann.ID, Email: ann.Email, Role: domain.RoleOwner},
+			{UserID: bob.ID, Email: bob.Email, Role: domain.RoleEditor},
+			{UserID: cat.ID, Email: cat.Email, Role: domain.RoleViewer},
+		},
+	}
+}
+
+func TestListPermissions(t *testing.T) {
+	as := func(user domain.User) context.Context {
+		return domain.ContextWithUser(context.Background(), user)
+	}
+
+	tests := []struct {
+		name string
+		act  func(u *TodoUseCase) error
+
+		wantErr     error
+		wantWrites  int
+		wantList    *int
+		wantVisible []int
+	}{
+		{
+			name: "viewer reads",
+			act: func(u *TodoUseCase) error {
+				_, err := u.GetTodoByID(as(cat), 1)
+				return err
+			},
+		},
+		{
+			name: "viewer updates -> forbidden",
+			act: func(u *TodoUseCase) error {
+				return u.UpdateTodoByID(as(cat), 1, domain.Todo{Title: "mine now"})
+			},
+			wantErr: domain.ErrForbidden,
+		},
+		{
+			name: "viewer patches -> forbidden",
+			act: func(u *TodoUseCase) error {
+				_, err := u.PatchTodoByID(as(cat), 1, 0, []byte(`{"completed":true}`))
+				return err
+			},
+			wantErr: domain.ErrForbidden,
+		},
+		{
+			name: "viewer deletes -> forbidden",
+			act: func(u *TodoUseCase) error {
+				return u.DeleteTodoByID(as(cat), 1, 0)
+			},
+			wantErr: domain.ErrForbidden,
+		},
+		{
+			name: "viewer attaches a tag -> forbidden",
+			act: func(u *TodoUseCase) error {
+				_, err := u.AttachTag(as(cat), 1, 5, 0)
+				return err
+			},
+			wantErr: domain.ErrForbidden,
+		},
+		{
+			name: "viewer creates in the list -> forbidden",
+			act: func(u *TodoUseCase) error {
+				_, err := u.CreateTodo(as(cat), domain.Todo{Title: "new", ListID: intPtr(10)})
+				return err
+			},
+			wantErr: domain.ErrForbidden,
+		},
+		{
+			name: "viewer creates a subtask in the list -> forbidden",
+			act: func(u *TodoUseCase) error {
+				_, err := u.CreateTodo(as(cat), domain.Todo{Title: "new", ParentID: intPtr(1)})
+				return err
+			},
+			wantErr: domain.ErrForbidden,
+		},
+		{
+			name: "editor updates",
+			act: func(u *TodoUseCase) error {
+				return u.UpdateTodoByID(as(bob), 1, domain.Todo{Title: "shared, renamed"})
+			},
+			wantWrites: 1,
+			wantList:   intPtr(10),
+		},
+		{
+			name: "editor deletes",
+			act: func(u *TodoUseCase) error {
+				return u.DeleteTodoByID(as(bob), 1, 0)
+			},
+			wantWrites: 1,
+		},
+		{
+			name: "editor creates in the list",
+			act: func(u *TodoUseCase) error {
+				_, err := u.CreateTodo(as(bob), domain.Todo{Title: "new", ListID: intPtr(10)})
+				return err
+			},
+			wantWrites: 1,
+			wantList:   intPtr(10),
+		},
+		{
+			name: "subtask joins the list of its parent",
+			act: func(u *TodoUseCase) error {
+				_, err := u.CreateTodo(as(bob), domain.Todo{Title: "new", ParentID: intPtr(1)})
+				return err
+			},
+			wantWrites: 1,
+			wantList:   intPtr(10),
+		},
+		{
+			name: "subtask in another list than its parent -> error",
+			act: func(u *TodoUseCase) error {
+				_, err := u.CreateTodo(as(bob), domain.Todo{Title: "new", ParentID: intPtr(2)})
+				if err == nil {
+					_, err = u.CreateTodo(as(bob), domain.Todo{Title: "new", ParentID: intPtr(1), ListID: intPtr(11)})
+				}
+				return err
+			},
+			wantErr:    domain.ErrListMismatch,
+			wantWrites: 1,
+		},
+		{
+			name: "editor takes a todo of someone else out of the list -> forbidden",
+			act: func(u *TodoUseCase) error {
+				_, err := u.PatchTodoByID(as(bob), 1, 0, []byte(`{"list_id":null}`))
+				return err
+			},
+			wantErr: domain.ErrForbidden,
+		},
+		{
+			name: "owner of the todo takes it out of the list",
+			act: func(u *TodoUseCase) error {
+				_, err := u.PatchTodoByID(as(ann), 1, 0, []byte(`{"list_id":null}`))
+				return err
+			},
+			wantWrites: 1,
+		},
+		{
+			name: "non-member reads -> not found",
+			act: func(u *TodoUseCase) error {
+				_, err := u.GetTodoByID(as(dan), 1)
+				return err
+			},
+			wantErr: domain.ErrTodoNotExist,
+		},
+		{
+			name: "non-member deletes -> not found",
+			act: func(u *TodoUseCase) error {
+				return u.DeleteTodoByID(as(dan), 1, 0)
+			},
+			wantErr: domain.ErrTodoNotExist,
+		},
+		{
+			name: "non-member creates in the list -> error",
+			act: func(u *TodoUseCase) error {
+				_, err := u.CreateTodo(as(dan), domain.Todo{Title: "new", ListID: intPtr(10)})
+				return err
+			},
+			wantErr: domain.ErrUnknownValue,
+		},
+		{
+			name: "member sees the todos of the list",
+			act: func(u *TodoUseCase) error {
+				_, err := u.ListTodos(as(cat), domain.TodoQuery{}, "")
+				return err
+			},
+			wantVisible: []int{10},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			todos := map[int]domain.Todo{
+				1: {ID: 1, Title: "shared", OwnerID: ann.ID, ListID: intPtr(10), Version: 1},
+				2: {ID: 2, Title: "bob's own", OwnerID: bob.ID, Version: 1},
+			}
+			mockRepo := &TodoRepositoryMock{
+				SaveFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+					return 3, nil
+				},
+				GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+					if todo, ok := todos[id]; ok {
+						return todo, nil
+					}
+					return domain.Todo{}, domain.ErrTodoNotExist
+				},
+				UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+					return nil
+				},
+				DeleteByIDFunc: func(ctx context.Context, id int, version int) error {
+					return nil
+				},
+				GetTagByIDFunc: func(ctx context.Context, id int) (domain.Tag, error) {
+					return domain.Tag{ID: id, Name: "home"}, nil
+				},
+				QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+					return nil, nil
+				},
+			}
+			usecase := New(mockRepo)
+			usecase.Lists = newListRepo(sharedList(), domain.List{ID: 11, Name: "work", Version: 1, Members: []domain.Member{
+				{UserID: bob.ID, Email: bob.Email, Role: domain.RoleOwner},
+			}})
+
+			// act
+			err := tc.act(usecase)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			writes := mockRepo.SaveCalls + mockRepo.UpdateByIDCalls + mockRepo.DeleteByIDCalls
+			if writes != tc.wantWrites {
+				t.Fatalf("unexpected writes: got %d, want %d", writes, tc.wantWrites)
+			}
+			if tc.wantList != nil {
+				got := mockRepo.LastSavedTodo.ListID
+				if got == nil || *got != *tc.wantList {
+					t.Errorf("unexpected list: got %v, want %d", got, *tc.wantList)
+				}
+			}
+			if tc.wantVisible != nil {
+				if v := mockRepo.LastQuery.Filter.Visible; v == nil || !slices.Equal(v.ListIDs, tc.wantVisible) {
+					t.Errorf("unexpected visibility: got %+v", v)
+				}
+			}
+		})
+	}
+}
+
+func TestListMembership(t *testing.T) {
+	// preparing
+	now := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+	ctxAnn := domain.ContextWithUser(context.Background(), ann)
+	ctxBob := domain.ContextWithUser(context.Background(), bob)
+	ctxDan := domain.ContextWithUser(context.Background(), dan)
+
+	lists := newListRepo()
+	usecase := New(&TodoRepositoryMock{
+		QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+			return nil, nil
+		},
+	})
+	usecase.Lists = lists
+	usecase.Now = func() time.Time { return now }
+
+	id, err := usecase.CreateList(ctxAnn, domain.List{Name: "home"})
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	mustFail := func(t *testing.T, err error, want error) {
+		t.Helper()
+		if !errors.Is(err, want) {
+			t.Fatalf("unexpected error: got %v, want %v", err, want)
+		}
+	}
+
+	t.Run("creator owns the list", func(t *testing.T) {
+		list, err := usecase.GetListByID(ctxAnn, id)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if role, ok := list.RoleOf(ann.ID); !ok || role != domain.RoleOwner || !list.CreatedAt.Equal(now) {
+			t.Errorf("unexpected list: got %+v", list)
+		}
+	})
+
+	t.Run("non-member -> not found", func(t *testing.T) {
+		_, err := usecase.GetListByID(ctxBob, id)
+		mustFail(t, err, domain.ErrListNotExist)
+		_, err = usecase.InviteMember(ctxBob, id, domain.Invitation{Email: dan.Email, Role: domain.RoleViewer})
+		mustFail(t, err, domain.ErrListNotExist)
+	})
+
+	t.Run("invite and accept", func(t *testing.T) {
+		if _, err := usecase.InviteMember(ctxAnn, id, domain.Invitation{Email: " BOB@example.com", Role: domain.RoleViewer}); err != nil {
+			t.Fatalf("invite: %v", err)
+		}
+		_, err := usecase.InviteMember(ctxAnn, id, domain.Invitation{Email: bob.Email, Role: domain.RoleEditor})
+		mustFail(t, err, domain.ErrMemberExists)
+		_, err = usecase.InviteMember(ctxAnn, id, domain.Invitation{Email: dan.Email, Role: domain.RoleOwner})
+		mustFail(t, err, domain.ErrUnknownValue)
+
+		pending, err := usecase.GetInvitations(ctxBob)
+		if err != nil || len(pending) != 1 || pending[0].ListID != id || pending[0].InvitedBy != ann.ID {
+			t.Fatalf("unexpected invitations: got %+v, %v", pending, err)
+		}
+
+		_, err = usecase.AcceptInvitation(ctxDan, id)
+		mustFail(t, err, domain.ErrInvitationNotExist)
+		_, err = usecase.AcceptInvitation(ctxBob, 999)
+		mustFail(t, err, domain.ErrInvitationNotExist)
+
+		list, err := usecase.AcceptInvitation(ctxBob, id)
+		if err != nil {
+			t.Fatalf("accept: %v", err)
+		}
+		if role, _ := list.RoleOf(bob.ID); role != domain.RoleViewer || len(list.Invitations) != 0 {
+			t.Errorf("unexpected list: got %+v", list)
+		}
+	})
+
+	t.Run("only the owner manages the list", func(t *testing.T) {
+		_, err := usecase.InviteMember(ctxBob, id, domain.Invitation{Email: dan.Email, Role: domain.RoleViewer})
+		mustFail(t, err, domain.ErrForbidden)
+		_, err = usecase.RenameList(ctxBob, id, "mine")
+		mustFail(t, err, domain.ErrForbidden)
+		mustFail(t, usecase.DeleteListByID(ctxBob, id), domain.ErrForbidden)
+		_, err = usecase.ChangeMemberRole(ctxAnn, id, ann.ID, domain.RoleViewer)
+		mustFail(t, err, domain.ErrForbidden)
+		mustFail(t, usecase.RemoveMember(ctxAnn, id, ann.ID), domain.ErrForbidden)
+	})
+
+	t.Run("owner changes roles", func(t *testing.T) {
+		list, err := usecase.ChangeMemberRole(ctxAnn, id, bob.ID, domain.RoleEditor)
+		if err != nil {
+			t.Fatalf("change role: %v", err)
+		}
+		if role, _ := list.RoleOf(bob.ID); role != domain.RoleEditor {
+			t.Errorf("unexpected role: got %s", role)
+		}
+		_, err = usecase.ChangeMemberRole(ctxAnn, id, dan.ID, domain.RoleEditor)
+		mustFail(t, err, domain.ErrMemberNotExist)
+	})
+
+	t.Run("invitations are shown to the owner only", func(t *testing.T) {
+		if _, err := usecase.InviteMember(ctxAnn, id, domain.Invitation{Email: dan.Email, Role: domain.RoleViewer}); err != nil {
+			t.Fatalf("invite: %v", err)
+		}
+		owned, err := usecase.GetListByID(ctxAnn, id)
+		if err != nil || len(owned.Invitations) != 1 {
+			t.Errorf("unexpected list of the owner: got %+v, %v", owned, err)
+		}
+		shared, err := usecase.GetListByID(ctxBob, id)
+		if err != nil || shared.Invitations != nil {
+			t.Errorf("unexpected list of a member: got %+v, %v", shared, err)
+		}
+
+		if _, err := usecase.RevokeInvitation(ctxAnn, id, dan.Email); err != nil {
+			t.Fatalf("revoke: %v", err)
+		}
+		mustFail(t, usecase.DeclineInvitation(ctxDan, id), domain.ErrInvitationNotExist)
+	})
+
+	t.Run("member leaves", func(t *testing.T) {
+		if err := usecase.RemoveMember(ctxBob, id, bob.ID); err != nil {
+			t.Fatalf("leave: %v", err)
+		}
+		_, err := usecase.GetListByID(ctxBob, id)
+		mustFail(t, err, domain.ErrListNotExist)
+	})
+
+	t.Run("owner deletes the empty list", func(t *testing.T) {
+		if err := usecase.DeleteListByID(ctxAnn, id); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if lists.DeleteListByIDCalls != 1 {
+			t.Errorf("unexpected deletes: got %d, want 1", lists.DeleteListByIDCalls)
+		}
+	})
+}
+
+func TestDeleteListWithTodos(t *testing.T) {
+	// preparing
+	ctx := domain.ContextWithUser(context.Background(), ann)
+	mockRepo := &TodoRepositoryMock{
+		QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+			if q.Filter.ListID == nil || *q.Filter.ListID != 10 {
+				t.Errorf("unexpected query: got %+v", q.Filter)
+			}
+			return []domain.Todo{{ID: 1, ListID: intPtr(10)}}, nil
+		},
+	}
+	lists := newListRepo(sharedList())
+	usecase := New(mockRepo)
+	usecase.Lists = lists
+
+	// act
+	err := usecase.DeleteListByID(ctx, 10)
+
+	// assert
+	if !errors.Is(err, domain.ErrListNotEmpty) {
+		t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrListNotEmpty)
+	}
+	if lists.DeleteListByIDCalls != 0 {
+		t.Errorf("list with todos must be kept")
+	}
+}
+
+func TestDeleteListWithTrashedTodos(t *testing.T) {
+	// preparing
+	ctx := domain.ContextWithUser(context.Background(), ann)
+	listID := sharedList().ID
+	entry := domain.TrashedTodo{Todo: domain.Todo{ID: 1, OwnerID: ann.ID, ListID: &listID}}
+	mockRepo := &TodoRepositoryMock{
+		QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+			return []domain.Todo{}, nil
+		},
+	}
+	lists := newListRepo(sharedList())
+	usecase := New(mockRepo)
+	usecase.Lists = lists
+	usecase.Trash = &TrashRepositoryMock{
+		ReadTrashFunc: func(ctx context.Context) ([]domain.TrashedTodo, error) {
+			return []domain.TrashedTodo{entry}, nil
+		},
+		GetTrashedByIDFunc: func(ctx context.Context, id int) (domain.TrashedTodo, error) {
+			return entry, nil
+		},
+		RestoreByIDFunc: func(ctx context.Context, id int) (domain.TrashedTodo, error) {
+			return entry, nil
+		},
+	}
+
+	// act
+	deleteErr := usecase.DeleteListByID(ctx, listID)
+	restored, restoreErr := usecase.RestoreTodo(ctx, entry.Todo.ID)
+
+	// assert
+	if !errors.Is(deleteErr, domain.ErrListNotEmpty) {
+		t.Fatalf("unexpected error: got %v, want %v", deleteErr, domain.ErrListNotEmpty)
+	}
+	if lists.DeleteListByIDCalls != 0 {
+		t.Errorf("list with trashed todos must be kept")
+	}
+	if restoreErr != nil || restored.ID != entry.Todo.ID {
+		t.Errorf("unexpected restore: got %+v, %v", restored, restoreErr)
+	}
+}
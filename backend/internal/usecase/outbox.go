@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// Transactor runs a group of repository calls as one: those made with the
+// context handed to fn are stored together when fn returns nil and not at
+// all otherwise. A call nested in another joins the outer one. Calls made
+// with another context may wait until fn returns, so fn must not make them.
+type Transactor interface {
+	Atomically(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// EventOutbox keeps the events of stored changes until every subscriber
+// has handled them, see outbox.Dispatcher.
+type EventOutbox interface {
+	// AddEvents stores the events. Within Transactor.Atomically they are
+	// stored together with the change, or not at all.
+	AddEvents(ctx context.Context, events ...domain.TodoEvent) error
+	// Wake tells that new events are stored, so their delivery need not
+	// wait for the next poll.
+	Wake()
+}
+
+// atomically runs fn in a transaction of Tx, or just runs it without one.
+// The events added by fn are delivered once it is committed.
+func (u *TodoUseCase) atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+	if u.Tx != nil {
+		err = u.Tx.Atomically(ctx, fn)
+	} else {
+		err = fn(ctx)
+	}
+	if err == nil && u.Outbox != nil {
+		u.Outbox.Wake()
+	}
+	return err
+}
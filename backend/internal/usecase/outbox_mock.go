@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// TransactorMock runs fn right away. CommitErr fails the commit of an fn
+// that succeeded.
+type TransactorMock struct {
+	CommitErr error
+
+	AtomicallyCalls int
+	RolledBack      int
+}
+
+func (t *TransactorMock) Atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.AtomicallyCalls++
+
+	err := fn(ctx)
+	if err == nil {
+		err = t.CommitErr
+	}
+	if err != nil {
+		t.RolledBack++
+	}
+	return err
+}
+
+// EventOutboxMock keeps the added events and numbers them from 1.
+type EventOutboxMock struct {
+	AddEventsErr error
+
+	AddEventsCalls int
+	WakeCalls      int
+
+	Added []domain.TodoEvent
+}
+
+func (o *EventOutboxMock) AddEvents(ctx context.Context, events ...domain.TodoEvent) error {
+	o.AddEventsCalls++
+
+	if o.AddEventsErr != nil {
+		return o.AddEventsErr
+	}
+	for _, event := range events {
+		event.ID = uint64(len(o.Added) + 1)
+		o.Added = append(o.Added, event)
+	}
+	return nil
+}
+
+func (o *EventOutboxMock) Wake() {
+	o.WakeCalls++
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestTodoEventsOutbox(t *testing.T) {
+	ctx := domain.ContextWithUser(context.Background(), ann)
+	errOutbox := errors.New("outbox is down")
+
+	type added struct {
+		typ domain.TodoEventType
+		id  int
+	}
+
+	tests := []struct {
+		name      string
+		act       func(u *TodoUseCase) error
+		outboxErr error
+		commitErr error
+
+		wantErr  error
+		want     []added
+		wantWake int
+	}{
+		{
+			name: "create",
+			act: func(u *TodoUseCase) error {
+				_, err := u.CreateTodo(ctx, domain.Todo{Title: "new"})
+				return err
+			},
+			want:     []added{{domain.TodoCreated, 3}},
+			wantWake: 1,
+		},
+		{
+			name: "patch completing the todo -> both events in one transaction",
+			act: func(u *TodoUseCase) error {
+				_, err := u.PatchTodoByID(ctx, 1, 1, []byte(`{"completed":true}`))
+				return err
+			},
+			want:     []added{{domain.TodoUpdated, 1}, {domain.TodoCompleted, 1}},
+			wantWake: 1,
+		},
+		{
+			name: "delete",
+			act: func(u *TodoUseCase) error {
+				return u.DeleteTodoByID(ctx, 1, 0)
+			},
+			want:     []added{{domain.TodoDeleted, 1}},
+			wantWake: 1,
+		},
+		{
+			name: "outbox failure -> error and rollback",
+			act: func(u *TodoUseCase) error {
+				_, err := u.CreateTodo(ctx, domain.Todo{Title: "new"})
+				return err
+			},
+			outboxErr: errOutbox,
+			wantErr:   errOutbox,
+		},
+		{
+			name: "failed commit -> no wake",
+			act: func(u *TodoUseCase) error {
+				return u.DeleteTodoByID(ctx, 1, 0)
+			},
+			commitErr: errOutbox,
+			wantErr:   errOutbox,
+			want:      []added{{domain.TodoDeleted, 1}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			todos := map[int]domain.Todo{
+				1: {ID: 1, Title: "mine", OwnerID: ann.ID, Version: 1},
+			}
+			mockRepo := &TodoRepositoryMock{
+				SaveFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+					todo.ID, todo.Version = 3, 1
+					todos[3] = todo
+					return 3, nil
+				},
+				GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+					if todo, ok := todos[id]; ok {
+						return todo, nil
+					}
+					return domain.Todo{}, domain.ErrTodoNotExist
+				},
+				UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+					todo.ID = id
+					todo.Version++
+					todos[id] = todo
+					return nil
+				},
+				DeleteByIDFunc: func(ctx context.Context, id int, version int) error {
+					delete(todos, id)
+					return nil
+				},
+			}
+			tx := &TransactorMock{CommitErr: tc.commitErr}
+			outbox := &EventOutboxMock{AddEventsErr: tc.outboxErr}
+			hub := &EventHubMock{}
+			usecase := New(mockRepo)
+			usecase.Tx = tx
+			usecase.Outbox = outbox
+			usecase.Events = hub
+
+			// act
+			err := tc.act(usecase)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if tx.AtomicallyCalls != 1 {
+				t.Errorf("unexpected transactions: got %d, want %d", tx.AtomicallyCalls, 1)
+			}
+			if wantRollback := tc.wantErr != nil; (tx.RolledBack == 1) != wantRollback {
+				t.Errorf("unexpected rollbacks: got %d, want rollback %v", tx.RolledBack, wantRollback)
+			}
+
+			var got []added
+			for _, event := range outbox.Added {
+				got = append(got, added{event.Type, event.Todo.ID})
+				if event.ActorID != ann.ID || event.Tenant != domain.DefaultTenant {
+					t.Errorf("unexpected actor or tenant of event: got %+v", event)
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("unexpected events: got %v, want %v", got, tc.want)
+			}
+			if outbox.WakeCalls != tc.wantWake {
+				t.Errorf("unexpected wakes: got %d, want %d", outbox.WakeCalls, tc.wantWake)
+			}
+
+			// the hub gets the events from the dispatcher of the outbox only
+			if len(hub.Published) != 0 {
+				t.Errorf("unexpected published events: got %+v, want none", hub.Published)
+			}
+		})
+	}
+}
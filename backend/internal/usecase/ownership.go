@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// access is what a request does with a todo.
+type access int
+
+const (
+	accessRead access = iota
+	accessWrite
+)
+
+// owner returns the id of the authenticated user. Without one, as in
+// background jobs, todos are not scoped to a user.
+func owner(ctx context.Context) (int, bool) {
+	user, ok := domain.UserFromContext(ctx)
+	return user.ID, ok
+}
+
+// getTodo reads a todo the authenticated user may access. Todos the user
+// cannot see are reported as missing, so their ids do not leak; todos the
+// user sees but may not change fail with domain.ErrForbidden.
+func (u *TodoUseCase) getTodo(ctx context.Context, id int, need access) (domain.Todo, error) {
+	todo, err := u.TodoRepo.GetByID(ctx, id)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	if err := u.checkAccess(ctx, todo, need); err != nil {
+		return domain.Todo{}, err
+	}
+	return todo, nil
+}
+
+// checkAccess makes sure the authenticated user may read or change the
+// todo, see getTodo.
+func (u *TodoUseCase) checkAccess(ctx context.Context, todo domain.Todo, need access) error {
+	userID, ok := owner(ctx)
+	if !ok {
+		return nil
+	}
+
+	role, err := u.todoRole(ctx, userID, todo)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return domain.ErrTodoNotExist
+	}
+	if need == accessWrite && !role.Allows(domain.RoleEditor) {
+		return fmt.Errorf("%w: %s cannot change todos", domain.ErrForbidden, role)
+	}
+
+	return nil
+}
+
+// todoRole returns the role of the user for a todo, empty when the user
+// cannot see it. A todo outside of lists belongs to its owner alone.
+func (u *TodoUseCase) todoRole(ctx context.Context, userID int, todo domain.Todo) (domain.Role, error) {
+	if todo.ListID == nil {
+		if todo.OwnerID == userID {
+			return domain.RoleOwner, nil
+		}
+		return "", nil
+	}
+	return u.listRole(ctx, userID, *todo.ListID)
+}
+
+// listRole returns the role of the user in the list, empty for missing
+// lists and lists the user is not a member of.
+func (u *TodoUseCase) listRole(ctx context.Context, userID int, listID int) (domain.Role, error) {
+	if u.Lists == nil {
+		return "", nil
+	}
+
+	list, err := u.Lists.GetListByID(ctx, listID)
+	if errors.Is(err, domain.ErrListNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get list by id: %w", err)
+	}
+
+	role, _ := list.RoleOf(userID)
+	return role, nil
+}
+
+// visibility returns what the authenticated user may read, nil without
+// one.
+func (u *TodoUseCase) visibility(ctx context.Context) (*domain.Visibility, error) {
+	userID, ok := owner(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	v := &domain.Visibility{UserID: userID}
+	if u.Lists == nil {
+		return v, nil
+	}
+
+	lists, err := u.Lists.ListsByMember(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get lists of user: %w", err)
+	}
+	for _, list := range lists {
+		v.ListIDs = append(v.ListIDs, list.ID)
+	}
+
+	return v, nil
+}
+
+// checkPlacement makes sure a todo is created in or moved to a list the
+// user may edit, and that a subtask shares the list of its parent, which
+// the user must see. A subtask created without a list joins the list of
+// its parent. current is nil for a new todo.
+func (u *TodoUseCase) checkPlacement(ctx context.Context, todo *domain.Todo, current *domain.Todo) error {
+	userID, ok := owner(ctx)
+	if !ok {
+		return nil
+	}
+
+	var currentList *int
+	if current != nil {
+		currentList = current.ListID
+	}
+	moved := current == nil || !equalIntPtr(todo.ParentID, current.ParentID) ||
+		!equalIntPtr(todo.ListID, currentList)
+
+	if todo.ParentID != nil && moved {
+		parent, err := u.getTodo(ctx, *todo.ParentID, accessRead)
+		if err != nil {
+			return fmt.Errorf("get parent todo: %w", todoRefError(parentError(err)))
+		}
+		if current == nil && todo.ListID == nil {
+			todo.ListID = parent.ListID
+		}
+		if !equalIntPtr(todo.ListID, parent.ListID) {
+			return listMismatch()
+		}
+	}
+
+	if equalIntPtr(todo.ListID, currentList) {
+		return nil
+	}
+
+	if todo.ListID != nil {
+		role, err := u.listRole(ctx, userID, *todo.ListID)
+		if err != nil {
+			return err
+		}
+		if role == "" {
+			return domain.ValidationErrors{{Field: "list_id", Err: domain.ErrUnknownValue}}
+		}
+		if !role.Allows(domain.RoleEditor) {
+			return fmt.Errorf("%w: %s cannot add todos", domain.ErrForbidden, role)
+		}
+	} else if current.OwnerID != userID {
+		// the todo would leave for the private todos of someone else
+		return fmt.Errorf("%w: only the owner of the todo can take it out of the list", domain.ErrForbidden)
+	}
+
+	if current != nil {
+		subtasks, err := u.TodoRepo.Query(ctx, domain.TodoQuery{Filter: domain.TodoFilter{ParentID: &current.ID}, Limit: 1})
+		if err != nil {
+			return fmt.Errorf("query subtasks: %w", err)
+		}
+		if len(subtasks) > 0 {
+			return listMismatch()
+		}
+	}
+
+	return nil
+}
+
+func listMismatch() error {
+	return domain.ValidationErrors{{Field: "list_id", Err: domain.ErrListMismatch}}
+}
+
+func parentError(err error) error {
+	if errors.Is(err, domain.ErrTodoNotExist) {
+		return domain.ErrParentNotExist
+	}
+	return err
+}
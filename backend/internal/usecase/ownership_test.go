@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestTodoOwnership(t *testing.T) {
+	ann := domain.ContextWithUser(context.Background(), domain.User{ID: 1, Email: "ann@example.com"})
+
+	tests := []struct {
+		name string
+		act  func(u *TodoUseCase) error
+
+		wantErr         error
+		wantUpdateCalls int
+		wantDeleteCalls int
+	}{
+		{
+			name: "own todo",
+			act: func(u *TodoUseCase) error {
+				_, err := u.GetTodoByID(ann, 1)
+				return err
+			},
+		},
+		{
+			name: "todo of another user -> not found",
+			act: func(u *TodoUseCase) error {
+				_, err := u.GetTodoByID(ann, 2)
+				return err
+			},
+			wantErr: domain.ErrTodoNotExist,
+		},
+		{
+			name: "update of another user's todo -> not found",
+			act: func(u *TodoUseCase) error {
+				return u.UpdateTodoByID(ann, 2, domain.Todo{Title: "mine now"})
+			},
+			wantErr: domain.ErrTodoNotExist,
+		},
+		{
+			name: "patch of another user's todo -> not found",
+			act: func(u *TodoUseCase) error {
+				_, err := u.PatchTodoByID(ann, 2, 0, []byte(`{"owner_id":1}`))
+				return err
+			},
+			wantErr: domain.ErrTodoNotExist,
+		},
+		{
+			name: "delete of another user's todo -> not found",
+			act: func(u *TodoUseCase) error {
+				return u.DeleteTodoByID(ann, 2, 0)
+			},
+			wantErr: domain.ErrTodoNotExist,
+		},
+		{
+			name: "delete of own todo",
+			act: func(u *TodoUseCase) error {
+				return u.DeleteTodoByID(ann, 1, 0)
+			},
+			wantDeleteCalls: 1,
+		},
+		{
+			name: "owner cannot be patched",
+			act: func(u *TodoUseCase) error {
+				todo, err := u.PatchTodoByID(ann, 1, 0, []byte(`{"owner_id":2}`))
+				if err == nil && todo.OwnerID != 1 {
+					t.Errorf("unexpected owner: got %d, want 1", todo.OwnerID)
+				}
+				return err
+			},
+			wantUpdateCalls: 1,
+		},
+		{
+			name: "subtask of another user's todo -> error",
+			act: func(u *TodoUseCase) error {
+				return u.UpdateTodoByID(ann, 1, domain.Todo{Title: "mine", ParentID: intPtr(2)})
+			},
+			wantErr: domain.ErrUnknownValue,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			todos := map[int]domain.Todo{
+				1: {ID: 1, Title: "mine", OwnerID: 1, Version: 1},
+				2: {ID: 2, Title: "theirs", OwnerID: 2, Version: 1},
+			}
+			mockRepo := &TodoRepositoryMock{
+				GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+					if todo, ok := todos[id]; ok {
+						return todo, nil
+					}
+					return domain.Todo{}, domain.ErrTodoNotExist
+				},
+				UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+					if todo.OwnerID != todos[id].OwnerID {
+						t.Errorf("owner must not change: got %d, want %d", todo.OwnerID, todos[id].OwnerID)
+					}
+					return nil
+				},
+				DeleteByIDFunc: func(ctx context.Context, id int, version int) error {
+					return nil
+				},
+			}
+			usecase := New(mockRepo)
+
+			// act
+			err := tc.act(usecase)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if mockRepo.UpdateByIDCalls != tc.wantUpdateCalls || mockRepo.DeleteByIDCalls != tc.wantDeleteCalls {
+				t.Errorf("unexpected calls: got %d updates %d deletes, want %d and %d",
+					mockRepo.UpdateByIDCalls, mockRepo.DeleteByIDCalls, tc.wantUpdateCalls, tc.wantDeleteCalls)
+			}
+		})
+	}
+}
+
+func TestTodoOwnershipScopesQueries(t *testing.T) {
+	// preparing
+	ann := domain.ContextWithUser(context.Background(), domain.User{ID: 1})
+	mockRepo := &TodoRepositoryMock{
+		SaveFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+			return 1, nil
+		},
+		QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+			return nil, nil
+		},
+	}
+	usecase := New(mockRepo)
+
+	// act
+	_, createErr := usecase.CreateTodo(ann, domain.Todo{Title: "mine", OwnerID: 2})
+	_, listErr := usecase.ListTodos(ann, domain.TodoQuery{}, "")
+
+	// assert
+	if createErr != nil || listErr != nil {
+		t.Fatalf("unexpected errors: got %v and %v, want nil", createErr, listErr)
+	}
+	if mockRepo.LastSavedTodo.OwnerID != 1 {
+		t.Errorf("unexpected owner of the new todo: got %d, want 1", mockRepo.LastSavedTodo.OwnerID)
+	}
+	if v := mockRepo.LastQuery.Filter.Visible; v == nil || v.UserID != 1 || len(v.ListIDs) != 0 {
+		t.Errorf("query must be scoped to the user: got %+v", v)
+	}
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/rrule"
+)
+
+// PreviewOccurrences returns up to n due dates that follow the current one
+// of a recurring todo, none for a todo that does not repeat.
+func (u *TodoUseCase) PreviewOccurrences(ctx context.Context, id int, n int) ([]time.Time, error) {
+	if n < 1 || n > domain.MaxOccurrencesPreview {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", domain.ErrInvalidQuery, domain.MaxOccurrencesPreview)
+	}
+
+	todo, err := u.getTodo(ctx, id, accessRead)
+	if err != nil {
+		return nil, fmt.Errorf("get todo by id: %w", err)
+	}
+	if todo.Recurrence == nil || todo.DueAt == nil {
+		return []time.Time{}, nil
+	}
+
+	occurrences, err := todo.Recurrence.Upcoming(*todo.DueAt, n)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate recurrence: %w", err)
+	}
+
+	return occurrences, nil
+}
+
+// normalizeRecurrence keeps the rule in its canonical form and starts the
+// series at the due date unless the client chose another start. The todo
+// must be valid.
+func normalizeRecurrence(todo *domain.Todo) {
+	if todo.Recurrence == nil {
+		return
+	}
+
+	rec := *todo.Recurrence
+	if rule, err := rrule.Parse(rec.Rule); err == nil {
+		rec.Rule = rule.String()
+	}
+	if rec.Start.IsZero() && todo.DueAt != nil {
+		rec.Start = *todo.DueAt
+	}
+	rec.Start = rec.Start.Truncate(time.Microsecond)
+
+	todo.Recurrence = &rec
+}
+
+// nextOccurrence returns the todo that follows a recurring todo being
+// completed, false when there is none.
+func (u *TodoUseCase) nextOccurrence(todo domain.Todo, current domain.Todo) (domain.Todo, bool, error) {
+	if todo.Recurrence == nil || todo.DueAt == nil || !todo.Completed || current.Completed {
+		return domain.Todo{}, false, nil
+	}
+
+	due, ok, err := todo.Recurrence.Next(*todo.DueAt)
+	if err != nil {
+		return domain.Todo{}, false, fmt.Errorf("evaluate recurrence: %w", err)
+	}
+	if !ok {
+		return domain.Todo{}, false, nil
+	}
+
+	var checklist []domain.ChecklistItem
+	for _, item := range todo.Checklist {
+		checklist = append(checklist, domain.ChecklistItem{Title: item.Title})
+	}
+
+	next := domain.Todo{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Priority:    todo.Priority,
+		DueAt:       &due,
+		Tags:        slices.Clone(todo.Tags),
+		ParentID:    todo.ParentID,
+		Checklist:   checklist,
+		Recurrence:  todo.Recurrence,
+		OwnerID:     todo.OwnerID,
+		ListID:      todo.ListID,
+	}
+	u.stampCreated(&next)
+
+	return next, true, nil
+}
+
+// store writes the todo. Completing an occurrence of a recurring todo
+// creates the next one first and hands the recurrence over to it, so
+// completing the same occurrence again does not repeat it twice.
+func (u *TodoUseCase) store(ctx context.Context, id int, todo *domain.Todo, current domain.Todo) error {
+	next, ok, err := u.nextOccurrence(*todo, current)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := u.TodoRepo.UpdateByID(ctx, id, *todo); err != nil {
+			return err
+		}
+		recordUpdated(ctx, current, *todo)
+		return u.publishStored(ctx, id, updatedEvents(*todo, current)...)
+	}
+
+	nextID, err := u.TodoRepo.Save(ctx, next)
+	if err != nil {
+		return fmt.Errorf("save next occurrence: %w", err)
+	}
+	recordCreated(ctx, nextID, next)
+
+	todo.Recurrence = nil
+	if err := u.TodoRepo.UpdateByID(ctx, id, *todo); err != nil {
+		// the next occurrence must not outlive a failed completion, which
+		// without Tx is not rolled back
+		if err := u.TodoRepo.DeleteByID(ctx, nextID, 0); err != nil {
+			slog.Warn("failed to delete next occurrence", "id", nextID, "error", err)
+		}
+		return err
+	}
+	recordUpdated(ctx, current, *todo)
+
+	if err := u.publishStored(ctx, id, updatedEvents(*todo, current)...); err != nil {
+		return err
+	}
+	if err := u.publishStored(ctx, nextID, domain.TodoCreated); err != nil {
+		return err
+	}
+	slog.Info("next occurrence created", "id", nextID, "previous", id, "due_at", next.DueAt)
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestCompleteRecurringTodo(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	// the last occurrence before summer time starts on 2030-03-31
+	due := time.Date(2030, time.March, 30, 9, 0, 0, 0, berlin)
+
+	newCurrent := func(rule string) domain.Todo {
+		return domain.Todo{
+			ID:         1,
+			Title:      "water the plants",
+			Version:    2,
+			DueAt:      &due,
+			Tags:       []int{3},
+			Checklist:  []domain.ChecklistItem{{Title: "balcony", Done: true}},
+			Recurrence: &domain.Recurrence{Rule: rule, Timezone: "Europe/Berlin", Start: due.AddDate(0, 0, -1)},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		rule      string
+		updateErr error
+
+		wantErr     error
+		wantSaves   int
+		wantDeletes int
+		wantNextDue time.Time
+		wantKept    bool
+	}{
+		{
+			name:        "next occurrence keeps the wall clock time",
+			rule:        "FREQ=DAILY",
+			wantSaves:   1,
+			wantNextDue: time.Date(2030, time.March, 31, 9, 0, 0, 0, berlin),
+		},
+		{
+			name:     "series has ended",
+			rule:     "FREQ=DAILY;COUNT=2",
+			wantKept: true,
+		},
+		{
+			name:        "failed update removes the next occurrence",
+			rule:        "FREQ=DAILY",
+			updateErr:   domain.ErrVersionMismatch,
+			wantErr:     domain.ErrVersionMismatch,
+			wantSaves:   1,
+			wantDeletes: 1,
+			wantNextDue: time.Date(2030, time.March, 31, 9, 0, 0, 0, berlin),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			current := newCurrent(tc.rule)
+			var (
+				saved   domain.Todo
+				updated domain.Todo
+			)
+			mockRepo := &TodoRepositoryMock{
+				GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+					return current, nil
+				},
+				SaveFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+					saved = todo
+					return 7, nil
+				},
+				UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+					updated = todo
+					return tc.updateErr
+				},
+				DeleteByIDFunc: func(ctx context.Context, id int, version int) error {
+					if id != 7 {
+						t.Errorf("unexpected deleted id: got %d, want 7", id)
+					}
+					return nil
+				},
+			}
+			usecase := New(mockRepo)
+
+			todo := current
+			todo.Completed = true
+
+			// act
+			err := usecase.UpdateTodoByID(context.Background(), 1, todo)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if mockRepo.SaveCalls != tc.wantSaves || mockRepo.DeleteByIDCalls != tc.wantDeletes {
+				t.Fatalf("unexpected calls: got %d saves %d deletes, want %d and %d",
+					mockRepo.SaveCalls, mockRepo.DeleteByIDCalls, tc.wantSaves, tc.wantDeletes)
+			}
+
+			if (updated.Recurrence != nil) != tc.wantKept {
+				t.Errorf("unexpected recurrence of the completed todo: got %+v", updated.Recurrence)
+			}
+			if tc.wantSaves == 0 {
+				return
+			}
+
+			if saved.DueAt == nil || !saved.DueAt.Equal(tc.wantNextDue) {
+				t.Errorf("unexpected next due date: got %v, want %v", saved.DueAt, tc.wantNextDue)
+			}
+			if saved.Completed || saved.Title != current.Title || len(saved.Tags) != 1 {
+				t.Errorf("unexpected next occurrence: got %+v", saved)
+			}
+			if len(saved.Checklist) != 1 || saved.Checklist[0].Done {
+				t.Errorf("checklist of the next occurrence must be reset: got %+v", saved.Checklist)
+			}
+			if saved.Recurrence == nil || !saved.Recurrence.Equal(*current.Recurrence) {
+				t.Errorf("recurrence must move to the next occurrence: got %+v", saved.Recurrence)
+			}
+		})
+	}
+}
+
+func TestCreateRecurringTodo(t *testing.T) {
+	// preparing
+	mockRepo := &TodoRepositoryMock{
+		SaveFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+			return 1, nil
+		},
+	}
+	usecase := New(mockRepo)
+	due := time.Date(2030, time.January, 7, 9, 0, 0, 0, time.UTC)
+
+	// act
+	_, err := usecase.CreateTodo(context.Background(), domain.Todo{
+		Title:      "water the plants",
+		DueAt:      &due,
+		Recurrence: &domain.Recurrence{Rule: "rrule:byday=mo,th;freq=weekly"},
+	})
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+	rec := mockRepo.LastSavedTodo.Recurrence
+	if rec == nil || rec.Rule != "FREQ=WEEKLY;BYDAY=MO,TH" || !rec.Start.Equal(due) {
+		t.Errorf("unexpected recurrence: got %+v", rec)
+	}
+}
+
+func TestPreviewOccurrences(t *testing.T) {
+	due := time.Date(2030, time.January, 31, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		recurrence *domain.Recurrence
+		count      int
+		wantErr    error
+		want       []time.Time
+	}{
+		{
+			name:       "monthly skips short months",
+			recurrence: &domain.Recurrence{Rule: "FREQ=MONTHLY", Start: due},
+			count:      2,
+			want:       []time.Time{due.AddDate(0, 2, 0), due.AddDate(0, 4, 0)},
+		},
+		{name: "not recurring", count: 2, want: []time.Time{}},
+		{name: "zero count -> error", count: 0, wantErr: domain.ErrInvalidQuery},
+		{name: "count too large -> error", count: domain.MaxOccurrencesPreview + 1, wantErr: domain.ErrInvalidQuery},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			mockRepo := &TodoRepositoryMock{
+				GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+					return domain.Todo{ID: id, Title: "pay the rent", DueAt: &due, Recurrence: tc.recurrence}, nil
+				},
+			}
+			usecase := New(mockRepo)
+
+			// act
+			got, err := usecase.PreviewOccurrences(context.Background(), 1, tc.count)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("unexpected occurrences: got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tc.want[i]) {
+					t.Errorf("unexpected occurrence %d: got %v, want %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
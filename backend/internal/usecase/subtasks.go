@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// ListSubtasks returns one page of the direct subtasks of the todo matching
+// the query.
+func (u *TodoUseCase) ListSubtasks(ctx context.Context, id int, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+	if _, err := u.getTodo(ctx, id, accessRead); err != nil {
+		return domain.TodoPage{}, fmt.Errorf("get todo by id: %w", err)
+	}
+
+	q.Filter.ParentID = &id
+
+	return u.ListTodos(ctx, q, cursor)
+}
+
+// GetTodoProgress tells how far the todo is, looking at its subtasks at any
+// depth.
+func (u *TodoUseCase) GetTodoProgress(ctx context.Context, id int) (domain.Progress, error) {
+	todo, err := u.getTodo(ctx, id, accessRead)
+	if err != nil {
+		return domain.Progress{}, fmt.Errorf("get todo by id: %w", err)
+	}
+
+	subtasks, err := u.subtasks(ctx, id)
+	if err != nil {
+		return domain.Progress{}, err
+	}
+
+	p := domain.Progress{
+		TodoID:         id,
+		Subtasks:       len(subtasks),
+		ChecklistItems: len(todo.Checklist),
+	}
+	for _, s := range subtasks {
+		if s.Completed {
+			p.SubtasksCompleted++
+		}
+	}
+	for _, item := range todo.Checklist {
+		if item.Done {
+			p.ChecklistDone++
+		}
+	}
+
+	done, err := u.completion(ctx, todo, subtasks)
+	if err != nil {
+		return domain.Progress{}, err
+	}
+	// the epsilon keeps 0.29 * 100 from landing on 28
+	p.Percent = int(math.Floor(done*100 + 1e-9))
+
+	return p, nil
+}
+
+// completion returns the share of the todo that is done, between 0 and 1.
+func (u *TodoUseCase) completion(ctx context.Context, todo domain.Todo, subtasks []domain.Todo) (float64, error) {
+	if todo.Completed {
+		return 1, nil
+	}
+
+	total := len(subtasks) + len(todo.Checklist)
+	if total == 0 {
+		return 0, nil
+	}
+
+	var done float64
+	for _, s := range subtasks {
+		if s.Completed {
+			done++
+			continue
+		}
+
+		children, err := u.subtasks(ctx, s.ID)
+		if err != nil {
+			return 0, err
+		}
+		c, err := u.completion(ctx, s, children)
+		if err != nil {
+			return 0, err
+		}
+		done += c
+	}
+	for _, item := range todo.Checklist {
+		if item.Done {
+			done++
+		}
+	}
+
+	return done / float64(total), nil
+}
+
+func (u *TodoUseCase) subtasks(ctx context.Context, id int) ([]domain.Todo, error) {
+	todos, err := u.TodoRepo.Query(ctx, domain.TodoQuery{Filter: domain.TodoFilter{ParentID: &id}})
+	if err != nil {
+		return nil, fmt.Errorf("query subtasks of todo %d: %w", id, err)
+	}
+	return todos, nil
+}
+
+func (u *TodoUseCase) hasOpenSubtasks(ctx context.Context, id int) (bool, error) {
+	open := false
+	todos, err := u.TodoRepo.Query(ctx, domain.TodoQuery{
+		Filter: domain.TodoFilter{ParentID: &id, Completed: &open},
+		Limit:  1,
+	})
+	if err != nil {
+		return false, fmt.Errorf("query open subtasks of todo %d: %w", id, err)
+	}
+	return len(todos) > 0, nil
+}
+
+// checkCompletion enforces Rules.BlockOpenSubtasks when the todo is about to
+// be completed.
+func (u *TodoUseCase) checkCompletion(ctx context.Context, todo domain.Todo, current domain.Todo) error {
+	if !u.Rules.BlockOpenSubtasks || !todo.Completed || current.Completed {
+		return nil
+	}
+
+	open, err := u.hasOpenSubtasks(ctx, current.ID)
+	if err != nil {
+		return err
+	}
+	if open {
+		return fmt.Errorf("complete todo %d: %w", current.ID, domain.ErrOpenSubtasks)
+	}
+
+	return nil
+}
+
+// completed enforces Rules.AutoCompleteParent once the todo is stored.
+func (u *TodoUseCase) completed(ctx context.Context, todo domain.Todo, current domain.Todo) error {
+	if !todo.Completed || todo.ParentID == nil {
+		return nil
+	}
+	// a completed todo moved under a new parent counts as well
+	if current.Completed && equalIntPtr(todo.ParentID, current.ParentID) {
+		return nil
+	}
+
+	return u.completeParents(ctx, *todo.ParentID)
+}
+
+// completeParents completes the todo once none of its subtasks is open,
+// then does the same for its parent. The change that triggered it stands
+// on its own, so failures of the parents are only logged; only a failure
+// to store their events is returned.
+func (u *TodoUseCase) completeParents(ctx context.Context, id int) error {
+	if !u.Rules.AutoCompleteParent {
+		return nil
+	}
+
+	for {
+		parent, err := u.TodoRepo.GetByID(ctx, id)
+		if err != nil {
+			slog.Warn("failed to auto-complete parent todo", "id", id, "error", err)
+			return nil
+		}
+		if parent.Completed {
+			return nil
+		}
+
+		open, err := u.hasOpenSubtasks(ctx, id)
+		if err != nil {
+			slog.Warn("failed to auto-complete parent todo", "id", id, "error", err)
+			return nil
+		}
+		if open {
+			return nil
+		}
+
+		todo := parent
+		todo.Completed = true
+		u.stampUpdated(&todo, parent)
+
+		if err := u.TodoRepo.UpdateByID(ctx, id, todo); err != nil {
+			slog.Warn("failed to auto-complete parent todo", "id", id, "error", err)
+			return nil
+		}
+		recordUpdated(ctx, parent, todo)
+		if err := u.publishStored(ctx, id, domain.TodoUpdated, domain.TodoCompleted); err != nil {
+			return err
+		}
+
+		if parent.ParentID == nil {
+			return nil
+		}
+		id = *parent.ParentID
+	}
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
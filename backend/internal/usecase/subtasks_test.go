@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// newTreeRepo serves the todos from a slice, updates change it in place.
+func newTreeRepo(todos []domain.Todo) *TodoRepositoryMock {
+	return &TodoRepositoryMock{
+		GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+			i := slices.IndexFunc(todos, func(t domain.Todo) bool { return t.ID == id })
+			if i < 0 {
+				return domain.Todo{}, domain.ErrTodoNotExist
+			}
+			return todos[i], nil
+		},
+		UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+			i := slices.IndexFunc(todos, func(t domain.Todo) bool { return t.ID == id })
+			todo.ID = id
+			todo.Version++
+			todos[i] = todo
+			return nil
+		},
+		QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+			return q.Apply(slices.Values(todos)), nil
+		},
+	}
+}
+
+func TestGetTodoProgress(t *testing.T) {
+	todos := []domain.Todo{
+		{ID: 1, Title: "move out", Checklist: []domain.ChecklistItem{{Title: "call the landlord", Done: true}, {Title: "hand over the keys"}}},
+		{ID: 2, Title: "pack the books", ParentID: intPtr(1), Completed: true},
+		{ID: 3, Title: "pack the kitchen", ParentID: intPtr(1)},
+		{ID: 4, Title: "pack the plates", ParentID: intPtr(3), Completed: true},
+		{ID: 5, Title: "pack the pans", ParentID: intPtr(3)},
+		{ID: 6, Title: "book the van", Completed: true, Checklist: []domain.ChecklistItem{{Title: "compare prices"}}},
+		{ID: 7, Title: "read the book"},
+	}
+
+	tests := []struct {
+		name    string
+		id      int
+		wantErr error
+		want    domain.Progress
+	}{
+		{
+			name: "subtasks weighted by their own progress",
+			id:   1,
+			want: domain.Progress{TodoID: 1, Percent: 62, Subtasks: 2, SubtasksCompleted: 1, ChecklistItems: 2, ChecklistDone: 1},
+		},
+		{name: "half of the subtasks", id: 3, want: domain.Progress{TodoID: 3, Percent: 50, Subtasks: 2, SubtasksCompleted: 1}},
+		{name: "completed todo", id: 6, want: domain.Progress{TodoID: 6, Percent: 100, ChecklistItems: 1}},
+		{name: "nothing to count", id: 7, want: domain.Progress{TodoID: 7}},
+		{name: "unknown todo -> error", id: 9, wantErr: domain.ErrTodoNotExist},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			usecase := New(newTreeRepo(slices.Clone(todos)))
+
+			// act
+			got, err := usecase.GetTodoProgress(context.Background(), tc.id)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("unexpected progress: got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSubtaskRules(t *testing.T) {
+	todos := []domain.Todo{
+		{ID: 1, Title: "move out", Version: 1},
+		{ID: 2, Title: "pack the kitchen", ParentID: intPtr(1), Version: 1},
+		{ID: 3, Title: "pack the plates", ParentID: intPtr(2), Version: 1},
+		{ID: 4, Title: "book the van", ParentID: intPtr(1), Completed: true, Version: 1},
+	}
+
+	tests := []struct {
+		name          string
+		rules         domain.SubtaskRules
+		id            int
+		wantErr       error
+		wantCompleted []int
+	}{
+		{name: "no rules", id: 2, wantCompleted: []int{2, 4}},
+		{name: "block open subtasks", rules: domain.SubtaskRules{BlockOpenSubtasks: true}, id: 2, wantErr: domain.ErrOpenSubtasks, wantCompleted: []int{4}},
+		{name: "block ignores leaves", rules: domain.SubtaskRules{BlockOpenSubtasks: true}, id: 3, wantCompleted: []int{3, 4}},
+		{name: "auto-complete parents all the way up", rules: domain.SubtaskRules{AutoCompleteParent: true}, id: 3, wantCompleted: []int{1, 2, 3, 4}},
+		{name: "auto-complete looks at direct subtasks only", rules: domain.SubtaskRules{AutoCompleteParent: true}, id: 2, wantCompleted: []int{1, 2, 4}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			stored := slices.Clone(todos)
+			usecase := New(newTreeRepo(stored))
+			usecase.Rules = tc.rules
+
+			// act
+			_, err := usecase.PatchTodoByID(context.Background(), tc.id, 0, []byte(`{"completed":true}`))
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+
+			var completed []int
+			for _, todo := range stored {
+				if todo.Completed {
+					completed = append(completed, todo.ID)
+				}
+				if todo.Completed && todo.CompletedAt == nil && todo.Version > 1 {
+					t.Errorf("todo %d completed without a timestamp", todo.ID)
+				}
+			}
+			if !slices.Equal(completed, tc.wantCompleted) {
+				t.Errorf("unexpected completed todos: got %v, want %v", completed, tc.wantCompleted)
+			}
+		})
+	}
+}
+
+func TestTodoParent(t *testing.T) {
+	t.Run("unknown parent on create -> validation error", func(t *testing.T) {
+		// preparing
+		mockRepo := &TodoRepositoryMock{
+			SaveFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+				return 0, domain.ErrParentNotExist
+			},
+		}
+		usecase := New(mockRepo)
+
+		// act
+		_, err := usecase.CreateTodo(context.Background(), domain.Todo{Title: "read the book", ParentID: intPtr(9)})
+
+		// assert
+		var verrs domain.ValidationErrors
+		if !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Field != "parent_id" {
+			t.Fatalf("unexpected error: got %v, want validation error of parent_id", err)
+		}
+	})
+
+	t.Run("cycle on update -> validation error", func(t *testing.T) {
+		// preparing
+		mockRepo := newTreeRepo([]domain.Todo{{ID: 1, Title: "move out", Version: 1}})
+		mockRepo.UpdateByIDFunc = func(ctx context.Context, id int, todo domain.Todo) error {
+			return domain.ErrParentCycle
+		}
+		usecase := New(mockRepo)
+
+		// act
+		err := usecase.UpdateTodoByID(context.Background(), 1, domain.Todo{Title: "move out", ParentID: intPtr(2)})
+
+		// assert
+		if !errors.Is(err, domain.ErrValidation) || !errors.Is(err, domain.ErrParentCycle) {
+			t.Fatalf("unexpected error: got %v, want validation error %v", err, domain.ErrParentCycle)
+		}
+	})
+
+	t.Run("update without parent and checklist keeps them", func(t *testing.T) {
+		// preparing
+		checklist := []domain.ChecklistItem{{Title: "compare prices"}}
+		mockRepo := newTreeRepo([]domain.Todo{{ID: 2, Title: "book the van", ParentID: intPtr(1), Checklist: checklist, Version: 1}})
+		usecase := New(mockRepo)
+
+		// act
+		err := usecase.UpdateTodoByID(context.Background(), 2, domain.Todo{Title: "book a bigger van"})
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		got := mockRepo.LastSavedTodo
+		if got.ParentID == nil || *got.ParentID != 1 || !slices.Equal(got.Checklist, checklist) {
+			t.Errorf("unexpected todo: got parent %v checklist %v", got.ParentID, got.Checklist)
+		}
+	})
+
+	t.Run("list subtasks of unknown todo -> error", func(t *testing.T) {
+		// preparing
+		mockRepo := newTreeRepo(nil)
+		usecase := New(mockRepo)
+
+		// act
+		_, err := usecase.ListSubtasks(context.Background(), 9, domain.TodoQuery{}, "")
+
+		// assert
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+		if mockRepo.QueryCalls != 0 {
+			t.Errorf("unexpected query calls: got %d, want 0", mockRepo.QueryCalls)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func (u *TodoUseCase) CreateTag(ctx context.Context, tag domain.Tag) (int, error) {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if err := tag.Validate(); err != nil {
+		return 0, fmt.Errorf("validate tag: %w", err)
+	}
+
+	id, err := u.TodoRepo.SaveTag(ctx, tag)
+	if err != nil {
+		return 0, fmt.Errorf("save tag in db: %w", err)
+	}
+
+	return id, nil
+}
+
+func (u *TodoUseCase) GetAllTags(ctx context.Context) ([]domain.Tag, error) {
+	return u.TodoRepo.ReadAllTags(ctx)
+}
+
+func (u *TodoUseCase) GetTagByID(ctx context.Context, id int) (domain.Tag, error) {
+	tag, err := u.TodoRepo.GetTagByID(ctx, id)
+	if err != nil {
+		return domain.Tag{}, fmt.Errorf("get tag by id: %w", err)
+	}
+	return tag, nil
+}
+
+func (u *TodoUseCase) UpdateTagByID(ctx context.Context, id int, tag domain.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if err := tag.Validate(); err != nil {
+		return fmt.Errorf("validate tag: %w", err)
+	}
+
+	if err := u.TodoRepo.UpdateTagByID(ctx, id, tag); err != nil {
+		return fmt.Errorf("update tag in db: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTagByID removes the tag and detaches it from every todo.
+func (u *TodoUseCase) DeleteTagByID(ctx context.Context, id int) error {
+	if !u.publishing() {
+		return u.TodoRepo.DeleteTagByID(ctx, id)
+	}
+
+	return u.atomically(ctx, func(ctx context.Context) error {
+		// the todos that lose the tag are published as updated
+		tagged, err := u.TodoRepo.Query(ctx, domain.TodoQuery{Filter: domain.TodoFilter{Tags: []int{id}}})
+		if err != nil {
+			return fmt.Errorf("query tagged todos: %w", err)
+		}
+
+		if err := u.TodoRepo.DeleteTagByID(ctx, id); err != nil {
+			return err
+		}
+		for _, todo := range tagged {
+			if err := u.publishStored(ctx, todo.ID, domain.TodoUpdated); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AttachTag adds the tag to the todo. Attaching a tag twice is a no-op.
+// A non-zero version must match the stored one.
+func (u *TodoUseCase) AttachTag(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error) {
+	if _, err := u.TodoRepo.GetTagByID(ctx, tagID); err != nil {
+		return domain.Todo{}, fmt.Errorf("get tag by id: %w", err)
+	}
+
+	return u.changeTags(ctx, todoID, version, func(tags []int) []int {
+		return append(tags, tagID)
+	})
+}
+
+// DetachTag removes the tag from the todo. Detaching a tag the todo does
+// not have is a no-op. A non-zero version must match the stored one.
+func (u *TodoUseCase) DetachTag(ctx context.Context, todoID int, tagID int, version int) (domain.Todo, error) {
+	return u.changeTags(ctx, todoID, version, func(tags []int) []int {
+		return slices.DeleteFunc(tags, func(id int) bool { return id == tagID })
+	})
+}
+
+// changeTags applies change to a copy of the stored tags of the todo.
+func (u *TodoUseCase) changeTags(ctx context.Context, id int, version int, change func([]int) []int) (domain.Todo, error) {
+	current, err := u.getTodo(ctx, id, accessWrite)
+	if err != nil {
+		return domain.Todo{}, fmt.Errorf("get todo by id: %w", err)
+	}
+
+	if version != 0 && version != current.Version {
+		return domain.Todo{}, fmt.Errorf("change todo tags: %w", domain.ErrVersionMismatch)
+	}
+
+	tags := domain.NormalizeTagIDs(change(slices.Clone(current.Tags)))
+	if slices.Equal(tags, current.Tags) {
+		return current, nil
+	}
+
+	todo := current
+	todo.Tags = tags
+	u.stampUpdated(&todo, current)
+
+	err = u.atomically(ctx, func(ctx context.Context) error {
+		if err := u.TodoRepo.UpdateByID(ctx, id, todo); err != nil {
+			return fmt.Errorf("update todo in db: %w", err)
+		}
+		return u.publishStored(ctx, id, domain.TodoUpdated)
+	})
+	if err != nil {
+		return domain.Todo{}, err
+	}
+	todo.Version++
+
+	return todo, nil
+}
+
+// resolveTagNames turns the tag names of the filter into ids. It reports
+// false when no todo can match: a name is unknown in all mode, or none of
+// the names is known in any mode.
+func (u *TodoUseCase) resolveTagNames(ctx context.Context, f *domain.TodoFilter) (bool, error) {
+	if len(f.TagNames) == 0 {
+		return true, nil
+	}
+
+	tags, err := u.TodoRepo.ReadAllTags(ctx)
+	if err != nil {
+		return false, fmt.Errorf("read tags: %w", err)
+	}
+
+	ids := make([]int, 0, len(f.TagNames))
+	for _, name := range f.TagNames {
+		i := slices.IndexFunc(tags, func(t domain.Tag) bool {
+			return domain.SameTagName(t.Name, strings.TrimSpace(name))
+		})
+		if i >= 0 {
+			ids = append(ids, tags[i].ID)
+		} else if f.AllTags {
+			return false, nil
+		}
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	f.Tags = append(f.Tags, ids...)
+	f.TagNames = nil
+
+	return true, nil
+}
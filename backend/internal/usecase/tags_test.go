@@ -0,0 +1,272 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestCreateTag(t *testing.T) {
+	tests := []struct {
+		name      string
+		tag       domain.Tag
+		saveErr   error
+		wantErr   error
+		wantCalls int
+		wantName  string
+	}{
+		{name: "success trims the name", tag: domain.Tag{Name: "  work ", Color: "#00ff00"}, wantCalls: 1, wantName: "work"},
+		{name: "blank name -> validation error", tag: domain.Tag{Name: "   "}, wantErr: domain.ErrValidation},
+		{name: "bad color -> validation error", tag: domain.Tag{Name: "work", Color: "green"}, wantErr: domain.ErrInvalidFormat},
+		{name: "name taken -> error", tag: domain.Tag{Name: "work"}, saveErr: domain.ErrTagExists, wantErr: domain.ErrTagExists, wantCalls: 1, wantName: "work"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			mockRepo := &TodoRepositoryMock{
+				SaveTagFunc: func(ctx context.Context, tag domain.Tag) (int, error) {
+					return 1, tc.saveErr
+				},
+			}
+			usecase := New(mockRepo)
+
+			// act
+			_, err := usecase.CreateTag(context.Background(), tc.tag)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if mockRepo.SaveTagCalls != tc.wantCalls {
+				t.Fatalf("unexpected calls: got %d, want %d", mockRepo.SaveTagCalls, tc.wantCalls)
+			}
+			if tc.wantCalls > 0 && mockRepo.LastSavedTag.Name != tc.wantName {
+				t.Errorf("unexpected name: got %q, want %q", mockRepo.LastSavedTag.Name, tc.wantName)
+			}
+		})
+	}
+}
+
+func TestAttachDetachTag(t *testing.T) {
+	now := time.Date(2030, time.March, 10, 9, 0, 0, 0, time.UTC)
+	created := now.Add(-time.Hour)
+
+	newMockRepo := func() *TodoRepositoryMock {
+		return &TodoRepositoryMock{
+			GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+				return domain.Todo{ID: id, Title: "read the book", Version: 3, Tags: []int{2}, CreatedAt: created, UpdatedAt: created}, nil
+			},
+			GetTagByIDFunc: func(ctx context.Context, id int) (domain.Tag, error) {
+				if id > 5 {
+					return domain.Tag{}, domain.ErrTagNotExist
+				}
+				return domain.Tag{ID: id, Name: "work"}, nil
+			},
+			UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+				return nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		detach      bool
+		tagID       int
+		version     int
+		wantErr     error
+		wantTags    []int
+		wantVersion int
+		wantUpdates int
+	}{
+		{name: "attach", tagID: 1, wantTags: []int{1, 2}, wantVersion: 4, wantUpdates: 1},
+		{name: "attach with matching version", tagID: 1, version: 3, wantTags: []int{1, 2}, wantVersion: 4, wantUpdates: 1},
+		{name: "attach twice is a no-op", tagID: 2, wantTags: []int{2}, wantVersion: 3},
+		{name: "attach unknown tag -> error", tagID: 9, wantErr: domain.ErrTagNotExist},
+		{name: "attach with stale version -> error", tagID: 1, version: 2, wantErr: domain.ErrVersionMismatch},
+		{name: "detach", detach: true, tagID: 2, wantTags: []int{}, wantVersion: 4, wantUpdates: 1},
+		{name: "detach missing tag is a no-op", detach: true, tagID: 9, wantTags: []int{2}, wantVersion: 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			mockRepo := newMockRepo()
+			usecase := New(mockRepo)
+			usecase.Now = func() time.Time { return now }
+
+			// act
+			var (
+				got domain.Todo
+				err error
+			)
+			if tc.detach {
+				got, err = usecase.DetachTag(context.Background(), 1, tc.tagID, tc.version)
+			} else {
+				got, err = usecase.AttachTag(context.Background(), 1, tc.tagID, tc.version)
+			}
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if mockRepo.UpdateByIDCalls != tc.wantUpdates {
+				t.Fatalf("unexpected update calls: got %d, want %d", mockRepo.UpdateByIDCalls, tc.wantUpdates)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			if !slices.Equal(got.Tags, tc.wantTags) {
+				t.Errorf("unexpected tags: got %v, want %v", got.Tags, tc.wantTags)
+			}
+			if got.Version != tc.wantVersion {
+				t.Errorf("unexpected version: got %d, want %d", got.Version, tc.wantVersion)
+			}
+			if tc.wantUpdates > 0 {
+				if mockRepo.LastSavedTodo.Version != 3 {
+					t.Errorf("update must be conditional on the read version: got %d, want %d", mockRepo.LastSavedTodo.Version, 3)
+				}
+				if !got.UpdatedAt.Equal(now) || !got.CreatedAt.Equal(created) {
+					t.Errorf("unexpected timestamps: got created %v updated %v", got.CreatedAt, got.UpdatedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestTodoTags(t *testing.T) {
+	t.Run("unknown tag on create -> validation error", func(t *testing.T) {
+		// preparing
+		mockRepo := &TodoRepositoryMock{
+			SaveFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+				return 0, domain.ErrTagNotExist
+			},
+		}
+		usecase := New(mockRepo)
+
+		// act
+		_, err := usecase.CreateTodo(context.Background(), domain.Todo{Title: "read the book", Tags: []int{9}})
+
+		// assert
+		var verrs domain.ValidationErrors
+		if !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Field != "tags" {
+			t.Fatalf("unexpected error: got %v, want validation error of tags", err)
+		}
+	})
+
+	t.Run("update without tags keeps them", func(t *testing.T) {
+		// preparing
+		mockRepo := &TodoRepositoryMock{
+			GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+				return domain.Todo{ID: id, Title: "read the book", Version: 1, Tags: []int{1, 2}}, nil
+			},
+			UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+				return nil
+			},
+		}
+		usecase := New(mockRepo)
+
+		// act
+		err := usecase.UpdateTodoByID(context.Background(), 1, domain.Todo{Title: "complete the game"})
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if want := []int{1, 2}; !slices.Equal(mockRepo.LastSavedTodo.Tags, want) {
+			t.Errorf("unexpected tags: got %v, want %v", mockRepo.LastSavedTodo.Tags, want)
+		}
+	})
+
+	t.Run("update with empty tags clears them", func(t *testing.T) {
+		// preparing
+		mockRepo := &TodoRepositoryMock{
+			GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+				return domain.Todo{ID: id, Title: "read the book", Version: 1, Tags: []int{1, 2}}, nil
+			},
+			UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+				return nil
+			},
+		}
+		usecase := New(mockRepo)
+
+		// act
+		err := usecase.UpdateTodoByID(context.Background(), 1, domain.Todo{Title: "complete the game", Tags: []int{}})
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(mockRepo.LastSavedTodo.Tags) != 0 {
+			t.Errorf("unexpected tags: got %v, want none", mockRepo.LastSavedTodo.Tags)
+		}
+	})
+}
+
+func TestListTodosByTagNames(t *testing.T) {
+	stored := []domain.Todo{
+		{ID: 1, Title: "read the book", Tags: []int{1}},
+		{ID: 2, Title: "write the report", Tags: []int{2, 3}},
+		{ID: 3, Title: "complete the game"},
+		{ID: 4, Title: "fix the roof", Tags: []int{1, 3}},
+	}
+	tags := []domain.Tag{{ID: 1, Name: "Home"}, {ID: 2, Name: "work"}, {ID: 3, Name: "urgent"}}
+
+	tests := []struct {
+		name        string
+		names       []string
+		all         bool
+		want        []int
+		wantQueries int
+	}{
+		{name: "any ignores case", names: []string{"home", "WORK"}, want: []int{1, 2, 4}, wantQueries: 1},
+		{name: "all", names: []string{"home", "urgent"}, all: true, want: []int{4}, wantQueries: 1},
+		{name: "any skips unknown names", names: []string{"garden", "work"}, want: []int{2}, wantQueries: 1},
+		{name: "any with only unknown names -> empty", names: []string{"garden"}, want: []int{}},
+		{name: "all with an unknown name -> empty", names: []string{"home", "garden"}, all: true, want: []int{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			mockRepo := &TodoRepositoryMock{
+				ReadAllTagsFunc: func(ctx context.Context) ([]domain.Tag, error) {
+					return tags, nil
+				},
+				QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+					return q.Apply(slices.Values(stored)), nil
+				},
+			}
+			usecase := New(mockRepo)
+
+			q := domain.TodoQuery{Filter: domain.TodoFilter{TagNames: tc.names, AllTags: tc.all}}
+
+			// act
+			page, err := usecase.ListTodos(context.Background(), q, "")
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			if mockRepo.QueryCalls != tc.wantQueries {
+				t.Fatalf("unexpected query calls: got %d, want %d", mockRepo.QueryCalls, tc.wantQueries)
+			}
+
+			got := make([]int, 0, len(page.Todos))
+			for _, todo := range page.Todos {
+				got = append(got, todo.ID)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("unexpected ids: got %v, want %v", got, tc.want)
+			}
+			if page.Todos == nil {
+				t.Errorf("todos must not be nil")
+			}
+		})
+	}
+}
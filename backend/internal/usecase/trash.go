@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// DefaultTrashRetention is how long deleted todos stay in the trash.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// TrashRepository keeps deleted todos until they are restored or purged.
+// An entry of the trash is known by the id of the deleted todo.
+type TrashRepository interface {
+	// TrashByID moves the todo and its subtasks to the trash as one entry
+	// deleted at the given time. The version is checked as by DeleteByID.
+	TrashByID(ctx context.Context, id int, version int, at time.Time) error
+	// ReadTrash returns the entries, the most recently deleted first.
+	ReadTrash(ctx context.Context) ([]domain.TrashedTodo, error)
+	GetTrashedByID(ctx context.Context, id int) (domain.TrashedTodo, error)
+	// RestoreByID puts the todos of the entry back with the same ids, see
+	// domain.TrashedTodo.Restored, and returns them as restored.
+	RestoreByID(ctx context.Context, id int) (domain.TrashedTodo, error)
+	PurgeByID(ctx context.Context, id int) error
+	// PurgeTrash deletes the entries deleted before the time in every
+	// workspace and returns how many there were.
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+}
+
+// GetTrash returns the deleted todos the user sees, the most recently
+// deleted first.
+func (u *TodoUseCase) GetTrash(ctx context.Context) ([]domain.TrashedTodo, error) {
+	if u.Trash == nil {
+		return []domain.TrashedTodo{}, nil
+	}
+
+	visible, err := u.visibility(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := u.Trash.ReadTrash(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read trash: %w", err)
+	}
+
+	res := make([]domain.TrashedTodo, 0, len(entries))
+	for _, entry := range entries {
+		if visible == nil || visible.Match(entry.Todo) {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+// RestoreTodo puts a deleted todo back together with its subtasks. The
+// user must be allowed to change it.
+func (u *TodoUseCase) RestoreTodo(ctx context.Context, id int) (domain.Todo, error) {
+	if err := u.checkTrashed(ctx, id); err != nil {
+		return domain.Todo{}, err
+	}
+
+	var restored domain.TrashedTodo
+	err := u.atomically(ctx, func(ctx context.Context) error {
+		var err error
+		if restored, err = u.Trash.RestoreByID(ctx, id); err != nil {
+			return err
+		}
+		// for subscribers the todos are back as if created again
+		for _, todo := range restored.Todos() {
+			if err := u.publish(ctx, domain.TodoCreated, todo); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.Todo{}, fmt.Errorf("restore todo: %w", err)
+	}
+
+	return restored.Todo, nil
+}
+
+// PurgeTodo deletes a todo in the trash and its subtasks for good.
+func (u *TodoUseCase) PurgeTodo(ctx context.Context, id int) error {
+	if err := u.checkTrashed(ctx, id); err != nil {
+		return err
+	}
+
+	if err := u.Trash.PurgeByID(ctx, id); err != nil {
+		return fmt.Errorf("purge todo: %w", err)
+	}
+	return nil
+}
+
+// EmptyTrash purges the todos deleted longer than retention ago, in every
+// workspace, and returns how many entries were purged.
+func (u *TodoUseCase) EmptyTrash(ctx context.Context, retention time.Duration) (int, error) {
+	if u.Trash == nil {
+		return 0, nil
+	}
+
+	n, err := u.Trash.PurgeTrash(ctx, u.now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("purge trash: %w", err)
+	}
+	return n, nil
+}
+
+// checkTrashed makes sure the todo is in the trash and the user may change
+// it.
+func (u *TodoUseCase) checkTrashed(ctx context.Context, id int) error {
+	if u.Trash == nil {
+		return domain.ErrTodoNotExist
+	}
+
+	entry, err := u.Trash.GetTrashedByID(ctx, id)
+	if errors.Is(err, domain.ErrTodoNotExist) {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("get trashed todo: %w", err)
+	}
+
+	return u.checkAccess(ctx, entry.Todo, accessWrite)
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+type TrashRepositoryMock struct {
+	TrashByIDFunc      func(ctx context.Context, id int, version int, at time.Time) error
+	ReadTrashFunc      func(ctx context.Context) ([]domain.TrashedTodo, error)
+	GetTrashedByIDFunc func(ctx context.Context, id int) (domain.TrashedTodo, error)
+	RestoreByIDFunc    func(ctx context.Context, id int) (domain.TrashedTodo, error)
+	PurgeByIDFunc      func(ctx context.Context, id int) error
+	PurgeTrashFunc     func(ctx context.Context, before time.Time) (int, error)
+
+	TrashByIDCalls   int
+	ReadTrashCalls   int
+	RestoreByIDCalls int
+	PurgeByIDCalls   int
+	PurgeTrashCalls  int
+
+	LastID      int
+	LastVersion int
+	LastAt      time.Time
+	LastBefore  time.Time
+}
+
+func (t *TrashRepositoryMock) TrashByID(ctx context.Context, id int, version int, at time.Time) error {
+	t.TrashByIDCalls++
+	t.LastID = id
+	t.LastVersion = version
+	t.LastAt = at
+
+	if t.TrashByIDFunc == nil {
+		panic("TrashByIDFunc is nil")
+	}
+
+	return t.TrashByIDFunc(ctx, id, version, at)
+}
+
+func (t *TrashRepositoryMock) ReadTrash(ctx context.Context) ([]domain.TrashedTodo, error) {
+	t.ReadTrashCalls++
+
+	if t.ReadTrashFunc == nil {
+		panic("ReadTrashFunc is nil")
+	}
+
+	return t.ReadTrashFunc(ctx)
+}
+
+func (t *TrashRepositoryMock) GetTrashedByID(ctx context.Context, id int) (domain.TrashedTodo, error) {
+	t.LastID = id
+
+	if t.GetTrashedByIDFunc == nil {
+		panic("GetTrashedByIDFunc is nil")
+	}
+
+	return t.GetTrashedByIDFunc(ctx, id)
+}
+
+func (t *TrashRepositoryMock) RestoreByID(ctx context.Context, id int) (domain.TrashedTodo, error) {
+	t.RestoreByIDCalls++
+	t.LastID = id
+
+	if t.RestoreByIDFunc == nil {
+		panic("RestoreByIDFunc is nil")
+	}
+
+	return t.RestoreByIDFunc(ctx, id)
+}
+
+func (t *TrashRepositoryMock) PurgeByID(ctx context.Context, id int) error {
+	t.PurgeByIDCalls++
+	t.LastID = id
+
+	if t.PurgeByIDFunc == nil {
+		panic("PurgeByIDFunc is nil")
+	}
+
+	return t.PurgeByIDFunc(ctx, id)
+}
+
+func (t *TrashRepositoryMock) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	t.PurgeTrashCalls++
+	t.LastBefore = before
+
+	if t.PurgeTrashFunc == nil {
+		panic("PurgeTrashFunc is nil")
+	}
+
+	return t.PurgeTrashFunc(ctx, before)
+}
@@ -0,0 +1,331 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestDeleteTodoByIDTrash(t *testing.T) {
+	// preparing
+	now := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+	mockRepo := &TodoRepositoryMock{
+		GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+			return domain.Todo{ID: id, OwnerID: ann.ID, Version: 3}, nil
+		},
+	}
+	trash := &TrashRepositoryMock{
+		TrashByIDFunc: func(ctx context.Context, id int, version int, at time.Time) error {
+			return nil
+		},
+	}
+	usecase := New(mockRepo)
+	usecase.Trash = trash
+	usecase.Now = func() time.Time { return now }
+
+	ctx := domain.ContextWithUser(context.Background(), ann)
+
+	// act
+	err := usecase.DeleteTodoByID(ctx, 1, 3)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+
+	if trash.TrashByIDCalls != 1 || mockRepo.DeleteByIDCalls != 0 {
+		t.Errorf("must move to the trash: got %d trash and %d delete calls", trash.TrashByIDCalls, mockRepo.DeleteByIDCalls)
+	}
+	if trash.LastID != 1 || trash.LastVersion != 3 || !trash.LastAt.Equal(now) {
+		t.Errorf("unexpected args: got id %d, version %d, at %v", trash.LastID, trash.LastVersion, trash.LastAt)
+	}
+}
+
+func TestGetTrash(t *testing.T) {
+	listID := sharedList().ID
+	entries := []domain.TrashedTodo{
+		{Todo: domain.Todo{ID: 3, OwnerID: bob.ID}},
+		{Todo: domain.Todo{ID: 2, OwnerID: bob.ID, ListID: &listID}},
+		{Todo: domain.Todo{ID: 1, OwnerID: ann.ID}},
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		noTrash bool
+
+		want []int
+	}{
+		{
+			name: "owner and list member",
+			ctx:  domain.ContextWithUser(context.Background(), ann),
+			want: []int{2, 1},
+		},
+		{
+			name: "other user",
+			ctx:  domain.ContextWithUser(context.Background(), dan),
+			want: []int{},
+		},
+		{
+			name: "without authentication",
+			ctx:  context.Background(),
+			want: []int{3, 2, 1},
+		},
+		{
+			name:    "no trash",
+			ctx:     domain.ContextWithUser(context.Background(), ann),
+			noTrash: true,
+			want:    []int{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			usecase := New(&TodoRepositoryMock{})
+			usecase.Lists = newListRepo(sharedList())
+			if !tc.noTrash {
+				usecase.Trash = &TrashRepositoryMock{
+					ReadTrashFunc: func(ctx context.Context) ([]domain.TrashedTodo, error) {
+						return entries, nil
+					},
+				}
+			}
+
+			// act
+			got, err := usecase.GetTrash(tc.ctx)
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+
+			ids := []int{}
+			for _, entry := range got {
+				ids = append(ids, entry.ID)
+			}
+			if !slices.Equal(ids, tc.want) {
+				t.Errorf("unexpected trash: got %v, want %v", ids, tc.want)
+			}
+		})
+	}
+}
+
+func TestRestoreTodo(t *testing.T) {
+	listID := sharedList().ID
+	parentID := 1
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		entry   domain.Todo
+		noTrash bool
+		missing bool
+
+		wantErr error
+	}{
+		{
+			name:  "owner",
+			ctx:   domain.ContextWithUser(context.Background(), ann),
+			entry: domain.Todo{ID: 1, OwnerID: ann.ID},
+		},
+		{
+			name:  "editor of the list",
+			ctx:   domain.ContextWithUser(context.Background(), bob),
+			entry: domain.Todo{ID: 1, OwnerID: ann.ID, ListID: &listID},
+		},
+		{
+			name:    "viewer of the list -> ErrForbidden",
+			ctx:     domain.ContextWithUser(context.Background(), cat),
+			entry:   domain.Todo{ID: 1, OwnerID: ann.ID, ListID: &listID},
+			wantErr: domain.ErrForbidden,
+		},
+		{
+			name:    "todo of another user -> ErrTodoNotExist",
+			ctx:     domain.ContextWithUser(context.Background(), dan),
+			entry:   domain.Todo{ID: 1, OwnerID: ann.ID},
+			wantErr: domain.ErrTodoNotExist,
+		},
+		{
+			name:    "not in the trash -> ErrTodoNotExist",
+			ctx:     domain.ContextWithUser(context.Background(), ann),
+			missing: true,
+			wantErr: domain.ErrTodoNotExist,
+		},
+		{
+			name:    "no trash -> ErrTodoNotExist",
+			ctx:     domain.ContextWithUser(context.Background(), ann),
+			noTrash: true,
+			wantErr: domain.ErrTodoNotExist,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			entry := domain.TrashedTodo{
+				Todo:     tc.entry,
+				Subtasks: []domain.Todo{{ID: 2, OwnerID: tc.entry.OwnerID, ListID: tc.entry.ListID, ParentID: &parentID}},
+			}
+			trash := &TrashRepositoryMock{
+				GetTrashedByIDFunc: func(ctx context.Context, id int) (domain.TrashedTodo, error) {
+					if tc.missing {
+						return domain.TrashedTodo{}, domain.ErrTodoNotExist
+					}
+					return entry, nil
+				},
+				RestoreByIDFunc: func(ctx context.Context, id int) (domain.TrashedTodo, error) {
+					return entry, nil
+				},
+			}
+			hub := &EventHubMock{}
+
+			usecase := New(&TodoRepositoryMock{})
+			usecase.Lists = newListRepo(sharedList())
+			usecase.Events = hub
+			if !tc.noTrash {
+				usecase.Trash = trash
+			}
+
+			// act
+			got, err := usecase.RestoreTodo(tc.ctx, 1)
+
+			// assert
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+				}
+				if trash.RestoreByIDCalls != 0 || len(hub.Published) != 0 {
+					t.Errorf("must not restore: got %d calls, %d events", trash.RestoreByIDCalls, len(hub.Published))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+
+			if got.ID != 1 || trash.LastID != 1 {
+				t.Errorf("unexpected todo: got %+v", got)
+			}
+
+			// the todo and its subtask come back
+			if len(hub.Published) != 2 {
+				t.Fatalf("unexpected events: got %+v", hub.Published)
+			}
+			for i, id := range []int{1, 2} {
+				if event := hub.Published[i]; event.Type != domain.TodoCreated || event.Todo.ID != id {
+					t.Errorf("unexpected event %d: got %+v", i, event)
+				}
+			}
+		})
+	}
+}
+
+func TestPurgeTodo(t *testing.T) {
+	listID := sharedList().ID
+	entry := domain.TrashedTodo{Todo: domain.Todo{ID: 1, OwnerID: ann.ID, ListID: &listID}}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+
+		wantErr error
+	}{
+		{
+			name: "editor of the list",
+			ctx:  domain.ContextWithUser(context.Background(), bob),
+		},
+		{
+			name:    "viewer of the list -> ErrForbidden",
+			ctx:     domain.ContextWithUser(context.Background(), cat),
+			wantErr: domain.ErrForbidden,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			trash := &TrashRepositoryMock{
+				GetTrashedByIDFunc: func(ctx context.Context, id int) (domain.TrashedTodo, error) {
+					return entry, nil
+				},
+				PurgeByIDFunc: func(ctx context.Context, id int) error {
+					return nil
+				},
+			}
+			usecase := New(&TodoRepositoryMock{})
+			usecase.Lists = newListRepo(sharedList())
+			usecase.Trash = trash
+
+			// act
+			err := usecase.PurgeTodo(tc.ctx, 1)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+
+			wantCalls := 1
+			if tc.wantErr != nil {
+				wantCalls = 0
+			}
+			if trash.PurgeByIDCalls != wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", trash.PurgeByIDCalls, wantCalls)
+			}
+		})
+	}
+}
+
+func TestEmptyTrash(t *testing.T) {
+	t.Run("purges older than the retention", func(t *testing.T) {
+		// preparing
+		now := time.Date(2030, time.March, 31, 9, 0, 0, 0, time.UTC)
+		trash := &TrashRepositoryMock{
+			PurgeTrashFunc: func(ctx context.Context, before time.Time) (int, error) {
+				return 2, nil
+			},
+		}
+		usecase := New(&TodoRepositoryMock{})
+		usecase.Trash = trash
+		usecase.Now = func() time.Time { return now }
+
+		// act
+		n, err := usecase.EmptyTrash(context.Background(), 30*24*time.Hour)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if n != 2 {
+			t.Errorf("unexpected count: got %d, want %d", n, 2)
+		}
+
+		want := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+		if !trash.LastBefore.Equal(want) {
+			t.Errorf("unexpected cutoff: got %v, want %v", trash.LastBefore, want)
+		}
+	})
+
+	t.Run("DB failed -> error", func(t *testing.T) {
+		// preparing
+		returnedError := errors.New("some error in DB")
+		usecase := New(&TodoRepositoryMock{})
+		usecase.Trash = &TrashRepositoryMock{
+			PurgeTrashFunc: func(ctx context.Context, before time.Time) (int, error) {
+				return 0, returnedError
+			},
+		}
+
+		// act
+		_, err := usecase.EmptyTrash(context.Background(), time.Hour)
+
+		// assert
+		if !errors.Is(err, returnedError) {
+			t.Fatalf("unexpected error: got %v, want %v", err, returnedError)
+		}
+	})
+}
@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// CommandLog keeps the recent commands of every user to be undone and
+// redone.
+type CommandLog interface {
+	Record(owner domain.CommandOwner, cmd domain.Command)
+	// Undo hands the last command done by the user to apply and keeps the
+	// command apply returns, the one that reverted it, to be redone. It
+	// fails with domain.ErrNothingToUndo when there is none.
+	Undo(owner domain.CommandOwner, apply func(domain.Command) (domain.Command, error)) (domain.Command, error)
+	// Redo hands the last command undone by the user to apply and keeps
+	// the command apply returns to be undone again. It fails with
+	// domain.ErrNothingToRedo when there is none.
+	Redo(owner domain.CommandOwner, apply func(domain.Command) (domain.Command, error)) (domain.Command, error)
+}
+
+// Undo reverts the last change of the user and returns it as reverted.
+// It fails with domain.ErrUndoConflict when one of the todos was changed
+// since, by anyone, and the change can then no longer be undone.
+func (u *TodoUseCase) Undo(ctx context.Context) (domain.Command, error) {
+	if u.Commands == nil {
+		return domain.Command{}, domain.ErrNothingToUndo
+	}
+
+	cmd, err := u.Commands.Undo(commandOwner(ctx), func(cmd domain.Command) (domain.Command, error) {
+		return u.applyCommand(ctx, cmd.Inverse())
+	})
+	if err != nil {
+		return domain.Command{}, fmt.Errorf("undo: %w", err)
+	}
+	return cmd, nil
+}
+
+// Redo makes the last undone change again, see Undo.
+func (u *TodoUseCase) Redo(ctx context.Context) (domain.Command, error) {
+	if u.Commands == nil {
+		return domain.Command{}, domain.ErrNothingToRedo
+	}
+
+	cmd, err := u.Commands.Redo(commandOwner(ctx), func(cmd domain.Command) (domain.Command, error) {
+		return u.applyCommand(ctx, cmd.Inverse())
+	})
+	if err != nil {
+		return domain.Command{}, fmt.Errorf("redo: %w", err)
+	}
+	return cmd, nil
+}
+
+func commandOwner(ctx context.Context) domain.CommandOwner {
+	res := domain.CommandOwner{Tenant: domain.CurrentTenant(ctx)}
+	if userID, ok := owner(ctx); ok {
+		res.UserID = userID
+	}
+	return res
+}
+
+type commandRecorderKey struct{}
+
+// commandRecorder collects the steps of the command running in a context.
+type commandRecorder struct {
+	steps []domain.CommandStep
+}
+
+// command runs fn like atomically and records the todos it changed as a
+// command of the user.
+func (u *TodoUseCase) command(ctx context.Context, fn func(ctx context.Context) error) error {
+	return u.recording(ctx, func(ctx context.Context) error {
+		return u.atomically(ctx, fn)
+	})
+}
+
+// recording runs fn and records the todos it changed as a command of the
+// user. A command running within another one, as an operation of a batch,
+// becomes a part of it, unless it fails.
+func (u *TodoUseCase) recording(ctx context.Context, fn func(ctx context.Context) error) error {
+	if u.Commands == nil {
+		return fn(ctx)
+	}
+
+	if outer, ok := ctx.Value(commandRecorderKey{}).(*commandRecorder); ok {
+		n := len(outer.steps)
+		if err := fn(ctx); err != nil {
+			outer.steps = outer.steps[:n]
+			return err
+		}
+		return nil
+	}
+
+	rec := &commandRecorder{}
+	if err := fn(context.WithValue(ctx, commandRecorderKey{}, rec)); err != nil {
+		return err
+	}
+	if len(rec.steps) == 0 {
+		return nil
+	}
+	// without the trash neither a deleted todo can be brought back nor a
+	// created one deleted without losing it for good
+	if u.Trash == nil {
+		for _, step := range rec.steps {
+			if step.Before == nil || step.After == nil {
+				return nil
+			}
+		}
+	}
+
+	u.Commands.Record(commandOwner(ctx), domain.Command{Steps: rec.steps, At: u.now()})
+	return nil
+}
+
+// recordStep adds a change of a todo to the command running in ctx, if
+// any. before is nil for a created todo, after for a deleted one.
+func recordStep(ctx context.Context, before *domain.Todo, after *domain.Todo) {
+	rec, ok := ctx.Value(commandRecorderKey{}).(*commandRecorder)
+	if !ok {
+		return
+	}
+
+	step := domain.CommandStep{Before: before, After: after}
+	if before != nil {
+		step.ID = before.ID
+	} else if after != nil {
+		step.ID = after.ID
+	}
+	rec.steps = append(rec.steps, step)
+}
+
+// recordCreated adds a todo just saved under the id, see recordStep.
+func recordCreated(ctx context.Context, id int, todo domain.Todo) {
+	todo.ID = id
+	todo.Version = 1
+	todo.Tags = domain.NormalizeTagIDs(todo.Tags)
+	recordStep(ctx, nil, &todo)
+}
+
+// recordUpdated adds a todo just updated from current, see recordStep.
+func recordUpdated(ctx context.Context, current domain.Todo, todo domain.Todo) {
+	todo.ID = current.ID
+	todo.Version = current.Version + 1
+	todo.Tags = domain.NormalizeTagIDs(todo.Tags)
+	recordStep(ctx, &current, &todo)
+}
+
+// applyCommand brings every todo of the command from its before to its
+// after state, all of them or none, and returns the command as applied.
+// Each todo must still be exactly in its before state.
+func (u *TodoUseCase) applyCommand(ctx context.Context, cmd domain.Command) (domain.Command, error) {
+	applied := domain.Command{Steps: make([]domain.CommandStep, 0, len(cmd.Steps)), At: u.now()}
+
+	err := u.atomically(ctx, func(ctx context.Context) error {
+		for _, step := range cmd.Steps {
+			var (
+				res domain.CommandStep
+				err error
+			)
+			switch {
+			case step.Before != nil && step.After != nil:
+				res, err = u.applyUpdate(ctx, step)
+			case step.Before != nil:
+				res, err = u.applyDelete(ctx, step)
+			case step.After != nil:
+				res, err = u.applyRestore(ctx, step)
+			default:
+				continue
+			}
+			if err != nil {
+				return undoConflict(step.ID, err)
+			}
+			applied.Steps = append(applied.Steps, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.Command{}, err
+	}
+
+	return applied, nil
+}
+
+func (u *TodoUseCase) applyUpdate(ctx context.Context, step domain.CommandStep) (domain.CommandStep, error) {
+	current, err := u.getTodo(ctx, step.ID, accessWrite)
+	if err != nil {
+		return domain.CommandStep{}, err
+	}
+	if current.Version != step.Before.Version {
+		return domain.CommandStep{}, domain.ErrVersionMismatch
+	}
+
+	todo := *step.After
+	if err := u.checkPlacement(ctx, &todo, &current); err != nil {
+		return domain.CommandStep{}, err
+	}
+	todo.Version = current.Version
+	todo.UpdatedAt = u.now()
+
+	if err := u.TodoRepo.UpdateByID(ctx, step.ID, todo); err != nil {
+		return domain.CommandStep{}, err
+	}
+	if err := u.publishStored(ctx, step.ID, updatedEvents(todo, current)...); err != nil {
+		return domain.CommandStep{}, err
+	}
+
+	todo.ID = step.ID
+	todo.Version = current.Version + 1
+	todo.Tags = domain.NormalizeTagIDs(todo.Tags)
+	return domain.CommandStep{ID: step.ID, Before: &current, After: &todo}, nil
+}
+
+func (u *TodoUseCase) applyDelete(ctx context.Context, step domain.CommandStep) (domain.CommandStep, error) {
+	if u.Trash == nil {
+		return domain.CommandStep{}, domain.ErrTodoNotExist
+	}
+
+	current, err := u.getTodo(ctx, step.ID, accessWrite)
+	if err != nil {
+		return domain.CommandStep{}, err
+	}
+	if current.Version != step.Before.Version {
+		return domain.CommandStep{}, domain.ErrVersionMismatch
+	}
+
+	if err := u.Trash.TrashByID(ctx, step.ID, current.Version, u.now()); err != nil {
+		return domain.CommandStep{}, err
+	}
+	if err := u.publish(ctx, domain.TodoDeleted, current); err != nil {
+		return domain.CommandStep{}, err
+	}
+
+	return domain.CommandStep{ID: step.ID, Before: &current}, nil
+}
+
+func (u *TodoUseCase) applyRestore(ctx context.Context, step domain.CommandStep) (domain.CommandStep, error) {
+	if u.Trash == nil {
+		return domain.CommandStep{}, domain.ErrTodoNotExist
+	}
+
+	entry, err := u.Trash.GetTrashedByID(ctx, step.ID)
+	if err != nil {
+		return domain.CommandStep{}, err
+	}
+	if entry.Version != step.After.Version {
+		return domain.CommandStep{}, domain.ErrVersionMismatch
+	}
+	if err := u.checkAccess(ctx, entry.Todo, accessWrite); err != nil {
+		return domain.CommandStep{}, err
+	}
+
+	restored, err := u.Trash.RestoreByID(ctx, step.ID)
+	if err != nil {
+		return domain.CommandStep{}, err
+	}
+	for _, todo := range restored.Todos() {
+		if err := u.publish(ctx, domain.TodoCreated, todo); err != nil {
+			return domain.CommandStep{}, err
+		}
+	}
+
+	return domain.CommandStep{ID: step.ID, After: &restored.Todo}, nil
+}
+
+// undoConflict reports a todo that is no longer in the state a command
+// left it in, or whose old state no longer fits, e.g. refers to todos,
+// tags or lists gone since.
+func undoConflict(id int, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrVersionMismatch),
+		errors.Is(err, domain.ErrTodoNotExist),
+		errors.Is(err, domain.ErrValidation),
+		errors.Is(err, domain.ErrTagNotExist),
+		errors.Is(err, domain.ErrParentNotExist),
+		errors.Is(err, domain.ErrParentCycle):
+		return fmt.Errorf("%w: todo %d", domain.ErrUndoConflict, id)
+	}
+	return err
+}
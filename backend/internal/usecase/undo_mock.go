@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// CommandLogMock keeps the recorded commands.
+type CommandLogMock struct {
+	UndoFunc func(owner domain.CommandOwner, apply func(domain.Command) (domain.Command, error)) (domain.Command, error)
+	RedoFunc func(owner domain.CommandOwner, apply func(domain.Command) (domain.Command, error)) (domain.Command, error)
+
+	UndoCalls int
+	RedoCalls int
+
+	Recorded  []domain.Command
+	LastOwner domain.CommandOwner
+}
+
+func (l *CommandLogMock) Record(owner domain.CommandOwner, cmd domain.Command) {
+	l.LastOwner = owner
+	l.Recorded = append(l.Recorded, cmd)
+}
+
+func (l *CommandLogMock) Undo(owner domain.CommandOwner, apply func(domain.Command) (domain.Command, error)) (domain.Command, error) {
+	l.UndoCalls++
+	l.LastOwner = owner
+
+	if l.UndoFunc == nil {
+		panic("UndoFunc is nil")
+	}
+
+	return l.UndoFunc(owner, apply)
+}
+
+func (l *CommandLogMock) Redo(owner domain.CommandOwner, apply func(domain.Command) (domain.Command, error)) (domain.Command, error) {
+	l.RedoCalls++
+	l.LastOwner = owner
+
+	if l.RedoFunc == nil {
+		panic("RedoFunc is nil")
+	}
+
+	return l.RedoFunc(owner, apply)
+}
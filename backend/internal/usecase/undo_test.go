@@ -0,0 +1,401 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// newUndoRepos keeps the todos and the trash in maps and checks versions
+// like a repository does. The subtasks of a trashed todo are left alone.
+func newUndoRepos(todos ...domain.Todo) (*TodoRepositoryMock, *TrashRepositoryMock) {
+	stored := map[int]domain.Todo{}
+	for _, todo := range todos {
+		stored[todo.ID] = todo
+	}
+	trashed := map[int]domain.TrashedTodo{}
+	nextID := 100
+
+	repo := &TodoRepositoryMock{
+		SaveFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+			todo.ID = nextID
+			todo.Version = 1
+			stored[todo.ID] = todo
+			nextID++
+			return todo.ID, nil
+		},
+		GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+			todo, ok := stored[id]
+			if !ok {
+				return domain.Todo{}, domain.ErrTodoNotExist
+			}
+			return todo, nil
+		},
+		UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+			current, ok := stored[id]
+			if !ok {
+				return domain.ErrTodoNotExist
+			}
+			if todo.Version != 0 && todo.Version != current.Version {
+				return domain.ErrVersionMismatch
+			}
+			todo.ID = id
+			todo.Version = current.Version + 1
+			stored[id] = todo
+			return nil
+		},
+		QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+			return q.Apply(maps.Values(stored)), nil
+		},
+	}
+	trash := &TrashRepositoryMock{
+		TrashByIDFunc: func(ctx context.Context, id int, version int, at time.Time) error {
+			todo, ok := stored[id]
+			if !ok {
+				return domain.ErrTodoNotExist
+			}
+			if version != 0 && version != todo.Version {
+				return domain.ErrVersionMismatch
+			}
+			delete(stored, id)
+			trashed[id] = domain.TrashedTodo{Todo: todo, DeletedAt: at}
+			return nil
+		},
+		GetTrashedByIDFunc: func(ctx context.Context, id int) (domain.TrashedTodo, error) {
+			entry, ok := trashed[id]
+			if !ok {
+				return domain.TrashedTodo{}, domain.ErrTodoNotExist
+			}
+			return entry, nil
+		},
+		RestoreByIDFunc: func(ctx context.Context, id int) (domain.TrashedTodo, error) {
+			entry, ok := trashed[id]
+			if !ok {
+				return domain.TrashedTodo{}, domain.ErrTodoNotExist
+			}
+			delete(trashed, id)
+			stored[id] = entry.Todo
+			return entry, nil
+		},
+	}
+
+	return repo, trash
+}
+
+// describeSteps sums up the steps of a command, e.g. "update 1 v1->v2".
+func describeSteps(cmd domain.Command) []string {
+	res := []string{}
+	for _, step := range cmd.Steps {
+		switch {
+		case step.Before == nil:
+			res = append(res, fmt.Sprintf("create %d v%d", step.ID, step.After.Version))
+		case step.After == nil:
+			res = append(res, fmt.Sprintf("delete %d v%d", step.ID, step.Before.Version))
+		default:
+			res = append(res, fmt.Sprintf("update %d v%d->v%d", step.ID, step.Before.Version, step.After.Version))
+		}
+	}
+	return res
+}
+
+func TestCommandRecording(t *testing.T) {
+	parentID := 1
+
+	tests := []struct {
+		name    string
+		act     func(ctx context.Context, usecase *TodoUseCase) error
+		noTrash bool
+
+		want []string
+	}{
+		{
+			name: "create",
+			act: func(ctx context.Context, usecase *TodoUseCase) error {
+				_, err := usecase.CreateTodo(ctx, domain.Todo{Title: "new"})
+				return err
+			},
+			want: []string{"create 100 v1"},
+		},
+		{
+			name: "update",
+			act: func(ctx context.Context, usecase *TodoUseCase) error {
+				return usecase.UpdateTodoByID(ctx, 1, domain.Todo{Title: "renamed"})
+			},
+			want: []string{"update 1 v1->v2"},
+		},
+		{
+			name: "patch",
+			act: func(ctx context.Context, usecase *TodoUseCase) error {
+				_, err := usecase.PatchTodoByID(ctx, 1, 1, []byte(`{"title":"renamed"}`))
+				return err
+			},
+			want: []string{"update 1 v1->v2"},
+		},
+		{
+			name: "subtask completing its parent",
+			act: func(ctx context.Context, usecase *TodoUseCase) error {
+				return usecase.UpdateTodoByID(ctx, 2, domain.Todo{Title: "sub", Completed: true})
+			},
+			want: []string{"update 2 v1->v2", "update 1 v1->v2"},
+		},
+		{
+			name: "delete",
+			act: func(ctx context.Context, usecase *TodoUseCase) error {
+				return usecase.DeleteTodoByID(ctx, 2, 0)
+			},
+			want: []string{"delete 2 v1"},
+		},
+		{
+			name: "update without trash",
+			act: func(ctx context.Context, usecase *TodoUseCase) error {
+				return usecase.UpdateTodoByID(ctx, 1, domain.Todo{Title: "renamed"})
+			},
+			noTrash: true,
+			want:    []string{"update 1 v1->v2"},
+		},
+		{
+			name: "create without trash",
+			act: func(ctx context.Context, usecase *TodoUseCase) error {
+				_, err := usecase.CreateTodo(ctx, domain.Todo{Title: "new"})
+				return err
+			},
+			noTrash: true,
+		},
+		{
+			name: "failed change",
+			act: func(ctx context.Context, usecase *TodoUseCase) error {
+				_, err := usecase.PatchTodoByID(ctx, 1, 7, []byte(`{"title":"renamed"}`))
+				if !errors.Is(err, domain.ErrVersionMismatch) {
+					return fmt.Errorf("got %v, want version mismatch", err)
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			repo, trash := newUndoRepos(
+				domain.Todo{ID: 1, Title: "parent", OwnerID: ann.ID, Version: 1},
+				domain.Todo{ID: 2, Title: "sub", OwnerID: ann.ID, ParentID: &parentID, Version: 1},
+			)
+			commands := &CommandLogMock{}
+			usecase := New(repo)
+			usecase.Rules.AutoCompleteParent = true
+			usecase.Commands = commands
+			if !tc.noTrash {
+				usecase.Trash = trash
+			}
+
+			ctx := domain.ContextWithUser(context.Background(), ann)
+
+			// act
+			err := tc.act(ctx, usecase)
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+
+			if tc.want == nil {
+				if len(commands.Recorded) != 0 {
+					t.Fatalf("must not record: got %v", describeSteps(commands.Recorded[0]))
+				}
+				return
+			}
+			if len(commands.Recorded) != 1 {
+				t.Fatalf("unexpected commands: got %d, want 1", len(commands.Recorded))
+			}
+			if got := describeSteps(commands.Recorded[0]); !slices.Equal(got, tc.want) {
+				t.Errorf("unexpected steps: got %v, want %v", got, tc.want)
+			}
+			want := domain.CommandOwner{Tenant: domain.DefaultTenant, UserID: ann.ID}
+			if commands.LastOwner != want {
+				t.Errorf("unexpected owner: got %+v, want %+v", commands.LastOwner, want)
+			}
+		})
+	}
+}
+
+func TestUndo(t *testing.T) {
+	listID := sharedList().ID
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		stored  []domain.Todo
+		trashed []domain.Todo
+		cmd     domain.Command
+		noLog   bool
+
+		want      []string
+		wantTitle string
+		wantErr   error
+	}{
+		{
+			name:   "update",
+			ctx:    domain.ContextWithUser(context.Background(), ann),
+			stored: []domain.Todo{{ID: 1, Title: "new", OwnerID: ann.ID, Version: 2}},
+			cmd: domain.Command{Steps: []domain.CommandStep{{
+				ID:     1,
+				Before: &domain.Todo{ID: 1, Title: "old", OwnerID: ann.ID, Version: 1},
+				After:  &domain.Todo{ID: 1, Title: "new", OwnerID: ann.ID, Version: 2},
+			}}},
+			want:      []string{"update 1 v2->v3"},
+			wantTitle: "old",
+		},
+		{
+			name:   "changed since",
+			ctx:    domain.ContextWithUser(context.Background(), ann),
+			stored: []domain.Todo{{ID: 1, Title: "newer", OwnerID: ann.ID, Version: 3}},
+			cmd: domain.Command{Steps: []domain.CommandStep{{
+				ID:     1,
+				Before: &domain.Todo{ID: 1, Title: "old", OwnerID: ann.ID, Version: 1},
+				After:  &domain.Todo{ID: 1, Title: "new", OwnerID: ann.ID, Version: 2},
+			}}},
+			wantTitle: "newer",
+			wantErr:   domain.ErrUndoConflict,
+		},
+		{
+			name:   "create",
+			ctx:    domain.ContextWithUser(context.Background(), ann),
+			stored: []domain.Todo{{ID: 1, Title: "new", OwnerID: ann.ID, Version: 1}},
+			cmd: domain.Command{Steps: []domain.CommandStep{{
+				ID:    1,
+				After: &domain.Todo{ID: 1, Title: "new", OwnerID: ann.ID, Version: 1},
+			}}},
+			want: []string{"delete 1 v1"},
+		},
+		{
+			name:    "delete",
+			ctx:     domain.ContextWithUser(context.Background(), ann),
+			trashed: []domain.Todo{{ID: 1, Title: "gone", OwnerID: ann.ID, Version: 2}},
+			cmd: domain.Command{Steps: []domain.CommandStep{{
+				ID:     1,
+				Before: &domain.Todo{ID: 1, Title: "gone", OwnerID: ann.ID, Version: 2},
+			}}},
+			want:      []string{"create 1 v2"},
+			wantTitle: "gone",
+		},
+		{
+			name: "purged since",
+			ctx:  domain.ContextWithUser(context.Background(), ann),
+			cmd: domain.Command{Steps: []domain.CommandStep{{
+				ID:     1,
+				Before: &domain.Todo{ID: 1, Title: "gone", OwnerID: ann.ID, Version: 2},
+			}}},
+			wantErr: domain.ErrUndoConflict,
+		},
+		{
+			name:   "viewer of the list",
+			ctx:    domain.ContextWithUser(context.Background(), cat),
+			stored: []domain.Todo{{ID: 1, Title: "new", OwnerID: ann.ID, ListID: &listID, Version: 2}},
+			cmd: domain.Command{Steps: []domain.CommandStep{{
+				ID:     1,
+				Before: &domain.Todo{ID: 1, Title: "old", OwnerID: ann.ID, ListID: &listID, Version: 1},
+				After:  &domain.Todo{ID: 1, Title: "new", OwnerID: ann.ID, ListID: &listID, Version: 2},
+			}}},
+			wantTitle: "new",
+			wantErr:   domain.ErrForbidden,
+		},
+		{
+			name:    "no log",
+			ctx:     domain.ContextWithUser(context.Background(), ann),
+			noLog:   true,
+			wantErr: domain.ErrNothingToUndo,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			repo, trash := newUndoRepos(slices.Concat(tc.stored, tc.trashed)...)
+			for _, todo := range tc.trashed {
+				if err := trash.TrashByID(context.Background(), todo.ID, 0, time.Now()); err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+			}
+			usecase := New(repo)
+			usecase.Lists = newListRepo(sharedList())
+			usecase.Trash = trash
+			commands := &CommandLogMock{
+				UndoFunc: func(owner domain.CommandOwner, apply func(domain.Command) (domain.Command, error)) (domain.Command, error) {
+					return apply(tc.cmd)
+				},
+			}
+			if !tc.noLog {
+				usecase.Commands = commands
+			}
+
+			// act
+			got, err := usecase.Undo(tc.ctx)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if err == nil {
+				if steps := describeSteps(got); !slices.Equal(steps, tc.want) {
+					t.Errorf("unexpected steps: got %v, want %v", steps, tc.want)
+				}
+			}
+
+			if tc.wantTitle != "" {
+				todo, err := repo.GetByID(context.Background(), 1)
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+				if todo.Title != tc.wantTitle {
+					t.Errorf("unexpected title: got %q, want %q", todo.Title, tc.wantTitle)
+				}
+			}
+		})
+	}
+}
+
+func TestRedo(t *testing.T) {
+	// preparing
+	repo, trash := newUndoRepos(domain.Todo{ID: 1, Title: "old", OwnerID: ann.ID, Version: 3})
+	undone := domain.Command{Steps: []domain.CommandStep{{
+		ID:     1,
+		Before: &domain.Todo{ID: 1, Title: "new", OwnerID: ann.ID, Version: 2},
+		After:  &domain.Todo{ID: 1, Title: "old", OwnerID: ann.ID, Version: 3},
+	}}}
+	commands := &CommandLogMock{
+		RedoFunc: func(owner domain.CommandOwner, apply func(domain.Command) (domain.Command, error)) (domain.Command, error) {
+			return apply(undone)
+		},
+	}
+	usecase := New(repo)
+	usecase.Trash = trash
+	usecase.Commands = commands
+
+	ctx := domain.ContextWithUser(context.Background(), ann)
+
+	// act
+	got, err := usecase.Redo(ctx)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+
+	want := []string{"update 1 v3->v4"}
+	if steps := describeSteps(got); !slices.Equal(steps, want) {
+		t.Errorf("unexpected steps: got %v, want %v", steps, want)
+	}
+	todo, _ := repo.GetByID(ctx, 1)
+	if todo.Title != "new" {
+		t.Errorf("unexpected title: got %q, want %q", todo.Title, "new")
+	}
+	if commands.RedoCalls != 1 || commands.LastOwner.UserID != ann.ID {
+		t.Errorf("unexpected redo: got %d calls by user %d", commands.RedoCalls, commands.LastOwner.UserID)
+	}
+}
@@ -1,28 +1,130 @@
 package usecase
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"context"
 
 	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+	"github.com/VLGKiwi/todo-site/backend/internal/mergepatch"
 )
 
+// TodoRepository stores todos and the tags attached to them. Save and
+// UpdateByID store the tag ids of a todo sorted and deduplicated, and fail
+// with domain.ErrTagNotExist when one of them is unknown. They also fail
+// with domain.ErrParentNotExist for an unknown parent, and UpdateByID with
+// domain.ErrParentCycle when the parent is the todo or one of its
+// subtasks. DeleteByID removes the subtasks at any depth as well.
 type TodoRepository interface {
 	Save(ctx context.Context, todo domain.Todo) (int, error)
 	GetByID(ctx context.Context, id int) (domain.Todo, error)
 	UpdateByID(ctx context.Context, id int, todo domain.Todo) error
-	DeleteByID(ctx context.Context, id int) error
+	DeleteByID(ctx context.Context, id int, version int) error
 	ReadAll(ctx context.Context) ([]domain.Todo, error)
+	Query(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error)
+
+	// SaveTag and UpdateTagByID fail with domain.ErrTagExists when another
+	// tag has the same name.
+	SaveTag(ctx context.Context, tag domain.Tag) (int, error)
+	GetTagByID(ctx context.Context, id int) (domain.Tag, error)
+	UpdateTagByID(ctx context.Context, id int, tag domain.Tag) error
+	// DeleteTagByID detaches the tag from every todo, bumping their versions.
+	DeleteTagByID(ctx context.Context, id int) error
+	ReadAllTags(ctx context.Context) ([]domain.Tag, error)
 }
 
+// MaxPageSize caps the limit a client may ask for.
+const MaxPageSize = 1000
+
 type TodoUseCase struct {
 	TodoRepo TodoRepository
+	// Lists shares todos between users. Without it every todo is seen by
+	// its owner only.
+	Lists ListRepository
+	// Now is the clock used for timestamps and due date windows,
+	// time.Now when nil.
+	Now func() time.Time
+	// Rules between todos and their subtasks, none by default.
+	Rules domain.SubtaskRules
+	// Events gets every stored change of a todo. Without it nothing is
+	// published.
+	Events EventHub
+	// Tx makes every change a single transaction. Without it a change of
+	// several todos may be stored in part.
+	Tx Transactor
+	// Outbox stores the events together with the changes and delivers
+	// them to Events and the webhooks, even after a crash. Without it they
+	// are handed over right after the change, at most once.
+	Outbox EventOutbox
+	// Webhooks and Deliveries send the changes to the webhooks of the
+	// users. Without both no webhook can be created.
+	Webhooks   WebhookRepository
+	Deliveries WebhookDispatcher
+	// Trash keeps deleted todos until they are restored or purged. Without
+	// it deleted todos are gone for good.
+	Trash TrashRepository
+	// History keeps every change of the todos. Without it neither the
+	// history nor the past states can be read.
+	History TodoHistory
+	// Commands keeps the recent changes of every user to be undone.
+	// Without it nothing can be undone.
+	Commands CommandLog
 }
 
 func New(repo TodoRepository) *TodoUseCase {
 	return &TodoUseCase{
 		TodoRepo: repo,
+		Now:      time.Now,
+	}
+}
+
+// now is truncated to what every repository can store.
+func (u *TodoUseCase) now() time.Time {
+	now := time.Now
+	if u.Now != nil {
+		now = u.Now
+	}
+	return now().UTC().Truncate(time.Microsecond)
+}
+
+// stampCreated sets the timestamps of a new todo.
+func (u *TodoUseCase) stampCreated(todo *domain.Todo) {
+	now := u.now()
+
+	todo.CreatedAt = now
+	todo.UpdatedAt = now
+	todo.CompletedAt = nil
+	if todo.Completed {
+		todo.CompletedAt = &now
+	}
+	truncateDue(todo)
+}
+
+// stampUpdated carries the timestamps of the stored todo over to its new
+// state. CompletedAt is kept while the todo stays completed.
+func (u *TodoUseCase) stampUpdated(todo *domain.Todo, current domain.Todo) {
+	now := u.now()
+
+	todo.CreatedAt = current.CreatedAt
+	todo.UpdatedAt = now
+	switch {
+	case !todo.Completed:
+		todo.CompletedAt = nil
+	case current.Completed && current.CompletedAt != nil:
+		todo.CompletedAt = current.CompletedAt
+	default:
+		todo.CompletedAt = &now
+	}
+	truncateDue(todo)
+}
+
+func truncateDue(todo *domain.Todo) {
+	if todo.DueAt != nil {
+		due := todo.DueAt.Truncate(time.Microsecond)
+		todo.DueAt = &due
 	}
 }
 
@@ -33,42 +135,279 @@ func (u *TodoUseCase) CreateTodo(ctx context.Context, todo domain.Todo) (int, er
 		return 0, fmt.Errorf("validate todo: %w", err)
 	}
 
-	// save todo in db
-	id, err := u.TodoRepo.Save(ctx, todo)
+	if err := u.checkPlacement(ctx, &todo, nil); err != nil {
+		return 0, err
+	}
+
+	todo.OwnerID = 0
+	if ownerID, ok := owner(ctx); ok {
+		todo.OwnerID = ownerID
+	}
+	normalizeRecurrence(&todo)
+	u.stampCreated(&todo)
+
+	var id int
+	err := u.command(ctx, func(ctx context.Context) error {
+		// save todo in db
+		var err error
+		if id, err = u.TodoRepo.Save(ctx, todo); err != nil {
+			return fmt.Errorf("save todo in db: %w", todoRefError(err))
+		}
+		recordCreated(ctx, id, todo)
+		if err := u.publishStored(ctx, id, domain.TodoCreated); err != nil {
+			return err
+		}
+
+		if todo.Completed && todo.ParentID != nil {
+			return u.completeParents(ctx, *todo.ParentID)
+		}
+		return nil
+	})
 	if err != nil {
-		return 0, fmt.Errorf("save todo in db: %w", err)
+		return 0, err
 	}
 
 	return id, nil
 }
 
 func (u *TodoUseCase) GetAllTodos(ctx context.Context) ([]domain.Todo, error) {
+	visible, err := u.visibility(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if visible != nil {
+		return u.TodoRepo.Query(ctx, domain.TodoQuery{Filter: domain.TodoFilter{Visible: visible}})
+	}
+
 	// get all todos
 	return u.TodoRepo.ReadAll(ctx)
 }
 
+// ListTodos returns one page of todos matching the query. An empty cursor
+// starts from the beginning; a zero limit returns everything.
+func (u *TodoUseCase) ListTodos(ctx context.Context, q domain.TodoQuery, cursor string) (domain.TodoPage, error) {
+	if q.Sort != "" && !q.Sort.Valid() {
+		return domain.TodoPage{}, fmt.Errorf("%w: unknown sort field %q", domain.ErrInvalidQuery, q.Sort)
+	}
+	if q.Limit < 0 || q.Limit > MaxPageSize {
+		return domain.TodoPage{}, fmt.Errorf("%w: limit must be between 1 and %d", domain.ErrInvalidQuery, MaxPageSize)
+	}
+
+	visible, err := u.visibility(ctx)
+	if err != nil {
+		return domain.TodoPage{}, err
+	}
+	q.Filter.Visible = visible
+
+	if cursor != "" {
+		pivot, err := decodeCursor(q, cursor)
+		if err != nil {
+			return domain.TodoPage{}, err
+		}
+		q.After = &pivot
+	}
+
+	if ok, err := u.resolveTagNames(ctx, &q.Filter); err != nil {
+		return domain.TodoPage{}, err
+	} else if !ok {
+		return domain.TodoPage{Todos: []domain.Todo{}}, nil
+	}
+
+	limit := q.Limit
+	if limit > 0 {
+		// one extra todo tells whether there is a next page
+		q.Limit++
+	}
+
+	todos, err := u.queryTodos(ctx, q)
+	if err != nil {
+		return domain.TodoPage{}, fmt.Errorf("query todos: %w", err)
+	}
+
+	page := domain.TodoPage{Todos: todos}
+	if limit > 0 && len(todos) > limit {
+		page.Todos = todos[:limit]
+		page.NextCursor = encodeCursor(q, page.Todos[limit-1])
+	}
+
+	return page, nil
+}
+
 func (u *TodoUseCase) GetTodoByID(ctx context.Context, id int) (domain.Todo, error) {
-	todo, err := u.TodoRepo.GetByID(ctx, id)
+	todo, err := u.getTodo(ctx, id, accessRead)
 	if err != nil {
 		return domain.Todo{}, fmt.Errorf("get todo by id: %w", err)
 	}
 	return todo, nil
 }
 
+// UpdateTodoByID replaces the todo. Tags, the parent, the list, the
+// checklist and the recurrence left out (nil) are kept; an empty list detaches all the tags
+// or clears the checklist. Completing a recurring todo creates its next
+// occurrence.
 func (u *TodoUseCase) UpdateTodoByID(ctx context.Context, id int, todo domain.Todo) error {
 	// validate todo
 	if err := todo.Validate(); err != nil {
 		return fmt.Errorf("validate todo: %w", err)
 	}
 
-	// update todo in db
-	if err := u.TodoRepo.UpdateByID(ctx, id, todo); err != nil {
-		return fmt.Errorf("update todo in db: %w", err)
+	current, err := u.getTodo(ctx, id, accessWrite)
+	if err != nil {
+		return fmt.Errorf("get todo by id: %w", err)
+	}
+	todo.OwnerID = current.OwnerID
+	if todo.ListID == nil {
+		todo.ListID = current.ListID
+	}
+	if todo.Tags == nil {
+		todo.Tags = current.Tags
+	}
+	if todo.ParentID == nil {
+		todo.ParentID = current.ParentID
 	}
+	if todo.Checklist == nil {
+		todo.Checklist = current.Checklist
+	}
+	if todo.Recurrence == nil {
+		todo.Recurrence = current.Recurrence
+	}
+	// the kept recurrence needs a due date too
+	if err := todo.Validate(); err != nil {
+		return fmt.Errorf("validate todo: %w", err)
+	}
+	normalizeRecurrence(&todo)
+	if err := u.checkPlacement(ctx, &todo, &current); err != nil {
+		return err
+	}
+	if err := u.checkCompletion(ctx, todo, current); err != nil {
+		return err
+	}
+	u.stampUpdated(&todo, current)
+
+	return u.command(ctx, func(ctx context.Context) error {
+		// update todo in db
+		if err := u.store(ctx, id, &todo, current); err != nil {
+			return fmt.Errorf("update todo in db: %w", todoRefError(err))
+		}
 
-	return nil
+		return u.completed(ctx, todo, current)
+	})
 }
 
-func (u *TodoUseCase) DeleteTodoByID(ctx context.Context, id int) error {
-	return u.TodoRepo.DeleteByID(ctx, id)
+// PatchTodoByID applies a JSON Merge Patch (RFC 7396) to the stored todo.
+// Validation runs against the merged result, not the patch itself.
+// A non-zero version must match the stored one. Completing a recurring
+// todo creates its next occurrence.
+func (u *TodoUseCase) PatchTodoByID(ctx context.Context, id int, version int, patch []byte) (domain.Todo, error) {
+	current, err := u.getTodo(ctx, id, accessWrite)
+	if err != nil {
+		return domain.Todo{}, fmt.Errorf("get todo by id: %w", err)
+	}
+
+	if version != 0 && version != current.Version {
+		return domain.Todo{}, fmt.Errorf("patch todo: %w", domain.ErrVersionMismatch)
+	}
+
+	todo, err := applyPatch(current, patch)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	// the todo must not change between the read above and the write below
+	todo.Version = current.Version
+
+	// validate merged todo
+	if err := todo.Validate(); err != nil {
+		return domain.Todo{}, fmt.Errorf("validate todo: %w", err)
+	}
+
+	if err := u.checkPlacement(ctx, &todo, &current); err != nil {
+		return domain.Todo{}, err
+	}
+	if err := u.checkCompletion(ctx, todo, current); err != nil {
+		return domain.Todo{}, err
+	}
+	normalizeRecurrence(&todo)
+	u.stampUpdated(&todo, current)
+
+	err = u.command(ctx, func(ctx context.Context) error {
+		// update todo in db
+		if err := u.store(ctx, id, &todo, current); err != nil {
+			return fmt.Errorf("update todo in db: %w", todoRefError(err))
+		}
+
+		return u.completed(ctx, todo, current)
+	})
+	if err != nil {
+		return domain.Todo{}, err
+	}
+	todo.Version++
+	todo.Tags = domain.NormalizeTagIDs(todo.Tags)
+
+	return todo, nil
+}
+
+func applyPatch(todo domain.Todo, patch []byte) (domain.Todo, error) {
+	doc, err := json.Marshal(todo)
+	if err != nil {
+		return domain.Todo{}, fmt.Errorf("encode todo: %w", err)
+	}
+
+	merged, err := mergepatch.Apply(doc, patch)
+	if err != nil {
+		return domain.Todo{}, fmt.Errorf("%w: %v", domain.ErrInvalidPatch, err)
+	}
+
+	var res domain.Todo
+	if err := json.Unmarshal(merged, &res); err != nil {
+		return domain.Todo{}, fmt.Errorf("%w: %v", domain.ErrInvalidPatch, err)
+	}
+
+	// the id, the version and the owner are managed by the server
+	res.ID = todo.ID
+	res.Version = todo.Version
+	res.OwnerID = todo.OwnerID
+
+	return res, nil
+}
+
+// DeleteTodoByID moves the todo and its subtasks to the trash, or removes
+// them when there is none. A non-zero version must match the stored one.
+func (u *TodoUseCase) DeleteTodoByID(ctx context.Context, id int, version int) error {
+	var todo domain.Todo
+	if _, ok := owner(ctx); ok || u.publishing() || u.Commands != nil {
+		var err error
+		if todo, err = u.getTodo(ctx, id, accessWrite); err != nil {
+			return err
+		}
+	}
+
+	return u.command(ctx, func(ctx context.Context) error {
+		var err error
+		if u.Trash != nil {
+			err = u.Trash.TrashByID(ctx, id, version, u.now())
+		} else {
+			err = u.TodoRepo.DeleteByID(ctx, id, version)
+		}
+		if err != nil {
+			return err
+		}
+		recordStep(ctx, &todo, nil)
+		// subscribers drop the subtasks along with the todo
+		return u.publish(ctx, domain.TodoDeleted, todo)
+	})
+}
+
+// todoRefError reports unknown tags and parents of a todo as validation
+// errors, the client picked them.
+func todoRefError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrTagNotExist):
+		return domain.ValidationErrors{{Field: "tags", Err: domain.ErrUnknownValue}}
+	case errors.Is(err, domain.ErrParentNotExist):
+		return domain.ValidationErrors{{Field: "parent_id", Err: domain.ErrUnknownValue}}
+	case errors.Is(err, domain.ErrParentCycle):
+		return domain.ValidationErrors{{Field: "parent_id", Err: domain.ErrParentCycle}}
+	}
+	return err
 }
@@ -10,17 +10,35 @@ type TodoRepositoryMock struct {
 	SaveFunc       func(ctx context.Context, todo domain.Todo) (int, error)
 	GetByIDFunc    func(ctx context.Context, id int) (domain.Todo, error)
 	UpdateByIDFunc func(ctx context.Context, id int, todo domain.Todo) error
-	DeleteByIDFunc func(ctx context.Context, id int) error
+	DeleteByIDFunc func(ctx context.Context, id int, version int) error
 	ReadAllFunc    func(ctx context.Context) ([]domain.Todo, error)
+	QueryFunc      func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error)
+
+	SaveTagFunc       func(ctx context.Context, tag domain.Tag) (int, error)
+	GetTagByIDFunc    func(ctx context.Context, id int) (domain.Tag, error)
+	UpdateTagByIDFunc func(ctx context.Context, id int, tag domain.Tag) error
+	DeleteTagByIDFunc func(ctx context.Context, id int) error
+	ReadAllTagsFunc   func(ctx context.Context) ([]domain.Tag, error)
 
 	SaveCalls       int
 	GetByIDCalls    int
 	UpdateByIDCalls int
 	DeleteByIDCalls int
 	ReadAllCalls    int
+	QueryCalls      int
+
+	SaveTagCalls       int
+	GetTagByIDCalls    int
+	UpdateTagByIDCalls int
+	DeleteTagByIDCalls int
+	ReadAllTagsCalls   int
 
 	LastSavedTodo domain.Todo
 	LastGetID     int
+	LastVersion   int
+	LastQuery     domain.TodoQuery
+	LastSavedTag  domain.Tag
+	LastTagID     int
 }
 
 func (t *TodoRepositoryMock) Save(ctx context.Context, todo domain.Todo) (int, error) {
@@ -57,15 +75,16 @@ func (t *TodoRepositoryMock) UpdateByID(ctx context.Context, id int, todo domain
 	return t.UpdateByIDFunc(ctx, id, todo)
 }
 
-func (t *TodoRepositoryMock) DeleteByID(ctx context.Context, id int) error {
+func (t *TodoRepositoryMock) DeleteByID(ctx context.Context, id int, version int) error {
 	t.DeleteByIDCalls++
 	t.LastGetID = id
+	t.LastVersion = version
 
 	if t.DeleteByIDFunc == nil {
 		panic("DeleteByIDFunc is nil")
 	}
 
-	return t.DeleteByIDFunc(ctx, id)
+	return t.DeleteByIDFunc(ctx, id, version)
 }
 
 func (t *TodoRepositoryMock) ReadAll(ctx context.Context) ([]domain.Todo, error) {
@@ -77,3 +96,69 @@ func (t *TodoRepositoryMock) ReadAll(ctx context.Context) ([]domain.Todo, error)
 
 	return t.ReadAllFunc(ctx)
 }
+
+func (t *TodoRepositoryMock) Query(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+	t.QueryCalls++
+	t.LastQuery = q
+
+	if t.QueryFunc == nil {
+		panic("QueryFunc is nil")
+	}
+
+	return t.QueryFunc(ctx, q)
+}
+
+func (t *TodoRepositoryMock) SaveTag(ctx context.Context, tag domain.Tag) (int, error) {
+	t.SaveTagCalls++
+	t.LastSavedTag = tag
+
+	if t.SaveTagFunc == nil {
+		panic("SaveTagFunc is nil")
+	}
+
+	return t.SaveTagFunc(ctx, tag)
+}
+
+func (t *TodoRepositoryMock) GetTagByID(ctx context.Context, id int) (domain.Tag, error) {
+	t.GetTagByIDCalls++
+	t.LastTagID = id
+
+	if t.GetTagByIDFunc == nil {
+		panic("GetTagByIDFunc is nil")
+	}
+
+	return t.GetTagByIDFunc(ctx, id)
+}
+
+func (t *TodoRepositoryMock) UpdateTagByID(ctx context.Context, id int, tag domain.Tag) error {
+	t.UpdateTagByIDCalls++
+	t.LastSavedTag = tag
+	t.LastTagID = id
+
+	if t.UpdateTagByIDFunc == nil {
+		panic("UpdateTagByIDFunc is nil")
+	}
+
+	return t.UpdateTagByIDFunc(ctx, id, tag)
+}
+
+func (t *TodoRepositoryMock) DeleteTagByID(ctx context.Context, id int) error {
+	t.DeleteTagByIDCalls++
+	t.LastTagID = id
+
+	if t.DeleteTagByIDFunc == nil {
+		panic("DeleteTagByIDFunc is nil")
+	}
+
+	return t.DeleteTagByIDFunc(ctx, id)
+}
+
+func (t *TodoRepositoryMock) ReadAllTags(ctx context.Context) ([]domain.Tag, error) {
+	t.ReadAllTagsCalls++
+
+	if t.ReadAllTagsFunc == nil {
+		panic("ReadAllTagsFunc is nil")
+	}
+
+	return t.ReadAllTagsFunc(ctx)
+}
@@ -3,7 +3,9 @@ package usecase
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/VLGKiwi/todo-site/backend/internal/domain"
 )
@@ -49,6 +51,42 @@ func TestCreateTodo(t *testing.T) {
 		}
 	})
 
+	t.Run("sets timestamps", func(t *testing.T) {
+		// preparing
+		now := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+		mockRepo := &TodoRepositoryMock{
+			SaveFunc: func(ctx context.Context, todo domain.Todo) (int, error) {
+				return 1, nil
+			},
+		}
+
+		usecase := New(mockRepo)
+		usecase.Now = func() time.Time { return now }
+
+		inputTodo := domain.Todo{
+			Title:     "read the book",
+			Completed: true,
+			CreatedAt: now.Add(-time.Hour),
+		}
+
+		// act
+		_, err := usecase.CreateTodo(context.Background(), inputTodo)
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		saved := mockRepo.LastSavedTodo
+		if !saved.CreatedAt.Equal(now) || !saved.UpdatedAt.Equal(now) {
+			t.Errorf("unexpected timestamps: got created %v updated %v, want %v", saved.CreatedAt, saved.UpdatedAt, now)
+		}
+		if saved.CompletedAt == nil || !saved.CompletedAt.Equal(now) {
+			t.Errorf("unexpected completed_at: got %v, want %v", saved.CompletedAt, now)
+		}
+	})
+
 	t.Run("failed to validate -> error", func(t *testing.T) {
 		// preparing
 		mockRepo := &TodoRepositoryMock{}
@@ -75,6 +113,45 @@ func TestCreateTodo(t *testing.T) {
 		}
 	})
 
+	t.Run("every invalid field is reported", func(t *testing.T) {
+		// preparing
+		mockRepo := &TodoRepositoryMock{}
+
+		usecase := TodoUseCase{
+			TodoRepo: mockRepo,
+		}
+
+		ctx := context.Background()
+
+		inputTodo := domain.Todo{
+			Title:       "   ",
+			Description: "bell\a",
+		}
+
+		// act
+		_, err := usecase.CreateTodo(ctx, inputTodo)
+
+		// assert
+		var verrs domain.ValidationErrors
+		if !errors.As(err, &verrs) {
+			t.Fatalf("unexpected error: got %v, want %T", err, verrs)
+		}
+
+		gotFields := make([]string, 0, len(verrs))
+		for _, e := range verrs {
+			gotFields = append(gotFields, e.Field)
+		}
+		wantFields := []string{"title", "description"}
+		if !slices.Equal(gotFields, wantFields) {
+			t.Errorf("unexpected fields: got %v, want %v", gotFields, wantFields)
+		}
+
+		wantCalls := 0
+		if mockRepo.SaveCalls != wantCalls {
+			t.Errorf("unexpected calls: got %d, want %d", mockRepo.SaveCalls, wantCalls)
+		}
+	})
+
 	t.Run("failed to save todo -> error", func(t *testing.T) {
 		// preparing
 		expectedError := errors.New("failed to save todo")
@@ -190,6 +267,184 @@ func TestGetAllTodos(t *testing.T) {
 	})
 }
 
+func TestListTodos(t *testing.T) {
+	stored := []domain.Todo{
+		{ID: 1, Title: "read the book"},
+		{ID: 2, Title: "complete the game", Completed: true},
+		{ID: 3, Title: "buy milk"},
+		{ID: 4, Title: "get an internship", Completed: true},
+		{ID: 5, Title: "walk the dog"},
+	}
+
+	newMockRepo := func() *TodoRepositoryMock {
+		return &TodoRepositoryMock{
+			QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+				return q.Apply(slices.Values(stored)), nil
+			},
+		}
+	}
+
+	t.Run("pages cover every todo once", func(t *testing.T) {
+		// preparing
+		mockRepo := newMockRepo()
+		usecase := New(mockRepo)
+		ctx := context.Background()
+
+		q := domain.TodoQuery{Sort: domain.SortByTitle, Desc: true, Limit: 2}
+
+		// act
+		var (
+			got    []string
+			cursor string
+			pages  int
+		)
+		for {
+			page, err := usecase.ListTodos(ctx, q, cursor)
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			if len(page.Todos) > q.Limit {
+				t.Fatalf("page is too long: got %d, want at most %d", len(page.Todos), q.Limit)
+			}
+			for _, todo := range page.Todos {
+				got = append(got, todo.Title)
+			}
+			pages++
+
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+
+		// assert
+		want := []string{"walk the dog", "read the book", "get an internship", "complete the game", "buy milk"}
+		if !slices.Equal(got, want) {
+			t.Errorf("unexpected titles: got %v, want %v", got, want)
+		}
+
+		wantPages := 3
+		if pages != wantPages {
+			t.Errorf("unexpected pages: got %d, want %d", pages, wantPages)
+		}
+
+		// one extra todo is requested to know whether there is a next page
+		wantLimit := 3
+		if mockRepo.LastQuery.Limit != wantLimit {
+			t.Errorf("unexpected repo limit: got %d, want %d", mockRepo.LastQuery.Limit, wantLimit)
+		}
+	})
+
+	t.Run("no limit -> all todos without cursor", func(t *testing.T) {
+		// preparing
+		mockRepo := newMockRepo()
+		usecase := New(mockRepo)
+
+		// act
+		page, err := usecase.ListTodos(context.Background(), domain.TodoQuery{}, "")
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(page.Todos) != len(stored) {
+			t.Errorf("unexpected length: got %d, want %d", len(page.Todos), len(stored))
+		}
+		if page.NextCursor != "" {
+			t.Errorf("unexpected cursor: got %q, want empty", page.NextCursor)
+		}
+	})
+
+	t.Run("last page is full -> no cursor", func(t *testing.T) {
+		// preparing
+		mockRepo := newMockRepo()
+		usecase := New(mockRepo)
+
+		// act
+		page, err := usecase.ListTodos(context.Background(), domain.TodoQuery{Limit: len(stored)}, "")
+
+		// assert
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if page.NextCursor != "" {
+			t.Errorf("unexpected cursor: got %q, want empty", page.NextCursor)
+		}
+	})
+
+	t.Run("cursor of another order -> error", func(t *testing.T) {
+		// preparing
+		mockRepo := newMockRepo()
+		usecase := New(mockRepo)
+		ctx := context.Background()
+
+		page, err := usecase.ListTodos(ctx, domain.TodoQuery{Sort: domain.SortByTitle, Limit: 1}, "")
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+
+		// act
+		_, err = usecase.ListTodos(ctx, domain.TodoQuery{Sort: domain.SortByCompleted, Limit: 1}, page.NextCursor)
+
+		// assert
+		if !errors.Is(err, domain.ErrInvalidQuery) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrInvalidQuery)
+		}
+	})
+
+	invalid := []struct {
+		name   string
+		q      domain.TodoQuery
+		cursor string
+	}{
+		{name: "unknown sort", q: domain.TodoQuery{Sort: "deadline"}},
+		{name: "negative limit", q: domain.TodoQuery{Limit: -1}},
+		{name: "limit too big", q: domain.TodoQuery{Limit: MaxPageSize + 1}},
+		{name: "malformed cursor", cursor: "not a cursor"},
+	}
+
+	for _, tc := range invalid {
+		t.Run(tc.name+" -> error", func(t *testing.T) {
+			// preparing
+			mockRepo := newMockRepo()
+			usecase := New(mockRepo)
+
+			// act
+			_, err := usecase.ListTodos(context.Background(), tc.q, tc.cursor)
+
+			// assert
+			if !errors.Is(err, domain.ErrInvalidQuery) {
+				t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrInvalidQuery)
+			}
+
+			wantCalls := 0
+			if mockRepo.QueryCalls != wantCalls {
+				t.Errorf("unexpected calls: got %d, want %d", mockRepo.QueryCalls, wantCalls)
+			}
+		})
+	}
+
+	t.Run("DB failed -> error", func(t *testing.T) {
+		// preparing
+		returnedError := errors.New("some error in DB")
+
+		mockRepo := &TodoRepositoryMock{
+			QueryFunc: func(ctx context.Context, q domain.TodoQuery) ([]domain.Todo, error) {
+				return []domain.Todo{}, returnedError
+			},
+		}
+		usecase := New(mockRepo)
+
+		// act
+		_, err := usecase.ListTodos(context.Background(), domain.TodoQuery{}, "")
+
+		// assert
+		if !errors.Is(err, returnedError) {
+			t.Fatalf("unexpected error: got %v, want %v", err, returnedError)
+		}
+	})
+}
+
 func TestGetTodoByID(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		// preparing
@@ -266,16 +521,27 @@ func TestGetTodoByID(t *testing.T) {
 }
 
 func TestUpdateTodoByID(t *testing.T) {
+	created := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+	now := created.Add(24 * time.Hour)
+
+	storedTodo := domain.Todo{ID: 1, Title: "read the book", Version: 1, CreatedAt: created, UpdatedAt: created}
+
 	t.Run("success", func(t *testing.T) {
 		// preparing
 		mockRepo := &TodoRepositoryMock{
+			GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+				return storedTodo, nil
+			},
 			UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
 				return nil
 			},
 		}
 
 		inputTodo := domain.Todo{
-			Title: "complete the game",
+			Title:     "complete the game",
+			Completed: true,
+			// clients cannot rewrite the history
+			CreatedAt: now.Add(time.Hour),
 		}
 
 		inputID := 1
@@ -283,6 +549,7 @@ func TestUpdateTodoByID(t *testing.T) {
 		ctx := context.Background()
 
 		usecase := New(mockRepo)
+		usecase.Now = func() time.Time { return now }
 
 		// act
 		err := usecase.UpdateTodoByID(ctx, inputID, inputTodo)
@@ -300,12 +567,47 @@ func TestUpdateTodoByID(t *testing.T) {
 			t.Errorf("unexpected id arg: got %d , want %d", mockRepo.LastGetID, inputID)
 		}
 
+		saved := mockRepo.LastSavedTodo
+		if !saved.CreatedAt.Equal(created) {
+			t.Errorf("created_at must be kept: got %v, want %v", saved.CreatedAt, created)
+		}
+		if !saved.UpdatedAt.Equal(now) {
+			t.Errorf("unexpected updated_at: got %v, want %v", saved.UpdatedAt, now)
+		}
+		if saved.CompletedAt == nil || !saved.CompletedAt.Equal(now) {
+			t.Errorf("unexpected completed_at: got %v, want %v", saved.CompletedAt, now)
+		}
+
 		wantCalls := 1
 		if mockRepo.UpdateByIDCalls != wantCalls {
 			t.Errorf("unexpected calls: got %d, want %d", mockRepo.UpdateByIDCalls, wantCalls)
 		}
 	})
 
+	t.Run("todo not exist -> error", func(t *testing.T) {
+		// preparing
+		mockRepo := &TodoRepositoryMock{
+			GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+				return domain.Todo{}, domain.ErrTodoNotExist
+			},
+		}
+
+		usecase := New(mockRepo)
+
+		// act
+		err := usecase.UpdateTodoByID(context.Background(), 1, domain.Todo{Title: "complete the game"})
+
+		// assert
+		if !errors.Is(err, domain.ErrTodoNotExist) {
+			t.Fatalf("unexpected error: got %v, want %v", err, domain.ErrTodoNotExist)
+		}
+
+		wantCalls := 0
+		if mockRepo.UpdateByIDCalls != wantCalls {
+			t.Errorf("unexpected calls: got %d, want %d", mockRepo.UpdateByIDCalls, wantCalls)
+		}
+	})
+
 	t.Run("failed to validate -> error", func(t *testing.T) {
 		// preparing
 		inputID := 1
@@ -335,6 +637,9 @@ func TestUpdateTodoByID(t *testing.T) {
 	t.Run("failed to update -> error", func(t *testing.T) {
 		// preparing
 		mockRepo := &TodoRepositoryMock{
+			GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+				return storedTodo, nil
+			},
 			UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
 				return domain.ErrTodoNotExist
 			},
@@ -373,23 +678,187 @@ func TestUpdateTodoByID(t *testing.T) {
 	})
 }
 
+func TestPatchTodoByID(t *testing.T) {
+	created := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+	now := created.Add(24 * time.Hour)
+	due := time.Date(2030, time.March, 10, 18, 0, 0, 0, time.FixedZone("", 3*60*60))
+
+	storedTodo := domain.Todo{
+		ID:          1,
+		Title:       "read the book",
+		Description: "chapter one",
+		Version:     2,
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}
+
+	tests := []struct {
+		name  string
+		patch string
+
+		version   int
+		getErr    error
+		updateErr error
+
+		wantErr         error
+		wantTodo        domain.Todo
+		wantUpdateCalls int
+	}{
+		{
+			name:            "toggle completed keeps other fields",
+			patch:           `{"completed": true}`,
+			wantTodo:        domain.Todo{ID: 1, Title: "read the book", Description: "chapter one", Completed: true, Version: 3, CreatedAt: created, UpdatedAt: now, CompletedAt: &now},
+			wantUpdateCalls: 1,
+		},
+		{
+			name:            "matching version",
+			patch:           `{"completed": true}`,
+			version:         2,
+			wantTodo:        domain.Todo{ID: 1, Title: "read the book", Description: "chapter one", Completed: true, Version: 3, CreatedAt: created, UpdatedAt: now, CompletedAt: &now},
+			wantUpdateCalls: 1,
+		},
+		{
+			name:            "stale version -> error",
+			patch:           `{"completed": true}`,
+			version:         1,
+			wantErr:         domain.ErrVersionMismatch,
+			wantUpdateCalls: 0,
+		},
+		{
+			name:            "concurrent change between read and write -> error",
+			patch:           `{"completed": true}`,
+			updateErr:       domain.ErrVersionMismatch,
+			wantUpdateCalls: 1,
+		},
+		{
+			name:            "null removes description",
+			patch:           `{"description": null, "title": "complete the game"}`,
+			wantTodo:        domain.Todo{ID: 1, Title: "complete the game", Version: 3, CreatedAt: created, UpdatedAt: now},
+			wantUpdateCalls: 1,
+		},
+		{
+			name:            "server managed fields in patch are ignored",
+			patch:           `{"id": 42, "version": 42, "created_at": "2000-01-01T00:00:00Z", "completed_at": "2000-01-01T00:00:00Z"}`,
+			wantTodo:        domain.Todo{ID: 1, Title: "read the book", Description: "chapter one", Version: 3, CreatedAt: created, UpdatedAt: now},
+			wantUpdateCalls: 1,
+		},
+		{
+			name:  "due date and priority",
+			patch: `{"due_at": "2030-03-10T18:00:00+03:00", "priority": "high"}`,
+			wantTodo: domain.Todo{
+				ID: 1, Title: "read the book", Description: "chapter one", Version: 3,
+				Priority: domain.PriorityHigh, DueAt: &due, CreatedAt: created, UpdatedAt: now,
+			},
+			wantUpdateCalls: 1,
+		},
+		{
+			name:            "unknown priority -> error",
+			patch:           `{"priority": "urgent"}`,
+			wantErr:         domain.ErrInvalidPatch,
+			wantUpdateCalls: 0,
+		},
+		{
+			name:            "merged result is validated",
+			patch:           `{"title": null}`,
+			wantErr:         domain.ErrNoTitle,
+			wantUpdateCalls: 0,
+		},
+		{
+			name:            "malformed patch -> error",
+			patch:           `{"completed": `,
+			wantErr:         domain.ErrInvalidPatch,
+			wantUpdateCalls: 0,
+		},
+		{
+			name:            "wrong field type -> error",
+			patch:           `{"completed": "yes"}`,
+			wantErr:         domain.ErrInvalidPatch,
+			wantUpdateCalls: 0,
+		},
+		{
+			name:            "todo not exist -> error",
+			patch:           `{"completed": true}`,
+			getErr:          domain.ErrTodoNotExist,
+			wantErr:         domain.ErrTodoNotExist,
+			wantUpdateCalls: 0,
+		},
+		{
+			name:            "failed to update -> error",
+			patch:           `{"completed": true}`,
+			updateErr:       errors.New("failed to update"),
+			wantUpdateCalls: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			mockRepo := &TodoRepositoryMock{
+				GetByIDFunc: func(ctx context.Context, id int) (domain.Todo, error) {
+					return storedTodo, tc.getErr
+				},
+				UpdateByIDFunc: func(ctx context.Context, id int, todo domain.Todo) error {
+					return tc.updateErr
+				},
+			}
+
+			usecase := New(mockRepo)
+			usecase.Now = func() time.Time { return now }
+
+			// act
+			todo, err := usecase.PatchTodoByID(context.Background(), 1, tc.version, []byte(tc.patch))
+
+			// assert
+			switch {
+			case tc.updateErr != nil:
+				if !errors.Is(err, tc.updateErr) {
+					t.Fatalf("unexpected error: got %v, want %v", err, tc.updateErr)
+				}
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: got %v, want nil", err)
+				}
+				if !todo.Equal(tc.wantTodo) {
+					t.Errorf("unexpected todo: got %+v, want %+v", todo, tc.wantTodo)
+				}
+
+				// the repository gets the version read before merging as a precondition
+				wantSaved := tc.wantTodo
+				wantSaved.Version = storedTodo.Version
+				if !mockRepo.LastSavedTodo.Equal(wantSaved) {
+					t.Errorf("unexpected saved todo: got %+v, want %+v", mockRepo.LastSavedTodo, wantSaved)
+				}
+			}
+
+			if mockRepo.UpdateByIDCalls != tc.wantUpdateCalls {
+				t.Errorf("unexpected calls: got %d, want %d", mockRepo.UpdateByIDCalls, tc.wantUpdateCalls)
+			}
+		})
+	}
+}
+
 func TestDeleteTodoByID(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		// preparing
 		mockRepo := &TodoRepositoryMock{
-			DeleteByIDFunc: func(ctx context.Context, id int) error {
+			DeleteByIDFunc: func(ctx context.Context, id int, version int) error {
 				return nil
 			},
 		}
 
 		inputID := 1
+		inputVersion := 3
 
 		ctx := context.Background()
 
 		usecase := New(mockRepo)
 
 		// act
-		err := usecase.DeleteTodoByID(ctx, inputID)
+		err := usecase.DeleteTodoByID(ctx, inputID, inputVersion)
 
 		// assert
 		if err != nil {
@@ -400,6 +869,10 @@ func TestDeleteTodoByID(t *testing.T) {
 			t.Errorf("unexpecnted id arg: got %d, want %d", mockRepo.LastGetID, inputID)
 		}
 
+		if mockRepo.LastVersion != inputVersion {
+			t.Errorf("unexpected version arg: got %d, want %d", mockRepo.LastVersion, inputVersion)
+		}
+
 		wantCalls := 1
 		if mockRepo.DeleteByIDCalls != wantCalls {
 			t.Errorf("unexpected calls: got %d, want %d", mockRepo.DeleteByIDCalls, wantCalls)
@@ -411,7 +884,7 @@ func TestDeleteTodoByID(t *testing.T) {
 		returnedError := errors.New("some error in DB")
 
 		mockRepo := &TodoRepositoryMock{
-			DeleteByIDFunc: func(ctx context.Context, id int) error {
+			DeleteByIDFunc: func(ctx context.Context, id int, version int) error {
 				return returnedError
 			},
 		}
@@ -423,7 +896,7 @@ func TestDeleteTodoByID(t *testing.T) {
 		usecase := New(mockRepo)
 
 		// act
-		err := usecase.DeleteTodoByID(ctx, inputID)
+		err := usecase.DeleteTodoByID(ctx, inputID, 0)
 
 		// assert
 		if !errors.Is(err, returnedError) {
@@ -0,0 +1,224 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/auth"
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+// WebhookRepository stores the webhooks of the users.
+type WebhookRepository interface {
+	SaveWebhook(ctx context.Context, hook domain.Webhook) (int, error)
+	// ListWebhooks returns the webhooks of the user, ordered by id.
+	ListWebhooks(ctx context.Context, userID int) ([]domain.Webhook, error)
+	// ReadAllWebhooks returns the webhooks of every user of the workspace,
+	// ordered by id.
+	ReadAllWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	// DeleteWebhook fails with domain.ErrWebhookNotExist unless the webhook
+	// belongs to the user.
+	DeleteWebhook(ctx context.Context, userID, id int) error
+}
+
+// WebhookDispatcher delivers events to webhooks in the background and
+// keeps a log of the deliveries, see webhooks.Dispatcher. Deliveries are
+// told apart by the workspace of the webhook.
+type WebhookDispatcher interface {
+	// Enqueue hands the event to the webhook, once per event id: an event
+	// the webhook already got is skipped.
+	Enqueue(hook domain.Webhook, event domain.TodoEvent)
+	// Deliveries returns the deliveries of the webhook still in the log,
+	// newest first.
+	Deliveries(tenant domain.Tenant, webhookID int) []domain.WebhookDelivery
+	// DeadLetters returns the deliveries that ran out of attempts, newest
+	// first.
+	DeadLetters(tenant domain.Tenant) []domain.WebhookDelivery
+	// Redeliver gives a dead letter a fresh round of attempts. It fails
+	// with domain.ErrDeliveryNotExist for anything but a dead letter of the
+	// webhook.
+	Redeliver(tenant domain.Tenant, webhookID, id int) error
+	// Cancel stops the pending deliveries of a deleted webhook and forgets
+	// its log.
+	Cancel(tenant domain.Tenant, webhookID int)
+}
+
+// CreateWebhook subscribes the authenticated user to the changes of the
+// todos they can see. A webhook without a secret gets a generated one;
+// the returned webhook is the only time the secret is shown.
+func (u *TodoUseCase) CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.CreatedWebhook, error) {
+	user, err := u.webhookUser(ctx)
+	if err != nil {
+		return domain.CreatedWebhook{}, err
+	}
+
+	if err := hook.Validate(); err != nil {
+		return domain.CreatedWebhook{}, fmt.Errorf("validate webhook: %w", err)
+	}
+
+	secret := hook.Secret
+	if secret == "" {
+		if secret, err = auth.GenerateKey(domain.WebhookSecretPrefix); err != nil {
+			return domain.CreatedWebhook{}, err
+		}
+	}
+
+	stored := domain.Webhook{
+		UserID:    user.ID,
+		URL:       hook.URL,
+		Events:    domain.NormalizeWebhookEvents(hook.Events),
+		Secret:    secret,
+		CreatedAt: u.now(),
+	}
+
+	id, err := u.Webhooks.SaveWebhook(ctx, stored)
+	if err != nil {
+		return domain.CreatedWebhook{}, fmt.Errorf("save webhook in db: %w", err)
+	}
+	stored.ID = id
+
+	return domain.CreatedWebhook{Webhook: stored, Secret: secret}, nil
+}
+
+// GetWebhooks returns the webhooks of the authenticated user.
+func (u *TodoUseCase) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	user, err := u.webhookUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := u.Webhooks.ListWebhooks(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+
+	return hooks, nil
+}
+
+// DeleteWebhookByID deletes a webhook of the authenticated user together
+// with its pending deliveries.
+func (u *TodoUseCase) DeleteWebhookByID(ctx context.Context, id int) error {
+	user, err := u.webhookUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := u.Webhooks.DeleteWebhook(ctx, user.ID, id); err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	u.Deliveries.Cancel(domain.CurrentTenant(ctx), id)
+
+	return nil
+}
+
+// GetWebhookDeliveries returns the recent deliveries of a webhook of the
+// authenticated user, newest first.
+func (u *TodoUseCase) GetWebhookDeliveries(ctx context.Context, id int) ([]domain.WebhookDelivery, error) {
+	if _, err := u.getWebhook(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return u.Deliveries.Deliveries(domain.CurrentTenant(ctx), id), nil
+}
+
+// GetDeadLetters returns the deliveries to the webhooks of the
+// authenticated user that ran out of attempts, newest first.
+func (u *TodoUseCase) GetDeadLetters(ctx context.Context) ([]domain.WebhookDelivery, error) {
+	hooks, err := u.GetWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dead := make([]domain.WebhookDelivery, 0)
+	for _, d := range u.Deliveries.DeadLetters(domain.CurrentTenant(ctx)) {
+		if slices.ContainsFunc(hooks, func(hook domain.Webhook) bool { return hook.ID == d.WebhookID }) {
+			dead = append(dead, d)
+		}
+	}
+
+	return dead, nil
+}
+
+// RedeliverWebhook sends a dead letter of a webhook of the authenticated
+// user again, with a fresh round of attempts.
+func (u *TodoUseCase) RedeliverWebhook(ctx context.Context, id int, deliveryID int) error {
+	if _, err := u.getWebhook(ctx, id); err != nil {
+		return err
+	}
+
+	if err := u.Deliveries.Redeliver(domain.CurrentTenant(ctx), id, deliveryID); err != nil {
+		return fmt.Errorf("redeliver %d: %w", deliveryID, err)
+	}
+
+	return nil
+}
+
+func (u *TodoUseCase) webhookUser(ctx context.Context) (domain.User, error) {
+	if u.Webhooks == nil || u.Deliveries == nil {
+		return domain.User{}, errors.New("webhooks are not configured")
+	}
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.User{}, domain.ErrUnauthenticated
+	}
+	return user, nil
+}
+
+// getWebhook returns a webhook of the authenticated user; those of other
+// users are reported as missing.
+func (u *TodoUseCase) getWebhook(ctx context.Context, id int) (domain.Webhook, error) {
+	hooks, err := u.GetWebhooks(ctx)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+
+	i := slices.IndexFunc(hooks, func(hook domain.Webhook) bool { return hook.ID == id })
+	if i < 0 {
+		return domain.Webhook{}, domain.ErrWebhookNotExist
+	}
+	return hooks[i], nil
+}
+
+// NotifyWebhooks hands the event to every webhook of the workspace that
+// subscribed to it and whose owner can see the todo. The role is checked
+// now, so a user who has left a list stops getting its changes. It is a
+// subscriber of the outbox, see EventOutbox: every role is resolved before
+// the first webhook gets the event, so a failed check leaves nothing
+// enqueued and the retry of the outbox hands the event to every webhook
+// once.
+func (u *TodoUseCase) NotifyWebhooks(ctx context.Context, event domain.TodoEvent) error {
+	if u.Webhooks == nil || u.Deliveries == nil {
+		return nil
+	}
+
+	hooks, err := u.Webhooks.ReadAllWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("read webhooks: %w", err)
+	}
+
+	var targets []domain.Webhook
+	for _, hook := range hooks {
+		if !hook.Wants(event.Type) {
+			continue
+		}
+
+		role, err := u.todoRole(ctx, hook.UserID, event.Todo)
+		if err != nil {
+			return fmt.Errorf("check webhook %d: %w", hook.ID, err)
+		}
+		if role == "" {
+			continue
+		}
+
+		targets = append(targets, hook)
+	}
+
+	for _, hook := range targets {
+		u.Deliveries.Enqueue(hook, event)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+type WebhookRepositoryMock struct {
+	SaveWebhookFunc     func(ctx context.Context, hook domain.Webhook) (int, error)
+	ListWebhooksFunc    func(ctx context.Context, userID int) ([]domain.Webhook, error)
+	ReadAllWebhooksFunc func(ctx context.Context) ([]domain.Webhook, error)
+	DeleteWebhookFunc   func(ctx context.Context, userID, id int) error
+
+	SaveWebhookCalls     int
+	ListWebhooksCalls    int
+	ReadAllWebhooksCalls int
+	DeleteWebhookCalls   int
+
+	LastSavedWebhook domain.Webhook
+	LastUserID       int
+	LastID           int
+}
+
+func (r *WebhookRepositoryMock) SaveWebhook(ctx context.Context, hook domain.Webhook) (int, error) {
+	r.LastSavedWebhook = hook
+	r.SaveWebhookCalls++
+
+	if r.SaveWebhookFunc == nil {
+		panic("SaveWebhookFunc is nil")
+	}
+
+	return r.SaveWebhookFunc(ctx, hook)
+}
+
+func (r *WebhookRepositoryMock) ListWebhooks(ctx context.Context, userID int) ([]domain.Webhook, error) {
+	r.LastUserID = userID
+	r.ListWebhooksCalls++
+
+	if r.ListWebhooksFunc == nil {
+		panic("ListWebhooksFunc is nil")
+	}
+
+	return r.ListWebhooksFunc(ctx, userID)
+}
+
+func (r *WebhookRepositoryMock) ReadAllWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	r.ReadAllWebhooksCalls++
+
+	if r.ReadAllWebhooksFunc == nil {
+		panic("ReadAllWebhooksFunc is nil")
+	}
+
+	return r.ReadAllWebhooksFunc(ctx)
+}
+
+func (r *WebhookRepositoryMock) DeleteWebhook(ctx context.Context, userID, id int) error {
+	r.LastUserID = userID
+	r.LastID = id
+	r.DeleteWebhookCalls++
+
+	if r.DeleteWebhookFunc == nil {
+		panic("DeleteWebhookFunc is nil")
+	}
+
+	return r.DeleteWebhookFunc(ctx, userID, id)
+}
+
+// WebhookDispatcherMock keeps what was enqueued and canceled.
+type WebhookDispatcherMock struct {
+	DeliveriesFunc  func(tenant domain.Tenant, webhookID int) []domain.WebhookDelivery
+	DeadLettersFunc func(tenant domain.Tenant) []domain.WebhookDelivery
+	RedeliverFunc   func(tenant domain.Tenant, webhookID, id int) error
+
+	DeliveriesCalls  int
+	DeadLettersCalls int
+	RedeliverCalls   int
+
+	Enqueued []WebhookEnqueued
+	Canceled []int
+}
+
+// WebhookEnqueued is an event handed to a webhook.
+type WebhookEnqueued struct {
+	Hook  domain.Webhook
+	Event domain.TodoEvent
+}
+
+func (d *WebhookDispatcherMock) Enqueue(hook domain.Webhook, event domain.TodoEvent) {
+	d.Enqueued = append(d.Enqueued, WebhookEnqueued{Hook: hook, Event: event})
+}
+
+func (d *WebhookDispatcherMock) Deliveries(tenant domain.Tenant, webhookID int) []domain.WebhookDelivery {
+	d.DeliveriesCalls++
+
+	if d.DeliveriesFunc == nil {
+		panic("DeliveriesFunc is nil")
+	}
+
+	return d.DeliveriesFunc(tenant, webhookID)
+}
+
+func (d *WebhookDispatcherMock) DeadLetters(tenant domain.Tenant) []domain.WebhookDelivery {
+	d.DeadLettersCalls++
+
+	if d.DeadLettersFunc == nil {
+		panic("DeadLettersFunc is nil")
+	}
+
+	return d.DeadLettersFunc(tenant)
+}
+
+func (d *WebhookDispatcherMock) Redeliver(tenant domain.Tenant, webhookID, id int) error {
+	d.RedeliverCalls++
+
+	if d.RedeliverFunc == nil {
+		panic("RedeliverFunc is nil")
+	}
+
+	return d.RedeliverFunc(tenant, webhookID, id)
+}
+
+func (d *WebhookDispatcherMock) Cancel(tenant domain.Tenant, webhookID int) {
+	d.Canceled = append(d.Canceled, webhookID)
+}
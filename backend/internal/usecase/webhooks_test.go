@@ -0,0 +1,305 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		hook domain.Webhook
+
+		wantErr    error
+		wantSecret string
+		wantEvents []domain.TodoEventType
+	}{
+		{
+			name:       "without secret and events -> generated secret, every event",
+			ctx:        domain.ContextWithUser(context.Background(), ann),
+			hook:       domain.Webhook{URL: "https://example.com/hook"},
+			wantSecret: domain.WebhookSecretPrefix,
+			wantEvents: domain.WebhookEvents,
+		},
+		{
+			name: "own secret and events",
+			ctx:  domain.ContextWithUser(context.Background(), ann),
+			hook: domain.Webhook{
+				URL:    "https://example.com/hook",
+				Events: []domain.TodoEventType{domain.TodoDeleted, domain.TodoCompleted, domain.TodoDeleted},
+				Secret: "0123456789abcdef",
+			},
+			wantSecret: "0123456789abcdef",
+			wantEvents: []domain.TodoEventType{domain.TodoCompleted, domain.TodoDeleted},
+		},
+		{
+			name:    "invalid url -> validation error",
+			ctx:     domain.ContextWithUser(context.Background(), ann),
+			hook:    domain.Webhook{URL: "ftp://example.com/hook"},
+			wantErr: domain.ErrValidation,
+		},
+		{
+			name:    "short secret -> validation error",
+			ctx:     domain.ContextWithUser(context.Background(), ann),
+			hook:    domain.Webhook{URL: "https://example.com/hook", Secret: "short"},
+			wantErr: domain.ErrValidation,
+		},
+		{
+			name:    "without user -> unauthenticated",
+			ctx:     context.Background(),
+			hook:    domain.Webhook{URL: "https://example.com/hook"},
+			wantErr: domain.ErrUnauthenticated,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			repo := &WebhookRepositoryMock{
+				SaveWebhookFunc: func(ctx context.Context, hook domain.Webhook) (int, error) {
+					return 7, nil
+				},
+			}
+			usecase := New(&TodoRepositoryMock{})
+			usecase.Webhooks = repo
+			usecase.Deliveries = &WebhookDispatcherMock{}
+
+			// act
+			created, err := usecase.CreateWebhook(tc.ctx, tc.hook)
+
+			// assert
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if repo.SaveWebhookCalls != 0 {
+					t.Errorf("unexpected save calls: got %d, want 0", repo.SaveWebhookCalls)
+				}
+				return
+			}
+
+			if created.ID != 7 || created.UserID != ann.ID {
+				t.Errorf("unexpected webhook: got %+v", created)
+			}
+			if !strings.HasPrefix(created.Secret, tc.wantSecret) || repo.LastSavedWebhook.Secret != created.Secret {
+				t.Errorf("unexpected secret: got %q, stored %q, want %q...", created.Secret, repo.LastSavedWebhook.Secret, tc.wantSecret)
+			}
+			if !slices.Equal(repo.LastSavedWebhook.Events, tc.wantEvents) {
+				t.Errorf("unexpected events: got %v, want %v", repo.LastSavedWebhook.Events, tc.wantEvents)
+			}
+		})
+	}
+}
+
+func TestNotifyWebhooks(t *testing.T) {
+	listID := sharedList().ID
+	hooks := []domain.Webhook{
+		{ID: 1, UserID: ann.ID, Events: domain.WebhookEvents},
+		{ID: 2, UserID: bob.ID, Events: []domain.TodoEventType{domain.TodoDeleted}},
+		{ID: 3, UserID: cat.ID, Events: []domain.TodoEventType{domain.TodoCompleted}},
+		{ID: 4, UserID: dan.ID, Events: domain.WebhookEvents},
+	}
+
+	tests := []struct {
+		name  string
+		event domain.TodoEvent
+
+		wantHooks []int
+	}{
+		{
+			name:      "private todo -> owner only",
+			event:     domain.TodoEvent{Type: domain.TodoCompleted, Todo: domain.Todo{ID: 1, OwnerID: cat.ID}},
+			wantHooks: []int{3},
+		},
+		{
+			name:      "shared todo -> members subscribed to the event",
+			event:     domain.TodoEvent{Type: domain.TodoCompleted, Todo: domain.Todo{ID: 2, OwnerID: ann.ID, ListID: &listID}},
+			wantHooks: []int{1, 3},
+		},
+		{
+			name:      "deleted shared todo",
+			event:     domain.TodoEvent{Type: domain.TodoDeleted, Todo: domain.Todo{ID: 2, OwnerID: ann.ID, ListID: &listID}},
+			wantHooks: []int{1, 2},
+		},
+		{
+			name:  "todo nobody with a webhook sees -> nothing",
+			event: domain.TodoEvent{Type: domain.TodoCreated, Todo: domain.Todo{ID: 3, OwnerID: 42}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			dispatcher := &WebhookDispatcherMock{}
+			usecase := New(&TodoRepositoryMock{})
+			usecase.Lists = newListRepo(sharedList())
+			usecase.Webhooks = &WebhookRepositoryMock{
+				ReadAllWebhooksFunc: func(ctx context.Context) ([]domain.Webhook, error) {
+					return hooks, nil
+				},
+			}
+			usecase.Deliveries = dispatcher
+
+			// act
+			err := usecase.NotifyWebhooks(context.Background(), tc.event)
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: got %v, want nil", err)
+			}
+			var got []int
+			for _, e := range dispatcher.Enqueued {
+				got = append(got, e.Hook.ID)
+				if e.Event.Todo.ID != tc.event.Todo.ID || e.Event.Type != tc.event.Type {
+					t.Errorf("unexpected event: got %+v, want %+v", e.Event, tc.event)
+				}
+			}
+			if !slices.Equal(got, tc.wantHooks) {
+				t.Errorf("unexpected webhooks: got %v, want %v", got, tc.wantHooks)
+			}
+		})
+	}
+}
+
+func TestNotifyWebhooksFailedCheck(t *testing.T) {
+	// preparing
+	list := sharedList()
+	hooks := []domain.Webhook{
+		{ID: 1, UserID: ann.ID, Events: domain.WebhookEvents},
+		{ID: 2, UserID: bob.ID, Events: domain.WebhookEvents},
+	}
+	dispatcher := &WebhookDispatcherMock{}
+	calls := 0
+	usecase := New(&TodoRepositoryMock{})
+	usecase.Lists = &ListRepositoryMock{
+		GetListByIDFunc: func(ctx context.Context, id int) (domain.List, error) {
+			// the check of the first webhook passes, the second one fails
+			if calls++; calls > 1 {
+				return domain.List{}, errors.New("connection refused")
+			}
+			return list, nil
+		},
+	}
+	usecase.Webhooks = &WebhookRepositoryMock{
+		ReadAllWebhooksFunc: func(ctx context.Context) ([]domain.Webhook, error) {
+			return hooks, nil
+		},
+	}
+	usecase.Deliveries = dispatcher
+
+	// act
+	err := usecase.NotifyWebhooks(context.Background(), domain.TodoEvent{ID: 5, Type: domain.TodoCreated, Todo: domain.Todo{ID: 2, OwnerID: ann.ID, ListID: &list.ID}})
+
+	// assert
+	if err == nil {
+		t.Fatal("unexpected error: got nil, want an error")
+	}
+	if len(dispatcher.Enqueued) != 0 {
+		t.Errorf("unexpected enqueued: got %+v, want none", dispatcher.Enqueued)
+	}
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	ctx := domain.ContextWithTenant(domain.ContextWithUser(context.Background(), ann), "acme")
+
+	newUseCase := func() (*TodoUseCase, *WebhookDispatcherMock) {
+		dispatcher := &WebhookDispatcherMock{
+			DeliveriesFunc: func(tenant domain.Tenant, webhookID int) []domain.WebhookDelivery {
+				return []domain.WebhookDelivery{{ID: 9, WebhookID: webhookID, Tenant: tenant}}
+			},
+			DeadLettersFunc: func(tenant domain.Tenant) []domain.WebhookDelivery {
+				return []domain.WebhookDelivery{{ID: 3, WebhookID: 1}, {ID: 2, WebhookID: 2}, {ID: 1, WebhookID: 1}}
+			},
+			RedeliverFunc: func(tenant domain.Tenant, webhookID, id int) error {
+				if tenant != "acme" || id != 3 {
+					return domain.ErrDeliveryNotExist
+				}
+				return nil
+			},
+		}
+		usecase := New(&TodoRepositoryMock{})
+		usecase.Webhooks = &WebhookRepositoryMock{
+			ListWebhooksFunc: func(ctx context.Context, userID int) ([]domain.Webhook, error) {
+				if userID != ann.ID {
+					return []domain.Webhook{{ID: 2, UserID: userID}}, nil
+				}
+				return []domain.Webhook{{ID: 1, UserID: ann.ID}}, nil
+			},
+			DeleteWebhookFunc: func(ctx context.Context, userID, id int) error {
+				return nil
+			},
+		}
+		usecase.Deliveries = dispatcher
+		return usecase, dispatcher
+	}
+
+	t.Run("log of an own webhook", func(t *testing.T) {
+		usecase, _ := newUseCase()
+
+		got, err := usecase.GetWebhookDeliveries(ctx, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if len(got) != 1 || got[0].Tenant != "acme" || got[0].WebhookID != 1 {
+			t.Errorf("unexpected deliveries: got %+v", got)
+		}
+	})
+
+	t.Run("log of a webhook of another user -> not found", func(t *testing.T) {
+		usecase, dispatcher := newUseCase()
+
+		if _, err := usecase.GetWebhookDeliveries(ctx, 2); !errors.Is(err, domain.ErrWebhookNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrWebhookNotExist)
+		}
+		if err := usecase.RedeliverWebhook(ctx, 2, 2); !errors.Is(err, domain.ErrWebhookNotExist) {
+			t.Errorf("unexpected error on redeliver: got %v, want %v", err, domain.ErrWebhookNotExist)
+		}
+		if dispatcher.DeliveriesCalls+dispatcher.RedeliverCalls != 0 {
+			t.Errorf("unexpected dispatcher calls: got %d, want 0", dispatcher.DeliveriesCalls+dispatcher.RedeliverCalls)
+		}
+	})
+
+	t.Run("dead letters of own webhooks only", func(t *testing.T) {
+		usecase, _ := newUseCase()
+
+		got, err := usecase.GetDeadLetters(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		var ids []int
+		for _, d := range got {
+			ids = append(ids, d.ID)
+		}
+		if want := []int{3, 1}; !slices.Equal(ids, want) {
+			t.Errorf("unexpected dead letters: got %v, want %v", ids, want)
+		}
+	})
+
+	t.Run("redeliver", func(t *testing.T) {
+		usecase, _ := newUseCase()
+
+		if err := usecase.RedeliverWebhook(ctx, 1, 3); err != nil {
+			t.Errorf("unexpected error: got %v, want nil", err)
+		}
+		if err := usecase.RedeliverWebhook(ctx, 1, 5); !errors.Is(err, domain.ErrDeliveryNotExist) {
+			t.Errorf("unexpected error: got %v, want %v", err, domain.ErrDeliveryNotExist)
+		}
+	})
+
+	t.Run("delete cancels pending deliveries", func(t *testing.T) {
+		usecase, dispatcher := newUseCase()
+
+		if err := usecase.DeleteWebhookByID(ctx, 1); err != nil {
+			t.Fatalf("unexpected error: got %v, want nil", err)
+		}
+		if !slices.Equal(dispatcher.Canceled, []int{1}) {
+			t.Errorf("unexpected canceled webhooks: got %v, want [1]", dispatcher.Canceled)
+		}
+	})
+}
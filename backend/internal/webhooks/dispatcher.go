@@ -0,0 +1,399 @@
+// Package webhooks delivers the changes of todos to the URLs users
+// subscribed. Every delivery is a POST of a JSON payload signed with
+// HMAC-SHA256 of the webhook secret; failed deliveries are retried with
+// exponential backoff and end up as dead letters once out of attempts.
+//
+// Deliveries live in memory: those pending when the process stops are
+// lost, and the log keeps only the most recent ones.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body.
+	SignatureHeader = "X-Todo-Signature"
+	// EventHeader carries the type of the event.
+	EventHeader = "X-Todo-Event"
+	// DeliveryHeader carries the id of the event and the webhook, as in
+	// "42-5", the same for every attempt and every hand-off of the event,
+	// so receivers can drop repeats.
+	DeliveryHeader = "X-Todo-Delivery"
+
+	DefaultMaxAttempts    = 8
+	DefaultInitialBackoff = 10 * time.Second
+	DefaultMaxBackoff     = time.Hour
+	DefaultTimeout        = 10 * time.Second
+	DefaultLogSize        = 1000
+)
+
+// maxResponseBody is how much of a response is read, so the connection
+// can be reused; the rest is dropped.
+const maxResponseBody = 64 << 10
+
+type Options struct {
+	// Client sends the requests. The default one gives up after Timeout,
+	// does not follow redirects, a 3xx is a failed attempt, and refuses
+	// hosts of the local network, see ErrForbiddenAddress.
+	Client *http.Client
+	// Timeout caps every attempt of the default client, DefaultTimeout
+	// when zero.
+	Timeout time.Duration
+	// MaxAttempts is how often a delivery is tried before it becomes a
+	// dead letter.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled for every
+	// next one up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// LogSize caps both the delivery log and the dead letters.
+	LogSize int
+	// Now is the clock of the log, time.Now when nil.
+	Now func() time.Time
+}
+
+// Dispatcher sends deliveries in the background, one goroutine each. It is
+// safe for concurrent use; Close stops it.
+type Dispatcher struct {
+	opts Options
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	nextID int
+	// log and dead are ordered oldest first.
+	log  []*delivery
+	dead []*delivery
+}
+
+// delivery is guarded by the mutex of the dispatcher.
+type delivery struct {
+	domain.WebhookDelivery
+	url    string
+	secret string
+	// key is the value of DeliveryHeader.
+	key string
+	// round is the index of the first attempt of the current round, so a
+	// redelivered dead letter gets MaxAttempts again.
+	round    int
+	canceled bool
+}
+
+// payload is the body of every delivery. ID is the id of the event.
+type payload struct {
+	ID        uint64               `json:"id"`
+	Event     domain.TodoEventType `json:"event"`
+	CreatedAt time.Time            `json:"created_at"`
+	Data      domain.Todo          `json:"data"`
+}
+
+func NewDispatcher(opts Options) *Dispatcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Client == nil {
+		opts.Client = newClient(opts.Timeout)
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.LogSize <= 0 {
+		opts.LogSize = DefaultLogSize
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{opts: opts, ctx: ctx, cancel: cancel, nextID: 1}
+}
+
+// Close stops the retries and waits for the requests in flight to be
+// cancelled.
+func (d *Dispatcher) Close() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// Enqueue builds the payload of the event and sends it to the webhook in
+// the background. An event the webhook already got, told by its id, is
+// skipped while its delivery is still in the log: the outbox hands an
+// event over again when a subscriber fails. Events without an id are
+// always sent.
+func (d *Dispatcher) Enqueue(hook domain.Webhook, event domain.TodoEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if event.ID != 0 && slices.ContainsFunc(d.log, func(del *delivery) bool {
+		return del.Tenant == event.Tenant && del.WebhookID == hook.ID && del.EventID == event.ID
+	}) {
+		return
+	}
+
+	body, err := json.Marshal(payload{ID: event.ID, Event: event.Type, CreatedAt: event.At, Data: event.Todo})
+	if err != nil {
+		slog.Error("failed to encode webhook payload", "webhook_id", hook.ID, "error", err)
+		return
+	}
+
+	id := d.nextID
+	d.nextID++
+
+	key := fmt.Sprintf("%d-%d", event.ID, hook.ID)
+	if event.ID == 0 {
+		key = "local-" + strconv.Itoa(id)
+	}
+
+	del := &delivery{
+		WebhookDelivery: domain.WebhookDelivery{
+			ID:        id,
+			WebhookID: hook.ID,
+			Tenant:    event.Tenant,
+			EventID:   event.ID,
+			Event:     event.Type,
+			TodoID:    event.Todo.ID,
+			Status:    domain.DeliveryPending,
+			Payload:   body,
+			CreatedAt: d.opts.Now(),
+		},
+		url:    hook.URL,
+		secret: hook.Secret,
+		key:    key,
+	}
+	d.log = appendCapped(d.log, del, d.opts.LogSize)
+
+	d.start(del)
+}
+
+// start runs the attempts of the delivery. d.mu must be held.
+func (d *Dispatcher) start(del *delivery) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.run(del)
+	}()
+}
+
+func (d *Dispatcher) run(del *delivery) {
+	for {
+		attempt := d.attempt(del)
+
+		d.mu.Lock()
+		if del.canceled {
+			d.mu.Unlock()
+			return
+		}
+		del.Attempts = append(del.Attempts, attempt)
+		del.NextAttemptAt = nil
+
+		if attempt.Error == "" {
+			del.Status = domain.DeliverySucceeded
+			d.mu.Unlock()
+			return
+		}
+
+		tries := len(del.Attempts) - del.round
+		if tries >= d.opts.MaxAttempts {
+			del.Status = domain.DeliveryFailed
+			d.dead = appendCapped(d.dead, del, d.opts.LogSize)
+			d.mu.Unlock()
+			slog.Warn("webhook delivery failed", "id", del.ID, "webhook_id", del.WebhookID, "attempts", tries, "error", attempt.Error)
+			return
+		}
+
+		wait := d.backoff(tries)
+		next := d.opts.Now().Add(wait)
+		del.NextAttemptAt = &next
+		d.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-d.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt makes a single request of the delivery.
+func (d *Dispatcher) attempt(del *delivery) domain.DeliveryAttempt {
+	d.mu.Lock()
+	url, body, event, key := del.url, del.Payload, del.Event, del.key
+	signature := Sign(del.secret, body)
+	d.mu.Unlock()
+
+	start := d.opts.Now()
+	res := domain.DeliveryAttempt{At: start}
+
+	err := func() error {
+		req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "todo-site-webhooks")
+		req.Header.Set(SignatureHeader, signature)
+		req.Header.Set(EventHeader, string(event))
+		req.Header.Set(DeliveryHeader, key)
+
+		resp, err := d.opts.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+		res.StatusCode = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return nil
+	}()
+	if err != nil {
+		res.Error = err.Error()
+	}
+	res.DurationMS = d.opts.Now().Sub(start).Milliseconds()
+
+	return res
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(tries int) time.Duration {
+	wait := d.opts.InitialBackoff
+	for range tries - 1 {
+		if wait >= d.opts.MaxBackoff/2 {
+			return d.opts.MaxBackoff
+		}
+		wait *= 2
+	}
+	return min(wait, d.opts.MaxBackoff)
+}
+
+// Deliveries returns the deliveries of the webhook still in the log,
+// newest first.
+func (d *Dispatcher) Deliveries(tenant domain.Tenant, webhookID int) []domain.WebhookDelivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return collect(d.log, func(del *delivery) bool {
+		return del.Tenant == tenant && del.WebhookID == webhookID
+	})
+}
+
+// DeadLetters returns the deliveries of the workspace that ran out of
+// attempts, newest first.
+func (d *Dispatcher) DeadLetters(tenant domain.Tenant) []domain.WebhookDelivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return collect(d.dead, func(del *delivery) bool {
+		return del.Tenant == tenant
+	})
+}
+
+// Redeliver takes a dead letter of the webhook off the list and gives it a
+// fresh round of attempts.
+func (d *Dispatcher) Redeliver(tenant domain.Tenant, webhookID, id int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := slices.IndexFunc(d.dead, func(del *delivery) bool {
+		return del.Tenant == tenant && del.WebhookID == webhookID && del.ID == id
+	})
+	if i < 0 {
+		return domain.ErrDeliveryNotExist
+	}
+	del := d.dead[i]
+	d.dead = slices.Delete(d.dead, i, i+1)
+
+	del.Status = domain.DeliveryPending
+	del.round = len(del.Attempts)
+	if !slices.Contains(d.log, del) {
+		d.log = appendCapped(d.log, del, d.opts.LogSize)
+	}
+
+	d.start(del)
+	return nil
+}
+
+// Cancel stops the pending deliveries of the webhook and drops its log and
+// dead letters.
+func (d *Dispatcher) Cancel(tenant domain.Tenant, webhookID int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	match := func(del *delivery) bool {
+		if del.Tenant != tenant || del.WebhookID != webhookID {
+			return false
+		}
+		del.canceled = true
+		return true
+	}
+	d.log = slices.DeleteFunc(d.log, match)
+	d.dead = slices.DeleteFunc(d.dead, match)
+}
+
+// Sign returns the value of SignatureHeader for the body. Receivers
+// compute the same over the raw body and compare in constant time, see
+// Verify.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether the signature was made of the body with the
+// secret.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// appendCapped appends del and drops the oldest entries beyond size.
+func appendCapped(list []*delivery, del *delivery, size int) []*delivery {
+	list = append(list, del)
+	if over := len(list) - size; over > 0 {
+		list = slices.Delete(list, 0, over)
+	}
+	return list
+}
+
+// collect copies the matching deliveries, newest first. The mutex of the
+// dispatcher must be held.
+func collect(list []*delivery, match func(*delivery) bool) []domain.WebhookDelivery {
+	res := make([]domain.WebhookDelivery, 0)
+	for _, del := range slices.Backward(list) {
+		if !match(del) {
+			continue
+		}
+		c := del.WebhookDelivery
+		c.Attempts = append([]domain.DeliveryAttempt{}, c.Attempts...)
+		c.Payload = nil
+		res = append(res, c)
+	}
+	return res
+}
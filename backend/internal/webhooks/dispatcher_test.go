@@ -0,0 +1,329 @@
+package webhooks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VLGKiwi/todo-site/backend/internal/domain"
+)
+
+const testSecret = "whsec_0123456789abcdef"
+
+// receiver answers the deliveries with the given statuses in turn, the
+// last one over and over, and keeps the requests.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	status := rc.statuses[min(len(rc.requests), len(rc.statuses))-1]
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func newDispatcher(t *testing.T, statuses ...int) (*Dispatcher, *receiver, domain.Webhook) {
+	t.Helper()
+
+	rc := &receiver{statuses: statuses}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	d := NewDispatcher(Options{Client: localClient(), MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond})
+	t.Cleanup(d.Close)
+
+	return d, rc, domain.Webhook{ID: 5, URL: srv.URL, Secret: testSecret}
+}
+
+// localClient is the default client without the address guard, so the
+// receivers of the tests on 127.0.0.1 can be reached.
+func localClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// settled waits for the delivery to leave the pending status.
+func settled(t *testing.T, d *Dispatcher, tenant domain.Tenant, webhookID, id int) domain.WebhookDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, del := range d.Deliveries(tenant, webhookID) {
+			if del.ID == id && del.Status != domain.DeliveryPending {
+				return del
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("delivery %d is still pending", id)
+	return domain.WebhookDelivery{}
+}
+
+func testEvent() domain.TodoEvent {
+	return domain.TodoEvent{
+		ID:     77,
+		Type:   domain.TodoCompleted,
+		Tenant: "acme",
+		Todo:   domain.Todo{ID: 12, Title: "milk", Completed: true},
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+
+		wantStatus   domain.DeliveryStatus
+		wantAttempts []int
+	}{
+		{
+			name:         "answered with 2xx -> succeeded",
+			statuses:     []int{http.StatusNoContent},
+			wantStatus:   domain.DeliverySucceeded,
+			wantAttempts: []int{http.StatusNoContent},
+		},
+		{
+			name:         "failures then 2xx -> retried until succeeded",
+			statuses:     []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK},
+			wantStatus:   domain.DeliverySucceeded,
+			wantAttempts: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK},
+		},
+		{
+			name:         "redirect -> failed attempt",
+			statuses:     []int{http.StatusFound, http.StatusOK},
+			wantStatus:   domain.DeliverySucceeded,
+			wantAttempts: []int{http.StatusFound, http.StatusOK},
+		},
+		{
+			name:         "out of attempts -> failed",
+			statuses:     []int{http.StatusServiceUnavailable},
+			wantStatus:   domain.DeliveryFailed,
+			wantAttempts: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			d, rc, hook := newDispatcher(t, tc.statuses...)
+
+			// act
+			d.Enqueue(hook, testEvent())
+			got := settled(t, d, "acme", hook.ID, 1)
+
+			// assert
+			if got.Status != tc.wantStatus {
+				t.Errorf("unexpected status: got %q, want %q", got.Status, tc.wantStatus)
+			}
+			if len(got.Attempts) != len(tc.wantAttempts) {
+				t.Fatalf("unexpected attempts: got %+v, want %v", got.Attempts, tc.wantAttempts)
+			}
+			for i, a := range got.Attempts {
+				if a.StatusCode != tc.wantAttempts[i] {
+					t.Errorf("unexpected status of attempt %d: got %d, want %d", i, a.StatusCode, tc.wantAttempts[i])
+				}
+				if failed := a.StatusCode/100 != 2; failed != (a.Error != "") {
+					t.Errorf("unexpected error of attempt %d: %q", i, a.Error)
+				}
+			}
+			if got.NextAttemptAt != nil {
+				t.Errorf("unexpected next attempt: got %v, want nil", got.NextAttemptAt)
+			}
+
+			dead := d.DeadLetters("acme")
+			if wantDead := tc.wantStatus == domain.DeliveryFailed; (len(dead) == 1) != wantDead {
+				t.Errorf("unexpected dead letters: got %+v", dead)
+			}
+
+			// every attempt sends the same signed payload
+			rc.mu.Lock()
+			defer rc.mu.Unlock()
+			for i, r := range rc.requests {
+				if sig := r.Header.Get(SignatureHeader); !Verify(testSecret, rc.bodies[i], sig) {
+					t.Errorf("unexpected signature of request %d: %q", i, sig)
+				}
+				if r.Header.Get(EventHeader) != string(domain.TodoCompleted) || r.Header.Get(DeliveryHeader) != "77-5" {
+					t.Errorf("unexpected headers of request %d: %v", i, r.Header)
+				}
+				if !strings.HasPrefix(string(rc.bodies[i]), `{"id":77,`) {
+					t.Errorf("unexpected payload id of request %d: %s", i, rc.bodies[i])
+				}
+				if string(rc.bodies[i]) != string(rc.bodies[0]) {
+					t.Errorf("unexpected body of request %d: got %s, want %s", i, rc.bodies[i], rc.bodies[0])
+				}
+			}
+		})
+	}
+}
+
+func TestEnqueueRepeatedEvent(t *testing.T) {
+	// preparing
+	d, rc, hook := newDispatcher(t, http.StatusOK)
+	other := domain.Webhook{ID: 6, URL: hook.URL, Secret: testSecret}
+
+	// act
+	d.Enqueue(hook, testEvent())
+	d.Enqueue(other, testEvent())
+	d.Enqueue(hook, testEvent())
+	next := testEvent()
+	next.ID = 78
+	d.Enqueue(hook, next)
+
+	// assert
+	if got := d.Deliveries("acme", hook.ID); len(got) != 2 || got[0].EventID != 78 || got[1].EventID != 77 {
+		t.Errorf("unexpected deliveries: got %+v, want events 78 and 77", got)
+	}
+	if got := d.Deliveries("acme", other.ID); len(got) != 1 {
+		t.Errorf("unexpected deliveries of another webhook: got %+v", got)
+	}
+	settled(t, d, "acme", hook.ID, 1)
+	settled(t, d, "acme", hook.ID, 3)
+	settled(t, d, "acme", other.ID, 2)
+	if rc.count() != 3 {
+		t.Errorf("unexpected requests: got %d, want 3", rc.count())
+	}
+}
+
+func TestForbiddenAddress(t *testing.T) {
+	// preparing
+	rc := &receiver{statuses: []int{http.StatusOK}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	d := NewDispatcher(Options{MaxAttempts: 1})
+	defer d.Close()
+	hook := domain.Webhook{ID: 5, URL: srv.URL, Secret: testSecret}
+
+	// act
+	d.Enqueue(hook, testEvent())
+
+	// assert
+	got := settled(t, d, "acme", hook.ID, 1)
+	if got.Status != domain.DeliveryFailed || len(got.Attempts) != 1 {
+		t.Fatalf("unexpected delivery: got %+v", got)
+	}
+	if a := got.Attempts[0]; a.StatusCode != 0 || !strings.Contains(a.Error, ErrForbiddenAddress.Error()) {
+		t.Errorf("unexpected attempt: got %+v, want %q", a, ErrForbiddenAddress)
+	}
+	if rc.count() != 0 {
+		t.Errorf("unexpected requests: got %d, want 0", rc.count())
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	// preparing
+	d, rc, hook := newDispatcher(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
+	d.Enqueue(hook, testEvent())
+	if got := settled(t, d, "acme", hook.ID, 1); got.Status != domain.DeliveryFailed {
+		t.Fatalf("unexpected status: got %q, want %q", got.Status, domain.DeliveryFailed)
+	}
+
+	// act
+	if err := d.Redeliver("globex", hook.ID, 1); err != domain.ErrDeliveryNotExist {
+		t.Errorf("unexpected error for another workspace: got %v, want %v", err, domain.ErrDeliveryNotExist)
+	}
+	if err := d.Redeliver("acme", hook.ID, 1); err != nil {
+		t.Fatalf("unexpected error: got %v, want nil", err)
+	}
+
+	// assert
+	got := settled(t, d, "acme", hook.ID, 1)
+	if got.Status != domain.DeliverySucceeded || len(got.Attempts) != 4 || rc.count() != 4 {
+		t.Errorf("unexpected delivery: got %+v after %d requests", got, rc.count())
+	}
+	if dead := d.DeadLetters("acme"); len(dead) != 0 {
+		t.Errorf("unexpected dead letters: got %+v", dead)
+	}
+	if err := d.Redeliver("acme", hook.ID, 1); err != domain.ErrDeliveryNotExist {
+		t.Errorf("unexpected error for a delivered one: got %v, want %v", err, domain.ErrDeliveryNotExist)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	// preparing
+	rc := &receiver{statuses: []int{http.StatusInternalServerError}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	d := NewDispatcher(Options{Client: localClient(), InitialBackoff: time.Hour})
+	defer d.Close()
+	hook := domain.Webhook{ID: 5, URL: srv.URL, Secret: testSecret}
+	d.Enqueue(hook, testEvent())
+	d.Enqueue(domain.Webhook{ID: 6, URL: srv.URL, Secret: testSecret}, testEvent())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for rc.count() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// act
+	d.Cancel("acme", hook.ID)
+
+	// assert
+	if got := d.Deliveries("acme", hook.ID); len(got) != 0 {
+		t.Errorf("unexpected deliveries of the canceled webhook: got %+v", got)
+	}
+	if got := d.Deliveries("acme", 6); len(got) != 1 {
+		t.Errorf("unexpected deliveries of another webhook: got %+v", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(Options{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute})
+	defer d.Close()
+
+	tests := []struct {
+		tries int
+		want  time.Duration
+	}{
+		{tries: 1, want: 10 * time.Second},
+		{tries: 2, want: 20 * time.Second},
+		{tries: 3, want: 40 * time.Second},
+		{tries: 4, want: time.Minute},
+		{tries: 100, want: time.Minute},
+	}
+
+	for _, tc := range tests {
+		if got := d.backoff(tc.tries); got != tc.want {
+			t.Errorf("unexpected backoff after %d tries: got %v, want %v", tc.tries, got, tc.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	sig := Sign(testSecret, body)
+
+	if !Verify(testSecret, body, sig) {
+		t.Errorf("signature of the body does not verify: %q", sig)
+	}
+	if Verify("whsec_another-secret-0000", body, sig) {
+		t.Errorf("signature verifies with another secret")
+	}
+	if Verify(testSecret, []byte(`{"id":2}`), sig) {
+		t.Errorf("signature verifies with another body")
+	}
+}
@@ -0,0 +1,78 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is the error of an attempt to a host that resolves
+// to an address of the network the service runs in.
+var ErrForbiddenAddress = errors.New("webhooks: forbidden address")
+
+// newClient returns the default client. It refuses to connect to
+// loopback, link-local, private, shared, translated and unspecified
+// addresses, see forbidden, so webhooks
+// cannot reach the service's own network. The check runs on the address
+// being dialed, after DNS resolution, so a name that resolves to such an
+// address is refused as well, even if it changes between lookups.
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: guardAddress}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed instead of the webhook, bypassing the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// guardAddress is the Control of the dialer of the default client.
+func guardAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if forbidden(ip) {
+		return fmt.Errorf("%w %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+// forbiddenPrefixes are the ranges netip.Addr has no method for. The
+// NAT64 and 6to4 ones embed an IPv4 address that may be a private one.
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4
+}
+
+func forbidden(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package webhooks
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGuardAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		wantErr bool
+	}{
+		{name: "loopback", address: "127.0.0.1:80", wantErr: true},
+		{name: "IPv6 loopback", address: "[::1]:443", wantErr: true},
+		{name: "cloud metadata", address: "169.254.169.254:80", wantErr: true},
+		{name: "private 10/8", address: "10.1.2.3:80", wantErr: true},
+		{name: "private 172.16/12", address: "172.16.0.1:80", wantErr: true},
+		{name: "private 192.168/16", address: "192.168.1.1:80", wantErr: true},
+		{name: "unique local", address: "[fd00::1]:80", wantErr: true},
+		{name: "IPv6 link-local", address: "[fe80::1]:80", wantErr: true},
+		{name: "unspecified", address: "0.0.0.0:80", wantErr: true},
+		{name: "this network", address: "0.1.2.3:80", wantErr: true},
+		{name: "carrier-grade NAT", address: "100.64.0.1:80", wantErr: true},
+		{name: "carrier-grade NAT, last address", address: "100.127.255.254:80", wantErr: true},
+		{name: "NAT64", address: "[64:ff9b::7f00:1]:80", wantErr: true},
+		{name: "NAT64 of a public address", address: "[64:ff9b::5db8:d822]:443", wantErr: true},
+		{name: "local-use NAT64", address: "[64:ff9b:1::a01:203]:80", wantErr: true},
+		{name: "6to4", address: "[2002:7f00:1::1]:80", wantErr: true},
+		{name: "IPv4-mapped loopback", address: "[::ffff:127.0.0.1]:80", wantErr: true},
+		{name: "IPv4-mapped carrier-grade NAT", address: "[::ffff:100.64.0.1]:80", wantErr: true},
+		{name: "public", address: "93.184.216.34:443", wantErr: false},
+		{name: "public next to carrier-grade NAT", address: "100.128.0.1:443", wantErr: false},
+		{name: "public IPv6", address: "[2606:2800:220:1::1]:443", wantErr: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// act
+			err := guardAddress("tcp", tc.address, nil)
+
+			// assert
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error for %s: got %v, want error %t", tc.address, err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrForbiddenAddress) {
+				t.Errorf("unexpected error for %s: got %v, want %v", tc.address, err, ErrForbiddenAddress)
+			}
+		})
+	}
+}
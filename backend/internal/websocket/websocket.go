@@ -0,0 +1,433 @@
+// Package websocket implements the server side of the WebSocket protocol
+// (RFC 6455) on top of net/http hijacking: the opening handshake, framing,
+// masking, fragmented messages and the ping, pong and close control frames.
+//
+// Extensions are not supported; a subprotocol is picked by the caller, see
+// Upgrade. Messages are read by one
+// goroutine at a time; writes are safe for concurrent use.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrBadHandshake   = errors.New("websocket: bad handshake")
+	ErrBadOrigin      = errors.New("websocket: origin not allowed")
+	ErrProtocol       = errors.New("websocket: protocol error")
+	ErrMessageTooBig  = errors.New("websocket: message too big")
+	ErrInvalidPayload = errors.New("websocket: text message is not valid utf-8")
+	ErrClosed         = errors.New("websocket: connection closed")
+)
+
+// MessageType is the type of a data message.
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// Close codes, RFC 6455 section 7.4.1.
+const (
+	CloseNormal         = 1000
+	CloseGoingAway      = 1001
+	CloseProtocolError  = 1002
+	CloseNoStatus       = 1005
+	CloseInvalidPayload = 1007
+	CloseMessageTooBig  = 1009
+	CloseInternalError  = 1011
+	CloseTryAgainLater  = 1013
+)
+
+// DefaultMaxMessageSize caps the messages read when Conn.MaxMessageSize is
+// not set.
+const DefaultMaxMessageSize = 1 << 20
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+
+	finBit  = 0x80
+	maskBit = 0x80
+
+	maxControlPayload = 125
+
+	// acceptGUID is appended to the key of the client, section 1.3.
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// CloseError is returned by ReadMessage once the peer closes the
+// connection.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed by peer with code %d %s", e.Code, e.Reason)
+}
+
+// Conn is an upgraded connection.
+type Conn struct {
+	// MaxMessageSize caps the size of a message read, fragments included,
+	// DefaultMaxMessageSize when zero. A larger message closes the
+	// connection with CloseMessageTooBig.
+	MaxMessageSize int64
+	// ReadTimeout closes a connection the peer sends nothing over, not even
+	// a pong, for that long. Zero means no timeout.
+	ReadTimeout time.Duration
+	// WriteTimeout caps every write. Zero means no timeout.
+	WriteTimeout time.Duration
+
+	conn net.Conn
+	br   *bufio.Reader
+
+	wmu       sync.Mutex
+	closeSent bool
+}
+
+// Upgrade completes the opening handshake and takes the connection over
+// from the server. On failure nothing is written: the caller answers the
+// request, with the Sec-WebSocket-Version header already set when the
+// version of the client is not supported. The caller picks one of the
+// Subprotocols of the client by setting the Sec-WebSocket-Protocol header
+// of w beforehand.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, fmt.Errorf("%w: method must be GET", ErrBadHandshake)
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		return nil, fmt.Errorf("%w: not a websocket upgrade", ErrBadHandshake)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, fmt.Errorf("%w: unsupported version", ErrBadHandshake)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, fmt.Errorf("%w: invalid Sec-WebSocket-Key", ErrBadHandshake)
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("hijack connection: %w", err)
+	}
+	// the deadlines of the server were meant for a single request
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("clear deadlines: %w", err)
+	}
+
+	// headers set so far, such as X-Request-ID, go along with the upgrade
+	header := w.Header().Clone()
+	header.Del("Sec-WebSocket-Version")
+	header.Set("Upgrade", "websocket")
+	header.Set("Connection", "Upgrade")
+	header.Set("Sec-WebSocket-Accept", AcceptKey(key))
+
+	if _, err := brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n"); err == nil {
+		if err = header.Write(brw); err == nil {
+			_, err = brw.WriteString("\r\n")
+		}
+	}
+	if err == nil {
+		err = brw.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("write handshake: %w", err)
+	}
+
+	return &Conn{conn: conn, br: brw.Reader}, nil
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept value for the key of a
+// client.
+func AcceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// Subprotocols returns the subprotocols the client offered, most preferred
+// first.
+func Subprotocols(r *http.Request) []string {
+	var res []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				res = append(res, p)
+			}
+		}
+	}
+	return res
+}
+
+// CheckOrigin returns ErrBadOrigin unless the request has no Origin, as
+// clients other than browsers do, comes from the host it is sent to, or
+// from one of the allowed origins, such as "https://todo.example.com".
+// Browsers send the Origin of the page with every handshake, so checking
+// it keeps other sites from opening a connection on behalf of the user.
+func CheckOrigin(r *http.Request, allowed []string) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrBadOrigin, origin)
+}
+
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next data message. Pings are answered and pongs
+// skipped on the way. A close frame of the peer is answered as well and
+// reported as a *CloseError; a broken frame closes the connection with the
+// matching code.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	limit := c.MaxMessageSize
+	if limit <= 0 {
+		limit = DefaultMaxMessageSize
+	}
+
+	var (
+		typ     MessageType
+		message []byte
+		started bool
+	)
+	for {
+		fin, op, payload, err := c.readFrame(limit - int64(len(message)))
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			return 0, nil, c.closed(payload)
+		case opText, opBinary:
+			if started {
+				return 0, nil, c.fail(fmt.Errorf("%w: new message inside a fragmented one", ErrProtocol))
+			}
+			started, typ = true, MessageType(op)
+		case opContinuation:
+			if !started {
+				return 0, nil, c.fail(fmt.Errorf("%w: continuation without a message", ErrProtocol))
+			}
+		default:
+			return 0, nil, c.fail(fmt.Errorf("%w: unknown opcode %#x", ErrProtocol, op))
+		}
+
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+
+		if typ == TextMessage && !utf8.Valid(message) {
+			return 0, nil, c.fail(ErrInvalidPayload)
+		}
+		return typ, message, nil
+	}
+}
+
+// readFrame reads a single frame of at most limit bytes and unmasks it.
+func (c *Conn) readFrame(limit int64) (fin bool, op byte, payload []byte, err error) {
+	if c.ReadTimeout > 0 {
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout)); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin, op = head[0]&finBit != 0, head[0]&0x0f
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("%w: reserved bits set", ErrProtocol)
+	}
+	// clients must mask every frame, section 5.1
+	if head[1]&maskBit == 0 {
+		return false, 0, nil, fmt.Errorf("%w: unmasked frame", ErrProtocol)
+	}
+
+	length := uint64(head[1] &^ maskBit)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+		if length>>63 != 0 {
+			return false, 0, nil, fmt.Errorf("%w: invalid length", ErrProtocol)
+		}
+	}
+
+	if op >= opClose {
+		if !fin || length > maxControlPayload {
+			return false, 0, nil, fmt.Errorf("%w: fragmented or long control frame", ErrProtocol)
+		}
+	} else if length > uint64(max(limit, 0)) {
+		return false, 0, nil, ErrMessageTooBig
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, op, payload, nil
+}
+
+// fail closes the connection with the code matching err.
+func (c *Conn) fail(err error) error {
+	code := 0
+	switch {
+	case errors.Is(err, ErrProtocol):
+		code = CloseProtocolError
+	case errors.Is(err, ErrMessageTooBig):
+		code = CloseMessageTooBig
+	case errors.Is(err, ErrInvalidPayload):
+		code = CloseInvalidPayload
+	}
+
+	if code != 0 {
+		c.Close(code, "")
+	} else {
+		c.conn.Close()
+	}
+	return err
+}
+
+// closed answers the close frame of the peer and closes the connection.
+func (c *Conn) closed(payload []byte) error {
+	cerr := &CloseError{Code: CloseNoStatus}
+	if len(payload) >= 2 {
+		cerr.Code = int(binary.BigEndian.Uint16(payload))
+		cerr.Reason = string(payload[2:])
+	}
+
+	// the reply echoes the code, section 5.5.1
+	echo := payload
+	if len(echo) > 2 {
+		echo = echo[:2]
+	}
+	c.writeFrame(opClose, echo)
+	c.conn.Close()
+
+	return cerr
+}
+
+// WriteMessage sends a data message in a single frame.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	return c.writeFrame(byte(typ), data)
+}
+
+// Ping sends a ping; the peer answers with a pong that keeps ReadTimeout
+// from expiring.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// Close sends a close frame with the code and reason, unless one is sent
+// already, and closes the connection. It is safe to call more than once.
+func (c *Conn) Close(code int, reason string) error {
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+
+	c.writeFrame(opClose, payload)
+	return c.conn.Close()
+}
+
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.closeSent {
+		return ErrClosed
+	}
+	if op == opClose {
+		c.closeSent = true
+	}
+
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, finBit|op)
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, payload...)
+
+	if c.WriteTimeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout)); err != nil {
+			return err
+		}
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
@@ -0,0 +1,383 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+func TestAcceptKey(t *testing.T) {
+	// the example of RFC 6455 section 1.3
+	if got, want := AcceptKey(testKey), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("unexpected accept key: got %q, want %q", got, want)
+	}
+}
+
+// echoServer echoes every message and reports how reading ended.
+func echoServer(t *testing.T, maxSize int64) (*httptest.Server, <-chan error) {
+	t.Helper()
+
+	done := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		conn.MaxMessageSize = maxSize
+		conn.ReadTimeout = time.Second
+
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				done <- err
+				return
+			}
+			if err := conn.WriteMessage(typ, msg); err != nil {
+				done <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, done
+}
+
+// dial opens a connection and completes the handshake.
+func dial(t *testing.T, srv *httptest.Server) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := "GET / HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + testKey + "\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status code: got %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != AcceptKey(testKey) {
+		t.Fatalf("unexpected accept key: got %q, want %q", got, AcceptKey(testKey))
+	}
+
+	return conn, br
+}
+
+func writeTestFrame(t *testing.T, conn net.Conn, fin bool, op byte, payload []byte, masked bool) {
+	t.Helper()
+
+	b0 := op
+	if fin {
+		b0 |= finBit
+	}
+	frame := []byte{b0}
+
+	var b1 byte
+	if masked {
+		b1 = maskBit
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, b1|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, b1|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, b1|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if masked {
+		mask := [4]byte{1, 2, 3, 4}
+		frame = append(frame, mask[:]...)
+		for i, c := range payload {
+			frame = append(frame, c^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("write frame: %v", err)
+	}
+}
+
+func readTestFrame(t *testing.T, br *bufio.Reader) (byte, []byte) {
+	t.Helper()
+
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if head[1]&maskBit != 0 {
+		t.Fatal("server frames must not be masked")
+	}
+
+	n := uint64(head[1])
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(br, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(br, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	return head[0] & 0x0f, payload
+}
+
+func closeCode(payload []byte) int {
+	if len(payload) < 2 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+func TestConn(t *testing.T) {
+	t.Run("echoes text and binary messages", func(t *testing.T) {
+		// preparing
+		srv, _ := echoServer(t, 0)
+		conn, br := dial(t, srv)
+		long := strings.Repeat("x", 70000)
+
+		// act
+		writeTestFrame(t, conn, true, opText, []byte("hello"), true)
+		writeTestFrame(t, conn, true, opBinary, []byte(long), true)
+
+		// assert
+		if op, payload := readTestFrame(t, br); op != opText || string(payload) != "hello" {
+			t.Errorf("unexpected frame: got %#x %q, want text %q", op, payload, "hello")
+		}
+		if op, payload := readTestFrame(t, br); op != opBinary || len(payload) != len(long) {
+			t.Errorf("unexpected frame: got %#x of %d bytes, want binary of %d", op, len(payload), len(long))
+		}
+	})
+
+	t.Run("joins fragments and answers pings in between", func(t *testing.T) {
+		// preparing
+		srv, _ := echoServer(t, 0)
+		conn, br := dial(t, srv)
+
+		// act
+		writeTestFrame(t, conn, false, opText, []byte("hel"), true)
+		writeTestFrame(t, conn, true, opPing, []byte("are you there"), true)
+		writeTestFrame(t, conn, true, opContinuation, []byte("lo"), true)
+
+		// assert
+		if op, payload := readTestFrame(t, br); op != opPong || string(payload) != "are you there" {
+			t.Errorf("unexpected frame: got %#x %q, want pong", op, payload)
+		}
+		if op, payload := readTestFrame(t, br); op != opText || string(payload) != "hello" {
+			t.Errorf("unexpected frame: got %#x %q, want text %q", op, payload, "hello")
+		}
+	})
+
+	t.Run("close is echoed", func(t *testing.T) {
+		// preparing
+		srv, done := echoServer(t, 0)
+		conn, br := dial(t, srv)
+
+		// act
+		writeTestFrame(t, conn, true, opClose, append(binary.BigEndian.AppendUint16(nil, CloseNormal), "bye"...), true)
+
+		// assert
+		if op, payload := readTestFrame(t, br); op != opClose || closeCode(payload) != CloseNormal {
+			t.Errorf("unexpected frame: got %#x code %d, want close %d", op, closeCode(payload), CloseNormal)
+		}
+		var cerr *CloseError
+		if err := <-done; !errors.As(err, &cerr) || cerr.Code != CloseNormal || cerr.Reason != "bye" {
+			t.Errorf("unexpected error: got %v, want close %d bye", err, CloseNormal)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		write func(t *testing.T, conn net.Conn)
+
+		wantCode int
+		wantErr  error
+	}{
+		{
+			name: "unmasked frame -> protocol error",
+			write: func(t *testing.T, conn net.Conn) {
+				writeTestFrame(t, conn, true, opText, []byte("hello"), false)
+			},
+			wantCode: CloseProtocolError,
+			wantErr:  ErrProtocol,
+		},
+		{
+			name: "continuation without a message -> protocol error",
+			write: func(t *testing.T, conn net.Conn) {
+				writeTestFrame(t, conn, true, opContinuation, []byte("hello"), true)
+			},
+			wantCode: CloseProtocolError,
+			wantErr:  ErrProtocol,
+		},
+		{
+			name: "fragmented ping -> protocol error",
+			write: func(t *testing.T, conn net.Conn) {
+				writeTestFrame(t, conn, false, opPing, nil, true)
+			},
+			wantCode: CloseProtocolError,
+			wantErr:  ErrProtocol,
+		},
+		{
+			name: "message over the limit -> too big",
+			write: func(t *testing.T, conn net.Conn) {
+				writeTestFrame(t, conn, false, opText, []byte("0123456789"), true)
+				writeTestFrame(t, conn, true, opContinuation, []byte("0123456789"), true)
+			},
+			wantCode: CloseMessageTooBig,
+			wantErr:  ErrMessageTooBig,
+		},
+		{
+			name: "text that is not utf-8 -> invalid payload",
+			write: func(t *testing.T, conn net.Conn) {
+				writeTestFrame(t, conn, true, opText, []byte{0xff, 0xfe}, true)
+			},
+			wantCode: CloseInvalidPayload,
+			wantErr:  ErrInvalidPayload,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			srv, done := echoServer(t, 16)
+			conn, br := dial(t, srv)
+
+			// act
+			tc.write(t, conn)
+
+			// assert
+			if op, payload := readTestFrame(t, br); op != opClose || closeCode(payload) != tc.wantCode {
+				t.Errorf("unexpected frame: got %#x code %d, want close %d", op, closeCode(payload), tc.wantCode)
+			}
+			if err := <-done; !errors.Is(err, tc.wantErr) {
+				t.Errorf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+
+		wantVersionHeader bool
+	}{
+		{
+			name:    "plain request -> error",
+			headers: map[string]string{},
+		},
+		{
+			name: "unsupported version -> error",
+			headers: map[string]string{
+				"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Key": testKey, "Sec-WebSocket-Version": "8",
+			},
+			wantVersionHeader: true,
+		},
+		{
+			name: "malformed key -> error",
+			headers: map[string]string{
+				"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Key": "short", "Sec-WebSocket-Version": "13",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// preparing
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, v := range tc.headers {
+				req.Header.Set(name, v)
+			}
+			rec := httptest.NewRecorder()
+
+			// act
+			_, err := Upgrade(rec, req)
+
+			// assert
+			if !errors.Is(err, ErrBadHandshake) {
+				t.Fatalf("unexpected error: got %v, want %v", err, ErrBadHandshake)
+			}
+			if got := rec.Header().Get("Sec-WebSocket-Version") == "13"; got != tc.wantVersionHeader {
+				t.Errorf("unexpected Sec-WebSocket-Version header: got %v, want %v", got, tc.wantVersionHeader)
+			}
+		})
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	allowed := []string{"https://todo.example.com/", "http://localhost:3000"}
+
+	tests := []struct {
+		name   string
+		origin string
+
+		wantErr error
+	}{
+		{name: "no origin -> allowed", origin: ""},
+		{name: "same host -> allowed", origin: "https://api.example.com"},
+		{name: "allowed origin -> allowed", origin: "https://todo.example.com"},
+		{name: "allowed origin with port -> allowed", origin: "http://localhost:3000"},
+		{name: "another port -> error", origin: "http://localhost:3001", wantErr: ErrBadOrigin},
+		{name: "another site -> error", origin: "https://evil.example", wantErr: ErrBadOrigin},
+		{name: "opaque origin -> error", origin: "null", wantErr: ErrBadOrigin},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+
+			if err := CheckOrigin(req, allowed); !errors.Is(err, tc.wantErr) {
+				t.Errorf("unexpected error: got %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSubprotocols(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("Sec-WebSocket-Protocol", "todo-site, bearer.abc")
+	req.Header.Add("Sec-WebSocket-Protocol", " chat ")
+
+	got := Subprotocols(req)
+
+	if want := []string{"todo-site", "bearer.abc", "chat"}; !slices.Equal(got, want) {
+		t.Errorf("unexpected subprotocols: got %q, want %q", got, want)
+	}
+}